@@ -1,12 +1,16 @@
 package api
 
 import (
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"plandex/auth"
+	"plandex/logging"
 	"plandex/types"
 	"time"
+
+	"github.com/plandex/plandex/shared"
 )
 
 const dialTimeout = 10 * time.Second
@@ -47,21 +51,47 @@ func (t *authenticatedTransport) RoundTrip(req *http.Request) (*http.Response, e
 	return t.underlyingTransport.RoundTrip(req)
 }
 
+// loggingTransport gives each request an id that the server logs too, and logs the request with it
+type loggingTransport struct {
+	underlyingTransport http.RoundTripper
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestId := logging.NewRequestId()
+	req.Header.Set(shared.RequestIdHeader, requestId)
+
+	start := time.Now()
+	resp, err := t.underlyingTransport.RoundTrip(req)
+	if err != nil {
+		slog.Warn("API request failed", "request_id", requestId, "method", req.Method, "path", req.URL.Path, "error", err)
+		return resp, err
+	}
+
+	// error responses are logged with their message by handleApiError
+	slog.Debug("API request", "request_id", requestId, "method", req.Method, "path", req.URL.Path, "status", resp.StatusCode, "duration_ms", time.Since(start).Milliseconds())
+
+	return resp, nil
+}
+
 var netDialer = &net.Dialer{
 	Timeout: dialTimeout,
 }
 
 var unauthenticatedClient = &http.Client{
-	Transport: &http.Transport{
-		Dial: netDialer.Dial,
+	Transport: &loggingTransport{
+		underlyingTransport: &http.Transport{
+			Dial: netDialer.Dial,
+		},
 	},
 	Timeout: fastReqTimeout,
 }
 
 var authenticatedFastClient = &http.Client{
 	Transport: &authenticatedTransport{
-		underlyingTransport: &http.Transport{
-			Dial: netDialer.Dial,
+		underlyingTransport: &loggingTransport{
+			underlyingTransport: &http.Transport{
+				Dial: netDialer.Dial,
+			},
 		},
 	},
 	Timeout: fastReqTimeout,
@@ -69,8 +99,10 @@ var authenticatedFastClient = &http.Client{
 
 var authenticatedSlowClient = &http.Client{
 	Transport: &authenticatedTransport{
-		underlyingTransport: &http.Transport{
-			Dial: netDialer.Dial,
+		underlyingTransport: &loggingTransport{
+			underlyingTransport: &http.Transport{
+				Dial: netDialer.Dial,
+			},
 		},
 	},
 	Timeout: slowReqTimeout,
@@ -78,8 +110,10 @@ var authenticatedSlowClient = &http.Client{
 
 var authenticatedStreamingClient = &http.Client{
 	Transport: &authenticatedTransport{
-		underlyingTransport: &http.Transport{
-			Dial: netDialer.Dial,
+		underlyingTransport: &loggingTransport{
+			underlyingTransport: &http.Transport{
+				Dial: netDialer.Dial,
+			},
 		},
 	},
 	// No global timeout set for the streaming client
@@ -2,15 +2,33 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"plandex/auth"
+	"plandex/term"
 	"strings"
+	"time"
 
 	"github.com/plandex/plandex/shared"
 )
 
 func handleApiError(r *http.Response, errBody []byte) *shared.ApiError {
+	apiError := parseApiError(r, errBody)
+
+	requestId := r.Header.Get(shared.RequestIdHeader)
+	slog.Warn("API error", "request_id", requestId, "status", r.StatusCode, "type", apiError.Type, "msg", apiError.Msg)
+
+	// so server errors can be looked up in the server's logs
+	if r.StatusCode >= http.StatusInternalServerError && requestId != "" {
+		apiError.Msg += fmt.Sprintf(" (request id %s)", requestId)
+	}
+
+	return apiError
+}
+
+func parseApiError(r *http.Response, errBody []byte) *shared.ApiError {
 	// Check if the response is JSON
 	if r.Header.Get("Content-Type") != "application/json" {
 		return &shared.ApiError{
@@ -33,8 +51,29 @@ func handleApiError(r *http.Response, errBody []byte) *shared.ApiError {
 	return &apiError
 }
 
+// the longest wait for a rate limit before retrying. Longer waits, like for a daily token quota, are errors.
+const maxRateLimitWait = 2 * time.Minute
+
+// refreshTokenIfNeeded returns true if the request should be retried: after refreshing an invalid token, or after waiting
+// out a rate limit
 func refreshTokenIfNeeded(apiErr *shared.ApiError) (bool, *shared.ApiError) {
-	if apiErr.Type == shared.ApiErrorTypeInvalidToken {
+	if apiErr.Type == shared.ApiErrorTypeRateLimited && apiErr.RateLimitError != nil {
+		wait := time.Duration(apiErr.RateLimitError.RetryAfterSeconds) * time.Second
+		if wait <= maxRateLimitWait {
+			term.Countdown(apiErr.Msg+". Retrying", wait)
+			return true, nil
+		}
+		wait = wait.Round(time.Minute)
+		return false, &shared.ApiError{
+			Type:           apiErr.Type,
+			Status:         apiErr.Status,
+			Msg:            fmt.Sprintf("%s. Try again in %dh %dm.", apiErr.Msg, int(wait.Hours()), int(wait.Minutes())%60),
+			RateLimitError: apiErr.RateLimitError,
+		}
+	}
+
+	// an API key can't be refreshed; the error says it's invalid
+	if apiErr.Type == shared.ApiErrorTypeInvalidToken && !auth.UsingApiKey() {
 		err := auth.RefreshInvalidToken()
 		if err != nil {
 			return false, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: "error refreshing invalid token"}
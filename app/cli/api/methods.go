@@ -7,8 +7,10 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"plandex/types"
 	"strings"
+	"time"
 
 	"github.com/plandex/plandex/shared"
 )
@@ -20,7 +22,7 @@ func (a *Api) StartTrial() (*shared.StartTrialResponse, *shared.ApiError) {
 
 	resp, err := unauthenticatedClient.Post(serverUrl, "application/json", nil)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 
 	defer resp.Body.Close()
@@ -50,7 +52,7 @@ func (a *Api) CreateProject(req shared.CreateProjectRequest) (*shared.CreateProj
 
 	resp, err := authenticatedFastClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -77,7 +79,7 @@ func (a *Api) ListProjects() ([]*shared.Project, *shared.ApiError) {
 	serverUrl := getApiHost() + "/projects"
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -115,7 +117,7 @@ func (a *Api) SetProjectPlan(projectId string, req shared.SetProjectPlanRequest)
 
 	resp, err := authenticatedFastClient.Do(request)
 	if err != nil {
-		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -147,7 +149,7 @@ func (a *Api) RenameProject(projectId string, req shared.RenameProjectRequest) *
 
 	resp, err := authenticatedFastClient.Do(request)
 	if err != nil {
-		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -174,7 +176,7 @@ func (a *Api) ListPlans(projectIds []string) ([]*shared.Plan, *shared.ApiError)
 
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -209,7 +211,7 @@ func (a *Api) ListArchivedPlans(projectIds []string) ([]*shared.Plan, *shared.Ap
 
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -245,7 +247,7 @@ func (a *Api) ListPlansRunning(projectIds []string, includeRecent bool) (*shared
 
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -287,7 +289,7 @@ func (a *Api) GetCurrentBranchByPlanId(projectId string, req shared.GetCurrentBr
 	resp, err := authenticatedFastClient.Do(request)
 
 	if err != nil {
-		return nil, &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -321,7 +323,7 @@ func (a *Api) CreatePlan(projectId string, req shared.CreatePlanRequest) (*share
 
 	resp, err := authenticatedFastClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -349,7 +351,7 @@ func (a *Api) GetPlan(planId string) (*shared.Plan, *shared.ApiError) {
 
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 
 	defer resp.Body.Close()
@@ -383,7 +385,7 @@ func (a *Api) DeletePlan(planId string) *shared.ApiError {
 
 	resp, err := authenticatedFastClient.Do(req)
 	if err != nil {
-		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -411,7 +413,7 @@ func (a *Api) DeleteAllPlans(projectId string) *shared.ApiError {
 
 	resp, err := authenticatedFastClient.Do(req)
 	if err != nil {
-		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -453,7 +455,7 @@ func (a *Api) TellPlan(planId, branch string, req shared.TellPlanRequest, onStre
 
 	resp, err := client.Do(request)
 	if err != nil {
-		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 
 	if resp.StatusCode >= 400 {
@@ -470,7 +472,7 @@ func (a *Api) TellPlan(planId, branch string, req shared.TellPlanRequest, onStre
 
 	if req.ConnectStream {
 		log.Println("Connecting stream")
-		connectPlanRespStream(resp.Body, onStream)
+		connectPlanRespStream(planId, branch, resp.Body, onStream)
 	} else {
 		// log.Println("Background exec - not connecting stream")
 		resp.Body.Close()
@@ -504,7 +506,7 @@ func (a *Api) BuildPlan(planId, branch string, req shared.BuildPlanRequest, onSt
 
 	resp, err := client.Do(request)
 	if err != nil {
-		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 
 	if resp.StatusCode >= 400 {
@@ -523,7 +525,7 @@ func (a *Api) BuildPlan(planId, branch string, req shared.BuildPlanRequest, onSt
 
 	if req.ConnectStream {
 		log.Println("Connecting stream")
-		connectPlanRespStream(resp.Body, onStream)
+		connectPlanRespStream(planId, branch, resp.Body, onStream)
 	} else {
 		// log.Println("Background exec - not connecting stream")
 		resp.Body.Close()
@@ -548,7 +550,7 @@ func (a *Api) RespondMissingFile(planId, branch string, req shared.RespondMissin
 
 	resp, err := authenticatedFastClient.Do(request)
 	if err != nil {
-		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 
 	if resp.StatusCode >= 400 {
@@ -577,7 +579,7 @@ func (a *Api) ConnectPlan(planId, branch string, onStream types.OnStreamPlan) *s
 
 	resp, err := authenticatedStreamingClient.Do(req)
 	if err != nil {
-		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 
 	if resp.StatusCode >= 400 {
@@ -593,7 +595,7 @@ func (a *Api) ConnectPlan(planId, branch string, onStream types.OnStreamPlan) *s
 		return apiErr
 	}
 
-	connectPlanRespStream(resp.Body, onStream)
+	connectPlanRespStream(planId, branch, resp.Body, onStream)
 
 	return nil
 }
@@ -608,7 +610,7 @@ func (a *Api) StopPlan(planId, branch string) *shared.ApiError {
 
 	resp, err := authenticatedFastClient.Do(req)
 	if err != nil {
-		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -630,7 +632,7 @@ func (a *Api) GetCurrentPlanState(planId, branch string) (*shared.CurrentPlanSta
 
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -653,17 +655,30 @@ func (a *Api) GetCurrentPlanState(planId, branch string) (*shared.CurrentPlanSta
 	return &state, nil
 }
 
-func (a *Api) ApplyPlan(planId, branch string) *shared.ApiError {
+func (a *Api) ApplyPlan(planId, branch string, applyReq *shared.ApplyPlanRequest) *shared.ApiError {
 	serverUrl := fmt.Sprintf("%s/plans/%s/%s/apply", getApiHost(), planId, branch)
 
-	req, err := http.NewRequest(http.MethodPatch, serverUrl, nil)
+	// without a request body, all pending changes are applied
+	var body io.Reader
+	if applyReq != nil {
+		reqBytes, err := json.Marshal(applyReq)
+		if err != nil {
+			return &shared.ApiError{Msg: fmt.Sprintf("error marshalling request: %v", err)}
+		}
+		body = bytes.NewBuffer(reqBytes)
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, serverUrl, body)
 	if err != nil {
 		return &shared.ApiError{Msg: fmt.Sprintf("error creating request: %v", err)}
 	}
+	if applyReq != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	resp, err := authenticatedFastClient.Do(req)
 	if err != nil {
-		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -673,7 +688,7 @@ func (a *Api) ApplyPlan(planId, branch string) *shared.ApiError {
 
 		didRefresh, apiErr := refreshTokenIfNeeded(apiErr)
 		if didRefresh {
-			return a.ApplyPlan(planId, branch)
+			return a.ApplyPlan(planId, branch, applyReq)
 		}
 		return apiErr
 	}
@@ -691,7 +706,7 @@ func (a *Api) ArchivePlan(planId string) *shared.ApiError {
 
 	resp, err := authenticatedFastClient.Do(req)
 	if err != nil {
-		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -719,7 +734,7 @@ func (a *Api) RejectAllChanges(planId, branch string) *shared.ApiError {
 
 	resp, err := authenticatedFastClient.Do(req)
 	if err != nil {
-		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -737,10 +752,44 @@ func (a *Api) RejectAllChanges(planId, branch string) *shared.ApiError {
 	return nil
 }
 
-func (a *Api) RejectFile(planId, branch, filePath string) *shared.ApiError {
+func (a *Api) RejectFile(planId, branch, filePath, comment string) *shared.ApiError {
 	serverUrl := fmt.Sprintf("%s/plans/%s/%s/reject_file", getApiHost(), planId, branch)
 
-	reqBytes, err := json.Marshal(shared.RejectFileRequest{FilePath: filePath})
+	reqBytes, err := json.Marshal(shared.RejectFileRequest{FilePath: filePath, Comment: comment})
+
+	if err != nil {
+		return &shared.ApiError{Msg: fmt.Sprintf("error marshalling request: %v", err)}
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, serverUrl, bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return &shared.ApiError{Msg: fmt.Sprintf("error creating request: %v", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := authenticatedFastClient.Do(req)
+	if err != nil {
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+		didRefresh, apiErr := refreshTokenIfNeeded(apiErr)
+		if didRefresh {
+			a.RejectFile(planId, branch, filePath, comment)
+		}
+		return apiErr
+	}
+
+	return nil
+}
+
+func (a *Api) EditFile(planId, branch, filePath, content string) *shared.ApiError {
+	serverUrl := fmt.Sprintf("%s/plans/%s/%s/edit_file", getApiHost(), planId, branch)
+
+	reqBytes, err := json.Marshal(shared.EditFileRequest{FilePath: filePath, Content: content})
 
 	if err != nil {
 		return &shared.ApiError{Msg: fmt.Sprintf("error marshalling request: %v", err)}
@@ -754,7 +803,7 @@ func (a *Api) RejectFile(planId, branch, filePath string) *shared.ApiError {
 
 	resp, err := authenticatedFastClient.Do(req)
 	if err != nil {
-		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -763,7 +812,7 @@ func (a *Api) RejectFile(planId, branch, filePath string) *shared.ApiError {
 		apiErr := handleApiError(resp, errorBody)
 		didRefresh, apiErr := refreshTokenIfNeeded(apiErr)
 		if didRefresh {
-			a.RejectFile(planId, branch, filePath)
+			return a.EditFile(planId, branch, filePath, content)
 		}
 		return apiErr
 	}
@@ -781,7 +830,7 @@ func (a *Api) LoadContext(planId, branch string, req shared.LoadContextRequest)
 	// use the slow client since we may be uploading relatively large files
 	resp, err := authenticatedSlowClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -823,7 +872,7 @@ func (a *Api) UpdateContext(planId, branch string, req shared.UpdateContextReque
 	// use the slow client since we may be uploading relatively large files
 	resp, err := authenticatedSlowClient.Do(request)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -861,7 +910,7 @@ func (a *Api) DeleteContext(planId, branch string, req shared.DeleteContextReque
 
 	resp, err := authenticatedFastClient.Do(request)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -884,12 +933,50 @@ func (a *Api) DeleteContext(planId, branch string, req shared.DeleteContextReque
 	return &deleteContextResponse, nil
 }
 
+func (a *Api) PinContext(planId, branch string, req shared.PinContextRequest) (*shared.PinContextResponse, *shared.ApiError) {
+	serverUrl := fmt.Sprintf("%s/plans/%s/%s/context/pin", getApiHost(), planId, branch)
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error marshalling request: %v", err)}
+	}
+
+	request, err := http.NewRequest(http.MethodPut, serverUrl, bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error creating request: %v", err)}
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := authenticatedFastClient.Do(request)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.PinContext(planId, branch, req)
+		}
+		return nil, apiErr
+	}
+
+	var pinContextResponse shared.PinContextResponse
+	err = json.NewDecoder(resp.Body).Decode(&pinContextResponse)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &pinContextResponse, nil
+}
+
 func (a *Api) ListContext(planId, branch string) ([]*shared.Context, *shared.ApiError) {
 	serverUrl := fmt.Sprintf("%s/plans/%s/%s/context", getApiHost(), planId, branch)
 
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -917,7 +1004,7 @@ func (a *Api) ListConvo(planId, branch string) ([]*shared.ConvoMessage, *shared.
 
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -940,12 +1027,104 @@ func (a *Api) ListConvo(planId, branch string) ([]*shared.ConvoMessage, *shared.
 	return convos, nil
 }
 
+func (a *Api) ListPlanSteps(planId, branch string) ([]*shared.PlanStep, *shared.ApiError) {
+	serverUrl := fmt.Sprintf("%s/plans/%s/%s/steps", getApiHost(), planId, branch)
+
+	resp, err := authenticatedFastClient.Get(serverUrl)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.ListPlanSteps(planId, branch)
+		}
+		return nil, apiErr
+	}
+
+	var steps []*shared.PlanStep
+	err = json.NewDecoder(resp.Body).Decode(&steps)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return steps, nil
+}
+
+func (a *Api) DiffPlan(planId, branch, from, to string) (*shared.PlanDiffResponse, *shared.ApiError) {
+	query := url.Values{}
+	query.Set("from", from)
+	query.Set("to", to)
+	serverUrl := fmt.Sprintf("%s/plans/%s/%s/diff?%s", getApiHost(), planId, branch, query.Encode())
+
+	resp, err := authenticatedFastClient.Get(serverUrl)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.DiffPlan(planId, branch, from, to)
+		}
+		return nil, apiErr
+	}
+
+	var diff shared.PlanDiffResponse
+	err = json.NewDecoder(resp.Body).Decode(&diff)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &diff, nil
+}
+
+func (a *Api) Review(planId, branch string, req shared.ReviewRequest) (*shared.ReviewResponse, *shared.ApiError) {
+	serverUrl := fmt.Sprintf("%s/plans/%s/%s/review", getApiHost(), planId, branch)
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error marshalling request: %v", err)}
+	}
+
+	// use the slow client since the review is a model call
+	resp, err := authenticatedSlowClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.Review(planId, branch, req)
+		}
+		return nil, apiErr
+	}
+
+	var review shared.ReviewResponse
+	err = json.NewDecoder(resp.Body).Decode(&review)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &review, nil
+}
+
 func (a *Api) ListLogs(planId, branch string) (*shared.LogResponse, *shared.ApiError) {
 	serverUrl := fmt.Sprintf("%s/plans/%s/%s/logs", getApiHost(), planId, branch)
 
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 
 	defer resp.Body.Close()
@@ -969,6 +1148,67 @@ func (a *Api) ListLogs(planId, branch string) (*shared.LogResponse, *shared.ApiE
 	return &logs, nil
 }
 
+func (a *Api) ExportPlan(planId, branch string) (*shared.PlanArchive, *shared.ApiError) {
+	serverUrl := fmt.Sprintf("%s/plans/%s/%s/export", getApiHost(), planId, branch)
+
+	resp, err := authenticatedFastClient.Get(serverUrl)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.ExportPlan(planId, branch)
+		}
+		return nil, apiErr
+	}
+
+	var archive shared.PlanArchive
+	err = json.NewDecoder(resp.Body).Decode(&archive)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &archive, nil
+}
+
+func (a *Api) ImportPlan(projectId string, archive *shared.PlanArchive) (*shared.ImportPlanResponse, *shared.ApiError) {
+	serverUrl := fmt.Sprintf("%s/projects/%s/plans/import", getApiHost(), projectId)
+	reqBytes, err := json.Marshal(archive)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error marshalling request: %v", err)}
+	}
+
+	resp, err := authenticatedFastClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.ImportPlan(projectId, archive)
+		}
+		return nil, apiErr
+	}
+
+	var respBody shared.ImportPlanResponse
+	err = json.NewDecoder(resp.Body).Decode(&respBody)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &respBody, nil
+}
+
 func (a *Api) RewindPlan(planId, branch string, req shared.RewindPlanRequest) (*shared.RewindPlanResponse, *shared.ApiError) {
 	serverUrl := fmt.Sprintf("%s/plans/%s/%s/rewind", getApiHost(), planId, branch)
 	reqBytes, err := json.Marshal(req)
@@ -984,7 +1224,7 @@ func (a *Api) RewindPlan(planId, branch string, req shared.RewindPlanRequest) (*
 
 	resp, err := authenticatedFastClient.Do(request)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 
 	defer resp.Body.Close()
@@ -1008,6 +1248,46 @@ func (a *Api) RewindPlan(planId, branch string, req shared.RewindPlanRequest) (*
 	return &rewindPlanResponse, nil
 }
 
+func (a *Api) CompactConvo(planId, branch string, req shared.CompactConvoRequest) (*shared.CompactConvoResponse, *shared.ApiError) {
+	serverUrl := fmt.Sprintf("%s/plans/%s/%s/compact", getApiHost(), planId, branch)
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error marshalling request: %v", err)}
+	}
+
+	request, err := http.NewRequest(http.MethodPatch, serverUrl, bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error creating request: %v", err)}
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	// summarizing the conversation can take a while if there's no stored summary to reuse
+	resp, err := authenticatedSlowClient.Do(request)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.CompactConvo(planId, branch, req)
+		}
+		return nil, apiErr
+	}
+
+	var compactConvoResponse shared.CompactConvoResponse
+	err = json.NewDecoder(resp.Body).Decode(&compactConvoResponse)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &compactConvoResponse, nil
+}
+
 func (a *Api) SignIn(req shared.SignInRequest, customHost string) (*shared.SessionResponse, *shared.ApiError) {
 	host := customHost
 	if host == "" {
@@ -1021,7 +1301,7 @@ func (a *Api) SignIn(req shared.SignInRequest, customHost string) (*shared.Sessi
 
 	resp, err := unauthenticatedClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1053,7 +1333,7 @@ func (a *Api) CreateAccount(req shared.CreateAccountRequest, customHost string)
 
 	resp, err := unauthenticatedClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1081,7 +1361,7 @@ func (a *Api) ConvertTrial(req shared.ConvertTrialRequest) (*shared.SessionRespo
 
 	resp, err := authenticatedFastClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1109,7 +1389,7 @@ func (a *Api) CreateOrg(req shared.CreateOrgRequest) (*shared.CreateOrgResponse,
 
 	resp, err := authenticatedFastClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1136,7 +1416,7 @@ func (a *Api) GetOrgSession() *shared.ApiError {
 	serverUrl := getApiHost() + "/orgs/session"
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1153,7 +1433,7 @@ func (a *Api) ListOrgs() ([]*shared.Org, *shared.ApiError) {
 	serverUrl := getApiHost() + "/orgs"
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1185,7 +1465,7 @@ func (a *Api) DeleteUser(userId string) *shared.ApiError {
 
 	resp, err := authenticatedFastClient.Do(req)
 	if err != nil {
-		return &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1206,7 +1486,7 @@ func (a *Api) ListOrgRoles() ([]*shared.OrgRole, *shared.ApiError) {
 	serverUrl := getApiHost() + "/orgs/roles"
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %s", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %s", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1239,7 +1519,7 @@ func (a *Api) InviteUser(req shared.InviteRequest) *shared.ApiError {
 
 	resp, err := authenticatedFastClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
 	if err != nil {
-		return &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1261,7 +1541,7 @@ func (a *Api) ListPendingInvites() ([]*shared.Invite, *shared.ApiError) {
 	serverUrl := getApiHost() + "/invites/pending"
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1288,7 +1568,7 @@ func (a *Api) ListAcceptedInvites() ([]*shared.Invite, *shared.ApiError) {
 	serverUrl := getApiHost() + "/invites/accepted"
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1315,7 +1595,7 @@ func (a *Api) ListAllInvites() ([]*shared.Invite, *shared.ApiError) {
 	serverUrl := getApiHost() + "/invites/all"
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1347,7 +1627,7 @@ func (a *Api) DeleteInvite(inviteId string) *shared.ApiError {
 
 	resp, err := authenticatedFastClient.Do(req)
 	if err != nil {
-		return &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1365,65 +1645,44 @@ func (a *Api) DeleteInvite(inviteId string) *shared.ApiError {
 	return nil
 }
 
-func (a *Api) CreateEmailVerification(email, customHost, userId string) (*shared.CreateEmailVerificationResponse, *shared.ApiError) {
-	host := customHost
-	if host == "" {
-		host = cloudApiHost
-	}
-	serverUrl := host + "/accounts/email_verifications"
-	req := shared.CreateEmailVerificationRequest{Email: email, UserId: userId}
+func (a *Api) CreateApiKey(req shared.CreateApiKeyRequest) (*shared.CreateApiKeyResponse, *shared.ApiError) {
+	serverUrl := getApiHost() + "/api_keys"
 	reqBytes, err := json.Marshal(req)
 	if err != nil {
 		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error marshalling request: %v", err)}
 	}
 
-	resp, err := unauthenticatedClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
+	resp, err := authenticatedFastClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
 		errorBody, _ := io.ReadAll(resp.Body)
-		return nil, handleApiError(resp, errorBody)
-	}
-
-	var verificationResponse shared.CreateEmailVerificationResponse
-	err = json.NewDecoder(resp.Body).Decode(&verificationResponse)
-	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
-	}
-
-	return &verificationResponse, nil
-}
-
-func (a *Api) SignOut() *shared.ApiError {
-	serverUrl := getApiHost() + "/accounts/sign_out"
+		apiErr := handleApiError(resp, errorBody)
 
-	req, err := http.NewRequest(http.MethodPost, serverUrl, nil)
-	if err != nil {
-		return &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error creating request: %v", err)}
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.CreateApiKey(req)
+		}
+		return nil, apiErr
 	}
 
-	resp, err := authenticatedFastClient.Do(req)
+	var res shared.CreateApiKeyResponse
+	err = json.NewDecoder(resp.Body).Decode(&res)
 	if err != nil {
-		return &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		errorBody, _ := io.ReadAll(resp.Body)
-		return handleApiError(resp, errorBody)
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
 	}
 
-	return nil
+	return &res, nil
 }
 
-func (a *Api) ListUsers() (*shared.ListUsersResponse, *shared.ApiError) {
-	serverUrl := getApiHost() + "/users"
+func (a *Api) ListApiKeys() ([]*shared.ApiKey, *shared.ApiError) {
+	serverUrl := getApiHost() + "/api_keys"
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1432,12 +1691,366 @@ func (a *Api) ListUsers() (*shared.ListUsersResponse, *shared.ApiError) {
 		apiErr := handleApiError(resp, errorBody)
 		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
 		if tokenRefreshed {
-			return a.ListUsers()
+			return a.ListApiKeys()
 		}
 		return nil, apiErr
 	}
 
-	var r *shared.ListUsersResponse
+	var apiKeys []*shared.ApiKey
+	err = json.NewDecoder(resp.Body).Decode(&apiKeys)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return apiKeys, nil
+}
+
+func (a *Api) RevokeApiKey(idOrName string) *shared.ApiError {
+	serverUrl := fmt.Sprintf("%s/api_keys/%s", getApiHost(), url.PathEscape(idOrName))
+	req, err := http.NewRequest(http.MethodDelete, serverUrl, nil)
+	if err != nil {
+		return &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error creating request: %v", err)}
+	}
+
+	resp, err := authenticatedFastClient.Do(req)
+	if err != nil {
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.RevokeApiKey(idOrName)
+		}
+		return apiErr
+	}
+
+	return nil
+}
+
+func (a *Api) ListQuotas() ([]*shared.Quota, *shared.ApiError) {
+	serverUrl := getApiHost() + "/quotas"
+	resp, err := authenticatedFastClient.Get(serverUrl)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.ListQuotas()
+		}
+		return nil, apiErr
+	}
+
+	var quotas []*shared.Quota
+	err = json.NewDecoder(resp.Body).Decode(&quotas)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return quotas, nil
+}
+
+// SetQuota returns the updated quota, or nil if it no longer has any limits
+func (a *Api) SetQuota(req shared.SetQuotaRequest) (*shared.Quota, *shared.ApiError) {
+	serverUrl := getApiHost() + "/quotas"
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error marshalling request: %v", err)}
+	}
+
+	request, err := http.NewRequest(http.MethodPut, serverUrl, bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error creating request: %v", err)}
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := authenticatedFastClient.Do(request)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.SetQuota(req)
+		}
+		return nil, apiErr
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error reading response: %v", err)}
+	}
+
+	if len(body) == 0 {
+		return nil, nil
+	}
+
+	var quota shared.Quota
+	err = json.Unmarshal(body, &quota)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &quota, nil
+}
+
+func (a *Api) ListPlanShares(planId string) ([]*shared.PlanShare, *shared.ApiError) {
+	serverUrl := fmt.Sprintf("%s/plans/%s/shares", getApiHost(), planId)
+	resp, err := authenticatedFastClient.Get(serverUrl)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.ListPlanShares(planId)
+		}
+		return nil, apiErr
+	}
+
+	var shares []*shared.PlanShare
+	err = json.NewDecoder(resp.Body).Decode(&shares)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return shares, nil
+}
+
+func (a *Api) SharePlan(planId string, req shared.SharePlanRequest) *shared.ApiError {
+	serverUrl := fmt.Sprintf("%s/plans/%s/shares", getApiHost(), planId)
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error marshalling request: %v", err)}
+	}
+
+	resp, err := authenticatedFastClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.SharePlan(planId, req)
+		}
+		return apiErr
+	}
+
+	return nil
+}
+
+func (a *Api) DeletePlanShare(planId, shareId string) *shared.ApiError {
+	serverUrl := fmt.Sprintf("%s/plans/%s/shares/%s", getApiHost(), planId, shareId)
+	req, err := http.NewRequest(http.MethodDelete, serverUrl, nil)
+	if err != nil {
+		return &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error creating request: %v", err)}
+	}
+
+	resp, err := authenticatedFastClient.Do(req)
+	if err != nil {
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.DeletePlanShare(planId, shareId)
+		}
+		return apiErr
+	}
+
+	return nil
+}
+
+func (a *Api) CreateEmailVerification(email, customHost, userId string) (*shared.CreateEmailVerificationResponse, *shared.ApiError) {
+	host := customHost
+	if host == "" {
+		host = cloudApiHost
+	}
+	serverUrl := host + "/accounts/email_verifications"
+	req := shared.CreateEmailVerificationRequest{Email: email, UserId: userId}
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error marshalling request: %v", err)}
+	}
+
+	resp, err := unauthenticatedClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		return nil, handleApiError(resp, errorBody)
+	}
+
+	var verificationResponse shared.CreateEmailVerificationResponse
+	err = json.NewDecoder(resp.Body).Decode(&verificationResponse)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &verificationResponse, nil
+}
+
+func (a *Api) GetSsoConfig(customHost string) (*shared.SsoConfigResponse, *shared.ApiError) {
+	host := customHost
+	if host == "" {
+		host = cloudApiHost
+	}
+	serverUrl := host + "/accounts/sso"
+
+	resp, err := unauthenticatedClient.Get(serverUrl)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		return nil, handleApiError(resp, errorBody)
+	}
+
+	var ssoConfig shared.SsoConfigResponse
+	err = json.NewDecoder(resp.Body).Decode(&ssoConfig)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &ssoConfig, nil
+}
+
+func (a *Api) StartSso(customHost string) (*shared.StartSsoResponse, *shared.ApiError) {
+	host := customHost
+	if host == "" {
+		host = cloudApiHost
+	}
+	serverUrl := host + "/accounts/sso/start"
+
+	resp, err := unauthenticatedClient.Post(serverUrl, "application/json", nil)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		return nil, handleApiError(resp, errorBody)
+	}
+
+	var startResponse shared.StartSsoResponse
+	err = json.NewDecoder(resp.Body).Decode(&startResponse)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &startResponse, nil
+}
+
+func (a *Api) PollSso(req shared.PollSsoRequest, customHost string) (*shared.SessionResponse, *shared.ApiError) {
+	host := customHost
+	if host == "" {
+		host = cloudApiHost
+	}
+	serverUrl := host + "/accounts/sso/poll"
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error marshalling request: %v", err)}
+	}
+
+	resp, err := unauthenticatedClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		return nil, handleApiError(resp, errorBody)
+	}
+
+	// the user hasn't finished signing in yet
+	if resp.StatusCode == http.StatusAccepted {
+		return nil, nil
+	}
+
+	var sessionResponse shared.SessionResponse
+	err = json.NewDecoder(resp.Body).Decode(&sessionResponse)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &sessionResponse, nil
+}
+
+func (a *Api) SignOut() *shared.ApiError {
+	serverUrl := getApiHost() + "/accounts/sign_out"
+
+	req, err := http.NewRequest(http.MethodPost, serverUrl, nil)
+	if err != nil {
+		return &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error creating request: %v", err)}
+	}
+
+	resp, err := authenticatedFastClient.Do(req)
+	if err != nil {
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		return handleApiError(resp, errorBody)
+	}
+
+	return nil
+}
+
+func (a *Api) ListUsers() (*shared.ListUsersResponse, *shared.ApiError) {
+	serverUrl := getApiHost() + "/users"
+	resp, err := authenticatedFastClient.Get(serverUrl)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.ListUsers()
+		}
+		return nil, apiErr
+	}
+
+	var r *shared.ListUsersResponse
 	err = json.NewDecoder(resp.Body).Decode(&r)
 	if err != nil {
 		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
@@ -1451,7 +2064,7 @@ func (a *Api) ListBranches(planId string) ([]*shared.Branch, *shared.ApiError) {
 
 	resp, err := authenticatedFastClient.Get(serverUrl)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %s", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %s", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1485,7 +2098,7 @@ func (a *Api) CreateBranch(planId, branch string, req shared.CreateBranchRequest
 
 	resp, err := authenticatedFastClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
 	if err != nil {
-		return &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %s", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %s", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1513,7 +2126,7 @@ func (a *Api) DeleteBranch(planId, branch string) *shared.ApiError {
 
 	resp, err := authenticatedFastClient.Do(req)
 	if err != nil {
-		return &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %s", err)}
+		return &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %s", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1537,7 +2150,7 @@ func (a *Api) GetSettings(planId, branch string) (*shared.PlanSettings, *shared.
 	resp, err := authenticatedFastClient.Get(serverUrl)
 
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %s", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %s", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1578,7 +2191,7 @@ func (a *Api) UpdateSettings(planId, branch string, req shared.UpdateSettingsReq
 
 	resp, err := authenticatedFastClient.Do(request)
 	if err != nil {
-		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %s", err)}
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %s", err)}
 	}
 	defer resp.Body.Close()
 
@@ -1602,3 +2215,69 @@ func (a *Api) UpdateSettings(planId, branch string, req shared.UpdateSettingsReq
 	return &updateRes, nil
 
 }
+
+func (a *Api) GetUsage(planId string, since time.Time) (*shared.UsageResponse, *shared.ApiError) {
+	query := url.Values{}
+	query.Set("since", since.Format(time.RFC3339))
+	if planId != "" {
+		query.Set("planId", planId)
+	}
+	serverUrl := fmt.Sprintf("%s/usage?%s", getApiHost(), query.Encode())
+
+	resp, err := authenticatedFastClient.Get(serverUrl)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.GetUsage(planId, since)
+		}
+		return nil, apiErr
+	}
+
+	var usage shared.UsageResponse
+	err = json.NewDecoder(resp.Body).Decode(&usage)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &usage, nil
+}
+
+func (a *Api) GetOrgUsage(since, until time.Time) (*shared.OrgUsageResponse, *shared.ApiError) {
+	query := url.Values{}
+	query.Set("since", since.Format(time.RFC3339))
+	if !until.IsZero() {
+		query.Set("until", until.Format(time.RFC3339))
+	}
+	serverUrl := fmt.Sprintf("%s/usage/org?%s", getApiHost(), query.Encode())
+
+	resp, err := authenticatedFastClient.Get(serverUrl)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := handleApiError(resp, errorBody)
+		tokenRefreshed, apiErr := refreshTokenIfNeeded(apiErr)
+		if tokenRefreshed {
+			return a.GetOrgUsage(since, until)
+		}
+		return nil, apiErr
+	}
+
+	var usage shared.OrgUsageResponse
+	err = json.NewDecoder(resp.Body).Decode(&usage)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &usage, nil
+}
@@ -4,24 +4,49 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"plandex/types"
+	"time"
 
 	"github.com/plandex/plandex/shared"
 )
 
-func connectPlanRespStream(body io.ReadCloser, onStream types.OnStreamPlan) {
+// a dropped connection is retried this many times, waiting twice as long after each try
+const maxStreamResumeAttempts = 5
+const streamResumeInitialDelay = time.Second
+
+func connectPlanRespStream(planId, branch string, body io.ReadCloser, onStream types.OnStreamPlan) {
 	reader := bufio.NewReader(body)
 
 	go func() {
+		// the seq of the last message received, for resuming after a dropped connection
+		var lastSeq int
+
 		for {
 			s, err := readUntilSeparator(reader, shared.STREAM_MESSAGE_SEPARATOR)
 			if err != nil {
 				log.Println("Error reading line:", err)
-				onStream(types.OnStreamPlanParams{Msg: nil, Err: err})
 				body.Close()
-				return
+
+				if lastSeq == 0 {
+					onStream(types.OnStreamPlanParams{Msg: nil, Err: err})
+					return
+				}
+
+				var apiErr *shared.ApiError
+				body, apiErr = resumePlanStream(planId, branch, lastSeq)
+				if apiErr != nil {
+					onStream(types.OnStreamPlanParams{Msg: &shared.StreamMessage{
+						Type:  shared.StreamMessageError,
+						Error: apiErr,
+					}})
+					return
+				}
+				reader = bufio.NewReader(body)
+				continue
 			}
 
 			var msg shared.StreamMessage
@@ -33,6 +58,13 @@ func connectPlanRespStream(body io.ReadCloser, onStream types.OnStreamPlan) {
 				return
 			}
 
+			if msg.Seq != 0 {
+				if msg.Seq <= lastSeq {
+					continue
+				}
+				lastSeq = msg.Seq
+			}
+
 			// log.Println("Received message:", msg)
 
 			onStream(types.OnStreamPlanParams{Msg: &msg, Err: nil})
@@ -46,6 +78,63 @@ func connectPlanRespStream(body io.ReadCloser, onStream types.OnStreamPlan) {
 	}()
 }
 
+// resumePlanStream reconnects to a plan's stream after the connection dropped, picking up after the last message received.
+// The server keeps the plan running while the client is disconnected, so nothing is lost as long as the plan is still active.
+func resumePlanStream(planId, branch string, lastSeq int) (io.ReadCloser, *shared.ApiError) {
+	serverUrl := fmt.Sprintf("%s/plans/%s/%s/connect?resumeFrom=%d", getApiHost(), planId, branch, lastSeq)
+
+	delay := streamResumeInitialDelay
+	var apiErr *shared.ApiError
+
+	for attempt := 0; attempt < maxStreamResumeAttempts; attempt++ {
+		time.Sleep(delay)
+		delay *= 2
+
+		log.Printf("Resuming plan stream after message %d, attempt %d\n", lastSeq, attempt+1)
+
+		req, err := http.NewRequest(http.MethodPatch, serverUrl, nil)
+		if err != nil {
+			return nil, &shared.ApiError{Msg: fmt.Sprintf("error creating request: %v", err)}
+		}
+
+		resp, err := authenticatedStreamingClient.Do(req)
+		if err != nil {
+			apiErr = &shared.ApiError{Type: shared.ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+			continue
+		}
+
+		if resp.StatusCode >= 400 {
+			errorBody, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			apiErr = handleApiError(resp, errorBody)
+
+			var didRefresh bool
+			didRefresh, apiErr = refreshTokenIfNeeded(apiErr)
+			if didRefresh {
+				continue
+			}
+
+			// the plan isn't active anymore, so it finished or failed while the client was disconnected
+			if resp.StatusCode == http.StatusNotFound {
+				break
+			}
+			continue
+		}
+
+		return resp.Body, nil
+	}
+
+	msg := "lost connection to the plan stream and couldn't reconnect"
+	if apiErr != nil {
+		msg += ": " + apiErr.Msg
+	}
+
+	return nil, &shared.ApiError{
+		Type: shared.ApiErrorTypeNetwork,
+		Msg:  msg + ". Use 'plandex ps' to check on the plan.",
+	}
+}
+
 func readUntilSeparator(reader *bufio.Reader, separator string) (string, error) {
 	var result []byte
 	sepBytes := []byte(separator)
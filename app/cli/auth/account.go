@@ -161,6 +161,16 @@ func promptSignInNewAccount() error {
 			return fmt.Errorf("error prompting host: %v", err)
 		}
 
+		useSso, err := promptUseSso(host)
+
+		if err != nil {
+			return err
+		}
+
+		if useSso {
+			return signInWithSso(host)
+		}
+
 		email, err = term.GetUserStringInput("Your email:")
 
 		if err != nil {
@@ -214,6 +224,10 @@ func signIn(email, pin, host string) error {
 		return fmt.Errorf("error signing in: %v", apiErr.Msg)
 	}
 
+	return setSessionAuth(res, host, false)
+}
+
+func setSessionAuth(res *shared.SessionResponse, host string, isSso bool) error {
 	orgId, orgName, err := resolveOrgAuth(res.Orgs)
 
 	if err != nil {
@@ -229,6 +243,7 @@ func signIn(email, pin, host string) error {
 			IsTrial:  false,
 			IsCloud:  host == "",
 			Host:     host,
+			IsSso:    isSso,
 		},
 		OrgId:   orgId,
 		OrgName: orgName,
@@ -21,6 +21,11 @@ func SetAuthHeader(req *http.Request) error {
 		return fmt.Errorf("error setting auth header: auth not loaded")
 	}
 
+	if UsingApiKey() {
+		req.Header.Set("Authorization", "Bearer "+Current.Token)
+		return nil
+	}
+
 	authHeader := shared.AuthHeader{
 		Token: Current.Token,
 		OrgId: Current.OrgId,
@@ -7,8 +7,17 @@ import (
 	"plandex/fs"
 	"plandex/term"
 	"plandex/types"
+	"strings"
+
+	"github.com/plandex/plandex/shared"
 )
 
+// set to authenticate with an API key instead of a signed in account, for CI jobs and bots
+const apiKeyEnvVar = "PLANDEX_API_KEY"
+
+// a self-hosted server to use with PLANDEX_API_KEY; Plandex Cloud if it isn't set
+const apiHostEnvVar = "PLANDEX_API_HOST"
+
 func MustResolveAuthWithOrg() {
 	MustResolveAuth(true)
 }
@@ -18,15 +27,23 @@ func MustResolveAuth(requireOrg bool) {
 		term.OutputErrorAndExit("error resolving auth: api client not set")
 	}
 
+	if resolveApiKeyAuth() {
+		return
+	}
+
 	// load HomeAuthPath file into ClientAuth struct
 	bytes, err := os.ReadFile(fs.HomeAuthPath)
 
 	if err != nil {
 		if os.IsNotExist(err) {
+			if term.NonInteractive {
+				term.OutputErrorAndExitWithCode(term.ExitAuth, "Not signed in. Run 'plandex sign-in' first.")
+			}
+
 			err = promptInitialAuth()
 
 			if err != nil {
-				term.OutputErrorAndExit("error resolving auth: %v", err)
+				term.OutputErrorAndExitWithCode(term.ExitAuth, "error resolving auth: %v", err)
 			}
 
 			return
@@ -49,18 +66,18 @@ func MustResolveAuth(requireOrg bool) {
 		term.StopSpinner()
 
 		if apiErr != nil {
-			term.OutputErrorAndExit("Error listing orgs: %v", apiErr.Msg)
+			term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error listing orgs: %v", apiErr.Msg)
 		}
 
 		orgId, orgName, err := resolveOrgAuth(orgs)
 
 		if err != nil {
-			term.OutputErrorAndExit("Error resolving org: %v", err)
+			term.OutputErrorAndExitWithCode(term.ExitAuth, "Error resolving org: %v", err)
 		}
 
 		if orgId == "" {
 			// still no org--exit now
-			term.OutputErrorAndExit("No org")
+			term.OutputErrorAndExitWithCode(term.ExitAuth, "No org")
 		}
 
 		Current.OrgId = orgId
@@ -75,11 +92,45 @@ func MustResolveAuth(requireOrg bool) {
 
 }
 
+// LoadAuth loads the current auth without prompting to sign in or choose an org, for uses like shell completion where a prompt isn't possible
+func LoadAuth() error {
+	if resolveApiKeyAuth() {
+		return nil
+	}
+
+	bytes, err := os.ReadFile(fs.HomeAuthPath)
+	if err != nil {
+		return err
+	}
+
+	var auth types.ClientAuth
+	err = json.Unmarshal(bytes, &auth)
+	if err != nil {
+		return err
+	}
+
+	if auth.OrgId == "" {
+		return fmt.Errorf("no org")
+	}
+
+	Current = &auth
+
+	return nil
+}
+
 func RefreshInvalidToken() error {
 	if Current == nil {
 		return fmt.Errorf("error refreshing token: auth not loaded")
 	}
 
+	if UsingApiKey() {
+		return fmt.Errorf("%s is invalid, expired, or revoked", apiKeyEnvVar)
+	}
+
+	if Current.IsSso {
+		return signInWithSso(Current.Host)
+	}
+
 	hasAccount, pin, err := verifyEmail(Current.Email, Current.Host)
 
 	if err != nil {
@@ -94,8 +145,37 @@ func RefreshInvalidToken() error {
 			host = "Plandex Cloud"
 		}
 
-		term.OutputErrorAndExit("Account %s not found on %s", Current.Email, host)
+		term.OutputErrorAndExitWithCode(term.ExitAuth, "Account %s not found on %s", Current.Email, host)
 	}
 
 	return nil
 }
+
+// resolveApiKeyAuth uses the API key from PLANDEX_API_KEY, if it's set, without reading or writing auth.json. The key
+// belongs to an org, so there's no org to choose.
+func resolveApiKeyAuth() bool {
+	key := os.Getenv(apiKeyEnvVar)
+	if key == "" {
+		return false
+	}
+
+	if !strings.HasPrefix(key, shared.ApiKeyPrefix) {
+		term.OutputErrorAndExitWithCode(term.ExitAuth, "%s should be an API key starting with '%s'. Create one with 'plandex api-keys create'.", apiKeyEnvVar, shared.ApiKeyPrefix)
+	}
+
+	host := strings.TrimSuffix(os.Getenv(apiHostEnvVar), "/")
+
+	Current = &types.ClientAuth{
+		ClientAccount: types.ClientAccount{
+			IsCloud: host == "",
+			Host:    host,
+			Token:   key,
+		},
+	}
+
+	return true
+}
+
+func UsingApiKey() bool {
+	return Current != nil && strings.HasPrefix(Current.Token, shared.ApiKeyPrefix)
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"fmt"
+	"os/exec"
+	"plandex/term"
+	"runtime"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/plandex/plandex/shared"
+)
+
+const (
+	SignInSsoOption   = "Sign in with SSO"
+	SignInEmailOption = "Sign in with an email pin"
+)
+
+const ssoPollInterval = 2 * time.Second
+
+// the server keeps a sign-in for 10 minutes
+const ssoTimeout = 10 * time.Minute
+
+// promptUseSso checks whether a host has SSO, and if it does, asks whether to use it, unless the host requires it
+func promptUseSso(host string) (bool, error) {
+	term.StartSpinner("")
+	ssoConfig, apiErr := apiClient.GetSsoConfig(host)
+	term.StopSpinner()
+
+	// older servers don't have SSO
+	if apiErr != nil || !ssoConfig.Enabled {
+		return false, nil
+	}
+
+	if ssoConfig.Required {
+		return true, nil
+	}
+
+	selected, err := term.SelectFromList("How do you want to sign in?", []string{SignInSsoOption, SignInEmailOption})
+
+	if err != nil {
+		return false, fmt.Errorf("error selecting sign in option: %v", err)
+	}
+
+	return selected == SignInSsoOption, nil
+}
+
+func signInWithSso(host string) error {
+	term.StartSpinner("")
+	startRes, apiErr := apiClient.StartSso(host)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		return fmt.Errorf("error starting SSO sign-in: %v", apiErr.Msg)
+	}
+
+	fmt.Println("🔑 Sign in with your identity provider in the browser. If it doesn't open, go to:")
+	fmt.Println()
+	fmt.Println(startRes.AuthUrl)
+	fmt.Println()
+	fmt.Printf("🔢 When you're asked for a code in the browser, enter %s\n", color.New(color.Bold, term.ColorHiCyan).Sprint(startRes.UserCode))
+	fmt.Println()
+
+	openBrowser(startRes.AuthUrl)
+
+	term.StartSpinner("Waiting for you to sign in...")
+
+	deadline := time.Now().Add(ssoTimeout)
+	var res *shared.SessionResponse
+	for {
+		res, apiErr = apiClient.PollSso(shared.PollSsoRequest{
+			LoginId:   startRes.LoginId,
+			PollToken: startRes.PollToken,
+		}, host)
+
+		if apiErr != nil {
+			term.StopSpinner()
+			return fmt.Errorf("error signing in with SSO: %v", apiErr.Msg)
+		}
+
+		if res != nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			term.StopSpinner()
+			return fmt.Errorf("timed out waiting for SSO sign-in")
+		}
+
+		time.Sleep(ssoPollInterval)
+	}
+
+	term.StopSpinner()
+
+	return setSessionAuth(res, host, true)
+}
+
+func openBrowser(url string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+
+	// the url is printed too, so it's fine if this fails, e.g. over ssh
+	cmd.Start()
+}
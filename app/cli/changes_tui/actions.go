@@ -3,16 +3,21 @@ package changes_tui
 import (
 	"fmt"
 	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"plandex/api"
 	"plandex/lib"
+	"strings"
 
 	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/muesli/reflow/wrap"
 	"github.com/plandex/plandex/shared"
 )
 
 func (m *changesUIModel) rejectFile() (*shared.CurrentPlanState, *shared.ApiError) {
-	err := api.Client.RejectFile(lib.CurrentPlanId, lib.CurrentBranch, m.selectionInfo.currentPath)
+	err := api.Client.RejectFile(lib.CurrentPlanId, lib.CurrentBranch, m.selectionInfo.currentPath, strings.TrimSpace(m.rejectComment.Value()))
 
 	if err != nil {
 		log.Printf("error rejecting file changes: %v", err)
@@ -29,6 +34,87 @@ func (m *changesUIModel) rejectFile() (*shared.CurrentPlanState, *shared.ApiErro
 	return planState, nil
 }
 
+func (m *changesUIModel) editFile(path, content string) (*shared.CurrentPlanState, *shared.ApiError) {
+	err := api.Client.EditFile(lib.CurrentPlanId, lib.CurrentBranch, path, content)
+
+	if err != nil {
+		log.Printf("error editing file changes: %v", err)
+		return nil, err
+	}
+
+	planState, err := api.Client.GetCurrentPlanState(lib.CurrentPlanId, lib.CurrentBranch)
+
+	if err != nil {
+		log.Printf("error getting current plan state: %v", err)
+		return nil, err
+	}
+
+	return planState, nil
+}
+
+// openEditor writes the plan's version of the file to a temp file and opens it in the user's editor, suspending the ui
+// until the editor is closed
+func (m *changesUIModel) openEditor() tea.Cmd {
+	path := m.selectionInfo.currentPath
+	original := m.currentPlan.CurrentPlanFiles.Files[path]
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "plandex_edit_*"+filepath.Ext(path))
+	if err != nil {
+		log.Printf("error creating temp file: %v", err)
+		return nil
+	}
+	filename := tempFile.Name()
+	tempFile.Close()
+
+	err = os.WriteFile(filename, []byte(original), 0644)
+	if err != nil {
+		log.Printf("error writing temp file: %v", err)
+		os.Remove(filename)
+		return nil
+	}
+
+	cmd := exec.Command(lib.GetEditor(), filename)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		defer os.Remove(filename)
+
+		if err != nil {
+			log.Printf("error running editor: %v", err)
+			return editorClosedMsg{}
+		}
+
+		bytes, err := os.ReadFile(filename)
+		if err != nil {
+			log.Printf("error reading edited file: %v", err)
+			return editorClosedMsg{}
+		}
+
+		return editorClosedMsg{path: path, content: string(bytes), changed: string(bytes) != original}
+	})
+}
+
+func (m *changesUIModel) toggleAccepted() {
+	path := m.selectionInfo.currentPath
+	if m.acceptedPaths[path] {
+		delete(m.acceptedPaths, path)
+	} else {
+		m.acceptedPaths[path] = true
+	}
+}
+
+// selectPath selects the file at path if it still has pending changes, or else the first file
+func (m *changesUIModel) selectPath(path string) {
+	m.selectedFileIndex = 0
+	for i, p := range m.currentPlan.PlanResult.SortedPaths {
+		if p == path {
+			m.selectedFileIndex = i
+			break
+		}
+	}
+	m.selectedReplacementIndex = 0
+	m.setSelectionInfo()
+	m.updateMainView(true)
+}
+
 func (m *changesUIModel) copyCurrentChange() error {
 	selectionInfo := m.selectionInfo
 	if selectionInfo.currentRep == nil {
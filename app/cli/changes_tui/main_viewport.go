@@ -123,7 +123,7 @@ func (m changesUIModel) renderMainViewFooter() string {
 	if m.didCopy {
 		footer = color.New(color.Bold, term.ColorHiCyan).Sprint(` copied to clipboard`)
 	} else {
-		footer = ` (c)opy change to clipboard • (r)eject file`
+		footer = ` (c)opy change to clipboard`
 	}
 	return style.Render(footer)
 }
@@ -142,7 +142,7 @@ func (m changesUIModel) renderScrollFooter() string {
 	var footer string
 
 	if m.selectedNewFile() || m.selectedFullFile() {
-		footer = ` (j/k) scroll • (d/u) page • (g/G) start/end • (r)eject file`
+		footer = ` (j/k) scroll • (d/u) page • (g/G) start/end`
 	} else {
 		footer = ` (j/k) scroll`
 		if m.oldScrollable() && m.newScrollable() {
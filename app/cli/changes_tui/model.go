@@ -4,6 +4,7 @@ import (
 	"github.com/charmbracelet/bubbles/help"
 	bubbleKey "github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -31,7 +32,12 @@ type changesUIModel struct {
 	isConfirmingRejectFile   bool
 	rejectFileErr            *shared.ApiError
 	justRejectedFile         bool
-	spinner                  spinner.Model
+	rejectComment            textinput.Model
+	isSavingEdit             bool
+	editFileErr              *shared.ApiError
+	// files accepted during review, which are applied when the ui is closed
+	acceptedPaths map[string]bool
+	spinner       spinner.Model
 }
 
 type keymap = struct {
@@ -46,6 +52,8 @@ type keymap = struct {
 	start,
 	end,
 	switchView,
+	accept,
+	edit,
 	reject,
 	copy,
 	applyAll,
@@ -63,12 +71,18 @@ func initialModel(currentPlan *shared.CurrentPlanState) *changesUIModel {
 	s.Spinner = spinner.Points
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
 
+	comment := textinput.New()
+	comment.Placeholder = "What's wrong with these changes? (optional)"
+	comment.CharLimit = 1000
+
 	initialState := changesUIModel{
 		currentPlan:              currentPlan,
 		selectedFileIndex:        0,
 		selectedReplacementIndex: 0,
 		help:                     help.New(),
 		spinner:                  s,
+		rejectComment:            comment,
+		acceptedPaths:            map[string]bool{},
 		keymap: keymap{
 			up: bubbleKey.NewBinding(
 				bubbleKey.WithKeys("up"),
@@ -122,6 +136,16 @@ func initialModel(currentPlan *shared.CurrentPlanState) *changesUIModel {
 				bubbleKey.WithHelp("tab", "switch view"),
 			),
 
+			accept: bubbleKey.NewBinding(
+				bubbleKey.WithKeys("a"),
+				bubbleKey.WithHelp("a", "accept file"),
+			),
+
+			edit: bubbleKey.NewBinding(
+				bubbleKey.WithKeys("e"),
+				bubbleKey.WithHelp("e", "edit file"),
+			),
+
 			reject: bubbleKey.NewBinding(
 				bubbleKey.WithKeys("r"),
 				bubbleKey.WithHelp("r", "reject file"),
@@ -138,13 +162,13 @@ func initialModel(currentPlan *shared.CurrentPlanState) *changesUIModel {
 			),
 
 			yes: bubbleKey.NewBinding(
-				bubbleKey.WithKeys("y"),
-				bubbleKey.WithHelp("y", "yes"),
+				bubbleKey.WithKeys("enter"),
+				bubbleKey.WithHelp("enter", "yes"),
 			),
 
 			no: bubbleKey.NewBinding(
-				bubbleKey.WithKeys("n"),
-				bubbleKey.WithHelp("n", "no"),
+				bubbleKey.WithKeys("esc"),
+				bubbleKey.WithHelp("esc", "no"),
 			),
 
 			quit: bubbleKey.NewBinding(
@@ -22,12 +22,17 @@ func (m changesUIModel) renderPathTabs() string {
 
 	for i, path := range paths {
 		selected := i == m.selectedFileIndex
+		accepted := m.acceptedPaths[path]
 
 		if len(path) > 40 {
 			path = path[:20] + "⋯" + path[len(path)-20:]
 		}
 
-		tab := " 📄 " + path + "  "
+		icon := "📄"
+		if accepted {
+			icon = "✅"
+		}
+		tab := " " + icon + " " + path + "  "
 
 		pathColor := term.ColorHiGreen
 		bgColor := color.BgGreen
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"plandex/lib"
 	"plandex/term"
+	"sort"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/plandex/plandex/shared"
@@ -37,13 +38,25 @@ func StartChangesUI(currentPlan *shared.CurrentPlanState) error {
 		mod = &c
 	}
 
-	if mod.shouldApplyAll {
-		lib.MustApplyPlan(lib.CurrentPlanId, lib.CurrentBranch, false)
+	if mod.rejectFileErr != nil {
+		fmt.Println()
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(mod.rejectFileErr), "Server error: %s", mod.rejectFileErr.Msg)
 	}
 
-	if mod.rejectFileErr != nil {
+	if mod.editFileErr != nil {
 		fmt.Println()
-		term.OutputErrorAndExit("Server error: " + mod.rejectFileErr.Msg)
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(mod.editFileErr), "Server error: %s", mod.editFileErr.Msg)
+	}
+
+	if mod.shouldApplyAll {
+		lib.MustApplyPlan(lib.CurrentPlanId, lib.CurrentBranch, lib.ApplyOpts{})
+	} else if len(mod.acceptedPaths) > 0 {
+		var paths []string
+		for path := range mod.acceptedPaths {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		lib.MustApplyPlan(lib.CurrentPlanId, lib.CurrentBranch, lib.ApplyOpts{Paths: paths})
 	}
 
 	if mod.justRejectedFile && len(mod.currentPlan.PlanResult.SortedPaths) == 0 {
@@ -15,6 +15,16 @@ type finishedRejectFile struct {
 	planState *shared.CurrentPlanState
 	err       *shared.ApiError
 }
+type editorClosedMsg struct {
+	path    string
+	content string
+	changed bool
+}
+type finishedEditFile struct {
+	path      string
+	planState *shared.CurrentPlanState
+	err       *shared.ApiError
+}
 
 func (m changesUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// log.Println("msg:", msg)
@@ -35,7 +45,7 @@ func (m changesUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case spinner.TickMsg:
-		if m.isRejectingFile {
+		if m.isRejectingFile || m.isSavingEdit {
 			spinnerModel, cmd := m.spinner.Update(msg)
 			m.spinner = spinnerModel
 			return m, cmd
@@ -52,6 +62,7 @@ func (m changesUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		}
 
+		delete(m.acceptedPaths, m.selectionInfo.currentPath)
 		m.currentPlan = msg.planState
 
 		if len(msg.planState.PlanResult.SortedPaths) == 0 {
@@ -65,15 +76,48 @@ func (m changesUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.setSelectionInfo()
 		m.updateMainView(true)
 
+	case editorClosedMsg:
+		if !msg.changed {
+			return m, nil
+		}
+
+		m.isSavingEdit = true
+		started := time.Now()
+		go func() {
+			planState, err := m.editFile(msg.path, msg.content)
+
+			elapsed := time.Since(started)
+			if elapsed < 500*time.Millisecond {
+				time.Sleep((500 * time.Millisecond) - elapsed)
+			}
+
+			program.Send(finishedEditFile{path: msg.path, planState: planState, err: err})
+		}()
+		return m, m.spinner.Tick
+
+	case finishedEditFile:
+		m.isSavingEdit = false
+
+		if msg.err != nil {
+			m.editFileErr = msg.err
+			return m, tea.Quit
+		}
+
+		m.currentPlan = msg.planState
+		m.selectPath(msg.path)
+
 	case tea.KeyMsg:
 		if m.isConfirmingRejectFile {
+			// anything besides confirming or cancelling is typed into the comment
 			if !bubbleKey.Matches(msg, m.keymap.yes) && !bubbleKey.Matches(msg, m.keymap.no) &&
-				!bubbleKey.Matches(msg, m.keymap.quit) {
-				return m, nil
+				msg.String() != "ctrl+c" {
+				var cmd tea.Cmd
+				m.rejectComment, cmd = m.rejectComment.Update(msg)
+				return m, cmd
 			}
 		}
 
-		if m.isRejectingFile {
+		if m.isRejectingFile || m.isSavingEdit {
 			if !bubbleKey.Matches(msg, m.keymap.quit) {
 				return m, nil
 			}
@@ -114,10 +158,18 @@ func (m changesUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case bubbleKey.Matches(msg, m.keymap.switchView):
 			m.switchView()
 
+		case bubbleKey.Matches(msg, m.keymap.accept):
+			m.toggleAccepted()
+
+		case bubbleKey.Matches(msg, m.keymap.edit):
+			return m, m.openEditor()
+
 		case bubbleKey.Matches(msg, m.keymap.reject):
 			m.isConfirmingRejectFile = true
+			m.rejectComment.Reset()
+			return m, m.rejectComment.Focus()
 
-		case bubbleKey.Matches(msg, m.keymap.yes):
+		case m.isConfirmingRejectFile && bubbleKey.Matches(msg, m.keymap.yes):
 			m.isRejectingFile = true
 			m.isConfirmingRejectFile = false
 			started := time.Now()
@@ -139,8 +191,9 @@ func (m changesUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}()
 			return m, m.spinner.Tick
 
-		case bubbleKey.Matches(msg, m.keymap.no):
+		case m.isConfirmingRejectFile && bubbleKey.Matches(msg, m.keymap.no):
 			m.isConfirmingRejectFile = false
+			m.rejectComment.Blur()
 
 		case bubbleKey.Matches(msg, m.keymap.applyAll):
 			m.shouldApplyAll = true
@@ -159,6 +212,14 @@ func (m changesUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// handle escape sequences sometimes sent by arrow keys
 			m.resolveEscapeSequence(msg.String())
 		}
+
+	default:
+		// keeps the comment box's cursor blinking
+		if m.isConfirmingRejectFile {
+			var cmd tea.Cmd
+			m.rejectComment, cmd = m.rejectComment.Update(msg)
+			return m, cmd
+		}
 	}
 
 	return m, nil
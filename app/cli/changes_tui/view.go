@@ -21,7 +21,7 @@ func (m changesUIModel) View() string {
 		return m.renderConfirmRejectFile()
 	}
 
-	if m.isRejectingFile {
+	if m.isRejectingFile || m.isSavingEdit {
 		return m.renderIsRejectingFile()
 	}
 
@@ -145,7 +145,13 @@ func (m changesUIModel) renderHelp() string {
 		help += "(↑/↓) select change • "
 	}
 
-	help += "(ctrl+a) apply all changes • (q)uit"
+	help += "(a)ccept • (e)dit • (r)eject file • (ctrl+a) apply all changes • "
+
+	if len(m.acceptedPaths) > 0 {
+		help += "(q)uit and apply accepted"
+	} else {
+		help += "(q)uit"
+	}
 	style := lipgloss.NewStyle().Width(m.width).Inherit(topBorderStyle).Foreground(lipgloss.Color(helpTextColor))
 	return style.Render(help)
 }
@@ -221,7 +227,9 @@ func (m changesUIModel) renderConfirmRejectFile() string {
 
 	prompt := color.New(color.Bold).Sprintf("🧐 Are you sure you want to reject changes to ") +
 		color.New(color.Bold, term.ColorHiMagenta).Sprint(m.selectionInfo.currentPath) + "?\n\n" +
-		color.New(term.ColorHiCyan, color.Bold).Sprintf("(y)es | (n)o")
+		"Say why and it's passed to the model with your next prompt:\n" +
+		m.rejectComment.View() + "\n\n" +
+		color.New(term.ColorHiCyan, color.Bold).Sprintf("(enter) reject | (esc) cancel")
 
 	return style.Render(prompt)
 }
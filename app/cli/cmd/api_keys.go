@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/format"
+	"plandex/term"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var apiKeyScope string
+var apiKeyExpiresInDays int
+
+var apiKeysCmd = &cobra.Command{
+	Use:   "api-keys",
+	Short: "List API keys for CI jobs and bots",
+	Args:  cobra.NoArgs,
+	Run:   listApiKeys,
+}
+
+var apiKeysCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an API key. Use it by setting PLANDEX_API_KEY",
+	Args:  cobra.ExactArgs(1),
+	Run:   createApiKey,
+}
+
+var apiKeysRevokeCmd = &cobra.Command{
+	Use:   "revoke [name-or-id]",
+	Short: "Revoke an API key",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   revokeApiKey,
+}
+
+func init() {
+	RootCmd.AddCommand(apiKeysCmd)
+	apiKeysCmd.AddCommand(apiKeysCreateCmd)
+	apiKeysCmd.AddCommand(apiKeysRevokeCmd)
+
+	apiKeysCreateCmd.Flags().StringVar(&apiKeyScope, "scope", string(shared.ApiKeyScopeRead), "What the key can do: 'read' (read-only), 'write' (everything but applying changes), or 'apply'")
+	apiKeysCreateCmd.Flags().IntVar(&apiKeyExpiresInDays, "expires", 90, "Days until the key expires, or 0 for a key that doesn't expire")
+}
+
+func listApiKeys(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+
+	term.StartSpinner("")
+	apiKeys, apiErr := api.Client.ListApiKeys()
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error listing API keys: %v", apiErr.Msg)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(apiKeys)
+		return
+	}
+
+	if len(apiKeys) == 0 {
+		fmt.Println("🤷‍♂️ No API keys")
+		fmt.Println()
+		term.PrintCmds("", "api-keys create")
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Name", "Key", "Scope", "Created", "Last Used", "Expires"})
+
+	for _, apiKey := range apiKeys {
+		lastUsed := "never"
+		if apiKey.LastUsedAt != nil {
+			lastUsed = format.Time(*apiKey.LastUsedAt)
+		}
+
+		expires := "never"
+		if apiKey.ExpiresAt != nil {
+			expires = format.Time(*apiKey.ExpiresAt)
+			if apiKey.ExpiresAt.Before(time.Now()) {
+				expires = color.New(color.FgHiRed).Sprint("expired " + expires)
+			}
+		}
+
+		table.Append([]string{apiKey.Name, apiKey.KeyPrefix + "…", string(apiKey.Scope), format.Time(apiKey.CreatedAt), lastUsed, expires})
+	}
+
+	table.Render()
+
+	fmt.Println()
+	term.PrintCmds("", "api-keys create", "api-keys revoke")
+}
+
+func createApiKey(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+
+	scope := shared.ApiKeyScope(apiKeyScope)
+	if !scope.Valid() {
+		scopes := []string{}
+		for _, s := range shared.ApiKeyScopes {
+			scopes = append(scopes, string(s))
+		}
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "Invalid scope '%s'. Use one of: %s", apiKeyScope, strings.Join(scopes, ", "))
+	}
+
+	term.StartSpinner("")
+	res, apiErr := api.Client.CreateApiKey(shared.CreateApiKeyRequest{
+		Name:          args[0],
+		Scope:         scope,
+		ExpiresInDays: apiKeyExpiresInDays,
+	})
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error creating API key: %v", apiErr.Msg)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(res)
+		return
+	}
+
+	expires := "doesn't expire"
+	if res.ApiKey.ExpiresAt != nil {
+		expires = "expires " + res.ApiKey.ExpiresAt.Local().Format("Jan 2, 2006")
+	}
+
+	fmt.Printf("✅ Created API key %s with %s scope (%s)\n", color.New(color.Bold, term.ColorHiCyan).Sprint(res.ApiKey.Name), res.ApiKey.Scope, expires)
+	fmt.Println()
+	fmt.Println(res.Key)
+	fmt.Println()
+	fmt.Println("Copy it now. It can't be shown again. To use it, set PLANDEX_API_KEY, and PLANDEX_API_HOST if you're self-hosting.")
+	fmt.Println()
+	term.PrintCmds("", "api-keys", "api-keys revoke")
+}
+
+func revokeApiKey(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+
+	var nameOrId string
+	if len(args) > 0 {
+		nameOrId = args[0]
+	} else {
+		term.StartSpinner("")
+		apiKeys, apiErr := api.Client.ListApiKeys()
+		term.StopSpinner()
+
+		if apiErr != nil {
+			term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error listing API keys: %v", apiErr.Msg)
+		}
+
+		if len(apiKeys) == 0 {
+			fmt.Println("🤷‍♂️ No API keys")
+			return
+		}
+
+		if term.NonInteractive {
+			term.OutputErrorAndExitWithCode(term.ExitUsage, "Specify the API key to revoke by name or id")
+		}
+
+		names := []string{}
+		for _, apiKey := range apiKeys {
+			names = append(names, apiKey.Name)
+		}
+
+		selected, err := term.SelectFromList("Select an API key to revoke:", names)
+		if err != nil {
+			term.OutputErrorAndExit("Error selecting API key: %v", err)
+		}
+		nameOrId = selected
+	}
+
+	term.StartSpinner("")
+	apiErr := api.Client.RevokeApiKey(nameOrId)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error revoking API key: %v", apiErr.Msg)
+	}
+
+	fmt.Println("✅ API key revoked")
+}
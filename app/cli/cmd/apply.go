@@ -2,16 +2,43 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"plandex/auth"
 	"plandex/lib"
+	"plandex/term"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
 var autoConfirm bool
+var applyDryRun bool
+var applyFiles []string
+var applyInteractive bool
+var applyUndo bool
+var applyNoHooks bool
+var applyCommit bool
+var applyCommitMsg string
+var applyEditMsg bool
+var applyNoVerify bool
+var applyGitBranch bool
+var applyGitBranchName string
+var applyForce bool
 
 func init() {
 	applyCmd.Flags().BoolVarP(&autoConfirm, "yes", "y", false, "Automatically confirm unless plan is outdated")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Show the changes as a unified diff without writing any files")
+	applyCmd.Flags().StringSliceVar(&applyFiles, "files", nil, "Only apply changes to these files (comma-separated); the rest stay pending")
+	applyCmd.Flags().BoolVarP(&applyInteractive, "interactive", "i", false, "Choose which changes to apply hunk by hunk; rejected changes stay pending")
+	applyCmd.Flags().BoolVar(&applyNoHooks, "no-hooks", false, "Don't run post-apply hooks from config")
+	applyCmd.Flags().BoolVarP(&applyCommit, "commit", "c", false, "Commit the applied files with a generated conventional commit message, without asking")
+	applyCmd.Flags().StringVarP(&applyCommitMsg, "message", "m", "", "Commit message to use instead of the generated one (implies --commit)")
+	applyCmd.Flags().BoolVarP(&applyEditMsg, "edit-message", "e", false, "Edit the commit message in git's editor before committing (implies --commit)")
+	applyCmd.Flags().BoolVar(&applyNoVerify, "no-verify", false, "Skip git's pre-commit and commit-msg hooks when committing")
+	applyCmd.Flags().BoolVar(&applyGitBranch, "branch", false, "Apply and commit on a git branch named after the plan, created if needed, leaving the current branch untouched")
+	applyCmd.Flags().StringVar(&applyGitBranchName, "branch-name", "", "Name of the git branch to apply on (implies --branch)")
+	applyCmd.Flags().BoolVar(&applyForce, "force", false, "Apply even if files with changes have uncommitted local modifications")
+	applyCmd.Flags().BoolVar(&applyUndo, "undo", false, "Roll back the latest apply (same as 'plandex rollback 1')")
 
 	RootCmd.AddCommand(applyCmd)
 }
@@ -27,10 +54,82 @@ func apply(cmd *cobra.Command, args []string) {
 	auth.MustResolveAuthWithOrg()
 	lib.MustResolveProject()
 
+	if applyUndo {
+		txs, err := lib.ListApplyTransactions()
+		if err != nil {
+			term.OutputErrorAndExit("Error listing applies: %v", err)
+		}
+		if len(txs) == 0 {
+			fmt.Println("🤷‍♂️ No applies to roll back")
+			return
+		}
+		mustRollbackApplies(txs[:1], autoConfirm)
+		return
+	}
+
 	if lib.CurrentPlanId == "" {
 		fmt.Println("🤷‍♂️ No current plan")
 		return
 	}
 
-	lib.MustApplyPlan(lib.CurrentPlanId, lib.CurrentBranch, autoConfirm)
+	if applyDryRun {
+		previewApply()
+		return
+	}
+
+	if !cmd.Flags().Changed("yes") {
+		config := lib.MustLoadConfig()
+		if config.AutoApply != nil {
+			autoConfirm = *config.AutoApply
+		}
+	}
+
+	lib.MustApplyPlan(lib.CurrentPlanId, lib.CurrentBranch, lib.ApplyOpts{
+		AutoConfirm:   autoConfirm,
+		Paths:         applyFiles,
+		Interactive:   applyInteractive,
+		NoHooks:       applyNoHooks,
+		Commit:        applyCommit || applyCommitMsg != "" || applyEditMsg || applyGitBranch || applyGitBranchName != "",
+		CommitMessage: applyCommitMsg,
+		EditMessage:   applyEditMsg,
+		NoVerify:      applyNoVerify,
+		GitBranch:     applyGitBranch || applyGitBranchName != "",
+		GitBranchName: applyGitBranchName,
+		Force:         applyForce,
+	})
+}
+
+func previewApply() {
+	patches, hasPendingBuilds := lib.MustGetApplyPatches(lib.CurrentPlanId, lib.CurrentBranch)
+
+	if hasPendingBuilds {
+		fmt.Fprintln(os.Stderr, color.New(color.Bold, term.ColorHiYellow).Sprint(term.Plain("⚠️  This plan has changes that haven't been built yet, so they aren't included")))
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if term.JsonOutput {
+		if patches == nil {
+			patches = []*lib.ApplyPatch{}
+		}
+		term.OutputJson(patches)
+		return
+	}
+
+	if len(patches) == 0 {
+		fmt.Println("🤷‍♂️ No changes to apply")
+		return
+	}
+
+	for _, patch := range patches {
+		fmt.Print(lib.ColorizeDiff(patch.Patch))
+	}
+
+	suffix := ""
+	if len(patches) > 1 {
+		suffix = "s"
+	}
+	fmt.Println()
+	fmt.Printf("👀 Applying would update %d file%s. Nothing was written.\n", len(patches), suffix)
+	fmt.Println()
+	term.PrintCmds("", "apply")
 }
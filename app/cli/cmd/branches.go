@@ -83,7 +83,7 @@ func branches(cmd *cobra.Command, args []string) {
 			}
 		}
 
-		table.Rich(row, style)
+		table.Rich(row, term.TableColors(style))
 
 	}
 	table.Render()
@@ -6,6 +6,7 @@ import (
 	"plandex/auth"
 	"plandex/lib"
 	"plandex/plan_exec"
+	streamtui "plandex/stream_tui"
 	"plandex/term"
 
 	"github.com/plandex/plandex/shared"
@@ -26,9 +27,12 @@ var buildCmd = &cobra.Command{
 func init() {
 	RootCmd.AddCommand(buildCmd)
 	buildCmd.Flags().BoolVar(&buildBg, "bg", false, "Execute autonomously in the background")
+	buildCmd.Flags().BoolVar(&streamtui.PlainOutput, "plain", false, "Stream the reply as plain text instead of in the stream UI (the default when output isn't a terminal)")
 }
 
 func build(cmd *cobra.Command, args []string) {
+	buildBg = term.RunInBackground(buildBg)
+
 	if os.Getenv("OPENAI_API_KEY") == "" {
 		term.OutputNoApiKeyMsgAndExit()
 	}
@@ -41,6 +45,8 @@ func build(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	lib.MustCheckBudget()
+
 	didBuild, err := plan_exec.Build(plan_exec.ExecParams{
 		CurrentPlanId: lib.CurrentPlanId,
 		CurrentBranch: lib.CurrentBranch,
@@ -34,17 +34,23 @@ func changes(cmd *cobra.Command, args []string) {
 
 	if apiErr != nil {
 		term.StopSpinner()
-		term.OutputErrorAndExit("Error getting current plan state: %s", apiErr.Msg)
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting current plan state: %s", apiErr.Msg)
 	}
 
 	// log.Println(spew.Sdump(currentPlanState))
 
+	// pending builds aren't built in JSON mode since that needs confirmation, so the state is output as-is
+	if term.JsonOutput {
+		term.OutputJson(currentPlanState)
+		return
+	}
+
 	for currentPlanState.HasPendingBuilds() {
 		plansRunningRes, apiErr := api.Client.ListPlansRunning([]string{lib.CurrentProjectId}, false)
 
 		if apiErr != nil {
 			term.StopSpinner()
-			term.OutputErrorAndExit("Error getting running plans: %s", apiErr.Msg)
+			term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting running plans: %s", apiErr.Msg)
 		}
 
 		viewIncomplete := false
@@ -99,7 +105,7 @@ func changes(cmd *cobra.Command, args []string) {
 
 			if apiErr != nil {
 				term.StopSpinner()
-				term.OutputErrorAndExit("Error getting current plan state: %s", apiErr.Msg)
+				term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting current plan state: %s", apiErr.Msg)
 			}
 		}
 	}
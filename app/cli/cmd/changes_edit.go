@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/lib"
+	"plandex/term"
+
+	"github.com/spf13/cobra"
+)
+
+var changesEditCmd = &cobra.Command{
+	Use:   "edit <file>",
+	Short: "Edit a file's pending changes in your editor",
+	Long: `Open the plan's version of a file with pending changes in your editor. When the editor is closed, your edits replace the file's pending changes, so small fixes don't need another prompt.
+
+The editor is set with 'plandex config set editor', or else $EDITOR or $VISUAL.`,
+	Args: cobra.ExactArgs(1),
+	Run:  editChanges,
+}
+
+func init() {
+	changesCmd.AddCommand(changesEditCmd)
+}
+
+func editChanges(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	term.StartSpinner("")
+	currentPlanState, apiErr := api.Client.GetCurrentPlanState(lib.CurrentPlanId, lib.CurrentBranch)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting current plan state: %s", apiErr.Msg)
+	}
+
+	pending := map[string]string{}
+	for _, path := range currentPlanState.PlanResult.SortedPaths {
+		pending[path] = currentPlanState.CurrentPlanFiles.Files[path]
+	}
+
+	path, err := lib.ResolvePendingPath(args[0], pending)
+	if err != nil {
+		term.OutputErrorAndExit("%v", err)
+	}
+
+	edited, err := lib.EditInEditor(pending[path], path)
+	if err != nil {
+		term.OutputErrorAndExit("Error editing file: %v", err)
+	}
+
+	if edited == pending[path] {
+		fmt.Printf("🤷‍♂️ No edits to %s\n", path)
+		return
+	}
+
+	term.StartSpinner("")
+	apiErr = api.Client.EditFile(lib.CurrentPlanId, lib.CurrentBranch, path, edited)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error saving edits: %s", apiErr.Msg)
+	}
+
+	fmt.Printf("✏️  Updated pending changes to %s\n", path)
+	fmt.Println()
+	term.PrintCmds("", "changes", "apply")
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/lib"
+	"plandex/term"
+
+	"github.com/fatih/color"
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var compactKeep int
+
+var compactCmd = &cobra.Command{
+	Use:   "compact",
+	Short: "Replace older conversation messages with a summary",
+	Long: `Replace older conversation messages with a summary to reclaim tokens in long-running plans.
+
+The most recent messages are kept as they are (whole turns, so at least --keep), and everything before them is replaced with a single summary message that records which messages it replaced. The summary generated in the background after each reply is reused when there is one, otherwise a new one is generated with the plan's summary model.
+
+The replaced messages are still in the plan's history, so 'plandex rewind' can undo a compaction.`,
+	Args: cobra.NoArgs,
+	Run:  compact,
+}
+
+func init() {
+	RootCmd.AddCommand(compactCmd)
+
+	compactCmd.Flags().IntVarP(&compactKeep, "keep", "k", 4, "Number of recent messages to keep verbatim")
+}
+
+func compact(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	if compactKeep < 0 {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "--keep can't be negative")
+	}
+
+	term.StartSpinner("🗜️  Compacting conversation...")
+	res, apiErr := api.Client.CompactConvo(lib.CurrentPlanId, lib.CurrentBranch, shared.CompactConvoRequest{
+		KeepRecent: compactKeep,
+		ApiKey:     os.Getenv("OPENAI_API_KEY"),
+	})
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error compacting conversation: %v", apiErr.Msg)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(res)
+		return
+	}
+
+	compaction := res.Compaction
+	fmt.Printf("🗜️  Compacted messages #%d-#%d into a summary\n", compaction.FromNum, compaction.ToNum)
+	fmt.Println(color.New(color.Bold, term.ColorHiCyan).Sprint("  Conversation size →") + fmt.Sprintf(" %d → %d 🪙", res.TokensBefore, res.TokensAfter))
+	fmt.Println()
+
+	term.PrintCmds("", "convo", "rewind")
+}
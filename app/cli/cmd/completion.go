@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/fs"
+	"plandex/lib"
+	"plandex/term"
+	"strings"
+
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate a shell completion script",
+	Long: `Generate a shell completion script for commands and flags, as well as plan, branch, and context names.
+
+Bash:
+  source <(plandex completion bash)
+
+Zsh:
+  plandex completion zsh > "${fpath[1]}/_plandex"
+
+Fish:
+  plandex completion fish > ~/.config/fish/completions/plandex.fish
+
+PowerShell:
+  plandex completion powershell | Out-String | Invoke-Expression`,
+	Args:                  cobra.ExactArgs(1),
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	DisableFlagsInUseLine: true,
+	Run:                   completion,
+}
+
+func init() {
+	RootCmd.CompletionOptions.DisableDefaultCmd = true
+	RootCmd.AddCommand(completionCmd)
+
+	cdCmd.ValidArgsFunction = completePlanNames
+	rmCmd.ValidArgsFunction = completePlanNames
+	checkoutCmd.ValidArgsFunction = completeBranchNames
+	deleteBranchCmd.ValidArgsFunction = completeBranchNames
+	contextRmCmd.ValidArgsFunction = completeContextNames
+	contextPinCmd.ValidArgsFunction = completeContextNames
+	contextUnpinCmd.ValidArgsFunction = completeContextNames
+	contextCmd.RegisterFlagCompletionFunc("filter", completeContextNames)
+	configGetCmd.ValidArgsFunction = completeConfigKeys
+	configSetCmd.ValidArgsFunction = completeConfigKeys
+}
+
+func completion(cmd *cobra.Command, args []string) {
+	var err error
+	switch args[0] {
+	case "bash":
+		err = RootCmd.GenBashCompletionV2(os.Stdout, true)
+	case "zsh":
+		err = RootCmd.GenZshCompletion(os.Stdout)
+	case "fish":
+		err = RootCmd.GenFishCompletion(os.Stdout, true)
+	case "powershell":
+		err = RootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+	default:
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "Unsupported shell %q (expected bash, zsh, fish, or powershell)", args[0])
+	}
+
+	if err != nil {
+		term.OutputErrorAndExit("Error generating completion script: %v", err)
+	}
+}
+
+// resolveForCompletion loads auth and the current project from local state without prompting or creating anything.
+// It returns false if either isn't available, in which case there's nothing to complete.
+func resolveForCompletion() bool {
+	// a prompt would hang the shell, so any that come up (e.g. to refresh an expired token) fail instead
+	term.NonInteractive = true
+
+	if auth.LoadAuth() != nil {
+		return false
+	}
+
+	if fs.PlandexDir == "" {
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(fs.PlandexDir, "project.json")); err != nil {
+		return false
+	}
+
+	lib.MaybeResolveProject()
+
+	return lib.CurrentProjectId != ""
+}
+
+func completePlanNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 || !resolveForCompletion() {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	plans, apiErr := api.Client.ListPlans([]string{lib.CurrentProjectId})
+	if apiErr != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, plan := range plans {
+		if strings.HasPrefix(plan.Name, toComplete) {
+			names = append(names, plan.Name)
+		}
+	}
+
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeBranchNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 || !resolveForCompletion() || lib.CurrentPlanId == "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	branches, apiErr := api.Client.ListBranches(lib.CurrentPlanId)
+	if apiErr != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, branch := range branches {
+		if strings.HasPrefix(branch.Name, toComplete) {
+			names = append(names, branch.Name)
+		}
+	}
+
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeContextNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if !resolveForCompletion() || lib.CurrentPlanId == "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	contexts, apiErr := api.Client.ListContext(lib.CurrentPlanId, lib.CurrentBranch)
+	if apiErr != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	alreadyArgs := map[string]bool{}
+	for _, arg := range args {
+		alreadyArgs[arg] = true
+	}
+
+	var names []string
+	for _, context := range contexts {
+		// file context is matched by path, so complete the path rather than the name
+		name := context.Name
+		if context.FilePath != "" {
+			name = context.FilePath
+		}
+		if !alreadyArgs[name] && strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeConfigKeys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var keys []string
+	for _, key := range lib.ConfigKeys {
+		if key == "models.<role>" {
+			for _, role := range shared.AllModelRoles {
+				keys = append(keys, "models."+string(role))
+			}
+			continue
+		}
+		if key == "formatters.<ext>" {
+			for _, ext := range []string{"go", "js", "jsx", "ts", "tsx", "py", "rs"} {
+				keys = append(keys, "formatters."+ext)
+			}
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completePlanTemplateNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	templates, err := lib.ListPlanTemplates()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, t := range templates {
+		if strings.HasPrefix(t.Name, toComplete) {
+			names = append(names, t.Name)
+		}
+	}
+
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/lib"
+	"plandex/term"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var configGlobal bool
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+
+	configSetCmd.Flags().BoolVarP(&configGlobal, "global", "g", false, "Set the value in the home-level config instead of the project's .plandex/config.yml")
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show plan config",
+	Run:   config,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Show project defaults from config.yml, merged with the home-level config",
+	Run:   configGet,
+	Args:  cobra.MaximumNArgs(1),
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set [key] [value]",
+	Short: "Set a project default in .plandex/config.yml (or the home-level config with --global). Leave the value blank to unset it",
+	Run:   configSet,
+	Args:  cobra.RangeArgs(1, 2),
+}
+
+func config(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	term.StartSpinner("")
+	settings, err := api.Client.GetSettings(lib.CurrentPlanId, lib.CurrentBranch)
+	term.StopSpinner()
+
+	if err != nil {
+		term.OutputErrorAndExit("Error getting settings: %v", err)
+		return
+	}
+
+	color.New(color.Bold, term.ColorHiCyan).Println("⚙️  Plan Config")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Name", "Value", "Description"})
+	table.Append([]string{"auto-update-context", fmt.Sprintf("%t", settings.AutoUpdateContext), shared.SettingDescriptions["auto-update-context"]})
+
+	maxContextTokens := fmt.Sprintf("%d (from model)", settings.GetPlannerEffectiveMaxTokens())
+	if settings.MaxContextTokens > 0 {
+		maxContextTokens = fmt.Sprintf("%d", settings.GetPlannerEffectiveMaxTokens())
+	}
+	table.Append([]string{"max-context-tokens", maxContextTokens, shared.SettingDescriptions["max-context-tokens"]})
+
+	maxParallelBuilds := fmt.Sprintf("%d (default)", settings.GetMaxParallelBuilds())
+	if settings.MaxParallelBuilds > 0 {
+		maxParallelBuilds = fmt.Sprintf("%d", settings.GetMaxParallelBuilds())
+	}
+	table.Append([]string{"max-parallel-builds", maxParallelBuilds, shared.SettingDescriptions["max-parallel-builds"]})
+	table.Render()
+
+	fmt.Println()
+	term.PrintCmds("", "set-config")
+}
+
+func configGet(cmd *cobra.Command, args []string) {
+	config := lib.MustLoadConfig()
+
+	if len(args) > 0 {
+		value, err := lib.GetConfigValue(config, args[0])
+		if err != nil {
+			term.OutputErrorAndExit("%v", err)
+		}
+		fmt.Println(value)
+		return
+	}
+
+	values := lib.ListConfigValues(config)
+	if len(values) == 0 {
+		fmt.Println("🤷‍♂️ No config values set")
+		fmt.Println()
+		printConfigKeys()
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Key", "Value"})
+	for _, kv := range values {
+		table.Append([]string{kv[0], kv[1]})
+	}
+	table.Render()
+}
+
+func configSet(cmd *cobra.Command, args []string) {
+	var value string
+	if len(args) > 1 {
+		value = args[1]
+	}
+
+	path, err := lib.SetConfigValue(args[0], value, configGlobal)
+	if err != nil {
+		term.OutputErrorAndExit("%v", err)
+	}
+
+	if value == "" {
+		fmt.Printf("✅ Unset %s in %s\n", args[0], path)
+	} else {
+		fmt.Printf("✅ Set %s in %s\n", args[0], path)
+	}
+}
+
+func printConfigKeys() {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Key", "Description"})
+	for _, key := range lib.ConfigKeys {
+		table.Append([]string{key, lib.ConfigKeyDescriptions[key]})
+	}
+	table.Render()
+}
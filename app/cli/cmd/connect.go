@@ -15,7 +15,7 @@ import (
 
 var connectCmd = &cobra.Command{
 	Use:     "connect [stream-id-or-plan] [branch]",
-	Aliases: []string{"conn"},
+	Aliases: []string{"conn", "attach"},
 	Short:   "Connect to an active stream",
 	// Long:  ``,
 	Args: cobra.MaximumNArgs(2),
@@ -24,7 +24,7 @@ var connectCmd = &cobra.Command{
 
 func init() {
 	RootCmd.AddCommand(connectCmd)
-
+	connectCmd.Flags().BoolVar(&streamtui.PlainOutput, "plain", false, "Stream the reply as plain text instead of in the stream UI (the default when output isn't a terminal)")
 }
 
 func connect(cmd *cobra.Command, args []string) {
@@ -6,6 +6,7 @@ import (
 	"plandex/auth"
 	"plandex/lib"
 	"plandex/plan_exec"
+	streamtui "plandex/stream_tui"
 	"plandex/term"
 
 	"github.com/plandex/plandex/shared"
@@ -25,9 +26,12 @@ func init() {
 	continueCmd.Flags().BoolVarP(&tellStop, "stop", "s", false, "Stop after a single reply")
 	continueCmd.Flags().BoolVarP(&tellNoBuild, "no-build", "n", false, "Don't build files")
 	continueCmd.Flags().BoolVar(&tellBg, "bg", false, "Execute autonomously in the background")
+	continueCmd.Flags().BoolVar(&streamtui.PlainOutput, "plain", false, "Stream the reply as plain text instead of in the stream UI (the default when output isn't a terminal)")
 }
 
 func doContinue(cmd *cobra.Command, args []string) {
+	tellBg = term.RunInBackground(tellBg)
+
 	if os.Getenv("OPENAI_API_KEY") == "" {
 		term.OutputNoApiKeyMsgAndExit()
 	}
@@ -40,11 +44,13 @@ func doContinue(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	lib.MustCheckBudget()
+
 	plan_exec.TellPlan(plan_exec.ExecParams{
 		CurrentPlanId: lib.CurrentPlanId,
 		CurrentBranch: lib.CurrentBranch,
 		CheckOutdatedContext: func(maybeContexts []*shared.Context) (bool, bool) {
-			return lib.MustCheckOutdatedContext(false, maybeContexts)
+			return lib.MustCheckOutdatedContextBeforePrompt(maybeContexts)
 		},
 	}, "", tellBg, tellStop, tellNoBuild, true)
 }
@@ -35,7 +35,7 @@ func convo(cmd *cobra.Command, args []string) {
 	term.StopSpinner()
 
 	if apiErr != nil {
-		term.OutputErrorAndExit("Error loading conversation: %v", apiErr.Msg)
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error loading conversation: %v", apiErr.Msg)
 	}
 
 	if len(conversation) == 0 {
@@ -45,9 +45,11 @@ func convo(cmd *cobra.Command, args []string) {
 
 	var convo string
 	var totalTokens int
-	for i, msg := range conversation {
+	for _, msg := range conversation {
 		var author string
-		if msg.Role == "assistant" {
+		if msg.Compaction != nil {
+			author = fmt.Sprintf("🗜️  Summary of #%d-#%d", msg.Compaction.FromNum, msg.Compaction.ToNum)
+		} else if msg.Role == "assistant" {
 			author = "🤖 Plandex"
 		} else if msg.Role == "user" {
 			author = "💬 You"
@@ -68,7 +70,7 @@ func convo(cmd *cobra.Command, args []string) {
 			formattedTs = msg.CreatedAt.Local().Format("Yesterday | 3:04pm MST")
 		}
 
-		header := fmt.Sprintf("#### %d | %s | %s | %d 🪙 ", i+1,
+		header := fmt.Sprintf("#### %d | %s | %s | %d 🪙 ", msg.Num,
 			author, formattedTs, msg.Tokens)
 
 		// convMarkdown = append(convMarkdown, header, msg.Message, "")
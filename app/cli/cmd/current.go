@@ -55,6 +55,14 @@ func current(cmd *cobra.Command, args []string) {
 		term.OutputErrorAndExit("Error getting current branches: %v", err)
 	}
 
+	if term.JsonOutput {
+		term.OutputJson(map[string]any{
+			"plan":   plan,
+			"branch": currentBranchesByPlanId[lib.CurrentPlanId],
+		})
+		return
+	}
+
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetAutoWrapText(false)
 	table.SetHeader([]string{"Current Plan", "Updated", "Created" /*"Branches",*/, "Branch", "Context", "Convo"})
@@ -76,7 +84,7 @@ func current(cmd *cobra.Command, args []string) {
 		{tablewriter.FgGreenColor, tablewriter.Bold},
 	}
 
-	table.Rich(row, style)
+	table.Rich(row, term.TableColors(style))
 
 	table.Render()
 	fmt.Println()
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/fs"
+	"plandex/lib"
+	"plandex/plan_exec"
+	"plandex/term"
+	"plandex/types"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var debugLimit int
+var debugVerify bool
+
+var debugCmd = &cobra.Command{
+	Use:   "debug -- <command>",
+	Short: "Run a failing command and have the plan diagnose and fix it",
+	Long: `Run a command from the project root, like a build or tests. If it fails, its output is loaded into context along with the project files its errors and stack traces point to, and the plan diagnoses the failure and fixes it.
+
+With --verify, the command runs again with the fix in place, and the plan keeps fixing until it passes, up to --verify-retries times.`,
+	Example: `  plandex debug -- go test ./...
+  plandex debug -- npm run build`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  debug,
+}
+
+func init() {
+	RootCmd.AddCommand(debugCmd)
+
+	debugCmd.Flags().IntVarP(&debugLimit, "limit", "n", 10, "Maximum number of files from the output to load")
+	debugCmd.Flags().BoolVar(&debugVerify, "verify", false, "Run the command again once the plan finishes, and keep fixing until it passes")
+	debugCmd.Flags().IntVar(&tellVerifyRetries, "verify-retries", 3, "How many times the plan continues to fix the command with --verify")
+	debugCmd.Flags().BoolVarP(&tellStop, "stop", "s", false, "Stop after a single reply")
+	debugCmd.Flags().BoolVar(&tellNoBuild, "no-build", false, "Don't build files")
+	debugCmd.Flags().BoolVar(&tellBg, "bg", false, "Execute autonomously in the background")
+}
+
+func debug(cmd *cobra.Command, args []string) {
+	tellBg = term.RunInBackground(tellBg)
+
+	if os.Getenv("OPENAI_API_KEY") == "" {
+		term.OutputNoApiKeyMsgAndExit()
+	}
+
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	if debugVerify && (tellBg || tellNoBuild) {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "--verify can't be used with --bg, --no-build, or in non-interactive mode")
+	}
+
+	lib.MustCheckBudget()
+
+	// a single arg is a command line for the shell, like 'npm test | tail', and multiple args are quoted as they were passed
+	command := args[0]
+	if len(args) > 1 {
+		command = lib.ShellJoin(args)
+	}
+
+	output, exitCode := lib.RunDebugCommand(command)
+
+	fmt.Println()
+	if exitCode == 0 {
+		fmt.Printf("✅ %s passed, so there's nothing to debug\n", command)
+		return
+	}
+	color.New(color.Bold, term.ColorHiRed).Printf("🚨 %s failed with exit code %d\n", command, exitCode)
+	fmt.Println()
+
+	traceFiles, err := lib.FindTraceFiles(output, debugLimit)
+	if err != nil {
+		term.OutputErrorAndExit("Error finding files in the output: %v", err)
+	}
+
+	lib.MustLoadDebugFailure(command, output, exitCode)
+	fmt.Println()
+
+	if len(traceFiles) > 0 {
+		mustLoadTraceFiles(traceFiles)
+	}
+
+	var locations []string
+	for _, file := range traceFiles {
+		if file.Line > 0 {
+			locations = append(locations, file.Path+":"+strconv.Itoa(file.Line))
+		} else {
+			locations = append(locations, file.Path)
+		}
+	}
+
+	prompt := fmt.Sprintf("`%s` fails with exit code %d. Its output is in context", command, exitCode)
+	if len(locations) > 0 {
+		prompt += fmt.Sprintf(", along with the files its errors point to (%s)", strings.Join(locations, ", "))
+	}
+	prompt += ". Diagnose the failure: explain the root cause, then fix it. If you need files that aren't in context to find the cause, say which ones."
+
+	params := plan_exec.ExecParams{
+		CurrentPlanId: lib.CurrentPlanId,
+		CurrentBranch: lib.CurrentBranch,
+		CheckOutdatedContext: func(maybeContexts []*shared.Context) (bool, bool) {
+			return lib.MustCheckOutdatedContextBeforePrompt(maybeContexts)
+		},
+	}
+
+	if debugVerify {
+		tellVerify = command
+		params.OnFinish = verifyPlan(params, 0)
+	}
+
+	plan_exec.TellPlan(params, prompt, tellBg, tellStop, tellNoBuild, false)
+}
+
+func mustLoadTraceFiles(traceFiles []*lib.TraceFile) {
+	term.StartSpinner("")
+	contexts, apiErr := api.Client.ListContext(lib.CurrentPlanId, lib.CurrentBranch)
+	term.StopSpinner()
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error retrieving context: %v", apiErr.Msg)
+	}
+
+	loadedPaths := map[string]bool{}
+	for _, context := range contexts {
+		if context.FilePath != "" {
+			loadedPaths[filepath.Clean(context.FilePath)] = true
+		}
+	}
+
+	var toLoad []string
+	fmt.Println("🐞 Files from the output:")
+	for _, file := range traceFiles {
+		fmt.Println(color.New(color.FgWhite).Sprintf("  • %s", file.Path))
+
+		// context is loaded relative to the current dir
+		path := file.Path
+		if rel, err := filepath.Rel(fs.Cwd, filepath.Join(fs.ProjectRoot, file.Path)); err == nil {
+			path = rel
+		}
+		if !loadedPaths[file.Path] && !loadedPaths[path] {
+			toLoad = append(toLoad, path)
+		}
+	}
+	fmt.Println()
+
+	if len(toLoad) > 0 {
+		lib.MustLoadContext(toLoad, &types.LoadContextParams{})
+		fmt.Println()
+	}
+}
@@ -42,7 +42,7 @@ func deleteBranch(cmd *cobra.Command, args []string) {
 	}
 
 	if nameOrIdx == "main" {
-		fmt.Println("🚨 Cannot delete main branch")
+		term.OutputSimpleError("Cannot delete main branch")
 		return
 	}
 
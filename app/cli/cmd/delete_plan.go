@@ -114,9 +114,11 @@ func del(cmd *cobra.Command, args []string) {
 	term.StopSpinner()
 
 	if apiErr != nil {
-		term.OutputErrorAndExit("Error deleting plan: %s", apiErr.Msg)
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error deleting plan: %s", apiErr.Msg)
 	}
 
+	lib.ForgetPlanBodies(plan.Id)
+
 	if lib.CurrentPlanId == plan.Id {
 		err := lib.ClearCurrentPlan()
 		if err != nil {
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/lib"
+	"plandex/term"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var diffContext bool
+var diffStat bool
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [from] [to]",
+	Short: "Compare the plan between two revisions",
+	Long: `Compare the plan between two revisions from its log.
+
+A revision is a sha from 'plandex log' or one relative to the latest, like HEAD~3. 'to' defaults to the latest revision and 'from' to the one before it.
+
+Shows how the plan's pending changes to each file differ between the revisions, and which changes were applied or rejected in between. With --context, compares the plan's context instead.`,
+	Args: cobra.MaximumNArgs(2),
+	Run:  diff,
+}
+
+func init() {
+	RootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().BoolVar(&diffContext, "context", false, "Compare context instead of changes")
+	diffCmd.Flags().BoolVar(&diffStat, "stat", false, "Only list what changed, without diffs")
+}
+
+func diff(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	from := "HEAD~1"
+	to := "HEAD"
+	if len(args) > 0 {
+		from = args[0]
+	}
+	if len(args) > 1 {
+		to = args[1]
+	}
+
+	term.StartSpinner("")
+	res, apiErr := api.Client.DiffPlan(lib.CurrentPlanId, lib.CurrentBranch, from, to)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error comparing revisions: %s", apiErr.Msg)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(res)
+		return
+	}
+
+	var changed bool
+	if diffContext {
+		changed = printContextDiff(res.From, res.To)
+	} else {
+		changed = printFilesDiff(res.From, res.To)
+	}
+
+	if !changed {
+		what := "changes"
+		if diffContext {
+			what = "context"
+		}
+		fmt.Printf("🤷‍♂️ No %s differences between %s and %s\n", what, res.From.Sha, res.To.Sha)
+	}
+}
+
+type revisionDiff struct {
+	name   string
+	change string
+	diff   string
+}
+
+func printFilesDiff(from, to *shared.PlanRevision) bool {
+	fromBodies := contextBodiesByPath(from)
+	toBodies := contextBodiesByPath(to)
+
+	pathsSet := map[string]bool{}
+	for path := range from.Files {
+		pathsSet[path] = true
+	}
+	for path := range to.Files {
+		pathsSet[path] = true
+	}
+	var paths []string
+	for path := range pathsSet {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var diffs []*revisionDiff
+	for _, path := range paths {
+		before, pendingBefore := from.Files[path]
+		after, pendingAfter := to.Files[path]
+
+		// a change that's no longer pending was applied to the project or rejected, so there's no plan version to compare
+		if !pendingAfter {
+			change := "rejected"
+			if to.AppliedAtByPath[path].After(from.AppliedAtByPath[path]) {
+				change = "applied"
+			}
+			diffs = append(diffs, &revisionDiff{name: path, change: change})
+			continue
+		}
+
+		change := "updated"
+		var original *string
+		if pendingBefore {
+			original = &before
+		} else {
+			change = "new"
+			// new changes are compared with the file as it was in context, if it was
+			if body, ok := fromBodies[path]; ok {
+				original = &body
+			} else if body, ok := toBodies[path]; ok {
+				original = &body
+			}
+		}
+
+		d := lib.UnifiedDiff(path, original, after)
+		if d == "" {
+			continue
+		}
+		diffs = append(diffs, &revisionDiff{name: path, change: change, diff: d})
+	}
+
+	printRevisionDiffs("File", from, to, diffs)
+	return len(diffs) > 0
+}
+
+func printContextDiff(from, to *shared.PlanRevision) bool {
+	fromById := map[string]*shared.Context{}
+	for _, context := range from.Contexts {
+		fromById[context.Id] = context
+	}
+	toIds := map[string]bool{}
+
+	var diffs []*revisionDiff
+	for _, context := range to.Contexts {
+		toIds[context.Id] = true
+
+		prev := fromById[context.Id]
+		if prev == nil {
+			diffs = append(diffs, &revisionDiff{name: context.Name, change: "added"})
+			continue
+		}
+
+		if prev.Body == context.Body {
+			continue
+		}
+		diffs = append(diffs, &revisionDiff{
+			name:   context.Name,
+			change: "updated",
+			diff:   lib.UnifiedDiff(context.Name, &prev.Body, context.Body),
+		})
+	}
+
+	for _, context := range from.Contexts {
+		if !toIds[context.Id] {
+			diffs = append(diffs, &revisionDiff{name: context.Name, change: "removed"})
+		}
+	}
+
+	printRevisionDiffs("Context", from, to, diffs)
+	return len(diffs) > 0
+}
+
+func printRevisionDiffs(kind string, from, to *shared.PlanRevision, diffs []*revisionDiff) {
+	if len(diffs) == 0 {
+		return
+	}
+
+	fmt.Printf("Comparing %s → %s\n\n", color.New(color.Bold, term.ColorHiCyan).Sprint(from.Sha), color.New(color.Bold, term.ColorHiCyan).Sprint(to.Sha))
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{kind, "Change", "Lines"})
+	for _, d := range diffs {
+		lines := ""
+		if d.diff != "" {
+			added, removed := countDiffLines(d.diff)
+			lines = color.New(color.FgGreen).Sprint("+"+strconv.Itoa(added)) + " " + color.New(color.FgRed).Sprint("-"+strconv.Itoa(removed))
+		}
+		table.Append([]string{d.name, d.change, lines})
+	}
+	table.Render()
+
+	if diffStat {
+		return
+	}
+
+	for _, d := range diffs {
+		if d.diff != "" {
+			fmt.Println()
+			fmt.Print(lib.ColorizeDiff(d.diff))
+		}
+	}
+}
+
+// contextBodiesByPath is the body of each file in context, which is what a file looks like in the plan before it has changes
+func contextBodiesByPath(revision *shared.PlanRevision) map[string]string {
+	res := map[string]string{}
+	for _, context := range revision.Contexts {
+		if context.ContextType == shared.ContextFileType && context.LineRange == "" && context.ChunkPart == 0 && !context.Summarized {
+			res[context.FilePath] = context.Body
+		}
+	}
+	return res
+}
+
+func countDiffLines(diff string) (added, removed int) {
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- ") {
+			continue
+		}
+		if strings.HasPrefix(line, "+") {
+			added++
+		} else if strings.HasPrefix(line, "-") {
+			removed++
+		}
+	}
+	return added, removed
+}
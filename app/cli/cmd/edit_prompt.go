@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/lib"
+	"plandex/plan_exec"
+	"plandex/term"
+	"strconv"
+
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var editPromptText string
+var editPromptFile string
+var editPromptYes bool
+var editPromptBg bool
+var editPromptStop bool
+var editPromptNoBuild bool
+
+var editPromptCmd = &cobra.Command{
+	Use:     "edit-prompt <message-number>",
+	Aliases: []string{"ep"},
+	Short:   "Edit an earlier prompt and replay the plan from there",
+	Long: `Edit an earlier prompt and replay the plan from there.
+
+The message number is the one shown by 'plandex convo'. The plan is rewound to just before that prompt, dropping it and every message after it along with their changes, and the edited prompt is sent in its place. The prompt opens in your editor unless it's passed with --prompt or --file.`,
+	Args: cobra.ExactArgs(1),
+	Run:  editPrompt,
+}
+
+func init() {
+	RootCmd.AddCommand(editPromptCmd)
+
+	editPromptCmd.Flags().StringVarP(&editPromptText, "prompt", "p", "", "The edited prompt")
+	editPromptCmd.Flags().StringVarP(&editPromptFile, "file", "f", "", "File containing the edited prompt")
+	editPromptCmd.Flags().BoolVarP(&editPromptYes, "yes", "y", false, "Rewind without confirmation")
+	editPromptCmd.Flags().BoolVarP(&editPromptStop, "stop", "s", false, "Stop after a single reply")
+	editPromptCmd.Flags().BoolVarP(&editPromptNoBuild, "no-build", "n", false, "Don't build files")
+	editPromptCmd.Flags().BoolVar(&editPromptBg, "bg", false, "Execute autonomously in the background")
+}
+
+func editPrompt(cmd *cobra.Command, args []string) {
+	editPromptBg = term.RunInBackground(editPromptBg)
+
+	if os.Getenv("OPENAI_API_KEY") == "" {
+		term.OutputNoApiKeyMsgAndExit()
+	}
+
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	num, err := strconv.Atoi(args[0])
+	if err != nil || num < 1 {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "Message number must be a positive integer, like the ones shown by 'plandex convo'")
+	}
+
+	lib.MustCheckBudget()
+
+	term.StartSpinner("")
+	convo, apiErr := api.Client.ListConvo(lib.CurrentPlanId, lib.CurrentBranch)
+	if apiErr != nil {
+		term.StopSpinner()
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error loading conversation: %v", apiErr.Msg)
+	}
+
+	logs, apiErr := api.Client.ListLogs(lib.CurrentPlanId, lib.CurrentBranch)
+	term.StopSpinner()
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting logs: %v", apiErr.Msg)
+	}
+
+	var original *shared.ConvoMessage
+	numDropped := 0
+	for _, msg := range convo {
+		if msg.Num == num {
+			original = msg
+		}
+		if msg.Num >= num {
+			numDropped++
+		}
+	}
+
+	if original == nil {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "There's no message #%d in the conversation", num)
+	}
+
+	if original.Role != "user" {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "Message #%d is a reply, not a prompt", num)
+	}
+
+	targetSha, err := lib.GetPromptRewindSha(logs, num)
+	if err != nil {
+		term.OutputErrorAndExit("Can't rewind to before message #%d: %v", num, err)
+	}
+
+	var prompt string
+	if editPromptText != "" {
+		prompt = editPromptText
+	} else if editPromptFile != "" {
+		bytes, err := os.ReadFile(editPromptFile)
+		if err != nil {
+			term.OutputErrorAndExit("Error reading prompt file: %v", err)
+		}
+		prompt = string(bytes)
+	} else if term.NonInteractive {
+		term.OutputInputRequiredAndExit("the edited prompt (pass it with --prompt or --file)")
+	} else {
+		prompt = editPromptInEditor(original.Message)
+	}
+
+	if prompt == "" {
+		fmt.Println("🤷‍♂️ No prompt to send")
+		return
+	}
+
+	if !editPromptYes {
+		suffix := "s"
+		if numDropped == 1 {
+			suffix = ""
+		}
+		confirmed, err := term.ConfirmYesNo("Rewind to before message #%d, dropping %d message%s, and send the edited prompt?", num, numDropped, suffix)
+		if err != nil {
+			term.OutputErrorAndExit("failed to get confirmation user input: %s", err)
+		}
+
+		if !confirmed {
+			fmt.Println("Edit canceled")
+			return
+		}
+	}
+
+	term.StartSpinner("")
+	_, apiErr = api.Client.RewindPlan(lib.CurrentPlanId, lib.CurrentBranch, shared.RewindPlanRequest{Sha: targetSha})
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error rewinding plan: %v", apiErr.Msg)
+	}
+
+	fmt.Printf("✅ Rewound to %s, before message #%d\n", targetSha, num)
+	fmt.Println()
+
+	params := plan_exec.ExecParams{
+		CurrentPlanId: lib.CurrentPlanId,
+		CurrentBranch: lib.CurrentBranch,
+		CheckOutdatedContext: func(maybeContexts []*shared.Context) (bool, bool) {
+			return lib.MustCheckOutdatedContextBeforePrompt(maybeContexts)
+		},
+	}
+
+	plan_exec.TellPlan(params, prompt, editPromptBg, editPromptStop, editPromptNoBuild, false)
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"plandex/api"
+	"plandex/auth"
+	"plandex/lib"
+	"plandex/term"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+const planArchiveExt = ".pdx"
+
+var exportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export the current plan to a portable archive",
+	Long:  `Export the current plan's conversation, context, settings, and pending changes on the current branch to a single archive file that can be loaded with 'plandex import'. Defaults to <plan name>.pdx in the current directory.`,
+	Args:  cobra.MaximumNArgs(1),
+	Run:   export,
+}
+
+func init() {
+	RootCmd.AddCommand(exportCmd)
+}
+
+func export(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	term.StartSpinner("")
+	archive, apiErr := api.Client.ExportPlan(lib.CurrentPlanId, lib.CurrentBranch)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExit("Error exporting plan: %v", apiErr)
+	}
+
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path = archive.Name + planArchiveExt
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		term.OutputErrorAndExit("Error creating %s: %v", path, err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	err = json.NewEncoder(gz).Encode(archive)
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		term.OutputErrorAndExit("Error writing %s: %v", path, err)
+	}
+
+	if term.JsonOutput {
+		absPath, _ := filepath.Abs(path)
+		term.OutputJson(map[string]string{"path": absPath, "plan": archive.Name, "branch": archive.Branch})
+		return
+	}
+
+	fmt.Printf("✅ Exported %s to %s\n", color.New(color.Bold, term.ColorHiGreen).Sprint(archive.Name), color.New(color.Bold, term.ColorHiCyan).Sprint(path))
+	fmt.Println()
+	term.PrintCmds("", "import")
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/lib"
+	"plandex/term"
+	"strconv"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var findLimit int
+
+var findCmd = &cobra.Command{
+	Use:   "find [query]",
+	Short: "Find the project files most relevant to a query",
+	Long:  `Find the project files most relevant to a query using embeddings. The first search indexes the project, and later searches only re-index files that changed. The index is stored in the .plandex directory.`,
+	Args:  cobra.ExactArgs(1),
+	Run:   find,
+}
+
+func init() {
+	RootCmd.AddCommand(findCmd)
+
+	findCmd.Flags().IntVarP(&findLimit, "limit", "n", 10, "Maximum number of files to show")
+}
+
+func find(cmd *cobra.Command, args []string) {
+	if os.Getenv("OPENAI_API_KEY") == "" {
+		term.OutputNoApiKeyMsgAndExit()
+	}
+
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	term.StartSpinner("🔎 Searching...")
+
+	results, err := lib.SearchProject(context.Background(), args[0], findLimit)
+	if err != nil {
+		term.StopSpinner()
+		term.OutputErrorAndExit("Error searching project: %v", err)
+	}
+
+	if lib.CurrentPlanId != "" {
+		contexts, apiErr := api.Client.ListContext(lib.CurrentPlanId, lib.CurrentBranch)
+		if apiErr != nil {
+			term.StopSpinner()
+			term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error retrieving context: %v", apiErr.Msg)
+		}
+
+		loadedPaths := map[string]bool{}
+		for _, context := range contexts {
+			if context.FilePath != "" {
+				loadedPaths[filepath.Clean(context.FilePath)] = true
+			}
+		}
+
+		for _, result := range results {
+			result.InContext = loadedPaths[filepath.Clean(result.Path)]
+		}
+	}
+
+	term.StopSpinner()
+
+	if len(results) == 0 {
+		fmt.Println("🤷‍♂️ No files found")
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"#", "File", "Relevance", "In Context"})
+
+	for i, result := range results {
+		inContext := ""
+		if result.InContext {
+			inContext = "✓"
+		}
+
+		table.Append([]string{
+			strconv.Itoa(i + 1),
+			color.New(color.Bold).Sprint(result.Path),
+			fmt.Sprintf("%.2f", result.Score),
+			inContext,
+		})
+	}
+
+	table.Render()
+
+	fmt.Println()
+	term.PrintCmds("", "load", "tell")
+}
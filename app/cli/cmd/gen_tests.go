@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/fs"
+	"plandex/lib"
+	"plandex/plan_exec"
+	"plandex/term"
+	"plandex/types"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+const genTestsNumExamples = 2
+
+var genTestsCmd = &cobra.Command{
+	Use:   "gen-tests <files...>",
+	Short: "Write tests for source files, following the project's existing tests",
+	Long: `Write tests for source files as pending changes.
+
+The project's test framework is detected from its manifests, and the existing tests closest to the files are loaded as examples so the new tests follow the same conventions. When the plan finishes, a summary shows which definitions in each file the new tests exercise.`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  genTests,
+}
+
+func init() {
+	RootCmd.AddCommand(genTestsCmd)
+
+	genTestsCmd.Flags().BoolVarP(&tellStop, "stop", "s", false, "Stop after a single reply")
+	genTestsCmd.Flags().BoolVar(&tellBg, "bg", false, "Execute autonomously in the background")
+}
+
+func genTests(cmd *cobra.Command, args []string) {
+	tellBg = term.RunInBackground(tellBg)
+
+	if os.Getenv("OPENAI_API_KEY") == "" {
+		term.OutputNoApiKeyMsgAndExit()
+	}
+
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	lib.MustCheckBudget()
+
+	// source paths relative to the project root
+	var sourcePaths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			term.OutputErrorAndExit("Error reading %s: %v", arg, err)
+		}
+		if info.IsDir() {
+			term.OutputErrorAndExitWithCode(term.ExitUsage, "%s is a directory. Pass the source files to write tests for.", arg)
+		}
+
+		path := filepath.Clean(arg)
+		if abs, err := filepath.Abs(arg); err == nil {
+			if rel, err := filepath.Rel(fs.ProjectRoot, abs); err == nil {
+				path = rel
+			}
+		}
+		sourcePaths = append(sourcePaths, path)
+	}
+
+	framework := lib.DetectTestFramework()
+
+	examples, err := lib.FindExampleTests(sourcePaths, genTestsNumExamples)
+	if err != nil {
+		term.OutputErrorAndExit("Error finding existing tests: %v", err)
+	}
+
+	defNamesByPath := map[string][]string{}
+	for _, path := range sourcePaths {
+		names, err := lib.GetDefNames(path)
+		if err != nil {
+			term.OutputErrorAndExit("Error reading definitions: %v", err)
+		}
+		defNamesByPath[path] = names
+	}
+
+	term.StartSpinner("")
+	contexts, apiErr := api.Client.ListContext(lib.CurrentPlanId, lib.CurrentBranch)
+	term.StopSpinner()
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error retrieving context: %v", apiErr.Msg)
+	}
+
+	loadedPaths := map[string]bool{}
+	for _, context := range contexts {
+		if context.FilePath != "" {
+			loadedPaths[filepath.Clean(context.FilePath)] = true
+		}
+	}
+
+	var toLoad []string
+	for _, path := range append(append([]string{}, sourcePaths...), examples...) {
+		// context is loaded relative to the current dir
+		cwdPath := path
+		if rel, err := filepath.Rel(fs.Cwd, filepath.Join(fs.ProjectRoot, path)); err == nil {
+			cwdPath = rel
+		}
+		if !loadedPaths[path] && !loadedPaths[cwdPath] {
+			toLoad = append(toLoad, cwdPath)
+		}
+	}
+
+	if framework != nil {
+		fmt.Printf("🧪 Test framework: %s\n", framework.Name)
+	}
+	if len(examples) > 0 {
+		fmt.Println("🧪 Following existing tests:")
+		for _, path := range examples {
+			fmt.Println(color.New(color.FgWhite).Sprintf("  • %s", path))
+		}
+	}
+	fmt.Println()
+
+	if len(toLoad) > 0 {
+		lib.MustLoadContext(toLoad, &types.LoadContextParams{})
+		fmt.Println()
+	}
+
+	params := plan_exec.ExecParams{
+		CurrentPlanId: lib.CurrentPlanId,
+		CurrentBranch: lib.CurrentBranch,
+		CheckOutdatedContext: func(maybeContexts []*shared.Context) (bool, bool) {
+			return lib.MustCheckOutdatedContextBeforePrompt(maybeContexts)
+		},
+		OnFinish: func() {
+			printTestsSummary(sourcePaths, defNamesByPath)
+		},
+	}
+
+	plan_exec.TellPlan(params, getGenTestsPrompt(sourcePaths, framework, examples, defNamesByPath), tellBg, tellStop, false, false)
+}
+
+func getGenTestsPrompt(sourcePaths []string, framework *lib.TestFramework, examples []string, defNamesByPath map[string][]string) string {
+	var b strings.Builder
+
+	b.WriteString("Write tests for these files, which are in context:\n\n")
+	for _, path := range sourcePaths {
+		b.WriteString("- " + path)
+		if names := defNamesByPath[path]; len(names) > 0 {
+			b.WriteString(" (defines " + strings.Join(names, ", ") + ")")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	if framework != nil {
+		fmt.Fprintf(&b, "The project's tests use %s and are run with `%s`. ", framework.Name, framework.Command)
+	}
+
+	if len(examples) > 0 {
+		fmt.Fprintf(&b, "Existing tests are in context too (%s). Follow their conventions: where test files go and how they're named, how tests are structured, and any helpers or fixtures they use. ", strings.Join(examples, ", "))
+	} else {
+		b.WriteString("The project doesn't have tests like these yet, so follow the usual conventions for the language and framework. ")
+	}
+
+	b.WriteString("Put the tests in new test files, or add to a file's existing test file, and don't change the source files. Cover each file's behavior, including edge cases and error handling.\n\n")
+	b.WriteString("When you're done, end with a summary of what the tests exercise: each definition in the files, and whether and how it's tested.")
+
+	return b.String()
+}
+
+// printTestsSummary shows which definitions in each source file are referenced by the plan's pending test files
+func printTestsSummary(sourcePaths []string, defNamesByPath map[string][]string) {
+	planState, apiErr := api.Client.GetCurrentPlanState(lib.CurrentPlanId, lib.CurrentBranch)
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting current plan state: %s", apiErr.Msg)
+	}
+
+	var tests []string
+	for _, path := range planState.PlanResult.SortedPaths {
+		if lib.IsTestFile(path) {
+			tests = append(tests, planState.CurrentPlanFiles.Files[path])
+		}
+	}
+
+	if len(tests) == 0 {
+		fmt.Println("🤷‍♂️ No pending test files")
+		fmt.Println()
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"File", "Exercised", "Not Exercised"})
+
+	for _, path := range sourcePaths {
+		names := defNamesByPath[path]
+		if len(names) == 0 {
+			table.Append([]string{path, "-", ""})
+			continue
+		}
+
+		var missing []string
+		for _, name := range names {
+			pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`)
+			found := false
+			for _, test := range tests {
+				if pattern.MatchString(test) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				missing = append(missing, name)
+			}
+		}
+
+		exercised := strconv.Itoa(len(names)-len(missing)) + "/" + strconv.Itoa(len(names))
+		table.Append([]string{path, exercised, strings.Join(missing, ", ")})
+	}
+
+	fmt.Println("🧪 Definitions referenced by the new tests")
+	table.Render()
+	fmt.Println()
+}
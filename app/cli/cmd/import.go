@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"plandex/api"
+	"plandex/auth"
+	"plandex/lib"
+	"plandex/term"
+
+	"github.com/fatih/color"
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var importName string
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a plan from an archive created by 'plandex export'",
+	Long:  `Import a plan from an archive created by 'plandex export' into the current project and set it as the current plan. The exported branch is imported as the new plan's main branch.`,
+	Args:  cobra.ExactArgs(1),
+	Run:   importPlan,
+}
+
+func init() {
+	RootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVarP(&importName, "name", "n", "", "Name of the imported plan (defaults to the exported plan's name)")
+}
+
+func importPlan(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveOrCreateProject()
+
+	path := args[0]
+
+	archive, err := readPlanArchive(path)
+	if err != nil {
+		term.OutputErrorAndExit("Error reading %s: %v", path, err)
+	}
+
+	if archive.Version != shared.PlanArchiveVersion {
+		term.OutputErrorAndExit("Unsupported archive version %d (this version of plandex supports version %d)", archive.Version, shared.PlanArchiveVersion)
+	}
+
+	if importName != "" {
+		archive.Name = importName
+	}
+
+	term.StartSpinner("")
+	res, apiErr := api.Client.ImportPlan(lib.CurrentProjectId, archive)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExit("Error importing plan: %v", apiErr)
+	}
+
+	err = lib.WriteCurrentPlan(res.Id)
+
+	if err != nil {
+		term.OutputErrorAndExit("Error setting current plan: %v", err)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(res)
+		return
+	}
+
+	fmt.Printf("✅ Imported plan %s and set it to current plan\n", color.New(color.Bold, term.ColorHiGreen).Sprint(res.Name))
+	fmt.Println()
+	term.PrintCmds("", "convo", "ls", "changes", "tell")
+}
+
+func readPlanArchive(path string) (*shared.PlanArchive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("not a plan archive: %v", err)
+	}
+	defer gz.Close()
+
+	var archive shared.PlanArchive
+	err = json.NewDecoder(gz).Decode(&archive)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding archive: %v", err)
+	}
+
+	return &archive, nil
+}
@@ -94,7 +94,7 @@ func invite(cmd *cobra.Command, args []string) {
 	apiErr := api.Client.InviteUser(inviteRequest)
 	term.StopSpinner()
 	if apiErr != nil {
-		term.OutputErrorAndExit("Failed to invite user: %s", apiErr.Msg)
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Failed to invite user: %s", apiErr.Msg)
 	}
 
 	fmt.Println("✅ Invite sent")
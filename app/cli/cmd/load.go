@@ -2,10 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"plandex/auth"
+	"plandex/fs"
 	"plandex/lib"
 	"plandex/term"
 	"plandex/types"
+	"plandex/url"
 
 	"github.com/spf13/cobra"
 )
@@ -13,15 +16,44 @@ import (
 var (
 	recursive       bool
 	namesOnly       bool
+	codeMap         bool
 	note            string
 	forceSkipIgnore bool
+	maxDepth        int
+	forceBinary     bool
+	pdfPages        string
+	summarize       bool
+	fullSpec        bool
+	head            int
+	headTokens      int
+	tail            int
+	tailTokens      int
+	noRedact        bool
+	symlinks        string
+	evict           bool
+
+	maxTokensPerFile  int
+	truncateOversized bool
+	chunkOversized    bool
+
+	urlConcurrency int
+	urlRetries     int
+	render         bool
+	raw            bool
+	crawl          bool
+	maxPages       int
+	headers        []string
+
+	dbUrl string
+
+	fromPlan string
 )
 
 var contextLoadCmd = &cobra.Command{
 	Use:     "load [files-or-urls...]",
 	Aliases: []string{"l", "add"},
 	Short:   "Load context from various inputs",
-	Long:    `Load context from a file path, a directory, a URL, a string, or piped data.`,
+	Long:    `Load context from a file path, a line range of a file (path:start-end), a directory, a .zip or .tar.gz archive, a URL, a remote git repository (host/org/repo[@ref][:subdir]), a string, piped data, or a database schema (--db).`,
 	Run:     contextLoad,
 }
 
@@ -29,7 +61,34 @@ func init() {
 	contextLoadCmd.Flags().StringVarP(&note, "note", "n", "", "Add a note to the context")
 	contextLoadCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Search directories recursively")
 	contextLoadCmd.Flags().BoolVar(&namesOnly, "tree", false, "Load directory tree with file names only")
+	contextLoadCmd.Flags().BoolVar(&codeMap, "map", false, "Load a map of the definitions and signatures in each file (go, python, javascript, typescript, rust, java) instead of their full contents")
+	contextLoadCmd.Flags().IntVarP(&maxDepth, "depth", "d", 0, "Maximum number of directory levels to descend (0 for no limit)")
 	contextLoadCmd.Flags().BoolVarP(&forceSkipIgnore, "force", "f", false, "Load files even when ignored by .gitignore or .plandexignore")
+	contextLoadCmd.Flags().BoolVar(&forceBinary, "force-binary", false, "Load files even when they appear to be binary")
+	contextLoadCmd.Flags().StringVar(&pdfPages, "pages", "", "Page range to extract when loading PDFs, e.g. 5-20")
+	contextLoadCmd.Flags().BoolVar(&summarize, "summarize", false, "Summarize large files with the model instead of loading their full contents")
+	contextLoadCmd.Flags().BoolVar(&fullSpec, "full", false, "Load OpenAPI and .proto specs in full instead of as a condensed summary of endpoints, messages, and schemas")
+	contextLoadCmd.Flags().IntVar(&head, "head", 0, "Keep only the first N lines of piped data")
+	contextLoadCmd.Flags().IntVar(&headTokens, "head-tokens", 0, "Keep only the first lines of piped data that fit in N tokens")
+	contextLoadCmd.Flags().IntVar(&tail, "tail", 0, "Keep only the last N lines of piped data, dropping earlier lines if they still exceed the token limit")
+	contextLoadCmd.Flags().IntVar(&tailTokens, "tail-tokens", 0, "Keep only the last lines of piped data that fit in N tokens")
+	contextLoadCmd.Flags().BoolVar(&noRedact, "no-redact", false, "Load without masking secrets like API keys, AWS credentials, private keys, and .env values")
+	contextLoadCmd.Flags().StringVar(&symlinks, "symlinks", "", "How to handle symlinks when loading directories: follow, skip, or error (follow, but fail on a cycle). Defaults to PLANDEX_SYMLINKS or follow")
+	contextLoadCmd.Flags().BoolVar(&evict, "evict", false, "If loading would exceed the token limit, offer to remove the least recently used unpinned context to make room")
+	contextLoadCmd.Flags().IntVar(&maxTokensPerFile, "max-tokens-per-file", 0, "Skip files with more tokens than this (0 for no limit)")
+	contextLoadCmd.Flags().BoolVar(&truncateOversized, "truncate", false, "With --max-tokens-per-file, load the beginning and end of large files instead of skipping them")
+	contextLoadCmd.Flags().BoolVar(&chunkOversized, "chunk", false, "With --max-tokens-per-file, split large files into parts at function or section boundaries and load each part separately")
+	contextLoadCmd.Flags().IntVar(&urlConcurrency, "url-concurrency", 5, "Maximum number of URLs to fetch at once")
+	contextLoadCmd.Flags().IntVar(&urlRetries, "url-retries", 3, "Number of times to retry a URL after a network error, 429, or 5xx response")
+	contextLoadCmd.Flags().BoolVar(&render, "render", false, "Render URLs in a headless browser so content generated by JavaScript is included (requires Chrome or Chromium)")
+	contextLoadCmd.Flags().BoolVar(&raw, "raw", false, "Load URLs as raw HTML instead of converting them to markdown")
+	contextLoadCmd.Flags().BoolVar(&crawl, "crawl", false, "Crawl each URL's site, using its sitemap.xml if it has one or else following same-origin links, and load every page. Can't be used with --render")
+	contextLoadCmd.Flags().IntVar(&maxPages, "max-pages", 50, "With --crawl, the maximum number of pages to load per site")
+	contextLoadCmd.Flags().StringArrayVarP(&headers, "header", "H", nil, "Header to send when fetching URLs, e.g. \"Authorization: Bearer ...\" (repeatable). Per-domain headers and cookies can also be set in url-credentials.json in the Plandex home dir")
+	contextLoadCmd.Flags().StringVar(&dbUrl, "db", "", "Load the schema of a postgres database (postgres://...): tables, columns, indexes, and foreign keys. The password isn't stored, so updates use PGPASSWORD or ~/.pgpass")
+	contextLoadCmd.Flags().StringVar(&fromPlan, "from-plan", "", "Share context from another plan (by name or index) instead of loading it. Args are the names, paths, or globs of the other plan's context, or all of it if none are given. Shared context isn't copied, so updates to it in the other plan apply here too")
+
+	contextLoadCmd.RegisterFlagCompletionFunc("from-plan", completePlanNames)
 	RootCmd.AddCommand(contextLoadCmd)
 }
 
@@ -42,11 +101,80 @@ func contextLoad(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if fromPlan != "" {
+		lib.MustLoadSharedContext(fromPlan, args)
+		fmt.Println()
+		term.PrintCmds("", "ls", "tell")
+		return
+	}
+
+	if summarize && os.Getenv("OPENAI_API_KEY") == "" {
+		term.OutputNoApiKeyMsgAndExit()
+	}
+
+	if chunkOversized && truncateOversized {
+		term.OutputErrorAndExit("--chunk and --truncate can't be used together")
+	}
+
+	if chunkOversized && maxTokensPerFile <= 0 {
+		term.OutputErrorAndExit("--chunk requires --max-tokens-per-file")
+	}
+
+	if crawl && render {
+		term.OutputErrorAndExit("--crawl and --render can't be used together")
+	}
+
+	if (head > 0 || headTokens > 0) && (tail > 0 || tailTokens > 0) {
+		term.OutputErrorAndExit("--head/--head-tokens and --tail/--tail-tokens can't be used together")
+	}
+
+	if symlinks != "" {
+		policy, err := fs.ParseSymlinkPolicy(symlinks)
+		if err != nil {
+			term.OutputErrorAndExit("%v", err)
+		}
+		fs.Symlinks = policy
+	}
+
+	parsedHeaders, err := url.ParseHeaders(headers)
+	if err != nil {
+		term.OutputErrorAndExit("%v", err)
+	}
+
 	lib.MustLoadContext(args, &types.LoadContextParams{
 		Note:            note,
 		Recursive:       recursive,
 		NamesOnly:       namesOnly,
+		Map:             codeMap,
 		ForceSkipIgnore: forceSkipIgnore,
+		MaxDepth:        maxDepth,
+		ForceBinary:     forceBinary,
+		PdfPages:        pdfPages,
+		Summarize:       summarize,
+		FullSpec:        fullSpec,
+		Head:            head,
+		HeadTokens:      headTokens,
+		Tail:            tail,
+		TailTokens:      tailTokens,
+		NoRedact:        noRedact,
+		Evict:           evict,
+
+		MaxTokensPerFile:  maxTokensPerFile,
+		TruncateOversized: truncateOversized,
+		ChunkOversized:    chunkOversized,
+
+		UrlConcurrency: urlConcurrency,
+		UrlRetries:     urlRetries,
+		Render:         render,
+		Raw:            raw,
+		Crawl:          crawl,
+		MaxPages:       maxPages,
+		Headers:        parsedHeaders,
+
+		DbUrl: dbUrl,
+
+		// scripts get a network error exit code instead
+		QueueOffline: !term.NonInteractive,
 	})
 
 	fmt.Println()
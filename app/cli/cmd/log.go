@@ -1,14 +1,19 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"plandex/api"
 	"plandex/auth"
 	"plandex/lib"
 	"plandex/term"
 	"regexp"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/plandex/plandex/shared"
 	"github.com/spf13/cobra"
 )
 
@@ -17,14 +22,22 @@ var logCmd = &cobra.Command{
 	Use:     "log",
 	Aliases: []string{"history", "logs"},
 	Short:   "Show plan history",
-	Long:    `Show plan history`,
-	Args:    cobra.NoArgs,
-	Run:     runLog,
+	Long: `Show plan history.
+
+With --export, renders the plan's full conversation instead: each prompt and reply, the files each reply changed, and the context loaded along the way, as Markdown (md), HTML (html), or JSON (json) for sharing in design docs or PR descriptions.`,
+	Args: cobra.NoArgs,
+	Run:  runLog,
 }
 
+var logExport string
+var logExportOutput string
+
 func init() {
 	// Add log command
 	RootCmd.AddCommand(logCmd)
+
+	logCmd.Flags().StringVar(&logExport, "export", "", "Export the conversation as md, html, or json")
+	logCmd.Flags().StringVarP(&logExportOutput, "output", "o", "", "File to write the export to (defaults to stdout)")
 }
 
 func runLog(cmd *cobra.Command, args []string) {
@@ -36,6 +49,15 @@ func runLog(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if logExport != "" {
+		exportLog()
+		return
+	}
+
+	if logExportOutput != "" {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "--output can only be used with --export")
+	}
+
 	term.StartSpinner("")
 	res, apiErr := api.Client.ListLogs(lib.CurrentPlanId, lib.CurrentBranch)
 	term.StopSpinner()
@@ -50,10 +72,53 @@ func runLog(cmd *cobra.Command, args []string) {
 		term.OutputErrorAndExit("Error converting timestamps: %v", err)
 	}
 
+	if term.JsonOutput {
+		term.OutputJson(shared.LogResponse{Shas: res.Shas, Body: withLocalTimestamps})
+		return
+	}
+
 	term.PageOutput(withLocalTimestamps)
 
 }
 
+func exportLog() {
+	if !slices.Contains(lib.LogExportFormats, logExport) {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "Invalid export format %q, use one of: %s", logExport, strings.Join(lib.LogExportFormats, ", "))
+	}
+
+	export := lib.MustGetLogExport(lib.CurrentPlanId, lib.CurrentBranch)
+
+	var out string
+	switch logExport {
+	case lib.LogExportMarkdown:
+		out = export.Markdown()
+	case lib.LogExportHtml:
+		var err error
+		out, err = export.Html()
+		if err != nil {
+			term.OutputErrorAndExit("Error exporting log: %v", err)
+		}
+	case lib.LogExportJson:
+		bytes, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			term.OutputErrorAndExit("Error exporting log: %v", err)
+		}
+		out = string(bytes) + "\n"
+	}
+
+	if logExportOutput == "" {
+		fmt.Print(out)
+		return
+	}
+
+	err := os.WriteFile(logExportOutput, []byte(out), 0644)
+	if err != nil {
+		term.OutputErrorAndExit("Error writing %s: %v", logExportOutput, err)
+	}
+
+	fmt.Printf("✅ Exported %d events to %s\n", len(export.Events), logExportOutput)
+}
+
 func convertTimestampsToLocal(input string) (string, error) {
 	t := time.Now()
 	zone, _ := t.Zone()
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"plandex/logging"
+	"plandex/term"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var logsLines int
+var logsFollow bool
+var logsPath bool
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the end of the local log file",
+	Long: `Show the end of the local log file, which has what the CLI logged with the ids of its API requests. When reporting a server error, include its request id so it can be found in the server's logs.
+
+Use --verbose or --debug with any command to see its logs as it runs.`,
+	Args: cobra.NoArgs,
+	Run:  logs,
+}
+
+func init() {
+	RootCmd.AddCommand(logsCmd)
+
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", 50, "Number of lines to show")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep showing new lines as they're logged")
+	logsCmd.Flags().BoolVar(&logsPath, "path", false, "Only show the log file's path")
+}
+
+func logs(cmd *cobra.Command, args []string) {
+	path := logging.LogPath()
+
+	if logsPath {
+		fmt.Println(path)
+		return
+	}
+
+	if logsLines < 0 {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "--lines can't be negative")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		term.OutputErrorAndExit("Error reading log file: %v", err)
+	}
+
+	os.Stdout.Write(lastLines(content, logsLines))
+
+	if !logsFollow {
+		return
+	}
+
+	offset := int64(len(content))
+	for {
+		time.Sleep(500 * time.Millisecond)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		// the file was rotated
+		if info.Size() < offset {
+			offset = 0
+		}
+		if info.Size() == offset {
+			continue
+		}
+
+		offset, err = copyFrom(path, offset)
+		if err != nil {
+			term.OutputErrorAndExit("Error reading log file: %v", err)
+		}
+	}
+}
+
+func lastLines(b []byte, n int) []byte {
+	if n == 0 {
+		return nil
+	}
+
+	end := len(b)
+	if end > 0 && b[end-1] == '\n' {
+		end--
+	}
+
+	for i := 0; i < n; i++ {
+		idx := bytes.LastIndexByte(b[:end], '\n')
+		if idx == -1 {
+			return b
+		}
+		end = idx
+	}
+
+	return b[end+1:]
+}
+
+// copyFrom writes the file from offset to stdout, and returns the new offset
+func copyFrom(path string, offset int64) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return offset, err
+	}
+	defer file.Close()
+
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return offset, err
+	}
+
+	n, err := io.Copy(os.Stdout, file)
+	return offset + n, err
+}
@@ -8,13 +8,22 @@ import (
 	"plandex/format"
 	"plandex/lib"
 	"plandex/term"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
+	"github.com/plandex/plandex/shared"
 	"github.com/spf13/cobra"
 )
 
+var (
+	lsSort   string
+	lsTypes  []string
+	lsFilter string
+)
+
 var contextCmd = &cobra.Command{
 	Use:     "ls",
 	Aliases: []string{"ls"},
@@ -22,6 +31,12 @@ var contextCmd = &cobra.Command{
 	Run:     listContext,
 }
 
+type lsContext struct {
+	// 1-based index as used by rm, pin, etc., which stays the same when filtering or sorting
+	Index int `json:"index"`
+	*shared.Context
+}
+
 func listContext(cmd *cobra.Command, args []string) {
 	auth.MustResolveAuthWithOrg()
 	lib.MustResolveProject()
@@ -34,10 +49,61 @@ func listContext(cmd *cobra.Command, args []string) {
 		term.OutputErrorAndExit("Error listing context: %v", err)
 	}
 
-	totalTokens := 0
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"#", "Name", "Type", "🪙", "Added", "Updated"})
-	table.SetAutoWrapText(false)
+	if lsSort != "" && lsSort != "tokens" && lsSort != "updated" && lsSort != "name" {
+		term.OutputErrorAndExit("Invalid --sort value %q (expected tokens, updated, or name)", lsSort)
+	}
+
+	var listed []*lsContext
+	for i, context := range contexts {
+		if len(lsTypes) > 0 {
+			t, _ := lib.GetContextTypeAndIcon(context)
+			matched := false
+			for _, lsType := range lsTypes {
+				if strings.EqualFold(lsType, t) || strings.EqualFold(lsType, string(context.ContextType)) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+
+		if lsFilter != "" {
+			matched, err := lib.ContextMatchesArg(i, context, lsFilter)
+			if err != nil {
+				term.OutputErrorAndExit("Error matching glob pattern: %v", err)
+			}
+			if !matched {
+				continue
+			}
+		}
+
+		listed = append(listed, &lsContext{Index: i + 1, Context: context})
+	}
+
+	switch lsSort {
+	case "tokens":
+		sort.SliceStable(listed, func(i, j int) bool {
+			return listed[i].NumTokens > listed[j].NumTokens
+		})
+	case "updated":
+		sort.SliceStable(listed, func(i, j int) bool {
+			return listed[i].UpdatedAt.After(listed[j].UpdatedAt)
+		})
+	case "name":
+		sort.SliceStable(listed, func(i, j int) bool {
+			return strings.ToLower(listed[i].Name) < strings.ToLower(listed[j].Name)
+		})
+	}
+
+	if term.JsonOutput {
+		if listed == nil {
+			listed = []*lsContext{}
+		}
+		term.OutputJson(listed)
+		return
+	}
 
 	if len(contexts) == 0 {
 		fmt.Println("🤷‍♂️ No context")
@@ -46,23 +112,33 @@ func listContext(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	for i, context := range contexts {
+	if len(listed) == 0 {
+		fmt.Println("🤷‍♂️ No matching context")
+		return
+	}
+
+	totalTokens := 0
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"#", "Name", "Type", "🪙", "Added", "Updated"})
+	table.SetAutoWrapText(false)
+
+	for _, context := range listed {
 		totalTokens += context.NumTokens
 
-		t, icon := lib.GetContextTypeAndIcon(context)
+		t, icon := lib.GetContextTypeAndIcon(context.Context)
 
 		row := []string{
-			strconv.Itoa(i + 1),
-			" " + icon + " " + context.Name,
+			strconv.Itoa(context.Index),
+			" " + icon + " " + context.Name + pinnedSuffix(context.Context) + sharedSuffix(context.Context),
 			t,
 			strconv.Itoa(context.NumTokens), //+ " 🪙",
 			format.Time(context.CreatedAt),
 			format.Time(context.UpdatedAt),
 		}
-		table.Rich(row, []tablewriter.Colors{
+		table.Rich(row, term.TableColors([]tablewriter.Colors{
 			{tablewriter.Bold},
 			{tablewriter.FgHiGreenColor, tablewriter.Bold},
-		})
+		}))
 	}
 
 	table.Render()
@@ -74,11 +150,29 @@ func listContext(cmd *cobra.Command, args []string) {
 	tokensTbl.Render()
 
 	fmt.Println()
-	term.PrintCmds("", "load", "rm", "clear")
+	term.PrintCmds("", "load", "rm", "pin", "clear")
 
 }
 
 func init() {
 	RootCmd.AddCommand(contextCmd)
 
+	contextCmd.Flags().StringVar(&lsSort, "sort", "", "Sort by tokens, updated, or name")
+	contextCmd.Flags().StringSliceVar(&lsTypes, "type", nil, "Only list context of these types, e.g. file, url, note, tree, map (repeatable or comma-separated)")
+	contextCmd.Flags().StringVar(&lsFilter, "filter", "", "Only list context matching a name, path, glob, or parent directory")
+
+}
+
+func pinnedSuffix(context *shared.Context) string {
+	if context.Pinned {
+		return " 📌"
+	}
+	return ""
+}
+
+func sharedSuffix(context *shared.Context) string {
+	if context.SourcePlanId != "" {
+		return " 🔗"
+	}
+	return ""
 }
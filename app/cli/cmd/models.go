@@ -7,6 +7,7 @@ import (
 	"plandex/auth"
 	"plandex/lib"
 	"plandex/term"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
@@ -46,15 +47,20 @@ func models(cmd *cobra.Command, args []string) {
 	color.New(color.Bold, term.ColorHiCyan).Println("🤖 Models")
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetAutoWrapText(false)
-	table.SetHeader([]string{"Role", "Provider", "Model", "Temperature", "Top P"})
+	table.SetHeader([]string{"Role", "Provider", "Model", "Temperature", "Top P", "Max Output"})
 
 	addModelRow := func(role string, config shared.ModelRoleConfig) {
+		maxOutput := "default"
+		if config.MaxCompletionTokens > 0 {
+			maxOutput = fmt.Sprintf("%d 🪙", config.MaxCompletionTokens)
+		}
 		table.Append([]string{
 			role,
 			string(config.BaseModelConfig.Provider),
 			config.BaseModelConfig.ModelName,
 			fmt.Sprintf("%.1f", config.Temperature),
 			fmt.Sprintf("%.1f", config.TopP),
+			maxOutput,
 		})
 	}
 
@@ -68,6 +74,28 @@ func models(cmd *cobra.Command, args []string) {
 
 	fmt.Println()
 
+	var fallbackRows [][]string
+	for _, role := range shared.AllModelRoles {
+		var names []string
+		for _, m := range modelSet.RoleConfig(role).Fallbacks {
+			names = append(names, fmt.Sprintf("%s → %s", m.Provider, m.ModelName))
+		}
+		if len(names) > 0 {
+			fallbackRows = append(fallbackRows, []string{string(role), strings.Join(names, "\n")})
+		}
+	}
+
+	if len(fallbackRows) > 0 {
+		color.New(color.Bold, term.ColorHiCyan).Println("🛟 Fallbacks")
+		table = tablewriter.NewWriter(os.Stdout)
+		table.SetAutoWrapText(false)
+		table.SetHeader([]string{"Role", "Fallback Models (in order)"})
+		table.AppendBulk(fallbackRows)
+		table.Render()
+
+		fmt.Println()
+	}
+
 	color.New(color.Bold, term.ColorHiCyan).Println("🧠 Planner Defaults")
 	table = tablewriter.NewWriter(os.Stdout)
 	table.SetAutoWrapText(false)
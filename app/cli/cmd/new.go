@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"plandex/api"
 	"plandex/auth"
@@ -15,6 +14,7 @@ import (
 )
 
 var name string
+var newFromTemplate string
 
 // newCmd represents the new command
 var newCmd = &cobra.Command{
@@ -29,19 +29,35 @@ var newCmd = &cobra.Command{
 func init() {
 	RootCmd.AddCommand(newCmd)
 	newCmd.Flags().StringVarP(&name, "name", "n", "", "Name of the new plan")
+	newCmd.Flags().StringVar(&newFromTemplate, "from-template", "", "Plan template from .plandex/plan-templates with a prompt skeleton, context, and models for the new plan")
+
+	newCmd.RegisterFlagCompletionFunc("from-template", completePlanTemplateNames)
 }
 
 func new(cmd *cobra.Command, args []string) {
 	auth.MustResolveAuthWithOrg()
 	lib.MustResolveOrCreateProject()
 
+	var template *lib.PlanTemplate
+	if newFromTemplate != "" {
+		var err error
+		template, err = lib.GetPlanTemplate(newFromTemplate)
+		if err != nil {
+			term.OutputErrorAndExit("Error loading plan template: %v", err)
+		}
+
+		if name == "" {
+			name = template.PlanName
+		}
+	}
+
 	term.StartSpinner("")
 	res, apiErr := api.Client.CreatePlan(lib.CurrentProjectId, shared.CreatePlanRequest{Name: name})
 	term.StopSpinner()
 
 	if apiErr != nil {
 		if apiErr.Type == shared.ApiErrorTypeTrialPlansExceeded {
-			fmt.Fprintf(os.Stderr, "🚨 You've reached the Plandex Cloud anonymous trial limit of %d plans\n", apiErr.TrialPlansExceededError.MaxPlans)
+			term.OutputSimpleError("You've reached the Plandex Cloud anonymous trial limit of %d plans", apiErr.TrialPlansExceededError.MaxPlans)
 
 			res, err := term.ConfirmYesNo("Upgrade to an unlimited free account?")
 
@@ -59,7 +75,7 @@ func new(cmd *cobra.Command, args []string) {
 			return
 		}
 
-		term.OutputErrorAndExit("Error creating plan: %v", apiErr.Msg)
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error creating plan: %v", apiErr.Msg)
 	}
 
 	err := lib.WriteCurrentPlan(res.Id)
@@ -68,11 +84,13 @@ func new(cmd *cobra.Command, args []string) {
 		term.OutputErrorAndExit("Error setting current plan: %v", err)
 	}
 
-	if name == "" {
-		name = "draft"
-	}
+	fmt.Printf("✅ Started new plan %s and set it to current plan\n", color.New(color.Bold, term.ColorHiGreen).Sprint(res.Name))
 
-	fmt.Printf("✅ Started new plan %s and set it to current plan\n", color.New(color.Bold, term.ColorHiGreen).Sprint(name))
+	lib.MustApplyConfigToNewPlan(res.Id)
+
+	if template != nil {
+		lib.MustApplyPlanTemplate(res.Id, template)
+	}
 
 	fmt.Println()
 	term.PrintCmds("", "load", "tell", "plans", "current")
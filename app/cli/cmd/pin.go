@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/lib"
+	"plandex/term"
+
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var contextPinCmd = &cobra.Command{
+	Use:   "pin",
+	Short: "Pin context",
+	Long:  `Pin context by index, name, or glob so it's never evicted by load --evict.`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		contextPin(args, true)
+	},
+}
+
+var contextUnpinCmd = &cobra.Command{
+	Use:   "unpin",
+	Short: "Unpin context",
+	Long:  `Unpin context by index, name, or glob.`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		contextPin(args, false)
+	},
+}
+
+func contextPin(args []string, pinned bool) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	term.StartSpinner("")
+	contexts, err := api.Client.ListContext(lib.CurrentPlanId, lib.CurrentBranch)
+
+	if err != nil {
+		term.OutputErrorAndExit("Error retrieving context: %v", err)
+	}
+
+	ids := map[string]bool{}
+
+	for i, context := range contexts {
+		for _, id := range args {
+			matched, err := lib.ContextMatchesArg(i, context, id)
+			if err != nil {
+				term.OutputErrorAndExit("Error matching glob pattern: %v", err)
+			}
+			if matched {
+				ids[context.Id] = true
+				break
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		term.StopSpinner()
+		fmt.Println("🤷‍♂️ No matching context")
+		return
+	}
+
+	res, err := api.Client.PinContext(lib.CurrentPlanId, lib.CurrentBranch, shared.PinContextRequest{
+		Ids:    ids,
+		Pinned: pinned,
+	})
+	term.StopSpinner()
+
+	if err != nil {
+		term.OutputErrorAndExit("Error pinning context: %v", err)
+	}
+
+	if res.Msg == "" {
+		if pinned {
+			fmt.Println("🤷‍♂️ Already pinned")
+		} else {
+			fmt.Println("🤷‍♂️ Not pinned")
+		}
+		return
+	}
+
+	fmt.Println("✅ " + res.Msg)
+}
+
+func init() {
+	RootCmd.AddCommand(contextPinCmd)
+	RootCmd.AddCommand(contextUnpinCmd)
+}
@@ -100,6 +100,11 @@ func plans(cmd *cobra.Command, args []string) {
 	}
 
 	if len(projectIds) == 0 {
+		if term.JsonOutput {
+			term.OutputJson([]*planJson{})
+			return
+		}
+
 		fmt.Println("🤷‍♂️ No plans")
 		fmt.Println()
 		term.PrintCmds("", "new")
@@ -115,6 +120,11 @@ func plans(cmd *cobra.Command, args []string) {
 	}
 
 	if len(plans) == 0 {
+		if term.JsonOutput {
+			term.OutputJson([]*planJson{})
+			return
+		}
+
 		fmt.Println("🤷‍♂️ No plans")
 		fmt.Println()
 		term.PrintCmds("", "new")
@@ -139,6 +149,7 @@ func plans(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	var currentBranchesByPlanId map[string]*shared.Branch
 	if len(currentProjectPlanIds) > 0 {
 		currentBranchNamesByPlanId, err := lib.GetCurrentBranchNamesByPlanId(currentProjectPlanIds)
 
@@ -146,14 +157,22 @@ func plans(cmd *cobra.Command, args []string) {
 			term.OutputErrorAndExit("Error getting current branches: %v", err)
 		}
 
-		currentBranchesByPlanId, apiErr := api.Client.GetCurrentBranchByPlanId(lib.CurrentProjectId, shared.GetCurrentBranchByPlanIdRequest{
+		var apiErr *shared.ApiError
+		currentBranchesByPlanId, apiErr = api.Client.GetCurrentBranchByPlanId(lib.CurrentProjectId, shared.GetCurrentBranchByPlanIdRequest{
 			CurrentBranchByPlanId: currentBranchNamesByPlanId,
 		})
 
 		if apiErr != nil {
 			term.OutputErrorAndExit("Error getting current branches: %v", apiErr)
 		}
+	}
 
+	if term.JsonOutput {
+		outputPlansJson(plans, currentBranchesByPlanId, parentProjectIdsWithPaths, childProjectIdsWithPaths)
+		return
+	}
+
+	if len(currentProjectPlanIds) > 0 {
 		table := tablewriter.NewWriter(os.Stdout)
 		table.SetAutoWrapText(false)
 		table.SetHeader([]string{"#", "Name", "Updated" /*, "Created" /*"Branches",*/, "Branch", "Context", "Convo"})
@@ -176,6 +195,9 @@ func plans(cmd *cobra.Command, args []string) {
 			} else {
 				name = p.Name
 			}
+			if p.SharedPermission != "" {
+				name += fmt.Sprintf(" (shared, %s)", p.SharedPermission)
+			}
 
 			currentBranch := currentBranchesByPlanId[p.Id]
 
@@ -201,7 +223,7 @@ func plans(cmd *cobra.Command, args []string) {
 				}
 			}
 
-			table.Rich(row, style)
+			table.Rich(row, term.TableColors(style))
 
 		}
 		table.Render()
@@ -317,3 +339,38 @@ func plans(cmd *cobra.Command, args []string) {
 		term.PrintCmds("", "new")
 	}
 }
+
+type planJson struct {
+	*shared.Plan
+	// project directory relative to the current directory
+	Path    string `json:"path"`
+	Current bool   `json:"current"`
+	// current branch, only set for plans in the current directory
+	Branch *shared.Branch `json:"branch,omitempty"`
+}
+
+func outputPlansJson(plans []*shared.Plan, currentBranchesByPlanId map[string]*shared.Branch, parentProjectIdsWithPaths, childProjectIdsWithPaths [][2]string) {
+	pathsByProjectId := map[string]string{}
+	if lib.CurrentProjectId != "" {
+		pathsByProjectId[lib.CurrentProjectId] = "."
+	}
+	for _, p := range append(parentProjectIdsWithPaths, childProjectIdsWithPaths...) {
+		rel, err := filepath.Rel(fs.Cwd, p[0])
+		if err != nil {
+			term.OutputErrorAndExit("Error getting relative path: %v", err)
+		}
+		pathsByProjectId[p[1]] = rel
+	}
+
+	res := []*planJson{}
+	for _, p := range plans {
+		res = append(res, &planJson{
+			Plan:    p,
+			Path:    pathsByProjectId[p.ProjectId],
+			Current: p.Id == lib.CurrentPlanId,
+			Branch:  currentBranchesByPlanId[p.Id],
+		})
+	}
+
+	term.OutputJson(res)
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"plandex/auth"
+	"plandex/fs"
+	"plandex/lib"
+	"plandex/term"
+
+	"github.com/spf13/cobra"
+)
+
+var prRemote string
+var prBase string
+var prTitle string
+var prBranchName string
+var prDraft bool
+
+var prCmd = &cobra.Command{
+	Use:   "pr",
+	Short: "Push the plan's git branch and open a pull request",
+	Long: `Push the plan's git branch and open a pull request on GitHub, or a merge request on GitLab.
+
+The branch is the one 'plandex apply --branch' created for the plan, or the current branch if there isn't one. The description is generated from a summary of the plan's conversation, with a list of the context it used.
+
+Needs an API token in GITHUB_TOKEN (or GH_TOKEN) for github.com, or GITLAB_TOKEN for gitlab.com. For GitHub Enterprise, set GH_HOST to its host and the token in GH_ENTERPRISE_TOKEN. For self-managed GitLab, set GITLAB_HOST to its host and the token in GITLAB_SELF_MANAGED_TOKEN.`,
+	Args: cobra.NoArgs,
+	Run:  pr,
+}
+
+func init() {
+	RootCmd.AddCommand(prCmd)
+
+	prCmd.Flags().StringVar(&prRemote, "remote", "origin", "Git remote to push to and open the pull request on")
+	prCmd.Flags().StringVar(&prBase, "base", "", "Branch to merge into (defaults to the remote's default branch)")
+	prCmd.Flags().StringVarP(&prTitle, "title", "t", "", "Pull request title (defaults to the branch's last commit message)")
+	prCmd.Flags().StringVar(&prBranchName, "branch-name", "", "Git branch to open the pull request from")
+	prCmd.Flags().BoolVar(&prDraft, "draft", false, "Open as a draft")
+}
+
+func pr(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	if !fs.ProjectRootIsGitRepo() {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "The project isn't in a git repo")
+	}
+
+	remoteUrl, err := lib.GitRemoteUrl(fs.ProjectRoot, prRemote)
+	if err != nil {
+		term.OutputErrorAndExit("Error getting remote: %v", err)
+	}
+
+	remote, err := lib.ParseGitRemote(remoteUrl)
+	if err != nil {
+		term.OutputErrorAndExit("Can't open a pull request on %s: %v", prRemote, err)
+	}
+
+	base := prBase
+	if base == "" {
+		base, err = lib.GitRemoteDefaultBranch(fs.ProjectRoot, prRemote)
+		if err != nil {
+			base = "main"
+		}
+	}
+
+	head := prBranchName
+	if head == "" {
+		head = lib.MustGetApplyBranchName(lib.CurrentPlanId, lib.CurrentBranch)
+		if !lib.GitBranchExists(fs.ProjectRoot, head) {
+			head, err = lib.GitCurrentBranch(fs.ProjectRoot)
+			if err != nil {
+				term.OutputErrorAndExit("Error getting current git branch: %v", err)
+			}
+		}
+	} else if !lib.GitBranchExists(fs.ProjectRoot, head) {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "There's no git branch named %s", head)
+	}
+
+	if head == base {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "There's no branch to open a pull request from, since you're on %s. Use 'plandex apply --branch' to apply the plan on its own branch.", base)
+	}
+
+	title := prTitle
+	if title == "" {
+		title, err = lib.GitLastCommitSubject(fs.ProjectRoot, head)
+		if err != nil {
+			term.OutputErrorAndExit("Error getting pull request title: %v", err)
+		}
+	}
+
+	body := lib.MustGetPullRequestBody(lib.CurrentPlanId, lib.CurrentBranch)
+
+	term.StartSpinner(fmt.Sprintf("⬆️  Pushing %s to %s...", head, prRemote))
+	err = lib.GitPush(fs.ProjectRoot, prRemote, head)
+	term.StopSpinner()
+	if err != nil {
+		term.OutputErrorAndExitWithCode(term.ExitNetwork, "Error pushing branch: %v", err)
+	}
+
+	term.StartSpinner("")
+	url, err := lib.CreatePullRequest(remote, lib.PullRequestParams{
+		Title: title,
+		Body:  body,
+		Head:  head,
+		Base:  base,
+		Draft: prDraft,
+	})
+	term.StopSpinner()
+	if err != nil {
+		term.OutputErrorAndExit("Error opening pull request: %v", err)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(map[string]string{"url": url, "head": head, "base": base})
+		return
+	}
+
+	label := "Pull request"
+	if remote.Kind == lib.PrHostGitlab {
+		label = "Merge request"
+	}
+	fmt.Printf("✅ %s opened for %s → %s\n", label, head, base)
+	fmt.Println(url)
+}
@@ -9,6 +9,7 @@ import (
 	"plandex/lib"
 	"plandex/term"
 
+	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
 	"github.com/plandex/plandex/shared"
 	"github.com/spf13/cobra"
@@ -16,7 +17,7 @@ import (
 
 var psCmd = &cobra.Command{
 	Use:   "ps",
-	Short: "List plans with active or recently finished streams",
+	Short: "List plans with active or recently finished streams, and work queued while offline",
 	Run:   ps,
 }
 
@@ -28,6 +29,9 @@ func ps(cmd *cobra.Command, args []string) {
 	auth.MustResolveAuthWithOrg()
 	lib.MustResolveProject()
 
+	// queued work is shown even when the server can't be reached
+	numQueued := printQueued()
+
 	if lib.CurrentPlanId == "" {
 		fmt.Println("🤷‍♂️ No current plan")
 		return
@@ -38,12 +42,19 @@ func ps(cmd *cobra.Command, args []string) {
 	term.StopSpinner()
 
 	if apiErr != nil {
+		if lib.IsOfflineErr(apiErr) {
+			term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Couldn't reach the server to list streams: %v", apiErr.Msg)
+		}
 		term.OutputErrorAndExit("Error getting running plans: %v", apiErr)
 		return
 	}
 
 	if len(res.Branches) == 0 {
 		fmt.Println("🤷‍♂️ No active or recently finished streams")
+		if numQueued > 0 {
+			fmt.Println()
+			term.PrintCmds("", "sync")
+		}
 		return
 	}
 
@@ -77,7 +88,7 @@ func ps(cmd *cobra.Command, args []string) {
 		}
 
 		var style []tablewriter.Colors
-		if b.Name == lib.CurrentPlanId {
+		if b.PlanId == lib.CurrentPlanId && b.Name == lib.CurrentBranch {
 			style = []tablewriter.Colors{
 				{tablewriter.FgGreenColor, tablewriter.Bold},
 			}
@@ -87,12 +98,54 @@ func ps(cmd *cobra.Command, args []string) {
 			}
 		}
 
-		table.Rich(row, style)
+		table.Rich(row, term.TableColors(style))
 
 	}
 	table.Render()
 
 	fmt.Println()
-	term.PrintCmds("", "connect", "stop")
+	if numQueued > 0 {
+		term.PrintCmds("", "connect", "stop", "sync")
+	} else {
+		term.PrintCmds("", "connect", "stop")
+	}
+}
+
+// printQueued shows the context loads and prompts waiting to be sent, and returns how many there are
+func printQueued() int {
+	ops, err := lib.ListQueuedOps()
+	if err != nil {
+		term.OutputErrorAndExit("Error listing queued items: %v", err)
+	}
+
+	if len(ops) == 0 {
+		return 0
+	}
+
+	color.New(color.Bold, term.ColorHiYellow).Println(term.Plain("📴 Queued while offline"))
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Id", "Plan", "Branch", "Queued", "Action", "Status"})
+
+	for _, op := range ops {
+		plan := op.PlanId
+		if op.PlanId == lib.CurrentPlanId {
+			plan = "current"
+		} else if len(plan) > 8 {
+			plan = plan[:8]
+		}
+
+		status := "Waiting to send"
+		if op.Error != "" {
+			status = "Failed: " + op.Error
+		}
+
+		table.Append([]string{op.Id, plan, op.Branch, format.Time(op.QueuedAt), lib.QueuedOpDesc(op), status})
+	}
+
+	table.Render()
+	fmt.Println()
 
+	return len(ops)
 }
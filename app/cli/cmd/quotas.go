@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/format"
+	"plandex/term"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var quotaRequestsPerMinute int
+var quotaTokensPerDay int
+var quotaConcurrentStreams int
+
+var quotasCmd = &cobra.Command{
+	Use:   "quotas",
+	Short: "List the org's quota and its users' quotas",
+	Args:  cobra.NoArgs,
+	Run:   listQuotas,
+}
+
+var quotasSetCmd = &cobra.Command{
+	Use:   "set [email]",
+	Short: "Set the org's quota, or a user's quota if an email is given",
+	Long: `Set the org's quota, which limits everyone in the org together, or a user's quota, which limits them on top of the org's.
+
+Only the limits that are passed are changed. A limit of 0 removes it. Tokens are model input and output tokens, counted per UTC day.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  setQuota,
+}
+
+func init() {
+	RootCmd.AddCommand(quotasCmd)
+	quotasCmd.AddCommand(quotasSetCmd)
+
+	quotasSetCmd.Flags().IntVar(&quotaRequestsPerMinute, "requests-per-minute", 0, "Max API requests per minute")
+	quotasSetCmd.Flags().IntVar(&quotaTokensPerDay, "tokens-per-day", 0, "Max model tokens per day")
+	quotasSetCmd.Flags().IntVar(&quotaConcurrentStreams, "concurrent-streams", 0, "Max plans streaming at once")
+}
+
+func listQuotas(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+
+	term.StartSpinner("")
+	quotas, apiErr := api.Client.ListQuotas()
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error listing quotas: %v", apiErr.Msg)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(quotas)
+		return
+	}
+
+	if len(quotas) == 0 {
+		fmt.Println("🤷‍♂️ No quotas")
+		fmt.Println()
+		term.PrintCmds("", "quotas set")
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"For", "Requests/Min", "Tokens/Day", "Streams", "Updated"})
+
+	for _, quota := range quotas {
+		forLabel := "org"
+		if quota.UserEmail != "" {
+			forLabel = quota.UserEmail
+		}
+
+		table.Append([]string{forLabel, quotaLimitLabel(quota.RequestsPerMinute), quotaLimitLabel(quota.TokensPerDay), quotaLimitLabel(quota.ConcurrentStreams), format.Time(quota.UpdatedAt)})
+	}
+
+	table.Render()
+
+	fmt.Println()
+	term.PrintCmds("", "quotas set")
+}
+
+func setQuota(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+
+	req := shared.SetQuotaRequest{}
+	if len(args) > 0 {
+		req.UserEmail = args[0]
+	}
+
+	if cmd.Flags().Changed("requests-per-minute") {
+		req.RequestsPerMinute = &quotaRequestsPerMinute
+	}
+	if cmd.Flags().Changed("tokens-per-day") {
+		req.TokensPerDay = &quotaTokensPerDay
+	}
+	if cmd.Flags().Changed("concurrent-streams") {
+		req.ConcurrentStreams = &quotaConcurrentStreams
+	}
+
+	if req.RequestsPerMinute == nil && req.TokensPerDay == nil && req.ConcurrentStreams == nil {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "Pass at least one of --requests-per-minute, --tokens-per-day, or --concurrent-streams")
+	}
+
+	term.StartSpinner("")
+	quota, apiErr := api.Client.SetQuota(req)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error setting quota: %v", apiErr.Msg)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(quota)
+		return
+	}
+
+	forLabel := "the org"
+	if req.UserEmail != "" {
+		forLabel = req.UserEmail
+	}
+
+	if quota == nil {
+		fmt.Printf("✅ Removed quota for %s\n", forLabel)
+		return
+	}
+
+	fmt.Printf("✅ Set quota for %s: %s requests per minute, %s tokens per day, %s concurrent streams\n", forLabel, quotaLimitLabel(quota.RequestsPerMinute), quotaLimitLabel(quota.TokensPerDay), quotaLimitLabel(quota.ConcurrentStreams))
+}
+
+func quotaLimitLabel(limit *int) string {
+	if limit == nil {
+		return "unlimited"
+	}
+	return strconv.Itoa(*limit)
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/fs"
+	"plandex/lib"
+	"plandex/plan_exec"
+	"plandex/term"
+	"plandex/types"
+
+	"github.com/fatih/color"
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var refactorLimit int
+var refactorYes bool
+var refactorNoMap bool
+
+var refactorCmd = &cobra.Command{
+	Use:   "refactor <instruction>",
+	Short: "Find the files a change affects across the project, load them, and make it",
+	Long: `Find the files a change affects across the project, load them into context, and send the change to the plan.
+
+Names in the instruction that are defined in the project, like functions and types, select the files that define them and the files that reference them. A search of the project for the instruction adds other relevant files. A map of the definitions across the project is loaded too, unless --no-map is set.`,
+	Args: cobra.ExactArgs(1),
+	Run:  refactor,
+}
+
+func init() {
+	RootCmd.AddCommand(refactorCmd)
+
+	refactorCmd.Flags().IntVarP(&refactorLimit, "limit", "n", 20, "Maximum number of files to load")
+	refactorCmd.Flags().BoolVarP(&refactorYes, "yes", "y", false, "Load the files without confirming")
+	refactorCmd.Flags().BoolVar(&refactorNoMap, "no-map", false, "Don't load a map of the project's definitions")
+	refactorCmd.Flags().BoolVarP(&tellStop, "stop", "s", false, "Stop after a single reply")
+	refactorCmd.Flags().BoolVar(&tellNoBuild, "no-build", false, "Don't build files")
+	refactorCmd.Flags().BoolVar(&tellBg, "bg", false, "Execute autonomously in the background")
+}
+
+func refactor(cmd *cobra.Command, args []string) {
+	tellBg = term.RunInBackground(tellBg)
+
+	if os.Getenv("OPENAI_API_KEY") == "" {
+		term.OutputNoApiKeyMsgAndExit()
+	}
+
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	lib.MustCheckBudget()
+
+	instruction := args[0]
+
+	term.StartSpinner("🔎 Finding affected files...")
+
+	files, hasDefs, err := lib.FindRefactorFiles(context.Background(), instruction, refactorLimit)
+	if err != nil {
+		term.StopSpinner()
+		term.OutputErrorAndExit("Error finding affected files: %v", err)
+	}
+
+	contexts, apiErr := api.Client.ListContext(lib.CurrentPlanId, lib.CurrentBranch)
+	if apiErr != nil {
+		term.StopSpinner()
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error retrieving context: %v", apiErr.Msg)
+	}
+
+	term.StopSpinner()
+
+	loadedPaths := map[string]bool{}
+	hasMap := false
+	for _, context := range contexts {
+		if context.FilePath != "" {
+			loadedPaths[filepath.Clean(context.FilePath)] = true
+		}
+		if context.ContextType == shared.ContextMapType {
+			hasMap = true
+		}
+	}
+
+	if len(files) == 0 {
+		fmt.Println("🤷‍♂️ No affected files found. Load them yourself and send the change with tell.")
+		fmt.Println()
+		term.PrintCmds("", "load", "tell")
+		return
+	}
+
+	var toLoad []string
+	fmt.Println("🔎 Files affected by the refactor:")
+	for _, file := range files {
+		// paths are relative to the project root, and context is loaded relative to the current dir
+		path := file.Path
+		if rel, err := filepath.Rel(fs.Cwd, filepath.Join(fs.ProjectRoot, file.Path)); err == nil {
+			path = rel
+		}
+
+		inContext := loadedPaths[filepath.Clean(file.Path)] || loadedPaths[filepath.Clean(path)]
+		suffix := ""
+		if inContext {
+			suffix = ", in context"
+		} else {
+			toLoad = append(toLoad, path)
+		}
+		fmt.Printf("  • %s %s\n", file.Path, color.New(color.FgHiBlack).Sprintf("(%s%s)", file.Reason, suffix))
+	}
+	fmt.Println()
+
+	loadMap := !refactorNoMap && !hasMap && hasDefs
+
+	if !refactorYes && !term.NonInteractive && (len(toLoad) > 0 || loadMap) {
+		confirmed, err := term.ConfirmYesNo("Load these files and start the refactor?")
+		if err != nil {
+			term.OutputErrorAndExit("Error getting confirmation user input: %v", err)
+		}
+		if !confirmed {
+			return
+		}
+	}
+
+	if len(toLoad) > 0 {
+		lib.MustLoadContext(toLoad, &types.LoadContextParams{})
+		fmt.Println()
+	}
+
+	if loadMap {
+		root, err := filepath.Rel(fs.Cwd, fs.ProjectRoot)
+		if err != nil {
+			root = fs.ProjectRoot
+		}
+		lib.MustLoadContext([]string{root}, &types.LoadContextParams{Map: true, Recursive: true})
+		fmt.Println()
+	}
+
+	loaded := "The files this is likely to affect are loaded in context."
+	if loadMap || hasMap {
+		loaded = "The files this is likely to affect are loaded in context, along with a map of the definitions across the project."
+	}
+	prompt := fmt.Sprintf("Refactor the project: %s\n\n%s Make the change consistently in every file that needs it, including call sites and other references. If files that aren't loaded also need changes, say which ones.", instruction, loaded)
+
+	params := plan_exec.ExecParams{
+		CurrentPlanId: lib.CurrentPlanId,
+		CurrentBranch: lib.CurrentBranch,
+		CheckOutdatedContext: func(maybeContexts []*shared.Context) (bool, bool) {
+			return lib.MustCheckOutdatedContextBeforePrompt(maybeContexts)
+		},
+	}
+
+	plan_exec.TellPlan(params, prompt, tellBg, tellStop, tellNoBuild, false)
+}
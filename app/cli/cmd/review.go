@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/fs"
+	"plandex/lib"
+	"plandex/term"
+	"plandex/types"
+	"sort"
+	"strconv"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var reviewNoLoad bool
+
+var reviewCmd = &cobra.Command{
+	Use:   "review <range>",
+	Short: "Review the changes in a range of git commits",
+	Long: `Review the changes in a range of git commits for bugs, security problems, and style, with each finding mapped to a file and line.
+
+The range is used as with git diff, like main..feature or abc123..def456. A single branch, like a PR branch, is compared with the current branch from where they diverged.
+
+The diff is also loaded into context, so you can ask about it or have the plan fix the findings with 'plandex tell'. With --json, the review is output as JSON for CI annotations.`,
+	Args: cobra.ExactArgs(1),
+	Run:  review,
+}
+
+func init() {
+	RootCmd.AddCommand(reviewCmd)
+
+	reviewCmd.Flags().BoolVar(&reviewNoLoad, "no-load", false, "Don't load the diff into context")
+}
+
+var reviewSeverityOrder = map[shared.ReviewSeverity]int{
+	shared.ReviewSeverityHigh:   0,
+	shared.ReviewSeverityMedium: 1,
+	shared.ReviewSeverityLow:    2,
+}
+
+func review(cmd *cobra.Command, args []string) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		term.OutputNoApiKeyMsgAndExit()
+	}
+
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	if !fs.ProjectRootIsGitRepo() {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "review needs the project to be in a git repo")
+	}
+
+	rng := args[0]
+
+	log, diff, err := lib.GitReviewRange(fs.ProjectRoot, rng)
+	if err != nil {
+		term.OutputErrorAndExit("Error getting changes: %v", err)
+	}
+
+	if diff == "" {
+		if term.JsonOutput {
+			term.OutputJson(&shared.ReviewResponse{Range: rng, Findings: []*shared.ReviewFinding{}})
+			return
+		}
+		fmt.Printf("🤷‍♂️ No changes in %s\n", rng)
+		return
+	}
+
+	if !reviewNoLoad {
+		mustLoadReviewDiff(rng, log, diff)
+	}
+
+	term.StartSpinner("🧐 Reviewing...")
+	res, apiErr := api.Client.Review(lib.CurrentPlanId, lib.CurrentBranch, shared.ReviewRequest{
+		Range:  rng,
+		Log:    log,
+		Diff:   lib.NumberDiffLines(diff),
+		ApiKey: apiKey,
+	})
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error reviewing changes: %s", apiErr.Msg)
+	}
+
+	sort.SliceStable(res.Findings, func(i, j int) bool {
+		a, b := res.Findings[i], res.Findings[j]
+		if reviewSeverityOrder[a.Severity] != reviewSeverityOrder[b.Severity] {
+			return reviewSeverityOrder[a.Severity] < reviewSeverityOrder[b.Severity]
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+
+	if term.JsonOutput {
+		term.OutputJson(res)
+		return
+	}
+
+	fmt.Println(res.Summary)
+	fmt.Println()
+
+	if len(res.Findings) == 0 {
+		fmt.Println("✅ No problems found")
+	} else {
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetAutoWrapText(true)
+		table.SetHeader([]string{"Location", "Severity", "Category", "Finding"})
+		for _, finding := range res.Findings {
+			location := finding.Path
+			if finding.Line > 0 {
+				location += ":" + strconv.Itoa(finding.Line)
+			}
+
+			severityColor := term.ColorHiCyan
+			switch finding.Severity {
+			case shared.ReviewSeverityHigh:
+				severityColor = term.ColorHiRed
+			case shared.ReviewSeverityMedium:
+				severityColor = term.ColorHiYellow
+			}
+
+			table.Append([]string{
+				location,
+				color.New(severityColor).Sprint(string(finding.Severity)),
+				string(finding.Category),
+				finding.Message,
+			})
+		}
+		table.Render()
+	}
+
+	if !reviewNoLoad {
+		fmt.Println()
+		term.PrintCmds("", "tell")
+	}
+}
+
+// mustLoadReviewDiff loads the changes being reviewed into context. The review itself doesn't depend on it, so if the diff
+// is too big for the context limit, the review goes ahead without it.
+func mustLoadReviewDiff(rng, log, diff string) {
+	note := fmt.Sprintf("Changes in %s:\n\nCommits:\n```\n%s\n```\n\n```diff\n%s\n```", rng, log, diff)
+
+	term.StartSpinner("📥 Loading diff...")
+	res, err := lib.LoadContext(context.Background(), nil, &types.LoadContextParams{Note: note})
+	term.StopSpinner()
+
+	if err != nil {
+		term.OutputErrorAndExit("Failed to load diff: %v", err)
+	}
+
+	if res.Res != nil && res.Res.MaxTokensExceeded {
+		fmt.Fprintln(os.Stderr, color.New(color.Bold, term.ColorHiYellow).Sprint(term.Plain("⚠️  The diff is too big to load into context, so it's only reviewed")))
+		fmt.Fprintln(os.Stderr)
+		return
+	}
+
+	if !term.JsonOutput {
+		fmt.Println("✅ Loaded the diff into context")
+		fmt.Println()
+	}
+}
@@ -20,6 +20,8 @@ var rewindCmd = &cobra.Command{
 	Long: `Rewind the plan to an earlier state.
 	
 	You can pass a "steps" number or a commit sha. If a steps number is passed, the plan will be rewound that many steps. If a commit sha is passed, the plan will be rewound to that commit. If neither a steps number nor a commit sha is passed, the target scope will be rewound by 1 step.
+
+	To change an earlier prompt and replay the plan from there, use 'plandex edit-prompt' instead.
 	`,
 	Args: cobra.MaximumNArgs(1),
 	Run:  rewind,
@@ -62,6 +64,10 @@ func rewind(cmd *cobra.Command, args []string) {
 
 	if err == nil && steps > 0 && steps < 999 {
 		// log.Println("steps:", steps)
+		if steps >= len(logsRes.Shas) {
+			term.StopSpinner()
+			term.OutputErrorAndExitWithCode(term.ExitUsage, "Can't rewind %d steps, the plan only has %d earlier states", steps, len(logsRes.Shas)-1)
+		}
 		// Rewind by the specified number of steps
 		targetSha = logsRes.Shas[steps]
 	} else if sha := stepsOrSha; sha != "" {
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"path/filepath"
 	"plandex/api"
 	"plandex/auth"
 	"plandex/lib"
@@ -41,30 +40,13 @@ func contextRm(cmd *cobra.Command, args []string) {
 
 	for i, context := range contexts {
 		for _, id := range args {
-			if fmt.Sprintf("%d", i+1) == id || context.Name == id || context.FilePath == id || context.Url == id {
+			matched, err := lib.ContextMatchesArg(i, context, id)
+			if err != nil {
+				term.OutputErrorAndExit("Error matching glob pattern: %v", err)
+			}
+			if matched {
 				deleteIds[context.Id] = true
 				break
-			} else if context.FilePath != "" {
-				// Check if id is a glob pattern
-				matched, err := filepath.Match(id, context.FilePath)
-				if err != nil {
-					term.OutputErrorAndExit("Error matching glob pattern: %v", err)
-				}
-				if matched {
-					deleteIds[context.Id] = true
-					break
-				}
-
-				// Check if id is a parent directory
-				parentDir := context.FilePath
-				for parentDir != "." && parentDir != "/" && parentDir != "" {
-					if parentDir == id {
-						deleteIds[context.Id] = true
-						break
-					}
-					parentDir = filepath.Dir(parentDir) // Move up one directory
-				}
-
 			}
 		}
 	}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex/format"
+	"plandex/lib"
+	"plandex/term"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var rollbackList bool
+var rollbackYes bool
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [index-or-id]",
+	Short: "Restore project files to how they were before an apply",
+	Long: `Restore project files to how they were before an apply.
+
+Pass the index of an apply from 'plandex rollback --list' (1 is the latest) or its id. Any applies after it are rolled back too. With no argument, you can choose from a list of recent applies.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  rollback,
+}
+
+func init() {
+	RootCmd.AddCommand(rollbackCmd)
+
+	rollbackCmd.Flags().BoolVarP(&rollbackList, "list", "l", false, "List recent applies that can be rolled back")
+	rollbackCmd.Flags().BoolVarP(&rollbackYes, "yes", "y", false, "Roll back without confirmation, even if files were changed since the apply")
+}
+
+func rollback(cmd *cobra.Command, args []string) {
+	lib.MustResolveProject()
+
+	txs, err := lib.ListApplyTransactions()
+	if err != nil {
+		term.OutputErrorAndExit("Error listing applies: %v", err)
+	}
+
+	if rollbackList {
+		listApplies(txs)
+		return
+	}
+
+	if len(txs) == 0 {
+		fmt.Println("🤷‍♂️ No applies to roll back")
+		return
+	}
+
+	var idx int
+	if len(args) > 0 {
+		idx = applyIndex(txs, strings.TrimSpace(args[0]))
+	} else {
+		opts := make([]string, len(txs))
+		for i, tx := range txs {
+			opts[i] = fmt.Sprintf("%d. %s • %s", i+1, format.Time(tx.CreatedAt), applyFilesLabel(tx))
+		}
+
+		sel, err := term.SelectFromList("Select an apply to roll back", opts)
+		if err != nil {
+			term.OutputErrorAndExit("Error selecting apply: %v", err)
+		}
+
+		for i, opt := range opts {
+			if opt == sel {
+				idx = i
+				break
+			}
+		}
+	}
+
+	mustRollbackApplies(txs[:idx+1], rollbackYes)
+}
+
+// mustRollbackApplies rolls back the given applies, ordered newest first, after confirming
+func mustRollbackApplies(txs []*lib.ApplyTransaction, autoConfirm bool) {
+	if len(txs) > 1 {
+		fmt.Printf("⏪ The %d applies after this one will be rolled back too\n\n", len(txs)-1)
+	}
+
+	modified, err := lib.ModifiedSinceApply(txs)
+	if err != nil {
+		term.OutputErrorAndExit("Error checking files: %v", err)
+	}
+
+	if len(modified) > 0 {
+		fmt.Fprintln(os.Stderr, color.New(color.Bold, term.ColorHiYellow).Sprint(term.Plain("⚠️  These files were changed after the apply, and rolling back will discard those changes:")))
+		for _, path := range modified {
+			fmt.Fprintln(os.Stderr, "  • "+path)
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if !autoConfirm {
+		numFiles := 0
+		seen := map[string]bool{}
+		for _, tx := range txs {
+			for _, file := range tx.Files {
+				if !seen[file.Path] {
+					seen[file.Path] = true
+					numFiles++
+				}
+			}
+		}
+
+		suffix := ""
+		if numFiles > 1 {
+			suffix = "s"
+		}
+		confirmed, err := term.ConfirmYesNo("Restore %d file%s to before the apply?", numFiles, suffix)
+		if err != nil {
+			term.OutputErrorAndExit("failed to get confirmation user input: %s", err)
+		}
+
+		if !confirmed {
+			fmt.Println("Rollback canceled")
+			return
+		}
+	}
+
+	restored, err := lib.RollbackApplies(txs)
+	if err != nil {
+		term.OutputErrorAndExit("Error rolling back: %v", err)
+	}
+
+	suffix := ""
+	if len(restored) > 1 {
+		suffix = "s"
+	}
+	fmt.Printf("⏪ Rolled back, %d file%s restored\n", len(restored), suffix)
+	for _, path := range restored {
+		fmt.Println("  • " + path)
+	}
+}
+
+func applyIndex(txs []*lib.ApplyTransaction, indexOrId string) int {
+	idx, err := strconv.Atoi(indexOrId)
+	if err == nil {
+		if idx < 1 || idx > len(txs) {
+			term.OutputErrorAndExitWithCode(term.ExitUsage, "Apply index out of range")
+		}
+		return idx - 1
+	}
+
+	for i, tx := range txs {
+		if tx.Id == indexOrId {
+			return i
+		}
+	}
+
+	term.OutputErrorAndExitWithCode(term.ExitUsage, "No apply with id %s", indexOrId)
+	return -1
+}
+
+func listApplies(txs []*lib.ApplyTransaction) {
+	if term.JsonOutput {
+		if txs == nil {
+			txs = []*lib.ApplyTransaction{}
+		}
+		term.OutputJson(txs)
+		return
+	}
+
+	if len(txs) == 0 {
+		fmt.Println("🤷‍♂️ No applies to roll back")
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"#", "Id", "Applied", "Branch", "Files"})
+
+	for i, tx := range txs {
+		planLbl := tx.Branch
+		if tx.PlanId != lib.CurrentPlanId {
+			planLbl += " (other plan)"
+		}
+
+		table.Append([]string{
+			strconv.Itoa(i + 1),
+			tx.Id,
+			format.Time(tx.CreatedAt),
+			planLbl,
+			applyFilesLabel(tx),
+		})
+	}
+
+	table.Render()
+	fmt.Println()
+	term.PrintCmds("", "rollback")
+}
+
+func applyFilesLabel(tx *lib.ApplyTransaction) string {
+	label := tx.Files[0].Path
+	if len(tx.Files) > 1 {
+		label = fmt.Sprintf("%s and %d more", tx.Files[0].Path, len(tx.Files)-1)
+	}
+	if tx.Partial {
+		label += " (stopped partway)"
+	}
+	return label
+}
@@ -1,8 +1,10 @@
 package cmd
 
 import (
-	"log"
+	"log/slog"
+	"os"
 
+	"plandex/logging"
 	"plandex/term"
 
 	"github.com/spf13/cobra"
@@ -22,14 +24,39 @@ var RootCmd = &cobra.Command{
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
 		// term.OutputErrorAndExit("Error executing root command: %v", err)
-		log.Fatalf("Error executing root command: %v", err)
+		slog.Error("Error executing root command", "error", err)
+		os.Exit(term.ExitUsage)
 	}
 }
 
 func run(cmd *cobra.Command, args []string) {
 }
 
+var logVerbose bool
+var logDebug bool
+
 func init() {
+	RootCmd.PersistentFlags().BoolVar(&logVerbose, "verbose", false, "Show logs on stderr as well as in the log file")
+	RootCmd.PersistentFlags().BoolVar(&logDebug, "debug", os.Getenv(logging.DebugEnvVar) != "", "Show debug logs, like each API request, on stderr and in the log file (also set by "+logging.DebugEnvVar+"=1)")
+	RootCmd.PersistentFlags().BoolVar(&term.JsonOutput, "json", term.JsonOutput, "Output results as JSON on stdout, with everything else on stderr (also set by PLANDEX_OUTPUT=json)")
+	RootCmd.PersistentFlags().BoolVarP(&term.Quiet, "quiet", "q", false, "Don't show spinners, suggested commands, or emoji")
+	RootCmd.PersistentFlags().BoolVar(&term.NoColor, "no-color", term.NoColor, "Turn off colored output (also set by NO_COLOR)")
+	RootCmd.PersistentFlags().BoolVar(&term.NonInteractive, "ci", term.NonInteractive, "Non-interactive mode for CI: no prompts or spinners, JSON output, and documented exit codes (also set by PLANDEX_NONINTERACTIVE)")
+	RootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		logging.SetVerbosity(logVerbose, logDebug)
+		slog.Debug("Running command", "command", cmd.CommandPath())
+
+		if term.NonInteractive {
+			term.StartNonInteractive()
+		}
+		if term.JsonOutput {
+			term.StartJsonOutput()
+		}
+		if term.NoColor {
+			term.StartNoColor()
+		}
+	}
+
 	var helpCmd = &cobra.Command{
 		Use:     "help",
 		Aliases: []string{"h"},
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"plandex/auth"
+	"plandex/lib"
+	"plandex/term"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var searchLimit int
+var searchCurrent bool
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search the conversations and context of the project's plans",
+	Long:  `Search the conversation messages and context of every plan in the project for all the words in a query. Words match case-insensitively, and also match longer words they start. The first search indexes the project's plans, and later searches only re-index plans that changed. The index is stored in the .plandex directory.`,
+	Args:  cobra.MinimumNArgs(1),
+	Run:   search,
+}
+
+func init() {
+	RootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 20, "Maximum number of matches to show")
+	searchCmd.Flags().BoolVar(&searchCurrent, "current", false, "Only search the current plan")
+}
+
+func search(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	var planId string
+	if searchCurrent {
+		if lib.CurrentPlanId == "" {
+			fmt.Println("🤷‍♂️ No current plan")
+			return
+		}
+		planId = lib.CurrentPlanId
+	}
+
+	query := strings.Join(args, " ")
+
+	term.StartSpinner("🔎 Searching...")
+	results, err := lib.SearchConvos(query, planId, searchLimit)
+	term.StopSpinner()
+
+	if err != nil {
+		term.OutputErrorAndExit("Error searching: %v", err)
+	}
+
+	if term.JsonOutput {
+		if results == nil {
+			results = []*lib.ConvoSearchResult{}
+		}
+		term.OutputJson(results)
+		return
+	}
+
+	if len(results) == 0 {
+		fmt.Println("🤷‍♂️ No matches")
+		return
+	}
+
+	highlightRe := lib.ConvoSearchMatchRegexp(query)
+	highlight := color.New(color.Bold, term.ColorHiYellow).SprintFunc()
+
+	for _, result := range results {
+		var location string
+		if result.Kind == lib.ConvoSearchMessage {
+			author := result.Role
+			if result.Role == "assistant" {
+				author = "🤖 Plandex"
+			} else if result.Role == "user" {
+				author = "💬 You"
+			}
+			location = fmt.Sprintf("#%d %s", result.MessageNum, author)
+		} else {
+			_, icon := lib.GetContextTypeAndIcon(&shared.Context{ContextType: result.ContextType})
+			location = fmt.Sprintf("%s %s", strings.TrimSpace(icon), result.ContextName)
+		}
+
+		plan := result.Plan
+		if result.Branch != "main" {
+			plan += " (" + result.Branch + ")"
+		}
+
+		fmt.Printf("%s | %s | %s\n",
+			color.New(color.Bold, term.ColorHiCyan).Sprint(plan),
+			location,
+			result.CreatedAt.Local().Format("Jan 2, 2006 3:04pm"),
+		)
+		fmt.Println("  " + highlightRe.ReplaceAllStringFunc(result.Snippet, func(s string) string { return highlight(s) }))
+		fmt.Println()
+	}
+
+	if searchCurrent {
+		term.PrintCmds("", "convo", "ls")
+	} else {
+		term.PrintCmds("", "cd", "convo", "ls")
+	}
+}
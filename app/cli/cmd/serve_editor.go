@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plandex/auth"
+	editorserver "plandex/editor_server"
+	"plandex/fs"
+	"plandex/lib"
+	"plandex/term"
+
+	"github.com/spf13/cobra"
+)
+
+var serveEditorSocket string
+
+var serveEditorCmd = &cobra.Command{
+	Use:   "serve-editor",
+	Short: "Serve a JSON-RPC API for editor plugins over a unix socket",
+	Long: `Serve a JSON-RPC 2.0 API for editor plugins over a unix socket, so they can drive the current plan without running commands and reading terminal output.
+
+Messages are one JSON object per line. Methods are plan.current, context.list, context.load, plan.tell, plan.stop, plan.respondMissingFile, changes.list, and changes.apply. While a prompt streams, its messages are sent as plan.stream notifications.
+
+The socket is .plandex/editor.sock in the project unless --socket is set. The server runs until it's interrupted.`,
+	Args: cobra.NoArgs,
+	Run:  serveEditor,
+}
+
+func init() {
+	RootCmd.AddCommand(serveEditorCmd)
+
+	serveEditorCmd.Flags().StringVar(&serveEditorSocket, "socket", "", "Path of the unix socket to listen on")
+}
+
+func serveEditor(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	socketPath := serveEditorSocket
+	if socketPath == "" {
+		socketPath = filepath.Join(fs.PlandexDir, "editor.sock")
+	}
+
+	// editors usually start the server with a pipe for stdin, which loading context would otherwise read as piped data
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		term.OutputErrorAndExit("Error opening %s: %v", os.DevNull, err)
+	}
+	os.Stdin = devNull
+
+	fmt.Printf("🔌 Serving editor API on %s\n", socketPath)
+
+	err = editorserver.Serve(socketPath)
+	if err != nil {
+		term.OutputErrorAndExit("Editor server error: %v", err)
+	}
+}
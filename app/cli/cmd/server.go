@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"plandex/auth"
+	"plandex/fs"
+	"plandex/term"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// the plandex-server binary to run, if it isn't on PATH
+const serverBinEnvVar = "PLANDEX_SERVER_BIN"
+
+var serverPort int
+
+var serverCmd = &cobra.Command{
+	Use:   "server",
+	Short: "Run a local Plandex server",
+	Long: `Run a Plandex server on this machine, for self-hosting without any infrastructure. The server stores its data in a SQLite database and local files under ~/.plandex-home/server, so it doesn't need Postgres.
+
+The plandex-server binary must be on PATH, or set in PLANDEX_SERVER_BIN. There's no email setup, so sign-in pins are written to the server's log and copied to the clipboard.`,
+}
+
+var serverStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start a local server in the background",
+	Args:  cobra.NoArgs,
+	Run:   serverStart,
+}
+
+var serverStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the local server",
+	Args:  cobra.NoArgs,
+	Run:   serverStop,
+}
+
+var serverStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the local server is running",
+	Args:  cobra.NoArgs,
+	Run:   serverStatus,
+}
+
+func init() {
+	RootCmd.AddCommand(serverCmd)
+	serverCmd.AddCommand(serverStartCmd)
+	serverCmd.AddCommand(serverStopCmd)
+	serverCmd.AddCommand(serverStatusCmd)
+
+	serverStartCmd.Flags().IntVarP(&serverPort, "port", "p", 8088, "Port to listen on")
+}
+
+func serverDir() string {
+	return filepath.Join(fs.HomePlandexDir, "server")
+}
+
+func serverPidPath() string {
+	return filepath.Join(serverDir(), "server.pid")
+}
+
+func serverLogPath() string {
+	return filepath.Join(serverDir(), "server.log")
+}
+
+func serverStart(cmd *cobra.Command, args []string) {
+	pid, port := getLocalServer()
+	if pid != 0 {
+		fmt.Printf("✅ Server is already running at http://localhost:%d\n", port)
+		return
+	}
+
+	bin := os.Getenv(serverBinEnvVar)
+	if bin == "" {
+		var err error
+		bin, err = exec.LookPath("plandex-server")
+		if err != nil {
+			term.OutputErrorAndExit("plandex-server isn't on PATH. Install it, or set %s to its path.", serverBinEnvVar)
+		}
+	}
+
+	dir := serverDir()
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		term.OutputErrorAndExit("Error creating server dir: %v", err)
+	}
+
+	logFile, err := os.OpenFile(serverLogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		term.OutputErrorAndExit("Error opening server log: %v", err)
+	}
+	defer logFile.Close()
+
+	serverProc := exec.Command(bin)
+	serverProc.Env = append(os.Environ(),
+		"DATABASE_URL=sqlite://"+filepath.Join(dir, "plandex.db"),
+		"PLANDEX_BASE_DIR="+filepath.Join(dir, "plans"),
+		"PORT="+strconv.Itoa(serverPort),
+		// development mode writes sign-in pins to the log instead of emailing them, and uses localhost as the host's ip
+		"GOENV=development",
+	)
+	serverProc.Stdout = logFile
+	serverProc.Stderr = logFile
+	serverProc.SysProcAttr = detachedSysProcAttr()
+
+	err = serverProc.Start()
+	if err != nil {
+		term.OutputErrorAndExit("Error starting plandex-server: %v", err)
+	}
+
+	err = os.WriteFile(serverPidPath(), []byte(fmt.Sprintf("%d %d", serverProc.Process.Pid, serverPort)), 0600)
+	if err != nil {
+		term.OutputErrorAndExit("Error writing server pid file: %v", err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		serverProc.Wait()
+		close(exited)
+	}()
+
+	term.StartSpinner("🚀 Starting server...")
+	deadline := time.Now().Add(30 * time.Second)
+	for !isServerHealthy(serverPort) {
+		select {
+		case <-exited:
+			term.StopSpinner()
+			os.Remove(serverPidPath())
+			term.OutputErrorAndExit("Server exited on start. See %s", serverLogPath())
+		case <-time.After(200 * time.Millisecond):
+		}
+
+		if time.Now().After(deadline) {
+			term.StopSpinner()
+			term.OutputErrorAndExit("Server didn't start within 30 seconds. See %s", serverLogPath())
+		}
+	}
+	term.StopSpinner()
+
+	fmt.Printf("✅ Server is running at http://localhost:%d\n", serverPort)
+	fmt.Println()
+	fmt.Printf("To use it, run 'plandex sign-in', choose '%s', and enter http://localhost:%d as the host. Your sign-in pin will be copied to the clipboard, and written to %s.\n", auth.SignInOtherOption, serverPort, serverLogPath())
+	fmt.Println()
+	term.PrintCmds("", "sign-in", "server status", "server stop")
+}
+
+func serverStop(cmd *cobra.Command, args []string) {
+	pid, _ := getLocalServer()
+	if pid == 0 {
+		fmt.Println("🤷‍♂️ Server isn't running")
+		return
+	}
+
+	proc, err := os.FindProcess(pid)
+	if err == nil {
+		// the server waits for active plans to finish before it exits
+		err = stopServerProcess(proc)
+	}
+	if err != nil {
+		term.OutputErrorAndExit("Error stopping server: %v", err)
+	}
+
+	term.StartSpinner("🛑 Stopping server...")
+	for isProcessRunning(pid) {
+		time.Sleep(200 * time.Millisecond)
+	}
+	term.StopSpinner()
+
+	os.Remove(serverPidPath())
+
+	fmt.Println("✅ Server stopped")
+}
+
+func serverStatus(cmd *cobra.Command, args []string) {
+	pid, port := getLocalServer()
+	if pid == 0 {
+		fmt.Println("💤 Server isn't running")
+		fmt.Println()
+		term.PrintCmds("", "server start")
+		return
+	}
+
+	if isServerHealthy(port) {
+		fmt.Printf("✅ Server is running at http://localhost:%d\n", port)
+	} else {
+		fmt.Printf("⚠️  Server is running, but http://localhost:%d/health isn't responding. See %s\n", port, serverLogPath())
+	}
+	fmt.Printf("Data is in %s\n", serverDir())
+	fmt.Println()
+	term.PrintCmds("", "server stop")
+}
+
+// getLocalServer returns the local server's pid and port, or a zero pid if it isn't running
+func getLocalServer() (int, int) {
+	bytes, err := os.ReadFile(serverPidPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			term.OutputErrorAndExit("Error reading server pid file: %v", err)
+		}
+		return 0, 0
+	}
+
+	var pid, port int
+	_, err = fmt.Sscanf(strings.TrimSpace(string(bytes)), "%d %d", &pid, &port)
+	if err != nil || !isProcessRunning(pid) {
+		os.Remove(serverPidPath())
+		return 0, 0
+	}
+
+	return pid, port
+}
+
+func isServerHealthy(port int) bool {
+	client := http.Client{Timeout: time.Second}
+	res, err := client.Get(fmt.Sprintf("http://localhost:%d/health", port))
+	if err != nil {
+		return false
+	}
+	res.Body.Close()
+	return res.StatusCode == http.StatusOK
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/lib"
+	"plandex/term"
+	"strconv"
+
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(setConfigCmd)
+}
+
+var setConfigCmd = &cobra.Command{
+	Use:   "set-config [setting] [value]",
+	Short: "Update plan config",
+	Run:   setConfig,
+	Args:  cobra.MaximumNArgs(2),
+}
+
+func setConfig(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	term.StartSpinner("")
+	settings, apiErr := api.Client.GetSettings(lib.CurrentPlanId, lib.CurrentBranch)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExit("Error getting current settings: %v", apiErr)
+		return
+	}
+
+	var setting, value string
+
+	if len(args) > 0 {
+		for _, s := range shared.ConfigSettingsDasherized {
+			if shared.Compact(s) == shared.Compact(args[0]) {
+				setting = s
+				break
+			}
+		}
+		if setting == "" {
+			fmt.Println("Unknown setting:", args[0])
+			return
+		}
+	} else {
+		var opts []string
+		for _, s := range shared.ConfigSettingsDasherized {
+			opts = append(opts, fmt.Sprintf("%s → %s", s, shared.SettingDescriptions[s]))
+		}
+
+		selection, err := term.SelectFromList("Select a setting to update:", opts)
+		if err != nil {
+			if err.Error() == "interrupt" {
+				return
+			}
+
+			term.OutputErrorAndExit("Error selecting setting: %v", err)
+			return
+		}
+
+		for i, opt := range opts {
+			if opt == selection {
+				setting = shared.ConfigSettingsDasherized[i]
+				break
+			}
+		}
+	}
+
+	if len(args) > 1 {
+		value = args[1]
+	} else {
+		msg := fmt.Sprintf("Set %s (true or false)", setting)
+		if setting == "max-context-tokens" {
+			msg = fmt.Sprintf("Set %s (leave blank to use the model's limit of %d)", setting, settings.GetPlannerModelMaxContextTokens())
+		} else if setting == "max-parallel-builds" {
+			msg = fmt.Sprintf("Set %s (leave blank for the default of %d)", setting, shared.DefaultMaxParallelBuilds)
+		}
+
+		var err error
+		value, err = term.GetUserStringInput(msg)
+		if err != nil {
+			if err.Error() == "interrupt" {
+				return
+			}
+
+			term.OutputErrorAndExit("Error getting value: %v", err)
+			return
+		}
+	}
+
+	switch setting {
+	case "auto-update-context":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			fmt.Println("Invalid value for auto-update-context:", value)
+			return
+		}
+
+		if b == settings.AutoUpdateContext {
+			fmt.Println("🤷‍♂️ No config settings were updated")
+			return
+		}
+
+		settings.AutoUpdateContext = b
+
+	case "max-context-tokens":
+		n := 0
+		if value != "" {
+			var err error
+			n, err = strconv.Atoi(value)
+			if err != nil || n < 0 {
+				fmt.Println("Invalid value for max-context-tokens:", value)
+				return
+			}
+		}
+
+		if n == settings.MaxContextTokens {
+			fmt.Println("🤷‍♂️ No config settings were updated")
+			return
+		}
+
+		if modelMax := settings.GetPlannerModelMaxContextTokens(); n > modelMax {
+			fmt.Printf("⚠️  The planner model's limit is %d 🪙, so that limit will be used instead\n", modelMax)
+		}
+
+		settings.MaxContextTokens = n
+
+	case "max-parallel-builds":
+		n := 0
+		if value != "" {
+			var err error
+			n, err = strconv.Atoi(value)
+			if err != nil || n < 0 {
+				fmt.Println("Invalid value for max-parallel-builds:", value)
+				return
+			}
+		}
+
+		if n == settings.MaxParallelBuilds {
+			fmt.Println("🤷‍♂️ No config settings were updated")
+			return
+		}
+
+		settings.MaxParallelBuilds = n
+	}
+
+	term.StartSpinner("")
+	res, apiErr := api.Client.UpdateSettings(
+		lib.CurrentPlanId,
+		lib.CurrentBranch,
+		shared.UpdateSettingsRequest{
+			Settings: settings,
+		})
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExit("Error updating settings: %v", apiErr)
+		return
+	}
+
+	fmt.Println(res.Msg)
+	fmt.Println()
+	term.PrintCmds("", "config", "log", "rewind")
+}
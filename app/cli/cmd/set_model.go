@@ -16,15 +16,30 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var setModelProvider string
+var setModelBaseUrl string
+var setModelDeployment string
+var setModelApiVersion string
+var setModelRegion string
+
 func init() {
 	RootCmd.AddCommand(modelsSetCmd)
+
+	modelsSetCmd.Flags().StringVar(&setModelProvider, "provider", "", "Provider to call the model through: openai, azure-openai, or bedrock")
+	modelsSetCmd.Flags().StringVar(&setModelBaseUrl, "base-url", "", "Azure OpenAI resource endpoint, like https://my-resource.openai.azure.com")
+	modelsSetCmd.Flags().StringVar(&setModelDeployment, "deployment", "", "Azure OpenAI deployment name (defaults to the model name)")
+	modelsSetCmd.Flags().StringVar(&setModelApiVersion, "api-version", "", "Azure OpenAI api version")
+	modelsSetCmd.Flags().StringVar(&setModelRegion, "region", "", "AWS region for Bedrock models (defaults to the server's AWS_REGION)")
 }
 
 var modelsSetCmd = &cobra.Command{
 	Use:   "set-model [role-or-setting] [property-or-value] [value]",
 	Short: "Update model settings",
-	Run:   modelsSet,
-	Args:  cobra.MaximumNArgs(3),
+	Long: `Update model settings.
+
+OpenAI models can also be called through Azure OpenAI by passing --provider azure-openai with your resource's --base-url, and optionally --deployment and --api-version. Bedrock models are called in the server's AWS account, in --region or the server's AWS_REGION.`,
+	Run:  modelsSet,
+	Args: cobra.MaximumNArgs(3),
 }
 
 func modelsSet(cmd *cobra.Command, args []string) {
@@ -61,15 +76,11 @@ func modelsSet(cmd *cobra.Command, args []string) {
 	var selectedModel *shared.BaseModelConfig
 	var temperature *float64
 	var topP *float64
+	var maxCompletionTokens *int
 
 	if len(args) > 0 {
 		roleOrSetting = args[0]
-		for _, r := range shared.AllModelRoles {
-			if strings.EqualFold(string(r), roleOrSetting) {
-				role = r
-				break
-			}
-		}
+		role = shared.ModelRoleFromString(roleOrSetting)
 		if role == "" {
 			for _, s := range shared.ModelOverridePropsDasherized {
 				compact := shared.Compact(s)
@@ -187,7 +198,7 @@ func modelsSet(cmd *cobra.Command, args []string) {
 	}
 
 	if role != "" {
-		if !(propertyCompact == "temperature" || propertyCompact == "topp") {
+		if !(propertyCompact == "temperature" || propertyCompact == "topp" || propertyCompact == "maxoutputtokens") {
 			for _, m := range shared.AvailableModels {
 				if propertyCompact == m.ModelName {
 					selectedModel = &m
@@ -201,6 +212,8 @@ func modelsSet(cmd *cobra.Command, args []string) {
 				"Select a model",
 				"Set temperature",
 				"Set top-p",
+				"Set max output tokens",
+				"Add a fallback model",
 			}
 
 			selection, err := term.SelectFromList("Select a property to update:", opts)
@@ -243,17 +256,67 @@ func modelsSet(cmd *cobra.Command, args []string) {
 				propertyCompact = "temperature"
 			} else if selection == "Set top-p" {
 				propertyCompact = "topp"
+			} else if selection == "Set max output tokens" {
+				propertyCompact = "maxoutputtokens"
+			} else if selection == "Add a fallback model" {
+				propertyCompact = "fallback"
+			}
+		}
+
+		var fallbackModel *shared.BaseModelConfig
+		var clearFallbacks bool
+
+		if propertyCompact == "fallback" {
+			if value == "" {
+				opts := []string{"Clear fallbacks"}
+				for _, m := range shared.AvailableModels {
+					label := fmt.Sprintf("%s → %s | max %d 🪙", m.Provider, m.ModelName, m.MaxTokens)
+					opts = append(opts, label)
+				}
+
+				selection, err := term.SelectFromList("Select a model to fall back to:", opts)
+				if err != nil {
+					if err.Error() == "interrupt" {
+						return
+					}
+
+					term.OutputErrorAndExit("Error selecting model: %v", err)
+					return
+				}
+
+				if selection == opts[0] {
+					value = "clear"
+				} else {
+					for i := range opts {
+						if opts[i] == selection {
+							value = shared.AvailableModels[i-1].ModelName
+							break
+						}
+					}
+				}
+			}
+
+			if value == "clear" {
+				clearFallbacks = true
+			} else {
+				m, ok := shared.AvailableModelsByName[value]
+				if !ok {
+					term.OutputErrorAndExitWithCode(term.ExitUsage, "Unknown model %s", value)
+				}
+				fallbackModel = &m
 			}
 		}
 
 		if selectedModel == nil {
-			if propertyCompact != "" {
+			if propertyCompact != "" && propertyCompact != "fallback" {
 				if value == "" {
-					msg := "Set"
+					msg := "Set "
 					if propertyCompact == "temperature" {
 						msg += "temperature (-2.0 to 2.0)"
 					} else if propertyCompact == "topp" {
 						msg += "top-p (0.0 to 1.0)"
+					} else if propertyCompact == "maxoutputtokens" {
+						msg += "max output tokens per reply (0 for the model's limit)"
 					}
 					var err error
 					value, err = term.GetUserStringInput(msg)
@@ -282,10 +345,35 @@ func modelsSet(cmd *cobra.Command, args []string) {
 						return
 					}
 					topP = &f
+				case "maxoutputtokens":
+					n, err := strconv.Atoi(value)
+					if err != nil || n < 0 {
+						fmt.Println("Invalid value for max-output-tokens:", value)
+						return
+					}
+					maxCompletionTokens = &n
 				}
 			}
 		}
 
+		if selectedModel != nil {
+			// copy so the provider settings don't modify shared.AvailableModels
+			model := *selectedModel
+			selectedModel = &model
+
+			err := applyModelProviderFlags(selectedModel)
+			if err != nil {
+				term.OutputErrorAndExitWithCode(term.ExitUsage, "%v", err)
+			}
+		} else if fallbackModel != nil {
+			err := applyModelProviderFlags(fallbackModel)
+			if err != nil {
+				term.OutputErrorAndExitWithCode(term.ExitUsage, "%v", err)
+			}
+		} else if setModelProvider != "" || setModelBaseUrl != "" || setModelDeployment != "" || setModelApiVersion != "" || setModelRegion != "" {
+			term.OutputErrorAndExitWithCode(term.ExitUsage, "Provider flags can only be used when setting a model")
+		}
+
 		if settings.ModelSet == nil {
 			settings.ModelSet = &shared.DefaultModelSet
 		}
@@ -299,6 +387,8 @@ func modelsSet(cmd *cobra.Command, args []string) {
 				settings.ModelSet.Planner.Temperature = float32(*temperature)
 			} else if topP != nil {
 				settings.ModelSet.Planner.TopP = float32(*topP)
+			} else if maxCompletionTokens != nil {
+				settings.ModelSet.Planner.MaxCompletionTokens = *maxCompletionTokens
 			}
 
 		case shared.ModelRolePlanSummary:
@@ -308,6 +398,8 @@ func modelsSet(cmd *cobra.Command, args []string) {
 				settings.ModelSet.PlanSummary.Temperature = float32(*temperature)
 			} else if topP != nil {
 				settings.ModelSet.PlanSummary.TopP = float32(*topP)
+			} else if maxCompletionTokens != nil {
+				settings.ModelSet.PlanSummary.MaxCompletionTokens = *maxCompletionTokens
 			}
 
 		case shared.ModelRoleBuilder:
@@ -318,6 +410,8 @@ func modelsSet(cmd *cobra.Command, args []string) {
 				settings.ModelSet.Builder.Temperature = float32(*temperature)
 			} else if topP != nil {
 				settings.ModelSet.Builder.TopP = float32(*topP)
+			} else if maxCompletionTokens != nil {
+				settings.ModelSet.Builder.MaxCompletionTokens = *maxCompletionTokens
 			}
 
 		case shared.ModelRoleName:
@@ -328,6 +422,8 @@ func modelsSet(cmd *cobra.Command, args []string) {
 				settings.ModelSet.Namer.Temperature = float32(*temperature)
 			} else if topP != nil {
 				settings.ModelSet.Namer.TopP = float32(*topP)
+			} else if maxCompletionTokens != nil {
+				settings.ModelSet.Namer.MaxCompletionTokens = *maxCompletionTokens
 			}
 
 		case shared.ModelRoleCommitMsg:
@@ -338,6 +434,8 @@ func modelsSet(cmd *cobra.Command, args []string) {
 				settings.ModelSet.CommitMsg.Temperature = float32(*temperature)
 			} else if topP != nil {
 				settings.ModelSet.CommitMsg.TopP = float32(*topP)
+			} else if maxCompletionTokens != nil {
+				settings.ModelSet.CommitMsg.MaxCompletionTokens = *maxCompletionTokens
 			}
 
 		case shared.ModelRoleExecStatus:
@@ -348,6 +446,17 @@ func modelsSet(cmd *cobra.Command, args []string) {
 				settings.ModelSet.ExecStatus.Temperature = float32(*temperature)
 			} else if topP != nil {
 				settings.ModelSet.ExecStatus.TopP = float32(*topP)
+			} else if maxCompletionTokens != nil {
+				settings.ModelSet.ExecStatus.MaxCompletionTokens = *maxCompletionTokens
+			}
+		}
+
+		if fallbackModel != nil || clearFallbacks {
+			roleConfig := settings.ModelSet.RoleConfig(role)
+			if clearFallbacks {
+				roleConfig.Fallbacks = nil
+			} else {
+				roleConfig.Fallbacks = append(roleConfig.Fallbacks, *fallbackModel)
 			}
 		}
 	}
@@ -375,3 +484,53 @@ func modelsSet(cmd *cobra.Command, args []string) {
 	fmt.Println()
 	term.PrintCmds("", "models", "log", "rewind")
 }
+
+func applyModelProviderFlags(model *shared.BaseModelConfig) error {
+	provider := model.Provider
+	if setModelProvider != "" {
+		provider = ""
+		for _, p := range shared.AllModelProviders {
+			if strings.EqualFold(string(p), setModelProvider) {
+				provider = p
+				break
+			}
+		}
+		if provider == "" {
+			return fmt.Errorf("unknown provider %s", setModelProvider)
+		}
+	}
+
+	if provider != shared.ModelProviderAzureOpenAI && (setModelBaseUrl != "" || setModelDeployment != "" || setModelApiVersion != "") {
+		return fmt.Errorf("--base-url, --deployment, and --api-version are only for Azure OpenAI")
+	}
+	if provider != shared.ModelProviderBedrock && setModelRegion != "" {
+		return fmt.Errorf("--region is only for Bedrock")
+	}
+
+	switch provider {
+	case shared.ModelProviderOpenAI:
+		if model.Provider != shared.ModelProviderOpenAI {
+			return fmt.Errorf("%s isn't an OpenAI model", model.ModelName)
+		}
+
+	case shared.ModelProviderAzureOpenAI:
+		if model.Provider != shared.ModelProviderOpenAI {
+			return fmt.Errorf("Azure OpenAI serves OpenAI models, and %s isn't one", model.ModelName)
+		}
+		if setModelBaseUrl == "" {
+			return fmt.Errorf("--base-url is required for Azure OpenAI")
+		}
+		model.Provider = shared.ModelProviderAzureOpenAI
+		model.BaseUrl = setModelBaseUrl
+		model.AzureDeployment = setModelDeployment
+		model.ApiVersion = setModelApiVersion
+
+	case shared.ModelProviderBedrock:
+		if model.Provider != shared.ModelProviderBedrock {
+			return fmt.Errorf("%s isn't available on Bedrock", model.ModelName)
+		}
+		model.Region = setModelRegion
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/format"
+	"plandex/lib"
+	"plandex/term"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var shareWithOrg bool
+var sharePermission string
+
+var shareCmd = &cobra.Command{
+	Use:   "share [email]",
+	Short: "Share the current plan with a user or the whole org, or list who it's shared with",
+	Long: `Share the current plan with a user in your org, or with everyone in the org with --org, or list who it's shared with if neither is given.
+
+Permissions are 'view' (read the plan), 'prompt' (also send prompts, build, and update context and pending changes), and 'apply' (also apply changes). Sharing again with the same user changes their permission.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  share,
+}
+
+var unshareCmd = &cobra.Command{
+	Use:   "unshare [email]",
+	Short: "Stop sharing the current plan with a user or the whole org",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   unshare,
+}
+
+func init() {
+	RootCmd.AddCommand(shareCmd)
+	RootCmd.AddCommand(unshareCmd)
+
+	shareCmd.Flags().BoolVar(&shareWithOrg, "org", false, "Share with everyone in the org")
+	shareCmd.Flags().StringVarP(&sharePermission, "permission", "p", string(shared.PlanPermissionView), "What they can do: 'view', 'prompt', or 'apply'")
+
+	unshareCmd.Flags().BoolVar(&shareWithOrg, "org", false, "Stop sharing with everyone in the org")
+}
+
+func share(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	if len(args) == 0 && !shareWithOrg {
+		listShares()
+		return
+	}
+
+	if len(args) > 0 && shareWithOrg {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "Specify an email or --org, not both")
+	}
+
+	permission := shared.PlanPermission(sharePermission)
+	if !permission.Valid() {
+		permissions := []string{}
+		for _, p := range shared.PlanPermissions {
+			permissions = append(permissions, string(p))
+		}
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "Invalid permission '%s'. Use one of: %s", sharePermission, strings.Join(permissions, ", "))
+	}
+
+	var email string
+	if len(args) > 0 {
+		email = args[0]
+	}
+
+	term.StartSpinner("")
+	apiErr := api.Client.SharePlan(lib.CurrentPlanId, shared.SharePlanRequest{
+		Email:      email,
+		Permission: permission,
+	})
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error sharing plan: %v", apiErr.Msg)
+	}
+
+	with := email
+	if email == "" {
+		with = "everyone in the org"
+	}
+	fmt.Printf("✅ Shared plan with %s with %s permission\n", with, permission)
+	fmt.Println()
+	term.PrintCmds("", "share", "unshare")
+}
+
+func listShares() {
+	term.StartSpinner("")
+	shares, apiErr := api.Client.ListPlanShares(lib.CurrentPlanId)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error listing plan shares: %v", apiErr.Msg)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(shares)
+		return
+	}
+
+	if len(shares) == 0 {
+		fmt.Println("🤷‍♂️ Plan isn't shared")
+		fmt.Println()
+		term.PrintCmds("", "share")
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Shared With", "Permission", "Since"})
+
+	for _, s := range shares {
+		table.Append([]string{shareLabel(s), string(s.Permission), format.Time(s.CreatedAt)})
+	}
+
+	table.Render()
+
+	fmt.Println()
+	term.PrintCmds("", "share", "unshare")
+}
+
+func unshare(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	term.StartSpinner("")
+	shares, apiErr := api.Client.ListPlanShares(lib.CurrentPlanId)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error listing plan shares: %v", apiErr.Msg)
+	}
+
+	if len(shares) == 0 {
+		fmt.Println("🤷‍♂️ Plan isn't shared")
+		return
+	}
+
+	var toDelete *shared.PlanShare
+	if shareWithOrg || len(args) > 0 {
+		for _, s := range shares {
+			if (shareWithOrg && s.UserId == nil) || (len(args) > 0 && strings.EqualFold(s.UserEmail, args[0])) {
+				toDelete = s
+				break
+			}
+		}
+
+		if toDelete == nil {
+			if shareWithOrg {
+				fmt.Println("🤷‍♂️ Plan isn't shared with the org")
+			} else {
+				fmt.Printf("🤷‍♂️ Plan isn't shared with %s\n", args[0])
+			}
+			return
+		}
+	} else {
+		if term.NonInteractive {
+			term.OutputErrorAndExitWithCode(term.ExitUsage, "Specify an email or --org")
+		}
+
+		labels := []string{}
+		byLabel := map[string]*shared.PlanShare{}
+		for _, s := range shares {
+			label := fmt.Sprintf("%s (%s)", shareLabel(s), s.Permission)
+			labels = append(labels, label)
+			byLabel[label] = s
+		}
+
+		selected, err := term.SelectFromList("Stop sharing with:", labels)
+		if err != nil {
+			term.OutputErrorAndExit("Error selecting share: %v", err)
+		}
+		toDelete = byLabel[selected]
+	}
+
+	term.StartSpinner("")
+	apiErr = api.Client.DeletePlanShare(lib.CurrentPlanId, toDelete.Id)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error unsharing plan: %v", apiErr.Msg)
+	}
+
+	fmt.Printf("✅ Stopped sharing plan with %s\n", shareLabel(toDelete))
+}
+
+func shareLabel(s *shared.PlanShare) string {
+	if s.UserId == nil {
+		return "everyone in the org"
+	}
+	if s.UserName != "" {
+		return fmt.Sprintf("%s <%s>", s.UserName, s.UserEmail)
+	}
+	return s.UserEmail
+}
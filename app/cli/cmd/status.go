@@ -1 +1,90 @@
 package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/lib"
+	"plandex/term"
+	"strconv"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:     "status",
+	Aliases: []string{"s"},
+	Short:   "Show the plan's step checklist",
+	Run:     status,
+}
+
+func init() {
+	RootCmd.AddCommand(statusCmd)
+}
+
+func status(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	term.StartSpinner("")
+	steps, apiErr := api.Client.ListPlanSteps(lib.CurrentPlanId, lib.CurrentBranch)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error loading plan steps: %v", apiErr.Msg)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(steps)
+		return
+	}
+
+	if len(steps) == 0 {
+		fmt.Println("🤷‍♂️ The plan hasn't been broken into steps")
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"#", "Step", "Status"})
+
+	numDone := 0
+	for _, step := range steps {
+		var status string
+		switch step.Status {
+		case shared.PlanStepStatusDone:
+			status = "✅ done"
+			numDone++
+		case shared.PlanStepStatusFailed:
+			status = "❌ failed"
+		default:
+			status = "⏳ pending"
+		}
+
+		table.Append([]string{strconv.Itoa(step.Num), step.Description, status})
+	}
+
+	table.Render()
+	fmt.Println()
+
+	next := shared.NextPlanStep(steps)
+	if next == nil {
+		color.New(color.Bold, term.ColorHiGreen).Printf("All %d steps are done\n", len(steps))
+		fmt.Println()
+		term.PrintCmds("", "changes", "apply")
+		return
+	}
+
+	fmt.Printf("%d/%d steps done. Next up is step %d: %s\n", numDone, len(steps), next.Num, next.Description)
+	fmt.Println()
+	term.PrintCmds("", "continue", "changes")
+}
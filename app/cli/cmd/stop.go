@@ -12,7 +12,7 @@ import (
 
 var stopCmd = &cobra.Command{
 	Use:   "stop [stream-id-or-plan] [branch]",
-	Short: "Connect to an active stream",
+	Short: "Stop an active stream",
 	// Long:  ``,
 	Args: cobra.MaximumNArgs(2),
 	Run:  stop,
@@ -42,7 +42,7 @@ func stop(cmd *cobra.Command, args []string) {
 	term.StopSpinner()
 
 	if apiErr != nil {
-		term.OutputErrorAndExit("Error stopping stream: %v", apiErr.Msg)
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error stopping stream: %v", apiErr.Msg)
 	}
 
 	fmt.Println("✅ Plan stream stopped")
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex/auth"
+	"plandex/lib"
+	"plandex/term"
+
+	"github.com/spf13/cobra"
+)
+
+var syncDiscard bool
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [queued-id...]",
+	Short: "Send context loads and prompts queued while offline",
+	Long: `Send context loads and prompts that were queued because the server or the plan's model providers couldn't be reached. Queued work is also sent by the first command that can reach the server, but failed items are only retried by this command.
+
+With --discard, remove the given queued items, or everything queued if no ids are given.`,
+	Run: syncQueue,
+}
+
+func init() {
+	RootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().BoolVar(&syncDiscard, "discard", false, "Remove queued items instead of sending them")
+}
+
+func syncQueue(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+
+	if syncDiscard {
+		discardQueued(args)
+		return
+	}
+
+	if len(args) > 0 {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "Queued ids can only be given with --discard")
+	}
+
+	ops, err := lib.ListQueuedOps()
+	if err != nil {
+		term.OutputErrorAndExit("Error listing queued items: %v", err)
+	}
+
+	if len(ops) == 0 {
+		if term.JsonOutput {
+			term.OutputJson(&lib.QueueSyncResult{})
+			return
+		}
+		fmt.Println("🤷‍♂️ Nothing queued")
+		return
+	}
+
+	term.StartSpinner("🔄 Sending queued work...")
+	res, err := lib.SyncQueue(true)
+	term.StopSpinner()
+
+	if err != nil {
+		term.OutputErrorAndExit("Error sending queued work: %v", err)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(res)
+	} else {
+		lib.PrintQueueSyncResult(res)
+	}
+
+	// the result already says what went wrong
+	if res.Offline {
+		os.Exit(term.ExitNetwork)
+	}
+	if len(res.Failed) > 0 {
+		os.Exit(term.ExitError)
+	}
+
+	if !term.JsonOutput {
+		fmt.Println()
+		term.PrintCmds("", "ps")
+	}
+}
+
+func discardQueued(ids []string) {
+	discarded, err := lib.DiscardQueuedOps(ids)
+	if err != nil {
+		term.OutputErrorAndExit("Error discarding queued items: %v", err)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(discarded)
+		return
+	}
+
+	if len(discarded) == 0 {
+		fmt.Println("🤷‍♂️ Nothing to discard")
+		return
+	}
+
+	for _, op := range discarded {
+		fmt.Println(term.Plain(fmt.Sprintf("🗑️  Discarded queued %s", lib.QueuedOpDesc(op))))
+	}
+}
@@ -7,21 +7,24 @@ import (
 	"plandex/auth"
 	"plandex/lib"
 	"plandex/plan_exec"
+	streamtui "plandex/stream_tui"
 	"plandex/term"
 	"strings"
 
+	"github.com/fatih/color"
 	"github.com/plandex/plandex/shared"
 	"github.com/spf13/cobra"
 )
 
-const defaultEditor = "vim"
-
-// const defaultEditor = "nano"
-
 var tellPromptFile string
 var tellBg bool
 var tellStop bool
 var tellNoBuild bool
+var tellAutoContext bool
+var tellTemplate string
+var tellVars []string
+var tellVerify string
+var tellVerifyRetries int
 
 // tellCmd represents the prompt command
 var tellCmd = &cobra.Command{
@@ -40,9 +43,17 @@ func init() {
 	tellCmd.Flags().BoolVarP(&tellStop, "stop", "s", false, "Stop after a single reply")
 	tellCmd.Flags().BoolVarP(&tellNoBuild, "no-build", "n", false, "Don't build files")
 	tellCmd.Flags().BoolVar(&tellBg, "bg", false, "Execute autonomously in the background")
+	tellCmd.Flags().BoolVar(&streamtui.PlainOutput, "plain", false, "Stream the reply as plain text instead of in the stream UI (the default when output isn't a terminal)")
+	tellCmd.Flags().StringVar(&tellTemplate, "template", "", "Prompt template to send (see 'plandex templates')")
+	tellCmd.Flags().StringArrayVar(&tellVars, "var", nil, "Value for a template placeholder as name=value (repeatable)")
+	tellCmd.Flags().BoolVar(&tellAutoContext, "auto-context", false, "Find the project files most relevant to the prompt and load them into context first")
+	tellCmd.Flags().StringVar(&tellVerify, "verify", "", "Command to run with the changes in place, like a build or tests. If it fails, its output is loaded and the plan continues to fix it")
+	tellCmd.Flags().IntVar(&tellVerifyRetries, "verify-retries", 3, "How many times the plan continues to fix a failed --verify command")
 }
 
 func doTell(cmd *cobra.Command, args []string) {
+	tellBg = term.RunInBackground(tellBg)
+
 	if os.Getenv("OPENAI_API_KEY") == "" {
 		term.OutputNoApiKeyMsgAndExit()
 	}
@@ -55,18 +66,37 @@ func doTell(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	lib.MustCheckBudget()
+
 	var prompt string
+	var opts *tellOptions
 
 	if len(args) > 0 {
 		prompt = args[0]
-	} else if tellPromptFile != "" {
-		bytes, err := os.ReadFile(tellPromptFile)
+	} else {
+		if tellTemplate != "" {
+			prompt = mustRenderTemplate(tellTemplate, tellVars)
+		} else if tellPromptFile != "" {
+			bytes, err := os.ReadFile(tellPromptFile)
+			if err != nil {
+				term.OutputErrorAndExit("Error reading prompt file: %v", err)
+			}
+			prompt = string(bytes)
+		} else if term.NonInteractive {
+			term.OutputInputRequiredAndExit("a prompt (pass it as an argument or with --file)")
+		} else {
+			prompt = getEditorPrompt()
+		}
+
+		var err error
+		opts, prompt, err = parseTellFrontMatter(prompt)
 		if err != nil {
-			term.OutputErrorAndExit("Error reading prompt file: %v", err)
+			term.OutputErrorAndExit("Error parsing prompt: %v", err)
 		}
-		prompt = string(bytes)
-	} else {
-		prompt = getEditorPrompt()
+	}
+
+	if opts != nil {
+		applyTellOptions(cmd, opts)
 	}
 
 	if prompt == "" {
@@ -74,13 +104,90 @@ func doTell(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	plan_exec.TellPlan(plan_exec.ExecParams{
+	if tellVerify != "" {
+		// the command runs once the plan finishes streaming, so the plan has to run in the foreground and build its changes
+		if tellBg {
+			term.OutputErrorAndExitWithCode(term.ExitUsage, "--verify can't be used with --bg or in non-interactive mode")
+		}
+		if tellNoBuild {
+			term.OutputErrorAndExitWithCode(term.ExitUsage, "--verify can't be used with --no-build")
+		}
+	}
+
+	if tellAutoContext {
+		lib.MustAutoLoadContext(prompt)
+	}
+
+	params := plan_exec.ExecParams{
 		CurrentPlanId: lib.CurrentPlanId,
 		CurrentBranch: lib.CurrentBranch,
 		CheckOutdatedContext: func(maybeContexts []*shared.Context) (bool, bool) {
-			return lib.MustCheckOutdatedContext(false, maybeContexts)
+			return lib.MustCheckOutdatedContextBeforePrompt(maybeContexts)
 		},
-	}, prompt, tellBg, tellStop, tellNoBuild, false)
+	}
+
+	if tellVerify != "" {
+		params.OnFinish = verifyPlan(params, 0)
+	}
+
+	plan_exec.TellPlan(params, prompt, tellBg, tellStop, tellNoBuild, false)
+}
+
+// verifyPlan returns a function that runs the --verify command once the plan finishes. If it fails, its output is
+// loaded and the plan is prompted to fix it, which is verified again in turn, until it passes or it's been fixed
+// --verify-retries times.
+func verifyPlan(params plan_exec.ExecParams, numFixes int) func() {
+	return func() {
+		output, exitCode := lib.MustVerifyPlan(params.CurrentPlanId, params.CurrentBranch, tellVerify)
+
+		fmt.Println()
+		if exitCode == 0 {
+			fmt.Println("✅ Verified")
+			fmt.Println()
+			return
+		}
+
+		color.New(color.Bold, term.ColorHiRed).Printf("🚨 %s failed with exit code %d\n", tellVerify, exitCode)
+
+		if numFixes >= tellVerifyRetries {
+			fmt.Println()
+			if numFixes > 0 {
+				fmt.Printf("The plan tried to fix it %d times. The changes are still pending.\n", numFixes)
+				fmt.Println()
+			}
+			term.PrintCmds("", "changes", "tell", "rewind")
+			os.Exit(term.ExitError)
+		}
+
+		fmt.Println()
+		lib.MustLoadVerifyFailure(tellVerify, output, exitCode)
+
+		lib.MustCheckBudget()
+
+		params.OnFinish = verifyPlan(params, numFixes+1)
+
+		fixPrompt := fmt.Sprintf("With your pending changes in place, `%s` failed. Its output is in context. Fix the errors.", tellVerify)
+		plan_exec.TellPlan(params, fixPrompt, false, tellStop, false, false)
+	}
+}
+
+func applyTellOptions(cmd *cobra.Command, opts *tellOptions) {
+	if opts.AutoContinue != nil && !cmd.Flags().Changed("stop") {
+		tellStop = !*opts.AutoContinue
+	}
+	if opts.NoBuild != nil && !cmd.Flags().Changed("no-build") {
+		tellNoBuild = *opts.NoBuild
+	}
+	if opts.Bg != nil && !cmd.Flags().Changed("bg") {
+		tellBg = *opts.Bg || term.NonInteractive
+	}
+	if opts.AutoContext != nil && !cmd.Flags().Changed("auto-context") {
+		tellAutoContext = *opts.AutoContext
+	}
+	if opts.Model != "" {
+		lib.MustSetModels(lib.CurrentPlanId, lib.CurrentBranch, map[string]string{string(shared.ModelRolePlanner): opts.Model})
+		fmt.Printf("🤖 Planner model set to %s\n", opts.Model)
+	}
 }
 
 func prepareEditorCommand(editor string, filename string) *exec.Cmd {
@@ -96,27 +203,43 @@ func prepareEditorCommand(editor string, filename string) *exec.Cmd {
 
 func getEditorInstructions(editor string) string {
 
-	return "Write your prompt below, then save and exit to send it to Plandex.\n\n"
+	return "Write your prompt below, then save and exit to send it to Plandex.\nTo set options, start the prompt with front matter between --- lines (model, auto-continue, no-build, bg, auto-context).\n\n"
 
 }
 
 func getEditorPrompt() string {
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		editor = os.Getenv("VISUAL")
-		if editor == "" {
-			editor = defaultEditor
+	// a prompt skeleton from a plan template is prefilled below the instructions
+	draft, err := lib.ReadDraftPrompt(lib.CurrentPlanId)
+	if err != nil {
+		term.OutputErrorAndExit("Error loading draft prompt: %v", err)
+	}
+
+	prompt := editPromptInEditor(draft)
+
+	if draft != "" {
+		err = lib.ClearDraftPrompt(lib.CurrentPlanId)
+		if err != nil {
+			term.OutputErrorAndExit("Error clearing draft prompt: %v", err)
 		}
 	}
 
+	return prompt
+}
+
+// editPromptInEditor opens the user's editor with the instructions and the given text, and returns what's there
+// once the editor is closed
+func editPromptInEditor(text string) string {
+	editor := lib.GetEditor()
+
 	tempFile, err := os.CreateTemp(os.TempDir(), "plandex_prompt_*")
 	if err != nil {
 		term.OutputErrorAndExit("Failed to create temporary file: %v", err)
 	}
 
 	instructions := getEditorInstructions(editor)
+
 	filename := tempFile.Name()
-	err = os.WriteFile(filename, []byte(instructions), 0644)
+	err = os.WriteFile(filename, []byte(instructions+text), 0644)
 	if err != nil {
 		term.OutputErrorAndExit("Failed to write instructions to temporary file: %v", err)
 	}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/plandex/plandex/shared"
+	"gopkg.in/yaml.v3"
+)
+
+// tellOptions can be set in front matter at the top of a prompt written in the editor or loaded with --file.
+// Flags given on the command line take precedence.
+type tellOptions struct {
+	// planner model for the current branch, as with set-model
+	Model        string `yaml:"model"`
+	AutoContinue *bool  `yaml:"auto-continue"`
+	NoBuild      *bool  `yaml:"no-build"`
+	Bg           *bool  `yaml:"bg"`
+	AutoContext  *bool  `yaml:"auto-context"`
+}
+
+// parseTellFrontMatter splits a '---' delimited yaml block from the start of a prompt.
+// A prompt without front matter is returned unchanged with nil options.
+func parseTellFrontMatter(prompt string) (*tellOptions, string, error) {
+	normalized := strings.ReplaceAll(prompt, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return nil, prompt, nil
+	}
+
+	// the newline after the opening delimiter is kept so an empty block still matches the closing delimiter
+	rest := normalized[len("---"):]
+	end := strings.Index(rest, "\n---")
+	if end == -1 {
+		return nil, prompt, nil
+	}
+
+	// the closing delimiter must be a line of its own
+	after := rest[end+len("\n---"):]
+	if after != "" && after[0] != '\n' {
+		return nil, prompt, nil
+	}
+
+	var opts tellOptions
+	decoder := yaml.NewDecoder(bytes.NewBufferString(rest[:end]))
+	decoder.KnownFields(true)
+	err := decoder.Decode(&opts)
+	if err != nil && err.Error() != "EOF" {
+		return nil, "", fmt.Errorf("invalid front matter (options are model, auto-continue, no-build, bg, and auto-context): %v", err)
+	}
+
+	if opts.Model != "" {
+		if _, ok := shared.AvailableModelsByName[opts.Model]; !ok {
+			return nil, "", fmt.Errorf("unknown model %q in front matter", opts.Model)
+		}
+	}
+
+	return &opts, strings.TrimSpace(after), nil
+}
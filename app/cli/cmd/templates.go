@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex/lib"
+	"plandex/term"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var templateFile string
+var templateGlobal bool
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "List prompt templates",
+	Run:   listTemplates,
+}
+
+var templatesListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List prompt templates",
+	Args:    cobra.NoArgs,
+	Run:     listTemplates,
+}
+
+var templatesSaveCmd = &cobra.Command{
+	Use:   "save <name> [template]",
+	Short: "Save a prompt template to .plandex/templates (or the home dir with --global). Use {name} for values set with --var",
+	Args:  cobra.RangeArgs(1, 2),
+	Run:   saveTemplate,
+}
+
+var templatesApplyCmd = &cobra.Command{
+	Use:   "apply <name>",
+	Short: "Send a prompt template to the current plan, as with tell --template",
+	Args:  cobra.ExactArgs(1),
+	Run:   applyTemplate,
+}
+
+func init() {
+	RootCmd.AddCommand(templatesCmd)
+	templatesCmd.AddCommand(templatesListCmd)
+	templatesCmd.AddCommand(templatesSaveCmd)
+	templatesCmd.AddCommand(templatesApplyCmd)
+
+	templatesSaveCmd.Flags().StringVarP(&templateFile, "file", "f", "", "File containing the template")
+	templatesSaveCmd.Flags().BoolVarP(&templateGlobal, "global", "g", false, "Save to the home dir so the template can be used in any project")
+
+	templatesApplyCmd.Flags().StringArrayVar(&tellVars, "var", nil, "Value for a template placeholder as name=value (repeatable)")
+	templatesApplyCmd.Flags().BoolVarP(&tellStop, "stop", "s", false, "Stop after a single reply")
+	templatesApplyCmd.Flags().BoolVarP(&tellNoBuild, "no-build", "n", false, "Don't build files")
+	templatesApplyCmd.Flags().BoolVar(&tellBg, "bg", false, "Execute autonomously in the background")
+
+	templatesApplyCmd.ValidArgsFunction = completeTemplateNames
+	tellCmd.RegisterFlagCompletionFunc("template", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeTemplateNames(cmd, nil, toComplete)
+	})
+}
+
+func listTemplates(cmd *cobra.Command, args []string) {
+	lib.MaybeResolveProject()
+
+	templates, err := lib.ListTemplates()
+	if err != nil {
+		term.OutputErrorAndExit("Error listing templates: %v", err)
+	}
+
+	if term.JsonOutput {
+		type templateJson struct {
+			Name   string   `json:"name"`
+			Vars   []string `json:"vars"`
+			Path   string   `json:"path"`
+			Global bool     `json:"global"`
+		}
+		res := []templateJson{}
+		for _, t := range templates {
+			res = append(res, templateJson{Name: t.Name, Vars: t.Vars(), Path: t.Path, Global: t.Global})
+		}
+		term.OutputJson(res)
+		return
+	}
+
+	if len(templates) == 0 {
+		fmt.Println("🤷‍♂️ No templates")
+		fmt.Println()
+		term.PrintCmds("", "templates save")
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Name", "Vars", "Scope", "Template"})
+
+	for _, t := range templates {
+		scope := "project"
+		if t.Global {
+			scope = "home"
+		}
+
+		preview := strings.TrimSpace(t.Body)
+		if i := strings.Index(preview, "\n"); i != -1 {
+			preview = preview[:i] + " …"
+		}
+		if len(preview) > 60 {
+			preview = preview[:60] + "…"
+		}
+
+		table.Append([]string{t.Name, strings.Join(t.Vars(), ", "), scope, preview})
+	}
+
+	table.Render()
+
+	fmt.Println()
+	term.PrintCmds("", "templates apply", "templates save")
+}
+
+func saveTemplate(cmd *cobra.Command, args []string) {
+	lib.MaybeResolveProject()
+
+	name := args[0]
+
+	var body string
+	if len(args) > 1 {
+		body = args[1]
+	} else if templateFile != "" {
+		bytes, err := os.ReadFile(templateFile)
+		if err != nil {
+			term.OutputErrorAndExit("Error reading template file: %v", err)
+		}
+		body = string(bytes)
+	} else {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "Pass the template as an argument or with --file")
+	}
+
+	if strings.TrimSpace(body) == "" {
+		term.OutputErrorAndExit("Template is empty")
+	}
+
+	path, err := lib.SaveTemplate(name, body, templateGlobal)
+	if err != nil {
+		term.OutputErrorAndExit("Error saving template: %v", err)
+	}
+
+	fmt.Printf("✅ Saved template %s to %s\n", name, path)
+}
+
+func applyTemplate(cmd *cobra.Command, args []string) {
+	tellTemplate = args[0]
+	doTell(cmd, nil)
+}
+
+// mustRenderTemplate renders a template with the --var values, prompting for any that weren't given
+func mustRenderTemplate(name string, vars []string) string {
+	t, err := lib.GetTemplate(name)
+	if err != nil {
+		term.OutputErrorAndExit("Error loading template: %v", err)
+	}
+
+	values, err := lib.ParseTemplateVars(vars)
+	if err != nil {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "%v", err)
+	}
+
+	if !term.NonInteractive {
+		for _, v := range t.Vars() {
+			if _, ok := values[v]; ok {
+				continue
+			}
+			value, err := term.GetUserStringInput(fmt.Sprintf("Value for {%s}:", v))
+			if err != nil {
+				term.OutputErrorAndExit("Error getting value for %s: %v", v, err)
+			}
+			values[v] = value
+		}
+	}
+
+	prompt, err := t.Render(values)
+	if err != nil {
+		term.OutputErrorAndExit("Error rendering template %s: %v", name, err)
+	}
+
+	return prompt
+}
+
+func completeTemplateNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	templates, err := lib.ListTemplates()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, t := range templates {
+		if strings.HasPrefix(t.Name, toComplete) {
+			names = append(names, t.Name)
+		}
+	}
+
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
@@ -2,18 +2,20 @@ package cmd
 
 import (
 	"fmt"
+	"plandex/api"
 	"plandex/auth"
 	"plandex/lib"
 	"plandex/term"
 
+	"github.com/plandex/plandex/shared"
 	"github.com/spf13/cobra"
 )
 
 var updateCmd = &cobra.Command{
-	Use:     "update ",
+	Use:     "update [name-or-glob...]",
 	Aliases: []string{"u"},
 	Short:   "Update outdated context",
-	Args:    cobra.MaximumNArgs(1),
+	Long:    `Check file, URL, directory tree, and code map contexts for changes and update only the outdated ones. Pass indexes, names, paths, or globs to limit the update to matching contexts.`,
 	Run:     update,
 }
 
@@ -26,19 +28,59 @@ func update(cmd *cobra.Command, args []string) {
 	auth.MustResolveAuthWithOrg()
 	lib.MustResolveProject()
 
-	term.StartSpinner("")
-	outdated, err := lib.CheckOutdatedContext(nil)
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	term.StartSpinner("🔬 Checking context...")
+
+	// nil checks all contexts
+	var contexts []*shared.Context
+
+	if len(args) > 0 {
+		allContexts, apiErr := api.Client.ListContext(lib.CurrentPlanId, lib.CurrentBranch)
+		if apiErr != nil {
+			term.StopSpinner()
+			term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error retrieving context: %v", apiErr.Msg)
+		}
+
+		contexts = []*shared.Context{}
+		for i, context := range allContexts {
+			for _, arg := range args {
+				matched, err := lib.ContextMatchesArg(i, context, arg)
+				if err != nil {
+					term.StopSpinner()
+					term.OutputErrorAndExit("Error matching glob pattern: %v", err)
+				}
+				if matched {
+					contexts = append(contexts, context)
+					break
+				}
+			}
+		}
+
+		if len(contexts) == 0 {
+			term.StopSpinner()
+			fmt.Println("🤷‍♂️ No context matches")
+			return
+		}
+	}
+
+	outdated, err := lib.CheckOutdatedContext(contexts)
+
+	term.StopSpinner()
 
 	if err != nil {
-		term.StopSpinner()
 		term.OutputErrorAndExit("failed to check outdated context: %s", err)
 	}
 
 	if len(outdated.UpdatedContexts) == 0 {
-		term.StopSpinner()
 		fmt.Println("✅ Context is up to date")
 		return
 	}
 
-	lib.MustUpdateContext(nil)
+	fmt.Println(lib.TableForContextOutdated(outdated))
+
+	lib.MustUpdateContext(outdated.UpdatedContexts)
 }
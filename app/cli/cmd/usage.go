@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/lib"
+	"plandex/term"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
+)
+
+var usageDays int
+var usageCurrent bool
+var usageOrg bool
+
+var usageExportFormat string
+var usageExportSince string
+var usageExportUntil string
+var usageExportOutput string
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show model token usage and spend by plan, day, and model",
+	Args:  cobra.NoArgs,
+	Run:   usage,
+}
+
+var usageExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export everyone's API calls and model usage in the org by day and user",
+	Long: `Export everyone's API calls and model usage in the org, with a row per user per day, for internal chargeback.
+
+Days are in UTC. Without --since and --until, the current month is exported.`,
+	Args: cobra.NoArgs,
+	Run:  exportUsage,
+}
+
+func init() {
+	RootCmd.AddCommand(usageCmd)
+	usageCmd.AddCommand(usageExportCmd)
+
+	usageCmd.Flags().IntVarP(&usageDays, "days", "d", 30, "Number of days to include, counting today")
+	usageCmd.Flags().BoolVar(&usageCurrent, "current", false, "Only include the current plan")
+	usageCmd.Flags().BoolVar(&usageOrg, "org", false, "Show everyone in the org by user")
+
+	usageExportCmd.Flags().StringVarP(&usageExportFormat, "format", "f", "csv", "csv or json")
+	usageExportCmd.Flags().StringVar(&usageExportSince, "since", "", "First day to include, as YYYY-MM-DD")
+	usageExportCmd.Flags().StringVar(&usageExportUntil, "until", "", "Last day to include, as YYYY-MM-DD")
+	usageExportCmd.Flags().StringVarP(&usageExportOutput, "output", "o", "", "File to write to instead of stdout")
+}
+
+func usage(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+
+	if usageDays < 1 {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "--days must be at least 1")
+	}
+
+	if usageOrg && usageCurrent {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "--org and --current can't be used together")
+	}
+
+	var planId string
+	if usageCurrent {
+		lib.MustResolveProject()
+
+		if lib.CurrentPlanId == "" {
+			fmt.Println("🤷‍♂️ No current plan")
+			return
+		}
+		planId = lib.CurrentPlanId
+	}
+
+	now := time.Now().UTC()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -(usageDays - 1))
+
+	if usageOrg {
+		orgUsage(since)
+		return
+	}
+
+	term.StartSpinner("")
+	res, apiErr := api.Client.GetUsage(planId, since)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting usage: %v", apiErr.Msg)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(res)
+		return
+	}
+
+	if res.Total.NumRequests == 0 {
+		fmt.Printf("🤷‍♂️ No model usage in the last %d days\n", usageDays)
+		return
+	}
+
+	color.New(color.Bold, term.ColorHiCyan).Printf("Spent %s in the last %d days\n", formatUsd(res.Total.CostUsd), usageDays)
+	if res.Total.CacheSavingsUsd > 0 {
+		fmt.Printf("Prompt caching saved %s on %d cached input 🪙\n", formatUsd(res.Total.CacheSavingsUsd), res.Total.CachedInputTokens)
+	}
+	fmt.Println()
+
+	if !usageCurrent {
+		printUsageTable("Plan", res.ByPlan)
+	}
+	printUsageTable("Day", res.ByDay)
+	printUsageTable("Model", res.ByModel)
+}
+
+func orgUsage(since time.Time) {
+	term.StartSpinner("")
+	res, apiErr := api.Client.GetOrgUsage(since, time.Time{})
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting org usage: %v", apiErr.Msg)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(res)
+		return
+	}
+
+	if len(res.ByUser) == 0 {
+		fmt.Printf("🤷‍♂️ No usage in the org in the last %d days\n", usageDays)
+		return
+	}
+
+	color.New(color.Bold, term.ColorHiCyan).Printf("The org spent %s in the last %d days\n", formatUsd(res.Total.CostUsd), usageDays)
+	fmt.Println()
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"User", "API Calls", "Requests", "Input", "Cached", "Output", "Cost"})
+
+	for _, row := range res.ByUser {
+		table.Rich([]string{
+			row.UserEmail,
+			strconv.Itoa(row.ApiCalls),
+			strconv.Itoa(row.ModelRequests),
+			strconv.Itoa(row.InputTokens) + " 🪙",
+			strconv.Itoa(row.CachedInputTokens) + " 🪙",
+			strconv.Itoa(row.OutputTokens) + " 🪙",
+			formatUsd(row.CostUsd),
+		}, term.TableColors([]tablewriter.Colors{{tablewriter.Bold}}))
+	}
+
+	table.Render()
+	fmt.Println()
+	term.PrintCmds("", "usage export")
+}
+
+func exportUsage(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+
+	if usageExportFormat != "csv" && usageExportFormat != "json" {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "--format must be csv or json")
+	}
+
+	// zero times are left to the server, which defaults to the current month
+	var since, until time.Time
+	if usageExportSince != "" {
+		var err error
+		since, err = time.Parse("2006-01-02", usageExportSince)
+		if err != nil {
+			term.OutputErrorAndExitWithCode(term.ExitUsage, "--since must be a date like 2024-06-01")
+		}
+	}
+	if usageExportUntil != "" {
+		lastDay, err := time.Parse("2006-01-02", usageExportUntil)
+		if err != nil {
+			term.OutputErrorAndExitWithCode(term.ExitUsage, "--until must be a date like 2024-06-30")
+		}
+		// the server's until is exclusive
+		until = lastDay.AddDate(0, 0, 1)
+	}
+
+	term.StartSpinner("")
+	res, apiErr := api.Client.GetOrgUsage(since, until)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting org usage: %v", apiErr.Msg)
+	}
+
+	out := os.Stdout
+	if usageExportOutput != "" {
+		f, err := os.Create(usageExportOutput)
+		if err != nil {
+			term.OutputErrorAndExit("Error creating %s: %v", usageExportOutput, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	var err error
+	if usageExportFormat == "csv" {
+		err = res.WriteCsv(out)
+	} else {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(res)
+	}
+	if err != nil {
+		term.OutputErrorAndExit("Error writing usage: %v", err)
+	}
+
+	if usageExportOutput != "" {
+		fmt.Printf("✅ Exported %d rows to %s\n", len(res.ByDayAndUser), usageExportOutput)
+	}
+}
+
+func printUsageTable(keyHeader string, rows []*shared.UsageRow) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{keyHeader, "Requests", "Input", "Cached", "Output", "Cost", "Saved"})
+
+	for _, row := range rows {
+		key := row.Key
+		if key == "" {
+			key = "(deleted)"
+		}
+
+		table.Rich([]string{
+			key,
+			strconv.Itoa(row.NumRequests),
+			strconv.Itoa(row.InputTokens) + " 🪙",
+			strconv.Itoa(row.CachedInputTokens) + " 🪙",
+			strconv.Itoa(row.OutputTokens) + " 🪙",
+			formatUsd(row.CostUsd),
+			formatUsd(row.CacheSavingsUsd),
+		}, term.TableColors([]tablewriter.Colors{{tablewriter.Bold}}))
+	}
+
+	table.Render()
+	fmt.Println()
+}
+
+func formatUsd(amount float64) string {
+	return fmt.Sprintf("$%.2f", amount)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+	"plandex/auth"
+	"plandex/lib"
+
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:     "watch",
+	Aliases: []string{"w"},
+	Short:   "Watch context files and update them when they change",
+	Args:    cobra.NoArgs,
+	Run:     watch,
+}
+
+func init() {
+	RootCmd.AddCommand(watchCmd)
+}
+
+func watch(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		fmt.Println("🤷‍♂️ No current plan")
+		return
+	}
+
+	lib.MustWatchContext()
+}
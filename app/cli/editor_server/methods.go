@@ -0,0 +1,236 @@
+package editor_server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"plandex/api"
+	"plandex/fs"
+	"plandex/lib"
+	"plandex/types"
+	"sync"
+
+	"github.com/plandex/plandex/shared"
+)
+
+type server struct {
+	// only one prompt streams at a time, like in the terminal
+	mu        sync.Mutex
+	streaming bool
+}
+
+func newServer() *server {
+	return &server{}
+}
+
+type currentPlanResult struct {
+	PlanId      string `json:"planId"`
+	Branch      string `json:"branch"`
+	ProjectRoot string `json:"projectRoot"`
+}
+
+type loadContextParams struct {
+	// files, directories, line ranges, or urls, as they'd be passed to 'plandex load'. Relative paths are relative to the
+	// directory the server was started in.
+	Paths     []string `json:"paths"`
+	Note      string   `json:"note"`
+	Recursive bool     `json:"recursive"`
+}
+
+type tellParams struct {
+	Prompt       string `json:"prompt"`
+	AutoContinue bool   `json:"autoContinue"`
+	NoBuild      bool   `json:"noBuild"`
+}
+
+type respondMissingFileParams struct {
+	Path   string                          `json:"path"`
+	Choice shared.RespondMissingFileChoice `json:"choice"`
+}
+
+type applyParams struct {
+	// only apply changes to these files, by path or the number shown by 'plandex changes'
+	Paths []string `json:"paths"`
+}
+
+func (s *server) dispatch(c *conn, method string, rawParams json.RawMessage) (interface{}, *rpcError) {
+	switch method {
+	case "plan.current":
+		return &currentPlanResult{PlanId: lib.CurrentPlanId, Branch: lib.CurrentBranch, ProjectRoot: fs.ProjectRoot}, nil
+
+	case "context.list":
+		contexts, apiErr := api.Client.ListContext(lib.CurrentPlanId, lib.CurrentBranch)
+		if apiErr != nil {
+			return nil, serverError("error listing context: %s", apiErr.Msg)
+		}
+		return contexts, nil
+
+	case "context.load":
+		var params loadContextParams
+		if rpcErr := decodeParams(rawParams, &params); rpcErr != nil {
+			return nil, rpcErr
+		}
+		if len(params.Paths) == 0 && params.Note == "" {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "paths or note is required"}
+		}
+
+		res, err := lib.LoadContext(context.Background(), params.Paths, &types.LoadContextParams{
+			Note:      params.Note,
+			Recursive: params.Recursive,
+		})
+		if err != nil {
+			return nil, serverError("%v", err)
+		}
+		return res, nil
+
+	case "plan.tell":
+		var params tellParams
+		if rpcErr := decodeParams(rawParams, &params); rpcErr != nil {
+			return nil, rpcErr
+		}
+		if params.Prompt == "" {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "prompt is required"}
+		}
+		return nil, s.tell(c, params)
+
+	case "plan.stop":
+		apiErr := api.Client.StopPlan(lib.CurrentPlanId, lib.CurrentBranch)
+		if apiErr != nil {
+			return nil, serverError("error stopping plan: %s", apiErr.Msg)
+		}
+		return nil, nil
+
+	case "plan.respondMissingFile":
+		var params respondMissingFileParams
+		if rpcErr := decodeParams(rawParams, &params); rpcErr != nil {
+			return nil, rpcErr
+		}
+		return nil, respondMissingFile(params)
+
+	case "changes.list":
+		state, apiErr := api.Client.GetCurrentPlanState(lib.CurrentPlanId, lib.CurrentBranch)
+		if apiErr != nil {
+			return nil, serverError("error getting current plan state: %s", apiErr.Msg)
+		}
+		return state, nil
+
+	case "changes.apply":
+		var params applyParams
+		if rpcErr := decodeParams(rawParams, &params); rpcErr != nil {
+			return nil, rpcErr
+		}
+
+		res, err := lib.ApplyPending(lib.CurrentPlanId, lib.CurrentBranch, params.Paths)
+		if err != nil {
+			return nil, serverError("%v", err)
+		}
+		return res, nil
+	}
+
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + method}
+}
+
+func decodeParams(rawParams json.RawMessage, params interface{}) *rpcError {
+	if len(rawParams) == 0 {
+		return nil
+	}
+	err := json.Unmarshal(rawParams, params)
+	if err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// tell sends a prompt and forwards the plan's stream to the editor as plan.stream notifications until it finishes, errors,
+// or is stopped. It returns once the prompt is sent.
+func (s *server) tell(c *conn, params tellParams) *rpcError {
+	if os.Getenv("OPENAI_API_KEY") == "" {
+		return serverError("OPENAI_API_KEY environment variable is not set")
+	}
+
+	s.mu.Lock()
+	if s.streaming {
+		s.mu.Unlock()
+		return serverError("a prompt is already streaming")
+	}
+	s.streaming = true
+	s.mu.Unlock()
+
+	finish := func() {
+		s.mu.Lock()
+		s.streaming = false
+		s.mu.Unlock()
+	}
+
+	contexts, apiErr := api.Client.ListContext(lib.CurrentPlanId, lib.CurrentBranch)
+	if apiErr != nil {
+		finish()
+		return serverError("error getting context: %s", apiErr.Msg)
+	}
+
+	paths, err := fs.GetProjectPaths(fs.GetBaseDirForContexts(contexts))
+	if err != nil {
+		finish()
+		return serverError("error getting project paths: %v", err)
+	}
+
+	buildMode := shared.BuildModeAuto
+	if params.NoBuild {
+		buildMode = shared.BuildModeNone
+	}
+
+	apiErr = api.Client.TellPlan(lib.CurrentPlanId, lib.CurrentBranch, shared.TellPlanRequest{
+		Prompt:        params.Prompt,
+		ConnectStream: true,
+		AutoContinue:  params.AutoContinue,
+		ProjectPaths:  paths.ActivePaths,
+		BuildMode:     buildMode,
+		ApiKey:        os.Getenv("OPENAI_API_KEY"),
+	}, func(streamParams types.OnStreamPlanParams) {
+		msg := streamParams.Msg
+		if streamParams.Err != nil {
+			msg = &shared.StreamMessage{
+				Type:  shared.StreamMessageError,
+				Error: &shared.ApiError{Msg: streamParams.Err.Error()},
+			}
+		}
+
+		c.notify("plan.stream", msg)
+
+		if msg.Type == shared.StreamMessageFinished || msg.Type == shared.StreamMessageError || msg.Type == shared.StreamMessageAborted {
+			finish()
+		}
+	})
+
+	if apiErr != nil {
+		finish()
+		return serverError("prompt error: %s", apiErr.Msg)
+	}
+
+	return nil
+}
+
+// respondMissingFile answers a prompt in the stream about a file that's being changed but isn't in context, like the
+// stream UI does when the user picks an option
+func respondMissingFile(params respondMissingFileParams) *rpcError {
+	switch params.Choice {
+	case shared.RespondMissingFileChoiceLoad, shared.RespondMissingFileChoiceSkip, shared.RespondMissingFileChoiceOverwrite:
+	default:
+		return &rpcError{Code: codeInvalidParams, Message: "choice must be load, skip, or overwrite"}
+	}
+
+	bytes, err := os.ReadFile(params.Path)
+	if err != nil {
+		return serverError("failed to read file: %v", err)
+	}
+
+	apiErr := api.Client.RespondMissingFile(lib.CurrentPlanId, lib.CurrentBranch, shared.RespondMissingFileRequest{
+		Choice:   params.Choice,
+		FilePath: params.Path,
+		Body:     string(bytes),
+	})
+	if apiErr != nil {
+		return serverError("error responding to missing file prompt: %s", apiErr.Msg)
+	}
+	return nil
+}
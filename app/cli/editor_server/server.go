@@ -0,0 +1,206 @@
+package editor_server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// The editor server speaks JSON-RPC 2.0 over a unix socket, one message per line. Editor plugins call methods to load
+// context, send prompts, and list and apply changes, and get a plan's stream as plan.stream notifications.
+
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeServerError    = -32000
+)
+
+// requests can carry whole prompts or notes, so lines can be long
+const maxMessageSize = 32 * 1024 * 1024
+
+type request struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type notification struct {
+	JsonRpc string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func serverError(msg string, args ...interface{}) *rpcError {
+	return &rpcError{Code: codeServerError, Message: fmt.Sprintf(msg, args...)}
+}
+
+// conn is a connected editor. Requests are handled concurrently, so writes are serialized.
+type conn struct {
+	netConn net.Conn
+	mu      sync.Mutex
+}
+
+func (c *conn) send(msg interface{}) {
+	bytes, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("editor server: error marshalling message: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err = c.netConn.Write(append(bytes, '\n'))
+	if err != nil {
+		log.Printf("editor server: error writing message: %v", err)
+	}
+}
+
+func (c *conn) notify(method string, params interface{}) {
+	c.send(notification{JsonRpc: "2.0", Method: method, Params: params})
+}
+
+// Serve listens on socketPath until the process is interrupted, then removes the socket
+func Serve(socketPath string) error {
+	err := removeStaleSocket(socketPath)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", socketPath, err)
+	}
+
+	// the socket can load context and apply changes, so only the user can connect
+	err = os.Chmod(socketPath, 0600)
+	if err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to set socket permissions: %v", err)
+	}
+
+	// closing the listener also removes the socket
+	defer listener.Close()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	done := make(chan struct{})
+	go func() {
+		<-sigCh
+		close(done)
+		listener.Close()
+	}()
+
+	s := newServer()
+
+	for {
+		netConn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-done:
+				return nil
+			default:
+				return fmt.Errorf("failed to accept connection: %v", err)
+			}
+		}
+		go s.handleConn(&conn{netConn: netConn})
+	}
+}
+
+// removeStaleSocket removes a socket left behind by a server that didn't shut down cleanly, but not one that's in use
+func removeStaleSocket(socketPath string) error {
+	if _, err := os.Stat(socketPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	c, err := net.Dial("unix", socketPath)
+	if err == nil {
+		c.Close()
+		return fmt.Errorf("an editor server is already running on %s", socketPath)
+	}
+
+	err = os.Remove(socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to remove stale socket %s: %v", socketPath, err)
+	}
+	return nil
+}
+
+func (s *server) handleConn(c *conn) {
+	defer c.netConn.Close()
+
+	scanner := bufio.NewScanner(c.netConn)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req request
+		err := json.Unmarshal(line, &req)
+		if err != nil {
+			c.send(response{JsonRpc: "2.0", Id: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: err.Error()}})
+			continue
+		}
+
+		if req.JsonRpc != "2.0" || req.Method == "" {
+			c.send(response{JsonRpc: "2.0", Id: idOrNull(req.Id), Error: &rpcError{Code: codeInvalidRequest, Message: "invalid request"}})
+			continue
+		}
+
+		go func() {
+			result, rpcErr := s.dispatch(c, req.Method, req.Params)
+
+			// requests without an id are notifications, which don't get a response
+			if req.Id == nil {
+				return
+			}
+
+			res := response{JsonRpc: "2.0", Id: req.Id}
+			if rpcErr != nil {
+				res.Error = rpcErr
+			} else if result == nil {
+				res.Result = struct{}{}
+			} else {
+				res.Result = result
+			}
+			c.send(res)
+		}()
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Printf("editor server: error reading from connection: %v", err)
+	}
+}
+
+func idOrNull(id json.RawMessage) json.RawMessage {
+	if id == nil {
+		return json.RawMessage("null")
+	}
+	return id
+}
@@ -13,7 +13,6 @@ import (
 	"sync"
 
 	"github.com/plandex/plandex/shared"
-	ignore "github.com/sabhiram/go-gitignore"
 )
 
 var Cwd string
@@ -25,6 +24,10 @@ var CacheDir string
 var HomeDir string
 var HomeAuthPath string
 var HomeAccountsPath string
+var HomeUrlCredentialsPath string
+var HomeDefaultIgnorePath string
+var HomeConfigPath string
+var HomeTrustedCommandsPath string
 
 func init() {
 	var err error
@@ -54,6 +57,10 @@ func init() {
 	CacheDir = filepath.Join(HomePlandexDir, "cache")
 	HomeAuthPath = filepath.Join(HomePlandexDir, "auth.json")
 	HomeAccountsPath = filepath.Join(HomePlandexDir, "accounts.json")
+	HomeUrlCredentialsPath = filepath.Join(HomePlandexDir, "url-credentials.json")
+	HomeDefaultIgnorePath = filepath.Join(HomePlandexDir, "default.plandexignore")
+	HomeConfigPath = filepath.Join(HomePlandexDir, "config.yml")
+	HomeTrustedCommandsPath = filepath.Join(HomePlandexDir, "trusted-commands.json")
 
 	err = os.MkdirAll(filepath.Join(CacheDir, "tiktoken"), os.ModePerm)
 	if err != nil {
@@ -112,9 +119,10 @@ func IsGitRepo(dir string) bool {
 
 		cmd.Dir = dir
 
-		err := cmd.Run()
+		// prints false rather than failing inside a .git dir
+		out, err := cmd.Output()
 
-		if err == nil {
+		if err == nil && strings.TrimSpace(string(out)) == "true" {
 			isGitRepo = true
 		}
 	}
@@ -125,7 +133,7 @@ func IsGitRepo(dir string) bool {
 type ProjectPaths struct {
 	ActivePaths    map[string]bool
 	AllPaths       map[string]bool
-	PlandexIgnored *ignore.GitIgnore
+	PlandexIgnored *PlandexIgnore
 	IgnoredPaths   map[string]string
 }
 
@@ -138,7 +146,10 @@ func GetProjectPaths(baseDir string) (*ProjectPaths, error) {
 }
 
 func GetPaths(baseDir, currentDir string) (*ProjectPaths, error) {
-	ignored, err := GetPlandexIgnore(currentDir)
+	isGitRepo := IsGitRepo(baseDir)
+
+	// git repos have .gitignore for dependency and build dirs, so the defaults are only needed outside of git
+	ignored, err := GetPlandexIgnore(currentDir, !isGitRepo)
 
 	if err != nil {
 		return nil, err
@@ -150,39 +161,38 @@ func GetPaths(baseDir, currentDir string) (*ProjectPaths, error) {
 	allDirs := map[string]bool{}
 	activeDirs := map[string]bool{}
 
-	isGitRepo := IsGitRepo(baseDir)
-
 	errCh := make(chan error)
 	var mu sync.Mutex
 	numRoutines := 0
 
-	if isGitRepo {
-		// combine `git ls-files` and `git ls-files --others --exclude-standard`
-		// to get all files in the repo
-
-		numRoutines++
-		go func() {
-			// get all tracked files in the repo
-			cmd := exec.Command("git", "ls-files")
-			cmd.Dir = baseDir
-			out, err := cmd.Output()
-
-			if err != nil {
-				errCh <- fmt.Errorf("error getting files in git repo: %s", err)
-				return
-			}
+	nestedWorktrees := map[string]bool{}
 
-			files := strings.Split(string(out), "\n")
+	if isGitRepo {
+		nestedWorktrees, err = gitNestedWorktrees(baseDir)
+		if err != nil {
+			return nil, err
+		}
 
+		// adds files listed by git relative to dir as active, along with their parent dirs
+		addGitFiles := func(dir string, files []string) error {
 			mu.Lock()
 			defer mu.Unlock()
+
 			for _, file := range files {
-				absFile := filepath.Join(baseDir, file)
+				if file == "" {
+					continue
+				}
+
+				absFile := filepath.Join(dir, file)
+
+				if isInNestedWorktree(absFile, nestedWorktrees) {
+					continue
+				}
+
 				relFile, err := filepath.Rel(currentDir, absFile)
 
 				if err != nil {
-					errCh <- fmt.Errorf("error getting relative path: %s", err)
-					return
+					return fmt.Errorf("error getting relative path: %s", err)
 				}
 
 				if ignored != nil && ignored.MatchesPath(relFile) {
@@ -192,50 +202,61 @@ func GetPaths(baseDir, currentDir string) (*ProjectPaths, error) {
 				activePaths[relFile] = true
 
 				parentDir := relFile
-				for parentDir != "." && parentDir != "/" && parentDir != "" {
+				for !IsTopDir(parentDir) {
 					parentDir = filepath.Dir(parentDir)
 					activeDirs[parentDir] = true
 				}
 			}
 
-			errCh <- nil
-		}()
+			return nil
+		}
+
+		// combine `git ls-files --recurse-submodules` and `git ls-files --others --exclude-standard`
+		// in the repo and each submodule to get all files in the repo
 
-		// get all untracked non-ignored files in the repo
 		numRoutines++
 		go func() {
-			cmd := exec.Command("git", "ls-files", "--others", "--exclude-standard")
+			// get all tracked files in the repo, including submodules
+			cmd := exec.Command("git", "ls-files", "--recurse-submodules")
 			cmd.Dir = baseDir
 			out, err := cmd.Output()
 
 			if err != nil {
-				errCh <- fmt.Errorf("error getting untracked files in git repo: %s", err)
+				errCh <- fmt.Errorf("error getting files in git repo: %s", err)
 				return
 			}
 
-			files := strings.Split(string(out), "\n")
+			errCh <- addGitFiles(baseDir, strings.Split(string(out), "\n"))
+		}()
 
-			mu.Lock()
-			defer mu.Unlock()
-			for _, file := range files {
-				absFile := filepath.Join(baseDir, file)
-				relFile, err := filepath.Rel(currentDir, absFile)
+		// get all untracked non-ignored files in the repo and each submodule
+		numRoutines++
+		go func() {
+			dirs := []string{baseDir}
+
+			submodules, err := gitSubmodulePaths(baseDir)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			for _, submodule := range submodules {
+				dirs = append(dirs, filepath.Join(baseDir, submodule))
+			}
+
+			for _, dir := range dirs {
+				cmd := exec.Command("git", "ls-files", "--others", "--exclude-standard")
+				cmd.Dir = dir
+				out, err := cmd.Output()
 
 				if err != nil {
-					errCh <- fmt.Errorf("error getting relative path: %s", err)
+					errCh <- fmt.Errorf("error getting untracked files in git repo: %s", err)
 					return
 				}
 
-				if ignored != nil && ignored.MatchesPath(relFile) {
-					continue
-				}
-
-				activePaths[relFile] = true
-
-				parentDir := relFile
-				for parentDir != "." && parentDir != "/" && parentDir != "" {
-					parentDir = filepath.Dir(parentDir)
-					activeDirs[parentDir] = true
+				err = addGitFiles(dir, strings.Split(string(out), "\n"))
+				if err != nil {
+					errCh <- err
+					return
 				}
 			}
 
@@ -246,7 +267,7 @@ func GetPaths(baseDir, currentDir string) (*ProjectPaths, error) {
 	// get all paths in the directory
 	numRoutines++
 	go func() {
-		err = filepath.Walk(baseDir, func(path string, info os.FileInfo, err error) error {
+		err = Walk(baseDir, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
@@ -258,6 +279,9 @@ func GetPaths(baseDir, currentDir string) (*ProjectPaths, error) {
 				if info.Name() == ".plandex" || info.Name() == ".plandex-dev" {
 					return filepath.SkipDir
 				}
+				if nestedWorktrees[path] {
+					return filepath.SkipDir
+				}
 
 				relPath, err := filepath.Rel(currentDir, path)
 				if err != nil {
@@ -287,7 +311,7 @@ func GetPaths(baseDir, currentDir string) (*ProjectPaths, error) {
 					activePaths[relPath] = true
 
 					parentDir := relPath
-					for parentDir != "." && parentDir != "/" && parentDir != "" {
+					for !IsTopDir(parentDir) {
 						parentDir = filepath.Dir(parentDir)
 						activeDirs[parentDir] = true
 					}
@@ -312,6 +336,24 @@ func GetPaths(baseDir, currentDir string) (*ProjectPaths, error) {
 		}
 	}
 
+	if isGitRepo {
+		// git lists a symlinked dir as a single file, so files under an active symlinked dir are active too
+		for path := range allPaths {
+			if activePaths[path] || (ignored != nil && ignored.MatchesPath(path)) {
+				continue
+			}
+
+			parentDir := path
+			for !IsTopDir(parentDir) {
+				parentDir = filepath.Dir(parentDir)
+				if activePaths[parentDir] && allDirs[parentDir] && isSymlink(filepath.Join(currentDir, parentDir)) {
+					activePaths[path] = true
+					break
+				}
+			}
+		}
+	}
+
 	for dir := range allDirs {
 		allPaths[dir] = true
 	}
@@ -339,29 +381,11 @@ func GetPaths(baseDir, currentDir string) (*ProjectPaths, error) {
 	}, nil
 }
 
-func GetPlandexIgnore(dir string) (*ignore.GitIgnore, error) {
-	ignorePath := filepath.Join(dir, ".plandexignore")
-
-	if _, err := os.Stat(ignorePath); err == nil {
-		ignored, err := ignore.CompileIgnoreFile(ignorePath)
-
-		if err != nil {
-			return nil, fmt.Errorf("error reading .plandexignore file: %s", err)
-		}
-
-		return ignored, nil
-	} else if !os.IsNotExist(err) {
-		return nil, fmt.Errorf("error checking for .plandexignore file: %s", err)
-	}
-
-	return nil, nil
-}
-
 func GetParentProjectIdsWithPaths() ([][2]string, error) {
 	var parentProjectIds [][2]string
 	currentDir := filepath.Dir(Cwd)
 
-	for currentDir != "/" {
+	for !IsRoot(currentDir) {
 		plandexDir := findPlandex(currentDir)
 		projectSettingsPath := filepath.Join(plandexDir, "project.json")
 		if _, err := os.Stat(projectSettingsPath); err == nil {
@@ -468,7 +492,7 @@ func GetBaseDirForFilePaths(paths []string) string {
 	for _, path := range paths {
 		currentDir := ProjectRoot
 
-		pathSplit := strings.Split(path, string(os.PathSeparator))
+		pathSplit := SplitPath(path)
 
 		n := 0
 		for _, p := range pathSplit {
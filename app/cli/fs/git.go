@@ -0,0 +1,91 @@
+package fs
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitSubmodulePaths returns the paths of all submodules under dir, recursively, relative to dir
+func gitSubmodulePaths(dir string) ([]string, error) {
+	cmd := exec.Command("git", "submodule", "foreach", "--quiet", "--recursive", "echo \"$displaypath\"")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error getting submodules: %s", err)
+	}
+
+	var paths []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			paths = append(paths, filepath.FromSlash(line))
+		}
+	}
+
+	return paths, nil
+}
+
+// gitNestedWorktrees returns the absolute paths of other worktrees of the repo at dir that are checked out inside dir.
+// They're separate checkouts of the same source, so they're left out of the project's paths rather than loaded twice.
+func gitNestedWorktrees(dir string) (map[string]bool, error) {
+	root, err := gitRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("git", "worktree", "list", "--porcelain")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error listing worktrees: %s", err)
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error getting absolute path: %s", err)
+	}
+	// git reports worktree paths with symlinks resolved
+	if realDir, err := filepath.EvalSymlinks(absDir); err == nil {
+		absDir = realDir
+	}
+
+	worktrees := map[string]bool{}
+	for _, line := range strings.Split(string(out), "\n") {
+		path, ok := strings.CutPrefix(line, "worktree ")
+		if !ok {
+			continue
+		}
+		path = filepath.Clean(path)
+		if path != root && path != absDir && isWithinDir(path, absDir) {
+			rel, err := filepath.Rel(absDir, path)
+			if err != nil {
+				continue
+			}
+			worktrees[filepath.Join(dir, rel)] = true
+		}
+	}
+
+	return worktrees, nil
+}
+
+// gitRoot returns the root of the git worktree containing dir. Inside a linked worktree, that's the worktree's own root rather than the main repo's.
+func gitRoot(dir string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error getting git root: %s", err)
+	}
+	return filepath.Clean(strings.TrimSpace(string(out))), nil
+}
+
+func isInNestedWorktree(path string, nestedWorktrees map[string]bool) bool {
+	for worktree := range nestedWorktrees {
+		if isWithinDir(path, worktree) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+// PlandexIgnore merges the .plandexignore files in a directory tree. Like .gitignore, each file's patterns are relative to its own directory,
+// and deeper files take precedence, so a nested file can re-include paths that a parent file ignores with a !pattern.
+type PlandexIgnore struct {
+	// ordered so parent directories come before their children
+	levels []*ignoreLevel
+}
+
+type ignoreLevel struct {
+	// slash-separated and relative to the root dir, or "" for the root
+	dir     string
+	ignored *ignore.GitIgnore
+	// the file's !pattern lines without the !, to tell when a path is explicitly re-included rather than just not matched
+	negated *ignore.GitIgnore
+}
+
+// DefaultIgnorePatterns cover dependency, virtualenv, build output, and cache dirs.
+// They can be replaced by writing patterns to default.plandexignore in the Plandex home dir.
+var DefaultIgnorePatterns = []string{
+	"node_modules/",
+	"bower_components/",
+	"vendor/",
+	".venv/",
+	"venv/",
+	"__pycache__/",
+	"*.pyc",
+	".tox/",
+	".mypy_cache/",
+	".pytest_cache/",
+	"dist/",
+	"build/",
+	"out/",
+	"target/",
+	".next/",
+	".nuxt/",
+	".svelte-kit/",
+	".turbo/",
+	".cache/",
+	".gradle/",
+	"coverage/",
+	".DS_Store",
+	"*.min.js",
+	"*.map",
+}
+
+// GetPlandexIgnore loads the .plandexignore files in dir and its subdirectories, returning nil if there are none.
+// If useDefaults is set and dir has no .plandexignore of its own, the default patterns apply in its place.
+// Subdirectories that are already ignored aren't searched, since nothing inside them can be re-included.
+func GetPlandexIgnore(dir string, useDefaults bool) (*PlandexIgnore, error) {
+	res := &PlandexIgnore{}
+
+	if useDefaults {
+		if _, err := os.Stat(filepath.Join(dir, ".plandexignore")); os.IsNotExist(err) {
+			level, err := getDefaultIgnoreLevel()
+			if err != nil {
+				return nil, err
+			}
+			res.levels = append(res.levels, level)
+		}
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		if info.Name() == ".git" || info.Name() == ".plandex" || info.Name() == ".plandex-dev" {
+			return filepath.SkipDir
+		}
+
+		relDir, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		relDir = filepath.ToSlash(relDir)
+		if relDir == "." {
+			relDir = ""
+		}
+
+		if relDir != "" && res.MatchesPath(relDir) {
+			return filepath.SkipDir
+		}
+
+		level, err := loadIgnoreLevel(path, relDir)
+		if err != nil {
+			return err
+		}
+		if level != nil {
+			res.levels = append(res.levels, level)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading .plandexignore files: %s", err)
+	}
+
+	if len(res.levels) == 0 {
+		return nil, nil
+	}
+
+	return res, nil
+}
+
+func getDefaultIgnoreLevel() (*ignoreLevel, error) {
+	patterns := DefaultIgnorePatterns
+
+	bytes, err := os.ReadFile(HomeDefaultIgnorePath)
+	if err == nil {
+		patterns = strings.Split(string(bytes), "\n")
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading %s: %s", HomeDefaultIgnorePath, err)
+	}
+
+	return compileIgnoreLevel("", patterns), nil
+}
+
+func loadIgnoreLevel(dir, relDir string) (*ignoreLevel, error) {
+	ignorePath := filepath.Join(dir, ".plandexignore")
+
+	bytes, err := os.ReadFile(ignorePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading %s: %s", ignorePath, err)
+	}
+
+	return compileIgnoreLevel(relDir, strings.Split(string(bytes), "\n")), nil
+}
+
+func compileIgnoreLevel(relDir string, lines []string) *ignoreLevel {
+	var negatedLines []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "!") {
+			negatedLines = append(negatedLines, line[1:])
+		}
+	}
+
+	level := &ignoreLevel{
+		dir:     relDir,
+		ignored: ignore.CompileIgnoreLines(lines...),
+	}
+	if len(negatedLines) > 0 {
+		level.negated = ignore.CompileIgnoreLines(negatedLines...)
+	}
+
+	return level
+}
+
+// MatchesPath reports whether a path relative to the root dir is ignored
+func (pi *PlandexIgnore) MatchesPath(path string) bool {
+	path = filepath.ToSlash(path)
+
+	ignored := false
+	for _, level := range pi.levels {
+		subPath := path
+		if level.dir != "" {
+			if !strings.HasPrefix(path, level.dir+"/") {
+				continue
+			}
+			subPath = strings.TrimPrefix(path, level.dir+"/")
+		}
+
+		if level.ignored.MatchesPath(subPath) {
+			ignored = true
+		} else if level.negated != nil && level.negated.MatchesPath(subPath) {
+			ignored = false
+		}
+	}
+
+	return ignored
+}
@@ -0,0 +1,26 @@
+package fs
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// IsRoot reports whether path is a filesystem root: / on unix, or a drive root (C:\) or UNC share (\\server\share) on windows.
+// filepath.Dir returns a root unchanged, so a loop that walks up with filepath.Dir must stop at one rather than at "/".
+func IsRoot(path string) bool {
+	if !filepath.IsAbs(path) && filepath.VolumeName(path) == "" {
+		return false
+	}
+	path = filepath.Clean(path)
+	return filepath.Dir(path) == path
+}
+
+// IsTopDir reports whether filepath.Dir can't go any higher from dir: the top of a relative path ("." or "") or a filesystem root
+func IsTopDir(dir string) bool {
+	return dir == "" || dir == "." || IsRoot(dir)
+}
+
+// SplitPath splits a path into its elements. On windows, either separator is accepted since paths can come from the server or other platforms with forward slashes.
+func SplitPath(path string) []string {
+	return strings.Split(filepath.ToSlash(path), "/")
+}
@@ -0,0 +1,63 @@
+//go:build !windows
+
+package fs
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIsRoot(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/", true},
+		{"//", true},
+		{"/home", false},
+		{"/home/user/", false},
+		{".", false},
+		{"", false},
+		{"home", false},
+	}
+
+	for _, tt := range tests {
+		got := IsRoot(tt.path)
+		if got != tt.want {
+			t.Errorf("IsRoot(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIsTopDirStopsWalkingUp(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/home/user/project", "/"},
+		{"src/lib/file.go", "."},
+		{"file.go", "."},
+	}
+
+	for _, tt := range tests {
+		dir := tt.path
+		for i := 0; !IsTopDir(dir); i++ {
+			if i > 10 {
+				t.Fatalf("walking up from %q didn't stop", tt.path)
+			}
+			dir = filepath.Dir(dir)
+		}
+		if dir != tt.want {
+			t.Errorf("walking up from %q stopped at %q, want %q", tt.path, dir, tt.want)
+		}
+	}
+}
+
+func TestSplitPath(t *testing.T) {
+	got := SplitPath("../../src/file.go")
+	want := []string{"..", "..", "src", "file.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitPath = %v, want %v", got, want)
+	}
+}
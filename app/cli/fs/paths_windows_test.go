@@ -0,0 +1,76 @@
+package fs
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIsRoot(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{`C:\`, true},
+		{`c:\`, true},
+		{`C:`, true},
+		{`C:\Users`, false},
+		{`C:\Users\me\`, false},
+		{`\\server\share`, true},
+		{`\\server\share\`, true},
+		{`\\server\share\dir`, false},
+		{`.`, false},
+		{`src\lib`, false},
+	}
+
+	for _, tt := range tests {
+		got := IsRoot(tt.path)
+		if got != tt.want {
+			t.Errorf("IsRoot(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIsTopDirStopsWalkingUp(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`C:\Users\me\project`, `C:\`},
+		{`D:\project`, `D:\`},
+		{`\\server\share\team\project`, `\\server\share\`},
+		{`src\lib\file.go`, `.`},
+		{`file.go`, `.`},
+	}
+
+	for _, tt := range tests {
+		dir := tt.path
+		for i := 0; !IsTopDir(dir); i++ {
+			if i > 10 {
+				t.Fatalf("walking up from %q didn't stop", tt.path)
+			}
+			dir = filepath.Dir(dir)
+		}
+		if dir != tt.want {
+			t.Errorf("walking up from %q stopped at %q, want %q", tt.path, dir, tt.want)
+		}
+	}
+}
+
+func TestSplitPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{`..\..\src\file.go`, []string{"..", "..", "src", "file.go"}},
+		{`../../src/file.go`, []string{"..", "..", "src", "file.go"}},
+		{`..\../src\file.go`, []string{"..", "..", "src", "file.go"}},
+	}
+
+	for _, tt := range tests {
+		got := SplitPath(tt.path)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type SymlinkPolicy string
+
+const (
+	// follow symlinks, skipping any that would loop back into a directory already being walked
+	SymlinkFollow SymlinkPolicy = "follow"
+	// ignore symlinks entirely
+	SymlinkSkip SymlinkPolicy = "skip"
+	// follow symlinks, but fail on a cycle
+	SymlinkError SymlinkPolicy = "error"
+)
+
+// Symlinks is the policy used by Walk. It defaults to PLANDEX_SYMLINKS if set, otherwise follow.
+var Symlinks = SymlinkFollow
+
+func init() {
+	if s := os.Getenv("PLANDEX_SYMLINKS"); s != "" {
+		policy, err := ParseSymlinkPolicy(s)
+		if err == nil {
+			Symlinks = policy
+		}
+	}
+}
+
+func ParseSymlinkPolicy(s string) (SymlinkPolicy, error) {
+	switch policy := SymlinkPolicy(strings.ToLower(s)); policy {
+	case SymlinkFollow, SymlinkSkip, SymlinkError:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid symlink policy %q (expected follow, skip, or error)", s)
+}
+
+// Walk is like filepath.Walk, but handles symlinks according to Symlinks instead of treating every symlink as a file.
+// Paths under a followed symlink are reported under the link's path rather than the target's.
+// A symlink whose target is inside root isn't followed, since the target is walked anyway and would otherwise be loaded twice.
+// A symlink to a directory that's already being walked is a cycle.
+func Walk(root string, fn filepath.WalkFunc) error {
+	rootReal, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+
+	// the root is always followed since it was asked for explicitly
+	info, err := os.Stat(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+
+	w := &walker{rootReal: rootReal, fn: fn}
+	err = w.walk(root, rootReal, info, nil)
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+type walker struct {
+	rootReal string
+	fn       filepath.WalkFunc
+}
+
+func (w *walker) walk(path, realPath string, info os.FileInfo, ancestors []string) error {
+	err := w.fn(path, info, nil)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return nil
+	}
+
+	ancestors = append(ancestors, realPath)
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return w.fn(path, info, err)
+	}
+
+	for _, entry := range entries {
+		childPath := filepath.Join(path, entry.Name())
+		childReal := filepath.Join(realPath, entry.Name())
+
+		childInfo, err := os.Lstat(childPath)
+		if err != nil {
+			err = w.fn(childPath, nil, err)
+			if err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+
+		if childInfo.Mode()&os.ModeSymlink != 0 {
+			if Symlinks == SymlinkSkip {
+				continue
+			}
+
+			childReal, err = filepath.EvalSymlinks(childPath)
+			if err != nil {
+				// broken links are skipped
+				continue
+			}
+
+			childInfo, err = os.Stat(childPath)
+			if err != nil {
+				continue
+			}
+
+			if childInfo.IsDir() && isAncestor(ancestors, childReal) {
+				if Symlinks == SymlinkError {
+					return fmt.Errorf("symlink cycle: %s points to %s, which contains it", childPath, childReal)
+				}
+				continue
+			}
+
+			if isWithinDir(childReal, w.rootReal) {
+				continue
+			}
+		}
+
+		err = w.walk(childPath, childReal, childInfo, ancestors)
+		if err != nil {
+			if err == filepath.SkipDir && childInfo.IsDir() {
+				continue
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isAncestor(ancestors []string, realPath string) bool {
+	for _, ancestor := range ancestors {
+		if ancestor == realPath {
+			return true
+		}
+	}
+	return false
+}
+
+func isWithinDir(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator))
+}
+
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"plandex/api"
 	"plandex/term"
+	"strings"
 
 	"github.com/plandex/plandex/shared"
 )
@@ -37,7 +38,8 @@ func SelectActiveStream(args []string) (string, string, bool) {
 		for _, b := range res.Branches {
 			id := res.StreamIdByBranchId[b.Id]
 			plan := res.PlansById[b.PlanId]
-			if id == streamIdOrPlan || plan.Name == streamIdOrPlan {
+			// ps shows the first 4 characters of the stream id, so any prefix of it matches
+			if strings.HasPrefix(id, streamIdOrPlan) || plan.Name == streamIdOrPlan {
 				planId = b.PlanId
 				branch = b.Name
 				break
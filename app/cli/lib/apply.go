@@ -2,15 +2,21 @@ package lib
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"plandex/api"
 	"plandex/fs"
 	"plandex/term"
+	"plandex/types"
+	"sort"
 	"strings"
+
+	"github.com/fatih/color"
+	"github.com/plandex/plandex/shared"
 )
 
-func MustApplyPlan(planId, branch string, autoConfirm bool) {
+func MustApplyPlan(planId, branch string, opts ApplyOpts) {
 	term.StartSpinner("")
 
 	currentPlanState, apiErr := api.Client.GetCurrentPlanState(planId, branch)
@@ -60,7 +66,22 @@ func MustApplyPlan(planId, branch string, autoConfirm bool) {
 		}
 	}
 
-	anyOutdated, didUpdate := MustCheckOutdatedContext(true, nil)
+	// files with pending changes are merged with any local changes below, so only other context needs updating first
+	contexts, apiErr := api.Client.ListContext(planId, branch)
+	if apiErr != nil {
+		term.StopSpinner()
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting context: %v", apiErr.Msg)
+	}
+
+	var contextsToCheck []*shared.Context
+	for _, context := range contexts {
+		if _, pending := currentPlanState.CurrentPlanFiles.Files[context.FilePath]; pending && isWholeFileContext(context) {
+			continue
+		}
+		contextsToCheck = append(contextsToCheck, context)
+	}
+
+	anyOutdated, didUpdate := MustCheckOutdatedContext(true, contextsToCheck)
 
 	if anyOutdated && !didUpdate {
 		term.StopSpinner()
@@ -71,6 +92,11 @@ func MustApplyPlan(planId, branch string, autoConfirm bool) {
 	currentPlanFiles := currentPlanState.CurrentPlanFiles
 	isRepo := fs.ProjectRootIsGitRepo()
 
+	if opts.GitBranch && !isRepo {
+		term.StopSpinner()
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "--branch needs the project to be in a git repo")
+	}
+
 	toApply := currentPlanFiles.Files
 
 	if len(toApply) == 0 {
@@ -79,7 +105,63 @@ func MustApplyPlan(planId, branch string, autoConfirm bool) {
 		return
 	}
 
-	if !autoConfirm {
+	numPending := len(toApply)
+
+	// a nil request applies everything that's pending
+	var applyReq *shared.ApplyPlanRequest
+
+	if len(opts.Paths) > 0 {
+		selected, err := resolveApplyPaths(opts.Paths, toApply)
+		if err != nil {
+			term.StopSpinner()
+			term.OutputErrorAndExit("%v", err)
+		}
+		toApply = selected
+		applyReq = &shared.ApplyPlanRequest{}
+	}
+
+	if isRepo && !opts.Force {
+		mustCheckUncommittedTargets(toApply, opts.AutoConfirm)
+	}
+
+	mergeRes, err := mergeLocalChanges(currentPlanState.ContextsByPath, toApply)
+	if err != nil {
+		term.StopSpinner()
+		term.OutputErrorAndExit("failed to merge local changes: %v", err)
+	}
+	if len(mergeRes.contentByPath) > 0 {
+		merged := make(map[string]string, len(toApply))
+		for path, content := range toApply {
+			merged[path] = content
+		}
+		for path, content := range mergeRes.contentByPath {
+			merged[path] = content
+		}
+		toApply = merged
+	}
+
+	if opts.Interactive {
+		term.StopSpinner()
+		selected, partialByPath := mustSelectHunks(toApply)
+
+		if len(selected) == 0 {
+			fmt.Println("🤷‍♂️ No changes applied")
+			return
+		}
+
+		toApply = selected
+		applyReq = &shared.ApplyPlanRequest{PartialByPath: partialByPath}
+		term.ResumeSpinner()
+	}
+
+	if applyReq != nil {
+		for path := range toApply {
+			applyReq.Paths = append(applyReq.Paths, path)
+		}
+	}
+
+	// in interactive mode, each change was already confirmed
+	if !opts.AutoConfirm && !opts.Interactive {
 		term.StopSpinner()
 		numToApply := len(toApply)
 		suffix := ""
@@ -98,6 +180,11 @@ func MustApplyPlan(planId, branch string, autoConfirm bool) {
 		term.ResumeSpinner()
 	}
 
+	// project hooks and formatters are approved before anything is applied
+	term.StopSpinner()
+	config := MustLoadCommandConfig()
+	term.ResumeSpinner()
+
 	onErr := func(errMsg string, errArgs ...interface{}) {
 		term.StopSpinner()
 		term.OutputErrorAndExit(errMsg, errArgs...)
@@ -108,80 +195,130 @@ func MustApplyPlan(planId, branch string, autoConfirm bool) {
 		term.OutputSimpleError(errMsg, unformattedErrMsg)
 	}
 
-	apiErr = api.Client.ApplyPlan(planId, branch)
+	var originalGitBranch string
+	if opts.GitBranch {
+		term.StopSpinner()
+		originalGitBranch = mustCheckoutApplyBranch(planId, branch, opts.GitBranchName)
+		term.ResumeSpinner()
+	}
+
+	apiErr = api.Client.ApplyPlan(planId, branch, applyReq)
 
 	if apiErr != nil {
-		onErr("failed to set pending results applied: %s", apiErr.Msg)
+		term.StopSpinner()
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "failed to set pending results applied: %s", apiErr.Msg)
 		return
 	}
 
-	var updatedFiles []string
-	for path, content := range toApply {
-		// Compute destination path
-		dstPath := filepath.Join(fs.ProjectRoot, path)
+	// an apply that can't be recorded still goes ahead, just without rollback
+	tx, recordErr := beginApply(planId, branch)
 
-		content = strings.ReplaceAll(content, "\\`\\`\\`", "```")
+	written, err := writeApplyFiles(config, tx, toApply, mergeRes, applyReq)
+	if err != nil {
+		onErr("%v", err)
+		return
+	}
+	updatedFiles := written.updatedFiles
+	conflictsByPath := written.conflictsByPath
 
-		// Check if the file exists
-		var exists bool
-		_, err := os.Stat(dstPath)
-		if err == nil {
-			exists = true
-		} else {
-			if os.IsNotExist(err) {
-				exists = false
-			} else {
-				onErr("failed to check if %s exists:", dstPath)
-				return
-			}
-		}
+	if tx != nil {
+		recordErr = tx.complete()
+	}
 
-		if exists {
-			// read file content
-			bytes, err := os.ReadFile(dstPath)
+	term.StopSpinner()
 
-			if err != nil {
-				onErr("failed to read %s:", dstPath)
-				return
-			}
+	printFormatResults(written.formattedPaths, written.formatErrs)
 
-			// Check if the file has changed
-			if string(bytes) == content {
-				// log.Println("File is unchanged, skipping")
-				continue
-			} else {
-				updatedFiles = append(updatedFiles, path)
-			}
-		} else {
-			updatedFiles = append(updatedFiles, path)
+	if recordErr != nil {
+		fmt.Fprintln(os.Stderr, color.New(color.Bold, term.ColorHiYellow).Sprint(term.Plain(fmt.Sprintf("⚠️  Couldn't record this apply for rollback: %v", recordErr))))
+		fmt.Fprintln(os.Stderr)
+	}
 
-			// Create the directory if it doesn't exist
-			err := os.MkdirAll(filepath.Dir(dstPath), 0755)
-			if err != nil {
-				onErr("failed to create directory %s:", filepath.Dir(dstPath))
-				return
-			}
-		}
+	// runs after the message below about pending changes, since it exits
+	if len(conflictsByPath) > 0 {
+		defer func() {
+			printApplyConflicts(conflictsByPath)
+			os.Exit(term.ExitConflict)
+		}()
+	}
 
-		// Write the file
-		err = os.WriteFile(dstPath, []byte(content), 0644)
-		if err != nil {
-			onErr("failed to write %s:", dstPath)
-			return
+	var mergedPaths []string
+	for _, path := range mergeRes.cleanPaths {
+		if _, ok := toApply[path]; ok {
+			mergedPaths = append(mergedPaths, path)
 		}
 	}
+	if len(mergedPaths) > 0 {
+		fmt.Println("🔀 Merged with local changes made since context was loaded:")
+		for _, path := range mergedPaths {
+			fmt.Println("  • " + path)
+		}
+		fmt.Println()
+	}
 
-	term.StopSpinner()
+	anyStillPending := applyReq != nil && (len(toApply) < numPending || len(applyReq.PartialByPath) > 0)
+	if anyStillPending {
+		defer func() {
+			fmt.Println()
+			fmt.Println("⏳ Changes that weren't applied are still pending")
+			fmt.Println()
+			term.PrintCmds("", "changes", "apply")
+		}()
+	}
 
 	if len(updatedFiles) == 0 {
 		fmt.Println("✅ Applied changes, but no files were updated")
 		return
 	} else {
-		if isRepo {
+		// files with conflict markers are left for the user to resolve and commit
+		var toCommit []string
+		for _, path := range updatedFiles {
+			if _, ok := conflictsByPath[path]; !ok {
+				toCommit = append(toCommit, path)
+			}
+		}
+
+		commitOpts := GitCommitOpts{NoVerify: opts.NoVerify, Edit: opts.EditMessage}
+
+		if opts.Commit && !isRepo {
+			fmt.Fprintln(os.Stderr, color.New(color.Bold, term.ColorHiYellow).Sprint(term.Plain("⚠️  The project isn't in a git repo, so nothing was committed")))
+			fmt.Fprintln(os.Stderr)
+		} else if opts.Commit && len(toCommit) > 0 {
+			msg := opts.CommitMessage
+			if msg == "" {
+				msg = currentPlanState.ConventionalCommitMsg(toCommit)
+			}
+
+			err := GitAddAndCommitPaths(fs.ProjectRoot, msg, toCommit, commitOpts, true)
+			if err != nil {
+				onGitErr("Failed to commit changes:", err.Error())
+			} else {
+
+				suffix := ""
+				if len(toCommit) > 1 {
+					suffix = "s"
+				}
+				fmt.Printf("📝 Committed %d file%s\n", len(toCommit), suffix)
+				if !opts.EditMessage {
+					fmt.Println(color.New(term.ColorHiCyan).Sprint(strings.SplitN(msg, "\n", 2)[0]))
+				}
+				if len(toCommit) < len(updatedFiles) {
+					fmt.Println("ℹ️  Files with conflicts weren't included. Commit them once you resolve the conflicts.")
+				}
+				fmt.Println()
+				if opts.GitBranch {
+					printApplyBranch(originalGitBranch)
+				}
+			}
+		} else if isRepo && len(toCommit) > 0 {
 			fmt.Println("✏️  Plandex can commit these updates with an automatically generated message.")
 			fmt.Println()
 			fmt.Println("ℹ️  Only the files that Plandex is updating will be included the commit. Any other changes, staged or unstaged, will remain exactly as they are.")
 			fmt.Println()
+			if len(toCommit) < len(updatedFiles) {
+				fmt.Println("ℹ️  Files with conflicts won't be included until you resolve them.")
+				fmt.Println()
+			}
 
 			confirmed, err := term.ConfirmYesNo("Commit Plandex updates now?")
 
@@ -198,7 +335,7 @@ func MustApplyPlan(planId, branch string, autoConfirm bool) {
 
 				// spew.Dump(currentPlanState)
 
-				err := GitAddAndCommitPaths(fs.ProjectRoot, msg, updatedFiles, true)
+				err := GitAddAndCommitPaths(fs.ProjectRoot, msg, toCommit, commitOpts, true)
 				if err != nil {
 					onGitErr("Failed to commit changes:", err.Error())
 				}
@@ -210,6 +347,264 @@ func MustApplyPlan(planId, branch string, autoConfirm bool) {
 			suffix = "s"
 		}
 		fmt.Printf("✅ Applied changes, %d file%s updated\n", len(updatedFiles), suffix)
+		fmt.Println()
+		term.PrintCmds("", "rollback")
+
+		// hooks would fail on conflict markers, so they wait until conflicts are resolved
+		if !opts.NoHooks && len(conflictsByPath) == 0 {
+			MustRunPostApplyHooks(config)
+		}
+	}
+
+}
+
+type applyWriteResult struct {
+	updatedFiles    []string
+	conflictsByPath map[string]int
+	formattedPaths  []string
+	formatErrs      map[string]error
+}
+
+// writeApplyFiles writes the changes being applied to the project, formatting each file unless it has conflict markers or
+// is only partly applied. Files that are already up to date are left alone. If tx is set, each file is recorded in it
+// before it's written, so the apply can be rolled back even if it stops partway through.
+func writeApplyFiles(config *types.PlandexConfig, tx *ApplyTransaction, toApply map[string]string, mergeRes *localMergeResult, applyReq *shared.ApplyPlanRequest) (*applyWriteResult, error) {
+	res := &applyWriteResult{
+		conflictsByPath: map[string]int{},
+		formatErrs:      map[string]error{},
 	}
 
+	for path, content := range toApply {
+		dstPath := filepath.Join(fs.ProjectRoot, path)
+
+		content = unescapePlanFileContent(content)
+
+		// in interactive mode, hunks with conflict markers may not have been applied
+		if n, ok := mergeRes.conflictsByPath[path]; ok && strings.Contains(content, conflictStartMarker+"\n") {
+			res.conflictsByPath[path] = n
+		}
+
+		// partly applied files are written as selected so the changes kept pending still line up
+		_, isPartial := partialByPath(applyReq)[path]
+		if _, hasConflicts := res.conflictsByPath[path]; !hasConflicts && !isPartial {
+			formatted, err := formatFileContent(config, path, content)
+			if err != nil {
+				res.formatErrs[path] = err
+			} else if formatted != content {
+				res.formattedPaths = append(res.formattedPaths, path)
+				content = formatted
+			}
+		}
+
+		var before *string
+		bytes, err := os.ReadFile(dstPath)
+		if err == nil {
+			if string(bytes) == content {
+				continue
+			}
+			s := string(bytes)
+			before = &s
+		} else if os.IsNotExist(err) {
+			err := os.MkdirAll(filepath.Dir(dstPath), 0755)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create directory %s: %v", filepath.Dir(dstPath), err)
+			}
+		} else {
+			return nil, fmt.Errorf("failed to read %s: %v", dstPath, err)
+		}
+
+		if tx != nil {
+			err = tx.recordFile(path, before, content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to record %s for rollback: %v", path, err)
+			}
+		}
+
+		err = os.WriteFile(dstPath, []byte(content), 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %v", dstPath, err)
+		}
+		res.updatedFiles = append(res.updatedFiles, path)
+	}
+
+	return res, nil
+}
+
+// printApplyBranch shows where an apply with --branch was committed
+func printApplyBranch(originalGitBranch string) {
+	current, err := GitCurrentBranch(fs.ProjectRoot)
+	if err != nil || current == originalGitBranch {
+		return
+	}
+
+	fmt.Printf("🌿 The changes are committed on %s, and %s is unchanged. To go back to it:\n", current, originalGitBranch)
+	fmt.Println()
+	fmt.Printf("git checkout %s\n", originalGitBranch)
+	fmt.Println()
+}
+
+// ApplyPatch is the change that apply would make to a project file
+type ApplyPatch struct {
+	Path  string `json:"path"`
+	IsNew bool   `json:"isNew"`
+	Patch string `json:"patch"`
+}
+
+// MustGetApplyPatches diffs the plan's pending changes against the project files without writing anything.
+// Changes that still need to be built aren't included, which is reported by hasPendingBuilds.
+func MustGetApplyPatches(planId, branch string) (patches []*ApplyPatch, hasPendingBuilds bool) {
+	term.StartSpinner("")
+	currentPlanState, apiErr := api.Client.GetCurrentPlanState(planId, branch)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting current plan state: %v", apiErr.Msg)
+	}
+
+	toApply := currentPlanState.CurrentPlanFiles.Files
+
+	config := MustLoadCommandConfig()
+
+	// show what apply would actually write, including any merge with local changes and formatting
+	mergeRes, err := mergeLocalChanges(currentPlanState.ContextsByPath, toApply)
+	if err != nil {
+		term.OutputErrorAndExit("failed to merge local changes: %v", err)
+	}
+
+	paths := make([]string, 0, len(toApply))
+	for path := range toApply {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		content := unescapePlanFileContent(toApply[path])
+		if merged, ok := mergeRes.contentByPath[path]; ok {
+			content = merged
+		}
+
+		if _, hasConflicts := mergeRes.conflictsByPath[path]; !hasConflicts {
+			formatted, err := formatFileContent(config, path, content)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, color.New(color.Bold, term.ColorHiYellow).Sprint(term.Plain(fmt.Sprintf("⚠️  Couldn't format %s: %v", path, err))))
+			} else {
+				content = formatted
+			}
+		}
+
+		var original *string
+		bytes, err := os.ReadFile(filepath.Join(fs.ProjectRoot, path))
+		if err == nil {
+			s := string(bytes)
+			original = &s
+		} else if !os.IsNotExist(err) {
+			term.OutputErrorAndExit("failed to read %s: %v", path, err)
+		}
+
+		patch := UnifiedDiff(path, original, content)
+		if patch == "" {
+			continue
+		}
+
+		patches = append(patches, &ApplyPatch{
+			Path:  path,
+			IsNew: original == nil,
+			Patch: patch,
+		})
+	}
+
+	return patches, currentPlanState.HasPendingBuilds()
+}
+
+func unescapePlanFileContent(content string) string {
+	return strings.ReplaceAll(content, "\\`\\`\\`", "```")
+}
+
+// mustCheckUncommittedTargets warns about files with changes to apply that also have uncommitted local modifications,
+// since those can't be restored from git if the apply goes wrong. When the apply won't be confirmed, it stops unless forced.
+func mustCheckUncommittedTargets(toApply map[string]string, autoConfirm bool) {
+	paths := make([]string, 0, len(toApply))
+	for path := range toApply {
+		paths = append(paths, path)
+	}
+
+	uncommitted, err := GitUncommittedPaths(fs.ProjectRoot, paths)
+	if err != nil {
+		term.StopSpinner()
+		term.OutputErrorAndExit("failed to check git status: %v", err)
+	}
+
+	if len(uncommitted) == 0 {
+		return
+	}
+	sort.Strings(uncommitted)
+
+	term.StopSpinner()
+	fmt.Println(color.New(color.Bold, term.ColorHiYellow).Sprint("⚠️  These files have uncommitted changes that applying will modify:"))
+	for _, path := range uncommitted {
+		fmt.Println("  • " + path)
+	}
+	fmt.Println()
+
+	if autoConfirm {
+		term.OutputErrorAndExitWithCode(term.ExitConflict, "Commit or stash them first, or apply anyway with --force")
+	}
+
+	term.ResumeSpinner()
+}
+
+func printApplyConflicts(conflictsByPath map[string]int) {
+	paths := make([]string, 0, len(conflictsByPath))
+	for path := range conflictsByPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	fmt.Println()
+	color.New(color.Bold, term.ColorHiYellow).Println("⚠️  Some changes conflict with local changes made since context was loaded")
+	fmt.Println()
+	for _, path := range paths {
+		n := conflictsByPath[path]
+		suffix := ""
+		if n > 1 {
+			suffix = "s"
+		}
+		fmt.Printf("  • %s (%d conflict%s)\n", path, n, suffix)
+	}
+	fmt.Println()
+	fmt.Printf("Both versions were written between %s and %s markers. Edit each file to resolve them.\n", conflictStartMarker, conflictEndMarker)
+}
+
+func partialByPath(applyReq *shared.ApplyPlanRequest) map[string]*shared.PartialApply {
+	if applyReq == nil {
+		return nil
+	}
+	return applyReq.PartialByPath
+}
+
+func printFormatResults(formattedPaths []string, formatErrs map[string]error) {
+	if len(formattedPaths) > 0 {
+		suffix := ""
+		if len(formattedPaths) > 1 {
+			suffix = "s"
+		}
+		fmt.Printf("🧹 Formatted %d file%s\n", len(formattedPaths), suffix)
+	}
+
+	if len(formatErrs) == 0 {
+		return
+	}
+
+	paths := make([]string, 0, len(formatErrs))
+	for path := range formatErrs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	fmt.Fprintln(os.Stderr, color.New(color.Bold, term.ColorHiYellow).Sprint(term.Plain("⚠️  Some files couldn't be formatted, so they were written as generated:")))
+	for _, path := range paths {
+		slog.Warn("Couldn't format file", "path", path, "error", formatErrs[path])
+		fmt.Fprintf(os.Stderr, "  • %s: %v\n", path, formatErrs[path])
+	}
+	fmt.Fprintln(os.Stderr)
 }
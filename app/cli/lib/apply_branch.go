@@ -0,0 +1,68 @@
+package lib
+
+import (
+	"fmt"
+	"plandex/api"
+	"plandex/fs"
+	"plandex/term"
+	"regexp"
+	"strings"
+)
+
+var nonBranchChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// MustGetApplyBranchName returns the git branch an apply with --branch goes to by default: the plan's name under
+// plandex/, along with the plan's branch unless it's main
+func MustGetApplyBranchName(planId, branch string) string {
+	plan, apiErr := api.Client.GetPlan(planId)
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting plan: %v", apiErr.Msg)
+	}
+
+	name := strings.Trim(nonBranchChars.ReplaceAllString(strings.ToLower(plan.Name), "-"), "-")
+	if name == "" {
+		name = "plan"
+	}
+
+	if branch != "" && branch != "main" {
+		name += "-" + strings.Trim(nonBranchChars.ReplaceAllString(strings.ToLower(branch), "-"), "-")
+	}
+
+	return "plandex/" + name
+}
+
+// mustCheckoutApplyBranch switches the project's repo to the git branch an apply should land on, creating it from the
+// current branch if it doesn't exist yet. It returns the branch that was checked out before, which the apply leaves
+// untouched.
+func mustCheckoutApplyBranch(planId, branch, gitBranch string) string {
+	if gitBranch == "" {
+		gitBranch = MustGetApplyBranchName(planId, branch)
+	} else if !GitValidBranchName(gitBranch) {
+		term.OutputErrorAndExitWithCode(term.ExitUsage, "%s isn't a valid git branch name", gitBranch)
+	}
+
+	original, err := GitCurrentBranch(fs.ProjectRoot)
+	if err != nil {
+		term.OutputErrorAndExit("Error getting current git branch: %v", err)
+	}
+
+	if original == gitBranch {
+		return original
+	}
+
+	exists := GitBranchExists(fs.ProjectRoot, gitBranch)
+
+	err = GitCheckoutBranch(fs.ProjectRoot, gitBranch, !exists)
+	if err != nil {
+		term.OutputErrorAndExit("Error switching to git branch %s: %v", gitBranch, err)
+	}
+
+	if exists {
+		fmt.Printf("🌿 Switched to existing git branch %s\n", gitBranch)
+	} else {
+		fmt.Printf("🌿 Created git branch %s from %s\n", gitBranch, original)
+	}
+	fmt.Println()
+
+	return original
+}
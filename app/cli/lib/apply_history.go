@@ -0,0 +1,361 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plandex/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+// only the most recent applies in each project are kept for rollback
+const maxApplyTransactions = 20
+
+// ApplyTransaction records the project files an apply changed, so rollback can restore them as they were before it.
+// It's stored in the home dir under the project, with a snapshot of each file from before the apply and a reverse patch.
+type ApplyTransaction struct {
+	Id        string             `json:"id"`
+	PlanId    string             `json:"planId"`
+	Branch    string             `json:"branch"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Files     []*ApplyFileRecord `json:"files"`
+	// set until every file has been written, so an apply that stopped partway through can still be rolled back
+	Partial bool `json:"partial,omitempty"`
+}
+
+type ApplyFileRecord struct {
+	Path string `json:"path"`
+	// false if the apply created the file, so rollback removes it
+	Existed bool `json:"existed"`
+	// sha256 of the content the apply wrote, to find files edited since
+	AppliedSha string `json:"appliedSha"`
+}
+
+func applyHistoryDir() string {
+	return filepath.Join(fs.HomePlandexDir, CurrentProjectId, "applies")
+}
+
+func (tx *ApplyTransaction) dir() string {
+	return filepath.Join(applyHistoryDir(), tx.Id)
+}
+
+func (tx *ApplyTransaction) snapshotPath(path string) string {
+	return filepath.Join(tx.dir(), "before", path)
+}
+
+// ReversePatchPath is a unified diff that undoes the apply, for use with git apply or patch
+func (tx *ApplyTransaction) ReversePatchPath() string {
+	return filepath.Join(tx.dir(), "reverse.patch")
+}
+
+// beginApply records an apply before it writes any files. Each file is recorded with recordFile before it's written,
+// and the apply is marked complete with complete once they all are.
+func beginApply(planId, branch string) (*ApplyTransaction, error) {
+	if fs.HomePlandexDir == "" || CurrentProjectId == "" {
+		return nil, fmt.Errorf("no current project")
+	}
+
+	now := time.Now().UTC()
+	tx := &ApplyTransaction{
+		Id:        strings.Replace(now.Format("20060102-150405.000000"), ".", "-", 1),
+		PlanId:    planId,
+		Branch:    branch,
+		CreatedAt: now,
+		Partial:   true,
+	}
+
+	err := os.MkdirAll(tx.dir(), os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("error creating apply dir: %v", err)
+	}
+
+	err = tx.save()
+	if err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// recordFile snapshots a file and adds it to the apply before the apply writes it. before is the file's content, or nil
+// if the apply is creating it, and written is the content the apply is about to write.
+func (tx *ApplyTransaction) recordFile(path string, before *string, written string) error {
+	var reversePatch string
+	if before == nil {
+		// undoing the apply deletes the file
+		patch := UnifiedDiff(path, &written, "")
+		reversePatch = strings.Replace(patch, "+++ b/"+path+"\n", "+++ /dev/null\n", 1)
+	} else {
+		reversePatch = UnifiedDiff(path, &written, *before)
+
+		snapshotPath := tx.snapshotPath(path)
+		err := os.MkdirAll(filepath.Dir(snapshotPath), os.ModePerm)
+		if err != nil {
+			return fmt.Errorf("error creating snapshot dir: %v", err)
+		}
+
+		err = os.WriteFile(snapshotPath, []byte(*before), 0644)
+		if err != nil {
+			return fmt.Errorf("error writing snapshot of %s: %v", path, err)
+		}
+	}
+
+	f, err := os.OpenFile(tx.ReversePatchPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening reverse patch: %v", err)
+	}
+	_, err = f.WriteString(reversePatch)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("error writing reverse patch: %v", err)
+	}
+
+	tx.Files = append(tx.Files, &ApplyFileRecord{
+		Path:       path,
+		Existed:    before != nil,
+		AppliedSha: contentSha(written),
+	})
+
+	return tx.save()
+}
+
+// complete marks the apply as finished once its files are written. An apply that didn't change any files isn't kept.
+func (tx *ApplyTransaction) complete() error {
+	if len(tx.Files) == 0 {
+		err := os.RemoveAll(tx.dir())
+		if err != nil {
+			return fmt.Errorf("error removing apply %s: %v", tx.Id, err)
+		}
+		return nil
+	}
+
+	tx.Partial = false
+	err := tx.save()
+	if err != nil {
+		return err
+	}
+
+	return pruneApplyTransactions()
+}
+
+func (tx *ApplyTransaction) save() error {
+	bytes, err := json.MarshalIndent(tx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling apply: %v", err)
+	}
+
+	// written to a temp file and renamed, so an interrupted save doesn't leave a truncated record
+	path := filepath.Join(tx.dir(), "apply.json")
+	err = os.WriteFile(path+".tmp", bytes, 0644)
+	if err != nil {
+		return fmt.Errorf("error writing apply: %v", err)
+	}
+
+	err = os.Rename(path+".tmp", path)
+	if err != nil {
+		return fmt.Errorf("error writing apply: %v", err)
+	}
+
+	return nil
+}
+
+// recordApply records an apply of files that haven't been written yet, all at once. beforeByPath has each file's
+// content before the apply, or nil if the apply creates it, and writtenByPath has the content the apply will write.
+func recordApply(planId, branch string, beforeByPath map[string]*string, writtenByPath map[string]string) (*ApplyTransaction, error) {
+	tx, err := beginApply(planId, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(writtenByPath))
+	for path := range writtenByPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		err := tx.recordFile(path, beforeByPath[path], writtenByPath[path])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tx, tx.complete()
+}
+
+// ListApplyTransactions returns the project's recorded applies, newest first
+func ListApplyTransactions() ([]*ApplyTransaction, error) {
+	if fs.HomePlandexDir == "" || CurrentProjectId == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(applyHistoryDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading applies dir: %v", err)
+	}
+
+	var txs []*ApplyTransaction
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		bytes, err := os.ReadFile(filepath.Join(applyHistoryDir(), entry.Name(), "apply.json"))
+		if os.IsNotExist(err) {
+			// an apply that failed before recording anything
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading apply %s: %v", entry.Name(), err)
+		}
+
+		var tx ApplyTransaction
+		err = json.Unmarshal(bytes, &tx)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshalling apply %s: %v", entry.Name(), err)
+		}
+		// an apply that stopped before recording any files has nothing to roll back
+		if len(tx.Files) == 0 {
+			continue
+		}
+		txs = append(txs, &tx)
+	}
+
+	sort.Slice(txs, func(i, j int) bool {
+		return txs[i].CreatedAt.After(txs[j].CreatedAt)
+	})
+
+	return txs, nil
+}
+
+// ModifiedSinceApply returns the files of the given transactions, ordered newest first, that were changed or removed
+// after the latest of them to touch each file wrote it
+func ModifiedSinceApply(txs []*ApplyTransaction) ([]string, error) {
+	var paths []string
+	seen := map[string]bool{}
+	for _, tx := range txs {
+		for _, file := range tx.Files {
+			if seen[file.Path] {
+				continue
+			}
+			seen[file.Path] = true
+
+			modified, err := tx.fileModifiedSinceApply(file)
+			if err != nil {
+				return nil, err
+			}
+			if modified {
+				paths = append(paths, file.Path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (tx *ApplyTransaction) fileModifiedSinceApply(file *ApplyFileRecord) (bool, error) {
+	bytes, err := os.ReadFile(filepath.Join(fs.ProjectRoot, file.Path))
+	if os.IsNotExist(err) {
+		// a partial apply may have stopped before creating the file
+		return !(tx.Partial && !file.Existed), nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading %s: %v", file.Path, err)
+	}
+
+	if contentSha(string(bytes)) == file.AppliedSha {
+		return false, nil
+	}
+
+	// or before writing it, in which case it still matches its snapshot
+	if tx.Partial && file.Existed {
+		snapshot, err := os.ReadFile(tx.snapshotPath(file.Path))
+		if err == nil && string(snapshot) == string(bytes) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// RollbackApplies restores the project files changed by each transaction, which must be ordered newest first, and
+// removes the transactions from the history. It returns the paths restored.
+func RollbackApplies(txs []*ApplyTransaction) ([]string, error) {
+	restored := map[string]bool{}
+
+	for _, tx := range txs {
+		for _, file := range tx.Files {
+			dstPath := filepath.Join(fs.ProjectRoot, file.Path)
+
+			if file.Existed {
+				bytes, err := os.ReadFile(tx.snapshotPath(file.Path))
+				if err != nil {
+					return nil, fmt.Errorf("error reading snapshot of %s: %v", file.Path, err)
+				}
+
+				err = os.MkdirAll(filepath.Dir(dstPath), 0755)
+				if err != nil {
+					return nil, fmt.Errorf("error creating directory %s: %v", filepath.Dir(dstPath), err)
+				}
+
+				err = os.WriteFile(dstPath, bytes, 0644)
+				if err != nil {
+					return nil, fmt.Errorf("error restoring %s: %v", file.Path, err)
+				}
+			} else {
+				err := os.Remove(dstPath)
+				if err != nil && !os.IsNotExist(err) {
+					return nil, fmt.Errorf("error removing %s: %v", file.Path, err)
+				}
+			}
+
+			restored[file.Path] = true
+		}
+
+		err := os.RemoveAll(tx.dir())
+		if err != nil {
+			return nil, fmt.Errorf("error removing apply %s: %v", tx.Id, err)
+		}
+	}
+
+	paths := make([]string, 0, len(restored))
+	for path := range restored {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+func pruneApplyTransactions() error {
+	txs, err := ListApplyTransactions()
+	if err != nil {
+		return err
+	}
+
+	if len(txs) <= maxApplyTransactions {
+		return nil
+	}
+
+	for _, tx := range txs[maxApplyTransactions:] {
+		err := os.RemoveAll(tx.dir())
+		if err != nil {
+			return fmt.Errorf("error removing apply %s: %v", tx.Id, err)
+		}
+	}
+
+	return nil
+}
+
+func contentSha(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
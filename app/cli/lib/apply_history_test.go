@@ -0,0 +1,92 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"plandex/fs"
+	"testing"
+)
+
+// an apply that stops partway through is still recorded, and rolling it back restores the files it got to
+func TestRollbackPartialApply(t *testing.T) {
+	prevHome, prevRoot, prevProjectId := fs.HomePlandexDir, fs.ProjectRoot, CurrentProjectId
+	t.Cleanup(func() {
+		fs.HomePlandexDir, fs.ProjectRoot, CurrentProjectId = prevHome, prevRoot, prevProjectId
+	})
+	fs.HomePlandexDir = t.TempDir()
+	fs.ProjectRoot = t.TempDir()
+	CurrentProjectId = "project-1"
+
+	write := func(path, content string) {
+		if err := os.WriteFile(filepath.Join(fs.ProjectRoot, path), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(path string) (string, bool) {
+		bytes, err := os.ReadFile(filepath.Join(fs.ProjectRoot, path))
+		if os.IsNotExist(err) {
+			return "", false
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(bytes), true
+	}
+
+	write("a.go", "a before")
+	write("b.go", "b before")
+
+	tx, err := beginApply("plan-1", "main")
+	if err != nil {
+		t.Fatalf("beginApply() error = %v", err)
+	}
+
+	// a.go and new.go are written, then the apply stops after recording b.go but before writing it
+	aBefore, bBefore := "a before", "b before"
+	for _, f := range []struct {
+		path    string
+		before  *string
+		written string
+	}{
+		{"a.go", &aBefore, "a after"},
+		{"new.go", nil, "new"},
+		{"b.go", &bBefore, "b after"},
+	} {
+		if err := tx.recordFile(f.path, f.before, f.written); err != nil {
+			t.Fatalf("recordFile(%s) error = %v", f.path, err)
+		}
+	}
+	write("a.go", "a after")
+	write("new.go", "new")
+
+	txs, err := ListApplyTransactions()
+	if err != nil {
+		t.Fatalf("ListApplyTransactions() error = %v", err)
+	}
+	if len(txs) != 1 || !txs[0].Partial || len(txs[0].Files) != 3 {
+		t.Fatalf("expected the partial apply with 3 files, got %+v", txs)
+	}
+
+	modified, err := ModifiedSinceApply(txs)
+	if err != nil {
+		t.Fatalf("ModifiedSinceApply() error = %v", err)
+	}
+	if len(modified) != 0 {
+		t.Errorf("expected no files modified since the apply, got %v", modified)
+	}
+
+	_, err = RollbackApplies(txs)
+	if err != nil {
+		t.Fatalf("RollbackApplies() error = %v", err)
+	}
+
+	if content, _ := read("a.go"); content != aBefore {
+		t.Errorf("a.go = %q, want %q", content, aBefore)
+	}
+	if content, _ := read("b.go"); content != bBefore {
+		t.Errorf("b.go = %q, want %q", content, bBefore)
+	}
+	if _, exists := read("new.go"); exists {
+		t.Error("new.go should have been removed")
+	}
+}
@@ -0,0 +1,127 @@
+package lib
+
+import (
+	"fmt"
+	"log"
+	"plandex/api"
+	"sort"
+
+	"github.com/plandex/plandex/shared"
+)
+
+type ApplyPendingResult struct {
+	// files written to the project
+	Updated []string `json:"updated"`
+	// files merged cleanly with local changes made since their context was loaded
+	Merged []string `json:"merged,omitempty"`
+	// files written with conflict markers, and the number of conflicts in each
+	ConflictsByPath map[string]int `json:"conflictsByPath,omitempty"`
+	// files the configured formatter failed on, which were written unformatted
+	FormatErrs map[string]string `json:"formatErrs,omitempty"`
+	// whether changes to other files are still pending
+	StillPending bool `json:"stillPending"`
+}
+
+// ApplyPending applies the plan's pending changes, or only those to paths if any are given, without prompting or printing
+// anything, for callers that aren't a terminal like the editor server. As with apply --yes --force, files are merged with
+// local changes made since their context was loaded. Nothing is committed and post-apply hooks don't run.
+func ApplyPending(planId, branch string, paths []string) (*ApplyPendingResult, error) {
+	currentPlanState, apiErr := api.Client.GetCurrentPlanState(planId, branch)
+	if apiErr != nil {
+		return nil, fmt.Errorf("error getting current plan state: %v", apiErr.Msg)
+	}
+
+	if currentPlanState.HasPendingBuilds() {
+		return nil, fmt.Errorf("plan has changes that need to be built before applying")
+	}
+
+	res := &ApplyPendingResult{}
+
+	toApply := currentPlanState.CurrentPlanFiles.Files
+	if len(toApply) == 0 {
+		return res, nil
+	}
+	numPending := len(toApply)
+
+	// a nil request applies everything that's pending
+	var applyReq *shared.ApplyPlanRequest
+
+	if len(paths) > 0 {
+		selected, err := resolveApplyPaths(paths, toApply)
+		if err != nil {
+			return nil, err
+		}
+		toApply = selected
+		applyReq = &shared.ApplyPlanRequest{}
+		for path := range toApply {
+			applyReq.Paths = append(applyReq.Paths, path)
+		}
+	}
+
+	// there's no prompt here, so the project's formatters are only used if they were already approved
+	config, err := LoadApprovedCommandConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %v", err)
+	}
+
+	mergeRes, err := mergeLocalChanges(currentPlanState.ContextsByPath, toApply)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge local changes: %v", err)
+	}
+	if len(mergeRes.contentByPath) > 0 {
+		merged := make(map[string]string, len(toApply))
+		for path, content := range toApply {
+			merged[path] = content
+		}
+		for path, content := range mergeRes.contentByPath {
+			merged[path] = content
+		}
+		toApply = merged
+	}
+
+	apiErr = api.Client.ApplyPlan(planId, branch, applyReq)
+	if apiErr != nil {
+		return nil, fmt.Errorf("failed to set pending results applied: %s", apiErr.Msg)
+	}
+
+	// an apply that can't be recorded still goes ahead, just without rollback
+	tx, err := beginApply(planId, branch)
+	if err != nil {
+		log.Printf("couldn't record apply for rollback: %v", err)
+	}
+
+	written, err := writeApplyFiles(config, tx, toApply, mergeRes, applyReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx != nil {
+		err = tx.complete()
+		if err != nil {
+			log.Printf("couldn't record apply for rollback: %v", err)
+		}
+	}
+
+	res.Updated = written.updatedFiles
+	sort.Strings(res.Updated)
+	res.StillPending = len(toApply) < numPending
+
+	if len(written.conflictsByPath) > 0 {
+		res.ConflictsByPath = written.conflictsByPath
+	}
+
+	for _, path := range mergeRes.cleanPaths {
+		if _, ok := toApply[path]; ok {
+			res.Merged = append(res.Merged, path)
+		}
+	}
+
+	if len(written.formatErrs) > 0 {
+		res.FormatErrs = map[string]string{}
+		for path, err := range written.formatErrs {
+			res.FormatErrs[path] = err.Error()
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,229 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plandex/fs"
+	"plandex/term"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/plandex/plandex/shared"
+)
+
+// ApplyOpts selects which of a plan's pending changes apply writes. Changes that aren't selected stay pending.
+type ApplyOpts struct {
+	AutoConfirm bool
+	// only apply changes to these files
+	Paths []string
+	// ask about each change in each file, as with git add -p
+	Interactive bool
+	// skip the config's post-apply hooks
+	NoHooks bool
+	// commit the applied files without asking, with a conventional commit message unless CommitMessage is set
+	Commit        bool
+	CommitMessage string
+	// open git's editor on the commit message
+	EditMessage bool
+	// skip git's commit hooks
+	NoVerify bool
+	// apply and commit on a separate git branch, named GitBranchName or after the plan, leaving the current one untouched
+	GitBranch     bool
+	GitBranchName string
+	// apply even if files with changes have uncommitted local modifications
+	Force bool
+}
+
+// resolveApplyPaths narrows the files to apply to the given paths, which can be relative to the project root or the current dir
+func resolveApplyPaths(paths []string, toApply map[string]string) (map[string]string, error) {
+	selected := map[string]string{}
+
+	for _, arg := range paths {
+		path, err := ResolvePendingPath(arg, toApply)
+		if err != nil {
+			return nil, err
+		}
+		selected[path] = toApply[path]
+	}
+
+	return selected, nil
+}
+
+// ResolvePendingPath returns the path in files for a path that can be relative to the project root or the current dir
+func ResolvePendingPath(arg string, files map[string]string) (string, error) {
+	path := filepath.Clean(arg)
+
+	if _, ok := files[path]; !ok {
+		abs, err := filepath.Abs(arg)
+		if err == nil {
+			rel, err := filepath.Rel(fs.ProjectRoot, abs)
+			if err == nil {
+				path = rel
+			}
+		}
+	}
+
+	if _, ok := files[path]; !ok {
+		return "", fmt.Errorf("no pending changes for %s", arg)
+	}
+
+	return path, nil
+}
+
+// mustSelectHunks shows each change to each file and asks whether to apply it. It returns the content to write for each
+// file with any changes accepted, and for files with only some changes accepted, the rejected changes that stay pending.
+func mustSelectHunks(toApply map[string]string) (map[string]string, map[string]*shared.PartialApply) {
+	selected := map[string]string{}
+	partialByPath := map[string]*shared.PartialApply{}
+
+	paths := make([]string, 0, len(toApply))
+	for path := range toApply {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	quit := false
+
+	for _, path := range paths {
+		if quit {
+			break
+		}
+
+		content := unescapePlanFileContent(toApply[path])
+
+		var before string
+		isNew := true
+		bytes, err := os.ReadFile(filepath.Join(fs.ProjectRoot, path))
+		if err == nil {
+			before = string(bytes)
+			isNew = false
+		} else if !os.IsNotExist(err) {
+			term.OutputErrorAndExit("failed to read %s: %v", path, err)
+		}
+
+		if !isNew && before == content {
+			// nothing to review
+			selected[path] = toApply[path]
+			continue
+		}
+
+		hunks := diffHunks(diffLines(splitLines(before), splitLines(content)))
+		accepted := make([]bool, len(hunks))
+
+		lbl := "📄 " + path
+		if isNew {
+			lbl += " (new file)"
+		}
+		fmt.Println()
+		color.New(color.Bold, term.ColorHiCyan).Println(lbl)
+
+		var rest byte
+		for i, hunk := range hunks {
+			if rest != 0 {
+				accepted[i] = rest == 'a'
+				continue
+			}
+
+			fmt.Println()
+			fmt.Print(ColorizeDiff(hunk.String()))
+			fmt.Println()
+
+			choice := mustPromptHunk(i+1, len(hunks))
+			switch choice {
+			case 'y':
+				accepted[i] = true
+			case 'a':
+				accepted[i] = true
+				rest = 'a'
+			case 'd':
+				rest = 'd'
+			case 'q':
+				rest = 'd'
+				quit = true
+			}
+		}
+
+		numAccepted := 0
+		for _, a := range accepted {
+			if a {
+				numAccepted++
+			}
+		}
+
+		if numAccepted == 0 {
+			continue
+		}
+
+		if numAccepted == len(hunks) {
+			selected[path] = toApply[path]
+			continue
+		}
+
+		partial := applyHunks(splitLines(before), hunks, accepted)
+
+		var replacements []*shared.Replacement
+		// accepted hunks before a rejected one shift where it starts in the partly applied file
+		offset := 0
+		for i, hunk := range hunks {
+			if accepted[i] {
+				offset += hunk.bCount - hunk.aCount
+				continue
+			}
+			startLine := hunk.aStart + offset + 1
+			replacements = append(replacements, pendingReplacement(hunk.side(false), hunk.side(true), startLine, startLine+hunk.aCount-1))
+		}
+
+		// each rejected change includes the lines around it, which is almost always enough to place it, but if not, keep
+		// the rest of the file's changes pending as a single replacement
+		if res, ok := shared.ApplyReplacements(partial, replacements, false); !ok || res != content {
+			replacements = []*shared.Replacement{pendingReplacement(partial, content, 1, len(splitLines(partial)))}
+		}
+
+		selected[path] = partial
+		partialByPath[path] = &shared.PartialApply{
+			Content:      partial,
+			Replacements: replacements,
+		}
+	}
+
+	fmt.Println()
+
+	return selected, partialByPath
+}
+
+func pendingReplacement(old, new string, startLine, endLine int) *shared.Replacement {
+	return &shared.Replacement{
+		Old: old,
+		New: new,
+		StreamedChange: &shared.StreamedChange{
+			Summary: "Change kept pending by apply",
+			Old: shared.StreamedChangeSection{
+				StartLine: startLine,
+				EndLine:   endLine,
+			},
+			New: new,
+		},
+	}
+}
+
+func mustPromptHunk(num, total int) byte {
+	color.New(term.ColorHiMagenta, color.Bold).Printf("Apply this change (%d/%d)? (y)es | (n)o | (a)ll in file | (d)one with file | (q)uit", num, total)
+	color.New(term.ColorHiMagenta, color.Bold).Print("> ")
+
+	char, err := term.GetUserKeyInput()
+	if err != nil {
+		term.OutputErrorAndExit("failed to get user input: %v", err)
+	}
+
+	fmt.Println(string(char))
+
+	switch char {
+	case 'y', 'Y', 'n', 'N', 'a', 'A', 'd', 'D', 'q', 'Q':
+		return byte(char) | 0x20
+	}
+
+	fmt.Println()
+	color.New(term.ColorHiRed, color.Bold).Print("Invalid input.\nEnter 'y' to apply this change, 'n' to keep it pending, 'a' to apply it and the rest of the file's changes, 'd' to keep it and the rest of the file's changes pending, or 'q' to keep all remaining changes pending.\n\n")
+	return mustPromptHunk(num, total)
+}
@@ -0,0 +1,75 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"plandex/api"
+	"plandex/term"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const (
+	BudgetActionWarn  = "warn"
+	BudgetActionBlock = "block"
+)
+
+// MustCheckBudget compares the current plan's spend and today's spend with the plan-budget and daily-budget config values.
+// When a budget has been reached, it warns, or exits with ExitBudget if budget-action is block.
+func MustCheckBudget() {
+	config := MustLoadConfig()
+	if config.PlanBudget == nil && config.DailyBudget == nil {
+		return
+	}
+
+	var exceeded []string
+
+	if config.PlanBudget != nil {
+		term.StartSpinner("")
+		usage, apiErr := api.Client.GetUsage(CurrentPlanId, time.Time{})
+		term.StopSpinner()
+
+		if apiErr != nil {
+			term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting usage: %v", apiErr.Msg)
+		}
+
+		if usage.Total.CostUsd >= *config.PlanBudget {
+			exceeded = append(exceeded, fmt.Sprintf("This plan has spent $%.2f of its $%.2f plan-budget", usage.Total.CostUsd, *config.PlanBudget))
+		}
+	}
+
+	if config.DailyBudget != nil {
+		now := time.Now().UTC()
+		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+		term.StartSpinner("")
+		usage, apiErr := api.Client.GetUsage("", startOfDay)
+		term.StopSpinner()
+
+		if apiErr != nil {
+			term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting usage: %v", apiErr.Msg)
+		}
+
+		if usage.Total.CostUsd >= *config.DailyBudget {
+			exceeded = append(exceeded, fmt.Sprintf("You've spent $%.2f today of your $%.2f daily-budget", usage.Total.CostUsd, *config.DailyBudget))
+		}
+	}
+
+	if len(exceeded) == 0 {
+		return
+	}
+
+	if config.BudgetAction == BudgetActionBlock {
+		msg := exceeded[0]
+		for _, s := range exceeded[1:] {
+			msg += "\n" + s
+		}
+		term.OutputErrorAndExitWithCode(term.ExitBudget, "Budget reached: %s", msg)
+	}
+
+	for _, msg := range exceeded {
+		fmt.Fprintln(os.Stderr, color.New(color.Bold, term.ColorHiYellow).Sprint(term.Plain("⚠️  "+msg)))
+	}
+	fmt.Fprintln(os.Stderr)
+}
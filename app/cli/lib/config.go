@@ -0,0 +1,551 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plandex/api"
+	"plandex/fs"
+	"plandex/term"
+	"plandex/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/plandex/plandex/shared"
+	ignore "github.com/sabhiram/go-gitignore"
+	"gopkg.in/yaml.v3"
+)
+
+var ConfigKeys = []string{"models.<role>", "auto-apply", "ignore-extensions", "default-context", "editor", "plan-budget", "daily-budget", "budget-action", "post-apply-hooks", "on-hook-failure", "formatters.<ext>", "notify-slack", "notify-discord"}
+
+var ConfigKeyDescriptions = map[string]string{
+	"models.<role>":     "Model for a role (planner, builder, etc.) in each new plan",
+	"auto-apply":        "Apply plans without confirming, as with apply --yes (true or false)",
+	"ignore-extensions": "Comma-separated file extensions that are never loaded into context unless --force is set",
+	"default-context":   "Comma-separated globs (.gitignore syntax) of project files to load into each new plan",
+	"editor":            "Editor used to write prompts and edit changes, taking precedence over $EDITOR",
+	"plan-budget":       "Total model spend in USD allowed for each plan",
+	"daily-budget":      "Model spend in USD allowed per day (UTC) across all plans",
+	"budget-action":     "What happens when a budget is reached: warn (default) or block",
+	"post-apply-hooks":  "Comma-separated shell commands run from the project root after apply updates files",
+	"on-hook-failure":   "What happens when a post-apply hook fails: report (default), load the output into context, or fix (load it and prompt the plan to fix it)",
+	"formatters.<ext>":  "Formatter for files with an extension (go, py, ts, etc.), run before apply writes them. It reads stdin, writes stdout, and {path} is replaced with the file's path",
+	"notify-slack":      "Slack incoming webhook url posted to when a plan running in the background finishes",
+	"notify-discord":    "Discord webhook url posted to when a plan running in the background finishes",
+}
+
+func ProjectConfigPath() string {
+	if fs.PlandexDir == "" {
+		return ""
+	}
+	return filepath.Join(fs.PlandexDir, "config.yml")
+}
+
+// LoadConfig reads the home-level config and the current project's config, with the project's values taking precedence
+func LoadConfig() (*types.PlandexConfig, error) {
+	config, err := readConfig(fs.HomeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if path := ProjectConfigPath(); path != "" {
+		projectConfig, err := readConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		config = mergeConfig(config, projectConfig)
+	}
+
+	return config, nil
+}
+
+func MustLoadConfig() *types.PlandexConfig {
+	config, err := LoadConfig()
+	if err != nil {
+		term.OutputErrorAndExit("Error loading config: %v", err)
+	}
+	return config
+}
+
+func readConfig(path string) (*types.PlandexConfig, error) {
+	config := &types.PlandexConfig{}
+
+	bytes, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	err = yaml.Unmarshal(bytes, config)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+
+	return config, nil
+}
+
+func writeConfig(path string, config *types.PlandexConfig) error {
+	bytes, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("error marshalling config: %v", err)
+	}
+
+	err = os.WriteFile(path, bytes, 0644)
+	if err != nil {
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+
+	return nil
+}
+
+func mergeConfig(base, override *types.PlandexConfig) *types.PlandexConfig {
+	merged := *base
+
+	if len(override.Models) > 0 {
+		merged.Models = map[string]string{}
+		for role, model := range base.Models {
+			merged.Models[role] = model
+		}
+		for role, model := range override.Models {
+			merged.Models[role] = model
+		}
+	}
+	if override.AutoApply != nil {
+		merged.AutoApply = override.AutoApply
+	}
+	if override.IgnoreExtensions != nil {
+		merged.IgnoreExtensions = override.IgnoreExtensions
+	}
+	if override.DefaultContext != nil {
+		merged.DefaultContext = override.DefaultContext
+	}
+	if override.Editor != "" {
+		merged.Editor = override.Editor
+	}
+	if override.PlanBudget != nil {
+		merged.PlanBudget = override.PlanBudget
+	}
+	if override.DailyBudget != nil {
+		merged.DailyBudget = override.DailyBudget
+	}
+	if override.BudgetAction != "" {
+		merged.BudgetAction = override.BudgetAction
+	}
+	if override.PostApplyHooks != nil {
+		merged.PostApplyHooks = override.PostApplyHooks
+	}
+	if override.OnHookFailure != "" {
+		merged.OnHookFailure = override.OnHookFailure
+	}
+	if len(override.Formatters) > 0 {
+		merged.Formatters = map[string]string{}
+		for ext, cmd := range base.Formatters {
+			merged.Formatters[ext] = cmd
+		}
+		for ext, cmd := range override.Formatters {
+			merged.Formatters[ext] = cmd
+		}
+	}
+	if override.NotifySlack != "" {
+		merged.NotifySlack = override.NotifySlack
+	}
+	if override.NotifyDiscord != "" {
+		merged.NotifyDiscord = override.NotifyDiscord
+	}
+
+	return &merged
+}
+
+// SetConfigValue sets a key in the project config, or in the home config if global is true. An empty value unsets the key.
+func SetConfigValue(key, value string, global bool) (string, error) {
+	path := fs.HomeConfigPath
+	if !global {
+		path = ProjectConfigPath()
+		if path == "" {
+			return "", fmt.Errorf("no project found, use --global to set a home-level config value")
+		}
+	}
+
+	config, err := readConfig(path)
+	if err != nil {
+		return "", err
+	}
+
+	// commands set here are the user's own, so they stay approved as long as the project's commands already were
+	keepTrusted := false
+	if !global && (key == "post-apply-hooks" || strings.HasPrefix(key, "formatters.")) {
+		keepTrusted, err = projectCommandsTrusted(path, config)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = setConfigValue(config, key, value)
+	if err != nil {
+		return "", err
+	}
+
+	err = writeConfig(path, config)
+	if err != nil {
+		return "", err
+	}
+
+	if keepTrusted {
+		err = trustProjectCommands(path)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return path, nil
+}
+
+func setConfigValue(config *types.PlandexConfig, key, value string) error {
+	value = strings.TrimSpace(value)
+
+	if role, ok := strings.CutPrefix(key, "models."); ok {
+		if !isModelRole(role) {
+			return fmt.Errorf("unknown model role %q", role)
+		}
+		if value == "" {
+			delete(config.Models, role)
+			return nil
+		}
+		if _, ok := shared.AvailableModelsByName[value]; !ok {
+			return fmt.Errorf("unknown model %q", value)
+		}
+		if config.Models == nil {
+			config.Models = map[string]string{}
+		}
+		config.Models[role] = value
+		return nil
+	}
+
+	if ext, ok := strings.CutPrefix(key, "formatters."); ok {
+		ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+		if ext == "" {
+			return fmt.Errorf("missing extension in %q", key)
+		}
+		if value == "" {
+			delete(config.Formatters, ext)
+			return nil
+		}
+		if config.Formatters == nil {
+			config.Formatters = map[string]string{}
+		}
+		config.Formatters[ext] = value
+		return nil
+	}
+
+	switch key {
+	case "auto-apply":
+		if value == "" {
+			config.AutoApply = nil
+			return nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for auto-apply: %s", value)
+		}
+		config.AutoApply = &b
+	case "ignore-extensions":
+		var exts []string
+		for _, ext := range splitConfigList(value) {
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			exts = append(exts, ext)
+		}
+		config.IgnoreExtensions = exts
+	case "default-context":
+		config.DefaultContext = splitConfigList(value)
+	case "editor":
+		config.Editor = value
+	case "plan-budget", "daily-budget":
+		var budget *float64
+		if value != "" {
+			f, err := strconv.ParseFloat(strings.TrimPrefix(value, "$"), 64)
+			if err != nil || f < 0 {
+				return fmt.Errorf("invalid value for %s: %s", key, value)
+			}
+			budget = &f
+		}
+		if key == "plan-budget" {
+			config.PlanBudget = budget
+		} else {
+			config.DailyBudget = budget
+		}
+	case "budget-action":
+		if value != "" && value != BudgetActionWarn && value != BudgetActionBlock {
+			return fmt.Errorf("invalid value for budget-action: %s (expected %s or %s)", value, BudgetActionWarn, BudgetActionBlock)
+		}
+		config.BudgetAction = value
+	case "post-apply-hooks":
+		config.PostApplyHooks = splitConfigList(value)
+	case "on-hook-failure":
+		if value != "" && value != HookFailureReport && value != HookFailureLoad && value != HookFailureFix {
+			return fmt.Errorf("invalid value for on-hook-failure: %s (expected %s, %s, or %s)", value, HookFailureReport, HookFailureLoad, HookFailureFix)
+		}
+		config.OnHookFailure = value
+	case "notify-slack", "notify-discord":
+		target := &shared.NotifyTarget{Type: shared.NotifyTargetSlack, WebhookUrl: value}
+		if key == "notify-discord" {
+			target.Type = shared.NotifyTargetDiscord
+		}
+		if value != "" {
+			err := target.Validate()
+			if err != nil {
+				return err
+			}
+		}
+		if key == "notify-slack" {
+			config.NotifySlack = value
+		} else {
+			config.NotifyDiscord = value
+		}
+	default:
+		return fmt.Errorf("unknown config key %q", key)
+	}
+
+	return nil
+}
+
+// GetConfigValue returns a key's value from a loaded config, or "" if it's unset
+func GetConfigValue(config *types.PlandexConfig, key string) (string, error) {
+	if role, ok := strings.CutPrefix(key, "models."); ok {
+		if !isModelRole(role) {
+			return "", fmt.Errorf("unknown model role %q", role)
+		}
+		return config.Models[role], nil
+	}
+
+	if ext, ok := strings.CutPrefix(key, "formatters."); ok {
+		return config.Formatters[strings.ToLower(strings.TrimPrefix(ext, "."))], nil
+	}
+
+	switch key {
+	case "auto-apply":
+		if config.AutoApply == nil {
+			return "", nil
+		}
+		return strconv.FormatBool(*config.AutoApply), nil
+	case "ignore-extensions":
+		return strings.Join(config.IgnoreExtensions, ","), nil
+	case "default-context":
+		return strings.Join(config.DefaultContext, ","), nil
+	case "editor":
+		return config.Editor, nil
+	case "plan-budget":
+		return formatConfigBudget(config.PlanBudget), nil
+	case "daily-budget":
+		return formatConfigBudget(config.DailyBudget), nil
+	case "budget-action":
+		return config.BudgetAction, nil
+	case "post-apply-hooks":
+		return strings.Join(config.PostApplyHooks, ","), nil
+	case "on-hook-failure":
+		return config.OnHookFailure, nil
+	case "notify-slack":
+		return config.NotifySlack, nil
+	case "notify-discord":
+		return config.NotifyDiscord, nil
+	}
+
+	return "", fmt.Errorf("unknown config key %q", key)
+}
+
+// ListConfigValues returns each set key with its value, with model roles listed individually
+func ListConfigValues(config *types.PlandexConfig) [][2]string {
+	var res [][2]string
+
+	var roles []string
+	for role := range config.Models {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		res = append(res, [2]string{"models." + role, config.Models[role]})
+	}
+
+	var exts []string
+	for ext := range config.Formatters {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	for _, ext := range exts {
+		res = append(res, [2]string{"formatters." + ext, config.Formatters[ext]})
+	}
+
+	for _, key := range ConfigKeys[1:] {
+		if key == "formatters.<ext>" {
+			continue
+		}
+		value, _ := GetConfigValue(config, key)
+		if value != "" {
+			res = append(res, [2]string{key, value})
+		}
+	}
+
+	return res
+}
+
+func formatConfigBudget(budget *float64) string {
+	if budget == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*budget, 'f', -1, 64)
+}
+
+func splitConfigList(value string) []string {
+	var res []string
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func isModelRole(role string) bool {
+	for _, r := range shared.AllModelRoles {
+		if string(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasIgnoredExtension reports whether a path ends with one of the config's ignore-extensions, which can span dots like .min.js
+func HasIgnoredExtension(config *types.PlandexConfig, path string) bool {
+	base := strings.ToLower(filepath.Base(path))
+	for _, ignored := range config.IgnoreExtensions {
+		if strings.HasSuffix(base, strings.ToLower(ignored)) {
+			return true
+		}
+	}
+	return false
+}
+
+// MustApplyConfigToNewPlan sets the config's models on a newly created plan and loads its default context
+func MustApplyConfigToNewPlan(planId string) {
+	config := MustLoadConfig()
+
+	if len(config.Models) > 0 {
+		MustSetModels(planId, "main", config.Models)
+		fmt.Println("🤖 Set models from config")
+	}
+
+	if len(config.DefaultContext) > 0 {
+		paths, err := getDefaultContextPaths(config.DefaultContext)
+		if err != nil {
+			term.OutputErrorAndExit("Error getting default context: %v", err)
+		}
+
+		if len(paths) == 0 {
+			fmt.Println("🤷‍♂️ No files matched default-context in config")
+			return
+		}
+
+		fmt.Println()
+		MustLoadContext(paths, &types.LoadContextParams{})
+	}
+}
+
+// MustSetModels updates a branch's model settings with model names by role, as in the models section of config.yml
+func MustSetModels(planId, branch string, models map[string]string) {
+	term.StartSpinner("")
+	settings, apiErr := api.Client.GetSettings(planId, branch)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting settings: %v", apiErr.Msg)
+	}
+
+	applyConfigModels(settings, models)
+
+	term.StartSpinner("")
+	_, apiErr = api.Client.UpdateSettings(planId, branch, shared.UpdateSettingsRequest{Settings: settings})
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error updating settings: %v", apiErr.Msg)
+	}
+}
+
+func applyConfigModels(settings *shared.PlanSettings, models map[string]string) {
+	if settings.ModelSet == nil {
+		modelSet := shared.DefaultModelSet
+		settings.ModelSet = &modelSet
+	}
+
+	for role, name := range models {
+		model, ok := shared.AvailableModelsByName[name]
+		if !ok {
+			continue
+		}
+
+		switch shared.ModelRole(role) {
+		case shared.ModelRolePlanner:
+			settings.ModelSet.Planner.BaseModelConfig = model
+			settings.ModelSet.Planner.PlannerModelConfig = shared.PlannerModelConfigByName[name]
+		case shared.ModelRolePlanSummary:
+			settings.ModelSet.PlanSummary.BaseModelConfig = model
+		case shared.ModelRoleBuilder:
+			settings.ModelSet.Builder.BaseModelConfig = model
+			settings.ModelSet.Builder.TaskModelConfig = shared.TaskModelConfigByName[name]
+		case shared.ModelRoleName:
+			settings.ModelSet.Namer.BaseModelConfig = model
+			settings.ModelSet.Namer.TaskModelConfig = shared.TaskModelConfigByName[name]
+		case shared.ModelRoleCommitMsg:
+			settings.ModelSet.CommitMsg.BaseModelConfig = model
+			settings.ModelSet.CommitMsg.TaskModelConfig = shared.TaskModelConfigByName[name]
+		case shared.ModelRoleExecStatus:
+			settings.ModelSet.ExecStatus.BaseModelConfig = model
+			settings.ModelSet.ExecStatus.TaskModelConfig = shared.TaskModelConfigByName[name]
+		}
+	}
+}
+
+// getDefaultContextPaths returns the project files matching any of the default-context globs, relative to the current dir
+func getDefaultContextPaths(globs []string) ([]string, error) {
+	projectPaths, err := fs.GetProjectPaths(fs.ProjectRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := ignore.CompileIgnoreLines(globs...)
+
+	var paths []string
+	for path := range projectPaths.ActivePaths {
+		if !matcher.MatchesPath(filepath.ToSlash(path)) {
+			continue
+		}
+
+		info, err := os.Stat(filepath.Join(fs.ProjectRoot, path))
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// ConfigNotifyTargets returns the chat channels to notify when a plan running in the background finishes
+func ConfigNotifyTargets(config *types.PlandexConfig) []*shared.NotifyTarget {
+	var targets []*shared.NotifyTarget
+	if config.NotifySlack != "" {
+		targets = append(targets, &shared.NotifyTarget{Type: shared.NotifyTargetSlack, WebhookUrl: config.NotifySlack})
+	}
+	if config.NotifyDiscord != "" {
+		targets = append(targets, &shared.NotifyTarget{Type: shared.NotifyTargetDiscord, WebhookUrl: config.NotifyDiscord})
+	}
+	return targets
+}
@@ -0,0 +1,237 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plandex/fs"
+	"plandex/term"
+	"plandex/types"
+	"sort"
+
+	"github.com/fatih/color"
+)
+
+// approval decisions already made in this run, by project config path, so apply only asks once
+var projectCommandsApproved = map[string]bool{}
+
+// MustLoadCommandConfig loads the config like MustLoadConfig, but only keeps the post-apply hooks and formatters from
+// the project config once they're approved. A project's config.yml comes with the repo, so its shell commands are
+// confirmed once, and again whenever they change. Commands in the home config always run.
+func MustLoadCommandConfig() *types.PlandexConfig {
+	config, err := readConfig(fs.HomeConfigPath)
+	if err != nil {
+		term.OutputErrorAndExit("Error loading config: %v", err)
+	}
+
+	path := ProjectConfigPath()
+	if path == "" {
+		return config
+	}
+
+	projectConfig, err := readConfig(path)
+	if err != nil {
+		term.OutputErrorAndExit("Error loading config: %v", err)
+	}
+
+	if !mustApproveProjectCommands(path, projectConfig) {
+		projectConfig.PostApplyHooks = nil
+		projectConfig.Formatters = nil
+	}
+
+	return mergeConfig(config, projectConfig)
+}
+
+// LoadApprovedCommandConfig loads the config for callers that can't prompt, keeping the project config's post-apply
+// hooks and formatters only if they were already approved
+func LoadApprovedCommandConfig() (*types.PlandexConfig, error) {
+	config, err := readConfig(fs.HomeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	path := ProjectConfigPath()
+	if path == "" {
+		return config, nil
+	}
+
+	projectConfig, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	approved, err := projectCommandsTrusted(path, projectConfig)
+	if err != nil {
+		return nil, err
+	}
+	if !approved {
+		projectConfig.PostApplyHooks = nil
+		projectConfig.Formatters = nil
+	}
+
+	return mergeConfig(config, projectConfig), nil
+}
+
+func mustApproveProjectCommands(path string, projectConfig *types.PlandexConfig) bool {
+	commands := projectCommands(projectConfig)
+	if len(commands) == 0 {
+		return true
+	}
+
+	if approved, ok := projectCommandsApproved[path]; ok {
+		return approved
+	}
+
+	trusted, err := readTrustedCommands()
+	if err != nil {
+		term.OutputErrorAndExit("Error loading trusted commands: %v", err)
+	}
+
+	hash := projectCommandsHash(projectConfig)
+	if trusted[path] == hash {
+		projectCommandsApproved[path] = true
+		return true
+	}
+
+	fmt.Println()
+	if _, ok := trusted[path]; ok {
+		color.New(color.Bold, term.ColorHiYellow).Printf("⚠️  The commands in %s have changed since you allowed them:\n", path)
+	} else {
+		color.New(color.Bold, term.ColorHiYellow).Printf("⚠️  %s runs these commands:\n", path)
+	}
+	for _, command := range commands {
+		fmt.Println("  " + command)
+	}
+	fmt.Println()
+
+	approved, err := term.ConfirmYesNo("Allow them to run? You'll be asked again if they change.")
+	if err != nil {
+		term.OutputErrorAndExit("failed to get confirmation user input: %s", err)
+	}
+	projectCommandsApproved[path] = approved
+
+	if !approved {
+		fmt.Println("The project's post-apply hooks and formatters will be skipped")
+		fmt.Println()
+		return false
+	}
+
+	trusted[path] = hash
+	err = writeTrustedCommands(trusted)
+	if err != nil {
+		term.OutputErrorAndExit("Error saving trusted commands: %v", err)
+	}
+	fmt.Println()
+
+	return true
+}
+
+// projectCommands lists a config's post-apply hooks and formatters as they're shown for approval
+func projectCommands(config *types.PlandexConfig) []string {
+	var commands []string
+	for _, hook := range config.PostApplyHooks {
+		commands = append(commands, "post-apply-hook: "+hook)
+	}
+
+	exts := make([]string, 0, len(config.Formatters))
+	for ext := range config.Formatters {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	for _, ext := range exts {
+		commands = append(commands, fmt.Sprintf("formatter for %s: %s", ext, config.Formatters[ext]))
+	}
+
+	return commands
+}
+
+func projectCommandsHash(config *types.PlandexConfig) string {
+	// map keys are marshalled in sorted order, so the hash only changes when the commands do
+	bytes, err := json.Marshal(map[string]interface{}{
+		"post-apply-hooks": config.PostApplyHooks,
+		"formatters":       config.Formatters,
+	})
+	if err != nil {
+		term.OutputErrorAndExit("Error hashing project commands: %v", err)
+	}
+
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:])
+}
+
+// projectCommandsTrusted returns whether a project config's commands are approved, or it doesn't have any
+func projectCommandsTrusted(path string, projectConfig *types.PlandexConfig) (bool, error) {
+	if len(projectCommands(projectConfig)) == 0 {
+		return true, nil
+	}
+
+	trusted, err := readTrustedCommands()
+	if err != nil {
+		return false, err
+	}
+
+	return trusted[path] == projectCommandsHash(projectConfig), nil
+}
+
+// trustProjectCommands records the project config's current commands as approved, for commands the user set themselves
+func trustProjectCommands(path string) error {
+	projectConfig, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+
+	trusted, err := readTrustedCommands()
+	if err != nil {
+		return err
+	}
+
+	if len(projectCommands(projectConfig)) == 0 {
+		delete(trusted, path)
+	} else {
+		trusted[path] = projectCommandsHash(projectConfig)
+	}
+
+	return writeTrustedCommands(trusted)
+}
+
+// readTrustedCommands returns the approved command hashes by project config path
+func readTrustedCommands() (map[string]string, error) {
+	trusted := map[string]string{}
+
+	bytes, err := os.ReadFile(fs.HomeTrustedCommandsPath)
+	if os.IsNotExist(err) {
+		return trusted, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", fs.HomeTrustedCommandsPath, err)
+	}
+
+	err = json.Unmarshal(bytes, &trusted)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling %s: %v", fs.HomeTrustedCommandsPath, err)
+	}
+
+	return trusted, nil
+}
+
+func writeTrustedCommands(trusted map[string]string) error {
+	bytes, err := json.MarshalIndent(trusted, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling trusted commands: %v", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(fs.HomeTrustedCommandsPath), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %v", filepath.Dir(fs.HomeTrustedCommandsPath), err)
+	}
+
+	err = os.WriteFile(fs.HomeTrustedCommandsPath, bytes, 0600)
+	if err != nil {
+		return fmt.Errorf("error writing %s: %v", fs.HomeTrustedCommandsPath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package lib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"plandex/fs"
+	"plandex/types"
+	"strings"
+)
+
+type archiveInput struct {
+	Path string
+}
+
+func parseArchiveInput(resource string) (*archiveInput, bool) {
+	if getArchiveFormat(resource) == "" {
+		return nil, false
+	}
+
+	info, err := os.Stat(resource)
+	if err != nil || info.IsDir() {
+		return nil, false
+	}
+
+	return &archiveInput{Path: resource}, true
+}
+
+func getArchiveFormat(path string) string {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	}
+	return ""
+}
+
+func (a *archiveInput) name() string {
+	return filepath.Base(a.Path)
+}
+
+// unpackArchive extracts the archive into the cache dir, keyed by the archive's content so an unchanged archive is only unpacked once.
+// Extraction happens in a temp dir that's renamed into place when complete, so a failed extraction never leaves a partial dir behind.
+// The extracted files are kept so contexts loaded from them can still be updated.
+func unpackArchive(a *archiveInput) (string, error) {
+	content, err := os.ReadFile(a.Path)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %v", a.Path, err)
+	}
+
+	hash := sha256.Sum256(content)
+	sha := hex.EncodeToString(hash[:])
+
+	archivesDir := filepath.Join(fs.CacheDir, "archives")
+	dir := filepath.Join(archivesDir, a.name()+"-"+sha[:12])
+
+	if _, err := os.Stat(dir); err == nil {
+		return dir, nil
+	}
+
+	err = os.MkdirAll(archivesDir, os.ModePerm)
+	if err != nil {
+		return "", fmt.Errorf("error creating cache dir: %v", err)
+	}
+
+	tmpDir, err := os.MkdirTemp(archivesDir, ".tmp-")
+	if err != nil {
+		return "", fmt.Errorf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	switch getArchiveFormat(a.Path) {
+	case "zip":
+		err = unpackZip(a.Path, tmpDir)
+	case "tar.gz":
+		err = unpackTar(a.Path, tmpDir, true)
+	case "tar":
+		err = unpackTar(a.Path, tmpDir, false)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error unpacking %s: %v", a.Path, err)
+	}
+
+	// making the dir a git repo means the archive's own .gitignore files are applied when getting paths
+	res, err := exec.Command("git", "-C", tmpDir, "init", "-q").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error initializing git repo for %s: %v, output: %s", a.Path, err, string(res))
+	}
+
+	err = os.Rename(tmpDir, dir)
+	if err != nil {
+		return "", fmt.Errorf("error moving unpacked %s into cache: %v", a.Path, err)
+	}
+
+	return dir, nil
+}
+
+func unpackZip(path, dest string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !f.Mode().IsRegular() {
+			continue
+		}
+
+		target, err := archiveTargetPath(dest, f.Name)
+		if err != nil {
+			return err
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+
+		err = writeArchiveFile(target, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unpackTar(path string, dest string, gzipped bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if gzipped {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		// directories are created as needed and links are skipped
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		target, err := archiveTargetPath(dest, header.Name)
+		if err != nil {
+			return err
+		}
+
+		err = writeArchiveFile(target, tr)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// archiveTargetPath returns where an archive entry is written, rejecting entries that would escape dest
+func archiveTargetPath(dest, name string) (string, error) {
+	target := filepath.Join(dest, filepath.FromSlash(name))
+	if target != dest && !strings.HasPrefix(target, dest+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid path in archive: %s", name)
+	}
+	return target, nil
+}
+
+func writeArchiveFile(target string, r io.Reader) error {
+	err := os.MkdirAll(filepath.Dir(target), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, r)
+	return err
+}
+
+// getArchiveFilePaths unpacks the archive and returns the absolute paths of the files to load, applying the archive's .gitignore and .plandexignore unless ignores are skipped
+func getArchiveFilePaths(a *archiveInput, params *types.LoadContextParams) (string, []string, error) {
+	dir, err := unpackArchive(a)
+	if err != nil {
+		return "", nil, err
+	}
+
+	paths, err := fs.GetPaths(dir, dir)
+	if err != nil {
+		return "", nil, fmt.Errorf("error getting paths for %s: %v", a.name(), err)
+	}
+
+	candidates := paths.ActivePaths
+	if params.ForceSkipIgnore {
+		candidates = paths.AllPaths
+	}
+
+	var res []string
+	for path := range candidates {
+		absPath := filepath.Join(dir, path)
+
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return "", nil, fmt.Errorf("error checking %s: %v", path, err)
+		}
+		if info.IsDir() {
+			continue
+		}
+
+		res = append(res, absPath)
+	}
+
+	return dir, res, nil
+}
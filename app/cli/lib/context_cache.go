@@ -0,0 +1,162 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"plandex/api"
+	"plandex/fs"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// Bodies a plan's server already has are tracked in the cache dir as empty files named by the body's sha256, so loading or
+// updating the same content again sends just the sha. The server says which shas it doesn't have, and those bodies are sent again.
+// Shas are only cached once a server has shown it accepts them, so older servers always get full bodies.
+
+// smaller bodies are always sent in full since the sha doesn't save much
+const minCachedBodySize = 1024
+
+func bodyCacheDir(planId string) string {
+	return filepath.Join(fs.CacheDir, "context", planId)
+}
+
+func cachedBodySha(planId, body string) string {
+	if len(body) < minCachedBodySize {
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(body))
+	sha := hex.EncodeToString(hash[:])
+
+	if _, err := os.Stat(filepath.Join(bodyCacheDir(planId), sha)); err != nil {
+		return ""
+	}
+	return sha
+}
+
+// the cache only saves uploads, so errors writing to it are logged rather than returned
+func cacheBodies(planId string, bodies []string) {
+	dir := bodyCacheDir(planId)
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		log.Printf("Error creating context cache dir: %v\n", err)
+		return
+	}
+
+	for _, body := range bodies {
+		if len(body) < minCachedBodySize {
+			continue
+		}
+
+		hash := sha256.Sum256([]byte(body))
+		err = os.WriteFile(filepath.Join(dir, hex.EncodeToString(hash[:])), nil, 0600)
+		if err != nil {
+			log.Printf("Error writing context cache: %v\n", err)
+			return
+		}
+	}
+}
+
+func forgetBodyShas(planId string, shas []string) {
+	log.Printf("Server is missing %d cached context bodies, sending them again\n", len(shas))
+
+	for _, sha := range shas {
+		err := os.Remove(filepath.Join(bodyCacheDir(planId), sha))
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("Error removing from context cache: %v\n", err)
+		}
+	}
+}
+
+// ForgetPlanBodies clears the cache for a deleted plan
+func ForgetPlanBodies(planId string) {
+	err := os.RemoveAll(bodyCacheDir(planId))
+	if err != nil {
+		log.Printf("Error removing context cache: %v\n", err)
+	}
+}
+
+// loadContextCached loads context, sending just the sha of any body the server already has. req isn't modified, so it can be sent again.
+func loadContextCached(planId, branch string, req shared.LoadContextRequest) (*shared.LoadContextResponse, *shared.ApiError) {
+	var sent shared.LoadContextRequest
+	for _, params := range req {
+		sha := cachedBodySha(planId, params.Body)
+		if sha == "" || params.SourceContextId != "" {
+			sent = append(sent, params)
+			continue
+		}
+
+		withSha := *params
+		withSha.Body = ""
+		withSha.BodySha = sha
+		sent = append(sent, &withSha)
+	}
+
+	res, apiErr := api.Client.LoadContext(planId, branch, sent)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	if len(res.MissingBodyShas) > 0 {
+		forgetBodyShas(planId, res.MissingBodyShas)
+
+		res, apiErr = api.Client.LoadContext(planId, branch, req)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+	}
+
+	if res.AcceptsBodySha && !res.MaxTokensExceeded {
+		var bodies []string
+		for _, params := range req {
+			bodies = append(bodies, params.Body)
+		}
+		cacheBodies(planId, bodies)
+	}
+
+	return res, nil
+}
+
+// updateContextCached is like loadContextCached for updates
+func updateContextCached(planId, branch string, req shared.UpdateContextRequest) (*shared.UpdateContextResponse, *shared.ApiError) {
+	sent := shared.UpdateContextRequest{}
+	for id, params := range req {
+		sha := cachedBodySha(planId, params.Body)
+		if sha == "" {
+			sent[id] = params
+			continue
+		}
+
+		withSha := *params
+		withSha.Body = ""
+		withSha.BodySha = sha
+		sent[id] = &withSha
+	}
+
+	res, apiErr := api.Client.UpdateContext(planId, branch, sent)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	if len(res.MissingBodyShas) > 0 {
+		forgetBodyShas(planId, res.MissingBodyShas)
+
+		res, apiErr = api.Client.UpdateContext(planId, branch, req)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+	}
+
+	if res.AcceptsBodySha && !res.MaxTokensExceeded {
+		var bodies []string
+		for _, params := range req {
+			bodies = append(bodies, params.Body)
+		}
+		cacheBodies(planId, bodies)
+	}
+
+	return res, nil
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"plandex/api"
 	"plandex/term"
+	"sort"
+	"strings"
 
 	"github.com/fatih/color"
 )
@@ -13,10 +15,13 @@ func checkContextConflicts(filesByPath map[string]string) (bool, error) {
 	// log.Println("Checking for context conflicts.")
 	// log.Println(spew.Sdump(filesByPath))
 
-	currentPlan, err := api.Client.GetCurrentPlanState(CurrentPlanId, CurrentBranch)
+	currentPlan, apiErr := api.Client.GetCurrentPlanState(CurrentPlanId, CurrentBranch)
 
-	if err != nil {
-		return false, fmt.Errorf("error getting current plan state: %v", err)
+	if apiErr != nil {
+		if IsOfflineErr(apiErr) {
+			return false, &offlineError{apiErr: apiErr}
+		}
+		return false, fmt.Errorf("error getting current plan state: %v", apiErr)
 	}
 
 	conflictedPaths := currentPlan.PlanResult.FileResultsByPath.ConflictedPaths(filesByPath)
@@ -25,6 +30,16 @@ func checkContextConflicts(filesByPath map[string]string) (bool, error) {
 
 	if len(conflictedPaths) > 0 {
 		term.StopSpinner()
+
+		if term.NonInteractive {
+			var paths []string
+			for path := range conflictedPaths {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			term.OutputErrorAndExitWithCode(term.ExitConflict, "Some context updates conflict with pending changes: %s", strings.Join(paths, ", "))
+		}
+
 		color.New(color.Bold, term.ColorHiYellow).Println("⚠️  Some updates conflict with pending changes:")
 		for path := range conflictedPaths {
 			fmt.Println("📄 " + path)
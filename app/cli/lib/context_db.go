@@ -0,0 +1,224 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const dbSchemaTimeout = 30 * time.Second
+
+// redactDbUrl removes the password from a database url so it can be stored with the context.
+// Updates reconnect with the redacted url, so the password then comes from PGPASSWORD or ~/.pgpass.
+func redactDbUrl(dbUrl string) (string, error) {
+	u, err := neturl.Parse(dbUrl)
+	if err != nil {
+		return "", fmt.Errorf("invalid database url: %v", err)
+	}
+
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return "", fmt.Errorf("unsupported database url scheme %q (only postgres:// and postgresql:// are supported)", u.Scheme)
+	}
+
+	if u.User != nil {
+		u.User = neturl.User(u.User.Username())
+	}
+
+	q := u.Query()
+	if q.Has("password") {
+		q.Del("password")
+		u.RawQuery = q.Encode()
+	}
+
+	return u.String(), nil
+}
+
+func dbContextName(dbUrl string) string {
+	u, err := neturl.Parse(dbUrl)
+	if err != nil {
+		return dbUrl
+	}
+	return u.Host + u.Path
+}
+
+type dbColumn struct {
+	name       string
+	dataType   string
+	notNull    bool
+	defaultVal sql.NullString
+}
+
+type dbRelation struct {
+	schema      string
+	name        string
+	kind        string
+	columns     []*dbColumn
+	constraints []string
+	indexes     []string
+}
+
+// getDbSchema introspects the tables, views, columns, constraints (including foreign keys), and indexes of a postgres database outside the system schemas.
+// The schema is returned as condensed DDL, one CREATE statement per relation followed by its indexes.
+func getDbSchema(dbUrl string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbSchemaTimeout)
+	defer cancel()
+
+	db, err := sql.Open("postgres", dbUrl)
+	if err != nil {
+		return "", fmt.Errorf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	var relations []*dbRelation
+	relationsByKey := map[string]*dbRelation{}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT n.nspname, c.relname, c.relkind, a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull, pg_get_expr(d.adbin, d.adrelid)
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE c.relkind IN ('r', 'p', 'v', 'm')
+			AND a.attnum > 0
+			AND NOT a.attisdropped
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+			AND n.nspname NOT LIKE 'pg_toast%'
+		ORDER BY n.nspname, c.relname, a.attnum`)
+	if err != nil {
+		return "", fmt.Errorf("error getting columns: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schema, name, kind string
+		col := &dbColumn{}
+		err := rows.Scan(&schema, &name, &kind, &col.name, &col.dataType, &col.notNull, &col.defaultVal)
+		if err != nil {
+			return "", fmt.Errorf("error scanning column: %v", err)
+		}
+
+		key := schema + "." + name
+		relation, ok := relationsByKey[key]
+		if !ok {
+			relation = &dbRelation{schema: schema, name: name, kind: kind}
+			relationsByKey[key] = relation
+			relations = append(relations, relation)
+		}
+		relation.columns = append(relation.columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error getting columns: %v", err)
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT n.nspname, c.relname, con.conname, pg_get_constraintdef(con.oid)
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE con.contype IN ('p', 'u', 'f', 'c', 'x')
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		ORDER BY n.nspname, c.relname, con.contype, con.conname`)
+	if err != nil {
+		return "", fmt.Errorf("error getting constraints: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schema, table, name, def string
+		err := rows.Scan(&schema, &table, &name, &def)
+		if err != nil {
+			return "", fmt.Errorf("error scanning constraint: %v", err)
+		}
+
+		if relation, ok := relationsByKey[schema+"."+table]; ok {
+			relation.constraints = append(relation.constraints, fmt.Sprintf("CONSTRAINT %s %s", name, def))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error getting constraints: %v", err)
+	}
+
+	// indexes that back a constraint are already covered by the constraint
+	rows, err = db.QueryContext(ctx, `
+		SELECT i.schemaname, i.tablename, i.indexdef
+		FROM pg_indexes i
+		WHERE i.schemaname NOT IN ('pg_catalog', 'information_schema')
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_constraint con
+				JOIN pg_class ic ON ic.oid = con.conindid
+				JOIN pg_namespace n ON n.oid = ic.relnamespace
+				WHERE ic.relname = i.indexname AND n.nspname = i.schemaname
+			)
+		ORDER BY i.schemaname, i.tablename, i.indexname`)
+	if err != nil {
+		return "", fmt.Errorf("error getting indexes: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schema, table, def string
+		err := rows.Scan(&schema, &table, &def)
+		if err != nil {
+			return "", fmt.Errorf("error scanning index: %v", err)
+		}
+
+		if relation, ok := relationsByKey[schema+"."+table]; ok {
+			relation.indexes = append(relation.indexes, def)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error getting indexes: %v", err)
+	}
+
+	if len(relations) == 0 {
+		return "", fmt.Errorf("no tables found")
+	}
+
+	var b strings.Builder
+	for _, relation := range relations {
+		b.WriteString(formatDbRelation(relation))
+		b.WriteString("\n")
+	}
+
+	return strings.TrimSpace(b.String()), nil
+}
+
+func formatDbRelation(relation *dbRelation) string {
+	var b strings.Builder
+
+	keyword := "TABLE"
+	switch relation.kind {
+	case "v":
+		keyword = "VIEW"
+	case "m":
+		keyword = "MATERIALIZED VIEW"
+	}
+
+	var lines []string
+	for _, col := range relation.columns {
+		line := col.name + " " + col.dataType
+		if col.notNull {
+			line += " NOT NULL"
+		}
+		if col.defaultVal.Valid {
+			line += " DEFAULT " + col.defaultVal.String
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, relation.constraints...)
+
+	fmt.Fprintf(&b, "CREATE %s %s.%s (\n", keyword, relation.schema, relation.name)
+	b.WriteString("  " + strings.Join(lines, ",\n  ") + "\n")
+	b.WriteString(");\n")
+
+	for _, index := range relation.indexes {
+		b.WriteString(index + ";\n")
+	}
+
+	return b.String()
+}
@@ -21,6 +21,18 @@ func GetContextTypeAndIcon(context *shared.Context) (string, string) {
 	case shared.ContextPipedDataType:
 		icon = "↔️ "
 		t = "piped"
+	case shared.ContextImageType:
+		icon = "🖼️ "
+		t = "image"
+	case shared.ContextMapType:
+		icon = "🗺️ "
+		t = "map"
+	case shared.ContextDbSchemaType:
+		icon = "🛢️ "
+		t = "schema"
+	case shared.ContextSpecType:
+		icon = "📐"
+		t = "spec"
 	}
 
 	return t, icon
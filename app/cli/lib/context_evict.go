@@ -0,0 +1,109 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"plandex/api"
+	"plandex/format"
+	"plandex/term"
+	"sort"
+	"strconv"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/plandex/plandex/shared"
+)
+
+// getEvictionCandidates picks the least recently loaded or updated unpinned contexts whose tokens cover the overage.
+// Contexts that the load would replace aren't candidates, since removing them frees nothing. It returns nil if all the candidates together aren't enough.
+func getEvictionCandidates(contexts []*shared.Context, loading shared.LoadContextRequest, overage int) []*shared.Context {
+	loadingPaths := map[string]bool{}
+	loadingUrls := map[string]bool{}
+	for _, context := range loading {
+		if context.FilePath != "" {
+			loadingPaths[context.FilePath] = true
+		}
+		if context.Url != "" {
+			loadingUrls[context.Url] = true
+		}
+	}
+
+	var candidates []*shared.Context
+	for _, context := range contexts {
+		if context.Pinned || loadingPaths[context.FilePath] || loadingUrls[context.Url] {
+			continue
+		}
+		candidates = append(candidates, context)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].UpdatedAt.Before(candidates[j].UpdatedAt)
+	})
+
+	freed := 0
+	for i, context := range candidates {
+		freed += context.NumTokens
+		if freed >= overage {
+			return candidates[:i+1]
+		}
+	}
+
+	return nil
+}
+
+// evictForLoad offers to remove the least recently used unpinned contexts to make room for a load that exceeded the token limit.
+// It returns true if contexts were removed and the load should be retried.
+func evictForLoad(loading shared.LoadContextRequest, overage int) (bool, error) {
+	contexts, apiErr := api.Client.ListContext(CurrentPlanId, CurrentBranch)
+	if apiErr != nil {
+		return false, fmt.Errorf("failed to list context: %v", apiErr.Msg)
+	}
+
+	toEvict := getEvictionCandidates(contexts, loading, overage)
+	if toEvict == nil {
+		term.OutputSimpleError("Removing all unpinned context still wouldn't free the %d 🪙 needed", overage)
+		return false, nil
+	}
+
+	fmt.Printf("🧹 Loading would exceed the token limit by %d 🪙. The least recently used unpinned context can be removed to make room:\n", overage)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Name", "Type", "🪙", "Updated"})
+	table.SetAutoWrapText(false)
+	for _, context := range toEvict {
+		t, icon := GetContextTypeAndIcon(context)
+		table.Append([]string{
+			" " + icon + " " + context.Name,
+			t,
+			"-" + strconv.Itoa(context.NumTokens),
+			format.Time(context.UpdatedAt),
+		})
+	}
+	table.Render()
+
+	confirmed, err := term.ConfirmYesNo("Remove %d piece(s) of context?", len(toEvict))
+	if err != nil {
+		return false, fmt.Errorf("failed to get confirmation: %v", err)
+	}
+	if !confirmed {
+		return false, nil
+	}
+
+	ids := map[string]bool{}
+	for _, context := range toEvict {
+		ids[context.Id] = true
+	}
+
+	term.StartSpinner("")
+	res, apiErr := api.Client.DeleteContext(CurrentPlanId, CurrentBranch, shared.DeleteContextRequest{Ids: ids})
+	term.StopSpinner()
+
+	if apiErr != nil {
+		return false, fmt.Errorf("failed to remove context: %v", apiErr.Msg)
+	}
+
+	fmt.Println("✅ " + color.New(color.FgWhite).Sprint(res.Msg))
+	fmt.Println()
+
+	return true, nil
+}
@@ -0,0 +1,70 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// matches path:start-end, e.g. main.go:120-240
+var lineRangeRegex = regexp.MustCompile(`^(.+):(\d+)-(\d+)$`)
+
+type lineRangeInput struct {
+	Path  string
+	Range string
+}
+
+func parseLineRangeInput(resource string) (*lineRangeInput, bool) {
+	// a file whose name actually ends in :n-m takes precedence
+	if _, err := os.Stat(resource); err == nil {
+		return nil, false
+	}
+
+	matches := lineRangeRegex.FindStringSubmatch(resource)
+	if matches == nil {
+		return nil, false
+	}
+
+	return &lineRangeInput{
+		Path:  matches[1],
+		Range: matches[2] + "-" + matches[3],
+	}, true
+}
+
+// extractLineRange returns lines start through end (1-indexed, inclusive) of content.
+// An end past the last line is clamped so a range still works after lines are removed from the end of the file.
+func extractLineRange(content, lineRange string) (string, error) {
+	startStr, endStr, ok := strings.Cut(lineRange, "-")
+	if !ok {
+		return "", fmt.Errorf("invalid line range %s", lineRange)
+	}
+
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid line range %s", lineRange)
+	}
+	end, err := strconv.Atoi(endStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid line range %s", lineRange)
+	}
+
+	if start < 1 || end < start {
+		return "", fmt.Errorf("invalid line range %s: lines are numbered from 1 and the end can't come before the start", lineRange)
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if start > len(lines) {
+		return "", fmt.Errorf("line range %s starts after the last line (%d)", lineRange, len(lines))
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	return strings.Join(lines[start-1:end], ""), nil
+}
@@ -1,16 +1,21 @@
 package lib
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"log"
+	"net/http"
 	"os"
-	"plandex/api"
+	"path/filepath"
 	"plandex/fs"
 	"plandex/term"
 	"plandex/types"
 	"plandex/url"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/fatih/color"
 	"github.com/plandex/plandex/shared"
@@ -19,11 +24,84 @@ import (
 func MustLoadContext(resources []string, params *types.LoadContextParams) {
 	term.StartSpinner("📥 Loading context...")
 
-	onErr := func(err error) {
-		term.StopSpinner()
+	res, err := LoadContext(context.Background(), resources, params)
+
+	term.StopSpinner()
+
+	if err != nil {
 		term.OutputErrorAndExit("Failed to load context: %v", err)
 	}
 
+	if res.Queued != nil {
+		if term.JsonOutput {
+			term.OutputJson(res)
+			os.Exit(0)
+		}
+
+		printCrawlMsgs(res.Crawls)
+		fmt.Println(term.Plain(fmt.Sprintf("📴 Couldn't reach the server, so the %s is queued. It'll be sent with the next command that can reach it.", QueuedOpDesc(res.Queued))))
+		printSkippedMsgs(res, params)
+		fmt.Println()
+		term.PrintCmds("", "tell", "ps", "sync")
+		os.Exit(0)
+	}
+
+	if res.Res == nil {
+		if term.JsonOutput {
+			term.OutputJson(res)
+			os.Exit(0)
+		}
+
+		printCrawlMsgs(res.Crawls)
+		fmt.Println(term.Plain("🤷‍♂️ No context loaded"))
+		printSkippedMsgs(res, params)
+		os.Exit(0)
+	}
+
+	if res.Res.MaxTokensExceeded {
+		overage := res.Res.TotalTokens - res.Res.MaxTokens
+		var hints []string
+		if !params.Evict {
+			hints = append(hints, "--evict to remove the least recently used unpinned context to make room")
+		}
+		if params.MaxTokensPerFile == 0 {
+			hints = append(hints, "--max-tokens-per-file to skip or truncate large files")
+		}
+		if len(hints) > 0 {
+			term.OutputErrorAndExitWithCode(term.ExitTokenLimit, "Update would add %d 🪙 and exceed token limit (%d) by %d 🪙\nUse %s", res.Res.TokensAdded, res.Res.MaxTokens, overage, strings.Join(hints, ", or "))
+		}
+		term.OutputErrorAndExitWithCode(term.ExitTokenLimit, "Update would add %d 🪙 and exceed token limit (%d) by %d 🪙\n", res.Res.TokensAdded, res.Res.MaxTokens, overage)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(res)
+		return
+	}
+
+	printCrawlMsgs(res.Crawls)
+
+	fmt.Println(term.Plain("✅ " + res.Res.Msg))
+
+	if len(res.Res.Unchanged) > 0 {
+		printUnchangedMsg(res.Res.Unchanged)
+	}
+
+	if len(res.Redacted) > 0 {
+		printRedactedMsg(res.Redacted)
+	}
+
+	printSkippedMsgs(res, params)
+}
+
+// LoadContext gathers the given resources, along with any note or piped data, and loads them into the current plan's context.
+// Failures for individual resources are collected and returned together rather than stopping at the first one. If any resource fails, nothing is loaded.
+// If nothing was loaded, the result's Res is nil. If loading would exceed the token limit, Res.MaxTokensExceeded is set and nothing is loaded.
+func LoadContext(ctx context.Context, resources []string, params *types.LoadContextParams) (*types.LoadContextResult, error) {
+	result := &types.LoadContextResult{
+		IgnoredPaths:   map[string]string{},
+		OversizedFiles: map[string]int{},
+	}
+
 	var loadContextReq shared.LoadContextRequest
 
 	if params.Note != "" {
@@ -34,75 +112,112 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 	}
 	fileInfo, err := os.Stdin.Stat()
 	if err != nil {
-		onErr(fmt.Errorf("failed to stat stdin: %v", err))
+		return nil, fmt.Errorf("failed to stat stdin: %v", err)
 	}
 	if fileInfo.Mode()&os.ModeNamedPipe != 0 {
-		reader := bufio.NewReader(os.Stdin)
-		pipedData, err := io.ReadAll(reader)
+		maxTokens, err := getPipedDataMaxTokens(params)
+		if err != nil {
+			return nil, err
+		}
+
+		pipedData, err := readPipedData(os.Stdin, params, maxTokens)
 		if err != nil {
-			onErr(fmt.Errorf("failed to read piped data: %v", err))
+			return nil, err
 		}
 
 		if len(pipedData) > 0 {
 			loadContextReq = append(loadContextReq, &shared.LoadContextParams{
 				ContextType: shared.ContextPipedDataType,
-				Body:        string(pipedData),
+				Body:        pipedData,
 			})
 		}
 	}
 
 	var inputUrls []string
 	var inputFilePaths []string
+	var inputRepos []*remoteRepo
+	var inputLineRanges []*lineRangeInput
+	var inputArchives []*archiveInput
 
 	if len(resources) > 0 {
 		for _, resource := range resources {
-			// so far resources are either files or urls
+			// resources are files, line ranges of files, archives, urls, or remote git repos
 			if url.IsValidURL(resource) {
 				inputUrls = append(inputUrls, resource)
+			} else if archive, ok := parseArchiveInput(resource); ok {
+				inputArchives = append(inputArchives, archive)
+			} else if lineRange, ok := parseLineRangeInput(resource); ok {
+				inputLineRanges = append(inputLineRanges, lineRange)
+			} else if repo, ok := parseRemoteRepo(resource); ok {
+				inputRepos = append(inputRepos, repo)
 			} else {
 				inputFilePaths = append(inputFilePaths, resource)
 			}
 		}
 	}
 
-	contextCh := make(chan *shared.LoadContextParams)
-	errCh := make(chan error)
+	// each resource is loaded in its own goroutine, with results and errors collected under the mutex
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
 
-	ignoredPaths := make(map[string]string)
+	onErr := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}
 
-	if len(inputFilePaths) > 0 {
-		baseDir := fs.GetBaseDirForFilePaths(inputFilePaths)
+	onContext := func(context *shared.LoadContextParams) {
+		mu.Lock()
+		defer mu.Unlock()
+		loadContextReq = append(loadContextReq, context)
+	}
 
-		paths, err := fs.GetProjectPaths(baseDir)
+	onSkipped := func(skipped *skippedFile) {
+		mu.Lock()
+		defer mu.Unlock()
+		if skipped.numTokens > 0 {
+			result.OversizedFiles[skipped.path] = skipped.numTokens
+		} else {
+			result.BinaryPaths = append(result.BinaryPaths, skipped.path)
+		}
+	}
+
+	loadFile := func(path, name string) {
+		defer wg.Done()
+
+		contexts, skipped, err := loadFileContext(path, name, params)
 		if err != nil {
-			onErr(fmt.Errorf("failed to get project paths: %v", err))
+			onErr(err)
+			return
 		}
 
-		// log.Println(spew.Sdump(paths))
+		if skipped != nil {
+			onSkipped(skipped)
+			return
+		}
 
-		// fmt.Println("active paths", len(paths.ActivePaths))
-		// fmt.Println("all paths", len(paths.AllPaths))
-		// fmt.Println("ignored paths", len(paths.IgnoredPaths))
+		for _, context := range contexts {
+			onContext(context)
+		}
+	}
+
+	if len(inputFilePaths) > 0 {
+		baseDir := fs.GetBaseDirForFilePaths(inputFilePaths)
 
-		// spew.Dump(paths.IgnoredPaths)
-		// spew.Dump(paths.ActivePaths)
+		paths, err := fs.GetProjectPaths(baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get project paths: %v", err)
+		}
 
 		if !params.ForceSkipIgnore {
 			var filteredPaths []string
 			for _, inputFilePath := range inputFilePaths {
-				// log.Println("inputFilePath", inputFilePath)
-
 				if _, ok := paths.ActivePaths[inputFilePath]; !ok {
-					// log.Println("not active", inputFilePath)
-
 					if _, ok := paths.IgnoredPaths[inputFilePath]; ok {
-						// log.Println("ignored", inputFilePath)
-
-						ignoredPaths[inputFilePath] = paths.IgnoredPaths[inputFilePath]
+						result.IgnoredPaths[inputFilePath] = paths.IgnoredPaths[inputFilePath]
 					}
 				} else {
-					// log.Println("active", inputFilePath)
-
 					filteredPaths = append(filteredPaths, inputFilePath)
 				}
 			}
@@ -111,11 +226,13 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 
 		if params.NamesOnly {
 			for _, inputFilePath := range inputFilePaths {
-
+				wg.Add(1)
 				go func(inputFilePath string) {
+					defer wg.Done()
+
 					flattenedPaths, err := ParseInputPaths([]string{inputFilePath}, params)
 					if err != nil {
-						errCh <- fmt.Errorf("failed to parse input paths: %v", err)
+						onErr(fmt.Errorf("failed to parse input paths: %v", err))
 						return
 					}
 
@@ -126,7 +243,9 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 								filteredPaths = append(filteredPaths, path)
 							} else {
 								if _, ok := paths.IgnoredPaths[path]; ok {
-									ignoredPaths[path] = paths.IgnoredPaths[path]
+									mu.Lock()
+									result.IgnoredPaths[path] = paths.IgnoredPaths[path]
+									mu.Unlock()
 								}
 							}
 						}
@@ -143,126 +262,377 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 						name = "parent"
 					}
 
-					contextCh <- &shared.LoadContextParams{
+					onContext(&shared.LoadContextParams{
 						ContextType:     shared.ContextDirectoryTreeType,
 						Name:            name,
 						Body:            body,
 						FilePath:        inputFilePath,
 						ForceSkipIgnore: params.ForceSkipIgnore,
+						MaxDepth:        params.MaxDepth,
+					})
+				}(inputFilePath)
+			}
+
+		} else if params.Map {
+			for _, inputFilePath := range inputFilePaths {
+				wg.Add(1)
+				go func(inputFilePath string) {
+					defer wg.Done()
+
+					body, ignored, err := getCodeMap(inputFilePath, params.ForceSkipIgnore, params.MaxDepth, paths)
+					if err != nil {
+						onErr(fmt.Errorf("failed to build code map for %s: %v", inputFilePath, err))
+						return
 					}
+
+					mu.Lock()
+					for path, reason := range ignored {
+						result.IgnoredPaths[path] = reason
+					}
+					mu.Unlock()
+
+					if body == "" {
+						onErr(fmt.Errorf("no definitions found in %s (supported languages: go, python, javascript, typescript, rust, java)", inputFilePath))
+						return
+					}
+
+					name := inputFilePath
+					if name == "." {
+						name = "cwd"
+					}
+					if name == ".." {
+						name = "parent"
+					}
+
+					onContext(&shared.LoadContextParams{
+						ContextType:     shared.ContextMapType,
+						Name:            name,
+						Body:            body,
+						FilePath:        inputFilePath,
+						ForceSkipIgnore: params.ForceSkipIgnore,
+						MaxDepth:        params.MaxDepth,
+					})
 				}(inputFilePath)
 			}
 
 		} else {
 			flattenedPaths, err := ParseInputPaths(inputFilePaths, params)
 			if err != nil {
-				onErr(fmt.Errorf("failed to parse input paths: %v", err))
+				return nil, fmt.Errorf("failed to parse input paths: %v", err)
 			}
 
 			if !params.ForceSkipIgnore {
+				config, err := LoadConfig()
+				if err != nil {
+					return nil, err
+				}
+
 				var filteredPaths []string
 				for _, path := range flattenedPaths {
-					if _, ok := paths.ActivePaths[path]; ok {
+					if HasIgnoredExtension(config, path) {
+						result.IgnoredPaths[path] = "config"
+					} else if _, ok := paths.ActivePaths[path]; ok {
 						filteredPaths = append(filteredPaths, path)
 					} else {
 						if _, ok := paths.IgnoredPaths[path]; ok {
-							ignoredPaths[path] = paths.IgnoredPaths[path]
+							result.IgnoredPaths[path] = paths.IgnoredPaths[path]
 						}
 					}
 				}
 				flattenedPaths = filteredPaths
 			}
 
-			inputFilePaths = flattenedPaths
-
 			for _, path := range flattenedPaths {
+				wg.Add(1)
+				go loadFile(path, path)
+			}
+		}
+	}
 
-				go func(path string) {
-					fileContent, err := os.ReadFile(path)
-					if err != nil {
-						errCh <- fmt.Errorf("failed to read the file %s: %v", path, err)
-						return
-					}
-					body := string(fileContent)
+	for _, lineRange := range inputLineRanges {
+		wg.Add(1)
+		go func(lineRange *lineRangeInput) {
+			defer wg.Done()
 
-					contextCh <- &shared.LoadContextParams{
-						ContextType: shared.ContextFileType,
-						Name:        path,
-						Body:        body,
-						FilePath:    path,
-					}
-				}(path)
+			fileContent, err := os.ReadFile(lineRange.Path)
+			if err != nil {
+				onErr(fmt.Errorf("failed to read the file %s: %v", lineRange.Path, err))
+				return
+			}
+
+			if !params.ForceBinary && isBinary(fileContent) {
+				onSkipped(&skippedFile{path: lineRange.Path})
+				return
+			}
+
+			body, err := extractLineRange(string(fileContent), lineRange.Range)
+			if err != nil {
+				onErr(fmt.Errorf("failed to load %s: %v", lineRange.Path, err))
+				return
+			}
+
+			onContext(&shared.LoadContextParams{
+				ContextType: shared.ContextFileType,
+				Name:        lineRange.Path + ":" + lineRange.Range,
+				Body:        body,
+				FilePath:    lineRange.Path,
+				LineRange:   lineRange.Range,
+			})
+		}(lineRange)
+	}
+
+	for _, repo := range inputRepos {
+		repoFilePaths, err := getRemoteRepoFilePaths(repo, params)
+		if err != nil {
+			onErr(fmt.Errorf("failed to load %s: %v", repo.name(), err))
+			continue
+		}
+
+		repoName := repo.name()
+		repoDir := repo.cacheDir()
+
+		for _, path := range repoFilePaths {
+			relPath, err := filepath.Rel(repoDir, path)
+			if err != nil {
+				onErr(fmt.Errorf("failed to get relative path for %s: %v", path, err))
+				continue
+			}
+
+			wg.Add(1)
+			go loadFile(path, repoName+"/"+filepath.ToSlash(relPath))
+		}
+	}
+
+	if params.DbUrl != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			redactedUrl, err := redactDbUrl(params.DbUrl)
+			if err != nil {
+				onErr(err)
+				return
+			}
+
+			body, err := getDbSchema(params.DbUrl)
+			if err != nil {
+				onErr(fmt.Errorf("failed to get the database schema for %s: %v", dbContextName(redactedUrl), err))
+				return
 			}
+
+			onContext(&shared.LoadContextParams{
+				ContextType: shared.ContextDbSchemaType,
+				Name:        dbContextName(redactedUrl),
+				Body:        body,
+				Url:         redactedUrl,
+			})
+		}()
+	}
+
+	for _, archive := range inputArchives {
+		archiveDir, archiveFilePaths, err := getArchiveFilePaths(archive, params)
+		if err != nil {
+			onErr(fmt.Errorf("failed to load %s: %v", archive.Path, err))
+			continue
+		}
+
+		archiveName := archive.name()
+
+		for _, path := range archiveFilePaths {
+			relPath, err := filepath.Rel(archiveDir, path)
+			if err != nil {
+				onErr(fmt.Errorf("failed to get relative path for %s: %v", path, err))
+				continue
+			}
+
+			wg.Add(1)
+			go loadFile(path, archiveName+"/"+filepath.ToSlash(relPath))
 		}
 	}
 
-	if len(inputUrls) > 0 {
+	// bound the number of concurrent fetches so loading many urls doesn't hammer a host or exhaust connections
+	urlConcurrency := params.UrlConcurrency
+	if urlConcurrency < 1 {
+		urlConcurrency = 1
+	}
+	urlSem := make(chan struct{}, urlConcurrency)
+
+	if params.Crawl {
+		// pages are deduplicated across sites as well as within each crawl
+		crawledShas := map[string]bool{}
+
 		for _, u := range inputUrls {
+			wg.Add(1)
 			go func(u string) {
-				body, err := url.FetchURLContent(u)
+				defer wg.Done()
+
+				crawlRes, err := url.CrawlSite(ctx, u, url.CrawlParams{
+					MaxPages:    params.MaxPages,
+					Concurrency: urlConcurrency,
+					Retries:     params.UrlRetries,
+					Raw:         params.Raw,
+					Headers:     params.Headers,
+				})
 				if err != nil {
-					errCh <- fmt.Errorf("failed to fetch content from URL %s: %v", u, err)
+					onErr(fmt.Errorf("failed to crawl %s: %v", u, err))
 					return
 				}
 
-				name := url.SanitizeURL(u)
-				// show the first 20 characters, then ellipsis then the last 20 characters of 'name'
-				if len(name) > 40 {
-					name = name[:20] + "⋯" + name[len(name)-20:]
+				report := &types.CrawlReport{
+					Url:           u,
+					NumDuplicates: crawlRes.NumDuplicates,
+					FailedUrls:    crawlRes.FailedUrls,
 				}
 
-				contextCh <- &shared.LoadContextParams{
+				for _, page := range crawlRes.Pages {
+					mu.Lock()
+					dup := crawledShas[page.Sha]
+					crawledShas[page.Sha] = true
+					mu.Unlock()
+
+					if dup {
+						report.NumDuplicates++
+						continue
+					}
+
+					numTokens, err := shared.GetNumTokens(page.Body)
+					if err != nil {
+						onErr(fmt.Errorf("failed to get the number of tokens for %s: %v", page.Url, err))
+						return
+					}
+
+					report.NumPages++
+					report.NumTokens += numTokens
+
+					onContext(&shared.LoadContextParams{
+						ContextType: shared.ContextURLType,
+						Name:        urlContextName(page.Url),
+						Body:        page.Body,
+						Url:         page.Url,
+						Raw:         params.Raw,
+					})
+				}
+
+				mu.Lock()
+				result.Crawls = append(result.Crawls, report)
+				mu.Unlock()
+			}(u)
+		}
+	} else {
+		for _, u := range inputUrls {
+			wg.Add(1)
+			go func(u string) {
+				defer wg.Done()
+
+				select {
+				case urlSem <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				defer func() { <-urlSem }()
+
+				var body string
+				var err error
+				if params.Render {
+					body, err = url.RenderURLContentContext(ctx, u, params.Raw, params.Headers)
+				} else {
+					body, err = url.FetchURLContentWithRetries(ctx, u, params.Raw, params.Headers, params.UrlRetries)
+				}
+				if err != nil {
+					onErr(fmt.Errorf("failed to fetch content from URL %s: %v", u, err))
+					return
+				}
+
+				onContext(&shared.LoadContextParams{
 					ContextType: shared.ContextURLType,
-					Name:        name,
+					Name:        urlContextName(u),
 					Body:        body,
 					Url:         u,
-				}
+					Rendered:    params.Render,
+					Raw:         params.Raw,
+				})
 			}(u)
 		}
 	}
 
-	for i := 0; i < len(inputFilePaths)+len(inputUrls); i++ {
-		select {
-		case err := <-errCh:
-			onErr(err)
-		case context := <-contextCh:
-			loadContextReq = append(loadContextReq, context)
-		}
+	doneCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(doneCh)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-doneCh:
 	}
 
-	filesToLoad := map[string]string{}
-	for _, context := range loadContextReq {
-		if context.ContextType == shared.ContextFileType {
-			filesToLoad[context.FilePath] = context.Body
-		}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if len(loadContextReq) == 0 {
+		return result, nil
 	}
 
-	hasConflicts, err := checkContextConflicts(filesToLoad)
+	hasConflicts, err := checkContextConflicts(wholeFilesByPath(loadContextReq))
 
-	if err != nil {
-		onErr(fmt.Errorf("failed to check context conflicts: %v", err))
+	var offlineErr *offlineError
+	offline := errors.As(err, &offlineErr) && params.QueueOffline
+	if err != nil && !offline {
+		return nil, fmt.Errorf("failed to check context conflicts: %v", err)
 	}
 
-	if len(loadContextReq) == 0 {
-		term.StopSpinner()
-		fmt.Println("🤷‍♂️ No context loaded")
-		if len(ignoredPaths) > 0 {
-			printIgnoredMsg()
+	// redaction happens after the conflict check, which needs the real file contents
+	if params.NoRedact {
+		for _, context := range loadContextReq {
+			context.NoRedact = true
 		}
-		os.Exit(0)
+	} else {
+		result.Redacted = redactContexts(loadContextReq)
 	}
 
-	res, apiErr := api.Client.LoadContext(CurrentPlanId, CurrentBranch, loadContextReq)
+	var res *shared.LoadContextResponse
+	var apiErr *shared.ApiError
+	if offline {
+		apiErr = offlineErr.apiErr
+	} else {
+		res, apiErr = loadContextCached(CurrentPlanId, CurrentBranch, loadContextReq)
+	}
 
 	if apiErr != nil {
-		onErr(fmt.Errorf("failed to load context: %v", apiErr.Msg))
+		if params.QueueOffline && IsOfflineErr(apiErr) {
+			log.Printf("Queueing context load: %s\n", apiErr.Msg)
+			result.Queued, err = queueLoadContext(loadContextReq)
+			if err != nil {
+				return nil, fmt.Errorf("failed to queue context load: %v", err)
+			}
+			return result, nil
+		}
+		return nil, fmt.Errorf("failed to load context: %v", apiErr.Msg)
 	}
 
-	term.StopSpinner()
+	if res.MaxTokensExceeded && params.Evict {
+		term.StopSpinner()
+		evicted, err := evictForLoad(loadContextReq, res.TotalTokens-res.MaxTokens)
+		if err != nil {
+			return nil, err
+		}
+
+		if evicted {
+			term.StartSpinner("📥 Loading context...")
+			res, apiErr = loadContextCached(CurrentPlanId, CurrentBranch, loadContextReq)
+
+			if apiErr != nil {
+				return nil, fmt.Errorf("failed to load context: %v", apiErr.Msg)
+			}
+		}
+	}
+
+	result.Res = res
 
 	if res.MaxTokensExceeded {
-		overage := res.TotalTokens - res.MaxTokens
-		term.OutputErrorAndExit("Update would add %d 🪙 and exceed token limit (%d) by %d 🪙\n", res.TokensAdded, res.MaxTokens, overage)
+		return result, nil
 	}
 
 	if hasConflicts {
@@ -270,20 +640,262 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 		_, err := buildPlanInlineFn(nil)
 
 		if err != nil {
-			onErr(fmt.Errorf("failed to build plan: %v", err))
+			return nil, fmt.Errorf("failed to build plan: %v", err)
 		}
 
 		fmt.Println()
 	}
 
-	fmt.Println("✅ " + res.Msg)
+	return result, nil
+}
+
+// only whole files can be compared with pending changes
+func wholeFilesByPath(req shared.LoadContextRequest) map[string]string {
+	filesByPath := map[string]string{}
+	for _, context := range req {
+		if context.ContextType == shared.ContextFileType && context.LineRange == "" && context.ChunkPart == 0 {
+			filesByPath[context.FilePath] = context.Body
+		}
+	}
+	return filesByPath
+}
+
+func urlContextName(u string) string {
+	name := url.SanitizeURL(u)
+	// show the first 20 characters, then ellipsis then the last 20 characters of 'name'
+	if len(name) > 40 {
+		name = name[:20] + "⋯" + name[len(name)-20:]
+	}
+	return name
+}
+
+func printCrawlMsgs(crawls []*types.CrawlReport) {
+	for _, crawl := range crawls {
+		label := "page"
+		if crawl.NumPages != 1 {
+			label = "pages"
+		}
+		fmt.Print(term.Plain(fmt.Sprintf("🕸️  Crawled %d %s from %s | %d 🪙\n", crawl.NumPages, label, crawl.Url, crawl.NumTokens)))
+
+		if crawl.NumDuplicates > 0 {
+			fmt.Println(color.New(color.FgWhite).Sprintf("  • Skipped %d duplicate pages", crawl.NumDuplicates))
+		}
+
+		if len(crawl.FailedUrls) > 0 {
+			fmt.Println(color.New(color.FgWhite).Sprintf("  • Couldn't load %d pages:", len(crawl.FailedUrls)))
+			for _, u := range crawl.FailedUrls {
+				fmt.Println(color.New(color.FgWhite).Sprintf("    %s", u))
+			}
+		}
+	}
+}
+
+func printUnchangedMsg(names []string) {
+	fmt.Println()
+	fmt.Println(term.Plain("ℹ️  " + color.New(color.FgWhite).Sprint("Already in context, unchanged:")))
+	for _, name := range names {
+		fmt.Println(color.New(color.FgWhite).Sprintf("  • %s", name))
+	}
+}
 
-	if len(ignoredPaths) > 0 {
+func printSkippedMsgs(res *types.LoadContextResult, params *types.LoadContextParams) {
+	if len(res.IgnoredPaths) > 0 {
 		printIgnoredMsg()
 	}
+
+	if len(res.BinaryPaths) > 0 {
+		printBinaryMsg(res.BinaryPaths)
+	}
+
+	if len(res.OversizedFiles) > 0 {
+		printOversizedMsg(res.OversizedFiles, params.MaxTokensPerFile)
+	}
+}
+
+type skippedFile struct {
+	path string
+	// set if the file was skipped for exceeding the per-file token limit, otherwise it was skipped for being binary
+	numTokens int
+}
+
+// loadFileContext reads a file and converts it to the appropriate context type.
+// A file is returned as a single context unless it's split into parts by --chunk.
+// It returns a skippedFile instead of contexts if the file is binary or exceeds the per-file token limit.
+func loadFileContext(path, name string, params *types.LoadContextParams) ([]*shared.LoadContextParams, *skippedFile, error) {
+	fileContent, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the file %s: %v", path, err)
+	}
+
+	if shared.IsImageMimeType(http.DetectContentType(fileContent)) {
+		dataUrl, err := shared.GetImageDataUrl(fileContent)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode the image %s: %v", path, err)
+		}
+
+		return []*shared.LoadContextParams{{
+			ContextType: shared.ContextImageType,
+			Name:        name,
+			Body:        dataUrl,
+			FilePath:    path,
+		}}, nil, nil
+	}
+
+	if isPdf(fileContent) {
+		body, err := getPdfText(fileContent, params.PdfPages)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to extract text from the pdf %s: %v", path, err)
+		}
+
+		context := &shared.LoadContextParams{
+			ContextType: shared.ContextFileType,
+			Name:        name,
+			Body:        body,
+			FilePath:    path,
+			PdfPages:    params.PdfPages,
+		}
+
+		return applyMaxTokensPerFile(context, params)
+	}
+
+	if format := getSpecFormat(path, fileContent); format != "" {
+		body := string(fileContent)
+		if !params.FullSpec {
+			body, err = summarizeSpec(format, fileContent)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to summarize the spec %s: %v", path, err)
+			}
+		}
+
+		// Raw marks a spec loaded in full so updates don't condense it
+		context := &shared.LoadContextParams{
+			ContextType: shared.ContextSpecType,
+			Name:        name,
+			Body:        body,
+			FilePath:    path,
+			Raw:         params.FullSpec,
+		}
+
+		return applyMaxTokensPerFile(context, params)
+	}
+
+	if !params.ForceBinary && isBinary(fileContent) {
+		return nil, &skippedFile{path: path}, nil
+	}
+
+	context := &shared.LoadContextParams{
+		ContextType: shared.ContextFileType,
+		Name:        name,
+		Body:        string(fileContent),
+		FilePath:    path,
+	}
+
+	if params.Summarize {
+		context.Summarize = true
+		context.ApiKey = os.Getenv("OPENAI_API_KEY")
+	}
+
+	return applyMaxTokensPerFile(context, params)
+}
+
+// applyMaxTokensPerFile handles a context body that exceeds the per-file token limit.
+// With --chunk it's split into parts that each fit the limit, and with --truncate it's truncated. Otherwise, an oversized file is returned as skipped.
+func applyMaxTokensPerFile(context *shared.LoadContextParams, params *types.LoadContextParams) ([]*shared.LoadContextParams, *skippedFile, error) {
+	// summarized files are shrunk server-side instead
+	if params.MaxTokensPerFile <= 0 || context.Summarize {
+		return []*shared.LoadContextParams{context}, nil, nil
+	}
+
+	numTokens, err := shared.GetNumTokens(context.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the number of tokens in the file %s: %v", context.FilePath, err)
+	}
+
+	if numTokens <= params.MaxTokensPerFile {
+		return []*shared.LoadContextParams{context}, nil, nil
+	}
+
+	// only plain files are chunked, since parts are re-read as plain text on update
+	if params.ChunkOversized && context.ContextType == shared.ContextFileType {
+		return chunkFileContext(context, params.MaxTokensPerFile)
+	}
+
+	if !params.TruncateOversized {
+		return nil, &skippedFile{path: context.FilePath, numTokens: numTokens}, nil
+	}
+
+	context.Body, err = shared.TruncateHeadTail(context.Body, params.MaxTokensPerFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to truncate the file %s: %v", context.FilePath, err)
+	}
+
+	return []*shared.LoadContextParams{context}, nil, nil
+}
+
+// chunkFileContext splits a file context into parts named like "file.go (part 2/5)".
+// Each part records its number and the token limit so it can be re-chunked the same way on update.
+func chunkFileContext(context *shared.LoadContextParams, maxTokens int) ([]*shared.LoadContextParams, *skippedFile, error) {
+	chunks, err := shared.ChunkByBoundaries(context.Body, maxTokens)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to split the file %s into parts: %v", context.FilePath, err)
+	}
+
+	var contexts []*shared.LoadContextParams
+	for i, chunk := range chunks {
+		part := *context
+		part.Name = chunkName(context.Name, i+1, len(chunks))
+		part.Body = chunk
+		part.ChunkPart = i + 1
+		part.ChunkMaxTokens = maxTokens
+		contexts = append(contexts, &part)
+	}
+
+	return contexts, nil, nil
+}
+
+func chunkName(name string, part, numParts int) string {
+	return fmt.Sprintf("%s (part %d/%d)", name, part, numParts)
 }
 
 func printIgnoredMsg() {
 	fmt.Println()
-	fmt.Println("ℹ️  " + color.New(color.FgWhite).Sprint("Due to .gitignore or .plandexignore, some paths weren't loaded.\nUse --force / -f to load ignored paths."))
+	fmt.Println(term.Plain("ℹ️  " + color.New(color.FgWhite).Sprint("Due to .gitignore, .plandexignore, the default ignores for dependency and build dirs, or ignore-extensions in config, some paths weren't loaded.\nUse --force / -f to load ignored paths.")))
+}
+
+func printBinaryMsg(binaryPaths []string) {
+	sort.Strings(binaryPaths)
+
+	fmt.Println()
+	fmt.Println(term.Plain("ℹ️  " + color.New(color.FgWhite).Sprint("These files look like binary files and weren't loaded:")))
+	for _, path := range binaryPaths {
+		fmt.Println(color.New(color.FgWhite).Sprint("  • " + path))
+	}
+	fmt.Println(color.New(color.FgWhite).Sprint("Use --force-binary to load them anyway."))
+}
+
+func printOversizedMsg(oversizedFiles map[string]int, maxTokensPerFile int) {
+	var paths []string
+	for path := range oversizedFiles {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return oversizedFiles[paths[i]] > oversizedFiles[paths[j]]
+	})
+
+	fmt.Println()
+	fmt.Println(term.Plain("ℹ️  " + color.New(color.FgWhite).Sprintf("These files exceed the per-file limit of %d 🪙 and weren't loaded:", maxTokensPerFile)))
+	for _, path := range paths {
+		fmt.Println(term.Plain(color.New(color.FgWhite).Sprintf("  • %s | %d 🪙", path, oversizedFiles[path])))
+	}
+	fmt.Println(color.New(color.FgWhite).Sprint("Use --truncate to load the beginning and end of each file instead, or --chunk to load each file in parts."))
+}
+
+// isBinary uses the same heuristic as git: content with a NUL byte in the first 8000 bytes is treated as binary
+func isBinary(content []byte) bool {
+	sniffLen := 8000
+	if len(content) < sniffLen {
+		sniffLen = len(content)
+	}
+
+	return bytes.IndexByte(content[:sniffLen], 0) != -1
 }
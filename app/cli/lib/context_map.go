@@ -0,0 +1,99 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plandex/fs"
+	"plandex/types"
+	"sort"
+	"strings"
+)
+
+// Code maps are parsed with tree-sitter, which needs cgo. Builds without cgo fall back to go/parser,
+// so only go files are mapped (see context_map_nocgo.go).
+
+// getCodeMap builds the code map for a file or directory, descending into directories up to maxDepth.
+// Unless ignores are skipped, files that aren't active project paths are left out and returned as ignored.
+func getCodeMap(inputPath string, forceSkipIgnore bool, maxDepth int, paths *fs.ProjectPaths) (string, map[string]string, error) {
+	flattenedPaths, err := ParseInputPaths([]string{inputPath}, &types.LoadContextParams{
+		Recursive:       true,
+		ForceSkipIgnore: forceSkipIgnore,
+		MaxDepth:        maxDepth,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse input paths: %v", err)
+	}
+
+	ignored := map[string]string{}
+
+	if !forceSkipIgnore {
+		if paths == nil {
+			return "", nil, fmt.Errorf("project paths are nil")
+		}
+
+		var filteredPaths []string
+		for _, path := range flattenedPaths {
+			if _, ok := paths.ActivePaths[path]; ok {
+				filteredPaths = append(filteredPaths, path)
+			} else if reason, ok := paths.IgnoredPaths[path]; ok {
+				ignored[path] = reason
+			}
+		}
+		flattenedPaths = filteredPaths
+	}
+
+	body, err := buildCodeMap(flattenedPaths)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return body, ignored, nil
+}
+
+// buildCodeMap lists the definitions in each file with a supported language, with a signature per line and nested definitions indented, e.g.
+//
+//	lib/context_map.go
+//	  func buildCodeMap(paths []string) (string, error)
+//
+// Files in unsupported languages are skipped.
+func buildCodeMap(paths []string) (string, error) {
+	sorted := make([]string, len(paths))
+	copy(sorted, paths)
+	sort.Strings(sorted)
+
+	var b strings.Builder
+
+	for _, path := range sorted {
+		lang, ok := mapLanguagesByExt[strings.ToLower(filepath.Ext(path))]
+		if !ok {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the file %s: %v", path, err)
+		}
+
+		if isBinary(content) {
+			continue
+		}
+
+		defs, err := getCodeMapDefs(content, lang)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+
+		if len(defs) == 0 {
+			continue
+		}
+
+		b.WriteString(path + "\n")
+		for _, def := range defs {
+			b.WriteString(def + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,137 @@
+//go:build cgo
+
+package lib
+
+import (
+	"context"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/rust"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+type mapLanguage struct {
+	language *sitter.Language
+	// node types that are included in the map
+	defTypes map[string]bool
+}
+
+func newMapLanguage(language *sitter.Language, defTypes ...string) *mapLanguage {
+	lang := &mapLanguage{language: language, defTypes: map[string]bool{}}
+	for _, t := range defTypes {
+		lang.defTypes[t] = true
+	}
+	return lang
+}
+
+var (
+	mapGo         = newMapLanguage(golang.GetLanguage(), "function_declaration", "method_declaration", "type_spec")
+	mapPython     = newMapLanguage(python.GetLanguage(), "function_definition", "class_definition")
+	mapJavascript = newMapLanguage(javascript.GetLanguage(), "function_declaration", "generator_function_declaration", "class_declaration", "method_definition")
+	mapTypescript = newMapLanguage(typescript.GetLanguage(), "function_declaration", "generator_function_declaration", "class_declaration", "abstract_class_declaration", "method_definition", "method_signature", "interface_declaration", "type_alias_declaration", "enum_declaration")
+	mapTsx        = newMapLanguage(tsx.GetLanguage(), "function_declaration", "generator_function_declaration", "class_declaration", "abstract_class_declaration", "method_definition", "method_signature", "interface_declaration", "type_alias_declaration", "enum_declaration")
+	mapRust       = newMapLanguage(rust.GetLanguage(), "function_item", "function_signature_item", "struct_item", "enum_item", "trait_item", "impl_item", "type_item")
+	mapJava       = newMapLanguage(java.GetLanguage(), "class_declaration", "interface_declaration", "enum_declaration", "record_declaration", "method_declaration", "constructor_declaration")
+)
+
+var mapLanguagesByExt = map[string]*mapLanguage{
+	".go":   mapGo,
+	".py":   mapPython,
+	".js":   mapJavascript,
+	".jsx":  mapJavascript,
+	".mjs":  mapJavascript,
+	".cjs":  mapJavascript,
+	".ts":   mapTypescript,
+	".mts":  mapTypescript,
+	".cts":  mapTypescript,
+	".tsx":  mapTsx,
+	".rs":   mapRust,
+	".java": mapJava,
+}
+
+func getCodeMapDefs(content []byte, lang *mapLanguage) ([]string, error) {
+	root, err := sitter.ParseCtx(context.Background(), content, lang.language)
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []string
+
+	var walk func(node *sitter.Node, depth int)
+	walk = func(node *sitter.Node, depth int) {
+		childDepth := depth
+
+		if lang.defTypes[node.Type()] {
+			sig := getCodeMapSignature(node, content)
+			if sig != "" {
+				defs = append(defs, strings.Repeat("  ", depth+1)+sig)
+				childDepth++
+			}
+		}
+
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			walk(node.NamedChild(i), childDepth)
+		}
+	}
+
+	walk(root, 0)
+
+	return defs, nil
+}
+
+// getCodeMapDefNames returns the names of the definitions that are included in the code map
+func getCodeMapDefNames(content []byte, lang *mapLanguage) ([]string, error) {
+	root, err := sitter.ParseCtx(context.Background(), content, lang.language)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	var walk func(node *sitter.Node)
+	walk = func(node *sitter.Node) {
+		if lang.defTypes[node.Type()] {
+			if name := node.ChildByFieldName("name"); name != nil {
+				names = append(names, name.Content(content))
+			}
+		}
+
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			walk(node.NamedChild(i))
+		}
+	}
+
+	walk(root)
+
+	return names, nil
+}
+
+// getCodeMapSignature returns the part of a definition before its body, collapsed onto a single line
+func getCodeMapSignature(node *sitter.Node, content []byte) string {
+	end := node.EndByte()
+	if body := node.ChildByFieldName("body"); body != nil {
+		end = body.StartByte()
+	}
+
+	sig := string(content[node.StartByte():end])
+
+	// types without a body field (go type specs, ts type aliases, etc.) are cut at the first line
+	if node.ChildByFieldName("body") == nil {
+		sig, _, _ = strings.Cut(sig, "\n")
+	}
+
+	sig = strings.Join(strings.Fields(sig), " ")
+	sig = strings.TrimRight(sig, " {:=")
+
+	if node.Type() == "type_spec" {
+		sig = "type " + sig
+	}
+
+	return sig
+}
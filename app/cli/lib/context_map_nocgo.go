@@ -0,0 +1,107 @@
+//go:build !cgo
+
+package lib
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// without cgo there's no tree-sitter, so go files are parsed with go/parser and other languages aren't mapped
+type mapLanguage struct{}
+
+var mapGo = &mapLanguage{}
+
+var mapLanguagesByExt = map[string]*mapLanguage{
+	".go": mapGo,
+}
+
+type codeMapDef struct {
+	name  string
+	sig   string
+	depth int
+}
+
+// getCodeMapGoDefs lists funcs, methods, and types, including types declared inside funcs, the same way the tree-sitter map does.
+// Like tree-sitter, a file with syntax errors is mapped as far as it parses.
+func getCodeMapGoDefs(content []byte) ([]codeMapDef, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.SkipObjectResolution)
+	if file == nil {
+		return nil, err
+	}
+
+	offset := func(pos token.Pos) int {
+		return fset.Position(pos).Offset
+	}
+
+	typeDef := func(spec *ast.TypeSpec, depth int) codeMapDef {
+		sig, _, _ := strings.Cut(string(content[offset(spec.Pos()):offset(spec.End())]), "\n")
+		return codeMapDef{name: spec.Name.Name, sig: "type " + collapseCodeMapSignature(sig), depth: depth}
+	}
+
+	var defs []codeMapDef
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			end := offset(decl.End())
+			if decl.Body != nil {
+				end = offset(decl.Body.Lbrace)
+			}
+			defs = append(defs, codeMapDef{name: decl.Name.Name, sig: collapseCodeMapSignature(string(content[offset(decl.Pos()):end])), depth: 1})
+
+			if decl.Body != nil {
+				ast.Inspect(decl.Body, func(node ast.Node) bool {
+					if spec, ok := node.(*ast.TypeSpec); ok {
+						defs = append(defs, typeDef(spec, 2))
+					}
+					return true
+				})
+			}
+
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if spec, ok := spec.(*ast.TypeSpec); ok {
+					defs = append(defs, typeDef(spec, 1))
+				}
+			}
+		}
+	}
+
+	return defs, nil
+}
+
+func collapseCodeMapSignature(sig string) string {
+	sig = strings.Join(strings.Fields(sig), " ")
+	return strings.TrimRight(sig, " {:=")
+}
+
+func getCodeMapDefs(content []byte, lang *mapLanguage) ([]string, error) {
+	goDefs, err := getCodeMapGoDefs(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []string
+	for _, def := range goDefs {
+		defs = append(defs, strings.Repeat("  ", def.depth)+def.sig)
+	}
+	return defs, nil
+}
+
+// getCodeMapDefNames returns the names of the definitions that are included in the code map
+func getCodeMapDefNames(content []byte, lang *mapLanguage) ([]string, error) {
+	goDefs, err := getCodeMapGoDefs(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, def := range goDefs {
+		names = append(names, def.name)
+	}
+	return names, nil
+}
@@ -0,0 +1,43 @@
+package lib
+
+import (
+	"fmt"
+	"path/filepath"
+	"plandex/fs"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// ContextMatchesArg checks whether a context matches an index (1-based, as shown by `plandex ls`), name, path, url, glob, or parent directory given on the command line
+func ContextMatchesArg(i int, context *shared.Context, arg string) (bool, error) {
+	if fmt.Sprintf("%d", i+1) == arg || context.Name == arg || context.FilePath == arg || context.Url == arg {
+		return true, nil
+	}
+
+	if context.FilePath == "" {
+		return filepath.Match(arg, context.Name)
+	}
+
+	// paths can be given with forward slashes on windows
+	pathArg := filepath.Clean(filepath.FromSlash(arg))
+
+	// Check if arg is a glob pattern
+	matched, err := filepath.Match(pathArg, context.FilePath)
+	if err != nil {
+		return false, err
+	}
+	if matched {
+		return true, nil
+	}
+
+	// Check if arg is a parent directory
+	parentDir := context.FilePath
+	for !fs.IsTopDir(parentDir) {
+		if parentDir == pathArg {
+			return true, nil
+		}
+		parentDir = filepath.Dir(parentDir) // Move up one directory
+	}
+
+	return false, nil
+}
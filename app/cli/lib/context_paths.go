@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"plandex/fs"
 	"plandex/types"
 	"strings"
 	"sync"
@@ -15,12 +16,15 @@ func ParseInputPaths(fileOrDirPaths []string, params *types.LoadContextParams) (
 	var firstErr error
 	resPaths := []string{}
 
+	// the same file can be reached through more than one input path when symlinks are followed, so files are deduped by their real path
+	seenRealPaths := map[string]bool{}
+
 	for _, path := range fileOrDirPaths {
 		wg.Add(1)
 		go func(p string) {
 			defer wg.Done()
 
-			err := filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			err := fs.Walk(p, func(path string, info os.FileInfo, err error) error {
 				if err != nil {
 					return err
 				}
@@ -42,17 +46,24 @@ func ParseInputPaths(fileOrDirPaths []string, params *types.LoadContextParams) (
 						return fmt.Errorf("cannot process directory %s: --recursive or --tree flag not set", path)
 					}
 
-					// calculate directory depth from base
-					// depth := strings.Count(path[len(p):], string(filepath.Separator))
-					// if params.MaxDepth != -1 && depth > params.MaxDepth {
-					// 	return filepath.SkipDir
-					// }
+					if params.MaxDepth > 0 && dirDepth(p, path) > params.MaxDepth {
+						return filepath.SkipDir
+					}
 
 					if params.NamesOnly {
 						// add directory name to results
 						resPaths = append(resPaths, path)
 					}
 				} else {
+					realPath, err := filepath.EvalSymlinks(path)
+					if err != nil {
+						return err
+					}
+					if seenRealPaths[realPath] {
+						return nil
+					}
+					seenRealPaths[realPath] = true
+
 					// add file path to results
 					resPaths = append(resPaths, path)
 				}
@@ -78,3 +89,13 @@ func ParseInputPaths(fileOrDirPaths []string, params *types.LoadContextParams) (
 
 	return resPaths, nil
 }
+
+// dirDepth is how many directories below root a path is, with root itself at 0. It works with any form of root, like
+// "." or one that ends in a separator.
+func dirDepth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
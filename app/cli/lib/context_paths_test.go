@@ -0,0 +1,27 @@
+package lib
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDirDepth(t *testing.T) {
+	tests := []struct {
+		root, path string
+		want       int
+	}{
+		{"src", "src", 0},
+		{"src", filepath.Join("src", "a"), 1},
+		{"src", filepath.Join("src", "a", "b"), 2},
+		{".", ".", 0},
+		{".", "a", 1},
+		{".", filepath.Join("a", "b"), 2},
+		{"src" + string(filepath.Separator), filepath.Join("src", "a"), 1},
+	}
+
+	for _, tt := range tests {
+		if got := dirDepth(tt.root, tt.path); got != tt.want {
+			t.Errorf("dirDepth(%q, %q) = %d, want %d", tt.root, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+func isPdf(content []byte) bool {
+	return http.DetectContentType(content) == "application/pdf"
+}
+
+// parsePdfPageRange parses a page range like "5-20" or "7". An empty string selects all pages, which is returned as 0, 0.
+func parsePdfPageRange(pages string) (int, int, error) {
+	if pages == "" {
+		return 0, 0, nil
+	}
+
+	startStr, endStr, isRange := strings.Cut(pages, "-")
+	if !isRange {
+		endStr = startStr
+	}
+
+	start, err := strconv.Atoi(strings.TrimSpace(startStr))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid page range %q", pages)
+	}
+	end, err := strconv.Atoi(strings.TrimSpace(endStr))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid page range %q", pages)
+	}
+
+	if start < 1 || end < start {
+		return 0, 0, fmt.Errorf("invalid page range %q", pages)
+	}
+
+	return start, end, nil
+}
+
+func getPdfText(content []byte, pages string) (res string, err error) {
+	// the pdf package panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to parse pdf: %v", r)
+		}
+	}()
+
+	start, end, err := parsePdfPageRange(pages)
+	if err != nil {
+		return "", err
+	}
+
+	reader, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return "", fmt.Errorf("failed to read pdf: %v", err)
+	}
+
+	numPages := reader.NumPage()
+	if start == 0 {
+		start = 1
+		end = numPages
+	}
+	if start > numPages {
+		return "", fmt.Errorf("page range %s is out of bounds, pdf has %d pages", pages, numPages)
+	}
+	if end > numPages {
+		end = numPages
+	}
+
+	var builder strings.Builder
+	fonts := make(map[string]*pdf.Font)
+
+	for i := start; i <= end; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		// cache fonts so the charmaps aren't parsed again for every page
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return "", fmt.Errorf("failed to extract text from page %d: %v", i, err)
+		}
+
+		fmt.Fprintf(&builder, "--- page %d ---\n%s\n\n", i, strings.TrimSpace(text))
+	}
+
+	return builder.String(), nil
+}
@@ -0,0 +1,99 @@
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"plandex/api"
+	"plandex/types"
+	"strings"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// getPipedDataMaxTokens returns the limit for piped data: the per-file limit if one is set, otherwise the plan's context limit
+func getPipedDataMaxTokens(params *types.LoadContextParams) (int, error) {
+	if params.MaxTokensPerFile > 0 {
+		return params.MaxTokensPerFile, nil
+	}
+
+	settings, apiErr := api.Client.GetSettings(CurrentPlanId, CurrentBranch)
+	if apiErr != nil {
+		return 0, fmt.Errorf("failed to get plan settings: %v", apiErr.Msg)
+	}
+
+	return settings.GetPlannerEffectiveMaxTokens(), nil
+}
+
+type pipedLine struct {
+	text      string
+	numTokens int
+}
+
+// readPipedData reads piped data a line at a time, counting tokens as it goes, so a huge stream is never held in memory in full.
+// With --head or --head-tokens, reading stops once the first lines are collected or the next line wouldn't fit within maxTokens.
+// With --tail or --tail-tokens, only the last lines are kept, dropping the oldest while they exceed either limit.
+// Otherwise, it stops reading and returns an error as soon as maxTokens is crossed.
+func readPipedData(r io.Reader, params *types.LoadContextParams, maxTokens int) (string, error) {
+	counter, err := shared.NewTokenCounter()
+	if err != nil {
+		return "", err
+	}
+
+	isHead := params.Head > 0 || params.HeadTokens > 0
+	isTail := params.Tail > 0 || params.TailTokens > 0
+
+	tokenLimit := maxTokens
+	if params.HeadTokens > 0 && params.HeadTokens < tokenLimit {
+		tokenLimit = params.HeadTokens
+	}
+	if params.TailTokens > 0 && params.TailTokens < tokenLimit {
+		tokenLimit = params.TailTokens
+	}
+
+	reader := bufio.NewReader(r)
+
+	var lines []*pipedLine
+	numTokens := 0
+
+	for {
+		text, err := reader.ReadString('\n')
+		if text != "" {
+			line := &pipedLine{text: text, numTokens: counter.Count(text)}
+
+			if isHead && numTokens+line.numTokens > tokenLimit {
+				break
+			}
+
+			lines = append(lines, line)
+			numTokens += line.numTokens
+
+			if isHead {
+				if params.Head > 0 && len(lines) == params.Head {
+					break
+				}
+			} else if isTail {
+				for (params.Tail > 0 && len(lines) > params.Tail) || (numTokens > tokenLimit && len(lines) > 0) {
+					numTokens -= lines[0].numTokens
+					lines = lines[1:]
+				}
+			} else if numTokens > maxTokens {
+				return "", fmt.Errorf("piped data exceeds the limit of %d 🪙\nUse --head or --tail to keep only the beginning or end", maxTokens)
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read piped data: %v", err)
+		}
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line.text)
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,138 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"plandex/fs"
+	"plandex/types"
+	"regexp"
+	"strings"
+)
+
+// matches host/org/repo[@ref][:subdir] for the common git hosts
+var remoteRepoRegex = regexp.MustCompile(`^((?:github\.com|gitlab\.com|bitbucket\.org)/[\w.-]+/[\w.-]+?)(?:\.git)?(?:@([^:]+))?(?::(.+))?$`)
+
+type remoteRepo struct {
+	Path   string
+	Ref    string
+	Subdir string
+}
+
+func parseRemoteRepo(resource string) (*remoteRepo, bool) {
+	// a local path always takes precedence
+	if _, err := os.Stat(resource); err == nil {
+		return nil, false
+	}
+
+	matches := remoteRepoRegex.FindStringSubmatch(resource)
+	if matches == nil {
+		return nil, false
+	}
+
+	return &remoteRepo{
+		Path:   matches[1],
+		Ref:    matches[2],
+		Subdir: strings.Trim(matches[3], "/"),
+	}, true
+}
+
+func (r *remoteRepo) name() string {
+	name := r.Path
+	if r.Ref != "" {
+		name += "@" + r.Ref
+	}
+	return name
+}
+
+func (r *remoteRepo) cacheDir() string {
+	ref := r.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	return filepath.Join(fs.CacheDir, "repos", filepath.FromSlash(r.Path), strings.ReplaceAll(ref, "/", "_"))
+}
+
+// cloneRemoteRepo makes a shallow clone of the repo in the cache dir, or brings an existing clone up to date with the ref
+func cloneRemoteRepo(r *remoteRepo) (string, error) {
+	dir := r.cacheDir()
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		ref := r.Ref
+		if ref == "" {
+			ref = "HEAD"
+		}
+
+		res, err := exec.Command("git", "-C", dir, "fetch", "--depth", "1", "origin", ref).CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("error fetching %s: %v, output: %s", r.name(), err, string(res))
+		}
+
+		res, err = exec.Command("git", "-C", dir, "reset", "--hard", "FETCH_HEAD").CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("error resetting %s: %v, output: %s", r.name(), err, string(res))
+		}
+
+		return dir, nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(dir), os.ModePerm)
+	if err != nil {
+		return "", fmt.Errorf("error creating cache dir: %v", err)
+	}
+
+	args := []string{"clone", "--depth", "1"}
+	if r.Ref != "" {
+		args = append(args, "--branch", r.Ref)
+	}
+	args = append(args, "https://"+r.Path+".git", dir)
+
+	res, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("error cloning %s: %v, output: %s", r.name(), err, string(res))
+	}
+
+	return dir, nil
+}
+
+// getRemoteRepoFilePaths clones the repo and returns the absolute paths of the files to load, applying the repo's .gitignore and .plandexignore unless ignores are skipped
+func getRemoteRepoFilePaths(r *remoteRepo, params *types.LoadContextParams) ([]string, error) {
+	dir, err := cloneRemoteRepo(r)
+	if err != nil {
+		return nil, err
+	}
+
+	baseDir := filepath.Join(dir, filepath.FromSlash(r.Subdir))
+	if _, err := os.Stat(baseDir); err != nil {
+		return nil, fmt.Errorf("%s not found in %s", r.Subdir, r.name())
+	}
+
+	paths, err := fs.GetPaths(baseDir, dir)
+	if err != nil {
+		return nil, fmt.Errorf("error getting paths for %s: %v", r.name(), err)
+	}
+
+	candidates := paths.ActivePaths
+	if params.ForceSkipIgnore {
+		candidates = paths.AllPaths
+	}
+
+	var res []string
+	for path := range candidates {
+		absPath := filepath.Join(dir, path)
+
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("error checking %s: %v", path, err)
+		}
+		if info.IsDir() {
+			continue
+		}
+
+		res = append(res, absPath)
+	}
+
+	return res, nil
+}
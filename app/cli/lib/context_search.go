@@ -0,0 +1,370 @@
+package lib
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"plandex/api"
+	"plandex/fs"
+	"plandex/term"
+	"plandex/types"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/plandex/plandex/shared"
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	searchEmbeddingModel   = openai.SmallEmbedding3
+	searchChunkChars       = 6000
+	searchMaxChunksPerFile = 10
+	searchMaxFileBytes     = 200 * 1024
+	searchBatchSize        = 64
+)
+
+// the index maps project-relative paths to one embedding per chunk of the file, along with the sha of the content that was embedded
+type searchIndex struct {
+	Model string                      `json:"model"`
+	Files map[string]*searchIndexFile `json:"files"`
+}
+
+type searchIndexFile struct {
+	Sha    string      `json:"sha"`
+	Chunks [][]float32 `json:"chunks"`
+}
+
+type searchChunk struct {
+	path  string
+	index int
+	text  string
+}
+
+// SearchProject returns up to limit project files ranked by how similar their content is to the query.
+// The embeddings index in the .plandex dir is brought up to date first, so only new or changed files are embedded.
+func SearchProject(ctx context.Context, query string, limit int) ([]*types.ContextSearchResult, error) {
+	client := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+
+	index, err := updateSearchIndex(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	queryEmbeddings, err := getEmbeddings(ctx, client, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %v", err)
+	}
+	queryEmbedding := queryEmbeddings[0]
+
+	var results []*types.ContextSearchResult
+	for path, file := range index.Files {
+		var score float32
+		for _, chunk := range file.Chunks {
+			s := cosineSimilarity(queryEmbedding, chunk)
+			if s > score {
+				score = s
+			}
+		}
+
+		results = append(results, &types.ContextSearchResult{
+			Path:  path,
+			Score: score,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+func updateSearchIndex(ctx context.Context, client *openai.Client) (*searchIndex, error) {
+	index, err := loadSearchIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := fs.GetProjectPaths(fs.ProjectRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project paths: %v", err)
+	}
+
+	var toEmbed []*searchChunk
+	shas := map[string]string{}
+	changed := false
+
+	for path := range paths.ActivePaths {
+		content, ok := readSearchableFile(filepath.Join(fs.ProjectRoot, path))
+		if !ok {
+			continue
+		}
+
+		hash := sha256.Sum256(content)
+		sha := hex.EncodeToString(hash[:])
+		shas[path] = sha
+
+		if file, ok := index.Files[path]; ok && file.Sha == sha {
+			continue
+		}
+
+		for i, text := range chunkForSearch(path, string(content)) {
+			toEmbed = append(toEmbed, &searchChunk{path: path, index: i, text: text})
+		}
+	}
+
+	for path := range index.Files {
+		if _, ok := shas[path]; !ok {
+			delete(index.Files, path)
+			changed = true
+		}
+	}
+
+	chunksByPath := map[string][][]float32{}
+
+	for i := 0; i < len(toEmbed); i += searchBatchSize {
+		end := i + searchBatchSize
+		if end > len(toEmbed) {
+			end = len(toEmbed)
+		}
+		batch := toEmbed[i:end]
+
+		var inputs []string
+		for _, chunk := range batch {
+			inputs = append(inputs, chunk.text)
+		}
+
+		embeddings, err := getEmbeddings(ctx, client, inputs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed project files: %v", err)
+		}
+
+		for j, chunk := range batch {
+			chunks := chunksByPath[chunk.path]
+			for len(chunks) <= chunk.index {
+				chunks = append(chunks, nil)
+			}
+			chunks[chunk.index] = embeddings[j]
+			chunksByPath[chunk.path] = chunks
+		}
+	}
+
+	for path, chunks := range chunksByPath {
+		index.Files[path] = &searchIndexFile{
+			Sha:    shas[path],
+			Chunks: chunks,
+		}
+		changed = true
+	}
+
+	if changed {
+		err = saveSearchIndex(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return index, nil
+}
+
+func loadSearchIndex() (*searchIndex, error) {
+	index := &searchIndex{
+		Model: string(searchEmbeddingModel),
+		Files: map[string]*searchIndexFile{},
+	}
+
+	bytes, err := os.ReadFile(searchIndexPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, fmt.Errorf("failed to read search index: %v", err)
+	}
+
+	var existing searchIndex
+	err = json.Unmarshal(bytes, &existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal search index: %v", err)
+	}
+
+	// embeddings from a different model aren't comparable, so start over
+	if existing.Model != index.Model || existing.Files == nil {
+		return index, nil
+	}
+
+	return &existing, nil
+}
+
+func saveSearchIndex(index *searchIndex) error {
+	bytes, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("failed to marshal search index: %v", err)
+	}
+
+	err = os.WriteFile(searchIndexPath(), bytes, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write search index: %v", err)
+	}
+
+	return nil
+}
+
+func searchIndexPath() string {
+	return filepath.Join(fs.PlandexDir, "search-index.json")
+}
+
+// readSearchableFile returns the file's content if it's a text file small enough to index
+func readSearchableFile(path string) ([]byte, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Size() == 0 || info.Size() > searchMaxFileBytes {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	if isBinary(content) || shared.IsImageMimeType(http.DetectContentType(content)) || isPdf(content) {
+		return nil, false
+	}
+
+	return content, true
+}
+
+// chunkForSearch splits content on line boundaries into chunks that fit comfortably in the embedding model's input.
+// Each chunk is prefixed with the path since file names are often the strongest signal.
+func chunkForSearch(path, content string) []string {
+	var chunks []string
+	var current strings.Builder
+
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if current.Len()+len(line) > searchChunkChars && current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+
+			if len(chunks) == searchMaxChunksPerFile {
+				break
+			}
+		}
+
+		if len(line) > searchChunkChars {
+			line = line[:searchChunkChars]
+		}
+		current.WriteString(line)
+	}
+
+	if current.Len() > 0 && len(chunks) < searchMaxChunksPerFile {
+		chunks = append(chunks, current.String())
+	}
+
+	for i, chunk := range chunks {
+		chunks[i] = path + "\n\n" + chunk
+	}
+
+	return chunks
+}
+
+func getEmbeddings(ctx context.Context, client *openai.Client, inputs []string) ([][]float32, error) {
+	resp, err := client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input: inputs,
+		Model: searchEmbeddingModel,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Data) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(resp.Data))
+	}
+
+	embeddings := make([][]float32, len(inputs))
+	for _, data := range resp.Data {
+		embeddings[data.Index] = data.Embedding
+	}
+
+	return embeddings, nil
+}
+
+func cosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
+
+// files scoring below this are unlikely to be relevant enough to be worth their tokens
+const autoContextMinScore = 0.3
+const autoContextLimit = 5
+
+// MustAutoLoadContext loads the project files most relevant to the prompt that aren't already in context
+func MustAutoLoadContext(prompt string) {
+	term.StartSpinner("🔎 Finding relevant files...")
+
+	results, err := SearchProject(context.Background(), prompt, autoContextLimit)
+	if err != nil {
+		term.StopSpinner()
+		term.OutputErrorAndExit("Error searching project: %v", err)
+	}
+
+	contexts, apiErr := api.Client.ListContext(CurrentPlanId, CurrentBranch)
+	if apiErr != nil {
+		term.StopSpinner()
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error retrieving context: %v", apiErr.Msg)
+	}
+
+	loadedPaths := map[string]bool{}
+	for _, context := range contexts {
+		if context.FilePath != "" {
+			loadedPaths[filepath.Clean(context.FilePath)] = true
+		}
+	}
+
+	var paths []string
+	for _, result := range results {
+		if result.Score < autoContextMinScore || loadedPaths[filepath.Clean(result.Path)] {
+			continue
+		}
+		paths = append(paths, result.Path)
+	}
+
+	if len(paths) == 0 {
+		term.StopSpinner()
+		fmt.Println("🔎 No additional relevant files found")
+		fmt.Println()
+		return
+	}
+
+	term.StopSpinner()
+	fmt.Println("🔎 Loading relevant files:")
+	for _, path := range paths {
+		fmt.Println(color.New(color.FgWhite).Sprintf("  • %s", path))
+	}
+	fmt.Println()
+
+	MustLoadContext(paths, &types.LoadContextParams{})
+	fmt.Println()
+}
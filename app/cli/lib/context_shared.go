@@ -0,0 +1,118 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"plandex/api"
+	"plandex/term"
+	"plandex/types"
+	"strconv"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// MustLoadSharedContext loads contexts from another plan in the current project by reference.
+// Shared contexts aren't copied: they're always read from the source plan's branch, so updates there apply to every plan that shares them.
+// With no args, all of the source plan's context is shared.
+func MustLoadSharedContext(planNameOrIdx string, args []string) {
+	term.StartSpinner("📥 Loading context...")
+
+	plans, apiErr := api.Client.ListPlans([]string{CurrentProjectId})
+	if apiErr != nil {
+		term.OutputErrorAndExit("Error getting plans: %v", apiErr)
+	}
+
+	var source *shared.Plan
+	idx, err := strconv.Atoi(planNameOrIdx)
+	if err == nil {
+		if idx > 0 && idx <= len(plans) {
+			source = plans[idx-1]
+		}
+	} else {
+		for _, p := range plans {
+			if p.Name == planNameOrIdx {
+				source = p
+				break
+			}
+		}
+	}
+
+	if source == nil {
+		term.OutputErrorAndExit("Plan %s not found", planNameOrIdx)
+	}
+
+	if source.Id == CurrentPlanId {
+		term.OutputErrorAndExit("Can't load context from the current plan")
+	}
+
+	branches, err := GetCurrentBranchNamesByPlanId([]string{source.Id})
+	if err != nil {
+		term.OutputErrorAndExit("Error getting current branch of %s: %v", source.Name, err)
+	}
+	sourceBranch := branches[source.Id]
+	if sourceBranch == "" {
+		sourceBranch = "main"
+	}
+
+	contexts, apiErr := api.Client.ListContext(source.Id, sourceBranch)
+	if apiErr != nil {
+		term.OutputErrorAndExit("Error getting context for %s: %v", source.Name, apiErr)
+	}
+
+	var req shared.LoadContextRequest
+	for i, context := range contexts {
+		matched := len(args) == 0
+		for _, arg := range args {
+			matched, err = ContextMatchesArg(i, context, arg)
+			if err != nil {
+				term.OutputErrorAndExit("Error matching glob pattern: %v", err)
+			}
+			if matched {
+				break
+			}
+		}
+
+		if matched {
+			req = append(req, &shared.LoadContextParams{
+				ContextType:     context.ContextType,
+				Name:            context.Name,
+				SourcePlanId:    source.Id,
+				SourceBranch:    sourceBranch,
+				SourceContextId: context.Id,
+			})
+		}
+	}
+
+	if len(req) == 0 {
+		term.StopSpinner()
+		if term.JsonOutput {
+			term.OutputJson(types.LoadContextResult{})
+			os.Exit(0)
+		}
+		fmt.Printf("🤷‍♂️ No matching context in %s\n", source.Name)
+		os.Exit(0)
+	}
+
+	res, apiErr := api.Client.LoadContext(CurrentPlanId, CurrentBranch, req)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExit("Failed to load context: %v", apiErr)
+	}
+
+	if res.MaxTokensExceeded {
+		overage := res.TotalTokens - res.MaxTokens
+		term.OutputErrorAndExitWithCode(term.ExitTokenLimit, "Update would add %d 🪙 and exceed token limit (%d) by %d 🪙\n", res.TokensAdded, res.MaxTokens, overage)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(types.LoadContextResult{Res: res})
+		return
+	}
+
+	fmt.Println(term.Plain("✅ " + res.Msg))
+
+	if len(res.Unchanged) > 0 {
+		printUnchangedMsg(res.Unchanged)
+	}
+}
@@ -0,0 +1,385 @@
+package lib
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	specFormatProto   = "proto"
+	specFormatOpenApi = "openapi"
+)
+
+// getSpecFormat returns the spec format of a .proto file or an OpenAPI / Swagger document in yaml or json, or "" if the file isn't a spec
+func getSpecFormat(path string, content []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".proto":
+		return specFormatProto
+	case ".yaml", ".yml", ".json":
+		var doc map[string]any
+		if yaml.Unmarshal(content, &doc) != nil {
+			return ""
+		}
+		if _, ok := doc["openapi"]; ok {
+			return specFormatOpenApi
+		}
+		if _, ok := doc["swagger"]; ok {
+			return specFormatOpenApi
+		}
+	}
+	return ""
+}
+
+// summarizeSpec condenses a spec to what's needed to write code against it
+func summarizeSpec(format string, content []byte) (string, error) {
+	switch format {
+	case specFormatProto:
+		return summarizeProto(string(content)), nil
+	case specFormatOpenApi:
+		var doc map[string]any
+		err := yaml.Unmarshal(content, &doc)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse spec: %v", err)
+		}
+		return summarizeOpenApi(doc), nil
+	}
+	return "", fmt.Errorf("unsupported spec format %s", format)
+}
+
+var (
+	protoBlockCommentRegex = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	protoLineCommentRegex  = regexp.MustCompile(`//.*`)
+	protoFieldOptionsRegex = regexp.MustCompile(`\s*\[[^\]]*\]`)
+	protoEmptyBlockRegex   = regexp.MustCompile(`\{\n\s*\}`)
+)
+
+// summarizeProto strips comments, imports, options, and field options from a .proto file and re-indents what's left, keeping the package, messages, enums, and services
+func summarizeProto(content string) string {
+	content = protoBlockCommentRegex.ReplaceAllString(content, "")
+	content = protoLineCommentRegex.ReplaceAllString(content, "")
+
+	var b strings.Builder
+	depth := 0
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "import ") || strings.HasPrefix(line, "option ") {
+			continue
+		}
+
+		line = protoFieldOptionsRegex.ReplaceAllString(line, "")
+		line = strings.Join(strings.Fields(line), " ")
+
+		opens := strings.Count(line, "{")
+		closes := strings.Count(line, "}")
+
+		// a leading close brace is dedented along with the rest of its line
+		if strings.HasPrefix(line, "}") {
+			depth--
+			closes--
+		}
+		if depth < 0 {
+			depth = 0
+		}
+
+		b.WriteString(strings.Repeat("  ", depth) + line + "\n")
+
+		depth += opens - closes
+	}
+
+	// blocks that only held options, like rpc http annotations, are left empty
+	return strings.TrimSpace(protoEmptyBlockRegex.ReplaceAllString(b.String(), "{}"))
+}
+
+var openApiMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// summarizeOpenApi lists each endpoint with its parameters, request body, and responses, followed by each schema's properties
+func summarizeOpenApi(doc map[string]any) string {
+	var b strings.Builder
+
+	version := specString(doc, "openapi")
+	label := "OpenAPI"
+	if version == "" {
+		version = specString(doc, "swagger")
+		label = "Swagger"
+	}
+
+	info := specMap(doc, "info")
+	fmt.Fprintf(&b, "%s %s | %s %s\n", label, version, specString(info, "title"), specString(info, "version"))
+
+	var servers []string
+	for _, server := range specList(doc, "servers") {
+		if s, ok := server.(map[string]any); ok {
+			servers = append(servers, specString(s, "url"))
+		}
+	}
+	if host := specString(doc, "host"); host != "" {
+		servers = append(servers, host+specString(doc, "basePath"))
+	}
+	if len(servers) > 0 {
+		b.WriteString("Servers: " + strings.Join(servers, ", ") + "\n")
+	}
+
+	paths := specMap(doc, "paths")
+	var pathKeys []string
+	for path := range paths {
+		pathKeys = append(pathKeys, path)
+	}
+	sort.Strings(pathKeys)
+
+	if len(pathKeys) > 0 {
+		b.WriteString("\nEndpoints:\n")
+	}
+
+	for _, path := range pathKeys {
+		pathItem, _ := paths[path].(map[string]any)
+		pathParams := specList(pathItem, "parameters")
+
+		for _, method := range openApiMethods {
+			op, ok := pathItem[method].(map[string]any)
+			if !ok {
+				continue
+			}
+
+			line := strings.ToUpper(method) + " " + path
+			desc := specString(op, "summary")
+			if desc == "" {
+				desc = specString(op, "operationId")
+			}
+			if desc != "" {
+				line += " — " + desc
+			}
+			b.WriteString(line + "\n")
+
+			var params []string
+			var body string
+			opParams := append(append([]any{}, pathParams...), specList(op, "parameters")...)
+			for _, p := range opParams {
+				param, ok := p.(map[string]any)
+				if !ok {
+					continue
+				}
+				if ref := specString(param, "$ref"); ref != "" {
+					params = append(params, specRefName(ref))
+					continue
+				}
+
+				in := specString(param, "in")
+				// swagger 2 puts the request body in a parameter
+				if in == "body" {
+					body = specSchemaType(specMap(param, "schema"))
+					continue
+				}
+
+				paramType := specSchemaType(specMap(param, "schema"))
+				if t := specString(param, "type"); t != "" {
+					paramType = t
+				}
+
+				s := fmt.Sprintf("%s (%s, %s", specString(param, "name"), in, paramType)
+				if required, _ := param["required"].(bool); required {
+					s += ", required"
+				}
+				params = append(params, s+")")
+			}
+
+			if len(params) > 0 {
+				b.WriteString("  params: " + strings.Join(params, ", ") + "\n")
+			}
+
+			if requestBody := specMap(op, "requestBody"); requestBody != nil {
+				if ref := specString(requestBody, "$ref"); ref != "" {
+					body = specRefName(ref)
+				} else {
+					body = specContentType(specMap(requestBody, "content"))
+				}
+			}
+			if body != "" {
+				b.WriteString("  body: " + body + "\n")
+			}
+
+			responses := specMap(op, "responses")
+			var codes []string
+			for code := range responses {
+				codes = append(codes, code)
+			}
+			sort.Strings(codes)
+
+			var resps []string
+			for _, code := range codes {
+				resp, _ := responses[code].(map[string]any)
+				var t string
+				if ref := specString(resp, "$ref"); ref != "" {
+					t = specRefName(ref)
+				} else if schema := specMap(resp, "schema"); schema != nil {
+					t = specSchemaType(schema)
+				} else {
+					t = specContentType(specMap(resp, "content"))
+				}
+
+				if t == "" {
+					resps = append(resps, code)
+				} else {
+					resps = append(resps, code+" "+t)
+				}
+			}
+			if len(resps) > 0 {
+				b.WriteString("  responses: " + strings.Join(resps, ", ") + "\n")
+			}
+		}
+	}
+
+	schemas := specMap(specMap(doc, "components"), "schemas")
+	if schemas == nil {
+		schemas = specMap(doc, "definitions")
+	}
+
+	var schemaNames []string
+	for name := range schemas {
+		schemaNames = append(schemaNames, name)
+	}
+	sort.Strings(schemaNames)
+
+	if len(schemaNames) > 0 {
+		b.WriteString("\nSchemas:\n")
+	}
+
+	for _, name := range schemaNames {
+		schema, _ := schemas[name].(map[string]any)
+		props := specMap(schema, "properties")
+
+		if len(props) == 0 {
+			b.WriteString(name + ": " + specSchemaType(schema) + "\n")
+			continue
+		}
+
+		required := map[string]bool{}
+		for _, r := range specList(schema, "required") {
+			if s, ok := r.(string); ok {
+				required[s] = true
+			}
+		}
+
+		var propNames []string
+		for prop := range props {
+			propNames = append(propNames, prop)
+		}
+		sort.Strings(propNames)
+
+		var fields []string
+		for _, prop := range propNames {
+			propSchema, _ := props[prop].(map[string]any)
+			field := prop + " " + specSchemaType(propSchema)
+			if required[prop] {
+				field += " (required)"
+			}
+			fields = append(fields, field)
+		}
+
+		b.WriteString(name + ": " + strings.Join(fields, ", ") + "\n")
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+func specSchemaType(schema map[string]any) string {
+	if schema == nil {
+		return ""
+	}
+
+	if ref := specString(schema, "$ref"); ref != "" {
+		return specRefName(ref)
+	}
+
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		if list := specList(schema, key); len(list) > 0 {
+			sep := " | "
+			if key == "allOf" {
+				sep = " & "
+			}
+			var types []string
+			for _, item := range list {
+				if s, ok := item.(map[string]any); ok {
+					types = append(types, specSchemaType(s))
+				}
+			}
+			return strings.Join(types, sep)
+		}
+	}
+
+	t := specString(schema, "type")
+	switch t {
+	case "array":
+		return "[]" + specSchemaType(specMap(schema, "items"))
+	case "":
+		t = "object"
+	}
+
+	if enum := specList(schema, "enum"); len(enum) > 0 {
+		var values []string
+		for _, v := range enum {
+			values = append(values, fmt.Sprint(v))
+		}
+		return t + " enum(" + strings.Join(values, ", ") + ")"
+	}
+
+	if format := specString(schema, "format"); format != "" {
+		return t + "(" + format + ")"
+	}
+
+	return t
+}
+
+// specContentType returns the schema type of the first media type in an OpenAPI 3 content object, preferring json
+func specContentType(content map[string]any) string {
+	if content == nil {
+		return ""
+	}
+
+	var mediaTypes []string
+	for mediaType := range content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Slice(mediaTypes, func(i, j int) bool {
+		iJson := strings.Contains(mediaTypes[i], "json")
+		jJson := strings.Contains(mediaTypes[j], "json")
+		if iJson != jJson {
+			return iJson
+		}
+		return mediaTypes[i] < mediaTypes[j]
+	})
+
+	media, _ := content[mediaTypes[0]].(map[string]any)
+	t := specSchemaType(specMap(media, "schema"))
+	if t == "" {
+		return mediaTypes[0]
+	}
+	return mediaTypes[0] + " " + t
+}
+
+func specRefName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+func specMap(m map[string]any, key string) map[string]any {
+	v, _ := m[key].(map[string]any)
+	return v
+}
+
+func specList(m map[string]any, key string) []any {
+	v, _ := m[key].([]any)
+	return v
+}
+
+func specString(m map[string]any, key string) string {
+	v, ok := m[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
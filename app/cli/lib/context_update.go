@@ -10,6 +10,8 @@ import (
 	"plandex/term"
 	"plandex/types"
 	"plandex/url"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,6 +22,23 @@ import (
 )
 
 func MustCheckOutdatedContext(quiet bool, maybeContexts []*shared.Context) (contextOutdated, updated bool) {
+	return mustCheckOutdatedContext(quiet, false, maybeContexts)
+}
+
+// MustCheckOutdatedContextBeforePrompt is called before tell and continue. Outdated context is updated without confirmation when the plan's auto-update-context config is on.
+func MustCheckOutdatedContextBeforePrompt(maybeContexts []*shared.Context) (contextOutdated, updated bool) {
+	term.StartSpinner("")
+	settings, apiErr := api.Client.GetSettings(CurrentPlanId, CurrentBranch)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting plan config: %v", apiErr.Msg)
+	}
+
+	return mustCheckOutdatedContext(false, settings.AutoUpdateContext, maybeContexts)
+}
+
+func mustCheckOutdatedContext(quiet, autoUpdate bool, maybeContexts []*shared.Context) (contextOutdated, updated bool) {
 	if !quiet {
 		term.StartSpinner("🔬 Checking context...")
 	}
@@ -63,6 +82,22 @@ func MustCheckOutdatedContext(quiet bool, maybeContexts []*shared.Context) (cont
 		lbl = strconv.Itoa(outdatedRes.NumTrees) + " " + lbl
 		types = append(types, lbl)
 	}
+	if outdatedRes.NumMaps > 0 {
+		lbl := "code map"
+		if outdatedRes.NumMaps > 1 {
+			lbl = "code maps"
+		}
+		lbl = strconv.Itoa(outdatedRes.NumMaps) + " " + lbl
+		types = append(types, lbl)
+	}
+	if outdatedRes.NumSchemas > 0 {
+		lbl := "database schema"
+		if outdatedRes.NumSchemas > 1 {
+			lbl = "database schemas"
+		}
+		lbl = strconv.Itoa(outdatedRes.NumSchemas) + " " + lbl
+		types = append(types, lbl)
+	}
 
 	var msg string
 	if len(types) <= 2 {
@@ -83,11 +118,16 @@ func MustCheckOutdatedContext(quiet bool, maybeContexts []*shared.Context) (cont
 	}
 	color.New(term.ColorHiCyan, color.Bold).Printf("%s in context %s modified 👇\n\n", msg, phrase)
 
-	tableString := tableForContextOutdated(outdatedRes)
+	tableString := TableForContextOutdated(outdatedRes)
 	fmt.Println(tableString)
 
 	fmt.Println()
 
+	if autoUpdate {
+		MustUpdateContext(maybeContexts)
+		return true, true
+	}
+
 	var confirmed bool
 
 	confirmed, err = term.ConfirmYesNo("Update context now?")
@@ -142,6 +182,15 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 		contexts = maybeContexts
 	}
 
+	// shared contexts are always read from the plan they're shared from, so they're never outdated here
+	var ownContexts []*shared.Context
+	for _, context := range contexts {
+		if context.SourcePlanId == "" {
+			ownContexts = append(ownContexts, context)
+		}
+	}
+	contexts = ownContexts
+
 	var errs []error
 
 	req := shared.UpdateContextRequest{}
@@ -150,6 +199,8 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 	var numFiles int
 	var numUrls int
 	var numTrees int
+	var numMaps int
+	var numSchemas int
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 	contextsById := map[string]*shared.Context{}
@@ -158,7 +209,7 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 	var hasDirectoryTreeWithIgnoredPaths bool
 
 	for _, context := range contexts {
-		if context.ContextType == shared.ContextDirectoryTreeType && !context.ForceSkipIgnore {
+		if (context.ContextType == shared.ContextDirectoryTreeType || context.ContextType == shared.ContextMapType) && !context.ForceSkipIgnore {
 			hasDirectoryTreeWithIgnoredPaths = true
 			break
 		}
@@ -173,6 +224,16 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 		}
 	}
 
+	// parts of chunked files, by file, and the new parts of files that now split into a different number of parts
+	chunkPartsByFile := map[string][]*shared.Context{}
+	rechunkedFiles := map[string][]string{}
+	for _, context := range contexts {
+		if context.ContextType == shared.ContextFileType && context.ChunkPart > 0 {
+			key := chunkedFileKey(context)
+			chunkPartsByFile[key] = append(chunkPartsByFile[key], context)
+		}
+	}
+
 	for _, context := range contexts {
 		contextsById[context.Id] = context
 
@@ -189,12 +250,51 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 					return
 				}
 
-				hash := sha256.Sum256(fileContent)
+				body := string(fileContent)
+				if isPdf(fileContent) {
+					body, err = getPdfText(fileContent, context.PdfPages)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("failed to extract text from the pdf %s: %v", context.FilePath, err))
+						return
+					}
+				}
+
+				// a line range is re-extracted by line number, so it tracks the same span of the file after edits
+				if context.LineRange != "" {
+					body, err = extractLineRange(body, context.LineRange)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("failed to update %s: %v", context.Name, err))
+						return
+					}
+				}
+
+				// a part of a chunked file is re-chunked with the same limit. If the file now splits into a different number of
+				// parts, part numbers no longer line up, so the file's whole set of parts is replaced instead.
+				if context.ChunkPart > 0 {
+					chunks, err := shared.ChunkByBoundaries(body, context.ChunkMaxTokens)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("failed to split the file %s into parts: %v", context.FilePath, err))
+						return
+					}
+
+					_, numParts, ok := parseChunkName(context.Name)
+					if ok && numParts != len(chunks) {
+						rechunkedFiles[chunkedFileKey(context)] = chunks
+						return
+					}
+
+					body = ""
+					if context.ChunkPart <= len(chunks) {
+						body = chunks[context.ChunkPart-1]
+					}
+				}
+
+				body = redactContextBody(context, body)
+
+				hash := sha256.Sum256([]byte(body))
 				sha := hex.EncodeToString(hash[:])
 
 				if sha != context.Sha {
-					body := string(fileContent)
-
 					numTokens, err := shared.GetNumTokens(body)
 					if err != nil {
 						errs = append(errs, fmt.Errorf("failed to get the number of tokens in the file %s: %v", context.FilePath, err))
@@ -205,6 +305,52 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 					numFiles++
 					updatedContexts = append(updatedContexts, context)
 
+					params := &shared.UpdateContextParams{
+						Body: body,
+					}
+
+					// summarized files are re-summarized by the server on update
+					if context.Summarized {
+						params.ApiKey = os.Getenv("OPENAI_API_KEY")
+					}
+
+					req[context.Id] = params
+				}
+			}(context)
+
+		} else if context.ContextType == shared.ContextImageType {
+			wg.Add(1)
+			go func(context *shared.Context) {
+				defer wg.Done()
+				fileContent, err := os.ReadFile(context.FilePath)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to read the image %s: %v", context.FilePath, err))
+					return
+				}
+
+				body, err := shared.GetImageDataUrl(fileContent)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to encode the image %s: %v", context.FilePath, err))
+					return
+				}
+
+				hash := sha256.Sum256([]byte(body))
+				sha := hex.EncodeToString(hash[:])
+
+				if sha != context.Sha {
+					numTokens, err := shared.GetImageDataUrlNumTokens(body)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("failed to get the number of tokens in the image %s: %v", context.FilePath, err))
+						return
+					}
+					tokenDiffsById[context.Id] = numTokens - context.NumTokens
+
+					numFiles++
+					updatedContexts = append(updatedContexts, context)
+
 					req[context.Id] = &shared.UpdateContextParams{
 						Body: body,
 					}
@@ -218,6 +364,7 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 				flattenedPaths, err := ParseInputPaths([]string{context.FilePath}, &types.LoadContextParams{
 					NamesOnly:       true,
 					ForceSkipIgnore: context.ForceSkipIgnore,
+					MaxDepth:        context.MaxDepth,
 				})
 
 				mu.Lock()
@@ -243,7 +390,7 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 					flattenedPaths = filteredPaths
 				}
 
-				body := strings.Join(flattenedPaths, "\n")
+				body := redactContextBody(context, strings.Join(flattenedPaths, "\n"))
 				bytes := []byte(body)
 
 				hash := sha256.Sum256(bytes)
@@ -265,11 +412,137 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 				}
 			}(context)
 
+		} else if context.ContextType == shared.ContextMapType {
+			wg.Add(1)
+			go func(context *shared.Context) {
+				defer wg.Done()
+				body, _, err := getCodeMap(context.FilePath, context.ForceSkipIgnore, context.MaxDepth, paths)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to build the code map %s: %v", context.FilePath, err))
+					return
+				}
+
+				body = redactContextBody(context, body)
+
+				hash := sha256.Sum256([]byte(body))
+				sha := hex.EncodeToString(hash[:])
+
+				if sha != context.Sha {
+					numTokens, err := shared.GetNumTokens(body)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("failed to get the number of tokens in the code map %s: %v", context.FilePath, err))
+						return
+					}
+					tokenDiffsById[context.Id] = numTokens - context.NumTokens
+
+					numMaps++
+					updatedContexts = append(updatedContexts, context)
+					req[context.Id] = &shared.UpdateContextParams{
+						Body: body,
+					}
+				}
+			}(context)
+
+		} else if context.ContextType == shared.ContextSpecType {
+			wg.Add(1)
+			go func(context *shared.Context) {
+				defer wg.Done()
+				fileContent, err := os.ReadFile(context.FilePath)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to read the file %s: %v", context.FilePath, err))
+					return
+				}
+
+				body := string(fileContent)
+				if !context.Raw {
+					format := getSpecFormat(context.FilePath, fileContent)
+					if format == "" {
+						errs = append(errs, fmt.Errorf("%s is no longer a valid spec", context.FilePath))
+						return
+					}
+
+					body, err = summarizeSpec(format, fileContent)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("failed to summarize the spec %s: %v", context.FilePath, err))
+						return
+					}
+				}
+
+				body = redactContextBody(context, body)
+
+				hash := sha256.Sum256([]byte(body))
+				sha := hex.EncodeToString(hash[:])
+
+				if sha != context.Sha {
+					numTokens, err := shared.GetNumTokens(body)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("failed to get the number of tokens in the file %s: %v", context.FilePath, err))
+						return
+					}
+					tokenDiffsById[context.Id] = numTokens - context.NumTokens
+
+					numFiles++
+					updatedContexts = append(updatedContexts, context)
+					req[context.Id] = &shared.UpdateContextParams{
+						Body: body,
+					}
+				}
+			}(context)
+
+		} else if context.ContextType == shared.ContextDbSchemaType {
+			wg.Add(1)
+			go func(context *shared.Context) {
+				defer wg.Done()
+				body, err := getDbSchema(context.Url)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to get the database schema for %s (the password isn't stored, so set PGPASSWORD or use ~/.pgpass): %v", context.Name, err))
+					return
+				}
+
+				body = redactContextBody(context, body)
+
+				hash := sha256.Sum256([]byte(body))
+				sha := hex.EncodeToString(hash[:])
+
+				if sha != context.Sha {
+					numTokens, err := shared.GetNumTokens(body)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("failed to get the number of tokens in the database schema %s: %v", context.Name, err))
+						return
+					}
+					tokenDiffsById[context.Id] = numTokens - context.NumTokens
+
+					numSchemas++
+					updatedContexts = append(updatedContexts, context)
+					req[context.Id] = &shared.UpdateContextParams{
+						Body: body,
+					}
+				}
+			}(context)
+
 		} else if context.ContextType == shared.ContextURLType {
 			wg.Add(1)
 			go func(context *shared.Context) {
 				defer wg.Done()
-				body, err := url.FetchURLContent(context.Url)
+
+				var body string
+				var err error
+				if context.Rendered {
+					body, err = url.RenderURLContent(context.Url, context.Raw)
+				} else {
+					body, err = url.FetchURLContent(context.Url, context.Raw)
+				}
 
 				mu.Lock()
 				defer mu.Unlock()
@@ -279,6 +552,8 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 					return
 				}
 
+				body = redactContextBody(context, body)
+
 				hash := sha256.Sum256([]byte(body))
 				sha := hex.EncodeToString(hash[:])
 
@@ -307,10 +582,19 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 		return nil, fmt.Errorf("failed to check context outdated: %v", errs)
 	}
 
+	rechunkedParams, err := rechunkedFileParams(rechunkedFiles, chunkPartsByFile, tokenDiffsById)
+	if err != nil {
+		return nil, err
+	}
+	for key := range rechunkedFiles {
+		numFiles++
+		updatedContexts = append(updatedContexts, chunkPartsByFile[key]...)
+	}
+
 	var msg string
 	var hasConflicts bool
 
-	if len(req) == 0 {
+	if len(req) == 0 && len(rechunkedFiles) == 0 {
 		return &types.ContextOutdatedResult{
 			Msg: "Context is up to date",
 		}, nil
@@ -318,7 +602,8 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 		filesToLoad := map[string]string{}
 		for id := range req {
 			context := contextsById[id]
-			if context.ContextType == shared.ContextFileType {
+			// only whole files can be compared with pending changes
+			if context.ContextType == shared.ContextFileType && context.LineRange == "" && context.ChunkPart == 0 {
 				filesToLoad[context.FilePath] = context.Body
 			}
 		}
@@ -329,11 +614,24 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 			return nil, fmt.Errorf("failed to check context conflicts: %v", err)
 		}
 
-		res, apiErr := api.Client.UpdateContext(CurrentPlanId, CurrentBranch, req)
-		if apiErr != nil {
-			return nil, fmt.Errorf("failed to update context: %v", apiErr)
+		if len(req) > 0 {
+			res, apiErr := updateContextCached(CurrentPlanId, CurrentBranch, req)
+			if apiErr != nil {
+				return nil, fmt.Errorf("failed to update context: %v", apiErr)
+			}
+			msg = res.Msg
+		}
+
+		if len(rechunkedFiles) > 0 {
+			res, err := replaceChunkedFiles(rechunkedFiles, chunkPartsByFile, rechunkedParams)
+			if err != nil {
+				return nil, err
+			}
+			if msg != "" {
+				msg += "\n"
+			}
+			msg += res.Msg
 		}
-		msg = res.Msg
 	}
 
 	if hasConflicts {
@@ -354,10 +652,122 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 		NumFiles:        numFiles,
 		NumUrls:         numUrls,
 		NumTrees:        numTrees,
+		NumMaps:         numMaps,
+		NumSchemas:      numSchemas,
 	}, nil
 }
 
-func tableForContextOutdated(updateRes *types.ContextOutdatedResult) string {
+// chunkedFileKey identifies the parts that were split from the same file with the same limit
+func chunkedFileKey(context *shared.Context) string {
+	return context.FilePath + "|" + strconv.Itoa(context.ChunkMaxTokens)
+}
+
+var chunkNameRegex = regexp.MustCompile(`^(.*) \(part (\d+)/(\d+)\)$`)
+
+// parseChunkName returns the file's name and its number of parts from a part's name, as written by chunkName
+func parseChunkName(name string) (string, int, bool) {
+	matches := chunkNameRegex.FindStringSubmatch(name)
+	if matches == nil {
+		return "", 0, false
+	}
+
+	numParts, err := strconv.Atoi(matches[3])
+	if err != nil {
+		return "", 0, false
+	}
+
+	return matches[1], numParts, true
+}
+
+// rechunkedFileParams builds the new parts of each rechunked file, and sets the token diffs of its existing parts
+func rechunkedFileParams(rechunkedFiles map[string][]string, chunkPartsByFile map[string][]*shared.Context, tokenDiffsById map[string]int) (map[string][]*shared.LoadContextParams, error) {
+	res := map[string][]*shared.LoadContextParams{}
+
+	for key, chunks := range rechunkedFiles {
+		parts := chunkPartsByFile[key]
+		sort.Slice(parts, func(i, j int) bool {
+			return parts[i].ChunkPart < parts[j].ChunkPart
+		})
+		first := parts[0]
+
+		name, _, _ := parseChunkName(first.Name)
+
+		var params []*shared.LoadContextParams
+		var chunkTokens []int
+		for i, chunk := range chunks {
+			body := redactContextBody(first, chunk)
+
+			numTokens, err := shared.GetNumTokens(body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the number of tokens in the file %s: %v", first.FilePath, err)
+			}
+			chunkTokens = append(chunkTokens, numTokens)
+
+			params = append(params, &shared.LoadContextParams{
+				ContextType:    shared.ContextFileType,
+				Name:           chunkName(name, i+1, len(chunks)),
+				FilePath:       first.FilePath,
+				Body:           body,
+				PdfPages:       first.PdfPages,
+				NoRedact:       first.NoRedact,
+				ChunkPart:      i + 1,
+				ChunkMaxTokens: first.ChunkMaxTokens,
+			})
+		}
+		res[key] = params
+
+		for id, diff := range rechunkedTokenDiffs(parts, chunkTokens) {
+			tokenDiffsById[id] = diff
+		}
+	}
+
+	return res, nil
+}
+
+// rechunkedTokenDiffs compares each existing part, sorted by part number, with the new part of the same number. Parts
+// past the old count are counted on the last one, so the diffs add up to the file's change.
+func rechunkedTokenDiffs(parts []*shared.Context, chunkTokens []int) map[string]int {
+	diffs := map[string]int{}
+	for i, part := range parts {
+		diff := -part.NumTokens
+		if part.ChunkPart <= len(chunkTokens) {
+			diff += chunkTokens[part.ChunkPart-1]
+		}
+		if i == len(parts)-1 {
+			for n := part.ChunkPart; n < len(chunkTokens); n++ {
+				diff += chunkTokens[n]
+			}
+		}
+		diffs[part.Id] = diff
+	}
+	return diffs
+}
+
+// replaceChunkedFiles removes every part of each rechunked file, then loads its new parts
+func replaceChunkedFiles(rechunkedFiles map[string][]string, chunkPartsByFile map[string][]*shared.Context, rechunkedParams map[string][]*shared.LoadContextParams) (*shared.LoadContextResponse, error) {
+	ids := map[string]bool{}
+	var loadReq shared.LoadContextRequest
+	for key := range rechunkedFiles {
+		for _, part := range chunkPartsByFile[key] {
+			ids[part.Id] = true
+		}
+		loadReq = append(loadReq, rechunkedParams[key]...)
+	}
+
+	_, apiErr := api.Client.DeleteContext(CurrentPlanId, CurrentBranch, shared.DeleteContextRequest{Ids: ids})
+	if apiErr != nil {
+		return nil, fmt.Errorf("failed to remove the old parts of chunked files: %v", apiErr.Msg)
+	}
+
+	res, apiErr := loadContextCached(CurrentPlanId, CurrentBranch, loadReq)
+	if apiErr != nil {
+		return nil, fmt.Errorf("failed to load the new parts of chunked files: %v", apiErr.Msg)
+	}
+
+	return res, nil
+}
+
+func TableForContextOutdated(updateRes *types.ContextOutdatedResult) string {
 	updatedContexts := updateRes.UpdatedContexts
 	tokenDiffsById := updateRes.TokenDiffsById
 
@@ -388,11 +798,11 @@ func tableForContextOutdated(updateRes *types.ContextOutdatedResult) string {
 			diffStr,
 		}
 
-		table.Rich(row, []tablewriter.Colors{
+		table.Rich(row, term.TableColors([]tablewriter.Colors{
 			{tableColor, tablewriter.Bold},
 			{tableColor},
 			{tableColor},
-		})
+		}))
 	}
 
 	table.Render()
@@ -0,0 +1,37 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/plandex/plandex/shared"
+)
+
+func TestRechunkedTokenDiffs(t *testing.T) {
+	parts := []*shared.Context{
+		{Id: "part-1", NumTokens: 50, ChunkPart: 1},
+		{Id: "part-2", NumTokens: 30, ChunkPart: 2},
+	}
+
+	// grew from 2 parts to 3, so the new third part is counted on the last old part
+	diffs := rechunkedTokenDiffs(parts, []int{40, 45, 20})
+	if diffs["part-1"] != -10 || diffs["part-2"] != 35 {
+		t.Errorf("unexpected diffs when growing: %v", diffs)
+	}
+
+	// shrank to 1 part, so the second part is removed entirely
+	diffs = rechunkedTokenDiffs(parts, []int{60})
+	if diffs["part-1"] != 10 || diffs["part-2"] != -30 {
+		t.Errorf("unexpected diffs when shrinking: %v", diffs)
+	}
+}
+
+func TestParseChunkName(t *testing.T) {
+	name, numParts, ok := parseChunkName(chunkName("docs/notes (draft).md", 2, 12))
+	if !ok || name != "docs/notes (draft).md" || numParts != 12 {
+		t.Errorf("parseChunkName() = %q, %d, %v", name, numParts, ok)
+	}
+
+	if _, _, ok := parseChunkName("main.go"); ok {
+		t.Errorf("expected a name without a part to not parse")
+	}
+}
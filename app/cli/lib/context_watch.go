@@ -0,0 +1,151 @@
+package lib
+
+import (
+	"fmt"
+	"path/filepath"
+	"plandex/api"
+	"plandex/term"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/fsnotify/fsnotify"
+	"github.com/plandex/plandex/shared"
+)
+
+// editors often write a file in several steps (truncate, write, rename), so wait for things to settle before updating
+const watchDebounce = 500 * time.Millisecond
+
+func MustWatchContext() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		term.OutputErrorAndExit("failed to start file watcher: %v", err)
+	}
+	defer watcher.Close()
+
+	fileContexts, err := watchFileContexts(watcher, nil)
+	if err != nil {
+		term.OutputErrorAndExit("failed to watch context: %v", err)
+	}
+
+	if len(fileContexts) == 0 {
+		fmt.Println("🤷‍♂️ No files in context to watch")
+		return
+	}
+
+	printWatching(fileContexts)
+
+	var timer *time.Timer
+	updateCh := make(chan struct{}, 1)
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			if _, ok := fileContexts[filepath.Clean(event.Name)]; !ok {
+				continue
+			}
+
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(watchDebounce, func() {
+				select {
+				case updateCh <- struct{}{}:
+				default:
+				}
+			})
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			term.OutputErrorAndExit("file watcher error: %v", err)
+
+		case <-updateCh:
+			fileContexts, err = watchFileContexts(watcher, fileContexts)
+			if err != nil {
+				term.OutputErrorAndExit("failed to watch context: %v", err)
+			}
+
+			var contexts []*shared.Context
+			for _, context := range fileContexts {
+				contexts = append(contexts, context)
+			}
+
+			if len(contexts) == 0 {
+				continue
+			}
+
+			term.StartSpinner("🔄 Updating context...")
+			updateRes, err := UpdateContext(contexts)
+			term.StopSpinner()
+
+			if err != nil {
+				term.OutputErrorAndExit("Error updating context: %v", err)
+			}
+
+			if len(updateRes.UpdatedContexts) == 0 {
+				continue
+			}
+
+			fmt.Println(TableForContextOutdated(updateRes))
+			fmt.Println("✅ " + updateRes.Msg)
+			fmt.Println()
+		}
+	}
+}
+
+// watchFileContexts lists the plan's current file contexts and adds watches for any new ones.
+// Directories are watched rather than files so that changes made by atomic rename are picked up.
+func watchFileContexts(watcher *fsnotify.Watcher, prev map[string]*shared.Context) (map[string]*shared.Context, error) {
+	contexts, apiErr := api.Client.ListContext(CurrentPlanId, CurrentBranch)
+	if apiErr != nil {
+		return nil, fmt.Errorf("error retrieving context: %v", apiErr.Msg)
+	}
+
+	watchedDirs := map[string]bool{}
+	for path := range prev {
+		watchedDirs[filepath.Dir(path)] = true
+	}
+
+	fileContexts := map[string]*shared.Context{}
+	for _, context := range contexts {
+		// shared contexts are updated through the plan they're shared from
+		if context.ContextType != shared.ContextFileType || context.SourcePlanId != "" {
+			continue
+		}
+
+		path := filepath.Clean(context.FilePath)
+		fileContexts[path] = context
+
+		dir := filepath.Dir(path)
+		if watchedDirs[dir] {
+			continue
+		}
+
+		err := watcher.Add(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch %s: %v", dir, err)
+		}
+		watchedDirs[dir] = true
+	}
+
+	return fileContexts, nil
+}
+
+func printWatching(fileContexts map[string]*shared.Context) {
+	label := "file"
+	if len(fileContexts) > 1 {
+		label = "files"
+	}
+	fmt.Printf("👀 Watching %d %s in context for changes\n", len(fileContexts), label)
+	fmt.Println(color.New(color.FgWhite).Sprint("Press ctrl+c to stop"))
+	fmt.Println()
+}
@@ -0,0 +1,433 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"plandex/api"
+	"plandex/fs"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// bump when the index format or tokenizing changes so old indexes are rebuilt
+const convoSearchIndexVersion = 1
+
+const (
+	convoSearchSnippetChars = 80
+	convoSearchMaxTermLen   = 64
+)
+
+const (
+	ConvoSearchMessage = "message"
+	ConvoSearchContext = "context"
+)
+
+// the full-text index has the searchable documents of each plan in the project (its conversation messages and
+// context bodies) along with an inverted index from terms to documents. A plan is re-indexed when its current branch
+// changes or is updated.
+type convoSearchIndex struct {
+	Version int                                `json:"version"`
+	Plans   map[string]*convoSearchIndexedPlan `json:"plans"`
+}
+
+type convoSearchIndexedPlan struct {
+	Name      string            `json:"name"`
+	Branch    string            `json:"branch"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+	Docs      []*convoSearchDoc `json:"docs"`
+	// term -> indexes of the docs it appears in
+	Terms map[string][]int `json:"terms"`
+}
+
+type convoSearchDoc struct {
+	// ConvoSearchMessage or ConvoSearchContext
+	Kind string `json:"kind"`
+	// for messages
+	MessageNum int    `json:"messageNum,omitempty"`
+	Role       string `json:"role,omitempty"`
+	// for contexts
+	ContextName string             `json:"contextName,omitempty"`
+	ContextType shared.ContextType `json:"contextType,omitempty"`
+	Text        string             `json:"text"`
+	CreatedAt   time.Time          `json:"createdAt"`
+}
+
+type ConvoSearchResult struct {
+	PlanId      string             `json:"planId"`
+	Plan        string             `json:"plan"`
+	Branch      string             `json:"branch"`
+	Kind        string             `json:"kind"`
+	MessageNum  int                `json:"messageNum,omitempty"`
+	Role        string             `json:"role,omitempty"`
+	ContextName string             `json:"contextName,omitempty"`
+	ContextType shared.ContextType `json:"contextType,omitempty"`
+	Snippet     string             `json:"snippet"`
+	Score       int                `json:"score"`
+	CreatedAt   time.Time          `json:"createdAt"`
+}
+
+// SearchConvos returns the messages and contexts matching every term in the query across the project's plans, or
+// just the given plan if planId is set. Terms match words they're a prefix of, case-insensitively. The index in the
+// .plandex dir is brought up to date first, so only plans that changed since the last search are fetched again.
+func SearchConvos(query, planId string, limit int) ([]*ConvoSearchResult, error) {
+	terms := tokenizeForSearch(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("query has no searchable words")
+	}
+
+	index, err := updateConvoSearchIndex(planId)
+	if err != nil {
+		return nil, err
+	}
+
+	matchRe := ConvoSearchMatchRegexp(query)
+
+	var results []*ConvoSearchResult
+	for id, plan := range index.Plans {
+		if planId != "" && id != planId {
+			continue
+		}
+
+		for _, docIdx := range plan.match(terms) {
+			doc := plan.Docs[docIdx]
+			results = append(results, &ConvoSearchResult{
+				PlanId:      id,
+				Plan:        plan.Name,
+				Branch:      plan.Branch,
+				Kind:        doc.Kind,
+				MessageNum:  doc.MessageNum,
+				Role:        doc.Role,
+				ContextName: doc.ContextName,
+				ContextType: doc.ContextType,
+				Snippet:     searchSnippet(doc.Text, matchRe),
+				Score:       len(matchRe.FindAllStringIndex(doc.Text, -1)),
+				CreatedAt:   doc.CreatedAt,
+			})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].CreatedAt.After(results[j].CreatedAt)
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// ConvoSearchMatchRegexp matches the query's words anywhere in text, for finding and highlighting snippets
+func ConvoSearchMatchRegexp(query string) *regexp.Regexp {
+	var quoted []string
+	for _, term := range tokenizeForSearch(query) {
+		quoted = append(quoted, regexp.QuoteMeta(term))
+	}
+	return regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+}
+
+// match returns the docs that have every term, each as a prefix of one of the doc's terms
+func (plan *convoSearchIndexedPlan) match(terms []string) []int {
+	var matched map[int]bool
+
+	for _, term := range terms {
+		docs := map[int]bool{}
+		for indexed, postings := range plan.Terms {
+			if !strings.HasPrefix(indexed, term) {
+				continue
+			}
+			for _, docIdx := range postings {
+				if matched == nil || matched[docIdx] {
+					docs[docIdx] = true
+				}
+			}
+		}
+
+		if len(docs) == 0 {
+			return nil
+		}
+		matched = docs
+	}
+
+	res := make([]int, 0, len(matched))
+	for docIdx := range matched {
+		res = append(res, docIdx)
+	}
+	sort.Ints(res)
+	return res
+}
+
+func updateConvoSearchIndex(planId string) (*convoSearchIndex, error) {
+	index, err := loadConvoSearchIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	plans, apiErr := api.Client.ListPlans([]string{CurrentProjectId})
+	if apiErr != nil {
+		return nil, fmt.Errorf("error getting plans: %v", apiErr.Msg)
+	}
+
+	var planIds []string
+	plansById := map[string]*shared.Plan{}
+	for _, plan := range plans {
+		plansById[plan.Id] = plan
+		if planId == "" || plan.Id == planId {
+			planIds = append(planIds, plan.Id)
+		}
+	}
+
+	changed := false
+
+	// drop plans that were deleted or archived
+	for id := range index.Plans {
+		if plansById[id] == nil {
+			delete(index.Plans, id)
+			changed = true
+		}
+	}
+
+	if len(planIds) == 0 {
+		if changed {
+			return index, saveConvoSearchIndex(index)
+		}
+		return index, nil
+	}
+
+	branchNamesByPlanId, err := GetCurrentBranchNamesByPlanId(planIds)
+	if err != nil {
+		return nil, fmt.Errorf("error getting current branches: %v", err)
+	}
+
+	branchesByPlanId, apiErr := api.Client.GetCurrentBranchByPlanId(CurrentProjectId, shared.GetCurrentBranchByPlanIdRequest{
+		CurrentBranchByPlanId: branchNamesByPlanId,
+	})
+	if apiErr != nil {
+		return nil, fmt.Errorf("error getting current branches: %v", apiErr.Msg)
+	}
+
+	for _, id := range planIds {
+		branch := branchesByPlanId[id]
+		if branch == nil {
+			continue
+		}
+
+		existing := index.Plans[id]
+		if existing != nil && existing.Branch == branch.Name && existing.UpdatedAt.Equal(branch.UpdatedAt) {
+			existing.Name = plansById[id].Name
+			continue
+		}
+
+		indexed, err := indexPlanForSearch(id, branch)
+		if err != nil {
+			return nil, err
+		}
+		indexed.Name = plansById[id].Name
+
+		index.Plans[id] = indexed
+		changed = true
+	}
+
+	if changed {
+		err = saveConvoSearchIndex(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return index, nil
+}
+
+func indexPlanForSearch(planId string, branch *shared.Branch) (*convoSearchIndexedPlan, error) {
+	convo, apiErr := api.Client.ListConvo(planId, branch.Name)
+	if apiErr != nil {
+		return nil, fmt.Errorf("error loading conversation: %v", apiErr.Msg)
+	}
+
+	// context bodies aren't included when listing context, so they come from the plan's archive
+	archive, apiErr := api.Client.ExportPlan(planId, branch.Name)
+	if apiErr != nil {
+		return nil, fmt.Errorf("error loading context: %v", apiErr.Msg)
+	}
+
+	plan := &convoSearchIndexedPlan{
+		Branch:    branch.Name,
+		UpdatedAt: branch.UpdatedAt,
+		Terms:     map[string][]int{},
+	}
+
+	for _, msg := range convo {
+		plan.addDoc(&convoSearchDoc{
+			Kind:       ConvoSearchMessage,
+			MessageNum: msg.Num,
+			Role:       msg.Role,
+			Text:       msg.Message,
+			CreatedAt:  msg.CreatedAt,
+		})
+	}
+
+	var archivePaths []string
+	for archivePath := range archive.Files {
+		archivePaths = append(archivePaths, archivePath)
+	}
+	sort.Strings(archivePaths)
+
+	for _, archivePath := range archivePaths {
+		if path.Dir(archivePath) != "context" || path.Ext(archivePath) != ".meta" {
+			continue
+		}
+
+		var meta struct {
+			ContextType shared.ContextType `json:"contextType"`
+			Name        string             `json:"name"`
+			FilePath    string             `json:"filePath"`
+			Url         string             `json:"url"`
+			CreatedAt   time.Time          `json:"createdAt"`
+		}
+		err := json.Unmarshal([]byte(archive.Files[archivePath]), &meta)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", archivePath, err)
+		}
+
+		if meta.ContextType == shared.ContextImageType {
+			continue
+		}
+
+		name := meta.Name
+		if meta.FilePath != "" {
+			name = meta.FilePath
+		} else if meta.Url != "" {
+			name = meta.Url
+		}
+
+		plan.addDoc(&convoSearchDoc{
+			Kind:        ConvoSearchContext,
+			ContextName: name,
+			ContextType: meta.ContextType,
+			Text:        archive.Files[strings.TrimSuffix(archivePath, ".meta")+".body"],
+			CreatedAt:   meta.CreatedAt,
+		})
+	}
+
+	return plan, nil
+}
+
+func (plan *convoSearchIndexedPlan) addDoc(doc *convoSearchDoc) {
+	docIdx := len(plan.Docs)
+	plan.Docs = append(plan.Docs, doc)
+
+	seen := map[string]bool{}
+	for _, term := range tokenizeForSearch(doc.ContextName + "\n" + doc.Text) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		plan.Terms[term] = append(plan.Terms[term], docIdx)
+	}
+}
+
+// tokenizeForSearch splits text into lowercase words of letters and digits
+func tokenizeForSearch(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	res := words[:0]
+	for _, word := range words {
+		if len(word) <= convoSearchMaxTermLen {
+			res = append(res, word)
+		}
+	}
+	return res
+}
+
+// searchSnippet is the text around the first match, on one line
+func searchSnippet(text string, matchRe *regexp.Regexp) string {
+	loc := matchRe.FindStringIndex(text)
+	start, end := 0, len(text)
+	if loc != nil {
+		start = loc[0] - convoSearchSnippetChars
+		end = loc[1] + convoSearchSnippetChars
+	} else {
+		end = 2 * convoSearchSnippetChars
+	}
+
+	if start < 0 {
+		start = 0
+	}
+	if end > len(text) {
+		end = len(text)
+	}
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	snippet := strings.Join(strings.Fields(text[start:end]), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+func loadConvoSearchIndex() (*convoSearchIndex, error) {
+	index := &convoSearchIndex{
+		Version: convoSearchIndexVersion,
+		Plans:   map[string]*convoSearchIndexedPlan{},
+	}
+
+	bytes, err := os.ReadFile(convoSearchIndexPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, fmt.Errorf("failed to read conversation search index: %v", err)
+	}
+
+	var existing convoSearchIndex
+	err = json.Unmarshal(bytes, &existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal conversation search index: %v", err)
+	}
+
+	if existing.Version != index.Version || existing.Plans == nil {
+		return index, nil
+	}
+
+	return &existing, nil
+}
+
+func saveConvoSearchIndex(index *convoSearchIndex) error {
+	bytes, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation search index: %v", err)
+	}
+
+	err = os.WriteFile(convoSearchIndexPath(), bytes, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write conversation search index: %v", err)
+	}
+
+	return nil
+}
+
+func convoSearchIndexPath() string {
+	return filepath.Join(fs.PlandexDir, "convo-search-index.json")
+}
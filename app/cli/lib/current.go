@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"plandex/api"
 	"plandex/fs"
+	"plandex/logging"
 	"plandex/term"
 	"plandex/types"
 
@@ -91,6 +92,8 @@ func resolveProject(mustResolve, shouldCreate bool) {
 	}
 
 	MustLoadCurrentPlan()
+
+	autoSyncQueue()
 }
 
 func MustLoadCurrentPlan() {
@@ -135,6 +138,8 @@ func MustLoadCurrentPlan() {
 				term.OutputErrorAndExit("error setting current branch: %v", err)
 			}
 		}
+
+		logging.SetPlan(CurrentPlanId, CurrentBranch)
 	}
 }
 
@@ -176,7 +181,7 @@ func mustInitProject() {
 	res, apiErr := api.Client.CreateProject(shared.CreateProjectRequest{Name: filepath.Base(fs.ProjectRoot)})
 
 	if apiErr != nil {
-		term.OutputErrorAndExit("error creating project: %v", apiErr.Msg)
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "error creating project: %v", apiErr.Msg)
 	}
 
 	log.Println("Project created:", res.Id)
@@ -0,0 +1,146 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plandex/fs"
+	"plandex/term"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type TraceFile struct {
+	// relative to the project root
+	Path string
+	// the first line the output mentions, or 0 if it doesn't mention one
+	Line int
+}
+
+var (
+	// python: File "app/main.py", line 12
+	pythonTracePattern = regexp.MustCompile(`File "([^"]+)", line (\d+)`)
+	// java: at com.foo.Bar.baz(Bar.java:12)
+	javaTracePattern = regexp.MustCompile(`\(([A-Za-z0-9_$]+\.(?:java|kt|scala)):(\d+)\)`)
+	// most other languages and tools: path/to/file.go:12, ./src/app.ts:12:5, at fn (/abs/path/app.js:12:5), --> src/main.rs:12:5
+	pathLineTracePattern = regexp.MustCompile(`((?:[A-Za-z]:)?[A-Za-z0-9_\-./\\@]*[A-Za-z0-9_\-@]\.[A-Za-z0-9]+):(\d+)`)
+)
+
+// RunDebugCommand runs a command with the shell from the project root, showing its output and also returning it
+func RunDebugCommand(command string) (string, int) {
+	fmt.Println()
+	color.New(color.Bold, term.ColorHiCyan).Printf("🐞 Running %s\n", command)
+
+	output, exitCode, err := runHook(command)
+	if err != nil {
+		term.OutputErrorAndExit("Error running %s: %v", command, err)
+	}
+
+	return output, exitCode
+}
+
+// MustLoadDebugFailure loads the output of a failed command into context
+func MustLoadDebugFailure(command, output string, exitCode int) {
+	mustLoadCommandOutput(command, output, exitCode, "when run from the project root")
+}
+
+// FindTraceFiles returns the project files mentioned in a command's output, like in stack traces and compiler errors,
+// in the order they first appear, up to limit. Files outside the project or that it ignores are skipped.
+func FindTraceFiles(output string, limit int) ([]*TraceFile, error) {
+	paths, err := fs.GetProjectPaths(fs.ProjectRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project paths: %v", err)
+	}
+
+	// for traces that only include the file's name, like java's
+	pathsByBase := map[string][]string{}
+	for path := range paths.ActivePaths {
+		base := filepath.Base(path)
+		pathsByBase[base] = append(pathsByBase[base], path)
+	}
+
+	type match struct {
+		pos  int
+		path string
+		line int
+	}
+	var matches []*match
+
+	for _, pattern := range []*regexp.Regexp{pythonTracePattern, javaTracePattern, pathLineTracePattern} {
+		for _, m := range pattern.FindAllStringSubmatchIndex(output, -1) {
+			line, _ := strconv.Atoi(output[m[4]:m[5]])
+			matches = append(matches, &match{pos: m[0], path: output[m[2]:m[3]], line: line})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].pos < matches[j].pos
+	})
+
+	var res []*TraceFile
+	seen := map[string]bool{}
+
+	for _, m := range matches {
+		if len(res) >= limit {
+			break
+		}
+
+		path := resolveTracePath(m.path, paths.ActivePaths, pathsByBase)
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		res = append(res, &TraceFile{Path: path, Line: m.line})
+	}
+
+	return res, nil
+}
+
+// resolveTracePath returns the project path for a path in a trace, or "" if it isn't a project file
+func resolveTracePath(tracePath string, activePaths map[string]bool, pathsByBase map[string][]string) string {
+	tracePath = strings.TrimPrefix(filepath.FromSlash(tracePath), "."+string(os.PathSeparator))
+
+	candidates := []string{tracePath}
+	if filepath.IsAbs(tracePath) {
+		rel, err := filepath.Rel(fs.ProjectRoot, tracePath)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return ""
+		}
+		candidates = []string{rel}
+	} else if cwdRel, err := filepath.Rel(fs.ProjectRoot, filepath.Join(fs.Cwd, tracePath)); err == nil {
+		candidates = append(candidates, cwdRel)
+	}
+
+	for _, candidate := range candidates {
+		candidate = filepath.Clean(candidate)
+		if activePaths[candidate] {
+			return candidate
+		}
+	}
+
+	// a bare file name is only resolved if it's unambiguous
+	if !strings.ContainsRune(tracePath, os.PathSeparator) {
+		if matches := pathsByBase[tracePath]; len(matches) == 1 {
+			return matches[0]
+		}
+	}
+
+	return ""
+}
+
+// ShellJoin joins a command's args into a string for the shell, quoting the ones that need it
+func ShellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./=:,+@%") == "" {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
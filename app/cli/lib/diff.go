@@ -0,0 +1,264 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+const diffContextLines = 3
+
+type diffOp struct {
+	// ' ' for an unchanged line, '-' for a removed line, '+' for an added line
+	kind byte
+	line string
+}
+
+// UnifiedDiff returns a unified diff between two versions of a file, or "" if they're the same.
+// The original is nil for a new file.
+func UnifiedDiff(path string, original *string, updated string) string {
+	var before string
+	fromPath := "a/" + path
+	if original == nil {
+		fromPath = "/dev/null"
+	} else {
+		before = *original
+	}
+
+	if original != nil && before == updated {
+		return ""
+	}
+
+	ops := diffLines(splitLines(before), splitLines(updated))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ b/%s\n", fromPath, path)
+	for _, hunk := range diffHunks(ops) {
+		b.WriteString(hunk.String())
+	}
+	return b.String()
+}
+
+// ColorizeDiff colors a unified diff's headers, hunk ranges, and changed lines for the terminal
+func ColorizeDiff(diff string) string {
+	lines := strings.SplitAfter(diff, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ "):
+			lines[i] = color.New(color.Bold).Sprint(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = color.New(color.FgCyan).Sprint(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = color.New(color.FgRed).Sprint(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = color.New(color.FgGreen).Sprint(line)
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// splitLines keeps each line's trailing newline so a missing newline at the end of a file shows up as a change
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines finds the shortest edit script from a to b with Myers' algorithm
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+
+	// trace[d] holds v for diagonals -d..d before step d, which is all the backtrack needs
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int{}, v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace)
+			}
+		}
+	}
+
+	return nil
+}
+
+func backtrackDiff(a, b []string, trace [][]int) []diffOp {
+	x, y := len(a), len(b)
+	var ops []diffOp
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{'+', b[prevY]})
+			} else {
+				ops = append(ops, diffOp{'-', a[prevX]})
+			}
+		}
+
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+
+	return ops
+}
+
+// diffHunk is a group of nearby changes with the unchanged lines around them
+type diffHunk struct {
+	// the range of the hunk's lines in the original and updated files, as 0-based start and count
+	aStart, aCount int
+	bStart, bCount int
+	ops            []diffOp
+}
+
+func (h *diffHunk) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(h.aStart, h.aCount), hunkRange(h.bStart, h.bCount))
+	for _, op := range h.ops {
+		b.WriteByte(op.kind)
+		b.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+	return b.String()
+}
+
+// side returns the hunk's text as it reads in the original file, or in the updated file if updated is true
+func (h *diffHunk) side(updated bool) string {
+	skip := byte('+')
+	if updated {
+		skip = '-'
+	}
+
+	var b strings.Builder
+	for _, op := range h.ops {
+		if op.kind != skip {
+			b.WriteString(op.line)
+		}
+	}
+	return b.String()
+}
+
+// diffHunks groups changes with diffContextLines of unchanged lines around them, merging changes that are close together
+func diffHunks(ops []diffOp) []*diffHunk {
+	// line numbers in a and b before each op
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		if op.kind != '+' {
+			aPos[i+1]++
+		}
+		if op.kind != '-' {
+			bPos[i+1]++
+		}
+	}
+
+	var hunks []*diffHunk
+
+	i := 0
+	for i < len(ops) {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		start := max(0, i-diffContextLines)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			} else if j-end > 2*diffContextLines {
+				break
+			}
+		}
+		stop := min(len(ops), end+diffContextLines+1)
+
+		hunks = append(hunks, &diffHunk{
+			aStart: aPos[start],
+			aCount: aPos[stop] - aPos[start],
+			bStart: bPos[start],
+			bCount: bPos[stop] - bPos[start],
+			ops:    ops[start:stop],
+		})
+
+		i = stop
+	}
+
+	return hunks
+}
+
+// applyHunks returns the original lines with only the accepted hunks applied
+func applyHunks(original []string, hunks []*diffHunk, accepted []bool) string {
+	var b strings.Builder
+	cursor := 0
+	for i, hunk := range hunks {
+		for _, line := range original[cursor:hunk.aStart] {
+			b.WriteString(line)
+		}
+		b.WriteString(hunk.side(accepted[i]))
+		cursor = hunk.aStart + hunk.aCount
+	}
+	for _, line := range original[cursor:] {
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+func hunkRange(start, count int) string {
+	// an empty range is numbered by the line before it
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
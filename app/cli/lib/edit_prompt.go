@@ -0,0 +1,42 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// GetPromptRewindSha finds the state of the plan from just before the prompt with the given message number was
+// sent, so the plan can be rewound to it and the prompt sent again
+func GetPromptRewindSha(logs *shared.LogResponse, messageNum int) (string, error) {
+	commitMsg := fmt.Sprintf("Message #%d | 💬 User prompt", messageNum)
+
+	var sha string
+	found := false
+	for _, line := range strings.Split(ansiRegex.ReplaceAllString(logs.Body, ""), "\n") {
+		if m := logEntryHeaderRegex.FindStringSubmatch(line); m != nil {
+			sha = m[1]
+			continue
+		}
+		if sha != "" && strings.HasPrefix(strings.TrimSpace(line), commitMsg) {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return "", fmt.Errorf("message #%d isn't in the plan's history", messageNum)
+	}
+
+	for i, s := range logs.Shas {
+		if s == sha {
+			if i+1 >= len(logs.Shas) {
+				return "", fmt.Errorf("there's no earlier state to rewind to")
+			}
+			return logs.Shas[i+1], nil
+		}
+	}
+
+	return "", fmt.Errorf("couldn't find update %s in the plan's history", sha)
+}
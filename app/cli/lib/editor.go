@@ -0,0 +1,61 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+const defaultEditor = "vim"
+
+// const defaultEditor = "nano"
+
+// GetEditor returns the editor from the config, or else from $EDITOR or $VISUAL, falling back to vim
+func GetEditor() string {
+	editor := MustLoadConfig().Editor
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = os.Getenv("VISUAL")
+		if editor == "" {
+			editor = defaultEditor
+		}
+	}
+	return editor
+}
+
+// EditInEditor opens content in the user's editor and returns what's there once the editor is closed. The temp file
+// gets the extension of path so the editor can highlight it.
+func EditInEditor(content, path string) (string, error) {
+	tempFile, err := os.CreateTemp(os.TempDir(), "plandex_edit_*"+filepath.Ext(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	filename := tempFile.Name()
+	tempFile.Close()
+	defer os.Remove(filename)
+
+	err = os.WriteFile(filename, []byte(content), 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to write temporary file: %v", err)
+	}
+
+	cmd := exec.Command(GetEditor(), filename)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("failed to run editor: %v", err)
+	}
+
+	bytes, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read temporary file: %v", err)
+	}
+
+	return string(bytes), nil
+}
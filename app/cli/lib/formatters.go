@@ -0,0 +1,84 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"plandex/fs"
+	"plandex/types"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const formatterTimeout = 30 * time.Second
+
+// formatterFor returns the config's formatter command for a path's extension, or "" if there isn't one
+func formatterFor(config *types.PlandexConfig, path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return ""
+	}
+
+	if cmd, ok := config.Formatters[strings.TrimPrefix(ext, ".")]; ok {
+		return cmd
+	}
+	// also allow keys written with the dot in config.yml
+	return config.Formatters[ext]
+}
+
+// formatFileContent runs a file's content through the config's formatter for its extension. The formatter reads the
+// content on stdin and writes the formatted content to stdout, and {path} in the command is replaced with the file's
+// path relative to the project root. Content is returned unchanged if no formatter is set.
+func formatFileContent(config *types.PlandexConfig, path, content string) (string, error) {
+	formatter := formatterFor(config, path)
+	if formatter == "" {
+		return content, nil
+	}
+
+	formatter = strings.ReplaceAll(formatter, "{path}", shellQuote(path))
+
+	ctx, cancel := context.WithTimeout(context.Background(), formatterTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", formatter)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", formatter)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Dir = fs.ProjectRoot
+	cmd.Stdin = strings.NewReader(content)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("%s timed out after %s", formatter, formatterTimeout)
+	}
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("%s failed: %s", formatter, msg)
+	}
+
+	// a formatter that prints nothing for non-empty input almost certainly wrote to a file instead of stdout
+	if stdout.Len() == 0 && strings.TrimSpace(content) != "" {
+		return "", fmt.Errorf("%s didn't write the formatted content to stdout", formatter)
+	}
+
+	return stdout.String(), nil
+}
+
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + s + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
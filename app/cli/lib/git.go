@@ -3,6 +3,7 @@ package lib
 import (
 	"fmt"
 	"log"
+	"os"
 	"os/exec"
 	"strings"
 	"sync"
@@ -10,6 +11,13 @@ import (
 
 var gitMutex sync.Mutex
 
+type GitCommitOpts struct {
+	// skip pre-commit and commit-msg hooks
+	NoVerify bool
+	// open git's editor on the message before committing
+	Edit bool
+}
+
 func GitAddAndCommit(dir, message string, lockMutex bool) error {
 	if lockMutex {
 		gitMutex.Lock()
@@ -21,7 +29,7 @@ func GitAddAndCommit(dir, message string, lockMutex bool) error {
 		return fmt.Errorf("error adding files to git repository for dir: %s, err: %v", dir, err)
 	}
 
-	err = GitCommit(dir, message, nil, false)
+	err = GitCommit(dir, message, nil, GitCommitOpts{}, false)
 	if err != nil {
 		return fmt.Errorf("error committing files to git repository for dir: %s, err: %v", dir, err)
 	}
@@ -29,7 +37,7 @@ func GitAddAndCommit(dir, message string, lockMutex bool) error {
 	return nil
 }
 
-func GitAddAndCommitPaths(dir, message string, paths []string, lockMutex bool) error {
+func GitAddAndCommitPaths(dir, message string, paths []string, opts GitCommitOpts, lockMutex bool) error {
 	if len(paths) == 0 {
 		return nil
 	}
@@ -46,7 +54,7 @@ func GitAddAndCommitPaths(dir, message string, paths []string, lockMutex bool) e
 		}
 	}
 
-	err := GitCommit(dir, message, paths, false)
+	err := GitCommit(dir, message, paths, opts, false)
 	if err != nil {
 		return fmt.Errorf("error committing files to git repository for dir: %s, err: %v", dir, err)
 	}
@@ -68,7 +76,7 @@ func GitAdd(repoDir, path string, lockMutex bool) error {
 	return nil
 }
 
-func GitCommit(repoDir, commitMsg string, paths []string, lockMutex bool) error {
+func GitCommit(repoDir, commitMsg string, paths []string, opts GitCommitOpts, lockMutex bool) error {
 	if lockMutex {
 		gitMutex.Lock()
 		defer gitMutex.Unlock()
@@ -76,10 +84,32 @@ func GitCommit(repoDir, commitMsg string, paths []string, lockMutex bool) error
 
 	args := []string{"-C", repoDir, "commit", "-m", commitMsg, "--allow-empty"}
 
+	if opts.NoVerify {
+		args = append(args, "--no-verify")
+	}
+
+	if opts.Edit {
+		args = append(args, "--edit")
+	}
+
 	if len(paths) > 0 {
+		args = append(args, "--")
 		args = append(args, paths...)
 	}
 
+	if opts.Edit {
+		// the editor needs the terminal
+		cmd := exec.Command("git", args...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err := cmd.Run()
+		if err != nil {
+			return fmt.Errorf("error committing files to git repository for dir: %s, err: %v", repoDir, err)
+		}
+		return nil
+	}
+
 	res, err := exec.Command("git", args...).CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("error committing files to git repository for dir: %s, err: %v, output: %s", repoDir, err, string(res))
@@ -198,6 +228,48 @@ func GitFileHasUncommittedChanges(path string) (bool, error) {
 	return strings.TrimSpace(string(res)) != "", nil
 }
 
+// GitUncommittedPaths returns which of paths, relative to repoDir, have uncommitted changes (staged, unstaged, or
+// untracked), as listed by git status
+func GitUncommittedPaths(repoDir string, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	gitMutex.Lock()
+	defer gitMutex.Unlock()
+
+	// git status lists paths relative to the repo root, so the prefix of repoDir within the repo is trimmed from them
+	res, err := exec.Command("git", "-C", repoDir, "rev-parse", "--show-prefix").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("error getting repo prefix | err: %v, output: %s", err, string(res))
+	}
+	prefix := strings.TrimSpace(string(res))
+
+	args := append([]string{"-C", repoDir, "status", "--porcelain", "-z", "--untracked-files=all", "--"}, paths...)
+	res, err = exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("error getting git status | err: %v, output: %s", err, string(res))
+	}
+
+	var uncommitted []string
+	entries := strings.Split(string(res), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+
+		// renames and copies are followed by the original path
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+		}
+
+		uncommitted = append(uncommitted, strings.TrimPrefix(entry[3:], prefix))
+	}
+
+	return uncommitted, nil
+}
+
 func GitCheckoutFile(path string) error {
 	gitMutex.Lock()
 	defer gitMutex.Unlock()
@@ -212,6 +284,100 @@ func GitCheckoutFile(path string) error {
 	return nil
 }
 
+func GitCurrentBranch(repoDir string) (string, error) {
+	gitMutex.Lock()
+	defer gitMutex.Unlock()
+
+	res, err := exec.Command("git", "-C", repoDir, "symbolic-ref", "--short", "HEAD").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error getting current branch (is HEAD detached?) | err: %v, output: %s", err, string(res))
+	}
+
+	return strings.TrimSpace(string(res)), nil
+}
+
+func GitBranchExists(repoDir, branch string) bool {
+	gitMutex.Lock()
+	defer gitMutex.Unlock()
+
+	err := exec.Command("git", "-C", repoDir, "show-ref", "--verify", "--quiet", "refs/heads/"+branch).Run()
+	return err == nil
+}
+
+func GitValidBranchName(branch string) bool {
+	err := exec.Command("git", "check-ref-format", "--branch", branch).Run()
+	return err == nil
+}
+
+// GitCheckoutBranch switches to a branch, creating it from HEAD if create is set. Uncommitted changes are carried over,
+// as with git checkout.
+func GitCheckoutBranch(repoDir, branch string, create bool) error {
+	gitMutex.Lock()
+	defer gitMutex.Unlock()
+
+	args := []string{"-C", repoDir, "checkout"}
+	if create {
+		args = append(args, "-b")
+	}
+	args = append(args, branch)
+
+	res, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error checking out branch %s | err: %v, output: %s", branch, err, string(res))
+	}
+
+	return nil
+}
+
+func GitRemoteUrl(repoDir, remote string) (string, error) {
+	gitMutex.Lock()
+	defer gitMutex.Unlock()
+
+	res, err := exec.Command("git", "-C", repoDir, "remote", "get-url", remote).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error getting url of remote %s | err: %v, output: %s", remote, err, string(res))
+	}
+
+	return strings.TrimSpace(string(res)), nil
+}
+
+// GitRemoteDefaultBranch is the branch the remote's HEAD points to, like main, as of the last fetch
+func GitRemoteDefaultBranch(repoDir, remote string) (string, error) {
+	gitMutex.Lock()
+	defer gitMutex.Unlock()
+
+	res, err := exec.Command("git", "-C", repoDir, "symbolic-ref", "--short", "refs/remotes/"+remote+"/HEAD").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error getting default branch of remote %s | err: %v, output: %s", remote, err, string(res))
+	}
+
+	return strings.TrimPrefix(strings.TrimSpace(string(res)), remote+"/"), nil
+}
+
+func GitPush(repoDir, remote, branch string) error {
+	gitMutex.Lock()
+	defer gitMutex.Unlock()
+
+	res, err := exec.Command("git", "-C", repoDir, "push", "--set-upstream", remote, branch).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error pushing branch %s to %s | err: %v, output: %s", branch, remote, err, string(res))
+	}
+
+	return nil
+}
+
+func GitLastCommitSubject(repoDir, branch string) (string, error) {
+	gitMutex.Lock()
+	defer gitMutex.Unlock()
+
+	res, err := exec.Command("git", "-C", repoDir, "log", "-1", "--format=%s", branch, "--").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error getting last commit on %s | err: %v, output: %s", branch, err, string(res))
+	}
+
+	return strings.TrimSpace(string(res)), nil
+}
+
 func parseConflictFiles(gitOutput string) []string {
 	var conflictFiles []string
 	lines := strings.Split(gitOutput, "\n")
@@ -0,0 +1,147 @@
+package lib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"plandex/fs"
+	"plandex/term"
+	"plandex/types"
+	"runtime"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// what happens when a post-apply hook fails
+const (
+	// print the output (default)
+	HookFailureReport = "report"
+	// also load the output into context as a note
+	HookFailureLoad = "load"
+	// also load the output and prompt the plan to fix it
+	HookFailureFix = "fix"
+)
+
+// only the end of a failing hook or verify command's output is loaded into context, since that's usually where the errors are
+const maxHookOutputBytes = 20000
+
+var tellPlanInlineFn func(prompt string)
+
+func SetTellPlanInlineFn(fn func(prompt string)) {
+	tellPlanInlineFn = fn
+}
+
+// MustRunPostApplyHooks runs the config's post-apply hooks in order from the project root, stopping at the first that
+// fails. Output is shown as it runs. On failure, it's handled according to on-hook-failure, and then the command exits
+// with an error unless the plan was prompted to fix it. The config should come from MustLoadCommandConfig, so the
+// project's hooks only run once they're approved.
+func MustRunPostApplyHooks(config *types.PlandexConfig) {
+	if len(config.PostApplyHooks) == 0 {
+		return
+	}
+
+	for _, hook := range config.PostApplyHooks {
+		fmt.Println()
+		color.New(color.Bold, term.ColorHiCyan).Printf("🪝 Running %s\n", hook)
+
+		output, exitCode, err := runHook(hook)
+		if err != nil {
+			term.OutputErrorAndExit("Error running post-apply hook %s: %v", hook, err)
+		}
+
+		if exitCode == 0 {
+			continue
+		}
+
+		fmt.Println()
+		color.New(color.Bold, term.ColorHiRed).Printf("🚨 %s failed with exit code %d\n", hook, exitCode)
+
+		mustHandleHookFailure(config, hook, output, exitCode)
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("✅ Post-apply hooks passed")
+}
+
+// runHook runs a hook with the shell, showing its output and also returning it
+func runHook(hook string) (string, int, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", hook)
+	} else {
+		cmd = exec.Command("sh", "-c", hook)
+	}
+
+	output := &lockedBuffer{}
+	cmd.Dir = fs.ProjectRoot
+	cmd.Stdout = io.MultiWriter(os.Stdout, output)
+	cmd.Stderr = io.MultiWriter(os.Stderr, output)
+
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return output.String(), exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	return output.String(), 0, nil
+}
+
+func mustHandleHookFailure(config *types.PlandexConfig, hook, output string, exitCode int) {
+	action := config.OnHookFailure
+	if action == "" {
+		action = HookFailureReport
+	}
+
+	if action == HookFailureReport {
+		os.Exit(term.ExitError)
+	}
+
+	fmt.Println()
+	mustLoadCommandOutput(hook, output, exitCode, "after the plan's changes were applied")
+
+	if action == HookFailureLoad {
+		os.Exit(term.ExitError)
+	}
+
+	MustCheckBudget()
+
+	tellPlanInlineFn(fmt.Sprintf("After applying your changes, `%s` failed. Its output is in context. Fix the errors.", hook))
+}
+
+// mustLoadCommandOutput loads the output of a failed command into context as a note, so the plan can see the errors
+func mustLoadCommandOutput(command, output string, exitCode int, when string) {
+	if len(output) > maxHookOutputBytes {
+		output = "[...output truncated...]\n" + output[len(output)-maxHookOutputBytes:]
+	}
+
+	note := fmt.Sprintf("Output of `%s`, which failed with exit code %d %s:\n\n```\n%s\n```", command, exitCode, when, output)
+
+	MustLoadContext(nil, &types.LoadContextParams{Note: note})
+}
+
+// lockedBuffer collects stdout and stderr together, which are copied from separate goroutines
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,270 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"plandex/api"
+	"plandex/term"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/plandex/plandex/shared"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+const (
+	LogExportMarkdown = "md"
+	LogExportHtml     = "html"
+	LogExportJson     = "json"
+)
+
+var LogExportFormats = []string{LogExportMarkdown, LogExportHtml, LogExportJson}
+
+const (
+	LogEventPrompt  = "prompt"
+	LogEventReply   = "reply"
+	LogEventContext = "context"
+)
+
+// LogExport is a plan's full conversation, with the file changes each reply made and the context loaded along the way,
+// in order, for sharing outside of plandex
+type LogExport struct {
+	Plan       string            `json:"plan"`
+	Branch     string            `json:"branch"`
+	ExportedAt time.Time         `json:"exportedAt"`
+	Events     []*LogExportEvent `json:"events"`
+}
+
+type LogExportEvent struct {
+	// LogEventPrompt, LogEventReply, or LogEventContext
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+	Tokens  int       `json:"tokens,omitempty"`
+	Stopped bool      `json:"stopped,omitempty"`
+	// for replies that changed files, a description of the changes and each file they touched
+	ChangesSummary string             `json:"changesSummary,omitempty"`
+	Changes        []*LogExportChange `json:"changes,omitempty"`
+}
+
+type LogExportChange struct {
+	Path string `json:"path"`
+	// pending, applied, or rejected
+	Status string `json:"status"`
+}
+
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+var logEntryHeaderRegex = regexp.MustCompile(`^📝 Update (\w+) \| (.+)$`)
+
+// log entries for context changes start with one of these, as written by the server's context handlers
+var contextLogPrefixes = []string{"Loaded ", "Removed ", "Updated ", "📌 Pinned ", "Unpinned "}
+
+func MustGetLogExport(planId, branch string) *LogExport {
+	term.StartSpinner("")
+	defer term.StopSpinner()
+
+	plan, apiErr := api.Client.GetPlan(planId)
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting plan: %v", apiErr.Msg)
+	}
+
+	convo, apiErr := api.Client.ListConvo(planId, branch)
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error loading conversation: %v", apiErr.Msg)
+	}
+
+	currentPlanState, apiErr := api.Client.GetCurrentPlanState(planId, branch)
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting current plan state: %v", apiErr.Msg)
+	}
+
+	logs, apiErr := api.Client.ListLogs(planId, branch)
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting logs: %v", apiErr.Msg)
+	}
+
+	export := &LogExport{
+		Plan:       plan.Name,
+		Branch:     branch,
+		ExportedAt: time.Now(),
+	}
+
+	descByConvoMessageId := map[string]*shared.ConvoMessageDescription{}
+	for _, desc := range currentPlanState.ConvoMessageDescriptions {
+		descByConvoMessageId[desc.ConvoMessageId] = desc
+	}
+
+	changesByConvoMessageId := map[string][]*LogExportChange{}
+	if currentPlanState.PlanResult != nil {
+		seen := map[string]bool{}
+		for _, result := range currentPlanState.PlanResult.Results {
+			key := result.ConvoMessageId + "|" + result.Path
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			status := "pending"
+			if result.AppliedAt != nil {
+				status = "applied"
+			} else if result.RejectedAt != nil {
+				status = "rejected"
+			}
+
+			changesByConvoMessageId[result.ConvoMessageId] = append(changesByConvoMessageId[result.ConvoMessageId], &LogExportChange{
+				Path:   result.Path,
+				Status: status,
+			})
+		}
+	}
+
+	for _, msg := range convo {
+		event := &LogExportEvent{
+			Type:    LogEventPrompt,
+			Time:    msg.CreatedAt,
+			Message: msg.Message,
+			Tokens:  msg.Tokens,
+			Stopped: msg.Stopped,
+		}
+
+		if msg.Role == "assistant" {
+			event.Type = LogEventReply
+			if desc := descByConvoMessageId[msg.Id]; desc != nil && desc.MadePlan {
+				event.ChangesSummary = desc.CommitMsg
+			}
+			event.Changes = changesByConvoMessageId[msg.Id]
+			sort.Slice(event.Changes, func(i, j int) bool {
+				return event.Changes[i].Path < event.Changes[j].Path
+			})
+		}
+
+		export.Events = append(export.Events, event)
+	}
+
+	export.Events = append(export.Events, contextLogEvents(logs.Body)...)
+
+	sort.SliceStable(export.Events, func(i, j int) bool {
+		return export.Events[i].Time.Before(export.Events[j].Time)
+	})
+
+	return export
+}
+
+// contextLogEvents finds the context changes in the plan's log
+func contextLogEvents(logBody string) []*LogExportEvent {
+	var events []*LogExportEvent
+	var current *LogExportEvent
+	var lines []string
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Message = strings.TrimSpace(strings.Join(lines, "\n"))
+		for _, prefix := range contextLogPrefixes {
+			if strings.HasPrefix(current.Message, prefix) {
+				events = append(events, current)
+				break
+			}
+		}
+		current = nil
+		lines = nil
+	}
+
+	for _, line := range strings.Split(ansiRegex.ReplaceAllString(logBody, ""), "\n") {
+		if m := logEntryHeaderRegex.FindStringSubmatch(line); m != nil {
+			flush()
+			t, err := time.Parse("Mon Jan 2, 2006 | 3:04:05pm MST", m[2])
+			if err != nil {
+				continue
+			}
+			current = &LogExportEvent{Type: LogEventContext, Time: t}
+			continue
+		}
+		if current != nil {
+			lines = append(lines, line)
+		}
+	}
+	flush()
+
+	return events
+}
+
+func (e *LogExport) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", e.Plan)
+	fmt.Fprintf(&b, "Branch `%s` · exported %s\n", e.Branch, e.ExportedAt.Format("Jan 2, 2006 3:04pm MST"))
+
+	for _, event := range e.Events {
+		ts := event.Time.Local().Format("Jan 2, 2006 3:04pm MST")
+
+		switch event.Type {
+		case LogEventPrompt:
+			fmt.Fprintf(&b, "\n---\n\n## 💬 You · %s\n\n%s\n", ts, strings.TrimSpace(event.Message))
+
+		case LogEventReply:
+			fmt.Fprintf(&b, "\n---\n\n## 🤖 Plandex · %s\n\n%s\n", ts, strings.TrimSpace(event.Message))
+
+			if event.Stopped {
+				b.WriteString("\n🛑 *Stopped early*\n")
+			}
+
+			if len(event.Changes) > 0 {
+				b.WriteString("\n### 🏗️ Changes\n\n")
+				if event.ChangesSummary != "" {
+					b.WriteString(strings.TrimSpace(event.ChangesSummary) + "\n\n")
+				}
+				for _, change := range event.Changes {
+					fmt.Fprintf(&b, "- `%s` (%s)\n", change.Path, change.Status)
+				}
+			}
+
+		case LogEventContext:
+			summary, table, _ := strings.Cut(event.Message, "\n")
+			fmt.Fprintf(&b, "\n> 📥 **Context** · %s · %s\n", ts, strings.TrimSpace(summary))
+			if table = strings.TrimSpace(table); table != "" {
+				b.WriteString("\n```\n" + table + "\n```\n")
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func (e *LogExport) Html() (string, error) {
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	var body bytes.Buffer
+	err := md.Convert([]byte(e.Markdown()), &body)
+	if err != nil {
+		return "", fmt.Errorf("error rendering html: %v", err)
+	}
+
+	return fmt.Sprintf(logExportHtmlTemplate, html.EscapeString(e.Plan), body.String()), nil
+}
+
+const logExportHtmlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { max-width: 860px; margin: 2rem auto; padding: 0 1rem; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.5; color: #1f2328; }
+pre { background: #f6f8fa; padding: 1rem; overflow-x: auto; border-radius: 6px; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 0.9em; }
+blockquote { margin: 1rem 0; padding: 0 1rem; color: #59636e; border-left: 4px solid #d1d9e0; }
+hr { border: none; border-top: 1px solid #d1d9e0; margin: 2rem 0; }
+h2 { font-size: 1.2rem; }
+h3 { font-size: 1rem; }
+</style>
+</head>
+<body>
+%s
+</body>
+</html>
+`
@@ -0,0 +1,245 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"plandex/fs"
+	"sort"
+	"strings"
+
+	"github.com/plandex/plandex/shared"
+)
+
+const (
+	conflictStartMarker = "<<<<<<< local"
+	conflictSepMarker   = "======="
+	conflictEndMarker   = ">>>>>>> plandex"
+)
+
+// mergeRegion is a change to lines [start, end) of the base file, replacing them with lines
+type mergeRegion struct {
+	start, end int
+	lines      []string
+}
+
+// ThreeWayMerge merges the local and plan versions of a file, both changed from base. Changes on only one side are
+// kept as they are. Where both sides changed the same lines differently, both versions are written between conflict
+// markers, like git. It returns the merged content and the number of conflicts.
+func ThreeWayMerge(base, local, plan string) (string, int) {
+	baseLines := splitLines(base)
+	localRegions := mergeRegions(diffLines(baseLines, splitLines(local)))
+	planRegions := mergeRegions(diffLines(baseLines, splitLines(plan)))
+
+	var b strings.Builder
+	numConflicts := 0
+	cursor := 0
+	i, j := 0, 0
+
+	for i < len(localRegions) || j < len(planRegions) {
+		// start a group with whichever change comes first, then pull in every change from either side that overlaps or
+		// touches it, so both sides' versions of the group's lines can be compared
+		var start, end int
+		var localGroup, planGroup []*mergeRegion
+
+		if j >= len(planRegions) || (i < len(localRegions) && localRegions[i].start <= planRegions[j].start) {
+			start, end = localRegions[i].start, localRegions[i].end
+		} else {
+			start, end = planRegions[j].start, planRegions[j].end
+		}
+
+		for {
+			if i < len(localRegions) && localRegions[i].start <= end {
+				end = max(end, localRegions[i].end)
+				localGroup = append(localGroup, localRegions[i])
+				i++
+			} else if j < len(planRegions) && planRegions[j].start <= end {
+				end = max(end, planRegions[j].end)
+				planGroup = append(planGroup, planRegions[j])
+				j++
+			} else {
+				break
+			}
+		}
+
+		writeLines(&b, baseLines[cursor:start])
+		cursor = end
+
+		if len(planGroup) == 0 {
+			writeLines(&b, applyRegions(baseLines, start, end, localGroup))
+			continue
+		}
+		if len(localGroup) == 0 {
+			writeLines(&b, applyRegions(baseLines, start, end, planGroup))
+			continue
+		}
+
+		localLines := applyRegions(baseLines, start, end, localGroup)
+		planLines := applyRegions(baseLines, start, end, planGroup)
+
+		if strings.Join(localLines, "") == strings.Join(planLines, "") {
+			writeLines(&b, localLines)
+			continue
+		}
+
+		numConflicts++
+		b.WriteString(conflictStartMarker + "\n")
+		writeConflictSide(&b, localLines)
+		b.WriteString(conflictSepMarker + "\n")
+		writeConflictSide(&b, planLines)
+		b.WriteString(conflictEndMarker + "\n")
+	}
+
+	writeLines(&b, baseLines[cursor:])
+
+	return b.String(), numConflicts
+}
+
+func mergeRegions(ops []diffOp) []*mergeRegion {
+	var regions []*mergeRegion
+	var current *mergeRegion
+	pos := 0
+
+	for _, op := range ops {
+		if op.kind == ' ' {
+			current = nil
+			pos++
+			continue
+		}
+
+		if current == nil {
+			current = &mergeRegion{start: pos, end: pos}
+			regions = append(regions, current)
+		}
+
+		if op.kind == '-' {
+			pos++
+			current.end = pos
+		} else {
+			current.lines = append(current.lines, op.line)
+		}
+	}
+
+	return regions
+}
+
+// applyRegions returns base lines [start, end) with the given changes, which all fall within that range, applied
+func applyRegions(baseLines []string, start, end int, regions []*mergeRegion) []string {
+	var res []string
+	cursor := start
+	for _, region := range regions {
+		res = append(res, baseLines[cursor:region.start]...)
+		res = append(res, region.lines...)
+		cursor = region.end
+	}
+	return append(res, baseLines[cursor:end]...)
+}
+
+func writeLines(b *strings.Builder, lines []string) {
+	for _, line := range lines {
+		b.WriteString(line)
+	}
+}
+
+// writeConflictSide writes one side of a conflict, ending it with a newline so the marker after it is on its own line
+func writeConflictSide(b *strings.Builder, lines []string) {
+	writeLines(b, lines)
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		b.WriteString("\n")
+	}
+}
+
+// mergeBase returns the content a plan's changes to a file were built on, if the local file has changed since then and
+// that content is known. That's the body of the file's context, as long as the context holds the whole file. A file that
+// isn't in context, like one the plan created and that was already applied, has no known base, so it isn't merged. An empty
+// base would make the whole file one conflict.
+func mergeBase(context *shared.Context, local string) (string, bool) {
+	if context == nil {
+		return "", false
+	}
+
+	if !isWholeFileContext(context) {
+		return "", false
+	}
+
+	// context bodies are stored redacted, so the local file is redacted the same way to check if it changed
+	hash := sha256.Sum256([]byte(redactContextBody(context, local)))
+	if hex.EncodeToString(hash[:]) == context.Sha {
+		return "", false
+	}
+
+	return context.Body, true
+}
+
+func isWholeFileContext(context *shared.Context) bool {
+	return context.ContextType == shared.ContextFileType && context.LineRange == "" && context.ChunkPart == 0 && !context.Summarized
+}
+
+type localMergeResult struct {
+	// content to write by path where it's not the plan's version as is: merged with local changes made since the file's
+	// context was loaded, or with secrets that were redacted from the context restored
+	contentByPath map[string]string
+	// paths merged cleanly, and the number of conflicts in each path that wasn't
+	cleanPaths      []string
+	conflictsByPath map[string]int
+}
+
+// mergeLocalChanges merges local changes made since a file's context was loaded into the plan's version of the file,
+// so apply doesn't overwrite them
+func mergeLocalChanges(contextsByPath map[string]*shared.Context, toApply map[string]string) (*localMergeResult, error) {
+	res := &localMergeResult{
+		contentByPath:   map[string]string{},
+		conflictsByPath: map[string]int{},
+	}
+
+	for path, content := range toApply {
+		var local *string
+		bytes, err := os.ReadFile(filepath.Join(fs.ProjectRoot, path))
+		if err == nil {
+			s := string(bytes)
+			local = &s
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		content = unescapePlanFileContent(content)
+		context := contextsByPath[path]
+
+		restored, err := restoreRedactedSecrets(path, context, local, content)
+		if err != nil {
+			return nil, err
+		}
+		if restored != content {
+			content = restored
+			res.contentByPath[path] = content
+		}
+
+		if local == nil || *local == content {
+			continue
+		}
+
+		base, ok := mergeBase(context, *local)
+		if !ok {
+			continue
+		}
+
+		// the base is redacted like the plan's version was, so its secrets are restored too, or they'd look like local changes
+		if restoredBase, err := restoreRedactedSecrets(path, context, local, base); err == nil {
+			base = restoredBase
+		}
+
+		merged, numConflicts := ThreeWayMerge(base, *local, content)
+		res.contentByPath[path] = merged
+
+		if numConflicts > 0 {
+			res.conflictsByPath[path] = numConflicts
+		} else {
+			res.cleanPaths = append(res.cleanPaths, path)
+		}
+	}
+
+	sort.Strings(res.cleanPaths)
+
+	return res, nil
+}
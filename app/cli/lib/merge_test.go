@@ -0,0 +1,157 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"plandex/fs"
+	"testing"
+
+	"github.com/plandex/plandex/shared"
+)
+
+func TestThreeWayMerge(t *testing.T) {
+	tests := []struct {
+		name          string
+		base          string
+		local         string
+		plan          string
+		want          string
+		wantConflicts int
+	}{
+		{
+			name:  "unchanged",
+			base:  "a\nb\n",
+			local: "a\nb\n",
+			plan:  "a\nb\n",
+			want:  "a\nb\n",
+		},
+		{
+			name:  "only local changed",
+			base:  "a\nb\nc\n",
+			local: "a\nB\nc\n",
+			plan:  "a\nb\nc\n",
+			want:  "a\nB\nc\n",
+		},
+		{
+			name:  "only plan changed",
+			base:  "a\nb\nc\n",
+			local: "a\nb\nc\n",
+			plan:  "a\nb\nC\nd\n",
+			want:  "a\nb\nC\nd\n",
+		},
+		{
+			name:  "plan deleted lines",
+			base:  "a\nb\nc\n",
+			local: "a\nb\nc\n",
+			plan:  "a\nc\n",
+			want:  "a\nc\n",
+		},
+		{
+			name:  "different lines changed",
+			base:  "a\nb\nc\nd\ne\n",
+			local: "A\nb\nc\nd\ne\n",
+			plan:  "a\nb\nc\nd\nE\n",
+			want:  "A\nb\nc\nd\nE\n",
+		},
+		{
+			name:  "same change on both sides",
+			base:  "a\nb\nc\n",
+			local: "a\nB\nc\n",
+			plan:  "a\nB\nc\n",
+			want:  "a\nB\nc\n",
+		},
+		{
+			name:          "same line changed differently",
+			base:          "a\nb\nc\n",
+			local:         "a\nB1\nc\n",
+			plan:          "a\nB2\nc\n",
+			want:          "a\n<<<<<<< local\nB1\n=======\nB2\n>>>>>>> plandex\nc\n",
+			wantConflicts: 1,
+		},
+		{
+			name:          "different insertions at the same place",
+			base:          "a\nc\n",
+			local:         "a\nb1\nc\n",
+			plan:          "a\nb2\nc\n",
+			want:          "a\n<<<<<<< local\nb1\n=======\nb2\n>>>>>>> plandex\nc\n",
+			wantConflicts: 1,
+		},
+		{
+			name:          "adjacent changes",
+			base:          "a\nb\n",
+			local:         "A\nb\n",
+			plan:          "a\nB\n",
+			want:          "<<<<<<< local\nA\nb\n=======\na\nB\n>>>>>>> plandex\n",
+			wantConflicts: 1,
+		},
+		{
+			name:          "two conflicts",
+			base:          "a\nb\nc\nd\ne\n",
+			local:         "A1\nb\nc\nd\nE1\n",
+			plan:          "A2\nb\nc\nd\nE2\n",
+			want:          "<<<<<<< local\nA1\n=======\nA2\n>>>>>>> plandex\nb\nc\nd\n<<<<<<< local\nE1\n=======\nE2\n>>>>>>> plandex\n",
+			wantConflicts: 2,
+		},
+		{
+			name:          "no trailing newline",
+			base:          "a\nb",
+			local:         "a\nB1",
+			plan:          "a\nB2",
+			want:          "a\n<<<<<<< local\nB1\n=======\nB2\n>>>>>>> plandex\n",
+			wantConflicts: 1,
+		},
+		{
+			name:  "empty base with the same content",
+			base:  "",
+			local: "x\ny\n",
+			plan:  "x\ny\n",
+			want:  "x\ny\n",
+		},
+		{
+			name:          "empty base with different content",
+			base:          "",
+			local:         "x\n",
+			plan:          "y\n",
+			want:          "<<<<<<< local\nx\n=======\ny\n>>>>>>> plandex\n",
+			wantConflicts: 1,
+		},
+		{
+			name:  "empty local",
+			base:  "a\n",
+			local: "",
+			plan:  "a\n",
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, numConflicts := ThreeWayMerge(tt.base, tt.local, tt.plan)
+			if got != tt.want {
+				t.Errorf("ThreeWayMerge() = %q, want %q", got, tt.want)
+			}
+			if numConflicts != tt.wantConflicts {
+				t.Errorf("ThreeWayMerge() conflicts = %d, want %d", numConflicts, tt.wantConflicts)
+			}
+		})
+	}
+}
+
+// a file the plan created, which was applied and then edited by the plan again, has no context to merge from
+func TestMergeLocalChangesWithoutContext(t *testing.T) {
+	fs.ProjectRoot = t.TempDir()
+
+	err := os.WriteFile(filepath.Join(fs.ProjectRoot, "new.go"), []byte("package main\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := mergeLocalChanges(map[string]*shared.Context{}, map[string]string{"new.go": "package main\n\nfunc main() {}\n"})
+	if err != nil {
+		t.Fatalf("mergeLocalChanges() error = %v", err)
+	}
+
+	if len(res.contentByPath) > 0 || len(res.conflictsByPath) > 0 {
+		t.Errorf("mergeLocalChanges() merged %v with conflicts %v, want the plan's version as is", res.contentByPath, res.conflictsByPath)
+	}
+}
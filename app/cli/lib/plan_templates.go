@@ -0,0 +1,170 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"plandex/fs"
+	"plandex/term"
+	"plandex/types"
+	"sort"
+	"strings"
+
+	"github.com/plandex/plandex/shared"
+	"gopkg.in/yaml.v3"
+)
+
+// PlanTemplate is a scaffold for new plans, stored as <name>.yml in a plan-templates dir so it can be committed and shared with a team
+type PlanTemplate struct {
+	Name   string `yaml:"-"`
+	Path   string `yaml:"-"`
+	Global bool   `yaml:"-"`
+
+	Description string `yaml:"description,omitempty"`
+	// name of the new plan unless --name is set
+	PlanName string `yaml:"plan-name,omitempty"`
+	// prompt skeleton that's opened in the editor on the plan's first tell
+	Prompt string `yaml:"prompt,omitempty"`
+	// globs (with .gitignore syntax) of project files loaded into context
+	Context []string `yaml:"context,omitempty"`
+	// model names by role, as in config.yml
+	Models map[string]string `yaml:"models,omitempty"`
+}
+
+func homePlanTemplatesDir() string {
+	return filepath.Join(fs.HomePlandexDir, "plan-templates")
+}
+
+func projectPlanTemplatesDir() string {
+	if fs.PlandexDir == "" {
+		return ""
+	}
+	return filepath.Join(fs.PlandexDir, "plan-templates")
+}
+
+// ListPlanTemplates returns plan templates from the home dir and the current project sorted by name, with a project template taking the place of a home template with the same name
+func ListPlanTemplates() ([]*PlanTemplate, error) {
+	byName := map[string]*PlanTemplate{}
+
+	dirs := []string{homePlanTemplatesDir()}
+	if dir := projectPlanTemplatesDir(); dir != "" {
+		dirs = append(dirs, dir)
+	}
+
+	for i, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading plan templates dir: %v", err)
+		}
+
+		for _, entry := range entries {
+			name := strings.TrimSuffix(strings.TrimSuffix(entry.Name(), ".yml"), ".yaml")
+			if entry.IsDir() || name == entry.Name() {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			t, err := readPlanTemplate(path)
+			if err != nil {
+				return nil, err
+			}
+
+			t.Name = name
+			t.Path = path
+			t.Global = i == 0
+			byName[name] = t
+		}
+	}
+
+	var res []*PlanTemplate
+	for _, t := range byName {
+		res = append(res, t)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res, nil
+}
+
+func GetPlanTemplate(name string) (*PlanTemplate, error) {
+	templates, err := ListPlanTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+		names = append(names, t.Name)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("plan template %q not found (add plan templates to .plandex/plan-templates)", name)
+	}
+	return nil, fmt.Errorf("plan template %q not found (available: %s)", name, strings.Join(names, ", "))
+}
+
+func readPlanTemplate(path string) (*PlanTemplate, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	t := &PlanTemplate{}
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+	err = decoder.Decode(t)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+
+	for role, model := range t.Models {
+		if !isModelRole(role) {
+			return nil, fmt.Errorf("unknown model role %q in %s", role, path)
+		}
+		if _, ok := shared.AvailableModelsByName[model]; !ok {
+			return nil, fmt.Errorf("unknown model %q in %s", model, path)
+		}
+	}
+
+	return t, nil
+}
+
+// MustApplyPlanTemplate sets a template's models and context on a newly created plan and saves its prompt skeleton as the plan's draft prompt.
+// It's applied after config.yml, so the template's models take precedence.
+func MustApplyPlanTemplate(planId string, t *PlanTemplate) {
+	if len(t.Models) > 0 {
+		MustSetModels(planId, "main", t.Models)
+		fmt.Printf("🤖 Set models from template %s\n", t.Name)
+	}
+
+	if len(t.Context) > 0 {
+		paths, err := getDefaultContextPaths(t.Context)
+		if err != nil {
+			term.OutputErrorAndExit("Error getting template context: %v", err)
+		}
+
+		if len(paths) == 0 {
+			fmt.Printf("🤷‍♂️ No files matched context in template %s\n", t.Name)
+		} else {
+			fmt.Println()
+			MustLoadContext(paths, &types.LoadContextParams{})
+		}
+	}
+
+	if strings.TrimSpace(t.Prompt) != "" {
+		err := WriteDraftPrompt(planId, t.Prompt)
+		if err != nil {
+			term.OutputErrorAndExit("Error saving prompt from template: %v", err)
+		}
+		fmt.Println("📝 Prompt skeleton will open in the editor on your first tell")
+	}
+}
@@ -172,3 +172,62 @@ func getPlanCurrentBranch(planId string) (string, error) {
 
 	return settings.Branch, nil
 }
+
+func draftPromptPath(planId string) string {
+	return filepath.Join(fs.HomePlandexDir, CurrentProjectId, planId, "draft_prompt.md")
+}
+
+// WriteDraftPrompt saves a prompt that's prefilled in the editor the next time the plan is sent a prompt with tell
+func WriteDraftPrompt(planId, prompt string) error {
+	if fs.HomePlandexDir == "" {
+		return fmt.Errorf("HomePlandexDir not set")
+	}
+
+	if CurrentProjectId == "" {
+		return fmt.Errorf("no current project")
+	}
+
+	path := draftPromptPath(planId)
+
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("error creating plan dir: %v", err)
+	}
+
+	err = os.WriteFile(path, []byte(prompt), 0644)
+	if err != nil {
+		return fmt.Errorf("error writing draft prompt: %v", err)
+	}
+
+	return nil
+}
+
+// ReadDraftPrompt returns the plan's draft prompt, or an empty string if it doesn't have one
+func ReadDraftPrompt(planId string) (string, error) {
+	if fs.HomePlandexDir == "" || CurrentProjectId == "" {
+		return "", nil
+	}
+
+	bytes, err := os.ReadFile(draftPromptPath(planId))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading draft prompt: %v", err)
+	}
+
+	return string(bytes), nil
+}
+
+func ClearDraftPrompt(planId string) error {
+	if fs.HomePlandexDir == "" || CurrentProjectId == "" {
+		return nil
+	}
+
+	err := os.Remove(draftPromptPath(planId))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing draft prompt: %v", err)
+	}
+
+	return nil
+}
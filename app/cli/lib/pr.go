@@ -0,0 +1,334 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"plandex/api"
+	"plandex/term"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const prReqTimeout = 30 * time.Second
+
+const (
+	PrHostGithub = "github"
+	PrHostGitlab = "gitlab"
+)
+
+// A self-hosted server has to be set explicitly, with its own token, so a token for github.com or gitlab.com is never sent
+// to another host. These follow the gh and glab CLIs.
+const (
+	githubHostEnvVar = "GH_HOST"
+	gitlabHostEnvVar = "GITLAB_HOST"
+)
+
+type PullRequestParams struct {
+	Title string
+	Body  string
+	// branch with the changes
+	Head string
+	// branch to merge into
+	Base  string
+	Draft bool
+}
+
+// GitRemote is a remote repo that pull requests can be opened on
+type GitRemote struct {
+	// PrHostGithub or PrHostGitlab
+	Kind string
+	Host string
+	// owner/repo on GitHub, or the project's full path, including any groups, on GitLab
+	Path string
+	// GitHub Enterprise or self-managed GitLab, set with GH_HOST or GITLAB_HOST
+	SelfHosted bool
+}
+
+var scpRemoteRe = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// ParseGitRemote finds the host and repo path in a remote's url, in https, ssh, or scp-like (git@host:path) form.
+// github.com and gitlab.com are supported, along with a GitHub Enterprise host set in GH_HOST or a self-managed GitLab
+// host set in GITLAB_HOST.
+func ParseGitRemote(remoteUrl string) (*GitRemote, error) {
+	var host, path string
+
+	if strings.Contains(remoteUrl, "://") {
+		u, err := url.Parse(remoteUrl)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse remote url %s: %v", remoteUrl, err)
+		}
+		host = u.Hostname()
+		path = u.Path
+	} else if m := scpRemoteRe.FindStringSubmatch(remoteUrl); m != nil {
+		host = m[1]
+		path = m[2]
+	} else {
+		return nil, fmt.Errorf("couldn't parse remote url %s", remoteUrl)
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || !strings.Contains(path, "/") {
+		return nil, fmt.Errorf("couldn't find a repo in remote url %s", remoteUrl)
+	}
+
+	remote := &GitRemote{Host: host, Path: path}
+
+	lowerHost := strings.ToLower(host)
+	switch {
+	case lowerHost == "github.com":
+		remote.Kind = PrHostGithub
+	case lowerHost == "gitlab.com":
+		remote.Kind = PrHostGitlab
+	case lowerHost == envHost(githubHostEnvVar):
+		remote.Kind = PrHostGithub
+		remote.SelfHosted = true
+	case lowerHost == envHost(gitlabHostEnvVar):
+		remote.Kind = PrHostGitlab
+		remote.SelfHosted = true
+	default:
+		return nil, fmt.Errorf("%s isn't github.com or gitlab.com. For GitHub Enterprise, set %s to %s, or for self-managed GitLab, set %s to %s.", host, githubHostEnvVar, host, gitlabHostEnvVar, host)
+	}
+
+	return remote, nil
+}
+
+// envHost returns the host name set in an environment variable, which can also be a url, or "" if it isn't set
+func envHost(name string) string {
+	host := strings.ToLower(strings.TrimSpace(os.Getenv(name)))
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	return strings.TrimSuffix(host, "/")
+}
+
+// TokenEnvVars are the environment variables checked for an API token, in order
+func (r *GitRemote) TokenEnvVars() []string {
+	switch {
+	case r.Kind == PrHostGithub && r.SelfHosted:
+		return []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	case r.Kind == PrHostGithub:
+		return []string{"GITHUB_TOKEN", "GH_TOKEN"}
+	case r.SelfHosted:
+		return []string{"GITLAB_SELF_MANAGED_TOKEN"}
+	}
+	return []string{"GITLAB_TOKEN"}
+}
+
+func (r *GitRemote) token() string {
+	for _, name := range r.TokenEnvVars() {
+		if token := os.Getenv(name); token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// CreatePullRequest opens a pull request on GitHub, or a merge request on GitLab, and returns its url
+func CreatePullRequest(remote *GitRemote, params PullRequestParams) (string, error) {
+	token := remote.token()
+	if token == "" {
+		return "", fmt.Errorf("no API token found, set %s", strings.Join(remote.TokenEnvVars(), " or "))
+	}
+
+	var reqUrl string
+	var body map[string]any
+	headers := map[string]string{}
+
+	if remote.Kind == PrHostGithub {
+		apiBase := "https://api.github.com"
+		if remote.SelfHosted {
+			apiBase = "https://" + remote.Host + "/api/v3"
+		}
+		reqUrl = fmt.Sprintf("%s/repos/%s/pulls", apiBase, remote.Path)
+		body = map[string]any{
+			"title": params.Title,
+			"body":  params.Body,
+			"head":  params.Head,
+			"base":  params.Base,
+			"draft": params.Draft,
+		}
+		headers["Authorization"] = "Bearer " + token
+		headers["Accept"] = "application/vnd.github+json"
+	} else {
+		title := params.Title
+		if params.Draft {
+			title = "Draft: " + title
+		}
+		reqUrl = fmt.Sprintf("https://%s/api/v4/projects/%s/merge_requests", remote.Host, url.PathEscape(remote.Path))
+		body = map[string]any{
+			"title":         title,
+			"description":   params.Body,
+			"source_branch": params.Head,
+			"target_branch": params.Base,
+		}
+		headers["PRIVATE-TOKEN"] = token
+	}
+
+	reqBytes, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling request: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, reqUrl, bytes.NewReader(reqBytes))
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{Timeout: prReqTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response: %v", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("%s returned %s: %s", remote.Host, resp.Status, prErrorMessage(respBytes))
+	}
+
+	var res struct {
+		HtmlUrl string `json:"html_url"`
+		WebUrl  string `json:"web_url"`
+	}
+	err = json.Unmarshal(respBytes, &res)
+	if err != nil {
+		return "", fmt.Errorf("error decoding response: %v", err)
+	}
+
+	if res.HtmlUrl != "" {
+		return res.HtmlUrl, nil
+	}
+	return res.WebUrl, nil
+}
+
+// prErrorMessage pulls the useful part out of a GitHub or GitLab error response, like "A pull request already exists"
+func prErrorMessage(body []byte) string {
+	var res struct {
+		Message any `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+
+	if json.Unmarshal(body, &res) != nil {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > 500 {
+			msg = msg[:500] + "..."
+		}
+		return msg
+	}
+
+	var msgs []string
+	switch m := res.Message.(type) {
+	case string:
+		msgs = append(msgs, m)
+	case []any:
+		for _, v := range m {
+			msgs = append(msgs, fmt.Sprint(v))
+		}
+	case map[string]any:
+		for k, v := range m {
+			msgs = append(msgs, fmt.Sprintf("%s %v", k, v))
+		}
+	}
+	for _, e := range res.Errors {
+		if e.Message != "" {
+			msgs = append(msgs, e.Message)
+		}
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// MustGetPullRequestBody describes a plan for a pull request: the latest summary of its conversation, or the
+// descriptions of its changes if it hasn't been summarized yet, followed by a collapsed list of the context it used
+func MustGetPullRequestBody(planId, branch string) string {
+	term.StartSpinner("")
+	defer term.StopSpinner()
+
+	plan, apiErr := api.Client.GetPlan(planId)
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting plan: %v", apiErr.Msg)
+	}
+
+	archive, apiErr := api.Client.ExportPlan(planId, branch)
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting plan conversation: %v", apiErr.Msg)
+	}
+
+	contexts, apiErr := api.Client.ListContext(planId, branch)
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting context: %v", apiErr.Msg)
+	}
+
+	var b strings.Builder
+
+	var summary string
+	var latest time.Time
+	for _, s := range archive.Summaries {
+		if s.LatestConvoMessageCreatedAt.After(latest) {
+			latest = s.LatestConvoMessageCreatedAt
+			summary = strings.TrimSpace(s.Summary)
+		}
+	}
+
+	if summary != "" {
+		b.WriteString(summary)
+		b.WriteString("\n")
+	} else {
+		currentPlanState, apiErr := api.Client.GetCurrentPlanState(planId, branch)
+		if apiErr != nil {
+			term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting current plan state: %v", apiErr.Msg)
+		}
+
+		descs := currentPlanState.ConvoMessageDescriptions
+		sort.Slice(descs, func(i, j int) bool {
+			return descs[i].CreatedAt.Before(descs[j].CreatedAt)
+		})
+
+		b.WriteString("## Changes\n\n")
+		for _, desc := range descs {
+			if desc.CommitMsg != "" {
+				b.WriteString("- " + strings.TrimSpace(strings.SplitN(desc.CommitMsg, "\n", 2)[0]) + "\n")
+			}
+		}
+	}
+
+	if len(contexts) > 0 {
+		sort.Slice(contexts, func(i, j int) bool {
+			return contexts[i].CreatedAt.Before(contexts[j].CreatedAt)
+		})
+
+		fmt.Fprintf(&b, "\n<details>\n<summary>Context used (%d)</summary>\n\n", len(contexts))
+		for _, context := range contexts {
+			t, icon := GetContextTypeAndIcon(context)
+			name := context.Name
+			if context.FilePath != "" {
+				name = context.FilePath
+			}
+			fmt.Fprintf(&b, "- %s `%s` | %s | %d 🪙\n", icon, name, t, context.NumTokens)
+		}
+		b.WriteString("\n</details>\n")
+	}
+
+	fmt.Fprintf(&b, "\n---\nOpened with `plandex pr` from plan **%s**", plan.Name)
+	if branch != "main" {
+		fmt.Fprintf(&b, " (branch %s)", branch)
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGitRemote(t *testing.T) {
+	t.Setenv("GH_HOST", "https://github.example.com/")
+	t.Setenv("GITLAB_HOST", "gitlab.example.com")
+
+	tests := []struct {
+		url        string
+		kind       string
+		selfHosted bool
+		path       string
+		tokens     []string
+	}{
+		{"https://github.com/plandex-ai/plandex.git", PrHostGithub, false, "plandex-ai/plandex", []string{"GITHUB_TOKEN", "GH_TOKEN"}},
+		{"git@github.com:plandex-ai/plandex.git", PrHostGithub, false, "plandex-ai/plandex", []string{"GITHUB_TOKEN", "GH_TOKEN"}},
+		{"ssh://git@gitlab.com/group/sub/project.git", PrHostGitlab, false, "group/sub/project", []string{"GITLAB_TOKEN"}},
+		{"git@GitHub.Example.com:org/repo.git", PrHostGithub, true, "org/repo", []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}},
+		{"https://gitlab.example.com/group/project", PrHostGitlab, true, "group/project", []string{"GITLAB_SELF_MANAGED_TOKEN"}},
+	}
+
+	for _, tt := range tests {
+		remote, err := ParseGitRemote(tt.url)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.url, err)
+			continue
+		}
+		if remote.Kind != tt.kind || remote.SelfHosted != tt.selfHosted || remote.Path != tt.path {
+			t.Errorf("%s: got %+v", tt.url, remote)
+		}
+		if !reflect.DeepEqual(remote.TokenEnvVars(), tt.tokens) {
+			t.Errorf("%s: got token env vars %v, want %v", tt.url, remote.TokenEnvVars(), tt.tokens)
+		}
+	}
+}
+
+// hosts that only look like GitHub or GitLab must not get their tokens
+func TestParseGitRemoteRejectsUnconfiguredHosts(t *testing.T) {
+	t.Setenv("GH_HOST", "")
+	t.Setenv("GITLAB_HOST", "")
+
+	for _, url := range []string{
+		"https://github.attacker.io/org/repo.git",
+		"git@gitlab.attacker.io:org/repo.git",
+		"https://github.com.attacker.io/org/repo",
+		"https://github.example.com/org/repo",
+	} {
+		_, err := ParseGitRemote(url)
+		if err == nil {
+			t.Errorf("%s: expected an error", url)
+		}
+	}
+}
@@ -0,0 +1,474 @@
+package lib
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"plandex/api"
+	"plandex/auth"
+	"plandex/fs"
+	"plandex/term"
+	"plandex/types"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/plandex/plandex/shared"
+)
+
+// a sync that crashed or was killed leaves its lock behind; it's ignored after this long
+const queueLockStaleAfter = 10 * time.Minute
+
+func queueDir() string {
+	return filepath.Join(fs.HomePlandexDir, "queue")
+}
+
+// offlineError wraps an api error from a request that failed because the server or model providers couldn't be reached
+type offlineError struct {
+	apiErr *shared.ApiError
+}
+
+func (e *offlineError) Error() string {
+	return e.apiErr.Msg
+}
+
+// IsOfflineErr is true for api errors that mean the request can be queued and sent again later
+func IsOfflineErr(apiErr *shared.ApiError) bool {
+	if apiErr == nil {
+		return false
+	}
+
+	if apiErr.Type == shared.ApiErrorTypeNetwork || apiErr.Type == shared.ApiErrorTypeProviderUnavailable {
+		return true
+	}
+
+	// a proxy or load balancer in front of the server answers for it while it's down
+	switch apiErr.Status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+func queueLoadContext(req shared.LoadContextRequest) (*types.QueuedOp, error) {
+	op := &types.QueuedOp{
+		Type:        types.QueuedOpLoadContext,
+		PlanId:      CurrentPlanId,
+		Branch:      CurrentBranch,
+		LoadContext: req,
+	}
+	return op, queueOp(op)
+}
+
+// QueueTell queues a prompt for the current plan. The request's model API key isn't stored.
+func QueueTell(req shared.TellPlanRequest) (*types.QueuedOp, error) {
+	req.ApiKey = ""
+	op := &types.QueuedOp{
+		Type:   types.QueuedOpTell,
+		PlanId: CurrentPlanId,
+		Branch: CurrentBranch,
+		Tell:   &req,
+	}
+	return op, queueOp(op)
+}
+
+func queueOp(op *types.QueuedOp) error {
+	if auth.Current == nil {
+		return fmt.Errorf("not signed in")
+	}
+
+	idBytes := make([]byte, 4)
+	_, err := rand.Read(idBytes)
+	if err != nil {
+		return fmt.Errorf("error generating id: %v", err)
+	}
+
+	op.Id = hex.EncodeToString(idBytes)
+	op.QueuedAt = time.Now()
+	op.Host = auth.Current.Host
+	op.OrgId = auth.Current.OrgId
+	op.UserId = auth.Current.UserId
+	op.ProjectId = CurrentProjectId
+
+	err = os.MkdirAll(queueDir(), 0700)
+	if err != nil {
+		return fmt.Errorf("error creating queue dir: %v", err)
+	}
+
+	return writeQueuedOp(op)
+}
+
+// queued ops have file contents and prompts, so only the user can read them
+func writeQueuedOp(op *types.QueuedOp) error {
+	bytes, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Errorf("error marshalling queued op: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(queueDir(), op.Id+".json"), bytes, 0600)
+	if err != nil {
+		return fmt.Errorf("error writing queued op: %v", err)
+	}
+
+	return nil
+}
+
+func removeQueuedOp(op *types.QueuedOp) error {
+	err := os.Remove(filepath.Join(queueDir(), op.Id+".json"))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing queued op: %v", err)
+	}
+	return nil
+}
+
+// ListQueuedOps returns the current account's queued ops in the order they were queued
+func ListQueuedOps() ([]*types.QueuedOp, error) {
+	if auth.Current == nil {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(queueDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading queue dir: %v", err)
+	}
+
+	var ops []*types.QueuedOp
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		bytes, err := os.ReadFile(filepath.Join(queueDir(), entry.Name()))
+		if os.IsNotExist(err) {
+			// sent by another process since the dir was read
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading queued op: %v", err)
+		}
+
+		var op types.QueuedOp
+		err = json.Unmarshal(bytes, &op)
+		if err != nil {
+			log.Printf("Error unmarshalling queued op %s: %v\n", entry.Name(), err)
+			continue
+		}
+
+		if op.Host != auth.Current.Host || op.OrgId != auth.Current.OrgId || op.UserId != auth.Current.UserId {
+			continue
+		}
+
+		ops = append(ops, &op)
+	}
+
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].QueuedAt.Before(ops[j].QueuedAt)
+	})
+
+	return ops, nil
+}
+
+// DiscardQueuedOps removes the queued ops whose ids start with any of the given prefixes, or all of them if none are given
+func DiscardQueuedOps(idPrefixes []string) ([]*types.QueuedOp, error) {
+	ops, err := ListQueuedOps()
+	if err != nil {
+		return nil, err
+	}
+
+	var discarded []*types.QueuedOp
+	for _, op := range ops {
+		matches := len(idPrefixes) == 0
+		for _, prefix := range idPrefixes {
+			if strings.HasPrefix(op.Id, prefix) {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+
+		err := removeQueuedOp(op)
+		if err != nil {
+			return discarded, err
+		}
+		discarded = append(discarded, op)
+	}
+
+	return discarded, nil
+}
+
+type QueueSyncResult struct {
+	Sent   []*types.QueuedOp `json:"sent"`
+	Failed []*types.QueuedOp `json:"failed"`
+	// ops that are still queued
+	Remaining int `json:"remaining"`
+	// the server or model providers still couldn't be reached
+	Offline bool `json:"offline"`
+	// another process is already syncing
+	Busy bool `json:"busy"`
+}
+
+// SyncQueue sends queued ops in the order they were queued. It stops at the first one that fails because it's still offline.
+// An op that fails for another reason is marked as failed, and later ops for its branch wait so they don't run without it.
+// Failed ops are only retried if retryFailed is set.
+func SyncQueue(retryFailed bool) (*QueueSyncResult, error) {
+	result := &QueueSyncResult{}
+
+	unlock, locked, err := lockQueue()
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		result.Busy = true
+		return result, nil
+	}
+	defer unlock()
+
+	ops, err := ListQueuedOps()
+	if err != nil {
+		return nil, err
+	}
+
+	blockedBranches := map[string]bool{}
+
+	for i, op := range ops {
+		branchKey := op.PlanId + "|" + op.Branch
+
+		if result.Offline || blockedBranches[branchKey] || (op.Error != "" && !retryFailed) {
+			if op.Error != "" {
+				blockedBranches[branchKey] = true
+			}
+			result.Remaining++
+			continue
+		}
+
+		sent, apiErr := sendQueuedOp(op)
+
+		if apiErr != nil {
+			if IsOfflineErr(apiErr) {
+				log.Printf("Still offline, %d queued ops remaining: %s\n", len(ops)-i, apiErr.Msg)
+				result.Offline = true
+				result.Remaining++
+				continue
+			}
+
+			op.Error = apiErr.Msg
+			err := writeQueuedOp(op)
+			if err != nil {
+				return nil, err
+			}
+
+			result.Failed = append(result.Failed, op)
+			result.Remaining++
+			blockedBranches[branchKey] = true
+			continue
+		}
+
+		if !sent {
+			result.Remaining++
+			blockedBranches[branchKey] = true
+			continue
+		}
+
+		err := removeQueuedOp(op)
+		if err != nil {
+			return nil, err
+		}
+		result.Sent = append(result.Sent, op)
+
+		// a prompt runs in the background, and the branch can't take another until it finishes
+		if op.Type == types.QueuedOpTell {
+			blockedBranches[branchKey] = true
+		}
+	}
+
+	return result, nil
+}
+
+// sendQueuedOp returns false if the op has to wait, like a prompt for a branch that's still streaming
+func sendQueuedOp(op *types.QueuedOp) (bool, *shared.ApiError) {
+	switch op.Type {
+	case types.QueuedOpLoadContext:
+		currentPlan, apiErr := api.Client.GetCurrentPlanState(op.PlanId, op.Branch)
+		if apiErr != nil {
+			return false, apiErr
+		}
+
+		// there's no one to confirm a rebuild of pending changes, so loads that would need one fail
+		conflictedPaths := currentPlan.PlanResult.FileResultsByPath.ConflictedPaths(wholeFilesByPath(op.LoadContext))
+		if len(conflictedPaths) > 0 {
+			var paths []string
+			for path := range conflictedPaths {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			return false, &shared.ApiError{
+				Type: shared.ApiErrorTypeOther,
+				Msg:  fmt.Sprintf("Conflicts with pending changes to %s. Load them again to update them and rebuild.", strings.Join(paths, ", ")),
+			}
+		}
+
+		res, apiErr := loadContextCached(op.PlanId, op.Branch, op.LoadContext)
+		if apiErr != nil {
+			return false, apiErr
+		}
+
+		if res.MaxTokensExceeded {
+			return false, &shared.ApiError{
+				Type: shared.ApiErrorTypeOther,
+				Msg:  fmt.Sprintf("Would exceed the token limit (%d) by %d 🪙. Load again with --evict or --max-tokens-per-file.", res.MaxTokens, res.TotalTokens-res.MaxTokens),
+			}
+		}
+
+		return true, nil
+
+	case types.QueuedOpTell:
+		running, apiErr := api.Client.ListPlansRunning([]string{op.ProjectId}, false)
+		if apiErr != nil {
+			return false, apiErr
+		}
+		for _, b := range running.Branches {
+			if b.PlanId == op.PlanId && b.Name == op.Branch {
+				return false, nil
+			}
+		}
+
+		req := *op.Tell
+		req.ApiKey = os.Getenv("OPENAI_API_KEY")
+		if req.ApiKey == "" {
+			return false, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: "OPENAI_API_KEY isn't set"}
+		}
+		// no one is watching the stream, so it runs in the background
+		req.ConnectStream = false
+
+		apiErr = api.Client.TellPlan(op.PlanId, op.Branch, req, nil)
+		if apiErr != nil {
+			return false, apiErr
+		}
+
+		return true, nil
+	}
+
+	return false, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("unknown queued op type %s", op.Type)}
+}
+
+// lockQueue returns false if another process is syncing the queue
+func lockQueue() (func(), bool, error) {
+	err := os.MkdirAll(queueDir(), 0700)
+	if err != nil {
+		return nil, false, fmt.Errorf("error creating queue dir: %v", err)
+	}
+
+	path := filepath.Join(queueDir(), "sync.lock")
+
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, true, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, false, fmt.Errorf("error locking queue: %v", err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || time.Since(info.ModTime()) < queueLockStaleAfter {
+			return nil, false, nil
+		}
+
+		log.Println("Removing stale queue lock")
+		os.Remove(path)
+	}
+
+	return nil, false, nil
+}
+
+// autoSyncQueue sends anything queued while offline. It's called whenever a project is resolved, so queued work goes out with
+// the first command that can reach the server.
+func autoSyncQueue() {
+	if auth.Current == nil {
+		return
+	}
+
+	ops, err := ListQueuedOps()
+	if err != nil {
+		log.Printf("Error listing queued ops: %v\n", err)
+		return
+	}
+
+	pending := false
+	for _, op := range ops {
+		if op.Error == "" {
+			pending = true
+			break
+		}
+	}
+	if !pending {
+		return
+	}
+
+	term.StartSpinner("🔄 Sending queued work...")
+	res, err := SyncQueue(false)
+	term.StopSpinner()
+
+	if err != nil {
+		log.Printf("Error syncing queue: %v\n", err)
+		return
+	}
+
+	if len(res.Sent) > 0 || len(res.Failed) > 0 {
+		PrintQueueSyncResult(res)
+		fmt.Println()
+	}
+}
+
+func PrintQueueSyncResult(res *QueueSyncResult) {
+	for _, op := range res.Sent {
+		if op.Type == types.QueuedOpTell {
+			fmt.Println(term.Plain(fmt.Sprintf("✅ Sent queued %s to branch %s. It's running in the background.", QueuedOpDesc(op), op.Branch)))
+		} else {
+			fmt.Println(term.Plain(fmt.Sprintf("✅ Sent queued %s to branch %s", QueuedOpDesc(op), op.Branch)))
+		}
+	}
+
+	for _, op := range res.Failed {
+		color.New(term.ColorHiRed, color.Bold).Println(term.Plain(fmt.Sprintf("🚨 Queued %s failed: %s", QueuedOpDesc(op), op.Error)))
+	}
+
+	if res.Busy {
+		fmt.Println(term.Plain("⏳ The queue is being sent by another plandex command"))
+	} else if res.Offline {
+		fmt.Println(term.Plain(fmt.Sprintf("📴 Still can't reach the server. %d queued.", res.Remaining)))
+	}
+}
+
+// QueuedOpDesc describes a queued op, like 'load of 3 contexts' or 'prompt "add a health check..."'
+func QueuedOpDesc(op *types.QueuedOp) string {
+	switch op.Type {
+	case types.QueuedOpLoadContext:
+		if len(op.LoadContext) == 1 {
+			return "load of 1 context"
+		}
+		return fmt.Sprintf("load of %d contexts", len(op.LoadContext))
+	case types.QueuedOpTell:
+		prompt := []rune(strings.Join(strings.Fields(op.Tell.Prompt), " "))
+		if len(prompt) > 40 {
+			prompt = append(prompt[:40], []rune("...")...)
+		}
+		if op.Tell.IsUserContinue || len(prompt) == 0 {
+			return "continue"
+		}
+		return fmt.Sprintf("prompt %q", string(prompt))
+	}
+	return string(op.Type)
+}
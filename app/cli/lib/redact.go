@@ -0,0 +1,253 @@
+package lib
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/plandex/plandex/shared"
+)
+
+type secretPattern struct {
+	kind  string
+	regex *regexp.Regexp
+}
+
+// private keys come first so the key material inside them isn't matched by the narrower patterns
+var secretPatterns = []*secretPattern{
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{"aws access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"aws secret key", regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}\b`)},
+	{"github token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+	{"stripe key", regexp.MustCompile(`\b(?:sk|rk)_(?:live|test)_[A-Za-z0-9]{16,}\b`)},
+	{"google api key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"api key", regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}\b`)},
+}
+
+// matches a KEY=value line in a .env file, with an optional export
+var envLineRegex = regexp.MustCompile(`(?m)^([ \t]*(?:export[ \t]+)?[A-Za-z_][A-Za-z0-9_]*[ \t]*=[ \t]*)(.+)$`)
+
+const redactedMarkerPrefix = "[REDACTED "
+
+func redactedMarker(kind string) string {
+	return fmt.Sprintf("%s%s]", redactedMarkerPrefix, kind)
+}
+
+// isEnvFile matches .env and .env.* files, except for examples and templates which are meant to be shared
+func isEnvFile(path string) bool {
+	base := filepath.Base(path)
+	if base != ".env" && !strings.HasPrefix(base, ".env.") {
+		return false
+	}
+	for _, suffix := range []string{".example", ".sample", ".template"} {
+		if strings.HasSuffix(base, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// redactSecrets masks anything that looks like a secret, returning the redacted body and the number of redactions of each kind.
+// In .env files, every value is masked.
+func redactSecrets(path, body string) (string, map[string]int) {
+	counts := map[string]int{}
+
+	for _, pattern := range secretPatterns {
+		body = pattern.regex.ReplaceAllStringFunc(body, func(string) string {
+			counts[pattern.kind]++
+			return redactedMarker(pattern.kind)
+		})
+	}
+
+	if path != "" && isEnvFile(path) {
+		body = envLineRegex.ReplaceAllStringFunc(body, func(line string) string {
+			matches := envLineRegex.FindStringSubmatch(line)
+			value := strings.TrimSpace(matches[2])
+			if strings.HasPrefix(value, redactedMarkerPrefix) || strings.HasPrefix(value, "#") {
+				return line
+			}
+			counts["env value"]++
+			return matches[1] + redactedMarker("env value")
+		})
+	}
+
+	return body, counts
+}
+
+// redactContexts redacts the body of each context in place, other than images, returning redaction counts by context name
+func redactContexts(contexts []*shared.LoadContextParams) map[string]map[string]int {
+	redacted := map[string]map[string]int{}
+
+	for _, context := range contexts {
+		if context.ContextType == shared.ContextImageType {
+			continue
+		}
+
+		body, counts := redactSecrets(context.FilePath, context.Body)
+		if len(counts) == 0 {
+			continue
+		}
+
+		context.Body = body
+
+		name := context.Name
+		if name == "" {
+			name = string(context.ContextType)
+		}
+		redacted[name] = counts
+	}
+
+	return redacted
+}
+
+func printRedactedMsg(redacted map[string]map[string]int) {
+	var names []string
+	for name := range redacted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println()
+	fmt.Println("🔒 " + color.New(color.FgWhite).Sprint("Secrets were redacted before loading:"))
+	for _, name := range names {
+		var kinds []string
+		for kind, n := range redacted[name] {
+			kinds = append(kinds, fmt.Sprintf("%d %s", n, kind))
+		}
+		sort.Strings(kinds)
+		fmt.Println(color.New(color.FgWhite).Sprintf("  • %s | %s", name, strings.Join(kinds, ", ")))
+	}
+	fmt.Println(color.New(color.FgWhite).Sprint("Use --no-redact to load them as-is."))
+}
+
+// redactContextBody applies the same redaction as loading did, so an unchanged source hashes the same as the stored body on update
+func redactContextBody(context *shared.Context, body string) string {
+	if context.NoRedact {
+		return body
+	}
+	body, _ = redactSecrets(context.FilePath, body)
+	return body
+}
+
+// redactedLines is a run of lines from a redacted body and the local file's lines they were redacted from
+type redactedLines struct {
+	redacted []string
+	original []string
+}
+
+// restoreRedactedSecrets puts the local file's secrets back into the plan's version of a file that was loaded with secrets
+// redacted, since the plan's changes were made on top of the redacted body. Each redacted line is restored from the line it
+// was redacted from in the local file. If any can't be, like a redacted line the plan changed, an error is returned rather
+// than writing markers over the file's secrets.
+func restoreRedactedSecrets(path string, context *shared.Context, local *string, content string) (string, error) {
+	if context == nil || context.NoRedact || !strings.Contains(context.Body, redactedMarkerPrefix) || !strings.Contains(content, redactedMarkerPrefix) {
+		return content, nil
+	}
+
+	var runs []*redactedLines
+	if local != nil {
+		redacted := redactContextBody(context, *local)
+
+		// lines are paired one to one when a change redacted the same number of lines it replaced, which is every kind of
+		// secret other than multi-line private keys
+		var current *redactedLines
+		flush := func() {
+			if current == nil {
+				return
+			}
+			if len(current.redacted) == len(current.original) {
+				for i := range current.redacted {
+					runs = append(runs, &redactedLines{redacted: current.redacted[i : i+1], original: current.original[i : i+1]})
+				}
+			} else if len(current.redacted) > 0 {
+				runs = append(runs, current)
+			}
+			current = nil
+		}
+
+		for _, op := range diffLines(splitLines(redacted), splitLines(*local)) {
+			if op.kind == ' ' {
+				flush()
+				continue
+			}
+			if current == nil {
+				current = &redactedLines{}
+			}
+			if op.kind == '-' {
+				current.redacted = append(current.redacted, strings.TrimSuffix(op.line, "\n"))
+			} else {
+				current.original = append(current.original, strings.TrimSuffix(op.line, "\n"))
+			}
+		}
+		flush()
+
+		// longer runs are matched first so a private key isn't mistaken for a shorter run
+		sort.SliceStable(runs, func(i, j int) bool {
+			return len(runs[i].redacted) > len(runs[j].redacted)
+		})
+	}
+
+	lines := splitLines(content)
+	var b strings.Builder
+	numUnrestored := 0
+
+	for i := 0; i < len(lines); {
+		if !strings.Contains(lines[i], redactedMarkerPrefix) {
+			b.WriteString(lines[i])
+			i++
+			continue
+		}
+
+		var match *redactedLines
+		ambiguous := false
+		for _, run := range runs {
+			if match != nil && len(run.redacted) < len(match.redacted) {
+				break
+			}
+			if !linesMatch(lines[i:], run.redacted) {
+				continue
+			}
+			if match != nil && strings.Join(match.original, "\n") != strings.Join(run.original, "\n") {
+				// the same redacted lines came from different secrets, so there's no telling which one the plan kept
+				ambiguous = true
+			}
+			match = run
+		}
+
+		if match == nil || ambiguous {
+			numUnrestored++
+			b.WriteString(lines[i])
+			i++
+			continue
+		}
+
+		last := lines[i+len(match.redacted)-1]
+		b.WriteString(strings.Join(match.original, "\n"))
+		if strings.HasSuffix(last, "\n") {
+			b.WriteString("\n")
+		}
+		i += len(match.redacted)
+	}
+
+	if numUnrestored > 0 {
+		return "", fmt.Errorf("%s was loaded with secrets redacted, and %d redacted lines in the plan's version couldn't be restored from the local file, so applying it would overwrite them with [REDACTED] markers. Load it again with --no-redact, or make the change by hand.", path, numUnrestored)
+	}
+
+	return b.String(), nil
+}
+
+func linesMatch(lines, redacted []string) bool {
+	if len(lines) < len(redacted) {
+		return false
+	}
+	for i, line := range redacted {
+		if strings.TrimSuffix(lines[i], "\n") != line {
+			return false
+		}
+	}
+	return true
+}
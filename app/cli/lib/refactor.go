@@ -0,0 +1,153 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"plandex/fs"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// names in an instruction shorter than this are too likely to be ordinary words
+const refactorMinNameLen = 3
+
+var refactorNamePattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+type RefactorFile struct {
+	Path string
+	// why the file was selected, e.g. "defines parseConfig"
+	Reason string
+}
+
+// FindRefactorFiles selects the project files a refactor is likely to touch, most relevant first. Names in the instruction
+// that are defined in the project's code map select the files that define them and then the files that reference them.
+// A search of the project for the instruction adds files that are relevant without being named. Paths are relative to the
+// project root. hasDefs is whether any file in the project has definitions for a code map.
+func FindRefactorFiles(ctx context.Context, instruction string, limit int) (files []*RefactorFile, hasDefs bool, err error) {
+	paths, err := fs.GetProjectPaths(fs.ProjectRoot)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get project paths: %v", err)
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range refactorNamePattern.FindAllString(instruction, -1) {
+		if len(name) >= refactorMinNameLen {
+			wanted[name] = true
+		}
+	}
+
+	var sortedPaths []string
+	for path := range paths.ActivePaths {
+		sortedPaths = append(sortedPaths, path)
+	}
+	sort.Strings(sortedPaths)
+
+	selected := map[string]bool{}
+	add := func(path, reason string) {
+		if selected[path] || len(files) >= limit {
+			return
+		}
+		selected[path] = true
+		files = append(files, &RefactorFile{Path: path, Reason: reason})
+	}
+
+	var names []string
+	definedBy := map[string][]string{}
+
+	for _, path := range sortedPaths {
+		lang, ok := mapLanguagesByExt[strings.ToLower(filepath.Ext(path))]
+		if !ok {
+			continue
+		}
+
+		content, ok := readSearchableFile(filepath.Join(fs.ProjectRoot, path))
+		if !ok {
+			continue
+		}
+
+		defNames, err := getCodeMapDefNames(content, lang)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+
+		if len(defNames) > 0 {
+			hasDefs = true
+		}
+
+		for _, name := range defNames {
+			if !wanted[name] {
+				continue
+			}
+			if _, ok := definedBy[name]; !ok {
+				names = append(names, name)
+			}
+			definedBy[name] = append(definedBy[name], path)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, path := range definedBy[name] {
+			add(path, "defines "+name)
+		}
+	}
+
+	if len(names) > 0 {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = regexp.QuoteMeta(name)
+		}
+		refPattern := regexp.MustCompile(`\b(` + strings.Join(quoted, "|") + `)\b`)
+
+		type reference struct {
+			path  string
+			name  string
+			count int
+		}
+		var refs []*reference
+
+		for _, path := range sortedPaths {
+			if selected[path] {
+				continue
+			}
+
+			content, ok := readSearchableFile(filepath.Join(fs.ProjectRoot, path))
+			if !ok {
+				continue
+			}
+
+			matches := refPattern.FindAllString(string(content), -1)
+			if len(matches) == 0 {
+				continue
+			}
+			refs = append(refs, &reference{path: path, name: matches[0], count: len(matches)})
+		}
+
+		sort.SliceStable(refs, func(i, j int) bool {
+			return refs[i].count > refs[j].count
+		})
+
+		for _, ref := range refs {
+			add(ref.path, "references "+ref.name)
+		}
+	}
+
+	if len(files) < limit {
+		results, err := SearchProject(ctx, instruction, limit)
+		if err != nil {
+			return nil, false, err
+		}
+
+		for _, result := range results {
+			if result.Score < autoContextMinScore {
+				continue
+			}
+			add(result.Path, fmt.Sprintf("relevant (%.2f)", result.Score))
+		}
+	}
+
+	return files, hasDefs, nil
+}
@@ -0,0 +1,85 @@
+package lib
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// diffs bigger than this are too big to review in one go
+const maxReviewDiffBytes = 300000
+
+// GitReviewRange returns the commit log and the diff for a range to review. A range with .. or ... is used as with git
+// diff. A single revision, like a PR branch, is compared with the current branch from where they diverged.
+func GitReviewRange(repoDir, rng string) (log, diff string, err error) {
+	if strings.HasPrefix(rng, "-") {
+		return "", "", fmt.Errorf("invalid range: %s", rng)
+	}
+
+	gitMutex.Lock()
+	defer gitMutex.Unlock()
+
+	diffRange := rng
+	logRange := strings.Replace(rng, "...", "..", 1)
+	if !strings.Contains(rng, "..") {
+		diffRange = "HEAD..." + rng
+		logRange = "HEAD.." + rng
+	}
+
+	res, err := exec.Command("git", "-C", repoDir, "log", "--no-color", "--format=%h %s", logRange, "--").CombinedOutput()
+	if err != nil {
+		return "", "", fmt.Errorf("error getting commits for %s | err: %v, output: %s", rng, err, string(res))
+	}
+	log = strings.TrimSpace(string(res))
+
+	res, err = exec.Command("git", "-C", repoDir, "diff", "--no-color", "--no-ext-diff", diffRange, "--").CombinedOutput()
+	if err != nil {
+		return "", "", fmt.Errorf("error getting diff for %s | err: %v, output: %s", rng, err, string(res))
+	}
+	diff = string(res)
+
+	if len(diff) > maxReviewDiffBytes {
+		return "", "", fmt.Errorf("the diff for %s is too big to review (%d bytes, the limit is %d). Try a smaller range.", rng, len(diff), maxReviewDiffBytes)
+	}
+
+	return log, diff, nil
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// NumberDiffLines prefixes each line of a diff that's in the new version of a file with its line number, so findings
+// can refer to lines in the file
+func NumberDiffLines(diff string) string {
+	lines := strings.Split(diff, "\n")
+	newLine := 0
+	inHunk := false
+
+	for i, line := range lines {
+		if m := hunkHeaderPattern.FindStringSubmatch(line); m != nil {
+			newLine, _ = strconv.Atoi(m[1])
+			inHunk = true
+			continue
+		}
+
+		if strings.HasPrefix(line, "diff --git ") {
+			inHunk = false
+			continue
+		}
+
+		if !inHunk {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "+") || strings.HasPrefix(line, " "):
+			lines[i] = strconv.Itoa(newLine) + " | " + line
+			newLine++
+		case strings.HasPrefix(line, "-"):
+			lines[i] = "  | " + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
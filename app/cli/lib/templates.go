@@ -0,0 +1,170 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plandex/fs"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PromptTemplate is a saved prompt with {var} placeholders, stored as <name>.md in a templates dir
+type PromptTemplate struct {
+	Name string
+	Body string
+	Path string
+	// true for templates in the home dir, shared across projects
+	Global bool
+}
+
+var templateNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+var templateVarRegex = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_-]*)\}`)
+
+func homeTemplatesDir() string {
+	return filepath.Join(fs.HomePlandexDir, "templates")
+}
+
+func projectTemplatesDir() string {
+	if fs.PlandexDir == "" {
+		return ""
+	}
+	return filepath.Join(fs.PlandexDir, "templates")
+}
+
+// SaveTemplate saves a template to the project's .plandex/templates dir, or to the home dir if global is true
+func SaveTemplate(name, body string, global bool) (string, error) {
+	if !templateNameRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid template name %q (use letters, numbers, dashes, and underscores)", name)
+	}
+
+	dir := homeTemplatesDir()
+	if !global {
+		dir = projectTemplatesDir()
+		if dir == "" {
+			return "", fmt.Errorf("no project found, use --global to save a home-level template")
+		}
+	}
+
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return "", fmt.Errorf("error creating templates dir: %v", err)
+	}
+
+	path := filepath.Join(dir, name+".md")
+	err = os.WriteFile(path, []byte(body), 0644)
+	if err != nil {
+		return "", fmt.Errorf("error writing template: %v", err)
+	}
+
+	return path, nil
+}
+
+// ListTemplates returns templates from the home dir and the current project sorted by name, with a project template taking the place of a home template with the same name
+func ListTemplates() ([]*PromptTemplate, error) {
+	byName := map[string]*PromptTemplate{}
+
+	dirs := []string{homeTemplatesDir()}
+	if dir := projectTemplatesDir(); dir != "" {
+		dirs = append(dirs, dir)
+	}
+
+	for i, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading templates dir: %v", err)
+		}
+
+		for _, entry := range entries {
+			name, ok := strings.CutSuffix(entry.Name(), ".md")
+			if entry.IsDir() || !ok {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			bytes, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("error reading template %s: %v", name, err)
+			}
+
+			byName[name] = &PromptTemplate{
+				Name:   name,
+				Body:   string(bytes),
+				Path:   path,
+				Global: i == 0,
+			}
+		}
+	}
+
+	var res []*PromptTemplate
+	for _, t := range byName {
+		res = append(res, t)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res, nil
+}
+
+func GetTemplate(name string) (*PromptTemplate, error) {
+	templates, err := ListTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("template %q not found", name)
+}
+
+// Vars returns the names of the template's {var} placeholders in the order they first appear
+func (t *PromptTemplate) Vars() []string {
+	var vars []string
+	seen := map[string]bool{}
+	for _, match := range templateVarRegex.FindAllStringSubmatch(t.Body, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			vars = append(vars, match[1])
+		}
+	}
+	return vars
+}
+
+// Render replaces the template's placeholders with values. Every placeholder must have a value.
+func (t *PromptTemplate) Render(values map[string]string) (string, error) {
+	var missing []string
+	for _, v := range t.Vars() {
+		if _, ok := values[v]; !ok {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing values for %s (set with --var name=value)", strings.Join(missing, ", "))
+	}
+
+	return templateVarRegex.ReplaceAllStringFunc(t.Body, func(match string) string {
+		return values[match[1:len(match)-1]]
+	}), nil
+}
+
+// ParseTemplateVars parses name=value pairs as given with --var
+func ParseTemplateVars(pairs []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --var %q (expected name=value)", pair)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
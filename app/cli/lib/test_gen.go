@@ -0,0 +1,160 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plandex/fs"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type TestFramework struct {
+	Name    string
+	Command string
+}
+
+// DetectTestFramework guesses the project's test framework from its manifests. It returns nil if there's no manifest
+// it recognizes.
+func DetectTestFramework() *TestFramework {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(fs.ProjectRoot, name))
+		return err == nil
+	}
+
+	if exists("package.json") {
+		bytes, err := os.ReadFile(filepath.Join(fs.ProjectRoot, "package.json"))
+		if err == nil {
+			var pkg struct {
+				Dependencies    map[string]string `json:"dependencies"`
+				DevDependencies map[string]string `json:"devDependencies"`
+			}
+			if json.Unmarshal(bytes, &pkg) == nil {
+				for _, candidate := range []TestFramework{
+					{Name: "vitest", Command: "npx vitest run"},
+					{Name: "jest", Command: "npx jest"},
+					{Name: "mocha", Command: "npx mocha"},
+					{Name: "ava", Command: "npx ava"},
+				} {
+					_, dep := pkg.Dependencies[candidate.Name]
+					_, devDep := pkg.DevDependencies[candidate.Name]
+					if dep || devDep {
+						return &candidate
+					}
+				}
+			}
+		}
+	}
+
+	switch {
+	case exists("go.mod"):
+		return &TestFramework{Name: "go test", Command: "go test ./..."}
+	case exists("Cargo.toml"):
+		return &TestFramework{Name: "cargo test", Command: "cargo test"}
+	case exists("pytest.ini") || exists("conftest.py") || exists("pyproject.toml") || exists("setup.cfg") || exists("tox.ini"):
+		return &TestFramework{Name: "pytest", Command: "pytest"}
+	case exists("pom.xml"):
+		return &TestFramework{Name: "JUnit", Command: "mvn test"}
+	case exists("build.gradle") || exists("build.gradle.kts"):
+		return &TestFramework{Name: "JUnit", Command: "./gradlew test"}
+	case exists("Gemfile"):
+		return &TestFramework{Name: "RSpec", Command: "bundle exec rspec"}
+	}
+
+	return nil
+}
+
+var testFilePattern = regexp.MustCompile(`(_test\.go$|\.(test|spec)\.[cm]?[jt]sx?$|(^|/)test_[^/]*\.py$|_test\.py$|Tests?\.java$|_spec\.rb$|(^|/)(tests?|__tests__|spec)/)`)
+
+func IsTestFile(path string) bool {
+	return testFilePattern.MatchString(filepath.ToSlash(path))
+}
+
+// FindExampleTests returns up to limit of the project's existing tests to show the model its conventions, preferring
+// tests in the same language and closest to the given source files. Paths are relative to the project root.
+func FindExampleTests(sourcePaths []string, limit int) ([]string, error) {
+	paths, err := fs.GetProjectPaths(fs.ProjectRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project paths: %v", err)
+	}
+
+	exts := map[string]bool{}
+	dirs := map[string]bool{}
+	parentDirs := map[string]bool{}
+	for _, path := range sourcePaths {
+		exts[strings.ToLower(filepath.Ext(path))] = true
+		dirs[filepath.Dir(path)] = true
+		parentDirs[filepath.Dir(filepath.Dir(path))] = true
+	}
+
+	type candidate struct {
+		path  string
+		score int
+	}
+	var candidates []*candidate
+
+	for path := range paths.ActivePaths {
+		if !IsTestFile(path) || !exts[strings.ToLower(filepath.Ext(path))] {
+			continue
+		}
+
+		score := 0
+		if dirs[filepath.Dir(path)] {
+			score += 2
+		}
+		// a test in a parallel dir (like tests/ for src/) shares a parent with the source
+		if parentDirs[filepath.Dir(filepath.Dir(path))] {
+			score++
+		}
+		candidates = append(candidates, &candidate{path: path, score: score})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].path < candidates[j].path
+	})
+
+	var res []string
+	for _, c := range candidates {
+		if len(res) >= limit {
+			break
+		}
+		res = append(res, c.path)
+	}
+
+	return res, nil
+}
+
+// GetDefNames returns the names of the definitions in a source file, as listed in its code map. It returns nil for
+// files in languages the code map doesn't support.
+func GetDefNames(path string) ([]string, error) {
+	lang, ok := mapLanguagesByExt[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(fs.ProjectRoot, path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	names, err := getCodeMapDefNames(content, lang)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	seen := map[string]bool{}
+	var res []string
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			res = append(res, name)
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,64 @@
+package lib
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"plandex/fs"
+	"strconv"
+	"strings"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// TokenCountCache keeps token counts in the cache dir as one file per tokenizer and sha, so the same content
+// isn't tokenized again by later commands. Like the context cache, errors writing to it are logged rather than returned.
+type TokenCountCache struct{}
+
+func (TokenCountCache) path(sha string, tokenizer shared.TokenizerName) string {
+	return filepath.Join(fs.CacheDir, "tokens", string(tokenizer), sha)
+}
+
+func (c TokenCountCache) GetTokenCount(sha string, tokenizer shared.TokenizerName) (int, bool) {
+	content, err := os.ReadFile(c.path(sha, tokenizer))
+	if err != nil {
+		return 0, false
+	}
+
+	numTokens, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0, false
+	}
+	return numTokens, true
+}
+
+func (c TokenCountCache) SetTokenCount(sha string, tokenizer shared.TokenizerName, numTokens int) {
+	path := c.path(sha, tokenizer)
+	dir := filepath.Dir(path)
+
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		log.Printf("Error creating token count cache dir: %v\n", err)
+		return
+	}
+
+	// written to a temp file and renamed so a concurrent command never reads a partial count
+	tmp, err := os.CreateTemp(dir, ".tmp-")
+	if err != nil {
+		log.Printf("Error writing token count cache: %v\n", err)
+		return
+	}
+
+	_, err = tmp.WriteString(strconv.Itoa(numTokens))
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Printf("Error writing token count cache: %v\n", err)
+	}
+}
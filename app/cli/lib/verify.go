@@ -0,0 +1,122 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"plandex/api"
+	"plandex/fs"
+	"plandex/term"
+
+	"github.com/fatih/color"
+)
+
+// MustVerifyPlan runs a verify command, like a build or test command, from the project root with the plan's pending
+// changes written to the project files, and then restores the files. It returns the command's output and exit code.
+//
+// While the command runs, the files are recorded like an apply, so if the restore doesn't happen, 'plandex rollback'
+// can still undo them.
+func MustVerifyPlan(planId, branch, command string) (string, int) {
+	term.StartSpinner("")
+	currentPlanState, apiErr := api.Client.GetCurrentPlanState(planId, branch)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error getting current plan state: %v", apiErr.Msg)
+	}
+
+	toApply := currentPlanState.CurrentPlanFiles.Files
+
+	mergeRes, err := mergeLocalChanges(currentPlanState.ContextsByPath, toApply)
+	if err != nil {
+		term.OutputErrorAndExit("failed to merge local changes: %v", err)
+	}
+
+	beforeByPath := map[string]*string{}
+	writtenByPath := map[string]string{}
+	for path, content := range toApply {
+		content = unescapePlanFileContent(content)
+		// conflict markers would fail the command for the wrong reason, so conflicted files get the plan's version
+		if merged, ok := mergeRes.contentByPath[path]; ok && mergeRes.conflictsByPath[path] == 0 {
+			content = merged
+		}
+
+		bytes, err := os.ReadFile(filepath.Join(fs.ProjectRoot, path))
+		if err == nil {
+			if string(bytes) == content {
+				continue
+			}
+			before := string(bytes)
+			beforeByPath[path] = &before
+		} else if os.IsNotExist(err) {
+			beforeByPath[path] = nil
+		} else {
+			term.OutputErrorAndExit("failed to read %s: %v", path, err)
+		}
+
+		writtenByPath[path] = content
+	}
+
+	var tx *ApplyTransaction
+	if len(writtenByPath) > 0 {
+		tx, err = recordApply(planId, branch, beforeByPath, writtenByPath)
+		if err != nil {
+			term.OutputErrorAndExit("Error recording files before verifying: %v", err)
+		}
+	}
+
+	// an interrupt stops the command, but the files still need restoring before exiting
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+
+	restore := func() {
+		if tx == nil {
+			return
+		}
+		_, err := RollbackApplies([]*ApplyTransaction{tx})
+		if err != nil {
+			term.OutputErrorAndExit("Error restoring files after verifying: %v\n\nRun 'plandex rollback' to restore them", err)
+		}
+	}
+
+	for path, content := range writtenByPath {
+		dstPath := filepath.Join(fs.ProjectRoot, path)
+
+		err := os.MkdirAll(filepath.Dir(dstPath), 0755)
+		if err == nil {
+			err = os.WriteFile(dstPath, []byte(content), 0644)
+		}
+		if err != nil {
+			restore()
+			term.OutputErrorAndExit("failed to write %s: %v", path, err)
+		}
+	}
+
+	fmt.Println()
+	color.New(color.Bold, term.ColorHiCyan).Printf("🔎 Verifying with %s\n", command)
+
+	output, exitCode, err := runHook(command)
+
+	restore()
+
+	if err != nil {
+		term.OutputErrorAndExit("Error running %s: %v", command, err)
+	}
+
+	select {
+	case <-sigCh:
+		fmt.Println()
+		fmt.Println("🛑 Verify stopped")
+		os.Exit(term.ExitError)
+	default:
+	}
+
+	return output, exitCode
+}
+
+// MustLoadVerifyFailure loads the output of a failed verify command into context
+func MustLoadVerifyFailure(command, output string, exitCode int) {
+	mustLoadCommandOutput(command, output, exitCode, "with the plan's pending changes written to the project files")
+}
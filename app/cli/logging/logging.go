@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"plandex/fs"
+	"sync"
+)
+
+// when the log file gets bigger than this, it's moved to plandex.log.1, replacing the previous one
+const maxLogFileSize = 10 * 1024 * 1024
+
+// set by PLANDEX_DEBUG=1 as well as --debug, so logs can be turned up for commands run by other tools
+const DebugEnvVar = "PLANDEX_DEBUG"
+
+var level = new(slog.LevelVar)
+var out = &logWriter{}
+var base *slog.Logger
+
+func LogPath() string {
+	return filepath.Join(fs.HomePlandexDir, "plandex.log")
+}
+
+// Init makes a leveled, structured slog logger that writes to the log file the default. Output from the log package goes
+// through it too, at the info level.
+func Init() error {
+	path := LogPath()
+
+	info, err := os.Stat(path)
+	if err == nil && info.Size() > maxLogFileSize {
+		err = os.Rename(path, path+".1")
+		if err != nil {
+			return fmt.Errorf("error rotating log file: %v", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening log file: %v", err)
+	}
+	out.file = file
+
+	// so commands that fail before flags are parsed still log at the debug level
+	if os.Getenv(DebugEnvVar) != "" {
+		SetVerbosity(false, true)
+	}
+
+	base = slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: level}))
+	slog.SetDefault(base)
+
+	return nil
+}
+
+// SetVerbosity is called once flags are parsed. Verbose also writes logs to stderr, and debug adds debug logs, like each API
+// request, on top of that.
+func SetVerbosity(verbose, debug bool) {
+	if debug {
+		level.Set(slog.LevelDebug)
+	}
+	out.setMirror(verbose || debug)
+}
+
+// SetPlan adds the current plan and branch to everything logged after it's called
+func SetPlan(planId, branch string) {
+	if base == nil || planId == "" {
+		return
+	}
+	slog.SetDefault(base.With("plan_id", planId, "branch", branch))
+}
+
+// NewRequestId makes an id for an API request, which the server logs too
+func NewRequestId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type logWriter struct {
+	mu     sync.Mutex
+	file   *os.File
+	mirror bool
+}
+
+func (w *logWriter) setMirror(mirror bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.mirror = mirror
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.mirror {
+		os.Stderr.Write(p)
+	}
+	if w.file == nil {
+		return len(p), nil
+	}
+	return w.file.Write(p)
+}
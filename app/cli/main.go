@@ -1,23 +1,24 @@
 package main
 
 import (
-	"log"
 	"os"
-	"path/filepath"
 	"plandex/api"
 	"plandex/auth"
 	"plandex/cmd"
-	"plandex/fs"
 	"plandex/lib"
+	"plandex/logging"
 	"plandex/plan_exec"
 	"plandex/term"
+	"strings"
 
 	"github.com/plandex/plandex/shared"
+	"github.com/spf13/cobra"
 )
 
 func init() {
 	// inter-package dependency injections to avoid circular imports
 	auth.SetApiClient(api.Client)
+	shared.SetTokenCountCache(lib.TokenCountCache{})
 	lib.SetBuildPlanInlineFn(func(maybeContexts []*shared.Context) (bool, error) {
 		return plan_exec.Build(plan_exec.ExecParams{
 			CurrentPlanId: lib.CurrentPlanId,
@@ -27,23 +28,36 @@ func init() {
 			},
 		}, false)
 	})
+	lib.SetTellPlanInlineFn(func(prompt string) {
+		plan_exec.TellPlan(plan_exec.ExecParams{
+			CurrentPlanId: lib.CurrentPlanId,
+			CurrentBranch: lib.CurrentBranch,
+			CheckOutdatedContext: func(maybeContexts []*shared.Context) (bool, bool) {
+				return lib.MustCheckOutdatedContextBeforePrompt(maybeContexts)
+			},
+		}, prompt, term.NonInteractive, false, false, false)
+	})
 
-	// set up a file logger
-	// TODO: log rotation
-
-	file, err := os.OpenFile(filepath.Join(fs.HomePlandexDir, "plandex.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	err := logging.Init()
 	if err != nil {
-		term.OutputErrorAndExit("Error opening log file: %v", err)
+		term.OutputErrorAndExit("Error initializing logging: %v", err)
 	}
-
-	// Set the output of the logger to the file
-	log.SetOutput(file)
-
-	// log.Println("Starting Plandex - logging initialized")
 }
 
 func main() {
-	checkForUpgrade()
+	// flags aren't parsed until Execute, so --ci is checked here to skip the upgrade prompt
+	for _, arg := range os.Args[1:] {
+		if arg == "--ci" {
+			term.NonInteractive = true
+		}
+	}
+
+	// the shell reads completions from stdout, so nothing else can be output
+	isCompletion := len(os.Args) > 1 && strings.HasPrefix(os.Args[1], cobra.ShellCompRequestCmd)
+
+	if !isCompletion {
+		checkForUpgrade()
+	}
 
 	// Manually check for help flags at the root level
 	if len(os.Args) == 2 && (os.Args[1] == "-h" || os.Args[1] == "--help") {
@@ -6,4 +6,6 @@ type ExecParams struct {
 	CurrentPlanId        string
 	CurrentBranch        string
 	CheckOutdatedContext func(maybeContexts []*shared.Context) (bool, bool)
+	// called when a plan streamed in the foreground finishes, before the command exits
+	OnFinish func()
 }
@@ -7,9 +7,11 @@ import (
 	"plandex/api"
 	"plandex/auth"
 	"plandex/fs"
+	"plandex/lib"
 	"plandex/stream"
 	streamtui "plandex/stream_tui"
 	"plandex/term"
+	"strings"
 
 	"github.com/plandex/plandex/shared"
 )
@@ -22,14 +24,26 @@ func TellPlan(
 	tellNoBuild,
 	isUserContinue bool,
 ) {
+	// scripts get a network error exit code instead
+	canQueue := !term.NonInteractive
+
 	term.StartSpinner("")
 	contexts, apiErr := api.Client.ListContext(params.CurrentPlanId, params.CurrentBranch)
 
+	// when the server can't be reached, the prompt is queued without checking for outdated context
+	var offlineErr *shared.ApiError
 	if apiErr != nil {
-		term.OutputErrorAndExit("Error getting context: %v", apiErr)
+		if canQueue && lib.IsOfflineErr(apiErr) {
+			offlineErr = apiErr
+		} else {
+			term.OutputErrorAndExit("Error getting context: %v", apiErr)
+		}
 	}
 
-	anyOutdated, didUpdate := params.CheckOutdatedContext(contexts)
+	var anyOutdated, didUpdate bool
+	if offlineErr == nil {
+		anyOutdated, didUpdate = params.CheckOutdatedContext(contexts)
+	}
 
 	if anyOutdated && !didUpdate {
 		term.StopSpinner()
@@ -47,6 +61,16 @@ func TellPlan(
 		term.OutputErrorAndExit("Error getting project paths: %v", err)
 	}
 
+	// no one is watching a plan in the background, so chat channels from config are notified when it finishes
+	var notify []*shared.NotifyTarget
+	if tellBg {
+		config, err := lib.LoadConfig()
+		if err != nil {
+			term.OutputErrorAndExit("Error loading config: %v", err)
+		}
+		notify = lib.ConfigNotifyTargets(config)
+	}
+
 	var fn func() bool
 	fn = func() bool {
 
@@ -63,7 +87,7 @@ func TellPlan(
 			term.StartSpinner("💬 Sending prompt...")
 		}
 
-		apiErr := api.Client.TellPlan(params.CurrentPlanId, params.CurrentBranch, shared.TellPlanRequest{
+		req := shared.TellPlanRequest{
 			Prompt:         prompt,
 			ConnectStream:  !tellBg,
 			AutoContinue:   !tellStop,
@@ -71,13 +95,25 @@ func TellPlan(
 			BuildMode:      buildMode,
 			IsUserContinue: isUserContinue,
 			ApiKey:         os.Getenv("OPENAI_API_KEY"),
-		}, stream.OnStreamPlan)
+			Notify:         notify,
+		}
+
+		if offlineErr != nil {
+			queueTell(req, offlineErr)
+		}
+
+		apiErr := api.Client.TellPlan(params.CurrentPlanId, params.CurrentBranch, req, stream.OnStreamPlan)
 
 		term.StopSpinner()
 
+		if apiErr != nil && canQueue && lib.IsOfflineErr(apiErr) {
+			queueTell(req, apiErr)
+		}
+
 		if apiErr != nil {
 			if apiErr.Type == shared.ApiErrorTypeTrialMessagesExceeded {
-				fmt.Fprintf(os.Stderr, "\n🚨 You've reached the Plandex Cloud anonymous trial limit of %d messages per plan\n", apiErr.TrialMessagesExceededError.MaxReplies)
+				fmt.Fprintln(os.Stderr)
+				term.OutputSimpleError("You've reached the Plandex Cloud anonymous trial limit of %d messages per plan", apiErr.TrialMessagesExceededError.MaxReplies)
 
 				res, err := term.ConfirmYesNo("Upgrade to an unlimited free account?")
 
@@ -96,7 +132,7 @@ func TellPlan(
 				return false
 			}
 
-			term.OutputErrorAndExit("Prompt error: %v", apiErr.Msg)
+			term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Prompt error: %v", apiErr.Msg)
 		} else if apiErr != nil && isUserContinue && apiErr.Type == shared.ApiErrorTypeContinueNoMessages {
 			fmt.Println("🤷‍♂️ There's no plan yet to continue")
 			fmt.Println()
@@ -114,6 +150,10 @@ func TellPlan(
 
 				fmt.Println()
 
+				if params.OnFinish != nil {
+					params.OnFinish()
+				}
+
 				if tellStop {
 					term.PrintCmds("", "continue", "changes", "apply", "log", "rewind")
 				} else {
@@ -133,6 +173,13 @@ func TellPlan(
 
 	if tellBg {
 		fmt.Println("✅ Plan is active in the background")
+		if len(notify) > 0 {
+			var names []string
+			for _, target := range notify {
+				names = append(names, shared.Capitalize(string(target.Type)))
+			}
+			fmt.Printf("🔔 %s will be notified when it finishes\n", strings.Join(names, " and "))
+		}
 		fmt.Println()
 		term.PrintCmds("", "ps", "connect", "stop")
 	} else {
@@ -140,3 +187,29 @@ func TellPlan(
 		select {}
 	}
 }
+
+// queueTell queues the prompt to send once the server and model providers can be reached, then exits
+func queueTell(req shared.TellPlanRequest, apiErr *shared.ApiError) {
+	term.StopSpinner()
+	log.Printf("Queueing prompt: %s\n", apiErr.Msg)
+
+	op, err := lib.QueueTell(req)
+	if err != nil {
+		term.OutputErrorAndExit("Error queueing prompt: %v", err)
+	}
+
+	if term.JsonOutput {
+		term.OutputJson(op)
+		os.Exit(0)
+	}
+
+	reason := "Couldn't reach the server"
+	if apiErr.Type == shared.ApiErrorTypeProviderUnavailable {
+		reason = "The plan's model providers are failing"
+	}
+
+	fmt.Println(term.Plain(fmt.Sprintf("📴 %s, so the %s is queued. It'll be sent by the next command once it can go through, and run in the background.", reason, lib.QueuedOpDesc(op))))
+	fmt.Println()
+	term.PrintCmds("", "ps", "sync")
+	os.Exit(0)
+}
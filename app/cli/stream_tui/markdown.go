@@ -0,0 +1,152 @@
+package streamtui
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+)
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// markdownRenderer renders a reply as markdown as it streams. Replies only grow, so everything before the last blank line
+// that's outside a code block is finished: it's rendered once and kept, and only the block that's still streaming is rendered
+// again for each chunk. An unclosed code block is closed for rendering so its code is highlighted as it arrives.
+type markdownRenderer struct {
+	// glamour style name, picked before the UI starts since detecting the terminal's background reads from stdin
+	style string
+
+	width    int
+	renderer *glamour.TermRenderer
+
+	// the finished part of the reply, and its rendered output
+	done         string
+	doneRendered string
+}
+
+func newMarkdownRenderer(style string) *markdownRenderer {
+	return &markdownRenderer{style: style}
+}
+
+func (r *markdownRenderer) render(reply string, width int) string {
+	if r.renderer == nil || width != r.width {
+		renderer, err := glamour.NewTermRenderer(
+			glamour.WithStandardStyle(r.style),
+			glamour.WithWordWrap(width),
+		)
+		if err != nil {
+			log.Println("error creating markdown renderer:", err)
+			return reply
+		}
+		r.renderer = renderer
+		r.width = width
+		r.done = ""
+		r.doneRendered = ""
+	}
+
+	// the reply can be cut back, like when a file that's missing from context is skipped
+	if !strings.HasPrefix(reply, r.done) {
+		r.done = ""
+		r.doneRendered = ""
+	}
+
+	rest := reply[len(r.done):]
+	if n := finishedBlocksLen(rest); n > 0 {
+		r.doneRendered += r.renderBlocks(rest[:n], r.done == "")
+		r.done += rest[:n]
+		rest = rest[n:]
+	}
+
+	if rest == "" {
+		return r.doneRendered
+	}
+
+	if fence := openFence(rest); fence != "" {
+		rest += "\n" + fence
+	}
+
+	return r.doneRendered + r.renderBlocks(rest, r.done == "")
+}
+
+// each render starts with blank lines (more of them before a list or code block), which are only kept for the first blocks
+// since the blocks before already end with one
+func (r *markdownRenderer) renderBlocks(s string, first bool) string {
+	out, err := r.renderer.Render(s)
+	if err != nil {
+		log.Println("error rendering markdown:", err)
+		return s
+	}
+
+	if !first {
+		// blank lines are padded with spaces to the wrap width, and can have color codes
+		for {
+			i := strings.IndexByte(out, '\n')
+			if i == -1 || strings.TrimSpace(ansiEscape.ReplaceAllString(out[:i], "")) != "" {
+				break
+			}
+			out = out[i+1:]
+		}
+	}
+
+	return out
+}
+
+// finishedBlocksLen is the length of the start of s that ends in a blank line outside a code block and is followed by an
+// unindented line, so the blocks before it can't change as more of the reply arrives. An indented line could still belong to
+// the block before the blank line, like the next paragraph of a list item.
+func finishedBlocksLen(s string) int {
+	finished := 0
+	fence := ""
+	pos := 0
+
+	for {
+		i := strings.IndexByte(s[pos:], '\n')
+		if i == -1 {
+			return finished
+		}
+		line := s[pos : pos+i]
+		pos += i + 1
+
+		fence = nextFence(fence, line)
+
+		if fence == "" && strings.TrimSpace(line) == "" && pos < len(s) && !strings.ContainsRune(" \t\n", rune(s[pos])) {
+			finished = pos
+		}
+	}
+}
+
+// openFence returns the marker of a code block that's still open at the end of s, or "" if there isn't one
+func openFence(s string) string {
+	fence := ""
+	for _, line := range strings.Split(s, "\n") {
+		fence = nextFence(fence, line)
+	}
+	return fence
+}
+
+// nextFence returns the code block marker that's open after line, given the one that was open before it
+func nextFence(fence, line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return fence
+	}
+
+	for _, c := range []string{"`", "~"} {
+		marker := trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, c))]
+		if len(marker) < 3 {
+			continue
+		}
+
+		if fence == "" {
+			return marker
+		}
+
+		// a closing marker is at least as long as the opening one and has nothing after it
+		if strings.HasPrefix(marker, fence) && strings.TrimSpace(trimmed[len(marker):]) == "" {
+			return ""
+		}
+	}
+
+	return fence
+}
@@ -1,11 +1,15 @@
 package streamtui
 
 import (
+	"time"
+
 	bubbleKey "github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/fatih/color"
+	"github.com/muesli/termenv"
 	"github.com/plandex/plandex/shared"
 )
 
@@ -33,6 +37,7 @@ type streamUIModel struct {
 
 	reply       string
 	mainDisplay string
+	markdown    *markdownRenderer
 
 	mainViewport viewport.Model
 
@@ -43,6 +48,7 @@ type streamUIModel struct {
 	building       bool
 	tokensByPath   map[string]int
 	finishedByPath map[string]bool
+	queuedByPath   map[string]bool
 
 	ready  bool
 	width  int
@@ -59,6 +65,12 @@ type streamUIModel struct {
 
 	prompt string
 
+	// running totals for the footer, estimated the same way the server records usage
+	startedAt    time.Time
+	modelsByRole map[shared.ModelRole]string
+	outputTokens int
+	costUsd      float64
+
 	stopped    bool
 	background bool
 	finished   bool
@@ -84,8 +96,8 @@ type keymap = struct {
 func (m streamUIModel) Init() tea.Cmd {
 	m.mainViewport.MouseWheelEnabled = true
 
-	// start spinner
-	return m.spinner.Tick
+	// start spinner and elapsed time
+	return tea.Batch(m.spinner.Tick, usageTick())
 }
 
 func initialModel(prestartReply, prompt string, buildOnly bool) *streamUIModel {
@@ -99,12 +111,12 @@ func initialModel(prestartReply, prompt string, buildOnly bool) *streamUIModel {
 		reply:     prestartReply,
 		keymap: keymap{
 			quit: bubbleKey.NewBinding(
-				bubbleKey.WithKeys("b", "ctrl+c"),
-				bubbleKey.WithHelp("ctrl+c", "quit"),
+				bubbleKey.WithKeys("b"),
+				bubbleKey.WithHelp("b", "background"),
 			),
 
 			stop: bubbleKey.NewBinding(
-				bubbleKey.WithKeys("s"),
+				bubbleKey.WithKeys("s", "ctrl+c"),
 				bubbleKey.WithHelp("s", "stop"),
 			),
 
@@ -156,10 +168,24 @@ func initialModel(prestartReply, prompt string, buildOnly bool) *streamUIModel {
 
 		tokensByPath:   make(map[string]int),
 		finishedByPath: make(map[string]bool),
+		queuedByPath:   make(map[string]bool),
 		spinner:        s,
 		atScrollBottom: true,
 		starting:       true,
+		startedAt:      time.Now(),
+		modelsByRole:   make(map[shared.ModelRole]string),
+		markdown:       newMarkdownRenderer(markdownStyle()),
 	}
 
 	return &initialState
 }
+
+func markdownStyle() string {
+	if color.NoColor {
+		return "notty"
+	}
+	if termenv.HasDarkBackground() {
+		return "dark"
+	}
+	return "light"
+}
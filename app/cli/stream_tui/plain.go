@@ -0,0 +1,156 @@
+package streamtui
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"plandex/api"
+	"plandex/lib"
+	"plandex/term"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/plandex/plandex/shared"
+)
+
+// PlainOutput is set by the --plain flag. Instead of the stream UI, the reply is written to stdout as raw text as it streams,
+// which is also what happens when stdout isn't a terminal, like when it's piped to a file or another command.
+var PlainOutput bool
+
+var plainCh chan shared.StreamMessage
+var plainDone chan struct{}
+
+func usePlainOutput() bool {
+	return PlainOutput || !term.IsStdoutTerminal()
+}
+
+// streamPlain prints the stream until it finishes. Build progress goes to stderr so stdout is just the reply.
+func streamPlain() error {
+	mu.Lock()
+	plainCh = make(chan shared.StreamMessage)
+	plainDone = make(chan struct{})
+	mu.Unlock()
+
+	defer func() {
+		mu.Lock()
+		close(plainDone)
+		plainCh = nil
+		plainDone = nil
+		mu.Unlock()
+	}()
+
+	fmt.Print(prestartReply)
+	prestartReply = ""
+	prestartReplyChunks = 0
+	prestartUsage = nil
+
+	// like the stream UI, replies after the first are separated, unless the reply is continuing after a missing file prompt
+	describing := false
+	promptedMissingFile := false
+
+	// ctrl+c stops the plan like in the stream UI, and a second one exits without waiting for the server
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+	stopping := false
+
+	for {
+		var msg shared.StreamMessage
+		select {
+		case <-sigCh:
+			if stopping {
+				os.Exit(0)
+			}
+			stopping = true
+			apiErr := api.Client.StopPlan(lib.CurrentPlanId, lib.CurrentBranch)
+			if apiErr != nil {
+				fmt.Println()
+				term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error stopping plan: %s", apiErr.Msg)
+			}
+			continue
+		case msg = <-plainCh:
+		}
+
+		switch msg.Type {
+
+		case shared.StreamMessageConnectActive:
+			fmt.Print(strings.Join(msg.InitReplies, "\n\n"))
+			if msg.MissingFilePath != "" {
+				promptedMissingFile = true
+				promptMissingFilePlain(msg.MissingFilePath)
+			}
+
+		case shared.StreamMessagePromptMissingFile:
+			promptedMissingFile = true
+			promptMissingFilePlain(msg.MissingFilePath)
+
+		case shared.StreamMessageReply:
+			if describing {
+				describing = false
+				if promptedMissingFile {
+					promptedMissingFile = false
+				} else {
+					fmt.Print("\n\n")
+				}
+			}
+			fmt.Print(msg.ReplyChunk)
+
+		case shared.StreamMessageBuildInfo:
+			if msg.BuildInfo.Finished {
+				fmt.Fprintln(os.Stderr, term.Plain("✅ Built "+msg.BuildInfo.Path))
+			}
+
+		case shared.StreamMessageDescribing:
+			describing = true
+
+		case shared.StreamMessageError:
+			fmt.Println()
+			term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(msg.Error), "Server error: %s", msg.Error.Msg)
+
+		case shared.StreamMessageFinished:
+			fmt.Println()
+			return nil
+
+		case shared.StreamMessageAborted:
+			fmt.Println()
+			fmt.Println()
+			color.New(color.BgBlack, color.Bold, color.FgHiRed).Println(" 🛑 Stopped early ")
+			fmt.Println()
+			term.PrintCmds("", "continue", "status", "changes", "log", "rewind")
+			os.Exit(0)
+		}
+	}
+}
+
+func promptMissingFilePlain(path string) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		term.OutputErrorAndExit("failed to read file: %v", err)
+	}
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, term.Plain("📄 "+path+" isn't in context. Unless you load it into context or skip generating it, Plandex will fully overwrite the existing file rather than applying updates."))
+	selected, err := term.SelectFromList("What do you want to do?", missingFileSelectOpts)
+	if err != nil {
+		term.OutputErrorAndExit("Error selecting option: %v", err)
+	}
+
+	var choice shared.RespondMissingFileChoice
+	for i, opt := range missingFileSelectOpts {
+		if opt == selected {
+			choice = promptChoices[i]
+		}
+	}
+
+	apiErr := api.Client.RespondMissingFile(lib.CurrentPlanId, lib.CurrentBranch, shared.RespondMissingFileRequest{
+		Choice:   choice,
+		FilePath: path,
+		Body:     string(bytes),
+	})
+	if apiErr != nil {
+		log.Println("missing file prompt api error:", apiErr)
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(apiErr), "Error responding to missing file prompt: %s", apiErr.Msg)
+	}
+}
@@ -17,12 +17,14 @@ var mu sync.Mutex
 var wg sync.WaitGroup
 
 var prestartReply string
+var prestartReplyChunks int
+var prestartUsage []*shared.StreamUsage
 var prestartErr *shared.ApiError
 var prestartAbort bool
 
 func StartStreamUI(prompt string, buildOnly bool) error {
 	if prestartErr != nil {
-		term.OutputErrorAndExit("Server error: " + prestartErr.Msg)
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(prestartErr), "Server error: %s", prestartErr.Msg)
 	}
 
 	if prestartAbort {
@@ -30,7 +32,15 @@ func StartStreamUI(prompt string, buildOnly bool) error {
 		os.Exit(0)
 	}
 
+	if usePlainOutput() {
+		return streamPlain()
+	}
+
 	initial := initialModel(prestartReply, prompt, buildOnly)
+	for _, usage := range prestartUsage {
+		initial.addStreamUsage(usage)
+	}
+	initial.addOutputTokens(shared.ModelRolePlanner, prestartReplyChunks)
 
 	mu.Lock()
 	ui = tea.NewProgram(initial, tea.WithAltScreen())
@@ -40,6 +50,14 @@ func StartStreamUI(prompt string, buildOnly bool) error {
 	m, err := ui.Run()
 	wg.Done()
 
+	// another plan can stream in the same command after this one, like when a failed verify is fixed, so it starts fresh
+	mu.Lock()
+	ui = nil
+	mu.Unlock()
+	prestartReply = ""
+	prestartReplyChunks = 0
+	prestartUsage = nil
+
 	if err != nil {
 		return fmt.Errorf("error running stream UI: %v", err)
 	}
@@ -70,14 +88,14 @@ func StartStreamUI(prompt string, buildOnly bool) error {
 
 	if mod.apiErr != nil {
 		fmt.Println()
-		term.OutputErrorAndExit("Server error: " + mod.apiErr.Msg)
+		term.OutputErrorAndExitWithCode(term.ApiErrorExitCode(mod.apiErr), "Server error: %s", mod.apiErr.Msg)
 	}
 
 	if mod.stopped {
 		fmt.Println()
 		color.New(color.BgBlack, color.Bold, color.FgHiRed).Println(" 🛑 Stopped early ")
 		fmt.Println()
-		term.PrintCmds("", "log", "rewind", "tell")
+		term.PrintCmds("", "continue", "status", "changes", "log", "rewind")
 		os.Exit(0)
 	} else if mod.background {
 		fmt.Println()
@@ -104,6 +122,17 @@ func Quit() {
 }
 
 func Send(msg shared.StreamMessage) {
+	mu.Lock()
+	ch, done := plainCh, plainDone
+	mu.Unlock()
+	if ch != nil {
+		select {
+		case ch <- msg:
+		case <-done:
+		}
+		return
+	}
+
 	if ui == nil {
 		log.Println("stream ui is nil")
 
@@ -113,6 +142,9 @@ func Send(msg shared.StreamMessage) {
 
 		} else if msg.Type == shared.StreamMessageReply {
 			prestartReply += msg.ReplyChunk
+			prestartReplyChunks++
+		} else if msg.Type == shared.StreamMessageUsage {
+			prestartUsage = append(prestartUsage, msg.Usage)
 		}
 		return
 	}
@@ -40,6 +40,11 @@ func (m streamUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case delayFileRestartMsg:
 		m.finishedByPath[msg.path] = false
 
+	case usageTickMsg:
+		if !m.finished {
+			return m, usageTick()
+		}
+
 	// Scroll wheel doesn't seem to work--not sure why
 	// case tea.MouseMsg:
 	// 	if !m.promptingMissingFile {
@@ -62,6 +67,8 @@ func (m streamUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if apiErr != nil {
 				log.Println("stop plan api error:", apiErr)
 				m.apiErr = apiErr
+			} else {
+				m.stopped = true
 			}
 			return m, tea.Quit
 
@@ -123,7 +130,12 @@ func (m *streamUIModel) updateReplyDisplay() {
 	}
 
 	if m.reply != "" {
-		replyMd, _ := term.GetMarkdown(m.reply)
+		// the reply can start streaming before the window size is known
+		width := 80
+		if m.width > 0 {
+			width = min(m.width, 80)
+		}
+		replyMd := m.markdown.render(m.reply, width)
 		s += "\n" + color.New(color.BgBlue, color.Bold, color.FgHiWhite).Sprintf(" 🤖 Plandex reply 👇 ")
 		s += "\n\n" + strings.TrimSpace(replyMd)
 	} else {
@@ -283,6 +295,7 @@ func (m *streamUIModel) streamUpdate(msg *shared.StreamMessage) (tea.Model, tea.
 		// log.Println("reply chunk:", msg.ReplyChunk)
 
 		m.reply += msg.ReplyChunk
+		m.addOutputTokens(shared.ModelRolePlanner, 1)
 		m.updateReplyDisplay()
 
 	case shared.StreamMessageBuildInfo:
@@ -293,6 +306,7 @@ func (m *streamUIModel) streamUpdate(msg *shared.StreamMessage) (tea.Model, tea.
 		m.building = true
 		wasFinished := m.finishedByPath[msg.BuildInfo.Path]
 		nowFinished := msg.BuildInfo.Finished
+		m.queuedByPath[msg.BuildInfo.Path] = msg.BuildInfo.Queued
 
 		if msg.BuildInfo.Finished {
 			m.tokensByPath[msg.BuildInfo.Path] = 0
@@ -306,6 +320,7 @@ func (m *streamUIModel) streamUpdate(msg *shared.StreamMessage) (tea.Model, tea.
 			}
 
 			m.tokensByPath[msg.BuildInfo.Path] += msg.BuildInfo.NumTokens
+			m.addOutputTokens(shared.ModelRoleBuilder, msg.BuildInfo.NumTokens)
 		}
 
 		m.updateViewportDimensions()
@@ -314,6 +329,9 @@ func (m *streamUIModel) streamUpdate(msg *shared.StreamMessage) (tea.Model, tea.
 			return m, m.spinner.Tick
 		}
 
+	case shared.StreamMessageUsage:
+		m.addStreamUsage(msg.Usage)
+
 	case shared.StreamMessageDescribing:
 		m.processing = true
 		return m, m.spinner.Tick
@@ -339,6 +357,27 @@ func (m *streamUIModel) streamUpdate(msg *shared.StreamMessage) (tea.Model, tea.
 	return m, nil
 }
 
+func (m *streamUIModel) addStreamUsage(usage *shared.StreamUsage) {
+	if usage == nil {
+		return
+	}
+	m.modelsByRole[usage.Role] = usage.ModelName
+	m.costUsd += shared.GetModelCost(usage.ModelName, usage.InputTokens, usage.CachedInputTokens, 0)
+}
+
+func (m *streamUIModel) addOutputTokens(role shared.ModelRole, numTokens int) {
+	m.outputTokens += numTokens
+	m.costUsd += shared.GetModelCost(m.modelsByRole[role], 0, 0, numTokens)
+}
+
+type usageTickMsg struct{}
+
+func usageTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return usageTickMsg{}
+	})
+}
+
 type delayFileRestartMsg struct {
 	path string
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"plandex/term"
 
@@ -42,12 +43,24 @@ func (m streamUIModel) renderHelp() string {
 	style := lipgloss.NewStyle().Width(m.width).Foreground(lipgloss.Color(helpTextColor)).BorderStyle(lipgloss.NormalBorder()).BorderTop(true).BorderForeground(lipgloss.Color(borderColor))
 
 	if m.buildOnly {
-		return style.Render(" (s)top • (b)ackground")
+		return style.Render(m.renderUsage() + " (s)top • (b)ackground")
 	} else {
-		return style.Render(" (s)top • (b)ackground • (j/k) scroll • (d/u) page • (g/G) start/end")
+		return style.Render(m.renderUsage() + " (s)top • (b)ackground • (j/k) scroll • (d/u) page • (g/G) start/end")
 	}
 }
 
+func (m streamUIModel) renderUsage() string {
+	elapsed := time.Since(m.startedAt).Round(time.Second)
+	return fmt.Sprintf(" %d 🪙 • ~%s • %s |", m.outputTokens, formatCost(m.costUsd), elapsed)
+}
+
+func formatCost(costUsd float64) string {
+	if costUsd < 1 {
+		return fmt.Sprintf("$%.3f", costUsd)
+	}
+	return fmt.Sprintf("$%.2f", costUsd)
+}
+
 func (m streamUIModel) renderProcessing() string {
 	if m.starting || m.processing {
 		return "\n " + m.spinner.View()
@@ -114,6 +127,8 @@ func (m streamUIModel) doRenderBuild(outputStatic bool) string {
 
 		if finished {
 			block += " ✅"
+		} else if m.queuedByPath[filePath] {
+			block += " ⏳"
 		} else if tokens > 0 {
 			block += fmt.Sprintf(" %d 🪙", tokens)
 		}
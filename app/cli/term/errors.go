@@ -2,6 +2,7 @@ package term
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"strings"
 
@@ -11,17 +12,34 @@ import (
 
 func OutputNoApiKeyMsgAndExit() {
 	fmt.Fprintln(os.Stderr, color.New(color.Bold, ColorHiRed).Sprintln("\n🚨 OPENAI_API_KEY environment variable is not set.")+color.New().Sprintln("\nSet it with:\n\nexport OPENAI_API_KEY=your-api-key\n\nThen try again.\n\n👉 If you don't have an OpenAI account, sign up here → https://platform.openai.com/signup\n\n🔑 Generate an api key here → https://platform.openai.com/api-keys"))
-	os.Exit(1)
+	if JsonOutput {
+		OutputJson(map[string]string{"error": "OPENAI_API_KEY environment variable is not set"})
+	}
+	os.Exit(ExitError)
 }
 
 func OutputSimpleError(msg string, args ...interface{}) {
 	msg = fmt.Sprintf(msg, args...)
+	slog.Error(msg)
 	fmt.Fprintln(os.Stderr, color.New(ColorHiRed, color.Bold).Sprint("🚨 "+shared.Capitalize(msg)))
 }
 
+// OutputErrorAndExit exits with ExitError, or with the matching exit code from ApiErrorExitCode if any of the args is an api error
 func OutputErrorAndExit(msg string, args ...interface{}) {
+	code := ExitError
+	for _, arg := range args {
+		if apiErr, ok := arg.(*shared.ApiError); ok {
+			code = ApiErrorExitCode(apiErr)
+		}
+	}
+
+	OutputErrorAndExitWithCode(code, msg, args...)
+}
+
+func OutputErrorAndExitWithCode(code int, msg string, args ...interface{}) {
 	StopSpinner()
 	msg = fmt.Sprintf(msg, args...)
+	slog.Error(msg, "exit_code", code)
 
 	displayMsg := ""
 	errorParts := strings.Split(msg, ": ")
@@ -72,8 +90,13 @@ func OutputErrorAndExit(msg string, args ...interface{}) {
 		displayMsg = color.New(ColorHiRed, color.Bold).Sprint("🚨 " + msg)
 	}
 
-	fmt.Fprintln(os.Stderr, color.New(ColorHiRed, color.Bold).Sprint(displayMsg))
-	os.Exit(1)
+	fmt.Fprintln(os.Stderr, color.New(ColorHiRed, color.Bold).Sprint(Plain(displayMsg)))
+
+	if JsonOutput {
+		OutputJson(map[string]string{"error": msg})
+	}
+
+	os.Exit(code)
 }
 
 func OutputUnformattedErrorAndExit(msg string) {
@@ -10,46 +10,92 @@ import (
 )
 
 var CmdDesc = map[string][2]string{
-	"new":     {"", "start a new plan"},
-	"current": {"cu", "show current plan"},
-	"cd":      {"", "set current plan by name or index"},
-	"load":    {"l", "load files, dirs, urls, notes or piped data into context"},
-	"tell":    {"t", "describe a task, ask a question, or chat"},
-	"changes": {"ch", "review plan changes"},
+	"new":          {"", "start a new plan"},
+	"current":      {"cu", "show current plan"},
+	"cd":           {"", "set current plan by name or index"},
+	"load":         {"l", "load files, dirs, urls, notes or piped data into context"},
+	"tell":         {"t", "describe a task, ask a question, or chat"},
+	"changes":      {"ch", "review plan changes"},
+	"changes edit": {"", "edit a file's pending changes in your editor"},
+	"refactor":     {"", "find the files a change affects, load them, and make it"},
+	"gen-tests":    {"", "write tests for files, following the project's existing tests"},
+	"review":       {"", "review a range of git commits for bugs, security, and style"},
+	"debug":        {"", "run a failing command and have the plan fix it"},
 	// "diffs":       {"d", "show diffs between plan and project files"},
 	// "preview":     {"pv", "preview the plan in a branch"},
-	"apply":    {"ap", "apply plan changes to project files"},
-	"continue": {"c", "continue the plan"},
-	// "status":      {"s", "show status of the plan"},
-	"rewind":        {"rw", "rewind to a previous state"},
-	"ls":            {"", "list everything in context"},
-	"rm":            {"", "remove context by name, index, or glob"},
-	"clear":         {"", "remove all context"},
-	"delete-plan":   {"dp", "delete plan by name or index"},
-	"delete-branch": {"db", "delete a branch by name or index"},
-	"plans":         {"pl", "list plans"},
-	"update":        {"u", "update outdated context"},
-	"log":           {"", "show log of plan updates"},
-	"convo":         {"", "show plan conversation"},
-	"branches":      {"br", "list plan branches"},
-	"checkout":      {"co", "checkout or create a branch"},
-	"build":         {"b", "build any pending changes"},
-	"models":        {"", "show model settings"},
-	"set-model":     {"", "update model settings"},
-	"ps":            {"", "list active and recently finished plan streams"},
-	"stop":          {"", "stop an active plan stream"},
-	"connect":       {"conn", "connect to an active plan stream"},
-	"sign-in":       {"", "sign in, accept an invite, or create an account"},
-	"invite":        {"", "invite a user to join your org"},
-	"revoke":        {"", "revoke an invite or remove a user from your org"},
-	"users":         {"", "list users and pending invites in your org"},
+	"apply":           {"ap", "apply plan changes to project files"},
+	"continue":        {"c", "continue the plan"},
+	"rollback":        {"", "restore project files to before an apply"},
+	"pr":              {"", "push the plan's git branch and open a pull request"},
+	"status":          {"s", "show the plan's step checklist"},
+	"rewind":          {"rw", "rewind to a previous state"},
+	"edit-prompt":     {"ep", "edit an earlier prompt and replay from there"},
+	"ls":              {"", "list everything in context"},
+	"rm":              {"", "remove context by name, index, or glob"},
+	"clear":           {"", "remove all context"},
+	"pin":             {"", "pin context so load --evict never drops it"},
+	"unpin":           {"", "unpin context"},
+	"delete-plan":     {"dp", "delete plan by name or index"},
+	"delete-branch":   {"db", "delete a branch by name or index"},
+	"plans":           {"pl", "list plans"},
+	"export":          {"", "export the current plan to an archive file"},
+	"import":          {"", "import a plan from an archive file"},
+	"update":          {"u", "update outdated context"},
+	"watch":           {"w", "watch context files and update them on change"},
+	"find":            {"", "find the project files most relevant to a query"},
+	"log":             {"", "show log of plan updates"},
+	"diff":            {"", "compare the plan between two revisions"},
+	"convo":           {"", "show plan conversation"},
+	"compact":         {"", "replace older messages with a summary"},
+	"search":          {"", "search conversations and context across plans"},
+	"branches":        {"br", "list plan branches"},
+	"checkout":        {"co", "checkout or create a branch"},
+	"build":           {"b", "build any pending changes"},
+	"models":          {"", "show model settings"},
+	"set-model":       {"", "update model settings"},
+	"usage":           {"", "show model token usage and spend"},
+	"usage export":    {"", "export your org's usage by day and user as CSV or JSON"},
+	"config":          {"", "show plan config"},
+	"set-config":      {"", "update plan config"},
+	"config get":      {"", "show project defaults from config.yml"},
+	"config set":      {"", "set a project default in config.yml"},
+	"ps":              {"", "list active and recently finished plan streams, and queued work"},
+	"stop":            {"", "stop an active plan stream"},
+	"sync":            {"", "send context loads and prompts queued while offline"},
+	"connect":         {"conn", "connect to an active plan stream"},
+	"sign-in":         {"", "sign in, accept an invite, or create an account"},
+	"invite":          {"", "invite a user to join your org"},
+	"revoke":          {"", "revoke an invite or remove a user from your org"},
+	"users":           {"", "list users and pending invites in your org"},
+	"api-keys":        {"", "list API keys for CI jobs and bots"},
+	"share":           {"", "share the current plan with a user or your org"},
+	"unshare":         {"", "stop sharing the current plan"},
+	"api-keys create": {"", "create an API key"},
+	"api-keys revoke": {"", "revoke an API key"},
+	"quotas":          {"", "list org and user quotas"},
+	"quotas set":      {"", "set a quota for your org or a user"},
+	"logs":            {"", "show the end of the local log file"},
+	"server start":    {"", "start a local server with a SQLite database, for self-hosting"},
+	"server stop":     {"", "stop the local server"},
+	"server status":   {"", "show whether the local server is running"},
+	"completion":      {"", "generate a shell completion script"},
+	"serve-editor":    {"", "serve a JSON-RPC API for editor plugins"},
+	"templates":       {"", "list prompt templates"},
+	"templates save":  {"", "save a prompt template"},
+	"templates apply": {"", "send a prompt template to the current plan"},
 }
 
 func PrintCmds(prefix string, cmds ...string) {
+	if Quiet {
+		return
+	}
 	printCmds(os.Stderr, prefix, []color.Attribute{color.Bold, color.FgHiWhite, color.BgCyan, color.FgHiWhite}, cmds...)
 }
 
 func PrintCmdsWithColors(prefix string, colors []color.Attribute, cmds ...string) {
+	if Quiet {
+		return
+	}
 	printCmds(os.Stderr, prefix, colors, cmds...)
 }
 
@@ -112,15 +158,15 @@ func PrintCustomHelp() {
 	fmt.Fprintln(builder)
 
 	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " Plans ")
-	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "new", "plans", "cd", "current", "delete-plan")
+	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "new", "plans", "cd", "current", "delete-plan", "export", "import")
 	fmt.Fprintln(builder)
 
 	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " Changes ")
-	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "changes", "apply")
+	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "changes", "changes edit", "apply", "rollback", "pr", "review")
 	fmt.Fprintln(builder)
 
 	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " Context ")
-	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "load", "ls", "rm", "update", "clear")
+	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "load", "ls", "rm", "update", "watch", "find", "pin", "unpin", "clear")
 	fmt.Fprintln(builder)
 
 	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " Branches ")
@@ -128,23 +174,35 @@ func PrintCustomHelp() {
 	fmt.Fprintln(builder)
 
 	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " History ")
-	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "convo", "log", "rewind")
+	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "convo", "log", "diff", "status", "search", "rewind", "edit-prompt", "compact")
 	fmt.Fprintln(builder)
 
 	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " Control ")
-	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "tell", "continue", "build")
+	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "tell", "continue", "build", "refactor", "gen-tests", "debug")
+	fmt.Fprintln(builder)
+
+	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " Templates ")
+	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "templates", "templates save", "templates apply")
 	fmt.Fprintln(builder)
 
 	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " Streams ")
-	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "ps", "connect", "stop")
+	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "ps", "connect", "stop", "sync")
 	fmt.Fprintln(builder)
 
 	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " AI Models ")
-	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "models", "set-model")
+	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "models", "set-model", "usage")
+	fmt.Fprintln(builder)
+
+	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " Config ")
+	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "config", "set-config", "config get", "config set")
 	fmt.Fprintln(builder)
 
 	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " Accounts ")
-	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "sign-in", "invite", "revoke", "users")
+	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "sign-in", "invite", "revoke", "users", "share", "unshare", "api-keys", "quotas", "server start")
+	fmt.Fprintln(builder)
+
+	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " Shell & Editors ")
+	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "completion", "serve-editor", "logs")
 	fmt.Fprintln(builder)
 
 	fmt.Print(builder.String())
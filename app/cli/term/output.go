@@ -0,0 +1,132 @@
+package term
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/plandex/plandex/shared"
+)
+
+// JsonOutput is set by the global --json flag or PLANDEX_OUTPUT=json.
+// Commands that support it write a single JSON result to stdout with OutputJson.
+var JsonOutput = os.Getenv("PLANDEX_OUTPUT") == "json"
+
+var jsonOut io.Writer = os.Stdout
+
+// StartJsonOutput moves all human-readable output, including color and tables, to stderr so stdout only holds the JSON result
+func StartJsonOutput() {
+	jsonOut = os.Stdout
+	os.Stdout = os.Stderr
+	color.Output = color.Error
+}
+
+func OutputJson(v any) {
+	bytes, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		OutputErrorAndExit("Error marshalling JSON output: %v", err)
+	}
+	fmt.Fprintln(jsonOut, string(bytes))
+}
+
+// Quiet is set by the global --quiet flag. It turns off spinners, command suggestions, and emoji in messages.
+var Quiet bool
+
+// NoColor is set by the global --no-color flag or the NO_COLOR env var
+var NoColor = os.Getenv("NO_COLOR") != ""
+
+var emojiRegex = regexp.MustCompile(`[\x{1F000}-\x{1FAFF}\x{2600}-\x{27BF}\x{2B00}-\x{2BFF}\x{2139}\x{2194}-\x{21AA}\x{231A}-\x{23FF}\x{FE0F}\x{200D}]+ *`)
+var trailingSpaceRegex = regexp.MustCompile(` +(\n|$)`)
+
+func StartNoColor() {
+	color.NoColor = true
+}
+
+// Plain strips emoji from a message in quiet mode, and otherwise returns it unchanged
+func Plain(s string) string {
+	if !Quiet {
+		return s
+	}
+	s = emojiRegex.ReplaceAllString(s, "")
+	return trailingSpaceRegex.ReplaceAllString(s, "$1")
+}
+
+// TableColors returns a table row's colors, or nil when color is off, since tablewriter adds its color codes regardless of color.NoColor
+func TableColors(colors []tablewriter.Colors) []tablewriter.Colors {
+	if color.NoColor {
+		return nil
+	}
+	return colors
+}
+
+// IsStdoutTerminal is false when output is redirected to a file or pipe, as in CI, where the spinner's control codes would garble logs
+func IsStdoutTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// NonInteractive is set by the global --ci flag or PLANDEX_NONINTERACTIVE. It implies --json, --quiet, and --no-color,
+// and makes any prompt fail with ExitInputRequired instead of waiting for input.
+var NonInteractive = os.Getenv("PLANDEX_NONINTERACTIVE") != ""
+
+// Exit codes. These are documented in guides/USAGE.md, so they shouldn't change.
+const (
+	ExitOK = 0
+	// the command failed
+	ExitError = 1
+	// unknown command, or invalid args or flags
+	ExitUsage = 2
+	// the command needed input from a prompt, which isn't possible in non-interactive mode
+	ExitInputRequired = 3
+	// not signed in, or the server rejected the credentials
+	ExitAuth = 4
+	// the server, or the plan's model providers, couldn't be reached
+	ExitNetwork = 5
+	// loading context would exceed the token limit
+	ExitTokenLimit = 6
+	// changes conflict with project files or pending changes
+	ExitConflict = 7
+	// a budget from config has been reached and budget-action is block
+	ExitBudget = 8
+)
+
+// ApiErrorExitCode returns the exit code for a failed api request
+func ApiErrorExitCode(apiErr *shared.ApiError) int {
+	if apiErr == nil {
+		return ExitError
+	}
+	if apiErr.Type == shared.ApiErrorTypeInvalidToken || apiErr.Status == http.StatusUnauthorized || apiErr.Status == http.StatusForbidden {
+		return ExitAuth
+	}
+	if apiErr.Type == shared.ApiErrorTypeNetwork || apiErr.Type == shared.ApiErrorTypeProviderUnavailable {
+		return ExitNetwork
+	}
+	return ExitError
+}
+
+func StartNonInteractive() {
+	JsonOutput = true
+	Quiet = true
+	NoColor = true
+}
+
+// RunInBackground returns whether a plan should stream in the background. The streaming UI needs a terminal,
+// so in non-interactive mode the plan always runs in the background.
+func RunInBackground(bg bool) bool {
+	return bg || NonInteractive
+}
+
+func OutputInputRequiredAndExit(input string) {
+	StopSpinner()
+	msg := fmt.Sprintf("This command needs %s, which can't be prompted for in non-interactive mode", input)
+	fmt.Fprintln(os.Stderr, color.New(ColorHiRed, color.Bold).Sprint(Plain("🚨 "+msg)))
+	if JsonOutput {
+		OutputJson(map[string]string{"error": msg})
+	}
+	os.Exit(ExitInputRequired)
+}
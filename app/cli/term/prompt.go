@@ -11,6 +11,10 @@ import (
 )
 
 func GetUserStringInput(msg string) (string, error) {
+	if NonInteractive {
+		OutputInputRequiredAndExit(fmt.Sprintf("an answer to %q", msg))
+	}
+
 	res, err := prompt.New().Ask(msg).Input("")
 
 	if err != nil && err.Error() == "user quit prompt" {
@@ -21,6 +25,10 @@ func GetUserStringInput(msg string) (string, error) {
 }
 
 func GetUserPasswordInput(msg string) (string, error) {
+	if NonInteractive {
+		OutputInputRequiredAndExit(fmt.Sprintf("an answer to %q", msg))
+	}
+
 	res, err := prompt.New().Ask(msg).Input("", input.WithEchoMode(input.EchoPassword))
 
 	if err != nil && err.Error() == "user quit prompt" {
@@ -31,6 +39,10 @@ func GetUserPasswordInput(msg string) (string, error) {
 }
 
 func GetUserKeyInput() (rune, error) {
+	if NonInteractive {
+		OutputInputRequiredAndExit("a keypress")
+	}
+
 	if err := keyboard.Open(); err != nil {
 		return 0, fmt.Errorf("failed to open keyboard: %s", err)
 	}
@@ -47,6 +59,10 @@ func GetUserKeyInput() (rune, error) {
 }
 
 func ConfirmYesNo(fmtStr string, fmtArgs ...interface{}) (bool, error) {
+	if NonInteractive {
+		OutputInputRequiredAndExit(fmt.Sprintf("confirmation of %q", fmt.Sprintf(fmtStr, fmtArgs...)))
+	}
+
 	color.New(ColorHiMagenta, color.Bold).Printf(fmtStr+" (y)es | (n)o", fmtArgs...)
 	color.New(ColorHiMagenta, color.Bold).Print("> ")
 
@@ -68,6 +84,10 @@ func ConfirmYesNo(fmtStr string, fmtArgs ...interface{}) (bool, error) {
 }
 
 func ConfirmYesNoCancel(fmtStr string, fmtArgs ...interface{}) (bool, bool, error) {
+	if NonInteractive {
+		OutputInputRequiredAndExit(fmt.Sprintf("confirmation of %q", fmt.Sprintf(fmtStr, fmtArgs...)))
+	}
+
 	color.New(ColorHiMagenta, color.Bold).Printf(fmtStr+" (y)es | (n)o | (c)ancel", fmtArgs...)
 	color.New(ColorHiMagenta, color.Bold).Print("> ")
 
@@ -9,6 +9,10 @@ import (
 )
 
 func SelectFromList(msg string, options []string) (string, error) {
+	if NonInteractive {
+		OutputInputRequiredAndExit(fmt.Sprintf("a selection for %q", msg))
+	}
+
 	var selected string
 	prompt := &survey.Select{
 		Message:       color.New(ColorHiMagenta, color.Bold).Sprint(msg),
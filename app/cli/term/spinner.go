@@ -1,6 +1,8 @@
 package term
 
 import (
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/briandowns/spinner"
@@ -16,6 +18,11 @@ var lastMessage string
 var active bool
 
 func StartSpinner(msg string) {
+	// the spinner writes to stdout, which is reserved for the JSON result
+	if JsonOutput || Quiet || !IsStdoutTerminal() {
+		return
+	}
+
 	if active {
 		if msg == lastMessage {
 			return
@@ -32,6 +39,10 @@ func StartSpinner(msg string) {
 }
 
 func StopSpinner() {
+	if !active {
+		return
+	}
+
 	elapsed := time.Since(startedAt)
 
 	if lastMessage != "" && elapsed < withMessageMinDuration {
@@ -51,3 +62,28 @@ func ResumeSpinner() {
 		StartSpinner(lastMessage)
 	}
 }
+
+// Countdown waits for d, showing msg with the seconds left, then puts back the spinner if it was running
+func Countdown(msg string, d time.Duration) {
+	wasActive := active
+	spinnerMsg := lastMessage
+	StopSpinner()
+
+	if JsonOutput || Quiet || !IsStdoutTerminal() {
+		fmt.Fprintf(os.Stderr, "⏳ %s in %ds\n", msg, int(d.Seconds()))
+		time.Sleep(d)
+	} else {
+		deadline := time.Now().Add(d)
+		for left := time.Until(deadline); left > 0; left = time.Until(deadline) {
+			ClearCurrentLine()
+			fmt.Printf("\r⏳ %s in %ds", msg, int(left.Seconds()+0.999))
+			time.Sleep(min(time.Second, left))
+		}
+		ClearCurrentLine()
+		fmt.Print("\r")
+	}
+
+	if wasActive {
+		StartSpinner(spinnerMsg)
+	}
+}
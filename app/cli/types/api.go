@@ -1,6 +1,8 @@
 package types
 
 import (
+	"time"
+
 	"github.com/plandex/plandex/shared"
 )
 
@@ -19,6 +21,10 @@ type ApiClient interface {
 
 	CreateAccount(req shared.CreateAccountRequest, customHost string) (*shared.SessionResponse, *shared.ApiError)
 	SignIn(req shared.SignInRequest, customHost string) (*shared.SessionResponse, *shared.ApiError)
+	GetSsoConfig(customHost string) (*shared.SsoConfigResponse, *shared.ApiError)
+	StartSso(customHost string) (*shared.StartSsoResponse, *shared.ApiError)
+	// PollSso returns a nil session and no error while the user is still signing in
+	PollSso(req shared.PollSsoRequest, customHost string) (*shared.SessionResponse, *shared.ApiError)
 	SignOut() *shared.ApiError
 
 	GetOrgSession() *shared.ApiError
@@ -36,6 +42,17 @@ type ApiClient interface {
 	ListAllInvites() ([]*shared.Invite, *shared.ApiError)
 	DeleteInvite(inviteId string) *shared.ApiError
 
+	CreateApiKey(req shared.CreateApiKeyRequest) (*shared.CreateApiKeyResponse, *shared.ApiError)
+	ListApiKeys() ([]*shared.ApiKey, *shared.ApiError)
+	RevokeApiKey(idOrName string) *shared.ApiError
+
+	ListQuotas() ([]*shared.Quota, *shared.ApiError)
+	SetQuota(req shared.SetQuotaRequest) (*shared.Quota, *shared.ApiError)
+
+	ListPlanShares(planId string) ([]*shared.PlanShare, *shared.ApiError)
+	SharePlan(planId string, req shared.SharePlanRequest) *shared.ApiError
+	DeletePlanShare(planId, shareId string) *shared.ApiError
+
 	CreateProject(req shared.CreateProjectRequest) (*shared.CreateProjectResponse, *shared.ApiError)
 	ListProjects() ([]*shared.Project, *shared.ApiError)
 	SetProjectPlan(projectId string, req shared.SetProjectPlanRequest) *shared.ApiError
@@ -62,17 +79,26 @@ type ApiClient interface {
 	ArchivePlan(planId string) *shared.ApiError
 
 	GetCurrentPlanState(planId, branch string) (*shared.CurrentPlanState, *shared.ApiError)
-	ApplyPlan(planId, branch string) *shared.ApiError
+	ApplyPlan(planId, branch string, applyReq *shared.ApplyPlanRequest) *shared.ApiError
 	RejectAllChanges(planId, branch string) *shared.ApiError
-	RejectFile(planId, branch, filePath string) *shared.ApiError
+	RejectFile(planId, branch, filePath, comment string) *shared.ApiError
+	EditFile(planId, branch, filePath, content string) *shared.ApiError
 
 	LoadContext(planId, branch string, req shared.LoadContextRequest) (*shared.LoadContextResponse, *shared.ApiError)
 	UpdateContext(planId, branch string, req shared.UpdateContextRequest) (*shared.UpdateContextResponse, *shared.ApiError)
 	DeleteContext(planId, branch string, req shared.DeleteContextRequest) (*shared.DeleteContextResponse, *shared.ApiError)
+	PinContext(planId, branch string, req shared.PinContextRequest) (*shared.PinContextResponse, *shared.ApiError)
 	ListContext(planId, branch string) ([]*shared.Context, *shared.ApiError)
 
 	ListConvo(planId, branch string) ([]*shared.ConvoMessage, *shared.ApiError)
+	ListPlanSteps(planId, branch string) ([]*shared.PlanStep, *shared.ApiError)
+	CompactConvo(planId, branch string, req shared.CompactConvoRequest) (*shared.CompactConvoResponse, *shared.ApiError)
 	ListLogs(planId, branch string) (*shared.LogResponse, *shared.ApiError)
+	DiffPlan(planId, branch, from, to string) (*shared.PlanDiffResponse, *shared.ApiError)
+	Review(planId, branch string, req shared.ReviewRequest) (*shared.ReviewResponse, *shared.ApiError)
+
+	ExportPlan(planId, branch string) (*shared.PlanArchive, *shared.ApiError)
+	ImportPlan(projectId string, archive *shared.PlanArchive) (*shared.ImportPlanResponse, *shared.ApiError)
 	RewindPlan(planId, branch string, req shared.RewindPlanRequest) (*shared.RewindPlanResponse, *shared.ApiError)
 
 	ListBranches(planId string) ([]*shared.Branch, *shared.ApiError)
@@ -81,4 +107,7 @@ type ApiClient interface {
 
 	GetSettings(planId, branch string) (*shared.PlanSettings, *shared.ApiError)
 	UpdateSettings(planId, branch string, req shared.UpdateSettingsRequest) (*shared.UpdateSettingsResponse, *shared.ApiError)
+
+	GetUsage(planId string, since time.Time) (*shared.UsageResponse, *shared.ApiError)
+	GetOrgUsage(since, until time.Time) (*shared.OrgUsageResponse, *shared.ApiError)
 }
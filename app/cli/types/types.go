@@ -1,6 +1,11 @@
 package types
 
-import "github.com/plandex/plandex/shared"
+import (
+	"net/http"
+	"time"
+
+	"github.com/plandex/plandex/shared"
+)
 
 type ClientAccount struct {
 	IsCloud  bool   `json:"isCloud"`
@@ -10,6 +15,8 @@ type ClientAccount struct {
 	UserId   string `json:"userId"`
 	Token    string `json:"token"`
 	IsTrial  bool   `json:"isTrial"`
+	// signed in with the server's identity provider, so an expired token is refreshed by signing in with SSO again
+	IsSso bool `json:"isSso,omitempty"`
 }
 
 type ClientAuth struct {
@@ -22,7 +29,61 @@ type LoadContextParams struct {
 	Note            string
 	Recursive       bool
 	NamesOnly       bool
+	Map             bool
+	DbUrl           string
 	ForceSkipIgnore bool
+	MaxDepth        int
+	ForceBinary     bool
+	PdfPages        string
+	Summarize       bool
+	FullSpec        bool
+	Head            int
+	HeadTokens      int
+	Tail            int
+	TailTokens      int
+	NoRedact        bool
+	Evict           bool
+
+	MaxTokensPerFile  int
+	TruncateOversized bool
+	ChunkOversized    bool
+
+	UrlConcurrency int
+	UrlRetries     int
+	Render         bool
+	Raw            bool
+	Crawl          bool
+	MaxPages       int
+	Headers        http.Header
+
+	// queue the load to send later if the server can't be reached
+	QueueOffline bool
+}
+
+type LoadContextResult struct {
+	Res            *shared.LoadContextResponse `json:"res"`
+	IgnoredPaths   map[string]string           `json:"ignoredPaths,omitempty"`
+	BinaryPaths    []string                    `json:"binaryPaths,omitempty"`
+	OversizedFiles map[string]int              `json:"oversizedFiles,omitempty"`
+	Crawls         []*CrawlReport              `json:"crawls,omitempty"`
+	// redaction counts by kind for each context name
+	Redacted map[string]map[string]int `json:"redacted,omitempty"`
+	// set instead of Res when the server couldn't be reached and the load was queued
+	Queued *QueuedOp `json:"queued,omitempty"`
+}
+
+type ContextSearchResult struct {
+	Path      string
+	Score     float32
+	InContext bool
+}
+
+type CrawlReport struct {
+	Url           string   `json:"url"`
+	NumPages      int      `json:"numPages"`
+	NumTokens     int      `json:"numTokens"`
+	NumDuplicates int      `json:"numDuplicates"`
+	FailedUrls    []string `json:"failedUrls,omitempty"`
 }
 
 type ContextOutdatedResult struct {
@@ -32,6 +93,8 @@ type ContextOutdatedResult struct {
 	NumFiles        int
 	NumUrls         int
 	NumTrees        int
+	NumMaps         int
+	NumSchemas      int
 }
 
 const (
@@ -42,6 +105,37 @@ const (
 	PlanOutdatedStrategyCancel           string = "Cancel"
 )
 
+type QueuedOpType string
+
+const (
+	QueuedOpLoadContext QueuedOpType = "load"
+	QueuedOpTell        QueuedOpType = "tell"
+)
+
+// QueuedOp is a context load or prompt that couldn't be sent because the server or the plan's model providers were
+// unreachable. It's sent once they can be reached again.
+type QueuedOp struct {
+	Id       string       `json:"id"`
+	Type     QueuedOpType `json:"type"`
+	QueuedAt time.Time    `json:"queuedAt"`
+
+	// the account it was queued with, since it's only sent with the same one
+	Host   string `json:"host"`
+	OrgId  string `json:"orgId"`
+	UserId string `json:"userId"`
+
+	ProjectId string `json:"projectId"`
+	PlanId    string `json:"planId"`
+	Branch    string `json:"branch"`
+
+	LoadContext shared.LoadContextRequest `json:"loadContext,omitempty"`
+	// queued without the model API key, which is read from the environment when it's sent
+	Tell *shared.TellPlanRequest `json:"tell,omitempty"`
+
+	// set when sending failed for a reason other than being offline. Failed ops are only retried by 'plandex sync'.
+	Error string `json:"error,omitempty"`
+}
+
 type CurrentPlanSettings struct {
 	Id string `json:"id"`
 }
@@ -63,3 +157,33 @@ type ChangesUIScrollReplacement struct {
 type ChangesUIViewportsUpdate struct {
 	ScrollReplacement *ChangesUIScrollReplacement
 }
+
+// PlandexConfig holds per-project defaults from .plandex/config.yml, merged over the home-level config.yml in the Plandex home dir.
+// Unset fields fall back to the home config, then to Plandex's own defaults.
+type PlandexConfig struct {
+	// model names by role (planner, builder, etc.), applied to each new plan
+	Models map[string]string `yaml:"models,omitempty"`
+	// apply plans without confirming, as with apply --yes
+	AutoApply *bool `yaml:"auto-apply,omitempty"`
+	// file extensions that are never loaded into context unless --force is set
+	IgnoreExtensions []string `yaml:"ignore-extensions,omitempty"`
+	// globs (with .gitignore syntax) of project files loaded into context for each new plan
+	DefaultContext []string `yaml:"default-context,omitempty"`
+	// editor used to write prompts, taking precedence over $EDITOR
+	Editor string `yaml:"editor,omitempty"`
+	// total model spend in USD allowed for each plan
+	PlanBudget *float64 `yaml:"plan-budget,omitempty"`
+	// model spend in USD allowed per day (UTC) across all plans
+	DailyBudget *float64 `yaml:"daily-budget,omitempty"`
+	// what happens when a budget is reached: warn or block
+	BudgetAction string `yaml:"budget-action,omitempty"`
+	// shell commands run in order from the project root after apply updates files
+	PostApplyHooks []string `yaml:"post-apply-hooks,omitempty"`
+	// what happens when a post-apply hook fails: report, load, or fix
+	OnHookFailure string `yaml:"on-hook-failure,omitempty"`
+	// formatter commands by file extension (without the dot), run on files before apply writes them
+	Formatters map[string]string `yaml:"formatters,omitempty"`
+	// Slack and Discord incoming webhook urls posted to when a plan running in the background finishes
+	NotifySlack   string `yaml:"notify-slack,omitempty"`
+	NotifyDiscord string `yaml:"notify-discord,omitempty"`
+}
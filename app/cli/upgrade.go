@@ -21,7 +21,7 @@ import (
 )
 
 func checkForUpgrade() {
-	if os.Getenv("PLANDEX_SKIP_UPGRADE") != "" {
+	if os.Getenv("PLANDEX_SKIP_UPGRADE") != "" || term.NonInteractive {
 		return
 	}
 
@@ -0,0 +1,303 @@
+package url
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// links to these are skipped while crawling since they won't be useful as text
+var crawlSkipExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".ico": true,
+	".pdf": true, ".zip": true, ".gz": true, ".tar": true, ".dmg": true, ".exe": true,
+	".mp3": true, ".mp4": true, ".mov": true, ".woff": true, ".woff2": true, ".ttf": true,
+	".css": true, ".js": true,
+}
+
+// limits how many nested sitemaps in a sitemap index are fetched
+const maxSitemaps = 10
+
+type CrawlParams struct {
+	MaxPages    int
+	Concurrency int
+	Retries     int
+	Raw         bool
+	Headers     http.Header
+}
+
+type CrawledPage struct {
+	Url  string
+	Body string
+	Sha  string
+}
+
+type CrawlResult struct {
+	Pages         []*CrawledPage
+	NumDuplicates int
+	FailedUrls    []string
+}
+
+type crawlFetchResult struct {
+	url         string
+	content     []byte
+	contentType string
+	err         error
+}
+
+type sitemapXml struct {
+	Urls     []string `xml:"url>loc"`
+	Sitemaps []string `xml:"sitemap>loc"`
+}
+
+// CrawlSite loads up to params.MaxPages pages from the same origin as rootUrl.
+// Page urls come from the site's sitemap.xml if it has one; otherwise links are followed breadth-first starting from rootUrl.
+// Pages with identical content are only included once. Pages that fail to load are skipped and listed in FailedUrls, but if rootUrl itself fails, an error is returned.
+func CrawlSite(ctx context.Context, rootUrl string, params CrawlParams) (*CrawlResult, error) {
+	root, err := neturl.Parse(rootUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %v", rootUrl, err)
+	}
+	root.Fragment = ""
+
+	if params.MaxPages < 1 {
+		params.MaxPages = 1
+	}
+	if params.Concurrency < 1 {
+		params.Concurrency = 1
+	}
+
+	result := &CrawlResult{}
+	seenShas := map[string]bool{}
+
+	addPage := func(res *crawlFetchResult) {
+		body := string(res.content)
+		if !params.Raw && isHtml(res.contentType) {
+			md, err := HtmlToMarkdown(body, res.url)
+			if err != nil {
+				result.FailedUrls = append(result.FailedUrls, res.url)
+				return
+			}
+			body = md
+		}
+
+		hash := sha256.Sum256([]byte(body))
+		sha := hex.EncodeToString(hash[:])
+
+		if seenShas[sha] {
+			result.NumDuplicates++
+			return
+		}
+		seenShas[sha] = true
+
+		result.Pages = append(result.Pages, &CrawledPage{
+			Url:  res.url,
+			Body: body,
+			Sha:  sha,
+		})
+	}
+
+	sitemapUrls := getSitemapUrls(ctx, root, params)
+
+	if len(sitemapUrls) > 0 {
+		for _, res := range fetchCrawlBatch(ctx, sitemapUrls, params) {
+			if res.err != nil {
+				result.FailedUrls = append(result.FailedUrls, res.url)
+				continue
+			}
+			addPage(res)
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		return result, nil
+	}
+
+	seenUrls := map[string]bool{root.String(): true}
+	queue := []string{root.String()}
+
+	for len(queue) > 0 && len(result.Pages) < params.MaxPages {
+		n := params.MaxPages - len(result.Pages)
+		if n > len(queue) {
+			n = len(queue)
+		}
+		batch := queue[:n]
+		queue = queue[n:]
+
+		for _, res := range fetchCrawlBatch(ctx, batch, params) {
+			if res.err != nil {
+				if res.url == root.String() {
+					return nil, fmt.Errorf("failed to fetch %s: %v", res.url, res.err)
+				}
+				result.FailedUrls = append(result.FailedUrls, res.url)
+				continue
+			}
+
+			addPage(res)
+
+			if !isHtml(res.contentType) {
+				continue
+			}
+
+			for _, link := range getSameOriginLinks(res.url, res.content, root) {
+				if seenUrls[link] {
+					continue
+				}
+				seenUrls[link] = true
+				queue = append(queue, link)
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	return result, nil
+}
+
+// fetchCrawlBatch fetches the urls concurrently, returning results in the same order as urls
+func fetchCrawlBatch(ctx context.Context, urls []string, params CrawlParams) []*crawlFetchResult {
+	results := make([]*crawlFetchResult, len(urls))
+	sem := make(chan struct{}, params.Concurrency)
+	var wg sync.WaitGroup
+
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+
+			res := &crawlFetchResult{url: u}
+			results[i] = res
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				res.err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+
+			res.err = withRetries(ctx, params.Retries, func() error {
+				var err error
+				res.content, res.contentType, err = fetchURL(ctx, u, params.Headers)
+				return err
+			})
+		}(i, u)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// getSitemapUrls returns up to params.MaxPages same-origin page urls from the site's sitemap.xml, following a sitemap index if there is one.
+// Any error just means there's no usable sitemap, so it returns nil rather than failing the crawl.
+func getSitemapUrls(ctx context.Context, root *neturl.URL, params CrawlParams) []string {
+	sitemapUrl := root.Scheme + "://" + root.Host + "/sitemap.xml"
+
+	var urls []string
+	seen := map[string]bool{}
+	queue := []string{sitemapUrl}
+	numFetched := 0
+
+	for len(queue) > 0 && len(urls) < params.MaxPages && numFetched < maxSitemaps {
+		u := queue[0]
+		queue = queue[1:]
+		numFetched++
+
+		var content []byte
+		err := withRetries(ctx, params.Retries, func() error {
+			var err error
+			content, _, err = fetchURL(ctx, u, params.Headers)
+			return err
+		})
+		if err != nil {
+			continue
+		}
+
+		var sitemap sitemapXml
+		if err := xml.NewDecoder(bytes.NewReader(content)).Decode(&sitemap); err != nil {
+			continue
+		}
+
+		for _, loc := range sitemap.Sitemaps {
+			if link, ok := normalizeCrawlLink(root, strings.TrimSpace(loc), root); ok {
+				queue = append(queue, link)
+			}
+		}
+
+		for _, loc := range sitemap.Urls {
+			link, ok := normalizeCrawlLink(root, strings.TrimSpace(loc), root)
+			if !ok || seen[link] {
+				continue
+			}
+			seen[link] = true
+			urls = append(urls, link)
+
+			if len(urls) >= params.MaxPages {
+				break
+			}
+		}
+	}
+
+	return urls
+}
+
+func getSameOriginLinks(pageUrl string, content []byte, root *neturl.URL) []string {
+	base, err := neturl.Parse(pageUrl)
+	if err != nil {
+		return nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(content))
+	if err != nil {
+		return nil
+	}
+
+	var links []string
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		if link, ok := normalizeCrawlLink(base, href, root); ok {
+			links = append(links, link)
+		}
+	})
+
+	return links
+}
+
+// normalizeCrawlLink resolves href against base and returns it without its fragment, or false if it's on a different origin or doesn't look like a page
+func normalizeCrawlLink(base *neturl.URL, href string, root *neturl.URL) (string, bool) {
+	if href == "" {
+		return "", false
+	}
+
+	ref, err := neturl.Parse(href)
+	if err != nil {
+		return "", false
+	}
+
+	u := base.ResolveReference(ref)
+	u.Fragment = ""
+
+	if u.Scheme != root.Scheme || u.Host != root.Host {
+		return "", false
+	}
+
+	if crawlSkipExts[strings.ToLower(path.Ext(u.Path))] {
+		return "", false
+	}
+
+	return u.String(), true
+}
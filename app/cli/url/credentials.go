@@ -0,0 +1,118 @@
+package url
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"plandex/fs"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UrlCredentials are sent with every request to a domain listed in url-credentials.json in the plandex home dir, e.g.
+//
+//	{
+//	  "wiki.example.com": {
+//	    "headers": {"Authorization": "Bearer ..."},
+//	    "cookies": {"session": "..."}
+//	  }
+//	}
+//
+// A domain also matches its subdomains.
+type UrlCredentials struct {
+	Headers map[string]string `json:"headers"`
+	Cookies map[string]string `json:"cookies"`
+}
+
+var (
+	credentialsOnce sync.Once
+	credentials     map[string]*UrlCredentials
+	credentialsErr  error
+)
+
+func loadCredentials() (map[string]*UrlCredentials, error) {
+	credentialsOnce.Do(func() {
+		bytes, err := os.ReadFile(fs.HomeUrlCredentialsPath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				credentialsErr = fmt.Errorf("error reading %s: %v", fs.HomeUrlCredentialsPath, err)
+			}
+			return
+		}
+
+		err = json.Unmarshal(bytes, &credentials)
+		if err != nil {
+			credentialsErr = fmt.Errorf("error unmarshalling %s: %v", fs.HomeUrlCredentialsPath, err)
+		}
+	})
+
+	return credentials, credentialsErr
+}
+
+// getRequestHeaders merges the credentials configured for the url's domain with the given headers, which take precedence
+func getRequestHeaders(u string, headers http.Header) (http.Header, error) {
+	res := http.Header{}
+
+	parsed, err := neturl.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	host := strings.ToLower(parsed.Hostname())
+
+	allCreds, err := loadCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	// apply less specific domains first so that more specific ones override them
+	var matches []string
+	for domain := range allCreds {
+		d := strings.ToLower(domain)
+		if host == d || strings.HasSuffix(host, "."+d) {
+			matches = append(matches, domain)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return len(matches[i]) < len(matches[j])
+	})
+
+	var cookies []string
+	for _, domain := range matches {
+		creds := allCreds[domain]
+		for k, v := range creds.Headers {
+			res.Set(k, v)
+		}
+		for k, v := range creds.Cookies {
+			cookies = append(cookies, (&http.Cookie{Name: k, Value: v}).String())
+		}
+	}
+	if len(cookies) > 0 {
+		res.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	for k, vs := range headers {
+		res.Del(k)
+		for _, v := range vs {
+			res.Add(k, v)
+		}
+	}
+
+	return res, nil
+}
+
+// ParseHeaders parses headers in "Name: value" form, as passed to --header
+func ParseHeaders(raw []string) (http.Header, error) {
+	headers := http.Header{}
+	for _, h := range raw {
+		name, value, found := strings.Cut(h, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid header %q, expected \"Name: value\"", h)
+		}
+		headers.Add(name, strings.TrimSpace(value))
+	}
+	return headers, nil
+}
@@ -0,0 +1,60 @@
+package url
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+func RenderURLContent(url string, raw bool) (string, error) {
+	return RenderURLContentContext(context.Background(), url, raw, nil)
+}
+
+// RenderURLContentContext loads the page in headless Chrome so that content generated by JavaScript is included, then converts it to markdown unless raw is set
+func RenderURLContentContext(ctx context.Context, url string, raw bool, headers http.Header) (string, error) {
+	reqHeaders, err := getRequestHeaders(url, headers)
+	if err != nil {
+		return "", err
+	}
+
+	networkHeaders := network.Headers{}
+	for k := range reqHeaders {
+		networkHeaders[k] = reqHeaders.Get(k)
+	}
+
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, chromedp.DefaultExecAllocatorOptions[:]...)
+	defer cancelAlloc()
+
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+	defer cancelBrowser()
+
+	timeoutCtx, cancelTimeout := context.WithTimeout(browserCtx, httpTimeout)
+	defer cancelTimeout()
+
+	var html string
+	err = chromedp.Run(timeoutCtx,
+		network.Enable(),
+		network.SetExtraHTTPHeaders(networkHeaders),
+		chromedp.Navigate(url),
+		chromedp.WaitReady("body"),
+		chromedp.OuterHTML("html", &html),
+	)
+
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", errors.New("--render requires Chrome or Chromium to be installed")
+		}
+		return "", fmt.Errorf("error rendering %s: %v", url, err)
+	}
+
+	if raw {
+		return html, nil
+	}
+
+	return HtmlToMarkdown(html, url)
+}
@@ -1,26 +1,130 @@
 package url
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
-	"net/url"
-	"plandex/term"
+	neturl "net/url"
 	"regexp"
 	"strings"
+	"syscall"
 	"time"
 
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/JohannesKaufmann/html-to-markdown/plugin"
 	"github.com/PuerkitoBio/goquery"
 )
 
 const (
-	// Constants for fetchURLContent function
+	// Constants for fetchURL function
 	maxRedirections    = 10
 	httpTimeout        = 30 * time.Second
 	maxContentSizeInMB = 10
 )
 
-func FetchURLContent(url string) (string, error) {
+type httpStatusError struct {
+	statusCode int
+	status     string
+}
+
+func (e *httpStatusError) Error() string {
+	return "non-2xx HTTP response status: " + e.status
+}
+
+// FetchURLContent fetches the url, converting html responses to markdown unless raw is set
+func FetchURLContent(url string, raw bool) (string, error) {
+	return fetchURLContent(context.Background(), url, raw, nil)
+}
+
+// FetchURLContentWithRetries retries errors that isRetriableErr expects to go away with exponential backoff, up to maxRetries times
+func FetchURLContentWithRetries(ctx context.Context, url string, raw bool, headers http.Header, maxRetries int) (string, error) {
+	var content []byte
+	var contentType string
+	err := withRetries(ctx, maxRetries, func() error {
+		var err error
+		content, contentType, err = fetchURL(ctx, url, headers)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// converting isn't retried, since it would fail the same way every time
+	return convertContent(content, contentType, url, raw)
+}
+
+func withRetries(ctx context.Context, maxRetries int, fn func() error) error {
+	var numRetry int
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if !isRetriableErr(err) || numRetry >= maxRetries {
+			if numRetry > 0 {
+				return fmt.Errorf("%v (after %d retries)", err, numRetry)
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(1<<uint(numRetry)) * time.Second):
+		}
+
+		numRetry++
+	}
+}
+
+// isRetriableErr returns whether an error is likely to go away on its own: 429s, 5xx responses, timeouts, and connections
+// that were refused or dropped. Errors like a bad url, an unknown host, or too many redirects fail the same way every time.
+func isRetriableErr(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.statusCode == http.StatusTooManyRequests || statusErr.statusCode >= 500
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		// Temporary is deprecated, but it's still how dns errors report a lookup that can be retried
+		return netErr.Timeout() || netErr.Temporary()
+	}
+
+	return false
+}
+
+func fetchURLContent(ctx context.Context, url string, raw bool, headers http.Header) (string, error) {
+	content, contentType, err := fetchURL(ctx, url, headers)
+	if err != nil {
+		return "", err
+	}
+
+	return convertContent(content, contentType, url, raw)
+}
+
+// convertContent converts an html response to markdown unless raw is set
+func convertContent(content []byte, contentType, url string, raw bool) (string, error) {
+	if !raw && isHtml(contentType) {
+		return HtmlToMarkdown(string(content), url)
+	}
+	return string(content), nil
+}
+
+// fetchURL sends the headers along with any credentials configured for the url's domain
+func fetchURL(ctx context.Context, url string, headers http.Header) ([]byte, string, error) {
 	client := &http.Client{
 		Timeout: httpTimeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
@@ -31,14 +135,24 @@ func FetchURLContent(url string) (string, error) {
 		},
 	}
 
-	resp, err := client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return "", err
+		return nil, "", err
+	}
+
+	req.Header, err = getRequestHeaders(url, headers)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", errors.New("non-2xx HTTP response status: " + resp.Status)
+		return nil, "", &httpStatusError{statusCode: resp.StatusCode, status: resp.Status}
 	}
 
 	// Limit the response reader to a maximum amount
@@ -46,25 +160,53 @@ func FetchURLContent(url string) (string, error) {
 
 	content, err := io.ReadAll(limitedReader)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 
-	contentType := resp.Header.Get("Content-Type")
-	if strings.Contains(contentType, "text/html") {
-		return ExtractTextualContent(string(content)), nil
-	} else {
-		return string(content), nil
-	}
+	return content, resp.Header.Get("Content-Type"), nil
+}
+
+func isHtml(contentType string) bool {
+	return strings.Contains(contentType, "text/html")
 }
 
-func ExtractTextualContent(htmlContent string) string {
-	r := strings.NewReader(htmlContent)
-	doc, err := goquery.NewDocumentFromReader(r)
+// elements that are almost always navigation or page chrome rather than content
+const boilerplateSelector = "script, style, noscript, iframe, svg, form, nav, header, footer, aside, [role=navigation], [role=banner], [role=contentinfo], [aria-hidden=true]"
+
+// HtmlToMarkdown strips boilerplate from the page, keeps the main content if it can be identified, and converts the result to markdown.
+// Links are resolved against pageUrl.
+func HtmlToMarkdown(htmlContent, pageUrl string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
 	if err != nil {
-		term.OutputErrorAndExit("Failed to parse HTML: %v", err)
+		return "", fmt.Errorf("failed to parse HTML: %v", err)
+	}
+
+	doc.Find(boilerplateSelector).Remove()
+
+	content := doc.Find("body")
+	for _, selector := range []string{"main", "article", "[role=main]"} {
+		if sel := doc.Find(selector); sel.Length() == 1 && strings.TrimSpace(sel.Text()) != "" {
+			content = sel
+			break
+		}
+	}
+
+	var domain string
+	if u, err := neturl.Parse(pageUrl); err == nil {
+		domain = u.Host
+	}
+
+	converter := md.NewConverter(domain, true, nil)
+	converter.Use(plugin.GitHubFlavored())
+
+	title := strings.TrimSpace(doc.Find("title").First().Text())
+	markdown := strings.TrimSpace(converter.Convert(content))
+
+	if title != "" && !strings.HasPrefix(markdown, "# ") {
+		markdown = "# " + title + "\n\n" + markdown
 	}
 
-	return doc.Text()
+	return markdown, nil
 }
 
 func SanitizeURL(url string) string {
@@ -86,6 +228,6 @@ func SanitizeURL(url string) string {
 }
 
 func IsValidURL(str string) bool {
-	u, err := url.Parse(str)
+	u, err := neturl.Parse(str)
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
@@ -0,0 +1,23 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// CreateApiKey creates an API key. The key is only returned this once. API keys can't manage other keys, so this needs a
+// session token.
+func (c *Client) CreateApiKey(req CreateApiKeyRequest) (*CreateApiKeyResponse, *ApiError) {
+	var res CreateApiKeyResponse
+	return &res, c.do(http.MethodPost, "/api_keys", req, &res)
+}
+
+func (c *Client) ListApiKeys() ([]*ApiKey, *ApiError) {
+	var res []*ApiKey
+	return res, c.do(http.MethodGet, "/api_keys", nil, &res)
+}
+
+// RevokeApiKey revokes an API key by its id or name
+func (c *Client) RevokeApiKey(idOrName string) *ApiError {
+	return c.do(http.MethodDelete, "/api_keys/"+url.PathEscape(idOrName), nil, nil)
+}
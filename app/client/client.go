@@ -0,0 +1,120 @@
+// Package client is a typed Go client for the Plandex server's v1 HTTP API, for integrating with Plandex without the CLI.
+// The API is described by the OpenAPI document the server serves at /v1/openapi.json.
+package client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const CloudHost = "https://api.plandex.ai"
+
+const apiPrefix = "/v1"
+
+type Client struct {
+	host       string
+	authHeader string
+	httpClient *http.Client
+	// streams stay open while a plan runs, so they don't time out
+	streamingClient *http.Client
+}
+
+// New creates a client for the server at host, like CloudHost or http://localhost:8088, authenticated with the token and
+// org id from a session (SessionResponse), or with an API key, in which case orgId is ignored since a key belongs to
+// an org. Use an empty token for the routes that don't need auth.
+func New(host, token, orgId string) (*Client, error) {
+	c := &Client{
+		host:            strings.TrimSuffix(host, "/"),
+		httpClient:      &http.Client{Timeout: 10 * time.Minute},
+		streamingClient: &http.Client{},
+	}
+
+	if strings.HasPrefix(token, ApiKeyPrefix) {
+		c.authHeader = "Bearer " + token
+	} else if token != "" {
+		bytes, err := json.Marshal(AuthHeader{Token: token, OrgId: orgId})
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling auth header: %v", err)
+		}
+		c.authHeader = "Bearer " + base64.StdEncoding.EncodeToString(bytes)
+	}
+
+	return c, nil
+}
+
+// do sends req as JSON, if it isn't nil, and decodes the response into res, if it isn't nil
+func (c *Client) do(method, path string, req, res interface{}) *ApiError {
+	resp, apiErr := c.send(c.httpClient, method, path, req)
+	if apiErr != nil {
+		return apiErr
+	}
+	defer resp.Body.Close()
+
+	if res == nil {
+		return nil
+	}
+
+	err := json.NewDecoder(resp.Body).Decode(res)
+	if err != nil {
+		return &ApiError{Type: ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+	return nil
+}
+
+// send makes a request and returns the response if its status is ok. The caller closes the body.
+func (c *Client) send(httpClient *http.Client, method, path string, req interface{}) (*http.Response, *ApiError) {
+	var body io.Reader
+	if req != nil {
+		reqBytes, err := json.Marshal(req)
+		if err != nil {
+			return nil, &ApiError{Type: ApiErrorTypeOther, Msg: fmt.Sprintf("error marshalling request: %v", err)}
+		}
+		body = bytes.NewReader(reqBytes)
+	}
+
+	request, err := http.NewRequest(method, c.host+apiPrefix+path, body)
+	if err != nil {
+		return nil, &ApiError{Type: ApiErrorTypeOther, Msg: fmt.Sprintf("error creating request: %v", err)}
+	}
+	if req != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if c.authHeader != "" {
+		request.Header.Set("Authorization", c.authHeader)
+	}
+
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return nil, &ApiError{Type: ApiErrorTypeNetwork, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		errBody, _ := io.ReadAll(resp.Body)
+		return nil, apiError(resp, errBody)
+	}
+
+	return resp, nil
+}
+
+// apiError is the error the server responded with, which is JSON for errors like an invalid token and plain text otherwise
+func apiError(resp *http.Response, errBody []byte) *ApiError {
+	if resp.Header.Get("Content-Type") == "application/json" {
+		var apiErr ApiError
+		if err := json.Unmarshal(errBody, &apiErr); err == nil {
+			return &apiErr
+		}
+	}
+
+	return &ApiError{
+		Type:   ApiErrorTypeOther,
+		Status: resp.StatusCode,
+		Msg:    strings.TrimSpace(string(errBody)),
+	}
+}
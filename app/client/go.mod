@@ -0,0 +1,5 @@
+module github.com/plandex-ai/plandex/app/client
+
+go 1.21.3
+
+require github.com/sashabaranov/go-openai v1.19.4
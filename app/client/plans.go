@@ -0,0 +1,261 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+func planPath(planId, branch, rest string) string {
+	return fmt.Sprintf("/plans/%s/%s/%s", url.PathEscape(planId), url.PathEscape(branch), rest)
+}
+
+func projectsQuery(projectIds []string) url.Values {
+	query := url.Values{}
+	for _, id := range projectIds {
+		query.Add("projectId", id)
+	}
+	return query
+}
+
+func (c *Client) ListProjects() ([]*Project, *ApiError) {
+	var res []*Project
+	return res, c.do(http.MethodGet, "/projects", nil, &res)
+}
+
+func (c *Client) CreateProject(req CreateProjectRequest) (*CreateProjectResponse, *ApiError) {
+	var res CreateProjectResponse
+	return &res, c.do(http.MethodPost, "/projects", req, &res)
+}
+
+func (c *Client) ListPlans(projectIds []string) ([]*Plan, *ApiError) {
+	var res []*Plan
+	return res, c.do(http.MethodGet, "/plans?"+projectsQuery(projectIds).Encode(), nil, &res)
+}
+
+func (c *Client) ListPlansRunning(projectIds []string, includeRecent bool) (*ListPlansRunningResponse, *ApiError) {
+	query := projectsQuery(projectIds)
+	if includeRecent {
+		query.Set("recent", "true")
+	}
+	var res ListPlansRunningResponse
+	return &res, c.do(http.MethodGet, "/plans/ps?"+query.Encode(), nil, &res)
+}
+
+func (c *Client) GetPlan(planId string) (*Plan, *ApiError) {
+	var res Plan
+	return &res, c.do(http.MethodGet, "/plans/"+url.PathEscape(planId), nil, &res)
+}
+
+func (c *Client) CreatePlan(projectId string, req CreatePlanRequest) (*CreatePlanResponse, *ApiError) {
+	var res CreatePlanResponse
+	return &res, c.do(http.MethodPost, fmt.Sprintf("/projects/%s/plans", url.PathEscape(projectId)), req, &res)
+}
+
+func (c *Client) DeletePlan(planId string) *ApiError {
+	return c.do(http.MethodDelete, "/plans/"+url.PathEscape(planId), nil, nil)
+}
+
+func (c *Client) ListBranches(planId string) ([]*Branch, *ApiError) {
+	var res []*Branch
+	return res, c.do(http.MethodGet, fmt.Sprintf("/plans/%s/branches", url.PathEscape(planId)), nil, &res)
+}
+
+func (c *Client) ListPlanShares(planId string) ([]*PlanShare, *ApiError) {
+	var res []*PlanShare
+	return res, c.do(http.MethodGet, fmt.Sprintf("/plans/%s/shares", url.PathEscape(planId)), nil, &res)
+}
+
+// SharePlan shares a plan with a user in the org, or with the whole org if req.Email is empty
+func (c *Client) SharePlan(planId string, req SharePlanRequest) *ApiError {
+	return c.do(http.MethodPost, fmt.Sprintf("/plans/%s/shares", url.PathEscape(planId)), req, nil)
+}
+
+func (c *Client) DeletePlanShare(planId, shareId string) *ApiError {
+	return c.do(http.MethodDelete, fmt.Sprintf("/plans/%s/shares/%s", url.PathEscape(planId), url.PathEscape(shareId)), nil, nil)
+}
+
+func (c *Client) CreateBranch(planId, branch string, req CreateBranchRequest) *ApiError {
+	return c.do(http.MethodPost, planPath(planId, branch, "branches"), req, nil)
+}
+
+func (c *Client) DeleteBranch(planId, branch string) *ApiError {
+	return c.do(http.MethodDelete, fmt.Sprintf("/plans/%s/branches/%s", url.PathEscape(planId), url.PathEscape(branch)), nil, nil)
+}
+
+func (c *Client) GetSettings(planId, branch string) (*PlanSettings, *ApiError) {
+	var res PlanSettings
+	return &res, c.do(http.MethodGet, planPath(planId, branch, "settings"), nil, &res)
+}
+
+func (c *Client) UpdateSettings(planId, branch string, req UpdateSettingsRequest) (*UpdateSettingsResponse, *ApiError) {
+	var res UpdateSettingsResponse
+	return &res, c.do(http.MethodPut, planPath(planId, branch, "settings"), req, &res)
+}
+
+func (c *Client) GetUsage(planId string, since time.Time) (*UsageResponse, *ApiError) {
+	query := url.Values{}
+	if planId != "" {
+		query.Set("planId", planId)
+	}
+	if !since.IsZero() {
+		query.Set("since", since.Format(time.RFC3339))
+	}
+	var res UsageResponse
+	return &res, c.do(http.MethodGet, "/usage?"+query.Encode(), nil, &res)
+}
+
+// GetOrgUsage needs the view_org_usage permission. Zero times default to the current UTC month. Use the response's
+// WriteCsv for a CSV export.
+func (c *Client) GetOrgUsage(since, until time.Time) (*OrgUsageResponse, *ApiError) {
+	query := url.Values{}
+	if !since.IsZero() {
+		query.Set("since", since.Format(time.RFC3339))
+	}
+	if !until.IsZero() {
+		query.Set("until", until.Format(time.RFC3339))
+	}
+	var res OrgUsageResponse
+	return &res, c.do(http.MethodGet, "/usage/org?"+query.Encode(), nil, &res)
+}
+
+// Contexts
+
+func (c *Client) ListContext(planId, branch string) ([]*Context, *ApiError) {
+	var res []*Context
+	return res, c.do(http.MethodGet, planPath(planId, branch, "context"), nil, &res)
+}
+
+func (c *Client) LoadContext(planId, branch string, req LoadContextRequest) (*LoadContextResponse, *ApiError) {
+	var res LoadContextResponse
+	return &res, c.do(http.MethodPost, planPath(planId, branch, "context"), req, &res)
+}
+
+func (c *Client) UpdateContext(planId, branch string, req UpdateContextRequest) (*UpdateContextResponse, *ApiError) {
+	var res UpdateContextResponse
+	return &res, c.do(http.MethodPut, planPath(planId, branch, "context"), req, &res)
+}
+
+func (c *Client) DeleteContext(planId, branch string, req DeleteContextRequest) (*DeleteContextResponse, *ApiError) {
+	var res DeleteContextResponse
+	return &res, c.do(http.MethodDelete, planPath(planId, branch, "context"), req, &res)
+}
+
+func (c *Client) PinContext(planId, branch string, req PinContextRequest) (*PinContextResponse, *ApiError) {
+	var res PinContextResponse
+	return &res, c.do(http.MethodPut, planPath(planId, branch, "context/pin"), req, &res)
+}
+
+// Messages
+
+// TellPlan sends a prompt. If req.ConnectStream is set, the plan's stream is returned, and it's up to the caller to read it
+// until it ends and close it. Otherwise the plan runs in the background and the stream is nil.
+func (c *Client) TellPlan(planId, branch string, req TellPlanRequest) (*Stream, *ApiError) {
+	return c.startStream(http.MethodPost, planPath(planId, branch, "tell"), req, req.ConnectStream)
+}
+
+// BuildPlan builds pending changes, returning the build's stream if req.ConnectStream is set, like TellPlan
+func (c *Client) BuildPlan(planId, branch string, req BuildPlanRequest) (*Stream, *ApiError) {
+	return c.startStream(http.MethodPatch, planPath(planId, branch, "build"), req, req.ConnectStream)
+}
+
+// ConnectPlan connects to an active plan's stream. A resumeFrom above 0 picks up after the message with that Seq, for
+// reconnecting after a dropped connection.
+func (c *Client) ConnectPlan(planId, branch string, resumeFrom int) (*Stream, *ApiError) {
+	path := planPath(planId, branch, "connect")
+	if resumeFrom > 0 {
+		path += "?resumeFrom=" + strconv.Itoa(resumeFrom)
+	}
+	return c.startStream(http.MethodPatch, path, nil, true)
+}
+
+func (c *Client) startStream(method, path string, req interface{}, connect bool) (*Stream, *ApiError) {
+	httpClient := c.httpClient
+	if connect {
+		httpClient = c.streamingClient
+	}
+
+	resp, apiErr := c.send(httpClient, method, path, req)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	if !connect {
+		resp.Body.Close()
+		return nil, nil
+	}
+	return newStream(resp.Body), nil
+}
+
+func (c *Client) RespondMissingFile(planId, branch string, req RespondMissingFileRequest) *ApiError {
+	return c.do(http.MethodPost, planPath(planId, branch, "respond_missing_file"), req, nil)
+}
+
+func (c *Client) StopPlan(planId, branch string) *ApiError {
+	return c.do(http.MethodDelete, planPath(planId, branch, "stop"), nil, nil)
+}
+
+func (c *Client) ListConvo(planId, branch string) ([]*ConvoMessage, *ApiError) {
+	var res []*ConvoMessage
+	return res, c.do(http.MethodGet, planPath(planId, branch, "convo"), nil, &res)
+}
+
+func (c *Client) ListPlanSteps(planId, branch string) ([]*PlanStep, *ApiError) {
+	var res []*PlanStep
+	return res, c.do(http.MethodGet, planPath(planId, branch, "steps"), nil, &res)
+}
+
+func (c *Client) ListLogs(planId, branch string) (*LogResponse, *ApiError) {
+	var res LogResponse
+	return &res, c.do(http.MethodGet, planPath(planId, branch, "logs"), nil, &res)
+}
+
+func (c *Client) RewindPlan(planId, branch string, req RewindPlanRequest) (*RewindPlanResponse, *ApiError) {
+	var res RewindPlanResponse
+	return &res, c.do(http.MethodPatch, planPath(planId, branch, "rewind"), req, &res)
+}
+
+func (c *Client) DiffPlan(planId, branch, from, to string) (*PlanDiffResponse, *ApiError) {
+	query := url.Values{}
+	query.Set("from", from)
+	query.Set("to", to)
+	var res PlanDiffResponse
+	return &res, c.do(http.MethodGet, planPath(planId, branch, "diff?"+query.Encode()), nil, &res)
+}
+
+func (c *Client) CompactConvo(planId, branch string, req CompactConvoRequest) (*CompactConvoResponse, *ApiError) {
+	var res CompactConvoResponse
+	return &res, c.do(http.MethodPatch, planPath(planId, branch, "compact"), req, &res)
+}
+
+// Changes and applies
+
+// GetCurrentPlanState returns the plan's pending changes, by path in CurrentPlanFiles, along with the results they come from
+func (c *Client) GetCurrentPlanState(planId, branch string) (*CurrentPlanState, *ApiError) {
+	var res CurrentPlanState
+	return &res, c.do(http.MethodGet, planPath(planId, branch, "current_plan"), nil, &res)
+}
+
+// ApplyPlan marks pending changes applied, all of them if req is nil. Writing the files is up to the caller: the server
+// only has the plan's version of each file.
+func (c *Client) ApplyPlan(planId, branch string, req *ApplyPlanRequest) *ApiError {
+	var body interface{}
+	if req != nil {
+		body = req
+	}
+	return c.do(http.MethodPatch, planPath(planId, branch, "apply"), body, nil)
+}
+
+func (c *Client) RejectAllChanges(planId, branch string) *ApiError {
+	return c.do(http.MethodPatch, planPath(planId, branch, "reject_all"), nil, nil)
+}
+
+func (c *Client) RejectFile(planId, branch string, req RejectFileRequest) *ApiError {
+	return c.do(http.MethodPatch, planPath(planId, branch, "reject_file"), req, nil)
+}
+
+func (c *Client) EditFile(planId, branch string, req EditFileRequest) *ApiError {
+	return c.do(http.MethodPatch, planPath(planId, branch, "edit_file"), req, nil)
+}
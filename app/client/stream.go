@@ -0,0 +1,60 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Stream is a plan's stream of messages as it runs: reply chunks, build progress, prompts about missing files, and finally
+// a message of type finished, error, or aborted
+type Stream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+func newStream(body io.ReadCloser) *Stream {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 32*1024*1024)
+	scanner.Split(splitStreamMessages)
+	return &Stream{body: body, scanner: scanner}
+}
+
+// Next returns the next message, or io.EOF once the stream has ended
+func (s *Stream) Next() (*StreamMessage, error) {
+	for s.scanner.Scan() {
+		data := bytes.TrimSpace(s.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var msg StreamMessage
+		err := json.Unmarshal(data, &msg)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding stream message: %v", err)
+		}
+		return &msg, nil
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Close disconnects from the stream. The plan keeps running on the server.
+func (s *Stream) Close() error {
+	return s.body.Close()
+}
+
+func splitStreamMessages(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.Index(data, []byte(STREAM_MESSAGE_SEPARATOR)); i >= 0 {
+		return i + len(STREAM_MESSAGE_SEPARATOR), data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,810 @@
+package client
+
+// The request and response types of the v1 API. They match the server's types in the shared package, and are kept here
+// so the client can be fetched with go get on its own, without the rest of the repo. types_test.go checks that they
+// still match, so change both together.
+
+import (
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+type AuthHeader struct {
+	Token string `json:"token"`
+	OrgId string `json:"orgId"`
+}
+
+type ApiErrorType string
+
+const (
+	ApiErrorTypeInvalidToken          ApiErrorType = "invalid_token"
+	ApiErrorTypeTrialPlansExceeded    ApiErrorType = "trial_plans_exceeded"
+	ApiErrorTypeTrialMessagesExceeded ApiErrorType = "trial_messages_exceeded"
+	ApiErrorTypeTrialActionNotAllowed ApiErrorType = "trial_action_not_allowed"
+
+	ApiErrorTypeContinueNoMessages ApiErrorType = "continue_no_messages"
+
+	// an org or user quota was reached
+	ApiErrorTypeRateLimited ApiErrorType = "rate_limited"
+
+	// set by the client when a request couldn't be sent, e.g. because the server is unreachable
+	ApiErrorTypeNetwork ApiErrorType = "network"
+
+	// every model provider for the planner is failing, so a prompt can't run until one is reachable again
+	ApiErrorTypeProviderUnavailable ApiErrorType = "provider_unavailable"
+
+	ApiErrorTypeOther ApiErrorType = "other"
+)
+
+type TrialPlansExceededError struct {
+	MaxPlans int `json:"maxPlans"`
+}
+
+type TrialMessagesExceededError struct {
+	MaxReplies int `json:"maxMessages"`
+}
+
+type RateLimitError struct {
+	Quota QuotaType `json:"quota"`
+	// whether the org's quota or the user's quota was reached
+	ForUser bool `json:"forUser"`
+	Limit   int  `json:"limit"`
+	// how long until a retry could succeed. For concurrent streams, it's a suggested interval to check again.
+	RetryAfterSeconds int `json:"retryAfterSeconds"`
+}
+
+type ApiError struct {
+	Type   ApiErrorType `json:"type"`
+	Status int          `json:"status"`
+	Msg    string       `json:"msg"`
+
+	// only used for trial plans exceeded error
+	TrialPlansExceededError *TrialPlansExceededError `json:"trialPlansExceededError,omitempty"`
+
+	// only used for trial messages exceeded error
+	TrialMessagesExceededError *TrialMessagesExceededError `json:"trialMessagesExceededError,omitempty"`
+
+	// only used for rate limited error
+	RateLimitError *RateLimitError `json:"rateLimitError,omitempty"`
+}
+
+// API keys are sent as the bearer token as is, rather than as an encoded AuthHeader. Each key belongs to one org and acts as
+// the user who created it, limited by its scope.
+const ApiKeyPrefix = "pdx_"
+
+type ApiKeyScope string
+
+const (
+	// GET requests only
+	ApiKeyScopeRead ApiKeyScope = "read"
+	// everything on projects and plans except applying changes
+	ApiKeyScopeWrite ApiKeyScope = "write"
+	// everything on projects and plans
+	ApiKeyScopeApply ApiKeyScope = "apply"
+)
+
+type Org struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	IsPending bool   `json:"isPending"`
+}
+
+type Project struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Plan struct {
+	Id              string     `json:"id"`
+	OwnerId         string     `json:"ownerId"`
+	ProjectId       string     `json:"projectId"`
+	Name            string     `json:"name"`
+	SharedWithOrgAt *time.Time `json:"sharedWithOrgAt,omitempty"`
+	TotalReplies    int        `json:"totalReplies"`
+	ActiveBranches  int        `json:"activeBranches"`
+	ArchivedAt      *time.Time `json:"archivedAt,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+	// set when listing plans to the requesting user's permission on a plan that's shared with them rather than their own
+	SharedPermission PlanPermission `json:"sharedPermission,omitempty"`
+}
+
+// PlanPermission is what a user can do with a plan that's shared with them. Each permission includes the ones before it.
+// A plan's owner, and org owners and admins, can do everything.
+type PlanPermission string
+
+const (
+	// read the plan: its conversation, context, changes, and history
+	PlanPermissionView PlanPermission = "view"
+	// send prompts, build, and update context and pending changes
+	PlanPermissionPrompt PlanPermission = "prompt"
+	// apply changes
+	PlanPermissionApply PlanPermission = "apply"
+)
+
+// PlanShare grants a permission on a plan to a user in its org, or to everyone in the org if UserId is nil
+type PlanShare struct {
+	Id         string         `json:"id"`
+	PlanId     string         `json:"planId"`
+	UserId     *string        `json:"userId"`
+	UserEmail  string         `json:"userEmail,omitempty"`
+	UserName   string         `json:"userName,omitempty"`
+	Permission PlanPermission `json:"permission"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+}
+
+type Branch struct {
+	Id              string     `json:"id"`
+	PlanId          string     `json:"planId"`
+	OwnerId         string     `json:"ownerId"`
+	ParentBranchId  *string    `json:"parentBranchId"`
+	Name            string     `json:"name"`
+	Status          PlanStatus `json:"status"`
+	ContextTokens   int        `json:"contextTokens"`
+	ConvoTokens     int        `json:"convoTokens"`
+	SharedWithOrgAt *time.Time `json:"sharedWithOrgAt,omitempty"`
+	ArchivedAt      *time.Time `json:"archivedAt,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+type ContextType string
+
+const (
+	ContextFileType          ContextType = "file"
+	ContextURLType           ContextType = "url"
+	ContextNoteType          ContextType = "note"
+	ContextDirectoryTreeType ContextType = "directory tree"
+	ContextPipedDataType     ContextType = "piped data"
+	ContextImageType         ContextType = "image"
+	ContextMapType           ContextType = "map"
+	ContextDbSchemaType      ContextType = "db_schema"
+	ContextSpecType          ContextType = "spec"
+)
+
+type Context struct {
+	Id              string      `json:"id"`
+	OwnerId         string      `json:"ownerId"`
+	ContextType     ContextType `json:"contextType"`
+	Name            string      `json:"name"`
+	Url             string      `json:"url"`
+	FilePath        string      `json:"file_path"`
+	Sha             string      `json:"sha"`
+	NumTokens       int         `json:"numTokens"`
+	Body            string      `json:"body,omitempty"`
+	ForceSkipIgnore bool        `json:"forceSkipIgnore"`
+	MaxDepth        int         `json:"maxDepth,omitempty"`
+	PdfPages        string      `json:"pdfPages,omitempty"`
+	Rendered        bool        `json:"rendered,omitempty"`
+	Raw             bool        `json:"raw,omitempty"`
+	Summarized      bool        `json:"summarized,omitempty"`
+	ChunkPart       int         `json:"chunkPart,omitempty"`
+	ChunkMaxTokens  int         `json:"chunkMaxTokens,omitempty"`
+	LineRange       string      `json:"lineRange,omitempty"`
+	NoRedact        bool        `json:"noRedact,omitempty"`
+	Pinned          bool        `json:"pinned,omitempty"`
+	SourcePlanId    string      `json:"sourcePlanId,omitempty"`
+	SourceBranch    string      `json:"sourceBranch,omitempty"`
+	SourceContextId string      `json:"sourceContextId,omitempty"`
+	CreatedAt       time.Time   `json:"createdAt"`
+	UpdatedAt       time.Time   `json:"updatedAt"`
+}
+
+type ConvoMessage struct {
+	Id        string    `json:"id"`
+	UserId    string    `json:"userId"`
+	Role      string    `json:"role"`
+	Tokens    int       `json:"tokens"`
+	Num       int       `json:"num"`
+	Message   string    `json:"message"`
+	Stopped   bool      `json:"stopped"`
+	CreatedAt time.Time `json:"createdAt"`
+	// set on the summary message that replaced older messages when the conversation was compacted
+	Compaction *ConvoCompaction `json:"compaction,omitempty"`
+}
+
+// ConvoCompaction records which messages a compacted summary message replaced and where its summary came from
+type ConvoCompaction struct {
+	FromNum     int `json:"fromNum"`
+	ToNum       int `json:"toNum"`
+	NumMessages int `json:"numMessages"`
+	// total tokens of the messages that were replaced
+	Tokens int `json:"tokens"`
+	// the stored conversation summary that was reused, if there was one covering exactly the replaced messages
+	SummaryId string `json:"summaryId,omitempty"`
+	// the model that generated the summary otherwise
+	Model       string    `json:"model,omitempty"`
+	UserId      string    `json:"userId"`
+	CompactedAt time.Time `json:"compactedAt"`
+}
+
+type ConvoMessageDescription struct {
+	Id                    string          `json:"id"`
+	ConvoMessageId        string          `json:"convoMessageId"`
+	SummarizedToMessageId string          `json:"summarizedToMessageId"`
+	MadePlan              bool            `json:"madePlan"`
+	CommitMsg             string          `json:"commitMsg"`
+	Files                 []string        `json:"files"`
+	DidBuild              bool            `json:"didBuild"`
+	BuildPathsInvalidated map[string]bool `json:"buildPathsInvalidated"`
+	Error                 string          `json:"error"`
+	AppliedAt             *time.Time      `json:"appliedAt,omitempty"`
+	CreatedAt             time.Time       `json:"createdAt"`
+	UpdatedAt             time.Time       `json:"updatedAt"`
+}
+
+type Replacement struct {
+	Id             string          `json:"id"`
+	Old            string          `json:"old"`
+	New            string          `json:"new"`
+	Failed         bool            `json:"failed"`
+	RejectedAt     *time.Time      `json:"rejectedAt,omitempty"`
+	StreamedChange *StreamedChange `json:"streamedChange"`
+}
+
+type PlanFileResult struct {
+	Id             string         `json:"id"`
+	ConvoMessageId string         `json:"convoMessageId"`
+	PlanBuildId    string         `json:"planBuildId"`
+	Path           string         `json:"path"`
+	Content        string         `json:"content"`
+	AnyFailed      bool           `json:"anyFailed"`
+	AppliedAt      *time.Time     `json:"appliedAt,omitempty"`
+	RejectedAt     *time.Time     `json:"rejectedAt,omitempty"`
+	Replacements   []*Replacement `json:"replacements"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+}
+
+type CurrentPlanFiles struct {
+	Files           map[string]string    `json:"files"`
+	UpdatedAtByPath map[string]time.Time `json:"updatedAtByPath"`
+}
+
+type PlanFileResultsByPath map[string][]*PlanFileResult
+
+type PlanResult struct {
+	SortedPaths        []string                  `json:"sortedPaths"`
+	FileResultsByPath  PlanFileResultsByPath     `json:"fileResultsByPath"`
+	Results            []*PlanFileResult         `json:"results"`
+	ReplacementsByPath map[string][]*Replacement `json:"replacementsByPath"`
+}
+
+type CurrentPlanState struct {
+	PlanResult               *PlanResult                `json:"planResult"`
+	CurrentPlanFiles         *CurrentPlanFiles          `json:"currentPlanFiles"`
+	ConvoMessageDescriptions []*ConvoMessageDescription `json:"convoMessageDescriptions"`
+	ContextsByPath           map[string]*Context        `json:"contextsByPath"`
+}
+
+type BaseModelConfig struct {
+	Provider  ModelProvider `json:"provider"`
+	BaseUrl   string        `json:"baseUrl"`
+	ModelName string        `json:"modelName"`
+	MaxTokens int           `json:"maxTokens"`
+
+	HasImageSupport bool `json:"hasImageSupport"`
+
+	// for Azure OpenAI, the deployment serving the model (defaults to the model name) and the api version to call it with;
+	// the resource's endpoint goes in BaseUrl
+	AzureDeployment string `json:"azureDeployment,omitempty"`
+	ApiVersion      string `json:"apiVersion,omitempty"`
+	// for Bedrock, the AWS region to call the model in; defaults to the server's AWS_REGION
+	Region string `json:"region,omitempty"`
+}
+
+type PlannerModelConfig struct {
+	MaxConvoTokens       int `json:"maxConvoTokens"`
+	ReservedOutputTokens int `json:"maxOutputTokens"`
+}
+
+type TaskModelConfig struct {
+	OpenAIResponseFormat *openai.ChatCompletionResponseFormat `json:"openAIResponseFormat"`
+}
+
+type ModelRoleConfig struct {
+	Role            ModelRole       `json:"role"`
+	BaseModelConfig BaseModelConfig `json:"baseModelConfig"`
+	Temperature     float32         `json:"temperature"`
+	TopP            float32         `json:"topP"`
+	// caps the length of each reply from this role's model; 0 leaves it to the model
+	MaxCompletionTokens int `json:"maxCompletionTokens,omitempty"`
+	// models to fall back to, in order, when this role's model is rate limited, times out, or has server errors
+	Fallbacks []BaseModelConfig `json:"fallbacks,omitempty"`
+}
+
+type PlannerRoleConfig struct {
+	ModelRoleConfig
+	PlannerModelConfig
+}
+
+type TaskRoleConfig struct {
+	ModelRoleConfig
+	TaskModelConfig
+}
+
+type ModelSet struct {
+	Planner     PlannerRoleConfig `json:"planner"`
+	PlanSummary ModelRoleConfig   `json:"planSummary"`
+	Builder     TaskRoleConfig    `json:"builder"`
+	Namer       TaskRoleConfig    `json:"namer"`
+	CommitMsg   TaskRoleConfig    `json:"commitMsg"`
+	ExecStatus  TaskRoleConfig    `json:"execStatus"`
+}
+
+type ModelOverrides struct {
+	MaxConvoTokens       *int `json:"maxConvoTokens"`
+	MaxTokens            *int `json:"maxContextTokens"`
+	ReservedOutputTokens *int `json:"maxOutputTokens"`
+}
+
+type PlanSettings struct {
+	ModelOverrides    ModelOverrides `json:"modelOverrides"`
+	ModelSet          *ModelSet      `json:"modelSet"`
+	AutoUpdateContext bool           `json:"autoUpdateContext,omitempty"`
+	UpdatedAt         time.Time      `json:"updatedAt"`
+	// overrides the limit on what's sent to the planner, see GetPlannerEffectiveMaxTokens; 0 derives it from the model
+	MaxContextTokens int `json:"maxContextTokens,omitempty"`
+	// how many files are built at once, see GetMaxParallelBuilds; 0 uses DefaultMaxParallelBuilds
+	MaxParallelBuilds int `json:"maxParallelBuilds,omitempty"`
+}
+
+type QuotaType string
+
+const (
+	QuotaRequestsPerMinute QuotaType = "requests_per_minute"
+	QuotaTokensPerDay      QuotaType = "tokens_per_day"
+	QuotaConcurrentStreams QuotaType = "concurrent_streams"
+)
+
+// Quota limits an org's total use of the server, or one user's use within an org. A nil limit is unlimited. Tokens are
+// model input and output tokens, counted per UTC day.
+type Quota struct {
+	OrgId string `json:"orgId"`
+	// empty for the org's quota
+	UserId            string    `json:"userId,omitempty"`
+	UserEmail         string    `json:"userEmail,omitempty"`
+	RequestsPerMinute *int      `json:"requestsPerMinute"`
+	TokensPerDay      *int      `json:"tokensPerDay"`
+	ConcurrentStreams *int      `json:"concurrentStreams"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+type ApiKey struct {
+	Id    string      `json:"id"`
+	OrgId string      `json:"orgId"`
+	Name  string      `json:"name"`
+	Scope ApiKeyScope `json:"scope"`
+	// the start of the key, to tell keys apart; the full key is only returned when it's created
+	KeyPrefix  string     `json:"keyPrefix"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type NotifyTargetType string
+
+const (
+	NotifyTargetSlack   NotifyTargetType = "slack"
+	NotifyTargetDiscord NotifyTargetType = "discord"
+)
+
+// NotifyTarget is a Slack or Discord channel that's posted to through an incoming webhook
+type NotifyTarget struct {
+	Type       NotifyTargetType `json:"type"`
+	WebhookUrl string           `json:"webhookUrl"`
+}
+
+type ModelProvider string
+
+const (
+	ModelProviderOpenAI      ModelProvider = "openai"
+	ModelProviderAzureOpenAI ModelProvider = "azure-openai"
+	ModelProviderBedrock     ModelProvider = "bedrock"
+)
+
+type ModelRole string
+
+const (
+	ModelRolePlanner     ModelRole = "planner"
+	ModelRolePlanSummary ModelRole = "summarizer"
+	ModelRoleBuilder     ModelRole = "builder"
+	ModelRoleName        ModelRole = "names"
+	ModelRoleCommitMsg   ModelRole = "commit-messages"
+	ModelRoleExecStatus  ModelRole = "auto-continue"
+)
+
+type PlanStatus string
+
+const (
+	PlanStatusDraft       PlanStatus = "draft"
+	PlanStatusReplying    PlanStatus = "replying"
+	PlanStatusDescribing  PlanStatus = "describing"
+	PlanStatusBuilding    PlanStatus = "building"
+	PlanStatusMissingFile PlanStatus = "missingFile"
+	PlanStatusFinished    PlanStatus = "finished"
+	PlanStatusStopped     PlanStatus = "stopped"
+	PlanStatusError       PlanStatus = "error"
+)
+
+type PlanStepStatus string
+
+const (
+	PlanStepStatusPending PlanStepStatus = "pending"
+	PlanStepStatusDone    PlanStepStatus = "done"
+	PlanStepStatusFailed  PlanStepStatus = "failed"
+)
+
+// PlanStep is one of the subtasks the planner breaks a task into. The steps are tracked as replies complete them, so a plan
+// that's continued picks up from the first step that isn't done.
+type PlanStep struct {
+	Num         int            `json:"num"`
+	Description string         `json:"description"`
+	Status      PlanStepStatus `json:"status"`
+}
+
+type SessionResponse struct {
+	UserId   string `json:"userId"`
+	Token    string `json:"token"`
+	Email    string `json:"email"`
+	UserName string `json:"userName"`
+	Orgs     []*Org `json:"orgs"`
+}
+
+type CreateProjectRequest struct {
+	Name string `json:"name"`
+}
+
+type CreateProjectResponse struct {
+	Id string `json:"id"`
+}
+
+type CreatePlanRequest struct {
+	Name string `json:"name"`
+}
+
+type CreatePlanResponse struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type ListPlansRunningResponse struct {
+	Branches                   []*Branch            `json:"branches"`
+	StreamStartedAtByBranchId  map[string]time.Time `json:"streamStartedAtByBranchId"`
+	StreamFinishedAtByBranchId map[string]time.Time `json:"streamFinishedAtByBranchId"`
+	StreamIdByBranchId         map[string]string    `json:"streamIdByBranchId"`
+	PlansById                  map[string]*Plan     `json:"plansById"`
+}
+
+type BuildMode string
+
+const (
+	BuildModeAuto BuildMode = "auto"
+	BuildModeNone BuildMode = "none"
+)
+
+type TellPlanRequest struct {
+	Prompt         string          `json:"prompt"`
+	BuildMode      BuildMode       `json:"buildMode"`
+	ConnectStream  bool            `json:"connectStream"`
+	AutoContinue   bool            `json:"autoContinue"`
+	IsUserContinue bool            `json:"isUserContinue"`
+	ApiKey         string          `json:"apiKey"`
+	ProjectPaths   map[string]bool `json:"projectPaths"`
+	// chat channels to notify when the plan finishes, for plans running in the background
+	Notify []*NotifyTarget `json:"notify,omitempty"`
+}
+
+type BuildPlanRequest struct {
+	ConnectStream bool            `json:"connectStream"`
+	ApiKey        string          `json:"apiKey"`
+	ProjectPaths  map[string]bool `json:"projectPaths"`
+}
+
+type RespondMissingFileChoice string
+
+const (
+	RespondMissingFileChoiceLoad      RespondMissingFileChoice = "load"
+	RespondMissingFileChoiceSkip      RespondMissingFileChoice = "skip"
+	RespondMissingFileChoiceOverwrite RespondMissingFileChoice = "overwrite"
+)
+
+type RespondMissingFileRequest struct {
+	Choice   RespondMissingFileChoice `json:"choice"`
+	FilePath string                   `json:"filePath"`
+	Body     string                   `json:"body"`
+}
+
+type LoadContextParams struct {
+	ContextType     ContextType `json:"contextType"`
+	Name            string      `json:"name"`
+	Url             string      `json:"url"`
+	FilePath        string      `json:"file_path"`
+	Body            string      `json:"body"`
+	ForceSkipIgnore bool        `json:"forceSkipIgnore"`
+	MaxDepth        int         `json:"maxDepth,omitempty"`
+	PdfPages        string      `json:"pdfPages,omitempty"`
+	Rendered        bool        `json:"rendered,omitempty"`
+	Raw             bool        `json:"raw,omitempty"`
+	Summarize       bool        `json:"summarize,omitempty"`
+	ChunkPart       int         `json:"chunkPart,omitempty"`
+	ChunkMaxTokens  int         `json:"chunkMaxTokens,omitempty"`
+	LineRange       string      `json:"lineRange,omitempty"`
+	NoRedact        bool        `json:"noRedact,omitempty"`
+	ApiKey          string      `json:"apiKey,omitempty"`
+
+	// set to load another plan's context by reference instead of sending a body
+	SourcePlanId    string `json:"sourcePlanId,omitempty"`
+	SourceBranch    string `json:"sourceBranch,omitempty"`
+	SourceContextId string `json:"sourceContextId,omitempty"`
+
+	// sent in place of the body when the server already has a body with this sha256
+	BodySha string `json:"bodySha,omitempty"`
+}
+
+type LoadContextRequest []*LoadContextParams
+
+type LoadContextResponse struct {
+	TokensAdded       int      `json:"tokensAdded"`
+	TotalTokens       int      `json:"totalTokens"`
+	MaxTokensExceeded bool     `json:"maxTokensExceeded"`
+	MaxTokens         int      `json:"maxTokens"`
+	Msg               string   `json:"msg"`
+	Unchanged         []string `json:"unchanged,omitempty"`
+
+	// shas sent in place of bodies that the server doesn't have, so nothing was loaded. They need to be sent again with their bodies.
+	MissingBodyShas []string `json:"missingBodyShas,omitempty"`
+
+	// set by servers that accept a BodySha in place of a body
+	AcceptsBodySha bool `json:"acceptsBodySha,omitempty"`
+}
+
+type UpdateContextParams struct {
+	Body    string `json:"body"`
+	ApiKey  string `json:"apiKey,omitempty"`
+	BodySha string `json:"bodySha,omitempty"`
+}
+
+type UpdateContextRequest map[string]*UpdateContextParams
+
+type UpdateContextResponse = LoadContextResponse
+
+type DeleteContextRequest struct {
+	Ids map[string]bool `json:"ids"`
+}
+
+type PinContextRequest struct {
+	Ids    map[string]bool `json:"ids"`
+	Pinned bool            `json:"pinned"`
+}
+
+type PinContextResponse struct {
+	Msg string `json:"msg"`
+}
+
+type DeleteContextResponse struct {
+	TokensRemoved int    `json:"tokensRemoved"`
+	TotalTokens   int    `json:"totalTokens"`
+	Msg           string `json:"msg"`
+}
+
+// ApplyPlanRequest limits an apply to some of a plan's pending changes. Changes that aren't applied stay pending.
+type ApplyPlanRequest struct {
+	// when set, only pending changes to these paths are applied
+	Paths []string `json:"paths,omitempty"`
+	// files with only some of their changes applied, by path
+	PartialByPath map[string]*PartialApply `json:"partialByPath,omitempty"`
+}
+
+type PartialApply struct {
+	// the file's content as written to the project
+	Content string `json:"content"`
+	// the rejected changes to Content, which stay pending
+	Replacements []*Replacement `json:"replacements"`
+}
+
+type RejectFileRequest struct {
+	FilePath string `json:"filePath"`
+	// why the changes were rejected, which the model sees with the next prompt
+	Comment string `json:"comment,omitempty"`
+}
+
+type EditFileRequest struct {
+	FilePath string `json:"filePath"`
+	// the file as the user edited it, which replaces the plan's version
+	Content string `json:"content"`
+}
+
+type RewindPlanRequest struct {
+	Sha string `json:"sha"`
+}
+
+type RewindPlanResponse struct {
+	LatestSha    string `json:"latestSha"`
+	LatestCommit string `json:"latestCommit"`
+}
+
+type CompactConvoRequest struct {
+	// number of the most recent messages to keep verbatim
+	KeepRecent int    `json:"keepRecent"`
+	ApiKey     string `json:"apiKey"`
+}
+
+type CompactConvoResponse struct {
+	Compaction    *ConvoCompaction `json:"compaction"`
+	SummaryTokens int              `json:"summaryTokens"`
+	TokensBefore  int              `json:"tokensBefore"`
+	TokensAfter   int              `json:"tokensAfter"`
+}
+
+type LogResponse struct {
+	Shas []string `json:"shas"`
+	Body string   `json:"body"`
+}
+
+// PlanRevision is the plan's state as of one commit in its history
+type PlanRevision struct {
+	Sha string `json:"sha"`
+	// the plan's version of each file with pending changes
+	Files map[string]string `json:"files"`
+	// when each file was last applied
+	AppliedAtByPath map[string]time.Time `json:"appliedAtByPath"`
+	Contexts        []*Context           `json:"contexts"`
+}
+
+type PlanDiffResponse struct {
+	From *PlanRevision `json:"from"`
+	To   *PlanRevision `json:"to"`
+}
+
+type CreateBranchRequest struct {
+	Name string `json:"name"`
+}
+
+type UpdateSettingsRequest struct {
+	Settings *PlanSettings `json:"settings"`
+}
+
+type UpdateSettingsResponse struct {
+	Msg string `json:"msg"`
+}
+
+// UsageRow totals model usage for a plan, day, or model, or overall
+type UsageRow struct {
+	// plan name, day (YYYY-MM-DD in UTC), or model name
+	Key          string  `json:"key"`
+	PlanId       string  `json:"planId,omitempty"`
+	NumRequests  int     `json:"numRequests"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	CostUsd      float64 `json:"costUsd"`
+	// input tokens read from prompt caches, and how much less they cost than uncached input
+	CachedInputTokens int     `json:"cachedInputTokens"`
+	CacheSavingsUsd   float64 `json:"cacheSavingsUsd"`
+}
+
+type UsageResponse struct {
+	Total   *UsageRow   `json:"total"`
+	ByPlan  []*UsageRow `json:"byPlan"`
+	ByDay   []*UsageRow `json:"byDay"`
+	ByModel []*UsageRow `json:"byModel"`
+}
+
+// OrgUsageRow totals an org's API calls and model usage for a user on a day, for a user, or overall
+type OrgUsageRow struct {
+	// YYYY-MM-DD in UTC. Empty in totals that span days.
+	Day string `json:"day,omitempty"`
+	// empty in the org's total
+	UserId            string  `json:"userId,omitempty"`
+	UserEmail         string  `json:"userEmail,omitempty"`
+	ApiCalls          int     `json:"apiCalls"`
+	ModelRequests     int     `json:"modelRequests"`
+	InputTokens       int     `json:"inputTokens"`
+	CachedInputTokens int     `json:"cachedInputTokens"`
+	OutputTokens      int     `json:"outputTokens"`
+	CostUsd           float64 `json:"costUsd"`
+	CacheSavingsUsd   float64 `json:"cacheSavingsUsd"`
+}
+
+type OrgUsageResponse struct {
+	// the first day included and the day after the last, YYYY-MM-DD in UTC
+	Since        string         `json:"since"`
+	Until        string         `json:"until"`
+	Total        *OrgUsageRow   `json:"total"`
+	ByUser       []*OrgUsageRow `json:"byUser"`
+	ByDayAndUser []*OrgUsageRow `json:"byDayAndUser"`
+}
+
+type CreateApiKeyRequest struct {
+	Name  string      `json:"name"`
+	Scope ApiKeyScope `json:"scope"`
+	// 0 for a key that doesn't expire
+	ExpiresInDays int `json:"expiresInDays"`
+}
+
+type CreateApiKeyResponse struct {
+	ApiKey *ApiKey `json:"apiKey"`
+	// the key to authenticate with; it isn't stored, so it can't be shown again
+	Key string `json:"key"`
+}
+
+type SharePlanRequest struct {
+	// the user to share with, or empty to share with everyone in the org
+	Email      string         `json:"email"`
+	Permission PlanPermission `json:"permission"`
+}
+
+const STREAM_MESSAGE_SEPARATOR = "@@PX@@"
+
+type BuildInfo struct {
+	Path      string `json:"path"`
+	NumTokens int    `json:"numTokens"`
+	Finished  bool   `json:"finished"`
+	// waiting for another file's build to finish before starting, see PlanSettings.GetMaxParallelBuilds
+	Queued bool `json:"queued,omitempty"`
+}
+
+// StreamUsage is sent as each model stream starts. Output is counted at one token per streamed chunk, as the server records it, so the client can estimate cost as the stream runs.
+type StreamUsage struct {
+	Role        ModelRole `json:"role"`
+	ModelName   string    `json:"modelName"`
+	InputTokens int       `json:"inputTokens"`
+	// the part of InputTokens that's expected to be read from the model's prompt cache
+	CachedInputTokens int `json:"cachedInputTokens,omitempty"`
+}
+
+type StreamMessageType string
+
+const (
+	StreamMessageStart             StreamMessageType = "start"
+	StreamMessageConnectActive     StreamMessageType = "connectActive"
+	StreamMessageReply             StreamMessageType = "reply"
+	StreamMessageDescribing        StreamMessageType = "describing"
+	StreamMessageRepliesFinished   StreamMessageType = "repliesFinished"
+	StreamMessageBuildInfo         StreamMessageType = "buildInfo"
+	StreamMessageUsage             StreamMessageType = "usage"
+	StreamMessagePromptMissingFile StreamMessageType = "promptMissingFile"
+	StreamMessageAborted           StreamMessageType = "aborted"
+	StreamMessageFinished          StreamMessageType = "finished"
+	StreamMessageError             StreamMessageType = "error"
+)
+
+type StreamMessage struct {
+	Type StreamMessageType `json:"type"`
+
+	ReplyChunk string `json:"replyChunk,omitempty"`
+
+	BuildInfo       *BuildInfo               `json:"buildInfo,omitempty"`
+	Usage           *StreamUsage             `json:"usage,omitempty"`
+	Description     *ConvoMessageDescription `json:"description,omitempty"`
+	Error           *ApiError                `json:"error,omitempty"`
+	MissingFilePath string                   `json:"missingFilePath,omitempty"`
+	ModelStreamId   string                   `json:"modelStreamId,omitempty"`
+
+	InitPrompt    string   `json:"initPrompt,omitempty"`
+	InitReplies   []string `json:"initReplies,omitempty"`
+	InitBuildOnly bool     `json:"initBuildOnly,omitempty"`
+
+	// numbers the plan's stream messages in order, so a client can resume after the last one it received
+	Seq int `json:"seq,omitempty"`
+}
+
+type StreamedChangeSection struct {
+	MaybeStartLine int    `json:"maybeStartLine"`
+	MaybeEndLine   int    `json:"maybeEndLine"`
+	Err            string `json:"err"`
+
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+type StreamedChange struct {
+	Summary string `json:"summary"`
+	Section string `json:"section"`
+	// ChangeType     StreamedChangeType    `json:"changeType"`
+	Old StreamedChangeSection `json:"old"`
+	// New            StreamedChangeSection `json:"new"`
+	New string `json:"new"`
+}
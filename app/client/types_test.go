@@ -0,0 +1,136 @@
+package client
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// the shared package in the repo, which the server serializes these types from. It isn't there when the client is
+// fetched on its own, so the test is skipped.
+const sharedDir = "../shared"
+
+// TestTypesMatchShared checks that every struct, and every constant, in types.go has a counterpart in the shared package
+// with the same fields, types, and json tags, so the client can't drift from what the server sends
+func TestTypesMatchShared(t *testing.T) {
+	if _, err := os.Stat(sharedDir); os.IsNotExist(err) {
+		t.Skip("shared package not found")
+	}
+
+	clientFset := token.NewFileSet()
+	clientFile, err := parser.ParseFile(clientFset, "types.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientStructs, clientConsts := declsOf(clientFset, []*ast.File{clientFile})
+
+	sharedFset := token.NewFileSet()
+	paths, err := filepath.Glob(filepath.Join(sharedDir, "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sharedFiles []*ast.File
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(sharedFset, path, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sharedFiles = append(sharedFiles, file)
+	}
+	sharedStructs, sharedConsts := declsOf(sharedFset, sharedFiles)
+
+	if len(clientStructs) == 0 {
+		t.Fatal("no structs found in types.go")
+	}
+
+	for name, fields := range clientStructs {
+		sharedFields, ok := sharedStructs[name]
+		if !ok {
+			t.Errorf("%s isn't in shared", name)
+			continue
+		}
+		if !reflect.DeepEqual(fields, sharedFields) {
+			t.Errorf("%s doesn't match shared\nclient: %v\nshared: %v", name, fields, sharedFields)
+		}
+	}
+
+	for name, value := range clientConsts {
+		sharedValue, ok := sharedConsts[name]
+		if !ok {
+			t.Errorf("%s isn't in shared", name)
+			continue
+		}
+		if value != sharedValue {
+			t.Errorf("%s = %s, but it's %s in shared", name, value, sharedValue)
+		}
+	}
+}
+
+// declsOf returns the fields of each struct type, as "name type `tag`", and the value of each constant with a literal
+// value, by name
+func declsOf(fset *token.FileSet, files []*ast.File) (map[string][]string, map[string]string) {
+	structs := map[string][]string{}
+	consts := map[string]string{}
+
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+
+			for _, spec := range genDecl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					structType, ok := spec.Type.(*ast.StructType)
+					if !ok || !spec.Name.IsExported() {
+						continue
+					}
+					fields := []string{}
+					for _, field := range structType.Fields.List {
+						desc := exprString(fset, field.Type)
+						if field.Tag != nil {
+							desc += " " + field.Tag.Value
+						}
+						if len(field.Names) == 0 {
+							fields = append(fields, desc)
+						}
+						for _, name := range field.Names {
+							fields = append(fields, name.Name+" "+desc)
+						}
+					}
+					structs[spec.Name.Name] = fields
+
+				case *ast.ValueSpec:
+					if genDecl.Tok != token.CONST {
+						continue
+					}
+					for i, name := range spec.Names {
+						if i < len(spec.Values) {
+							if lit, ok := spec.Values[i].(*ast.BasicLit); ok {
+								consts[name.Name] = lit.Value
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return structs, consts
+}
+
+func exprString(fset *token.FileSet, expr ast.Expr) string {
+	var sb strings.Builder
+	printer.Fprint(&sb, fset, expr)
+	return sb.String()
+}
@@ -0,0 +1,76 @@
+package blobs
+
+import (
+	"fmt"
+	"os"
+)
+
+// Store keeps context bodies, which are large and never change once written, outside of plan repos, so they don't have to
+// be on the server's disk. The rest of a plan stays in its repo. Keys are relative to the store's prefix.
+type Store interface {
+	Put(key string, body []byte) error
+	Get(key string) ([]byte, error)
+	DeletePrefix(prefix string) error
+}
+
+// if nil, blobs are kept in plan repos on local disk as before
+var store Store
+
+// Init sets up the blob store from the environment. PLANDEX_BLOB_STORE is 's3' or 'gcs', or empty to keep everything on
+// local disk.
+func Init() error {
+	kind := os.Getenv("PLANDEX_BLOB_STORE")
+	if kind == "" {
+		return nil
+	}
+
+	bucket := os.Getenv("PLANDEX_BLOB_BUCKET")
+	if bucket == "" {
+		return fmt.Errorf("PLANDEX_BLOB_BUCKET is required when PLANDEX_BLOB_STORE is set")
+	}
+	prefix := os.Getenv("PLANDEX_BLOB_PREFIX")
+
+	var s Store
+	var err error
+	switch kind {
+	case "s3":
+		s, err = newS3Store(bucket, prefix)
+	case "gcs":
+		s, err = newGcsStore(bucket, prefix)
+	default:
+		return fmt.Errorf("invalid PLANDEX_BLOB_STORE '%s'. Use 's3' or 'gcs'", kind)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error initializing %s blob store: %v", kind, err)
+	}
+
+	store = s
+
+	return nil
+}
+
+func Enabled() bool {
+	return store != nil
+}
+
+func Put(key string, body []byte) error {
+	if store == nil {
+		return fmt.Errorf("blob store isn't configured")
+	}
+	return store.Put(key, body)
+}
+
+func Get(key string) ([]byte, error) {
+	if store == nil {
+		return nil, fmt.Errorf("blob store isn't configured, but %s is stored in it. Set PLANDEX_BLOB_STORE", key)
+	}
+	return store.Get(key)
+}
+
+func DeletePrefix(prefix string) error {
+	if store == nil {
+		return nil
+	}
+	return store.DeletePrefix(prefix)
+}
@@ -0,0 +1,148 @@
+package blobs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// s3Store works with S3 and with services that have an S3-compatible API, like GCS and MinIO
+type s3Store struct {
+	client *s3.S3
+	bucket string
+	prefix string
+}
+
+// newS3Store uses the standard AWS credential chain and AWS_REGION. PLANDEX_S3_ENDPOINT can point it at an S3-compatible
+// service instead.
+func newS3Store(bucket, prefix string) (*s3Store, error) {
+	config := &aws.Config{}
+
+	if endpoint := os.Getenv("PLANDEX_S3_ENDPOINT"); endpoint != "" {
+		config.Endpoint = aws.String(endpoint)
+		config.S3ForcePathStyle = aws.Bool(true)
+		if os.Getenv("AWS_REGION") == "" {
+			config.Region = aws.String("us-east-1")
+		}
+	}
+
+	return connectS3Store(config, bucket, prefix)
+}
+
+// newGcsStore uses GCS's S3-compatible XML API, with an HMAC key from GCS_HMAC_ACCESS_ID and GCS_HMAC_SECRET
+func newGcsStore(bucket, prefix string) (*s3Store, error) {
+	accessId := os.Getenv("GCS_HMAC_ACCESS_ID")
+	secret := os.Getenv("GCS_HMAC_SECRET")
+	if accessId == "" || secret == "" {
+		return nil, fmt.Errorf("GCS_HMAC_ACCESS_ID and GCS_HMAC_SECRET are required for the gcs blob store")
+	}
+
+	config := &aws.Config{
+		Endpoint:         aws.String("https://storage.googleapis.com"),
+		Region:           aws.String("auto"),
+		Credentials:      credentials.NewStaticCredentials(accessId, secret, ""),
+		S3ForcePathStyle: aws.Bool(true),
+	}
+
+	return connectS3Store(config, bucket, prefix)
+}
+
+func connectS3Store(config *aws.Config, bucket, prefix string) (*s3Store, error) {
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating session: %v", err)
+	}
+
+	s := &s3Store{
+		client: s3.New(sess),
+		bucket: bucket,
+		prefix: prefix,
+	}
+
+	// fail at startup rather than on the first context load if the bucket or credentials are wrong
+	_, err = s.client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		return nil, fmt.Errorf("error checking bucket %s: %v", bucket, err)
+	}
+
+	return s, nil
+}
+
+func (s *s3Store) Put(key string, body []byte) error {
+	_, err := s.client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(key)),
+		Body:   bytes.NewReader(body),
+	})
+
+	if err != nil {
+		return fmt.Errorf("error putting blob %s: %v", key, err)
+	}
+
+	return nil
+}
+
+func (s *s3Store) Get(key string) ([]byte, error) {
+	out, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(key)),
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting blob %s: %v", key, err)
+	}
+	defer out.Body.Close()
+
+	body, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading blob %s: %v", key, err)
+	}
+
+	return body, nil
+}
+
+// DeletePrefix deletes objects one at a time, since GCS doesn't support S3's batch delete
+func (s *s3Store) DeletePrefix(prefix string) error {
+	var keys []*string
+	err := s.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.key(prefix)),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			keys = append(keys, obj.Key)
+		}
+		return true
+	})
+
+	if err != nil {
+		return fmt.Errorf("error listing blobs in %s: %v", prefix, err)
+	}
+
+	for _, key := range keys {
+		_, err = s.client.DeleteObject(&s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    key,
+		})
+
+		if err != nil {
+			return fmt.Errorf("error deleting blob %s: %v", *key, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *s3Store) key(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	// not path.Join, which would drop the trailing slash from a prefix being deleted
+	return strings.TrimSuffix(s.prefix, "/") + "/" + key
+}
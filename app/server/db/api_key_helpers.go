@@ -0,0 +1,93 @@
+package db
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/plandex/plandex/shared"
+)
+
+func hashApiKey(key string) string {
+	hashBytes := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hashBytes[:])
+}
+
+// CreateApiKey stores a new key and returns it. Only its hash is stored, so this is the only time the key is available.
+func CreateApiKey(orgId, userId, name string, scope shared.ApiKeyScope, expiresAt *time.Time) (key string, apiKey *ApiKey, err error) {
+	bytes := make([]byte, 32)
+	_, err = rand.Read(bytes)
+	if err != nil {
+		return "", nil, fmt.Errorf("error generating api key: %v", err)
+	}
+
+	key = shared.ApiKeyPrefix + hex.EncodeToString(bytes)
+
+	apiKey = &ApiKey{}
+	err = Conn.Get(apiKey, "INSERT INTO api_keys (org_id, user_id, name, scope, key_hash, key_prefix, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *", orgId, userId, name, scope, hashApiKey(key), key[:len(shared.ApiKeyPrefix)+8], expiresAt)
+
+	if err != nil {
+		return "", nil, fmt.Errorf("error creating api key: %v", err)
+	}
+
+	return key, apiKey, nil
+}
+
+// ListApiKeys lists a user's keys in an org that haven't been revoked, including expired ones
+func ListApiKeys(orgId, userId string) ([]*ApiKey, error) {
+	var apiKeys []*ApiKey
+	err := Conn.Select(&apiKeys, "SELECT * FROM api_keys WHERE org_id = $1 AND user_id = $2 AND revoked_at IS NULL ORDER BY created_at", orgId, userId)
+
+	if err != nil {
+		return nil, fmt.Errorf("error listing api keys: %v", err)
+	}
+
+	return apiKeys, nil
+}
+
+// RevokeApiKey revokes one of a user's keys, by id or name. It returns false if there's no such key.
+func RevokeApiKey(orgId, userId, idOrName string) (bool, error) {
+	res, err := Conn.Exec("UPDATE api_keys SET revoked_at = NOW() WHERE org_id = $1 AND user_id = $2 AND (id::text = $3 OR name = $3) AND revoked_at IS NULL", orgId, userId, idOrName)
+
+	if err != nil {
+		return false, fmt.Errorf("error revoking api key: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error revoking api key: %v", err)
+	}
+
+	return n > 0, nil
+}
+
+func ValidateApiKey(key string) (*ApiKey, error) {
+	if !strings.HasPrefix(key, shared.ApiKeyPrefix) {
+		return nil, errors.New("invalid api key")
+	}
+
+	var apiKey ApiKey
+	err := Conn.Get(&apiKey, "SELECT * FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())", hashApiKey(key))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("invalid api key")
+		}
+
+		return nil, fmt.Errorf("error validating api key: %v", err)
+	}
+
+	_, err = Conn.Exec("UPDATE api_keys SET last_used_at = NOW() WHERE id = $1", apiKey.Id)
+	if err != nil {
+		// not worth failing the request over
+		log.Printf("error updating api key last used: %v\n", err)
+	}
+
+	return &apiKey, nil
+}
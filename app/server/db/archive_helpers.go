@@ -0,0 +1,285 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/plandex/plandex/shared"
+)
+
+const planSettingsFile = "settings.json"
+
+var planArchiveDirs = []string{"context", "conversation", "results", "descriptions"}
+
+// GetPlanArchiveFiles reads a plan's stored files by path relative to the plan dir. The branch being exported must already be checked out.
+func GetPlanArchiveFiles(orgId, planId string) (map[string]string, error) {
+	planDir := getPlanDir(orgId, planId)
+	files := map[string]string{}
+
+	bytes, err := os.ReadFile(filepath.Join(planDir, planSettingsFile))
+	if err == nil {
+		files[planSettingsFile] = string(bytes)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading settings: %v", err)
+	}
+
+	for _, dir := range planArchiveDirs {
+		entries, err := os.ReadDir(filepath.Join(planDir, dir))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s dir: %v", dir, err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+
+			bytes, err := os.ReadFile(filepath.Join(planDir, dir, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("error reading %s/%s: %v", dir, entry.Name(), err)
+			}
+
+			// archives are always plaintext, since the importing server has its own keys
+			bytes, err = decryptForOrg(orgId, bytes)
+			if err != nil {
+				return nil, fmt.Errorf("error decrypting %s/%s: %v", dir, entry.Name(), err)
+			}
+
+			// archive paths always use forward slashes so they're portable between servers
+			files[dir+"/"+entry.Name()] = string(bytes)
+		}
+	}
+
+	err = inlineArchiveSharedContexts(orgId, files)
+	if err != nil {
+		return nil, err
+	}
+
+	err = inlineArchiveContextBlobs(orgId, files)
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// inlineArchiveSharedContexts exports contexts shared from other plans as the plan's own copies, since references to other
+// plans aren't imported
+func inlineArchiveSharedContexts(orgId string, files map[string]string) error {
+	for path, content := range files {
+		if !strings.HasPrefix(path, "context/") || !strings.HasSuffix(path, ".meta") {
+			continue
+		}
+
+		var context Context
+		err := json.Unmarshal([]byte(content), &context)
+		if err != nil {
+			return fmt.Errorf("error unmarshalling %s: %v", path, err)
+		}
+
+		if context.SourceContextId == "" {
+			continue
+		}
+
+		// like resolveSharedContext, a source that can't be read is exported with an empty body
+		resolveSharedContext(orgId, &context, true)
+
+		files[strings.TrimSuffix(path, ".meta")+".body"] = context.Body
+
+		context.Body = ""
+		context.SourcePlanId = ""
+		context.SourceBranch = ""
+		context.SourceContextId = ""
+		bytes, err := json.MarshalIndent(context, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshalling %s: %v", path, err)
+		}
+		files[path] = string(bytes)
+	}
+
+	return nil
+}
+
+// inlineArchiveContextBlobs replaces references to bodies in the blob store with .body files, since the server importing
+// the archive can't read this server's blob store
+func inlineArchiveContextBlobs(orgId string, files map[string]string) error {
+	for path, content := range files {
+		if !strings.HasPrefix(path, "context/") || !strings.HasSuffix(path, ".meta") {
+			continue
+		}
+
+		var context Context
+		err := json.Unmarshal([]byte(content), &context)
+		if err != nil {
+			return fmt.Errorf("error unmarshalling %s: %v", path, err)
+		}
+
+		if context.BodyBlob == "" {
+			continue
+		}
+
+		err = loadContextBlob(orgId, &context)
+		if err != nil {
+			return fmt.Errorf("error reading body for %s: %v", path, err)
+		}
+
+		files[strings.TrimSuffix(path, ".meta")+".body"] = context.Body
+
+		context.Body = ""
+		context.BodyBlob = ""
+		bytes, err := json.MarshalIndent(context, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshalling %s: %v", path, err)
+		}
+		files[path] = string(bytes)
+	}
+
+	return nil
+}
+
+// StorePlanArchiveFiles writes an archive's files into a newly created plan's dir.
+// Ids in the stored json are updated so everything belongs to the new plan and the importing user.
+func StorePlanArchiveFiles(orgId, planId, userId string, files map[string]string) error {
+	planDir := getPlanDir(orgId, planId)
+
+	for path, content := range files {
+		err := validatePlanArchivePath(path)
+		if err != nil {
+			return err
+		}
+
+		if strings.HasSuffix(path, ".json") || strings.HasSuffix(path, ".meta") {
+			content, err = reassignArchivedJson(content, orgId, planId, userId)
+			if err != nil {
+				return fmt.Errorf("error updating %s: %v", path, err)
+			}
+		}
+
+		// shared contexts are read without checking the user can access the plan they're shared from, which is only checked
+		// when they're loaded, so an archive can't reference another plan. Exports include shared contexts as copies, and
+		// bodies from the blob store as .body files, so an archive can't reference another plan's blobs either.
+		if strings.HasPrefix(path, "context/") && strings.HasSuffix(path, ".meta") {
+			content, err = removeArchivedJsonKeys(content, "sourcePlanId", "sourceBranch", "sourceContextId", "bodyBlob")
+			if err != nil {
+				return fmt.Errorf("error updating %s: %v", path, err)
+			}
+		}
+
+		data := []byte(content)
+		if isEncryptedPlanFile(path) {
+			data, err = encryptForOrg(orgId, data)
+			if err != nil {
+				return fmt.Errorf("error encrypting %s: %v", path, err)
+			}
+		}
+
+		fullPath := filepath.Join(planDir, filepath.FromSlash(path))
+
+		err = os.MkdirAll(filepath.Dir(fullPath), os.ModePerm)
+		if err != nil {
+			return fmt.Errorf("error creating dir for %s: %v", path, err)
+		}
+
+		err = os.WriteFile(fullPath, data, 0644)
+		if err != nil {
+			return fmt.Errorf("error writing %s: %v", path, err)
+		}
+	}
+
+	return nil
+}
+
+// isEncryptedPlanFile is true for the plan files that are encrypted when encryption is on: context bodies and conversation
+// messages
+func isEncryptedPlanFile(path string) bool {
+	return (strings.HasPrefix(path, "context/") && strings.HasSuffix(path, ".body")) || strings.HasPrefix(path, "conversation/")
+}
+
+// validatePlanArchivePath only allows the settings file and files directly inside one of the plan's data dirs, so an archive can't write anywhere else
+func validatePlanArchivePath(path string) error {
+	if path == planSettingsFile {
+		return nil
+	}
+
+	dir, name, ok := strings.Cut(path, "/")
+	if ok && name != "" && !strings.ContainsAny(name, `/\`) && !strings.HasPrefix(name, ".") {
+		for _, d := range planArchiveDirs {
+			if dir == d {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("invalid path in archive: %s", path)
+}
+
+func reassignArchivedJson(content, orgId, planId, userId string) (string, error) {
+	var obj map[string]interface{}
+	err := json.Unmarshal([]byte(content), &obj)
+	if err != nil {
+		return "", err
+	}
+
+	for key, value := range map[string]string{"orgId": orgId, "planId": planId, "ownerId": userId, "userId": userId} {
+		if _, ok := obj[key]; ok {
+			obj[key] = value
+		}
+	}
+
+	bytes, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+func removeArchivedJsonKeys(content string, keys ...string) (string, error) {
+	var obj map[string]interface{}
+	err := json.Unmarshal([]byte(content), &obj)
+	if err != nil {
+		return "", err
+	}
+
+	for _, key := range keys {
+		delete(obj, key)
+	}
+
+	bytes, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+// GetPlanArchiveSummaries returns the convo summaries for a plan's current conversation
+func GetPlanArchiveSummaries(orgId, planId string) ([]*shared.ConvoSummary, error) {
+	convo, err := GetPlanConvo(orgId, planId)
+	if err != nil {
+		return nil, err
+	}
+
+	var messageIds []string
+	for _, msg := range convo {
+		messageIds = append(messageIds, msg.Id)
+	}
+
+	summaries, err := GetPlanSummaries(planId, messageIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*shared.ConvoSummary
+	for _, summary := range summaries {
+		res = append(res, summary.ToApi())
+	}
+
+	return res, nil
+}
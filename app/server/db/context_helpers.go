@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"sort"
@@ -12,6 +13,8 @@ import (
 	"sync"
 	"time"
 
+	"plandex-server/blobs"
+
 	"github.com/google/uuid"
 	"github.com/plandex/plandex/shared"
 )
@@ -83,7 +86,17 @@ func GetContext(orgId, planId, contextId string, includeBody bool) (*Context, er
 		return nil, fmt.Errorf("error unmarshalling context meta file: %v", err)
 	}
 
-	if includeBody {
+	if context.SourcePlanId != "" {
+		resolveSharedContext(orgId, &context, includeBody)
+		return &context, nil
+	}
+
+	if includeBody && context.BodyBlob != "" {
+		err = loadContextBlob(orgId, &context)
+		if err != nil {
+			return nil, err
+		}
+	} else if includeBody {
 		// read the body file
 		bodyPath := filepath.Join(contextDir, strings.TrimSuffix(contextId, ".meta")+".body")
 		bodyBytes, err := os.ReadFile(bodyPath)
@@ -92,7 +105,10 @@ func GetContext(orgId, planId, contextId string, includeBody bool) (*Context, er
 			return nil, fmt.Errorf("error reading context body file: %v", err)
 		}
 
-		context.Body = string(bodyBytes)
+		err = setContextBody(orgId, &context, bodyBytes)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &context, nil
@@ -107,7 +123,12 @@ func ContextRemove(contexts []*Context) error {
 		contextDir := getPlanContextDir(context.OrgId, context.PlanId)
 		for _, ext := range []string{".meta", ".body"} {
 			go func(context *Context, dir, ext string) {
-				errCh <- os.Remove(filepath.Join(dir, context.Id+ext))
+				err := os.Remove(filepath.Join(dir, context.Id+ext))
+				// contexts with their body in the blob store don't have a .body file
+				if os.IsNotExist(err) && ext == ".body" {
+					err = nil
+				}
+				errCh <- err
 			}(context, contextDir, ext)
 		}
 	}
@@ -141,6 +162,10 @@ func StoreContext(context *Context) error {
 	metaPath := filepath.Join(contextDir, metaFilename)
 
 	originalBody := context.Body
+	if context.SourcePlanId != "" {
+		// shared contexts are read from the source plan, so only the meta is stored
+		originalBody = ""
+	}
 	originalBody = strings.ReplaceAll(originalBody, "\\`\\`\\`", "\\\\`\\\\`\\\\`")
 	originalBody = strings.ReplaceAll(originalBody, "```", "\\`\\`\\`")
 
@@ -149,15 +174,39 @@ func StoreContext(context *Context) error {
 	body := []byte(originalBody)
 	context.Body = ""
 
+	// the blob is keyed by the body's hash, so earlier revisions of the meta in the plan's repo still point to their body
+	context.BodyBlob = ""
+	if blobs.Enabled() && context.SourcePlanId == "" {
+		hash := sha256.Sum256(body)
+		context.BodyBlob = fmt.Sprintf("%s/context/%s", planBlobPrefix(context.OrgId, context.PlanId), hex.EncodeToString(hash[:]))
+	}
+
+	body, err = encryptForOrg(context.OrgId, body)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt context body: %v", err)
+	}
+
+	if context.BodyBlob != "" {
+		err = blobs.Put(context.BodyBlob, body)
+		if err != nil {
+			return fmt.Errorf("failed to store context body: %v", err)
+		}
+	}
+
 	// Convert the ModelContextPart to JSON
 	data, err := json.MarshalIndent(context, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal context context: %v", err)
 	}
 
-	// Write the body to the file
-	if err = os.WriteFile(bodyPath, body, 0644); err != nil {
-		return fmt.Errorf("failed to write context body to file %s: %v", bodyPath, err)
+	if context.BodyBlob == "" {
+		// Write the body to the file
+		if err = os.WriteFile(bodyPath, body, 0644); err != nil {
+			return fmt.Errorf("failed to write context body to file %s: %v", bodyPath, err)
+		}
+	} else if err = os.Remove(bodyPath); err != nil && !os.IsNotExist(err) {
+		// a body file from before the blob store was set up
+		return fmt.Errorf("failed to remove context body file %s: %v", bodyPath, err)
 	}
 
 	// Write the meta data to the file
@@ -170,6 +219,32 @@ func StoreContext(context *Context) error {
 	return nil
 }
 
+// SetContextPinned updates only a context's meta file, so pinning doesn't rewrite the body or count as an update for eviction
+func SetContextPinned(orgId, planId, contextId string, pinned bool) (*Context, bool, error) {
+	context, err := GetContext(orgId, planId, contextId, false)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if context.Pinned == pinned {
+		return context, false, nil
+	}
+
+	context.Pinned = pinned
+
+	data, err := json.MarshalIndent(context, "", "  ")
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal context: %v", err)
+	}
+
+	metaPath := filepath.Join(getPlanContextDir(orgId, planId), contextId+".meta")
+	if err = os.WriteFile(metaPath, data, 0644); err != nil {
+		return nil, false, fmt.Errorf("failed to write context meta to file %s: %v", metaPath, err)
+	}
+
+	return context, true, nil
+}
+
 type LoadContextsParams struct {
 	Req                      *shared.LoadContextRequest
 	OrgId                    string
@@ -177,6 +252,9 @@ type LoadContextsParams struct {
 	BranchName               string
 	UserId                   string
 	SkipConflictInvalidation bool
+
+	// for contexts whose body was replaced with a summary, the sha of the original body, so updates can still be detected
+	SummarizedShas map[*shared.LoadContextParams]string
 }
 
 func LoadContexts(params LoadContextsParams) (*shared.LoadContextResponse, []*Context, error) {
@@ -187,8 +265,95 @@ func LoadContexts(params LoadContextsParams) (*shared.LoadContextResponse, []*Co
 	branchName := params.BranchName
 	userId := params.UserId
 
-	filesToLoad := map[string]string{}
+	summarizedShas := params.SummarizedShas
+
+	existingContexts, err := GetPlanContexts(orgId, planId, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting existing contexts: %v", err)
+	}
+
+	existingByKey := map[string]*Context{}
+	for _, context := range existingContexts {
+		existingByKey[contextDedupeKey(context.ContextType, context.FilePath, context.Url, context.Sha, context.ChunkPart, context.LineRange, context.SourceContextId)] = context
+	}
+
+	// skip anything that's already in context with the same content, and replace anything that's in context with different content
+	var toLoad []*shared.LoadContextParams
+	var unchanged []string
+	shaByParams := map[*shared.LoadContextParams]string{}
+	existingByParams := map[*shared.LoadContextParams]*Context{}
+	loadingKeys := map[string]bool{}
+
 	for _, context := range *req {
+		hash := sha256.Sum256([]byte(context.Body))
+		sha := hex.EncodeToString(hash[:])
+		if originalSha, summarized := summarizedShas[context]; summarized {
+			sha = originalSha
+		} else if context.Body == "" && context.BodySha != "" {
+			sha = context.BodySha
+		}
+		shaByParams[context] = sha
+
+		key := contextDedupeKey(context.ContextType, context.FilePath, context.Url, sha, context.ChunkPart, context.LineRange, context.SourceContextId)
+
+		if loadingKeys[key] {
+			continue
+		}
+		loadingKeys[key] = true
+
+		if existing, ok := existingByKey[key]; ok {
+			// a shared context is always read from its source, so loading it again never changes anything
+			if existing.Sha == sha || context.SourceContextId != "" {
+				unchanged = append(unchanged, context.Name)
+				continue
+			}
+			existingByParams[context] = existing
+		}
+
+		toLoad = append(toLoad, context)
+	}
+
+	// bodies sent as just a sha that aren't already in context can come from another context with the same content
+	bodyShas := map[string]bool{}
+	for _, context := range toLoad {
+		if context.Body == "" && context.BodySha != "" {
+			bodyShas[context.BodySha] = true
+		}
+	}
+
+	if len(bodyShas) > 0 {
+		bodiesBySha, err := getBodiesBySha(orgId, planId, existingContexts, bodyShas)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error getting context bodies: %v", err)
+		}
+
+		var missingShas []string
+		for _, context := range toLoad {
+			if context.Body != "" || context.BodySha == "" {
+				continue
+			}
+			body, ok := bodiesBySha[context.BodySha]
+			// a summary has to be made from the full body before locking
+			if !ok || context.Summarize {
+				missingShas = append(missingShas, context.BodySha)
+				continue
+			}
+			context.Body = body
+		}
+
+		if len(missingShas) > 0 {
+			return &shared.LoadContextResponse{
+				MissingBodyShas: missingShas,
+			}, nil, nil
+		}
+	}
+
+	filesToLoad := map[string]string{}
+	for _, context := range toLoad {
+		// shared files belong to the source plan's project, so they can't conflict with this plan's changes
+		if _, summarized := summarizedShas[context]; summarized || context.SourceContextId != "" {
+			continue
+		}
 		if context.ContextType == shared.ContextFileType {
 			filesToLoad[context.FilePath] = context.Body
 		}
@@ -219,9 +384,9 @@ func LoadContexts(params LoadContextsParams) (*shared.LoadContextResponse, []*Co
 
 	maxTokens := settings.GetPlannerEffectiveMaxTokens()
 
-	for _, context := range *req {
+	for _, context := range toLoad {
 		tempId := uuid.New().String()
-		numTokens, err := shared.GetNumTokens(context.Body)
+		numTokens, err := shared.GetContextNumTokens(context.ContextType, context.Body)
 
 		if err != nil {
 			return nil, nil, fmt.Errorf("error getting num tokens: %v", err)
@@ -230,8 +395,21 @@ func LoadContexts(params LoadContextsParams) (*shared.LoadContextResponse, []*Co
 		paramsByTempId[tempId] = context
 		numTokensByTempId[tempId] = numTokens
 
-		tokensAdded += numTokens
-		totalTokens += numTokens
+		tokenDiff := numTokens
+		if existing, ok := existingByParams[context]; ok {
+			tokenDiff -= existing.NumTokens
+		}
+
+		tokensAdded += tokenDiff
+		totalTokens += tokenDiff
+	}
+
+	if len(toLoad) == 0 {
+		return &shared.LoadContextResponse{
+			TotalTokens: totalTokens,
+			Msg:         "Already in context, unchanged",
+			Unchanged:   unchanged,
+		}, nil, nil
 	}
 
 	if totalTokens > maxTokens {
@@ -245,11 +423,11 @@ func LoadContexts(params LoadContextsParams) (*shared.LoadContextResponse, []*Co
 
 	dbContextsCh := make(chan *Context)
 	errCh := make(chan error)
+
 	for tempId, params := range paramsByTempId {
 
 		go func(tempId string, params *shared.LoadContextParams) {
-			hash := sha256.Sum256([]byte(params.Body))
-			sha := hex.EncodeToString(hash[:])
+			_, summarized := summarizedShas[params]
 
 			context := Context{
 				// Id generated by db layer
@@ -261,9 +439,28 @@ func LoadContexts(params LoadContextsParams) (*shared.LoadContextResponse, []*Co
 				Url:             params.Url,
 				FilePath:        params.FilePath,
 				NumTokens:       numTokensByTempId[tempId],
-				Sha:             sha,
+				Sha:             shaByParams[params],
 				Body:            params.Body,
 				ForceSkipIgnore: params.ForceSkipIgnore,
+				MaxDepth:        params.MaxDepth,
+				PdfPages:        params.PdfPages,
+				Rendered:        params.Rendered,
+				Raw:             params.Raw,
+				Summarized:      summarized,
+				ChunkPart:       params.ChunkPart,
+				ChunkMaxTokens:  params.ChunkMaxTokens,
+				LineRange:       params.LineRange,
+				NoRedact:        params.NoRedact,
+				SourcePlanId:    params.SourcePlanId,
+				SourceBranch:    params.SourceBranch,
+				SourceContextId: params.SourceContextId,
+			}
+
+			// replacing an existing context keeps its id so it's overwritten rather than duplicated
+			if existing, ok := existingByParams[params]; ok {
+				context.Id = existing.Id
+				context.CreatedAt = existing.CreatedAt
+				context.Pinned = existing.Pinned
 			}
 
 			err := StoreContext(&context)
@@ -281,7 +478,7 @@ func LoadContexts(params LoadContextsParams) (*shared.LoadContextResponse, []*Co
 	var dbContexts []*Context
 	var apiContexts []*shared.Context
 
-	for i := 0; i < len(*req); i++ {
+	for i := 0; i < len(toLoad); i++ {
 		select {
 		case err := <-errCh:
 			return nil, nil, fmt.Errorf("error storing context: %v", err)
@@ -308,9 +505,67 @@ func LoadContexts(params LoadContextsParams) (*shared.LoadContextResponse, []*Co
 		TokensAdded: tokensAdded,
 		TotalTokens: totalTokens,
 		Msg:         commitMsg,
+		Unchanged:   unchanged,
 	}, dbContexts, nil
 }
 
+// contexts with the same key are the same resource, so loading one again replaces the existing context instead of adding a duplicate
+func contextDedupeKey(contextType shared.ContextType, filePath, url, sha string, chunkPart int, lineRange, sourceContextId string) string {
+	// the same context can be shared from another plan alongside a plan's own copy of the resource
+	if sourceContextId != "" {
+		return "shared|" + sourceContextId
+	}
+
+	switch contextType {
+	case shared.ContextFileType:
+		// each part of a chunked file and each line range is its own context
+		if chunkPart > 0 {
+			return fmt.Sprintf("%s|%s|%d", contextType, filePath, chunkPart)
+		}
+		if lineRange != "" {
+			return fmt.Sprintf("%s|%s:%s", contextType, filePath, lineRange)
+		}
+		return string(contextType) + "|" + filePath
+	case shared.ContextImageType, shared.ContextDirectoryTreeType, shared.ContextMapType, shared.ContextSpecType:
+		return string(contextType) + "|" + filePath
+	case shared.ContextURLType, shared.ContextDbSchemaType:
+		return string(contextType) + "|" + url
+	default:
+		// notes and piped data have no source to identify them by, so only identical content counts as a duplicate
+		return string(contextType) + "|" + sha
+	}
+}
+
+// getBodiesBySha gets the bodies of a plan's contexts that have any of the given shas
+func getBodiesBySha(orgId, planId string, existingContexts []*Context, shas map[string]bool) (map[string]string, error) {
+	bodiesBySha := map[string]string{}
+
+	for _, existing := range existingContexts {
+		// summarized and shared contexts don't store the body their sha is for
+		if existing.Summarized || existing.SourceContextId != "" || !shas[existing.Sha] {
+			continue
+		}
+		if _, ok := bodiesBySha[existing.Sha]; ok {
+			continue
+		}
+
+		context, err := GetContext(orgId, planId, existing.Id, true)
+		if err != nil {
+			return nil, err
+		}
+
+		// bodies are stored with code fences escaped, so the body is only used if unescaping gives back the exact content
+		body := strings.ReplaceAll(context.Body, "\\`\\`\\`", "```")
+		body = strings.ReplaceAll(body, "\\\\`\\\\`\\\\`", "\\`\\`\\`")
+		hash := sha256.Sum256([]byte(body))
+		if hex.EncodeToString(hash[:]) == existing.Sha {
+			bodiesBySha[existing.Sha] = body
+		}
+	}
+
+	return bodiesBySha, nil
+}
+
 type UpdateContextsParams struct {
 	Req                      *shared.UpdateContextRequest
 	OrgId                    string
@@ -318,6 +573,9 @@ type UpdateContextsParams struct {
 	BranchName               string
 	ContextsById             map[string]*Context
 	SkipConflictInvalidation bool
+
+	// for contexts whose updated body was replaced with a summary, the sha of the original body
+	SummarizedShas map[string]string
 }
 
 func UpdateContexts(params UpdateContextsParams) (*shared.UpdateContextResponse, error) {
@@ -354,11 +612,72 @@ func UpdateContexts(params UpdateContextsParams) (*shared.UpdateContextResponse,
 		contextsById = params.ContextsById
 	}
 
+	// bodies sent as just a sha are either unchanged or come from another context with the same content
+	bodyShas := map[string]bool{}
+	for _, params := range *req {
+		if params.Body == "" && params.BodySha != "" {
+			bodyShas[params.BodySha] = true
+		}
+	}
+
+	if len(bodyShas) > 0 {
+		existingContexts, err := GetPlanContexts(orgId, planId, false)
+		if err != nil {
+			return nil, fmt.Errorf("error getting existing contexts: %v", err)
+		}
+
+		existingById := map[string]*Context{}
+		for _, context := range existingContexts {
+			existingById[context.Id] = context
+		}
+
+		bodiesBySha, err := getBodiesBySha(orgId, planId, existingContexts, bodyShas)
+		if err != nil {
+			return nil, fmt.Errorf("error getting context bodies: %v", err)
+		}
+
+		var missingShas []string
+		for id, params := range *req {
+			if params.Body != "" || params.BodySha == "" {
+				continue
+			}
+
+			existing := existingById[id]
+			if existing != nil && existing.Sha == params.BodySha {
+				delete(*req, id)
+				continue
+			}
+
+			body, ok := bodiesBySha[params.BodySha]
+			// a summarized context is re-summarized from the full body before locking
+			if !ok || (existing != nil && existing.Summarized) {
+				missingShas = append(missingShas, params.BodySha)
+				continue
+			}
+			params.Body = body
+		}
+
+		if len(missingShas) > 0 {
+			return &shared.UpdateContextResponse{
+				MissingBodyShas: missingShas,
+			}, nil
+		}
+
+		if len(*req) == 0 {
+			return &shared.UpdateContextResponse{
+				TotalTokens: totalTokens,
+				Msg:         "Context is up to date",
+			}, nil
+		}
+	}
+
 	var updatedContexts []*shared.Context
 
 	numFiles := 0
 	numUrls := 0
 	numTrees := 0
+	numMaps := 0
+	numSchemas := 0
 
 	var mu sync.Mutex
 	errCh := make(chan error)
@@ -384,7 +703,7 @@ func UpdateContexts(params UpdateContextsParams) (*shared.UpdateContextResponse,
 
 			contextsById[id] = context
 			updatedContexts = append(updatedContexts, context.ToApi())
-			updateNumTokens, err := shared.GetNumTokens(params.Body)
+			updateNumTokens, err := shared.GetContextNumTokens(context.ContextType, params.Body)
 
 			if err != nil {
 				errCh <- fmt.Errorf("error getting num tokens: %v", err)
@@ -399,12 +718,16 @@ func UpdateContexts(params UpdateContextsParams) (*shared.UpdateContextResponse,
 			context.NumTokens = updateNumTokens
 
 			switch context.ContextType {
-			case shared.ContextFileType:
+			case shared.ContextFileType, shared.ContextImageType, shared.ContextSpecType:
 				numFiles++
 			case shared.ContextURLType:
 				numUrls++
 			case shared.ContextDirectoryTreeType:
 				numTrees++
+			case shared.ContextMapType:
+				numMaps++
+			case shared.ContextDbSchemaType:
+				numSchemas++
 			}
 
 			errCh <- nil
@@ -426,6 +749,8 @@ func UpdateContexts(params UpdateContextsParams) (*shared.UpdateContextResponse,
 		NumFiles:        numFiles,
 		NumUrls:         numUrls,
 		NumTrees:        numTrees,
+		NumMaps:         numMaps,
+		NumSchemas:      numSchemas,
 		MaxTokens:       maxTokens,
 	}
 
@@ -440,7 +765,7 @@ func UpdateContexts(params UpdateContextsParams) (*shared.UpdateContextResponse,
 
 	filesToLoad := map[string]string{}
 	for _, context := range updatedContexts {
-		if context.ContextType == shared.ContextFileType {
+		if context.ContextType == shared.ContextFileType && !context.Summarized {
 			filesToLoad[context.FilePath] = (*req)[context.Id].Body
 		}
 	}
@@ -453,6 +778,7 @@ func UpdateContexts(params UpdateContextsParams) (*shared.UpdateContextResponse,
 	}
 
 	errCh = make(chan error)
+	summarizedShas := params.SummarizedShas
 
 	for id, params := range *req {
 		go func(id string, params *shared.UpdateContextParams) {
@@ -462,6 +788,10 @@ func UpdateContexts(params UpdateContextsParams) (*shared.UpdateContextResponse,
 			hash := sha256.Sum256([]byte(params.Body))
 			sha := hex.EncodeToString(hash[:])
 
+			if originalSha, ok := summarizedShas[id]; ok {
+				sha = originalSha
+			}
+
 			context.Body = params.Body
 			context.Sha = sha
 
@@ -573,3 +903,79 @@ func invalidateConflictedResults(orgId, planId string, filesToLoad map[string]st
 
 	return nil
 }
+
+// GetSharedContext reads a context from a plan at the latest commit on a branch, without checking the branch out, so it doesn't need the plan's repo lock
+func GetSharedContext(orgId, planId, branch, contextId string, includeBody bool) (*Context, error) {
+	dir := getPlanDir(orgId, planId)
+
+	metaBytes, err := gitShowFile(dir, branch, "context/"+contextId+".meta")
+	if err != nil {
+		return nil, fmt.Errorf("error reading shared context meta: %v", err)
+	}
+
+	var context Context
+	err = json.Unmarshal(metaBytes, &context)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling shared context meta: %v", err)
+	}
+
+	if includeBody && context.BodyBlob != "" {
+		err = loadContextBlob(orgId, &context)
+		if err != nil {
+			return nil, err
+		}
+	} else if includeBody {
+		bodyBytes, err := gitShowFile(dir, branch, "context/"+contextId+".body")
+		if err != nil {
+			return nil, fmt.Errorf("error reading shared context body: %v", err)
+		}
+
+		err = setContextBody(orgId, &context, bodyBytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &context, nil
+}
+
+// resolveSharedContext fills in a shared context's current tokens, sha, and body from its source.
+// If the source plan or context was removed, the context is left as stored with an empty body.
+func resolveSharedContext(orgId string, context *Context, includeBody bool) {
+	source, err := GetSharedContext(orgId, context.SourcePlanId, context.SourceBranch, context.SourceContextId, includeBody)
+	if err != nil {
+		log.Printf("error resolving shared context %s from plan %s: %v\n", context.Id, context.SourcePlanId, err)
+		context.Body = ""
+		return
+	}
+
+	context.NumTokens = source.NumTokens
+	context.Sha = source.Sha
+	context.Body = source.Body
+	if source.UpdatedAt.After(context.UpdatedAt) {
+		context.UpdatedAt = source.UpdatedAt
+	}
+}
+
+func loadContextBlob(orgId string, context *Context) error {
+	// a context's meta can come from an imported archive, so it can only point to a body in its own plan's part of the store
+	prefix := planBlobPrefix(orgId, context.PlanId) + "/"
+	if !strings.HasPrefix(context.BodyBlob, prefix) || strings.Contains(context.BodyBlob, "..") {
+		return fmt.Errorf("context body %s isn't in the plan's blob store", context.BodyBlob)
+	}
+
+	body, err := blobs.Get(context.BodyBlob)
+	if err != nil {
+		return fmt.Errorf("error reading context body: %v", err)
+	}
+	return setContextBody(orgId, context, body)
+}
+
+func setContextBody(orgId string, context *Context, stored []byte) error {
+	body, err := decryptForOrg(orgId, stored)
+	if err != nil {
+		return fmt.Errorf("error decrypting context body: %v", err)
+	}
+	context.Body = string(body)
+	return nil
+}
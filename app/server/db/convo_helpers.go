@@ -38,6 +38,13 @@ func GetPlanConvo(orgId, planId string) ([]*ConvoMessage, error) {
 				return
 			}
 
+			bytes, err = decryptForOrg(orgId, bytes)
+
+			if err != nil {
+				errCh <- fmt.Errorf("error decrypting convo file: %v", err)
+				return
+			}
+
 			var convoMessage ConvoMessage
 			err = json.Unmarshal(bytes, &convoMessage)
 
@@ -67,6 +74,56 @@ func GetPlanConvo(orgId, planId string) ([]*ConvoMessage, error) {
 	return convo, nil
 }
 
+// NextConvoMessageNum is the number for a new message added to the conversation. Once a conversation is compacted, it
+// has fewer messages than its highest number, so the count can't be used.
+func NextConvoMessageNum(convo []*ConvoMessage) int {
+	if len(convo) == 0 {
+		return 1
+	}
+	return convo[len(convo)-1].Num + 1
+}
+
+// CompactConvo replaces the compacted messages with a single summary message. The summary takes the place of the last
+// message it replaces, with its number and timestamp, so later messages keep their numbers and stay in order.
+func CompactConvo(orgId, planId string, compacted []*ConvoMessage, summaryMsg *ConvoMessage) error {
+	if len(compacted) == 0 {
+		return fmt.Errorf("no messages to compact")
+	}
+
+	convoDir := getPlanConversationDir(orgId, planId)
+	last := compacted[len(compacted)-1]
+
+	if summaryMsg.Id == "" {
+		summaryMsg.Id = uuid.New().String()
+	}
+	summaryMsg.Num = last.Num
+	summaryMsg.CreatedAt = last.CreatedAt
+
+	bytes, err := json.Marshal(summaryMsg)
+	if err != nil {
+		return fmt.Errorf("error marshalling convo summary message: %v", err)
+	}
+
+	bytes, err = encryptForOrg(orgId, bytes)
+	if err != nil {
+		return fmt.Errorf("error encrypting convo summary message: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(convoDir, summaryMsg.Id+".json"), bytes, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("error writing convo summary message: %v", err)
+	}
+
+	for _, msg := range compacted {
+		err = os.Remove(filepath.Join(convoDir, msg.Id+".json"))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing compacted convo message: %v", err)
+		}
+	}
+
+	return nil
+}
+
 func StoreConvoMessage(message *ConvoMessage, currentUserId, branch string, commit bool) (string, error) {
 	convoDir := getPlanConversationDir(message.OrgId, message.PlanId)
 
@@ -84,6 +141,12 @@ func StoreConvoMessage(message *ConvoMessage, currentUserId, branch string, comm
 		return "", fmt.Errorf("error marshalling convo message: %v", err)
 	}
 
+	bytes, err = encryptForOrg(message.OrgId, bytes)
+
+	if err != nil {
+		return "", fmt.Errorf("error encrypting convo message: %v", err)
+	}
+
 	err = os.MkdirAll(convoDir, os.ModePerm)
 
 	if err != nil {
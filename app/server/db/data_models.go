@@ -248,10 +248,136 @@ func (role *OrgRole) ToApi() *shared.OrgRole {
 	}
 }
 
+type PlanShare struct {
+	Id         string                `db:"id"`
+	OrgId      string                `db:"org_id"`
+	PlanId     string                `db:"plan_id"`
+	UserId     *string               `db:"user_id"`
+	Permission shared.PlanPermission `db:"permission"`
+	CreatedBy  *string               `db:"created_by"`
+	CreatedAt  time.Time             `db:"created_at"`
+	UpdatedAt  time.Time             `db:"updated_at"`
+
+	// joined from users when listing
+	UserEmail *string `db:"user_email"`
+	UserName  *string `db:"user_name"`
+}
+
+func (share *PlanShare) ToApi() *shared.PlanShare {
+	res := &shared.PlanShare{
+		Id:         share.Id,
+		PlanId:     share.PlanId,
+		UserId:     share.UserId,
+		Permission: share.Permission,
+		CreatedAt:  share.CreatedAt,
+		UpdatedAt:  share.UpdatedAt,
+	}
+	if share.UserEmail != nil {
+		res.UserEmail = *share.UserEmail
+	}
+	if share.UserName != nil {
+		res.UserName = *share.UserName
+	}
+	return res
+}
+
+type ApiKey struct {
+	Id         string             `db:"id"`
+	OrgId      string             `db:"org_id"`
+	UserId     string             `db:"user_id"`
+	Name       string             `db:"name"`
+	Scope      shared.ApiKeyScope `db:"scope"`
+	KeyHash    string             `db:"key_hash"`
+	KeyPrefix  string             `db:"key_prefix"`
+	ExpiresAt  *time.Time         `db:"expires_at"`
+	LastUsedAt *time.Time         `db:"last_used_at"`
+	RevokedAt  *time.Time         `db:"revoked_at"`
+	CreatedAt  time.Time          `db:"created_at"`
+}
+
+func (key *ApiKey) ToApi() *shared.ApiKey {
+	return &shared.ApiKey{
+		Id:         key.Id,
+		OrgId:      key.OrgId,
+		Name:       key.Name,
+		Scope:      key.Scope,
+		KeyPrefix:  key.KeyPrefix,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+type Quota struct {
+	Id                string    `db:"id"`
+	OrgId             string    `db:"org_id"`
+	UserId            *string   `db:"user_id"`
+	RequestsPerMinute *int      `db:"requests_per_minute"`
+	TokensPerDay      *int      `db:"tokens_per_day"`
+	ConcurrentStreams *int      `db:"concurrent_streams"`
+	CreatedAt         time.Time `db:"created_at"`
+	UpdatedAt         time.Time `db:"updated_at"`
+	// only set when listing
+	UserEmail *string `db:"user_email"`
+}
+
+func (quota *Quota) ToApi() *shared.Quota {
+	res := &shared.Quota{
+		OrgId:             quota.OrgId,
+		RequestsPerMinute: quota.RequestsPerMinute,
+		TokensPerDay:      quota.TokensPerDay,
+		ConcurrentStreams: quota.ConcurrentStreams,
+		UpdatedAt:         quota.UpdatedAt,
+	}
+	if quota.UserId != nil {
+		res.UserId = *quota.UserId
+	}
+	if quota.UserEmail != nil {
+		res.UserEmail = *quota.UserEmail
+	}
+	return res
+}
+
+// SsoLogin tracks a sign-in with the identity provider from when the CLI starts it until the CLI picks up the session
+type SsoLogin struct {
+	Id            string `db:"id"`
+	State         string `db:"state"`
+	Nonce         string `db:"nonce"`
+	CodeVerifier  string `db:"code_verifier"`
+	PollTokenHash string `db:"poll_token_hash"`
+	UserCodeHash  string `db:"user_code_hash"`
+	// set once the user signs in with the identity provider, along with UserId, and checked when they enter the code
+	ConfirmTokenHash *string    `db:"confirm_token_hash"`
+	UserId           *string    `db:"user_id"`
+	Error            *string    `db:"error"`
+	CompletedAt      *time.Time `db:"completed_at"`
+	CreatedAt        time.Time  `db:"created_at"`
+}
+
+type ModelUsage struct {
+	Id           string    `db:"id"`
+	OrgId        string    `db:"org_id"`
+	UserId       string    `db:"user_id"`
+	PlanId       string    `db:"plan_id"`
+	PlanName     string    `db:"plan_name"`
+	Branch       string    `db:"branch"`
+	ModelRole    string    `db:"model_role"`
+	ModelName    string    `db:"model_name"`
+	InputTokens  int       `db:"input_tokens"`
+	OutputTokens int       `db:"output_tokens"`
+	CostUsd      float64   `db:"cost_usd"`
+	CreatedAt    time.Time `db:"created_at"`
+	// the part of InputTokens that was read from the model's prompt cache, and how much that saved
+	CachedInputTokens int     `db:"cached_input_tokens"`
+	CacheSavingsUsd   float64 `db:"cache_savings_usd"`
+}
+
 type ModelStream struct {
-	Id              string     `db:"id"`
-	OrgId           string     `db:"org_id"`
-	PlanId          string     `db:"plan_id"`
+	Id     string `db:"id"`
+	OrgId  string `db:"org_id"`
+	PlanId string `db:"plan_id"`
+	// the user who started the stream. nil for streams from before it was tracked.
+	UserId          *string    `db:"user_id"`
 	InternalIp      string     `db:"internal_ip"`
 	Branch          string     `db:"branch"`
 	LastHeartbeatAt time.Time  `db:"last_heartbeat_at"`
@@ -305,8 +431,24 @@ type Context struct {
 	NumTokens       int                `json:"numTokens"`
 	Body            string             `json:"body,omitempty"`
 	ForceSkipIgnore bool               `json:"forceSkipIgnore"`
-	CreatedAt       time.Time          `json:"createdAt"`
-	UpdatedAt       time.Time          `json:"updatedAt"`
+	MaxDepth        int                `json:"maxDepth,omitempty"`
+	PdfPages        string             `json:"pdfPages,omitempty"`
+	Rendered        bool               `json:"rendered,omitempty"`
+	Raw             bool               `json:"raw,omitempty"`
+	Summarized      bool               `json:"summarized,omitempty"`
+	ChunkPart       int                `json:"chunkPart,omitempty"`
+	ChunkMaxTokens  int                `json:"chunkMaxTokens,omitempty"`
+	LineRange       string             `json:"lineRange,omitempty"`
+	NoRedact        bool               `json:"noRedact,omitempty"`
+	Pinned          bool               `json:"pinned,omitempty"`
+	// set when the body is in the blob store rather than in a .body file next to the meta
+	BodyBlob string `json:"bodyBlob,omitempty"`
+	// shared contexts are read from another plan's context at the latest commit on SourceBranch, so updates to the source propagate
+	SourcePlanId    string    `json:"sourcePlanId,omitempty"`
+	SourceBranch    string    `json:"sourceBranch,omitempty"`
+	SourceContextId string    `json:"sourceContextId,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
 }
 
 func (context *Context) ToApi() *shared.Context {
@@ -321,6 +463,19 @@ func (context *Context) ToApi() *shared.Context {
 		NumTokens:       context.NumTokens,
 		Body:            context.Body,
 		ForceSkipIgnore: context.ForceSkipIgnore,
+		MaxDepth:        context.MaxDepth,
+		PdfPages:        context.PdfPages,
+		Rendered:        context.Rendered,
+		Raw:             context.Raw,
+		Summarized:      context.Summarized,
+		ChunkPart:       context.ChunkPart,
+		ChunkMaxTokens:  context.ChunkMaxTokens,
+		LineRange:       context.LineRange,
+		NoRedact:        context.NoRedact,
+		Pinned:          context.Pinned,
+		SourcePlanId:    context.SourcePlanId,
+		SourceBranch:    context.SourceBranch,
+		SourceContextId: context.SourceContextId,
 		CreatedAt:       context.CreatedAt,
 		UpdatedAt:       context.UpdatedAt,
 	}
@@ -337,18 +492,21 @@ type ConvoMessage struct {
 	Message   string    `json:"message"`
 	Stopped   bool      `json:"stopped"`
 	CreatedAt time.Time `json:"createdAt"`
+	// set on the summary message that replaced older messages when the conversation was compacted
+	Compaction *shared.ConvoCompaction `json:"compaction,omitempty"`
 }
 
 func (msg *ConvoMessage) ToApi() *shared.ConvoMessage {
 	return &shared.ConvoMessage{
-		Id:        msg.Id,
-		UserId:    msg.UserId,
-		Role:      msg.Role,
-		Tokens:    msg.Tokens,
-		Num:       msg.Num,
-		Message:   msg.Message,
-		Stopped:   msg.Stopped,
-		CreatedAt: msg.CreatedAt,
+		Id:         msg.Id,
+		UserId:     msg.UserId,
+		Role:       msg.Role,
+		Tokens:     msg.Tokens,
+		Num:        msg.Num,
+		Message:    msg.Message,
+		Stopped:    msg.Stopped,
+		Compaction: msg.Compaction,
+		CreatedAt:  msg.CreatedAt,
 	}
 }
 
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -36,6 +37,17 @@ func Connect() error {
 		}
 	}
 
+	if strings.HasPrefix(dbUrl, sqliteUrlPrefix) {
+		err = connectSqlite(dbUrl)
+		if err != nil {
+			return err
+		}
+
+		log.Println("connected to sqlite database")
+
+		return nil
+	}
+
 	Conn, err = sqlx.Connect("postgres", dbUrl)
 	if err != nil {
 		return err
@@ -57,18 +69,17 @@ func MigrationsUp() error {
 		return errors.New("db not initialized")
 	}
 
-	driver, err := postgres.WithInstance(Conn.DB, &postgres.Config{})
+	var m *migrate.Migrate
+	var err error
 
-	if err != nil {
-		return fmt.Errorf("error creating postgres driver: %v", err)
+	if IsSqlite {
+		m, err = newSqliteMigrate()
+	} else {
+		m, err = newPostgresMigrate()
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://migrations",
-		"postgres", driver)
-
 	if err != nil {
-		return fmt.Errorf("error creating migration instance: %v", err)
+		return err
 	}
 
 	// Uncomment below (and update migration version) to reset migration state to a specific version after a failure
@@ -118,3 +129,21 @@ func MigrationsUp() error {
 
 	return nil
 }
+
+func newPostgresMigrate() (*migrate.Migrate, error) {
+	driver, err := postgres.WithInstance(Conn.DB, &postgres.Config{})
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating postgres driver: %v", err)
+	}
+
+	m, err := migrate.NewWithDatabaseInstance(
+		"file://migrations",
+		"postgres", driver)
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating migration instance: %v", err)
+	}
+
+	return m, nil
+}
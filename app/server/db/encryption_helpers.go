@@ -0,0 +1,137 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"plandex-server/encryption"
+	"strings"
+	"sync"
+)
+
+// prefix for encrypted values in text columns, which hold base64 rather than raw bytes
+const encryptedTextPrefix = "pdxenc1:"
+
+type orgDataKey struct {
+	WrappedKey []byte `db:"wrapped_key"`
+	KeySource  string `db:"key_source"`
+}
+
+// unwrapped data keys by org id, so the master key (a KMS call or a slow key derivation) is only used once per org
+var dataKeys = map[string][]byte{}
+var dataKeysMu sync.Mutex
+
+// getOrgDataKey returns an org's data key, creating it the first time the org stores anything encrypted
+func getOrgDataKey(orgId string) ([]byte, error) {
+	dataKeysMu.Lock()
+	defer dataKeysMu.Unlock()
+
+	if key, ok := dataKeys[orgId]; ok {
+		return key, nil
+	}
+
+	masterKey := encryption.GetMasterKey()
+	if masterKey == nil {
+		return nil, fmt.Errorf("stored data is encrypted, but no master key is configured. Set PLANDEX_ENCRYPTION_KMS_KEY_ID or PLANDEX_ENCRYPTION_PASSPHRASE")
+	}
+
+	var stored orgDataKey
+	err := Conn.Get(&stored, "SELECT wrapped_key, key_source FROM org_data_keys WHERE org_id = $1", orgId)
+
+	if err == sql.ErrNoRows {
+		key, err := encryption.NewDataKey()
+		if err != nil {
+			return nil, err
+		}
+
+		wrapped, err := masterKey.Wrap(orgId, key)
+		if err != nil {
+			return nil, err
+		}
+
+		// another server may have created the key first, so whichever is stored is the one used
+		_, err = Conn.Exec("INSERT INTO org_data_keys (org_id, wrapped_key, key_source) VALUES ($1, $2, $3) ON CONFLICT (org_id) DO NOTHING", orgId, wrapped, masterKey.Source())
+		if err != nil {
+			return nil, fmt.Errorf("error storing org data key: %v", err)
+		}
+
+		err = Conn.Get(&stored, "SELECT wrapped_key, key_source FROM org_data_keys WHERE org_id = $1", orgId)
+		if err != nil {
+			return nil, fmt.Errorf("error getting org data key: %v", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting org data key: %v", err)
+	}
+
+	if stored.KeySource != masterKey.Source() {
+		return nil, fmt.Errorf("org data key was wrapped by master key '%s', but the configured master key is '%s'", stored.KeySource, masterKey.Source())
+	}
+
+	key, err := masterKey.Unwrap(orgId, stored.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKeys[orgId] = key
+
+	return key, nil
+}
+
+// encryptForOrg encrypts data with the org's data key if encryption is on, and otherwise returns it as is
+func encryptForOrg(orgId string, data []byte) ([]byte, error) {
+	if !encryption.Enabled() {
+		return data, nil
+	}
+
+	key, err := getOrgDataKey(orgId)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryption.Seal(key, data, []byte(orgId))
+}
+
+// decryptForOrg decrypts data if it's encrypted. Data stored before encryption was turned on is returned as is.
+func decryptForOrg(orgId string, data []byte) ([]byte, error) {
+	if !encryption.IsEncrypted(data) {
+		return data, nil
+	}
+
+	key, err := getOrgDataKey(orgId)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryption.Open(key, data, []byte(orgId))
+}
+
+func encryptTextForOrg(orgId, text string) (string, error) {
+	if !encryption.Enabled() {
+		return text, nil
+	}
+
+	encrypted, err := encryptForOrg(orgId, []byte(text))
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedTextPrefix + base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+func decryptTextForOrg(orgId, text string) (string, error) {
+	if !strings.HasPrefix(text, encryptedTextPrefix) {
+		return text, nil
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(text, encryptedTextPrefix))
+	if err != nil {
+		return "", fmt.Errorf("error decoding encrypted text: %v", err)
+	}
+
+	decrypted, err := decryptForOrg(orgId, encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	return string(decrypted), nil
+}
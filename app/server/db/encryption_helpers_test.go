@@ -0,0 +1,79 @@
+package db
+
+import (
+	"bytes"
+	"plandex-server/encryption"
+	"testing"
+)
+
+// setupEncryption turns encryption on with a passphrase master key until the test ends
+func setupEncryption(t *testing.T) {
+	t.Cleanup(func() {
+		// runs after t.Setenv restores the environment, so the master key goes back to what it was
+		encryption.Init()
+		dataKeys = map[string][]byte{}
+	})
+	t.Setenv("PLANDEX_ENCRYPTION_KMS_KEY_ID", "")
+	t.Setenv("PLANDEX_ENCRYPTION_PASSPHRASE", "correct horse battery staple")
+
+	err := encryption.Init()
+	if err != nil {
+		t.Fatalf("encryption.Init() error = %v", err)
+	}
+}
+
+func TestEncryptForOrg(t *testing.T) {
+	setupSqliteTestDb(t)
+	setupEncryption(t)
+
+	mustExec(t, "INSERT INTO users (id, name, email, domain, is_trial) VALUES ('user-a', 'A', 'a@example.com', 'example.com', false)")
+	mustExec(t, "INSERT INTO orgs (id, name, domain, owner_id, is_trial) VALUES ('org-1', 'Org 1', 'example.com', 'user-a', false)")
+	mustExec(t, "INSERT INTO orgs (id, name, domain, owner_id, is_trial) VALUES ('org-2', 'Org 2', 'example.org', 'user-a', false)")
+
+	plaintext := []byte("package main\n")
+
+	encrypted, err := encryptForOrg("org-1", plaintext)
+	if err != nil {
+		t.Fatalf("encryptForOrg() error = %v", err)
+	}
+	if !encryption.IsEncrypted(encrypted) {
+		t.Fatalf("encryptForOrg() didn't encrypt")
+	}
+
+	decrypted, err := decryptForOrg("org-1", encrypted)
+	if err != nil {
+		t.Fatalf("decryptForOrg() error = %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("decryptForOrg() = %q, want %q", decrypted, plaintext)
+	}
+
+	// each org has its own data key, and the org id is the additional data, so another org can't read it
+	_, err = decryptForOrg("org-2", encrypted)
+	if err == nil {
+		t.Errorf("decryptForOrg() for a different org succeeded")
+	}
+
+	// data stored before encryption was turned on has no header and is read as is
+	decrypted, err = decryptForOrg("org-1", plaintext)
+	if err != nil {
+		t.Fatalf("decryptForOrg() of plaintext error = %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("decryptForOrg() of plaintext = %q, want %q", decrypted, plaintext)
+	}
+
+	text, err := decryptTextForOrg("org-1", "a summary from before encryption")
+	if err != nil || text != "a summary from before encryption" {
+		t.Errorf("decryptTextForOrg() of plaintext = %q, %v", text, err)
+	}
+
+	encryptedText, err := encryptTextForOrg("org-1", "a summary")
+	if err != nil {
+		t.Fatalf("encryptTextForOrg() error = %v", err)
+	}
+	text, err = decryptTextForOrg("org-1", encryptedText)
+	if err != nil || text != "a summary" {
+		t.Errorf("decryptTextForOrg() = %q, %v", text, err)
+	}
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RejectedFileFeedback is a comment left when rejecting changes to a file. It's kept until the model has replied to the next
+// prompt, so the plan can take it into account.
+type RejectedFileFeedback struct {
+	Path      string    `json:"path"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func getPlanRejectedFileFeedbackPath(orgId, planId string) string {
+	return filepath.Join(getPlanDir(orgId, planId), "rejected_file_feedback.json")
+}
+
+func GetRejectedFileFeedback(orgId, planId string) ([]*RejectedFileFeedback, error) {
+	bytes, err := os.ReadFile(getPlanRejectedFileFeedbackPath(orgId, planId))
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*RejectedFileFeedback{}, nil
+		}
+		return nil, fmt.Errorf("error reading rejected file feedback: %v", err)
+	}
+
+	var feedback []*RejectedFileFeedback
+	err = json.Unmarshal(bytes, &feedback)
+
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling rejected file feedback: %v", err)
+	}
+
+	return feedback, nil
+}
+
+func AddRejectedFileFeedback(orgId, planId, path, comment string) error {
+	feedback, err := GetRejectedFileFeedback(orgId, planId)
+
+	if err != nil {
+		return err
+	}
+
+	feedback = append(feedback, &RejectedFileFeedback{
+		Path:      path,
+		Comment:   comment,
+		CreatedAt: time.Now(),
+	})
+
+	bytes, err := json.Marshal(feedback)
+
+	if err != nil {
+		return fmt.Errorf("error marshalling rejected file feedback: %v", err)
+	}
+
+	err = os.WriteFile(getPlanRejectedFileFeedbackPath(orgId, planId), bytes, 0644)
+
+	if err != nil {
+		return fmt.Errorf("error writing rejected file feedback: %v", err)
+	}
+
+	return nil
+}
+
+func ClearRejectedFileFeedback(orgId, planId string) error {
+	err := os.Remove(getPlanRejectedFileFeedbackPath(orgId, planId))
+
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing rejected file feedback: %v", err)
+	}
+
+	return nil
+}
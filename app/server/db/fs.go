@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"plandex-server/blobs"
 )
 
 var BaseDir string
@@ -61,6 +62,12 @@ func DeletePlanDir(orgId, planId string) error {
 		return fmt.Errorf("error deleting plan dir: %v", err)
 	}
 
+	err = blobs.DeletePrefix(planBlobPrefix(orgId, planId) + "/")
+
+	if err != nil {
+		return fmt.Errorf("error deleting plan blobs: %v", err)
+	}
+
 	return nil
 }
 
@@ -68,6 +75,11 @@ func getPlanDir(orgId, planId string) string {
 	return filepath.Join(BaseDir, "orgs", orgId, "plans", planId)
 }
 
+// plan blobs use the same layout as plan dirs, so a bucket lifecycle rule can target one org's plans
+func planBlobPrefix(orgId, planId string) string {
+	return fmt.Sprintf("orgs/%s/plans/%s", orgId, planId)
+}
+
 func getPlanContextDir(orgId, planId string) string {
 	return filepath.Join(getPlanDir(orgId, planId), "context")
 }
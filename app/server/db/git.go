@@ -1,8 +1,10 @@
 package db
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -325,6 +327,104 @@ func gitCommit(repoDir, commitMsg string) error {
 	return nil
 }
 
+// gitShowFile reads a file as of the latest commit on a branch, or any other revision, without checking it out
+func gitShowFile(repoDir, branch, path string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "-C", repoDir, "show", branch+":"+path)
+	cmd.Stderr = &stderr
+	res, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s on branch %s for dir: %s, err: %v, output: %s", path, branch, repoDir, err, stderr.String())
+	}
+
+	return res, nil
+}
+
+// gitResolveRevision returns the short sha of the commit that a revision like HEAD~3 or a sha from the plan's log refers to
+func gitResolveRevision(repoDir, rev string) (string, error) {
+	// a revision that starts with a dash would be read as an option
+	if rev == "" || strings.HasPrefix(rev, "-") {
+		return "", fmt.Errorf("invalid revision: %q", rev)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "-C", repoDir, "rev-parse", "--verify", "--short", rev+"^{commit}")
+	cmd.Stderr = &stderr
+	res, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("invalid revision %q for dir: %s, err: %v, output: %s", rev, repoDir, err, stderr.String())
+	}
+
+	return strings.TrimSpace(string(res)), nil
+}
+
+// gitListFiles lists the files in the given dirs as of a revision
+func gitListFiles(repoDir, rev string, dirs ...string) ([]string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", repoDir, "ls-tree", "-r", "--name-only", rev, "--"}, dirs...)...)
+	cmd.Stderr = &stderr
+	res, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error listing files at %s for dir: %s, err: %v, output: %s", rev, repoDir, err, stderr.String())
+	}
+
+	var paths []string
+	for _, path := range strings.Split(string(res), "\n") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths, nil
+}
+
+// gitShowFiles reads files as of a revision with a single git process, since a plan can have hundreds of them
+func gitShowFiles(repoDir, rev string, paths []string) (map[string][]byte, error) {
+	var input strings.Builder
+	for _, path := range paths {
+		input.WriteString(rev + ":" + path + "\n")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "-C", repoDir, "cat-file", "--batch")
+	cmd.Stdin = strings.NewReader(input.String())
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error reading files at %s for dir: %s, err: %v, output: %s", rev, repoDir, err, stderr.String())
+	}
+
+	// each file is a "<sha> <type> <size>" line followed by its contents and a newline
+	reader := bufio.NewReader(bytes.NewReader(out))
+	files := map[string][]byte{}
+	for _, path := range paths {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s at %s: %v", path, rev, err)
+		}
+
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("error reading %s at %s: %s", path, rev, strings.TrimSpace(header))
+		}
+
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s at %s: invalid size %s", path, rev, fields[2])
+		}
+
+		content := make([]byte, size+1)
+		_, err = io.ReadFull(reader, content)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s at %s: %v", path, rev, err)
+		}
+
+		files[path] = content[:size]
+	}
+
+	return files, nil
+}
+
 func gitRemoveIndexLockFileIfExists(repoDir string) error {
 	// Remove the lock file if it exists
 	lockFilePath := filepath.Join(repoDir, ".git", "index.lock")
@@ -124,6 +124,22 @@ func CreateOrg(req *shared.CreateOrgRequest, userId string, domain *string, tx *
 	return org, nil
 }
 
+// GetOrgByNameOrId finds an org by id, or by name if the name is unique, returning nil if it doesn't find exactly one
+func GetOrgByNameOrId(nameOrId string) (*Org, error) {
+	var orgs []*Org
+	err := Conn.Select(&orgs, "SELECT * FROM orgs WHERE (id::text = $1 OR name = $1) AND is_trial = false", nameOrId)
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting org: %v", err)
+	}
+
+	if len(orgs) != 1 {
+		return nil, nil
+	}
+
+	return orgs[0], nil
+}
+
 func GetOrgForDomain(domain string) (*Org, error) {
 	var org Org
 	err := Conn.Get(&org, "SELECT * FROM orgs WHERE domain = $1", domain)
@@ -212,3 +228,13 @@ func ListOrgRoles(orgId string) ([]*OrgRole, error) {
 
 	return orgRoles, nil
 }
+
+func UpdateOrgUserRole(orgId, userId, orgRoleId string, tx *sql.Tx) error {
+	_, err := tx.Exec("UPDATE orgs_users SET org_role_id = $3 WHERE org_id = $1 AND user_id = $2", orgId, userId, orgRoleId)
+
+	if err != nil {
+		return fmt.Errorf("error updating org member role: %v", err)
+	}
+
+	return nil
+}
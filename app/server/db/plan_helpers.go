@@ -15,6 +15,27 @@ import (
 	"github.com/sashabaranov/go-openai"
 )
 
+// GetUniquePlanName appends a numeric suffix (name.2, name.3, etc.) if the owner already has a plan with the same name in the project
+func GetUniquePlanName(projectId, ownerId, name string) (string, error) {
+	i := 2
+	originalName := name
+	for {
+		var count int
+		err := Conn.Get(&count, "SELECT COUNT(*) FROM plans WHERE project_id = $1 AND owner_id = $2 AND name = $3", projectId, ownerId, name)
+
+		if err != nil {
+			return "", err
+		}
+
+		if count == 0 {
+			return name, nil
+		}
+
+		name = originalName + "." + fmt.Sprint(i)
+		i++
+	}
+}
+
 func CreatePlan(orgId, projectId, userId, name string) (*Plan, error) {
 	// start a transaction
 	tx, err := Conn.Begin()
@@ -360,43 +381,50 @@ func DeleteOwnerPlans(orgId, projectId, userId string) error {
 	return nil
 }
 
-func ValidatePlanAccess(planId, userId, orgId string) (*Plan, error) {
+// ValidatePlanAccess returns the plan if the user can access it, along with their permission on it. Its owner, and users
+// whose org role lets them update any plan, can do everything; anyone else gets the permission the plan is shared with them.
+func ValidatePlanAccess(planId, userId, orgId string, canAccessAny bool) (*Plan, shared.PlanPermission, error) {
 	// get plan
 	plan, err := GetPlan(planId)
 
 	if err != nil {
-		return nil, fmt.Errorf("error getting plan: %v", err)
+		return nil, "", fmt.Errorf("error getting plan: %v", err)
 	}
 
 	if plan == nil {
-		return nil, nil
+		return nil, "", nil
 	}
 
 	if plan.OrgId != orgId {
-		return nil, nil
+		return nil, "", nil
 	}
 
 	hasProjectAccess, err := ProjectExists(orgId, plan.ProjectId)
 
 	if err != nil {
-		return nil, fmt.Errorf("error validating project membership: %v", err)
+		return nil, "", fmt.Errorf("error validating project membership: %v", err)
 	}
 
 	if !hasProjectAccess {
-		return nil, nil
+		return nil, "", nil
 	}
 
 	// owner has access
-	if plan.OwnerId == userId {
-		return plan, nil
+	if plan.OwnerId == userId || canAccessAny {
+		return plan, shared.PlanPermissionApply, nil
+	}
+
+	permission, err := GetPlanSharePermission(planId, userId)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("error getting plan share permission: %v", err)
 	}
 
-	// plan is shared with org
-	if plan.SharedWithOrgAt != nil {
-		return plan, nil
+	if permission == "" {
+		return nil, "", nil
 	}
 
-	return nil, nil
+	return plan, permission, nil
 }
 
 func BumpPlanUpdatedAt(planId string, t time.Time) error {
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/plandex/plandex/shared"
+)
+
+// GetPlanSharePermission returns the most a plan's shares allow a user to do, from shares with them and with their whole
+// org, or an empty permission if it isn't shared with them
+func GetPlanSharePermission(planId, userId string) (shared.PlanPermission, error) {
+	var permissions []shared.PlanPermission
+	err := Conn.Select(&permissions, "SELECT permission FROM plan_shares WHERE plan_id = $1 AND (user_id = $2 OR user_id IS NULL)", planId, userId)
+
+	if err != nil {
+		return "", fmt.Errorf("error getting plan shares: %v", err)
+	}
+
+	var res shared.PlanPermission
+	for _, permission := range permissions {
+		if !res.Allows(permission) {
+			res = permission
+		}
+	}
+
+	return res, nil
+}
+
+func ListPlanShares(planId string) ([]*PlanShare, error) {
+	var shares []*PlanShare
+	err := Conn.Select(&shares, `SELECT ps.*, u.email AS user_email, u.name AS user_name
+		FROM plan_shares ps
+		LEFT JOIN users u ON u.id = ps.user_id
+		WHERE ps.plan_id = $1
+		ORDER BY ps.user_id IS NOT NULL, ps.created_at`, planId)
+
+	if err != nil {
+		return nil, fmt.Errorf("error listing plan shares: %v", err)
+	}
+
+	return shares, nil
+}
+
+// SetPlanShare shares a plan with a user, or with the whole org if userId is nil, replacing the permission of an existing
+// share with them
+func SetPlanShare(orgId, planId string, userId *string, permission shared.PlanPermission, createdBy string) error {
+	var res sql.Result
+	var err error
+	if userId == nil {
+		res, err = Conn.Exec("UPDATE plan_shares SET permission = $1 WHERE plan_id = $2 AND user_id IS NULL", permission, planId)
+	} else {
+		res, err = Conn.Exec("UPDATE plan_shares SET permission = $1 WHERE plan_id = $2 AND user_id = $3", permission, planId, *userId)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error updating plan share: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error updating plan share: %v", err)
+	}
+
+	if n > 0 {
+		return nil
+	}
+
+	_, err = Conn.Exec("INSERT INTO plan_shares (org_id, plan_id, user_id, permission, created_by) VALUES ($1, $2, $3, $4, $5)", orgId, planId, userId, permission, createdBy)
+
+	if err != nil {
+		return fmt.Errorf("error creating plan share: %v", err)
+	}
+
+	return nil
+}
+
+// DeletePlanShare removes a share from a plan. It returns false if the plan has no such share.
+func DeletePlanShare(planId, shareId string) (bool, error) {
+	res, err := Conn.Exec("DELETE FROM plan_shares WHERE plan_id = $1 AND id = $2", planId, shareId)
+
+	if err != nil {
+		return false, fmt.Errorf("error deleting plan share: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error deleting plan share: %v", err)
+	}
+
+	return n > 0, nil
+}
+
+// ListSharedPlans lists plans in the projects that other users have shared with a user or their org, along with the user's
+// permission on each
+func ListSharedPlans(projectIds []string, userId string) ([]*Plan, map[string]shared.PlanPermission, error) {
+	var rows []struct {
+		PlanId     string                `db:"plan_id"`
+		Permission shared.PlanPermission `db:"permission"`
+	}
+	err := Conn.Select(&rows, `SELECT ps.plan_id, ps.permission
+		FROM plan_shares ps
+		JOIN plans p ON p.id = ps.plan_id
+		WHERE p.project_id = ANY($1) AND p.owner_id != $2 AND p.archived_at IS NULL AND (ps.user_id = $2 OR ps.user_id IS NULL)`, pq.Array(projectIds), userId)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing plan shares: %v", err)
+	}
+
+	permissionByPlanId := map[string]shared.PlanPermission{}
+	var planIds []string
+	for _, row := range rows {
+		current, ok := permissionByPlanId[row.PlanId]
+		if !ok {
+			planIds = append(planIds, row.PlanId)
+		}
+		if !current.Allows(row.Permission) {
+			permissionByPlanId[row.PlanId] = row.Permission
+		}
+	}
+
+	if len(planIds) == 0 {
+		return nil, permissionByPlanId, nil
+	}
+
+	var plans []*Plan
+	err = Conn.Select(&plans, "SELECT * FROM plans WHERE id = ANY($1) ORDER BY updated_at DESC", pq.Array(planIds))
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing shared plans: %v", err)
+	}
+
+	return plans, permissionByPlanId, nil
+}
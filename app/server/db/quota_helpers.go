@@ -0,0 +1,158 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// GetQuotasForUser returns the org's quota and the user's quota in the org. Either is nil if it isn't set.
+func GetQuotasForUser(orgId, userId string) (orgQuota *Quota, userQuota *Quota, err error) {
+	var quotas []*Quota
+	err = Conn.Select(&quotas, "SELECT * FROM quotas WHERE org_id = $1 AND (user_id IS NULL OR user_id = $2)", orgId, userId)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting quotas: %v", err)
+	}
+
+	for _, quota := range quotas {
+		if quota.UserId == nil {
+			orgQuota = quota
+		} else {
+			userQuota = quota
+		}
+	}
+
+	return orgQuota, userQuota, nil
+}
+
+// ListQuotas lists an org's quota, if it's set, followed by its users' quotas
+func ListQuotas(orgId string) ([]*Quota, error) {
+	var quotas []*Quota
+	err := Conn.Select(&quotas, "SELECT q.*, u.email AS user_email FROM quotas q LEFT JOIN users u ON u.id = q.user_id WHERE q.org_id = $1 ORDER BY q.user_id IS NOT NULL, u.email", orgId)
+
+	if err != nil {
+		return nil, fmt.Errorf("error listing quotas: %v", err)
+	}
+
+	return quotas, nil
+}
+
+// SetQuota updates the org's quota, or a user's if userId is set. It returns nil if the quota no longer has any limits,
+// in which case it's removed.
+func SetQuota(orgId string, userId *string, req *shared.SetQuotaRequest) (*Quota, error) {
+	var existing Quota
+	var err error
+	if userId == nil {
+		err = Conn.Get(&existing, "SELECT * FROM quotas WHERE org_id = $1 AND user_id IS NULL", orgId)
+	} else {
+		err = Conn.Get(&existing, "SELECT * FROM quotas WHERE org_id = $1 AND user_id = $2", orgId, *userId)
+	}
+
+	found := true
+	if err == sql.ErrNoRows {
+		found = false
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting quota: %v", err)
+	}
+
+	requestsPerMinute := mergeQuotaLimit(existing.RequestsPerMinute, req.RequestsPerMinute)
+	tokensPerDay := mergeQuotaLimit(existing.TokensPerDay, req.TokensPerDay)
+	concurrentStreams := mergeQuotaLimit(existing.ConcurrentStreams, req.ConcurrentStreams)
+
+	if requestsPerMinute == nil && tokensPerDay == nil && concurrentStreams == nil {
+		if found {
+			_, err = Conn.Exec("DELETE FROM quotas WHERE id = $1", existing.Id)
+			if err != nil {
+				return nil, fmt.Errorf("error removing quota: %v", err)
+			}
+		}
+		return nil, nil
+	}
+
+	var quota Quota
+	if found {
+		err = Conn.Get(&quota, "UPDATE quotas SET requests_per_minute = $1, tokens_per_day = $2, concurrent_streams = $3 WHERE id = $4 RETURNING *", requestsPerMinute, tokensPerDay, concurrentStreams, existing.Id)
+	} else {
+		err = Conn.Get(&quota, "INSERT INTO quotas (org_id, user_id, requests_per_minute, tokens_per_day, concurrent_streams) VALUES ($1, $2, $3, $4, $5) RETURNING *", orgId, userId, requestsPerMinute, tokensPerDay, concurrentStreams)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error setting quota: %v", err)
+	}
+
+	return &quota, nil
+}
+
+// a nil update leaves the limit as is, and 0 removes it
+func mergeQuotaLimit(current, update *int) *int {
+	if update == nil {
+		return current
+	}
+	if *update == 0 {
+		return nil
+	}
+	return update
+}
+
+// IncrementRequestCount counts a request in the window starting at windowStart, and returns the window's count so far
+func IncrementRequestCount(key string, windowStart time.Time) (int, error) {
+	var count int
+	err := Conn.Get(&count, "INSERT INTO rate_limit_windows (key, window_start, count) VALUES ($1, $2, 1) ON CONFLICT (key, window_start) DO UPDATE SET count = rate_limit_windows.count + 1 RETURNING count", key, windowStart)
+
+	if err != nil {
+		return 0, fmt.Errorf("error counting request: %v", err)
+	}
+
+	// the first request in a window cleans up the key's earlier windows
+	if count == 1 {
+		_, err = Conn.Exec("DELETE FROM rate_limit_windows WHERE key = $1 AND window_start < $2", key, windowStart)
+		if err != nil {
+			return 0, fmt.Errorf("error removing old rate limit windows: %v", err)
+		}
+	}
+
+	return count, nil
+}
+
+// GetTokensUsedSince totals model input and output tokens used in an org since a time, or by one user in the org if
+// userId is set
+func GetTokensUsedSince(orgId, userId string, since time.Time) (int, error) {
+	query := "SELECT COALESCE(SUM(input_tokens + output_tokens), 0) FROM model_usage WHERE org_id = $1 AND created_at >= $2"
+	args := []interface{}{orgId, since}
+	if userId != "" {
+		query += " AND user_id = $3"
+		args = append(args, userId)
+	}
+
+	var tokens int
+	err := Conn.Get(&tokens, query, args...)
+
+	if err != nil {
+		return 0, fmt.Errorf("error getting tokens used: %v", err)
+	}
+
+	return tokens, nil
+}
+
+// CountActiveModelStreams counts an org's streams that are still running, or one user's if userId is set. Streams whose
+// host stopped sending heartbeats aren't counted.
+func CountActiveModelStreams(orgId, userId string) (int, error) {
+	query := "SELECT COUNT(*) FROM model_streams WHERE org_id = $1 AND finished_at IS NULL AND last_heartbeat_at > $2"
+	args := []interface{}{orgId, time.Now().UTC().Add(-modelStreamHeartbeatTimeout)}
+	if userId != "" {
+		query += " AND user_id = $3"
+		args = append(args, userId)
+	}
+
+	var count int
+	err := Conn.Get(&count, query, args...)
+
+	if err != nil {
+		return 0, fmt.Errorf("error counting active model streams: %v", err)
+	}
+
+	return count, nil
+}
@@ -1,6 +1,9 @@
 package db
 
-import "fmt"
+import (
+	"database/sql"
+	"fmt"
+)
 
 func GetOrgOwnerRoleId() (string, error) {
 	var roleId string
@@ -23,3 +26,20 @@ func GetOrgMemberRoleId() (string, error) {
 
 	return roleId, nil
 }
+
+// GetOrgRoleIdByName returns the id of a default role or one of the org's own roles, or an empty string if there's no role
+// with that name
+func GetOrgRoleIdByName(orgId, name string) (string, error) {
+	var roleId string
+	err := Conn.Get(&roleId, "SELECT id FROM org_roles WHERE name = $1 AND (org_id IS NULL OR org_id = $2)", name, orgId)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("error getting role id: %v", err)
+	}
+
+	return roleId, nil
+}
@@ -322,9 +322,28 @@ func GetPlanResult(planFileResults []*shared.PlanFileResult) *shared.PlanResult
 	}
 }
 
-func ApplyPlan(orgId, userId, branchName string, plan *Plan) error {
+// ApplyPlan marks a plan's pending results as applied and updates the plan's context to match. With a request that lists paths,
+// only the results for those paths are applied. Any partly applied files get a new pending result for their rejected changes.
+func ApplyPlan(orgId, userId, branchName string, plan *Plan, req *shared.ApplyPlanRequest) error {
 	planId := plan.Id
 
+	var pathsToApply map[string]bool
+	partialByPath := map[string]*shared.PartialApply{}
+	if req != nil {
+		if len(req.Paths) > 0 {
+			pathsToApply = map[string]bool{}
+			for _, path := range req.Paths {
+				pathsToApply[path] = true
+			}
+		}
+		for path, partial := range req.PartialByPath {
+			partialByPath[path] = partial
+			if pathsToApply != nil {
+				pathsToApply[path] = true
+			}
+		}
+	}
+
 	resultsDir := getPlanResultsDir(orgId, planId)
 
 	errCh := make(chan error)
@@ -379,11 +398,22 @@ func ApplyPlan(orgId, userId, branchName string, plan *Plan) error {
 	}
 
 	var pendingDbResults []*PlanFileResult
+	anyStillPending := len(partialByPath) > 0
+	latestPendingByPath := map[string]*PlanFileResult{}
 
 	for _, result := range results {
 		apiResult := result.ToApi()
 		if apiResult.IsPending() {
+			if pathsToApply != nil && !pathsToApply[result.Path] {
+				anyStillPending = true
+				continue
+			}
 			pendingDbResults = append(pendingDbResults, result)
+
+			latest := latestPendingByPath[result.Path]
+			if latest == nil || result.CreatedAt.After(latest.CreatedAt) {
+				latestPendingByPath[result.Path] = result
+			}
 		}
 	}
 
@@ -416,6 +446,14 @@ func ApplyPlan(orgId, userId, branchName string, plan *Plan) error {
 		currentPlanState = res
 	}
 
+	// the body each applied file's context is updated to
+	appliedBody := func(path string) string {
+		if partial, ok := partialByPath[path]; ok {
+			return partial.Content
+		}
+		return currentPlanState.CurrentPlanFiles.Files[path]
+	}
+
 	errCh = make(chan error)
 	now := time.Now()
 
@@ -442,6 +480,11 @@ func ApplyPlan(orgId, userId, branchName string, plan *Plan) error {
 		}(result)
 	}
 
+	// descriptions stay pending until all of their changes are applied
+	if anyStillPending {
+		convoMessageDescriptions = nil
+	}
+
 	for _, description := range convoMessageDescriptions {
 		go func(description *ConvoMessageDescription) {
 			description.AppliedAt = &now
@@ -465,7 +508,7 @@ func ApplyPlan(orgId, userId, branchName string, plan *Plan) error {
 					ContextType: shared.ContextFileType,
 					Name:        path,
 					FilePath:    path,
-					Body:        currentPlanState.CurrentPlanFiles.Files[path],
+					Body:        appliedBody(path),
 				})
 			}
 
@@ -496,7 +539,7 @@ func ApplyPlan(orgId, userId, branchName string, plan *Plan) error {
 			for path := range pendingUpdatedFilesSet {
 				context := contextsByPath[path]
 				updateReq[context.Id] = &shared.UpdateContextParams{
-					Body: currentPlanState.CurrentPlanFiles.Files[path],
+					Body: appliedBody(path),
 				}
 			}
 
@@ -538,6 +581,30 @@ func ApplyPlan(orgId, userId, branchName string, plan *Plan) error {
 		}
 	}
 
+	for path, partial := range partialByPath {
+		latest := latestPendingByPath[path]
+		if latest == nil {
+			return fmt.Errorf("no pending changes for partly applied file %s", path)
+		}
+
+		for _, replacement := range partial.Replacements {
+			replacement.Id = uuid.New().String()
+		}
+
+		err := StorePlanResult(&PlanFileResult{
+			OrgId:          orgId,
+			PlanId:         planId,
+			ConvoMessageId: latest.ConvoMessageId,
+			PlanBuildId:    latest.PlanBuildId,
+			Path:           path,
+			Replacements:   partial.Replacements,
+		})
+
+		if err != nil {
+			return fmt.Errorf("error storing rejected changes for %s: %v", path, err)
+		}
+	}
+
 	msg := "✅ Marked pending results as applied"
 
 	if loadContextRes != nil && !loadContextRes.MaxTokensExceeded {
@@ -701,6 +768,112 @@ func RejectPlanFile(orgId, planId, file string, now time.Time) error {
 	return nil
 }
 
+// EditPlanFile replaces the plan's version of a file that has pending changes with the user's edit. The edit is stored as one
+// more pending change on top of the others, so it shows up and can be rejected like any other change. It returns false if the
+// edit didn't change the file.
+func EditPlanFile(orgId, planId, path, content string) (bool, error) {
+	planState, err := GetCurrentPlanState(CurrentPlanStateParams{
+		OrgId:  orgId,
+		PlanId: planId,
+	})
+
+	if err != nil {
+		return false, fmt.Errorf("error getting current plan state: %v", err)
+	}
+
+	current, ok := planState.CurrentPlanFiles.Files[path]
+	if !ok {
+		return false, fmt.Errorf("no pending changes for file: %s", path)
+	}
+
+	if current == content {
+		return false, nil
+	}
+
+	// the edit belongs to the reply that made the latest change to the file
+	results := planState.PlanResult.FileResultsByPath[path]
+	latest := results[len(results)-1]
+
+	old, new, startLine, endLine := editReplacement(current, content)
+
+	err = StorePlanResult(&PlanFileResult{
+		OrgId:          orgId,
+		PlanId:         planId,
+		ConvoMessageId: latest.ConvoMessageId,
+		PlanBuildId:    latest.PlanBuildId,
+		Path:           path,
+		Replacements: []*shared.Replacement{
+			{
+				Id:  uuid.New().String(),
+				Old: old,
+				New: new,
+				StreamedChange: &shared.StreamedChange{
+					Summary: "Edited by you",
+					Old: shared.StreamedChangeSection{
+						StartLine: startLine,
+						EndLine:   endLine,
+					},
+					New: new,
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// editReplacement is the smallest replacement of whole lines that turns before into after. It's widened until its old text
+// only appears once in before, so it can't be applied in the wrong place. The line numbers are 1-based and inclusive.
+func editReplacement(before, after string) (old, new string, startLine, endLine int) {
+	splitLines := func(s string) []string {
+		lines := strings.SplitAfter(s, "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		return lines
+	}
+	a := splitLines(before)
+	b := splitLines(after)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	start := prefix
+	aEnd := len(a) - suffix
+	bEnd := len(b) - suffix
+
+	for {
+		old = strings.Join(a[start:aEnd], "")
+		// occurrences can overlap, so the first and last are compared rather than counting them
+		if (old != "" && strings.Index(before, old) == strings.LastIndex(before, old)) || (start == 0 && aEnd == len(a)) {
+			break
+		}
+
+		if start > 0 {
+			start--
+		}
+		if aEnd < len(a) {
+			aEnd++
+			bEnd++
+		}
+	}
+
+	new = strings.Join(b[start:bEnd], "")
+
+	return old, new, start + 1, aEnd
+}
+
 func RejectReplacement(orgId, planId, resultId, replacementId string) error {
 	resultsDir := getPlanResultsDir(orgId, planId)
 
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// GetPlanRevision reads the plan's pending files, applied files, and contexts as of a revision in its repo, like HEAD~3 or a
+// sha from the plan's log, without checking it out
+func GetPlanRevision(orgId, planId, rev string) (*shared.PlanRevision, error) {
+	dir := getPlanDir(orgId, planId)
+
+	sha, err := gitResolveRevision(dir, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := gitListFiles(dir, sha, "context", "results", "descriptions")
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := gitShowFiles(dir, sha, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	// the slices aren't nil so GetCurrentPlanState doesn't load the current ones instead
+	results := []*PlanFileResult{}
+	descriptions := []*ConvoMessageDescription{}
+	contexts := []*Context{}
+	appliedAtByPath := map[string]time.Time{}
+
+	for _, path := range paths {
+		bytes := files[path]
+
+		switch filepath.Dir(path) {
+		case "results":
+			var result PlanFileResult
+			err = json.Unmarshal(bytes, &result)
+			if err != nil {
+				return nil, fmt.Errorf("error unmarshalling result file %s: %v", path, err)
+			}
+			results = append(results, &result)
+
+			if result.AppliedAt != nil && result.AppliedAt.After(appliedAtByPath[result.Path]) {
+				appliedAtByPath[result.Path] = *result.AppliedAt
+			}
+
+		case "descriptions":
+			var description ConvoMessageDescription
+			err = json.Unmarshal(bytes, &description)
+			if err != nil {
+				return nil, fmt.Errorf("error unmarshalling description file %s: %v", path, err)
+			}
+			if description.MadePlan && description.AppliedAt == nil {
+				descriptions = append(descriptions, &description)
+			}
+
+		case "context":
+			if !strings.HasSuffix(path, ".meta") {
+				continue
+			}
+
+			var context Context
+			err = json.Unmarshal(bytes, &context)
+			if err != nil {
+				return nil, fmt.Errorf("error unmarshalling context meta file %s: %v", path, err)
+			}
+
+			// a shared context's body lives in its source plan, which has its own history, so it's read as it is now
+			if context.SourcePlanId != "" {
+				resolveSharedContext(orgId, &context, true)
+			} else if context.BodyBlob != "" {
+				err = loadContextBlob(orgId, &context)
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				err = setContextBody(orgId, &context, files[strings.TrimSuffix(path, ".meta")+".body"])
+				if err != nil {
+					return nil, err
+				}
+			}
+			contexts = append(contexts, &context)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].CreatedAt.Before(results[j].CreatedAt)
+	})
+	sort.Slice(descriptions, func(i, j int) bool {
+		return descriptions[i].CreatedAt.Before(descriptions[j].CreatedAt)
+	})
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i].CreatedAt.Before(contexts[j].CreatedAt)
+	})
+
+	planState, err := GetCurrentPlanState(CurrentPlanStateParams{
+		OrgId:                    orgId,
+		PlanId:                   planId,
+		PlanFileResults:          results,
+		ConvoMessageDescriptions: descriptions,
+		Contexts:                 contexts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting plan state at %s: %v", sha, err)
+	}
+
+	var apiContexts []*shared.Context
+	for _, context := range contexts {
+		apiContexts = append(apiContexts, context.ToApi())
+	}
+
+	return &shared.PlanRevision{
+		Sha:             sha,
+		Files:           planState.CurrentPlanFiles.Files,
+		AppliedAtByPath: appliedAtByPath,
+		Contexts:        apiContexts,
+	}, nil
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// The server can run on SQLite instead of Postgres, so it can be self-hosted as a single binary with nothing else to set up.
+// It's selected with a DATABASE_URL like sqlite:///path/to/plandex.db. Queries are written for Postgres, and the sqlite
+// driver below translates the few constructs they use that SQLite doesn't have. now() and uuid_generate_v4() are added as
+// functions, so queries and the schema can use them as they do in Postgres.
+//
+// Timestamps are stored as UTC text with a fixed width, so comparing them as strings compares them as times.
+
+const sqliteUrlPrefix = "sqlite://"
+
+const sqliteDriverName = "plandex-sqlite"
+
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000"
+
+// sqlite migrations have the same versions as the postgres migrations they match
+//
+//go:embed sqlite_migrations/*.sql
+var sqliteMigrations embed.FS
+
+var IsSqlite bool
+
+func init() {
+	sqlite.MustRegisterScalarFunction("now", 0, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		return time.Now().UTC().Format(sqliteTimeFormat), nil
+	})
+
+	sqlite.MustRegisterScalarFunction("uuid_generate_v4", 0, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		return uuid.NewString(), nil
+	})
+
+	// sql.Open doesn't connect, it just gets the sqlite driver with the functions above
+	sqliteDb, err := sql.Open("sqlite", "")
+	if err != nil {
+		panic(fmt.Errorf("error loading sqlite driver: %v", err))
+	}
+	sql.Register(sqliteDriverName, sqliteDriver{sqliteDb.Driver()})
+	sqlx.BindDriver(sqliteDriverName, sqlx.DOLLAR)
+}
+
+func connectSqlite(dbUrl string) error {
+	path := strings.TrimPrefix(dbUrl, sqliteUrlPrefix)
+	if path == "" {
+		return fmt.Errorf("DATABASE_URL %s doesn't have a path", dbUrl)
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("error creating database dir: %v", err)
+	}
+
+	// write transactions take the database's write lock when they begin, which is what the postgres queries get from FOR UPDATE
+	dsn := path + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(30000)&_txlock=immediate"
+
+	Conn, err = sqlx.Connect(sqliteDriverName, dsn)
+	if err != nil {
+		return err
+	}
+
+	IsSqlite = true
+
+	return nil
+}
+
+// the sqlite schema is embedded, so the server binary can run from anywhere
+func newSqliteMigrate() (*migrate.Migrate, error) {
+	source, err := iofs.New(sqliteMigrations, "sqlite_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error loading sqlite migrations: %v", err)
+	}
+
+	driver, err := migratesqlite.WithInstance(Conn.DB, &migratesqlite.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("error creating sqlite driver: %v", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "sqlite", driver)
+	if err != nil {
+		return nil, fmt.Errorf("error creating migration instance: %v", err)
+	}
+
+	return m, nil
+}
+
+type sqliteDriver struct {
+	driver.Driver
+}
+
+func (d sqliteDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteConn{conn}, nil
+}
+
+type sqliteConn struct {
+	driver.Conn
+}
+
+func (c *sqliteConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(translateToSqlite(query))
+}
+
+func (c *sqliteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *sqliteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, translateToSqlite(query))
+}
+
+// CheckNamedValue binds pq arrays as json for json_each, and times as fixed width UTC text
+func (c *sqliteConn) CheckNamedValue(nv *driver.NamedValue) error {
+	switch v := nv.Value.(type) {
+	case *pq.StringArray:
+		return bindSqliteJson(nv, []string(*v))
+	case pq.StringArray:
+		return bindSqliteJson(nv, []string(v))
+	case pq.GenericArray:
+		return bindSqliteJson(nv, v.A)
+	}
+
+	v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+
+	if t, ok := v.(time.Time); ok {
+		v = t.UTC().Format(sqliteTimeFormat)
+	}
+
+	nv.Value = v
+	return nil
+}
+
+func bindSqliteJson(nv *driver.NamedValue, v any) error {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error encoding array: %v", err)
+	}
+	nv.Value = string(bytes)
+	return nil
+}
+
+var sqliteRewrites = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// with the array bound as json
+	{regexp.MustCompile(`=\s*ANY\((\$\d+)\)`), "IN (SELECT value FROM json_each($1))"},
+	// transactions already hold the write lock
+	{regexp.MustCompile(`\s+FOR UPDATE\b`), ""},
+	{regexp.MustCompile(`ON CONFLICT ON CONSTRAINT \w+`), "ON CONFLICT"},
+	{regexp.MustCompile(`TO_CHAR\((.+?), 'YYYY-MM-DD'\)`), "strftime('%Y-%m-%d', $1)"},
+	{regexp.MustCompile(`([\w.$]+)::date\b`), "date($1)"},
+	{regexp.MustCompile(`::(text|uuid)\b`), ""},
+}
+
+var sqliteQueries sync.Map
+
+func translateToSqlite(query string) string {
+	if translated, ok := sqliteQueries.Load(query); ok {
+		return translated.(string)
+	}
+
+	translated := query
+	for _, rewrite := range sqliteRewrites {
+		translated = rewrite.re.ReplaceAllString(translated, rewrite.repl)
+	}
+
+	sqliteQueries.Store(query, translated)
+	return translated
+}
+
+func isSqliteNonUniqueErr(err error) bool {
+	if err, ok := err.(*sqlite.Error); ok {
+		return err.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || err.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
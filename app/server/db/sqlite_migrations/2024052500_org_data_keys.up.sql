@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS org_data_keys (
+  org_id TEXT PRIMARY KEY REFERENCES orgs(id) ON DELETE CASCADE,
+  -- the org's data key, encrypted with the server's master key
+  wrapped_key BLOB NOT NULL,
+  -- which master key wrapped it, e.g. 'kms:<key id>' or 'passphrase'
+  key_source VARCHAR(2048) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT (now())
+);
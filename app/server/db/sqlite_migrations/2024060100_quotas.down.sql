@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE name = 'manage_quotas';
+
+-- sqlite can't drop a column with a foreign key, so model_streams.user_id is left in place
+DROP INDEX IF EXISTS model_streams_active_org_idx;
+
+DROP TABLE IF EXISTS rate_limit_windows;
+DROP TABLE IF EXISTS quotas;
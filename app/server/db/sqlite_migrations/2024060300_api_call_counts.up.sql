@@ -0,0 +1,15 @@
+-- authenticated API calls per user per UTC day, for usage exports
+CREATE TABLE IF NOT EXISTS api_call_counts (
+  org_id TEXT NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  day DATE NOT NULL,
+  count INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (org_id, day, user_id)
+);
+
+INSERT INTO permissions (name, description) VALUES ('view_org_usage', 'View and export usage for everyone in the org');
+
+INSERT INTO org_roles_permissions (org_role_id, permission_id)
+SELECT r.id, p.id
+FROM org_roles r, permissions p
+WHERE r.org_id IS NULL AND r.name IN ('owner', 'admin') AND p.name = 'view_org_usage';
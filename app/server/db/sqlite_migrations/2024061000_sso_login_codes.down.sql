@@ -0,0 +1,2 @@
+ALTER TABLE sso_logins DROP COLUMN confirm_token_hash;
+ALTER TABLE sso_logins DROP COLUMN user_code_hash;
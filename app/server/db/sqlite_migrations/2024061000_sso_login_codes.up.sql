@@ -0,0 +1,4 @@
+-- the code shown in the terminal that starts an SSO sign-in, which has to be entered in the browser that finishes it
+ALTER TABLE sso_logins ADD COLUMN user_code_hash VARCHAR(64) NOT NULL DEFAULT '';
+-- set when the identity provider redirects back, so only that browser can enter the code
+ALTER TABLE sso_logins ADD COLUMN confirm_token_hash VARCHAR(64);
@@ -0,0 +1,315 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/plandex/plandex/shared"
+)
+
+func TestTranslateToSqlite(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			"SELECT * FROM orgs WHERE id = ANY($1)",
+			"SELECT * FROM orgs WHERE id IN (SELECT value FROM json_each($1))",
+		},
+		{
+			"SELECT id FROM repo_locks WHERE plan_id = $1 FOR UPDATE",
+			"SELECT id FROM repo_locks WHERE plan_id = $1",
+		},
+		{
+			"INSERT INTO orgs_users (org_id, user_id) VALUES ($1, $2) ON CONFLICT ON CONSTRAINT org_user_unique DO NOTHING",
+			"INSERT INTO orgs_users (org_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		},
+		{
+			"SELECT TO_CHAR(mu.created_at, 'YYYY-MM-DD') AS key FROM model_usage mu",
+			"SELECT strftime('%Y-%m-%d', mu.created_at) AS key FROM model_usage mu",
+		},
+		{
+			"WHERE day >= $2::date AND created_at::date < $3::date",
+			"WHERE day >= date($2) AND date(created_at) < date($3)",
+		},
+		{
+			"WHERE id::text = $1 OR COALESCE(mu.plan_id::text, '') = $1",
+			"WHERE id = $1 OR COALESCE(mu.plan_id, '') = $1",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := translateToSqlite(tt.query); got != tt.want {
+			t.Errorf("translateToSqlite(%q)\n got %q\nwant %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+// setupSqliteTestDb migrates a new in-memory database and makes it the connection the helpers use
+func setupSqliteTestDb(t *testing.T) {
+	prevConn, prevIsSqlite := Conn, IsSqlite
+	t.Cleanup(func() {
+		Conn, IsSqlite = prevConn, prevIsSqlite
+	})
+
+	// a shared cache keeps one database across the pool's connections, which helpers that take a tx also query outside it
+	conn, err := sqlx.Connect(sqliteDriverName, "file:"+t.Name()+"?mode=memory&cache=shared&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatalf("error opening sqlite: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	Conn, IsSqlite = conn, true
+
+	err = MigrationsUp()
+	if err != nil {
+		t.Fatalf("error running migrations: %v", err)
+	}
+}
+
+func mustExec(t *testing.T, query string, args ...interface{}) {
+	t.Helper()
+	_, err := Conn.Exec(query, args...)
+	if err != nil {
+		t.Fatalf("error running %s: %v", query, err)
+	}
+}
+
+// TestSqliteHelperQueries runs the helpers whose postgres queries need rewriting for sqlite against a real database
+func TestSqliteHelperQueries(t *testing.T) {
+	setupSqliteTestDb(t)
+
+	mustExec(t, "INSERT INTO users (id, name, email, domain, is_trial) VALUES ('user-a', 'A', 'a@example.com', 'example.com', false)")
+	mustExec(t, "INSERT INTO users (id, name, email, domain, is_trial) VALUES ('user-b', 'B', 'b@example.com', 'example.com', false)")
+	mustExec(t, "INSERT INTO orgs (id, name, domain, owner_id, is_trial) VALUES ('org-1', 'Acme', 'example.com', 'user-a', false)")
+	mustExec(t, "INSERT INTO projects (id, org_id, name) VALUES ('project-1', 'org-1', 'app')")
+	mustExec(t, "INSERT INTO plans (id, org_id, owner_id, project_id, name) VALUES ('plan-a', 'org-1', 'user-a', 'project-1', 'plan a')")
+	mustExec(t, "INSERT INTO plans (id, org_id, owner_id, project_id, name) VALUES ('plan-b', 'org-1', 'user-b', 'project-1', 'plan b')")
+	mustExec(t, "INSERT INTO branches (org_id, owner_id, plan_id, name, status) VALUES ('org-1', 'user-a', 'plan-a', 'main', 'ready')")
+	mustExec(t, "INSERT INTO branches (org_id, owner_id, plan_id, name, status) VALUES ('org-1', 'user-b', 'plan-b', 'main', 'ready')")
+	mustExec(t, "INSERT INTO plan_shares (org_id, plan_id, user_id, permission) VALUES ('org-1', 'plan-b', 'user-a', $1)", shared.PlanPermissionView)
+
+	t.Run("ON CONFLICT ON CONSTRAINT", func(t *testing.T) {
+		// the second run hits the unique constraint for both users
+		for i := 0; i < 2; i++ {
+			tx, err := Conn.Begin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = AddOrgDomainUsers("org-1", "example.com", tx)
+			if err != nil {
+				tx.Rollback()
+				t.Fatalf("AddOrgDomainUsers() error = %v", err)
+			}
+			if err := tx.Commit(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("ANY", func(t *testing.T) {
+		users, err := ListUsers("org-1")
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+		if len(users) != 2 {
+			t.Errorf("expected 2 users, got %d", len(users))
+		}
+
+		orgs, err := GetAccessibleOrgsForUser(&User{Id: "user-b", Email: "b@example.com"})
+		if err != nil {
+			t.Fatalf("GetAccessibleOrgsForUser() error = %v", err)
+		}
+		if len(orgs) != 1 || orgs[0].Id != "org-1" {
+			t.Errorf("expected org-1, got %v", orgs)
+		}
+
+		plans, err := ListOwnedPlans([]string{"project-1"}, "user-a", false)
+		if err != nil {
+			t.Fatalf("ListOwnedPlans() error = %v", err)
+		}
+		if len(plans) != 1 || plans[0].Id != "plan-a" {
+			t.Errorf("expected plan-a, got %v", plans)
+		}
+
+		sharedPlans, permissions, err := ListSharedPlans([]string{"project-1"}, "user-a")
+		if err != nil {
+			t.Fatalf("ListSharedPlans() error = %v", err)
+		}
+		if len(sharedPlans) != 1 || sharedPlans[0].Id != "plan-b" || permissions["plan-b"] != shared.PlanPermissionView {
+			t.Errorf("expected plan-b with view permission, got %v %v", sharedPlans, permissions)
+		}
+
+		branches, err := ListBranchesForPlans("org-1", []string{"plan-a", "plan-b"})
+		if err != nil {
+			t.Fatalf("ListBranchesForPlans() error = %v", err)
+		}
+		if len(branches) != 2 {
+			t.Errorf("expected 2 branches, got %d", len(branches))
+		}
+
+		mustExec(t, "INSERT INTO convo_summaries (org_id, plan_id, latest_convo_message_id, latest_convo_message_created_at, summary, tokens, num_messages) VALUES ('org-1', 'plan-a', 'msg-1', $1, 'summary', 10, 2)", time.Now())
+		summaries, err := GetPlanSummaries("plan-a", []string{"msg-1", "msg-2"})
+		if err != nil {
+			t.Fatalf("GetPlanSummaries() error = %v", err)
+		}
+		if len(summaries) != 1 {
+			t.Errorf("expected 1 summary, got %d", len(summaries))
+		}
+
+		mustExec(t, "INSERT INTO model_streams (org_id, plan_id, branch, internal_ip) VALUES ('org-1', 'plan-a', 'main', '127.0.0.1')")
+		streams, err := GetActiveOrRecentModelStreams([]string{"plan-a"})
+		if err != nil {
+			t.Fatalf("GetActiveOrRecentModelStreams() error = %v", err)
+		}
+		if len(streams) != 1 {
+			t.Errorf("expected 1 stream, got %d", len(streams))
+		}
+	})
+
+	t.Run("::text", func(t *testing.T) {
+		org, err := GetOrgByNameOrId("org-1")
+		if err != nil || org == nil {
+			t.Fatalf("GetOrgByNameOrId() by id = %v, %v", org, err)
+		}
+		org, err = GetOrgByNameOrId("Acme")
+		if err != nil || org == nil {
+			t.Fatalf("GetOrgByNameOrId() by name = %v, %v", org, err)
+		}
+
+		_, apiKey, err := CreateApiKey("org-1", "user-a", "ci", shared.ApiKeyScopeRead, nil)
+		if err != nil {
+			t.Fatalf("CreateApiKey() error = %v", err)
+		}
+		revoked, err := RevokeApiKey("org-1", "user-a", apiKey.Id)
+		if err != nil || !revoked {
+			t.Fatalf("RevokeApiKey() by id = %v, %v", revoked, err)
+		}
+		revoked, err = RevokeApiKey("org-1", "user-a", "ci")
+		if err != nil || revoked {
+			t.Fatalf("RevokeApiKey() of a revoked key = %v, %v", revoked, err)
+		}
+	})
+
+	t.Run("FOR UPDATE", func(t *testing.T) {
+		mustExec(t, "INSERT INTO repo_locks (id, org_id, user_id, plan_id, scope, branch) VALUES ('lock-1', 'org-1', 'user-a', 'plan-a', 'r', 'main')")
+
+		tx, err := Conn.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+
+		rows, err := tx.Query("SELECT id FROM repo_locks WHERE plan_id = $1 FOR UPDATE", "plan-a")
+		if err != nil {
+			t.Fatalf("error selecting locks: %v", err)
+		}
+		var ids []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if len(ids) != 1 {
+			t.Fatalf("expected 1 lock, got %v", ids)
+		}
+
+		_, err = tx.Exec("DELETE FROM repo_locks WHERE id = ANY($1)", pq.Array(ids))
+		if err != nil {
+			t.Fatalf("error deleting locks: %v", err)
+		}
+	})
+
+	t.Run("TO_CHAR and ::date", func(t *testing.T) {
+		planId := "plan-a"
+		err := StoreModelUsage(&ModelUsage{OrgId: "org-1", UserId: "user-a", PlanId: planId, Branch: "main", ModelRole: "planner", ModelName: "gpt-4o", InputTokens: 100, OutputTokens: 20, CostUsd: 0.5})
+		if err != nil {
+			t.Fatalf("StoreModelUsage() error = %v", err)
+		}
+		err = CountApiCall("org-1", "user-b")
+		if err != nil {
+			t.Fatalf("CountApiCall() error = %v", err)
+		}
+
+		now := time.Now().UTC()
+		today := now.Format("2006-01-02")
+
+		usage, err := GetUsage("org-1", "user-a", "", now.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("GetUsage() error = %v", err)
+		}
+		if len(usage.ByDay) != 1 || usage.ByDay[0].Key != today {
+			t.Errorf("expected usage for %s, got %v", today, usage.ByDay)
+		}
+		if len(usage.ByPlan) != 1 || usage.ByPlan[0].PlanId != planId {
+			t.Errorf("expected usage for %s, got %v", planId, usage.ByPlan)
+		}
+
+		orgUsage, err := GetOrgUsage("org-1", now, now.AddDate(0, 0, 1))
+		if err != nil {
+			t.Fatalf("GetOrgUsage() error = %v", err)
+		}
+		// user-a only has model usage and user-b only has api calls, so each row comes from one side of the join
+		if len(orgUsage.ByDayAndUser) != 2 {
+			t.Fatalf("expected 2 rows, got %d", len(orgUsage.ByDayAndUser))
+		}
+		for _, row := range orgUsage.ByDayAndUser {
+			if row.Day != today {
+				t.Errorf("expected day %s, got %s", today, row.Day)
+			}
+			if !strings.HasSuffix(row.UserEmail, "@example.com") {
+				t.Errorf("expected the user's email, got %q", row.UserEmail)
+			}
+		}
+	})
+}
+
+// TestOrgSharesMigration checks that plans shared with the org before per-plan permissions keep full access
+func TestOrgSharesMigration(t *testing.T) {
+	setupSqliteTestDb(t)
+
+	mustExec(t, "INSERT INTO users (id, name, email, domain, is_trial) VALUES ('user-a', 'A', 'a@example.com', 'example.com', false)")
+	mustExec(t, "INSERT INTO users (id, name, email, domain, is_trial) VALUES ('user-b', 'B', 'b@example.com', 'example.com', false)")
+	mustExec(t, "INSERT INTO orgs (id, name, domain, owner_id, is_trial) VALUES ('org-1', 'Acme', 'example.com', 'user-a', false)")
+	mustExec(t, "INSERT INTO projects (id, org_id, name) VALUES ('project-1', 'org-1', 'app')")
+	mustExec(t, "INSERT INTO plans (id, org_id, owner_id, project_id, name, shared_with_org_at) VALUES ('plan-a', 'org-1', 'user-a', 'project-1', 'plan a', $1)", time.Now())
+	mustExec(t, "INSERT INTO plans (id, org_id, owner_id, project_id, name) VALUES ('plan-b', 'org-1', 'user-a', 'project-1', 'plan b')")
+
+	migration, err := sqliteMigrations.ReadFile("sqlite_migrations/2024061500_org_shares_from_shared_with_org.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, string(migration))
+
+	_, permission, err := ValidatePlanAccess("plan-a", "user-b", "org-1", false)
+	if err != nil {
+		t.Fatalf("ValidatePlanAccess() error = %v", err)
+	}
+	if permission != shared.PlanPermissionApply {
+		t.Errorf("expected apply permission on a plan shared with the org, got %q", permission)
+	}
+
+	plan, _, err := ValidatePlanAccess("plan-b", "user-b", "org-1", false)
+	if err != nil {
+		t.Fatalf("ValidatePlanAccess() error = %v", err)
+	}
+	if plan != nil {
+		t.Errorf("expected no access to a plan that wasn't shared")
+	}
+
+	// unsharing removes the org-wide share, and nothing else still grants access
+	mustExec(t, "DELETE FROM plan_shares WHERE plan_id = 'plan-a' AND user_id IS NULL")
+	plan, _, err = ValidatePlanAccess("plan-a", "user-b", "org-1", false)
+	if err != nil {
+		t.Fatalf("ValidatePlanAccess() error = %v", err)
+	}
+	if plan != nil {
+		t.Errorf("expected no access after unsharing")
+	}
+}
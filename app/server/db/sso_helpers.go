@@ -0,0 +1,118 @@
+package db
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// how long a user has to finish signing in with the identity provider
+const ssoLoginExpirationMinutes = 10
+
+// HashSsoSecret hashes a sign-in's poll token, user code, or confirm token for storing and comparing
+func HashSsoSecret(secret string) string {
+	hashBytes := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hashBytes[:])
+}
+
+func CreateSsoLogin(state, nonce, codeVerifier, pollToken, userCode string) (string, error) {
+	var id string
+	err := Conn.QueryRow("INSERT INTO sso_logins (state, nonce, code_verifier, poll_token_hash, user_code_hash) VALUES ($1, $2, $3, $4, $5) RETURNING id", state, nonce, codeVerifier, HashSsoSecret(pollToken), HashSsoSecret(userCode)).Scan(&id)
+
+	if err != nil {
+		return "", fmt.Errorf("error creating sso login: %v", err)
+	}
+
+	return id, nil
+}
+
+// GetPendingSsoLoginByState returns the unexpired, unfinished sign-in for the state the identity provider redirected back
+// with, or nil if there isn't one
+func GetPendingSsoLoginByState(state string) (*SsoLogin, error) {
+	var login SsoLogin
+	err := Conn.Get(&login, "SELECT * FROM sso_logins WHERE state = $1 AND completed_at IS NULL AND created_at > $2", state, ssoLoginCutoff())
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("error getting sso login: %v", err)
+	}
+
+	return &login, nil
+}
+
+// GetSsoLogin returns an unexpired sign-in if the poll token matches, or nil if there isn't one
+func GetSsoLogin(id, pollToken string) (*SsoLogin, error) {
+	var login SsoLogin
+	err := Conn.Get(&login, "SELECT * FROM sso_logins WHERE id = $1 AND poll_token_hash = $2 AND created_at > $3", id, HashSsoSecret(pollToken), ssoLoginCutoff())
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("error getting sso login: %v", err)
+	}
+
+	return &login, nil
+}
+
+// SetSsoLoginUser records who signed in with the identity provider. The sign-in isn't complete until they enter the code
+// from their terminal in the same browser, which is checked with the confirm token.
+func SetSsoLoginUser(id, userId, confirmToken string) error {
+	_, err := Conn.Exec("UPDATE sso_logins SET user_id = $2, confirm_token_hash = $3 WHERE id = $1 AND completed_at IS NULL", id, userId, HashSsoSecret(confirmToken))
+
+	if err != nil {
+		return fmt.Errorf("error setting sso login user: %v", err)
+	}
+
+	return nil
+}
+
+// CompleteSsoLogin records who signed in, or the error if they couldn't
+func CompleteSsoLogin(id string, userId *string, errMsg *string) error {
+	_, err := Conn.Exec("UPDATE sso_logins SET user_id = $2, error = $3, completed_at = NOW() WHERE id = $1", id, userId, errMsg)
+
+	if err != nil {
+		return fmt.Errorf("error completing sso login: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteSsoLogin deletes a finished sign-in once its result is picked up. It returns false if it was already deleted, so
+// a session is only created once for each sign-in.
+func DeleteSsoLogin(id string, tx *sql.Tx) (bool, error) {
+	res, err := tx.Exec("DELETE FROM sso_logins WHERE id = $1 AND completed_at IS NOT NULL", id)
+
+	if err != nil {
+		return false, fmt.Errorf("error deleting sso login: %v", err)
+	}
+
+	rows, err := res.RowsAffected()
+
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %v", err)
+	}
+
+	return rows > 0, nil
+}
+
+// DeleteExpiredSsoLogins cleans up sign-ins that were never finished or picked up
+func DeleteExpiredSsoLogins() error {
+	_, err := Conn.Exec("DELETE FROM sso_logins WHERE created_at < $1", ssoLoginCutoff())
+
+	if err != nil {
+		return fmt.Errorf("error deleting expired sso logins: %v", err)
+	}
+
+	return nil
+}
+
+func ssoLoginCutoff() time.Time {
+	return time.Now().Add(-ssoLoginExpirationMinutes * time.Minute)
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// steps are kept in the plan's repo like the conversation, so they're versioned with it and rewind restores them
+
+func getPlanStepsPath(orgId, planId string) string {
+	return filepath.Join(getPlanDir(orgId, planId), "steps.json")
+}
+
+func GetPlanSteps(orgId, planId string) ([]*shared.PlanStep, error) {
+	bytes, err := os.ReadFile(getPlanStepsPath(orgId, planId))
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*shared.PlanStep{}, nil
+		}
+		return nil, fmt.Errorf("error reading plan steps: %v", err)
+	}
+
+	var steps []*shared.PlanStep
+	err = json.Unmarshal(bytes, &steps)
+
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling plan steps: %v", err)
+	}
+
+	return steps, nil
+}
+
+func StorePlanSteps(orgId, planId string, steps []*shared.PlanStep) error {
+	bytes, err := json.Marshal(steps)
+
+	if err != nil {
+		return fmt.Errorf("error marshalling plan steps: %v", err)
+	}
+
+	err = os.WriteFile(getPlanStepsPath(orgId, planId), bytes, os.ModePerm)
+
+	if err != nil {
+		return fmt.Errorf("error writing plan steps: %v", err)
+	}
+
+	return nil
+}
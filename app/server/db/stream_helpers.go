@@ -15,7 +15,7 @@ const modelStreamHeartbeatInterval = 1 * time.Second
 const modelStreamHeartbeatTimeout = 5 * time.Second
 
 func StoreModelStream(stream *ModelStream, ctx context.Context, cancelFn context.CancelFunc) error {
-	query := `INSERT INTO model_streams (org_id, plan_id, internal_ip, branch) VALUES (:org_id, :plan_id, :internal_ip, :branch) RETURNING id, created_at`
+	query := `INSERT INTO model_streams (org_id, plan_id, user_id, internal_ip, branch) VALUES (:org_id, :plan_id, :user_id, :internal_ip, :branch) RETURNING id, created_at`
 
 	row, err := Conn.NamedQuery(query, stream)
 
@@ -122,7 +122,7 @@ func GetActiveModelStream(planId, branch string) (*ModelStream, error) {
 
 func GetActiveOrRecentModelStreams(planIds []string) ([]*ModelStream, error) {
 	var streams []*ModelStream
-	err := Conn.Select(&streams, "SELECT * FROM model_streams WHERE plan_id = ANY($1) AND (finished_at IS NULL OR finished_at > NOW() - INTERVAL '1 hour') ORDER BY created_at", pq.Array(planIds))
+	err := Conn.Select(&streams, "SELECT * FROM model_streams WHERE plan_id = ANY($1) AND (finished_at IS NULL OR finished_at > $2) ORDER BY created_at", pq.Array(planIds), time.Now().UTC().Add(-time.Hour))
 
 	if err != nil {
 		return nil, fmt.Errorf("error getting active or recent model streams: %v", err)
@@ -15,13 +15,29 @@ func GetPlanSummaries(planId string, convoMessageIds []string) ([]*ConvoSummary,
 	if err != nil {
 		return nil, fmt.Errorf("error getting plan summaries: %v", err)
 	}
+
+	for _, summary := range summaries {
+		summary.Summary, err = decryptTextForOrg(summary.OrgId, summary.Summary)
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting summary: %v", err)
+		}
+	}
+
 	return summaries, nil
 }
 
 func StoreSummary(summary *ConvoSummary) error {
 	query := "INSERT INTO convo_summaries (org_id, plan_id, latest_convo_message_id, latest_convo_message_created_at, summary, tokens, num_messages) VALUES (:org_id, :plan_id, :latest_convo_message_id, :latest_convo_message_created_at, :summary, :tokens, :num_messages) RETURNING id, created_at"
 
-	row, err := Conn.NamedQuery(query, summary)
+	// the summary is stored encrypted, but the caller keeps using the plaintext
+	stored := *summary
+	var err error
+	stored.Summary, err = encryptTextForOrg(summary.OrgId, summary.Summary)
+	if err != nil {
+		return fmt.Errorf("error encrypting summary: %v", err)
+	}
+
+	row, err := Conn.NamedQuery(query, &stored)
 
 	if err != nil {
 		return fmt.Errorf("error storing summary: %v", err)
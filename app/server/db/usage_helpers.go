@@ -0,0 +1,212 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/plandex/plandex/shared"
+)
+
+func StoreModelUsage(usage *ModelUsage) error {
+	query := `INSERT INTO model_usage (org_id, user_id, plan_id, plan_name, branch, model_role, model_name, input_tokens, output_tokens, cost_usd, cached_input_tokens, cache_savings_usd)
+	VALUES ($1, $2, $3, COALESCE((SELECT name FROM plans WHERE id = $3), ''), $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err := Conn.Exec(query, usage.OrgId, usage.UserId, usage.PlanId, usage.Branch, usage.ModelRole, usage.ModelName, usage.InputTokens, usage.OutputTokens, usage.CostUsd, usage.CachedInputTokens, usage.CacheSavingsUsd)
+
+	if err != nil {
+		return fmt.Errorf("error storing model usage: %v", err)
+	}
+
+	return nil
+}
+
+// GetUsage totals a user's model usage in an org since a time, overall and by plan, day, and model. If planId is set, only that plan's usage is included.
+func GetUsage(orgId, userId, planId string, since time.Time) (*shared.UsageResponse, error) {
+	where := "WHERE mu.org_id = $1 AND mu.user_id = $2 AND mu.created_at >= $3"
+	args := []interface{}{orgId, userId, since}
+	if planId != "" {
+		where += " AND mu.plan_id = $4"
+		args = append(args, planId)
+	}
+
+	totals := "COUNT(*) AS num_requests, COALESCE(SUM(mu.input_tokens), 0) AS input_tokens, COALESCE(SUM(mu.output_tokens), 0) AS output_tokens, COALESCE(SUM(mu.cost_usd), 0) AS cost_usd, COALESCE(SUM(mu.cached_input_tokens), 0) AS cached_input_tokens, COALESCE(SUM(mu.cache_savings_usd), 0) AS cache_savings_usd"
+
+	var total usageRow
+	err := Conn.Get(&total, fmt.Sprintf("SELECT '' AS key, '' AS plan_id, %s FROM model_usage mu %s", totals, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting total usage: %v", err)
+	}
+
+	var byPlan []*usageRow
+	// a deleted plan's usage is listed under the name it had when the usage was recorded
+	err = Conn.Select(&byPlan, fmt.Sprintf("SELECT COALESCE(p.name, mu.plan_name) AS key, COALESCE(mu.plan_id::text, '') AS plan_id, %s FROM model_usage mu LEFT JOIN plans p ON p.id = mu.plan_id %s GROUP BY 1, 2 ORDER BY cost_usd DESC", totals, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting usage by plan: %v", err)
+	}
+
+	var byDay []*usageRow
+	err = Conn.Select(&byDay, fmt.Sprintf("SELECT TO_CHAR(mu.created_at, 'YYYY-MM-DD') AS key, '' AS plan_id, %s FROM model_usage mu %s GROUP BY 1 ORDER BY 1 DESC", totals, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting usage by day: %v", err)
+	}
+
+	var byModel []*usageRow
+	err = Conn.Select(&byModel, fmt.Sprintf("SELECT mu.model_name AS key, '' AS plan_id, %s FROM model_usage mu %s GROUP BY 1 ORDER BY cost_usd DESC", totals, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting usage by model: %v", err)
+	}
+
+	return &shared.UsageResponse{
+		Total:   total.ToApi(),
+		ByPlan:  usageRowsToApi(byPlan),
+		ByDay:   usageRowsToApi(byDay),
+		ByModel: usageRowsToApi(byModel),
+	}, nil
+}
+
+// CountApiCall counts an authenticated API call for a user in an org on the current UTC day
+func CountApiCall(orgId, userId string) error {
+	day := time.Now().UTC().Format("2006-01-02")
+
+	_, err := Conn.Exec("INSERT INTO api_call_counts (org_id, user_id, day, count) VALUES ($1, $2, $3, 1) ON CONFLICT (org_id, day, user_id) DO UPDATE SET count = api_call_counts.count + 1", orgId, userId, day)
+
+	if err != nil {
+		return fmt.Errorf("error counting api call: %v", err)
+	}
+
+	return nil
+}
+
+// GetOrgUsage totals everyone's API calls and model usage in an org from the UTC day of since up to, but not including,
+// the UTC day of until, by user and day, by user, and overall
+func GetOrgUsage(orgId string, since, until time.Time) (*shared.OrgUsageResponse, error) {
+	sinceDay := since.UTC().Format("2006-01-02")
+	untilDay := until.UTC().Format("2006-01-02")
+
+	query := `WITH calls AS (
+		SELECT day, user_id, SUM(count) AS api_calls
+		FROM api_call_counts
+		WHERE org_id = $1 AND day >= $2::date AND day < $3::date
+		GROUP BY 1, 2
+	), model AS (
+		SELECT created_at::date AS day, user_id, COUNT(*) AS model_requests, SUM(input_tokens) AS input_tokens, SUM(cached_input_tokens) AS cached_input_tokens, SUM(output_tokens) AS output_tokens, SUM(cost_usd) AS cost_usd, SUM(cache_savings_usd) AS cache_savings_usd
+		FROM model_usage
+		WHERE org_id = $1 AND created_at >= $2::date AND created_at < $3::date
+		GROUP BY 1, 2
+	)
+	SELECT TO_CHAR(COALESCE(c.day, m.day), 'YYYY-MM-DD') AS day, COALESCE(c.user_id, m.user_id) AS user_id, COALESCE(u.email, '') AS user_email,
+		COALESCE(c.api_calls, 0) AS api_calls, COALESCE(m.model_requests, 0) AS model_requests, COALESCE(m.input_tokens, 0) AS input_tokens, COALESCE(m.cached_input_tokens, 0) AS cached_input_tokens, COALESCE(m.output_tokens, 0) AS output_tokens, COALESCE(m.cost_usd, 0) AS cost_usd, COALESCE(m.cache_savings_usd, 0) AS cache_savings_usd
+	FROM calls c
+	FULL OUTER JOIN model m ON m.day = c.day AND m.user_id = c.user_id
+	LEFT JOIN users u ON u.id = COALESCE(c.user_id, m.user_id)
+	ORDER BY 1 DESC, 3`
+
+	var rows []*orgUsageRow
+	err := Conn.Select(&rows, query, orgId, sinceDay, untilDay)
+	if err != nil {
+		return nil, fmt.Errorf("error getting org usage: %v", err)
+	}
+
+	res := &shared.OrgUsageResponse{
+		Since:        sinceDay,
+		Until:        untilDay,
+		Total:        &shared.OrgUsageRow{},
+		ByUser:       []*shared.OrgUsageRow{},
+		ByDayAndUser: []*shared.OrgUsageRow{},
+	}
+
+	byUserId := map[string]*shared.OrgUsageRow{}
+	for _, row := range rows {
+		apiRow := row.ToApi()
+		res.ByDayAndUser = append(res.ByDayAndUser, apiRow)
+
+		userRow, ok := byUserId[row.UserId]
+		if !ok {
+			userRow = &shared.OrgUsageRow{UserId: row.UserId, UserEmail: row.UserEmail}
+			byUserId[row.UserId] = userRow
+			res.ByUser = append(res.ByUser, userRow)
+		}
+
+		addOrgUsage(userRow, apiRow)
+		addOrgUsage(res.Total, apiRow)
+	}
+
+	sort.SliceStable(res.ByUser, func(i, j int) bool {
+		return res.ByUser[i].CostUsd > res.ByUser[j].CostUsd
+	})
+
+	return res, nil
+}
+
+func addOrgUsage(total, row *shared.OrgUsageRow) {
+	total.ApiCalls += row.ApiCalls
+	total.ModelRequests += row.ModelRequests
+	total.InputTokens += row.InputTokens
+	total.CachedInputTokens += row.CachedInputTokens
+	total.OutputTokens += row.OutputTokens
+	total.CostUsd += row.CostUsd
+	total.CacheSavingsUsd += row.CacheSavingsUsd
+}
+
+type orgUsageRow struct {
+	Day               string  `db:"day"`
+	UserId            string  `db:"user_id"`
+	UserEmail         string  `db:"user_email"`
+	ApiCalls          int     `db:"api_calls"`
+	ModelRequests     int     `db:"model_requests"`
+	InputTokens       int     `db:"input_tokens"`
+	CachedInputTokens int     `db:"cached_input_tokens"`
+	OutputTokens      int     `db:"output_tokens"`
+	CostUsd           float64 `db:"cost_usd"`
+	CacheSavingsUsd   float64 `db:"cache_savings_usd"`
+}
+
+func (row *orgUsageRow) ToApi() *shared.OrgUsageRow {
+	return &shared.OrgUsageRow{
+		Day:               row.Day,
+		UserId:            row.UserId,
+		UserEmail:         row.UserEmail,
+		ApiCalls:          row.ApiCalls,
+		ModelRequests:     row.ModelRequests,
+		InputTokens:       row.InputTokens,
+		CachedInputTokens: row.CachedInputTokens,
+		OutputTokens:      row.OutputTokens,
+		CostUsd:           row.CostUsd,
+		CacheSavingsUsd:   row.CacheSavingsUsd,
+	}
+}
+
+type usageRow struct {
+	Key          string  `db:"key"`
+	PlanId       string  `db:"plan_id"`
+	NumRequests  int     `db:"num_requests"`
+	InputTokens  int     `db:"input_tokens"`
+	OutputTokens int     `db:"output_tokens"`
+	CostUsd      float64 `db:"cost_usd"`
+
+	CachedInputTokens int     `db:"cached_input_tokens"`
+	CacheSavingsUsd   float64 `db:"cache_savings_usd"`
+}
+
+func (row *usageRow) ToApi() *shared.UsageRow {
+	return &shared.UsageRow{
+		Key:          row.Key,
+		PlanId:       row.PlanId,
+		NumRequests:  row.NumRequests,
+		InputTokens:  row.InputTokens,
+		OutputTokens: row.OutputTokens,
+		CostUsd:      row.CostUsd,
+
+		CachedInputTokens: row.CachedInputTokens,
+		CacheSavingsUsd:   row.CacheSavingsUsd,
+	}
+}
+
+func usageRowsToApi(rows []*usageRow) []*shared.UsageRow {
+	res := []*shared.UsageRow{}
+	for _, row := range rows {
+		res = append(res, row.ToApi())
+	}
+	return res
+}
@@ -3,6 +3,10 @@ package db
 import "github.com/lib/pq"
 
 func IsNonUniqueErr(err error) bool {
+	if IsSqlite {
+		return isSqliteNonUniqueErr(err)
+	}
+
 	if err, ok := err.(*pq.Error); ok {
 		if err.Code == "23505" {
 			return true
@@ -0,0 +1,124 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"os"
+)
+
+// marks encrypted data, so data stored before encryption was turned on can still be read. Plaintext json and context
+// bodies never start with a null byte.
+var header = []byte("\x00pdxenc1\x00")
+
+const DataKeyLength = 32
+
+// MasterKey wraps and unwraps the per-org data keys that encrypt stored data, so the master key itself never touches data
+type MasterKey interface {
+	Wrap(orgId string, dataKey []byte) ([]byte, error)
+	Unwrap(orgId string, wrapped []byte) ([]byte, error)
+	// identifies the master key, so a data key wrapped by a different one gives a clear error
+	Source() string
+}
+
+var masterKey MasterKey
+
+// Init sets up the master key from the environment. Encryption is on if PLANDEX_ENCRYPTION_KMS_KEY_ID or
+// PLANDEX_ENCRYPTION_PASSPHRASE is set.
+func Init() error {
+	kmsKeyId := os.Getenv("PLANDEX_ENCRYPTION_KMS_KEY_ID")
+	passphrase := os.Getenv("PLANDEX_ENCRYPTION_PASSPHRASE")
+
+	if kmsKeyId != "" && passphrase != "" {
+		return fmt.Errorf("set PLANDEX_ENCRYPTION_KMS_KEY_ID or PLANDEX_ENCRYPTION_PASSPHRASE, not both")
+	}
+
+	var err error
+	if kmsKeyId != "" {
+		masterKey, err = newKmsMasterKey(kmsKeyId)
+	} else if passphrase != "" {
+		masterKey, err = newPassphraseMasterKey(passphrase)
+	}
+
+	return err
+}
+
+func Enabled() bool {
+	return masterKey != nil
+}
+
+func GetMasterKey() MasterKey {
+	return masterKey
+}
+
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, DataKeyLength)
+	_, err := rand.Read(key)
+	if err != nil {
+		return nil, fmt.Errorf("error generating data key: %v", err)
+	}
+	return key, nil
+}
+
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, header)
+}
+
+// Seal encrypts with AES-256-GCM. The additional data, like an org id, has to match when opening, so encrypted data can't
+// be moved to another org.
+func Seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGcm(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("error generating nonce: %v", err)
+	}
+
+	res := make([]byte, 0, len(header)+len(nonce)+len(plaintext)+gcm.Overhead())
+	res = append(res, header...)
+	res = append(res, nonce...)
+	return gcm.Seal(res, nonce, plaintext, additionalData), nil
+}
+
+func Open(key, data, additionalData []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, fmt.Errorf("data isn't encrypted")
+	}
+	data = data[len(header):]
+
+	gcm, err := newGcm(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted data is too short")
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting: %v", err)
+	}
+
+	return plaintext, nil
+}
+
+func newGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %v", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gcm: %v", err)
+	}
+
+	return gcm, nil
+}
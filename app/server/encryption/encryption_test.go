@@ -0,0 +1,110 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	key, err := NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("package main\n\nfunc main() {}\n")
+
+	sealed, err := Seal(key, plaintext, []byte("org-1"))
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if !IsEncrypted(sealed) {
+		t.Fatalf("sealed data doesn't start with the header")
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Fatalf("sealed data contains the plaintext")
+	}
+
+	opened, err := Open(key, sealed, []byte("org-1"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open() = %q, want %q", opened, plaintext)
+	}
+
+	// data sealed for one org can't be opened as another's
+	_, err = Open(key, sealed, []byte("org-2"))
+	if err == nil {
+		t.Errorf("Open() with a different org succeeded")
+	}
+
+	otherKey, err := NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Open(otherKey, sealed, []byte("org-1"))
+	if err == nil {
+		t.Errorf("Open() with a different key succeeded")
+	}
+
+	_, err = Open(key, plaintext, []byte("org-1"))
+	if err == nil {
+		t.Errorf("Open() of data without the header succeeded")
+	}
+}
+
+func TestPassphraseWrapUnwrap(t *testing.T) {
+	masterKey, err := newPassphraseMasterKey("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataKey, err := NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrapped, err := masterKey.Wrap("org-1", dataKey)
+	if err != nil {
+		t.Fatalf("Wrap() error = %v", err)
+	}
+
+	unwrapped, err := masterKey.Unwrap("org-1", wrapped)
+	if err != nil {
+		t.Fatalf("Unwrap() error = %v", err)
+	}
+	if !bytes.Equal(unwrapped, dataKey) {
+		t.Errorf("Unwrap() returned a different key")
+	}
+
+	_, err = masterKey.Unwrap("org-2", wrapped)
+	if err == nil {
+		t.Errorf("Unwrap() with a different org succeeded")
+	}
+
+	otherMasterKey, err := newPassphraseMasterKey("a different passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = otherMasterKey.Unwrap("org-1", wrapped)
+	if err == nil {
+		t.Errorf("Unwrap() with a different passphrase succeeded")
+	}
+
+	_, err = newPassphraseMasterKey("too short")
+	if err == nil {
+		t.Errorf("newPassphraseMasterKey() accepted a short passphrase")
+	}
+}
+
+// TestPassphraseKeyDerivation pins the derived key, so keys wrapped by earlier versions can still be unwrapped
+func TestPassphraseKeyDerivation(t *testing.T) {
+	masterKey := &passphraseMasterKey{passphrase: []byte("correct horse battery staple")}
+
+	got := hex.EncodeToString(masterKey.deriveKey([]byte("0123456789abcdef")))
+	want := "6c4a646aad10d067add5fb79d9078a16da83d50f81670a8e7593b249e6d94936"
+	if got != want {
+		t.Errorf("deriveKey() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,114 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// kmsMasterKey wraps data keys with an AWS KMS key, so the master key never leaves KMS. Credentials come from the standard
+// AWS credential chain.
+type kmsMasterKey struct {
+	client *kms.KMS
+	keyId  string
+}
+
+func newKmsMasterKey(keyId string) (*kmsMasterKey, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("error creating AWS session: %v", err)
+	}
+
+	return &kmsMasterKey{client: kms.New(sess), keyId: keyId}, nil
+}
+
+func (k *kmsMasterKey) Wrap(orgId string, dataKey []byte) ([]byte, error) {
+	out, err := k.client.Encrypt(&kms.EncryptInput{
+		KeyId:             aws.String(k.keyId),
+		Plaintext:         dataKey,
+		EncryptionContext: map[string]*string{"orgId": aws.String(orgId)},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error wrapping data key with KMS: %v", err)
+	}
+
+	return out.CiphertextBlob, nil
+}
+
+func (k *kmsMasterKey) Unwrap(orgId string, wrapped []byte) ([]byte, error) {
+	out, err := k.client.Decrypt(&kms.DecryptInput{
+		KeyId:             aws.String(k.keyId),
+		CiphertextBlob:    wrapped,
+		EncryptionContext: map[string]*string{"orgId": aws.String(orgId)},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error unwrapping data key with KMS: %v", err)
+	}
+
+	return out.Plaintext, nil
+}
+
+func (k *kmsMasterKey) Source() string {
+	return "kms:" + k.keyId
+}
+
+// OWASP's recommendation for PBKDF2-HMAC-SHA256
+const passphraseIterations = 600000
+
+const saltLength = 16
+
+// passphraseMasterKey derives a key from a passphrase to wrap data keys with. Each wrapped key has its own salt.
+type passphraseMasterKey struct {
+	passphrase []byte
+}
+
+func newPassphraseMasterKey(passphrase string) (*passphraseMasterKey, error) {
+	if len(passphrase) < 16 {
+		return nil, fmt.Errorf("PLANDEX_ENCRYPTION_PASSPHRASE must be at least 16 characters")
+	}
+
+	return &passphraseMasterKey{passphrase: []byte(passphrase)}, nil
+}
+
+func (k *passphraseMasterKey) Wrap(orgId string, dataKey []byte) ([]byte, error) {
+	salt := make([]byte, saltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("error generating salt: %v", err)
+	}
+
+	sealed, err := Seal(k.deriveKey(salt), dataKey, []byte(orgId))
+	if err != nil {
+		return nil, fmt.Errorf("error wrapping data key: %v", err)
+	}
+
+	return append(salt, sealed...), nil
+}
+
+func (k *passphraseMasterKey) Unwrap(orgId string, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < saltLength {
+		return nil, fmt.Errorf("wrapped data key is too short")
+	}
+
+	dataKey, err := Open(k.deriveKey(wrapped[:saltLength]), wrapped[saltLength:], []byte(orgId))
+	if err != nil {
+		return nil, fmt.Errorf("error unwrapping data key. Is PLANDEX_ENCRYPTION_PASSPHRASE the same as when it was created? %v", err)
+	}
+
+	return dataKey, nil
+}
+
+func (k *passphraseMasterKey) Source() string {
+	return "passphrase"
+}
+
+func (k *passphraseMasterKey) deriveKey(salt []byte) []byte {
+	return pbkdf2.Key(k.passphrase, salt, passphraseIterations, DataKeyLength, sha256.New)
+}
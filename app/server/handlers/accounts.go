@@ -15,6 +15,10 @@ import (
 func StartTrialHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Received request for StartTrialHandler")
 
+	if rejectIfSsoRequired(w) {
+		return
+	}
+
 	// start a transaction
 	tx, err := db.Conn.Begin()
 	if err != nil {
@@ -129,6 +133,10 @@ func StartTrialHandler(w http.ResponseWriter, r *http.Request) {
 func CreateAccountHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Received request for CreateAccountHandler")
 
+	if rejectIfSsoRequired(w) {
+		return
+	}
+
 	// read the request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"plandex-server/db"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/plandex/plandex/shared"
+)
+
+func CreateApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received a request for CreateApiKeyHandler")
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	if auth.User.IsTrial {
+		writeApiError(w, shared.ApiError{
+			Type:   shared.ApiErrorTypeTrialActionNotAllowed,
+			Status: http.StatusForbidden,
+			Msg:    "Anonymous trial user can't create API keys",
+		})
+		return
+	}
+
+	var req shared.CreateApiKeyRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Printf("Error unmarshalling request: %v\n", err)
+		http.Error(w, "Error unmarshalling request: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "API key name is required", http.StatusBadRequest)
+		return
+	}
+
+	if !req.Scope.Valid() {
+		log.Printf("Invalid scope: %v\n", req.Scope)
+		http.Error(w, "Invalid scope: "+string(req.Scope), http.StatusBadRequest)
+		return
+	}
+
+	if req.ExpiresInDays < 0 {
+		http.Error(w, "expiresInDays can't be negative", http.StatusBadRequest)
+		return
+	}
+
+	// keys can be revoked by name, so names are unique among a user's keys
+	apiKeys, err := db.ListApiKeys(auth.OrgId, auth.User.Id)
+	if err != nil {
+		log.Printf("Error listing api keys: %v\n", err)
+		http.Error(w, "Error listing api keys: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, apiKey := range apiKeys {
+		if apiKey.Name == req.Name {
+			http.Error(w, "An API key named "+req.Name+" already exists", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	key, apiKey, err := db.CreateApiKey(auth.OrgId, auth.User.Id, req.Name, req.Scope, expiresAt)
+	if err != nil {
+		log.Printf("Error creating api key: %v\n", err)
+		http.Error(w, "Error creating api key: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(shared.CreateApiKeyResponse{
+		ApiKey: apiKey.ToApi(),
+		Key:    key,
+	})
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Println("Successfully created api key")
+
+	w.Write(bytes)
+}
+
+func ListApiKeysHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received a request for ListApiKeysHandler")
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	apiKeys, err := db.ListApiKeys(auth.OrgId, auth.User.Id)
+	if err != nil {
+		log.Printf("Error listing api keys: %v\n", err)
+		http.Error(w, "Error listing api keys: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	apiApiKeys := []*shared.ApiKey{}
+	for _, apiKey := range apiKeys {
+		apiApiKeys = append(apiApiKeys, apiKey.ToApi())
+	}
+
+	bytes, err := json.Marshal(apiApiKeys)
+	if err != nil {
+		log.Printf("Error marshalling api keys: %v\n", err)
+		http.Error(w, "Error marshalling api keys: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+	log.Println("Successfully processed request for ListApiKeysHandler")
+}
+
+func RevokeApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received a request for RevokeApiKeyHandler")
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	apiKeyId := vars["apiKeyId"]
+
+	found, err := db.RevokeApiKey(auth.OrgId, auth.User.Id, apiKeyId)
+	if err != nil {
+		log.Printf("Error revoking api key: %v\n", err)
+		http.Error(w, "Error revoking api key: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !found {
+		log.Printf("Api key not found: %v\n", apiKeyId)
+		http.Error(w, "API key not found: "+apiKeyId, http.StatusNotFound)
+		return
+	}
+
+	log.Println("Successfully revoked api key")
+}
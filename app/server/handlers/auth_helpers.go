@@ -3,12 +3,15 @@ package handlers
 import (
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"plandex-server/db"
+	"plandex-server/logging"
 	"plandex-server/types"
 	"strings"
 
+	"github.com/gorilla/mux"
 	"github.com/plandex/plandex/shared"
 )
 
@@ -32,6 +35,19 @@ func authenticate(w http.ResponseWriter, r *http.Request, requireOrg bool) *type
 	// strip off the "Bearer " prefix
 	encoded := strings.TrimPrefix(authHeader, "Bearer ")
 
+	if strings.HasPrefix(encoded, shared.ApiKeyPrefix) {
+		auth := authenticateApiKey(w, r, encoded)
+		if auth == nil {
+			return nil
+		}
+		logging.SetUser(r.Context(), auth.User.Id, auth.OrgId)
+		if !checkRequestQuota(w, auth) {
+			return nil
+		}
+		meterApiCall(auth)
+		return auth
+	}
+
 	// decode the base64-encoded credentials
 	bytes, err := base64.StdEncoding.DecodeString(encoded)
 
@@ -73,6 +89,8 @@ func authenticate(w http.ResponseWriter, r *http.Request, requireOrg bool) *type
 		return nil
 	}
 
+	logging.SetUser(r.Context(), user.Id, parsed.OrgId)
+
 	if !requireOrg {
 		return &types.ServerAuth{
 			AuthToken: authToken,
@@ -123,8 +141,7 @@ func authenticate(w http.ResponseWriter, r *http.Request, requireOrg bool) *type
 		}
 	}
 
-	// get user permissions
-	permissions, err := db.GetUserPermissions(authToken.UserId, parsed.OrgId)
+	permissionsMap, err := getPermissionsMap(authToken.UserId, parsed.OrgId)
 
 	if err != nil {
 		log.Printf("error getting user permissions: %v\n", err)
@@ -132,21 +149,23 @@ func authenticate(w http.ResponseWriter, r *http.Request, requireOrg bool) *type
 		return nil
 	}
 
-	// build the permissions map
-	permissionsMap := make(map[types.Permission]bool)
-	for _, permission := range permissions {
-		permissionsMap[types.Permission(permission)] = true
-	}
-
 	log.Printf("UserId: %s, Email: %s, OrgId: %s\n", authToken.UserId, user.Email, parsed.OrgId)
 
-	return &types.ServerAuth{
+	auth := &types.ServerAuth{
 		AuthToken:   authToken,
 		User:        user,
 		OrgId:       parsed.OrgId,
 		Permissions: permissionsMap,
 	}
 
+	if !checkRequestQuota(w, auth) {
+		return nil
+	}
+
+	meterApiCall(auth)
+
+	return auth
+
 }
 
 func authorizeProject(w http.ResponseWriter, projectId string, auth *types.ServerAuth) bool {
@@ -198,9 +217,23 @@ func authorizeProjectDelete(w http.ResponseWriter, projectId string, auth *types
 }
 
 func authorizePlan(w http.ResponseWriter, planId string, auth *types.ServerAuth) *db.Plan {
+	return authorizePlanPermission(w, planId, auth, shared.PlanPermissionView)
+}
+
+func authorizePlanPrompt(w http.ResponseWriter, planId string, auth *types.ServerAuth) *db.Plan {
+	return authorizePlanPermission(w, planId, auth, shared.PlanPermissionPrompt)
+}
+
+func authorizePlanApply(w http.ResponseWriter, planId string, auth *types.ServerAuth) *db.Plan {
+	return authorizePlanPermission(w, planId, auth, shared.PlanPermissionApply)
+}
+
+// authorizePlanPermission checks that the user can access the plan, and that if it's shared with them rather than theirs,
+// it's shared with at least the required permission
+func authorizePlanPermission(w http.ResponseWriter, planId string, auth *types.ServerAuth, required shared.PlanPermission) *db.Plan {
 	log.Println("authorizing plan")
 
-	plan, err := db.ValidatePlanAccess(planId, auth.User.Id, auth.OrgId)
+	plan, permission, err := db.ValidatePlanAccess(planId, auth.User.Id, auth.OrgId, auth.HasPermission(types.PermissionUpdateAnyPlan))
 
 	if err != nil {
 		log.Printf("error validating plan membership: %v\n", err)
@@ -214,6 +247,12 @@ func authorizePlan(w http.ResponseWriter, planId string, auth *types.ServerAuth)
 		return nil
 	}
 
+	if !permission.Allows(required) {
+		log.Printf("user has %s permission on the plan, %s required\n", permission, required)
+		http.Error(w, fmt.Sprintf("Plan is shared with you with '%s' permission. This needs '%s' permission.", permission, required), http.StatusForbidden)
+		return nil
+	}
+
 	return plan
 }
 
@@ -280,3 +319,119 @@ func authorizePlanArchive(w http.ResponseWriter, planId string, auth *types.Serv
 
 	return plan
 }
+
+// authenticateApiKey authenticates a request made with an API key rather than a session token. The key acts as the user who
+// created it, in the key's org, so it loses access if the user leaves the org.
+func authenticateApiKey(w http.ResponseWriter, r *http.Request, key string) *types.ServerAuth {
+	apiKey, err := db.ValidateApiKey(key)
+
+	if err != nil {
+		log.Printf("error validating api key: %v\n", err)
+
+		writeApiError(w, shared.ApiError{
+			Type:   shared.ApiErrorTypeInvalidToken,
+			Status: http.StatusUnauthorized,
+			Msg:    "Invalid, expired, or revoked API key",
+		})
+		return nil
+	}
+
+	if !apiKeyAllows(apiKey.Scope, r) {
+		log.Printf("api key %s with scope %s not allowed: %s %s\n", apiKey.Id, apiKey.Scope, r.Method, r.URL.Path)
+		http.Error(w, "API key with scope '"+string(apiKey.Scope)+"' can't be used for this request", http.StatusForbidden)
+		return nil
+	}
+
+	user, err := db.GetUser(apiKey.UserId)
+
+	if err != nil {
+		log.Printf("error getting user: %v\n", err)
+		http.Error(w, "error getting user", http.StatusInternalServerError)
+		return nil
+	}
+
+	isMember, err := db.ValidateOrgMembership(apiKey.UserId, apiKey.OrgId)
+
+	if err != nil {
+		log.Printf("error validating org membership: %v\n", err)
+		http.Error(w, "error validating org membership", http.StatusInternalServerError)
+		return nil
+	}
+
+	if !isMember {
+		log.Println("api key's user is no longer a member of the org")
+		http.Error(w, "not a member of org", http.StatusUnauthorized)
+		return nil
+	}
+
+	permissionsMap, err := getPermissionsMap(apiKey.UserId, apiKey.OrgId)
+
+	if err != nil {
+		log.Printf("error getting user permissions: %v\n", err)
+		http.Error(w, "error getting user permissions", http.StatusInternalServerError)
+		return nil
+	}
+
+	log.Printf("UserId: %s, Email: %s, OrgId: %s, ApiKey: %s\n", apiKey.UserId, user.Email, apiKey.OrgId, apiKey.Id)
+
+	return &types.ServerAuth{
+		User:        user,
+		OrgId:       apiKey.OrgId,
+		Permissions: permissionsMap,
+		ApiKey:      apiKey,
+	}
+}
+
+// apiKeyAllows checks a request against an API key's scope. Whatever the scope, keys only work for projects, plans, and usage,
+// not for accounts, orgs, invites, managing API keys, or changing who plans are shared with.
+func apiKeyAllows(scope shared.ApiKeyScope, r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	path, err := route.GetPathTemplate()
+	if err != nil {
+		return false
+	}
+	// routes are served both with and without the version prefix
+	path = strings.TrimPrefix(path, "/v1")
+
+	if path == "/orgs/session" {
+		return r.Method == http.MethodGet
+	}
+
+	if !(strings.HasPrefix(path, "/projects") || strings.HasPrefix(path, "/plans") || strings.HasPrefix(path, "/usage")) {
+		return false
+	}
+
+	if strings.HasPrefix(path, "/plans/{planId}/shares") && r.Method != http.MethodGet {
+		return false
+	}
+
+	switch scope {
+	case shared.ApiKeyScopeRead:
+		// a lookup that's a POST because of its request body
+		return r.Method == http.MethodGet || path == "/projects/{projectId}/plans/current_branches"
+	case shared.ApiKeyScopeWrite:
+		return path != "/plans/{planId}/{branch}/apply"
+	case shared.ApiKeyScopeApply:
+		return true
+	}
+
+	return false
+}
+
+func getPermissionsMap(userId, orgId string) (map[types.Permission]bool, error) {
+	permissions, err := db.GetUserPermissions(userId, orgId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	permissionsMap := make(map[types.Permission]bool)
+	for _, permission := range permissions {
+		permissionsMap[types.Permission(permission)] = true
+	}
+
+	return permissionsMap, nil
+}
@@ -76,7 +76,7 @@ func CreateBranchHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("planId: ", planId)
 
-	plan := authorizePlan(w, planId, auth)
+	plan := authorizePlanPrompt(w, planId, auth)
 	if plan == nil {
 		return
 	}
@@ -167,7 +167,7 @@ func DeleteBranchHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("planId: ", planId)
 
-	if authorizePlan(w, planId, auth) == nil {
+	if authorizePlanPrompt(w, planId, auth) == nil {
 		return
 	}
 
@@ -12,7 +12,17 @@ import (
 )
 
 func loadContexts(w http.ResponseWriter, r *http.Request, auth *types.ServerAuth, loadReq *shared.LoadContextRequest, plan *db.Plan, branchName string) (*shared.LoadContextResponse, []*db.Context) {
-	var err error
+	if !resolveSharedLoadContexts(w, auth, loadReq, plan) {
+		return nil, nil
+	}
+
+	// summarize before locking since it requires model calls
+	summarizedShas, err := summarizeLoadContexts(plan, contextSummaryUsage(auth, plan, branchName), loadReq)
+	if err != nil {
+		log.Printf("Error summarizing contexts: %v\n", err)
+		http.Error(w, "Error summarizing contexts: "+err.Error(), http.StatusInternalServerError)
+		return nil, nil
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	unlockFn := lockRepo(w, r, auth, db.LockScopeWrite, ctx, cancel, true)
@@ -25,11 +35,12 @@ func loadContexts(w http.ResponseWriter, r *http.Request, auth *types.ServerAuth
 	}
 
 	res, dbContexts, err := db.LoadContexts(db.LoadContextsParams{
-		OrgId:      auth.OrgId,
-		Plan:       plan,
-		BranchName: branchName,
-		Req:        loadReq,
-		UserId:     auth.User.Id,
+		OrgId:          auth.OrgId,
+		Plan:           plan,
+		BranchName:     branchName,
+		Req:            loadReq,
+		UserId:         auth.User.Id,
+		SummarizedShas: summarizedShas,
 	})
 
 	if err != nil {
@@ -38,8 +49,14 @@ func loadContexts(w http.ResponseWriter, r *http.Request, auth *types.ServerAuth
 		return nil, nil
 	}
 
-	if res.MaxTokensExceeded {
-		log.Printf("The total number of tokens (%d) exceeds the maximum allowed (%d)", res.TotalTokens, res.MaxTokens)
+	res.AcceptsBodySha = true
+
+	if res.MaxTokensExceeded || len(res.MissingBodyShas) > 0 {
+		if res.MaxTokensExceeded {
+			log.Printf("The total number of tokens (%d) exceeds the maximum allowed (%d)", res.TotalTokens, res.MaxTokens)
+		} else {
+			log.Printf("%d bodies sent as just a sha aren't in the plan", len(res.MissingBodyShas))
+		}
 		bytes, err := json.Marshal(res)
 
 		if err != nil {
@@ -52,6 +69,11 @@ func loadContexts(w http.ResponseWriter, r *http.Request, auth *types.ServerAuth
 		return nil, nil
 	}
 
+	// nothing to commit if everything was already in context
+	if len(dbContexts) == 0 {
+		return res, dbContexts
+	}
+
 	err = db.GitAddAndCommit(auth.OrgId, plan.Id, branchName, res.Msg)
 
 	if err != nil {
@@ -62,3 +84,65 @@ func loadContexts(w http.ResponseWriter, r *http.Request, auth *types.ServerAuth
 
 	return res, dbContexts
 }
+
+// resolveSharedLoadContexts fills in the body and details of any contexts loaded by reference from another plan, after checking the user can access that plan
+func resolveSharedLoadContexts(w http.ResponseWriter, auth *types.ServerAuth, loadReq *shared.LoadContextRequest, plan *db.Plan) bool {
+	for _, params := range *loadReq {
+		if params.SourcePlanId == "" {
+			continue
+		}
+
+		if params.SourcePlanId == plan.Id {
+			log.Println("Can't share context from a plan with itself")
+			http.Error(w, "Can't share context from a plan with itself", http.StatusBadRequest)
+			return false
+		}
+
+		if authorizePlan(w, params.SourcePlanId, auth) == nil {
+			return false
+		}
+
+		if params.SourceBranch == "" {
+			params.SourceBranch = "main"
+		}
+
+		source, err := db.GetSharedContext(auth.OrgId, params.SourcePlanId, params.SourceBranch, params.SourceContextId, true)
+		if err != nil {
+			log.Printf("Error getting shared context: %v\n", err)
+			http.Error(w, "Error getting shared context: "+err.Error(), http.StatusNotFound)
+			return false
+		}
+
+		// a context that's itself shared is loaded from its original source
+		if source.SourcePlanId != "" {
+			if source.SourcePlanId == plan.Id {
+				log.Println("Context is already shared from this plan")
+				http.Error(w, "Context is already shared from this plan", http.StatusBadRequest)
+				return false
+			}
+
+			params.SourcePlanId = source.SourcePlanId
+			params.SourceBranch = source.SourceBranch
+			params.SourceContextId = source.SourceContextId
+
+			source, err = db.GetSharedContext(auth.OrgId, params.SourcePlanId, params.SourceBranch, params.SourceContextId, true)
+			if err != nil {
+				log.Printf("Error getting shared context: %v\n", err)
+				http.Error(w, "Error getting shared context: "+err.Error(), http.StatusNotFound)
+				return false
+			}
+		}
+
+		params.ContextType = source.ContextType
+		params.Name = source.Name
+		params.Url = source.Url
+		params.FilePath = source.FilePath
+		params.Body = source.Body
+		params.ChunkPart = source.ChunkPart
+		params.ChunkMaxTokens = source.ChunkMaxTokens
+		params.LineRange = source.LineRange
+		params.Summarize = false
+	}
+
+	return true
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"plandex-server/db"
+	"plandex-server/model"
+	"plandex-server/types"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// summarizeLoadContexts replaces the body of any oversized contexts loaded with --summarize with a model-generated summary.
+// It returns the sha of each original body so that the contexts can still be checked for updates.
+func summarizeLoadContexts(plan *db.Plan, usage model.UsageParams, req *shared.LoadContextRequest) (map[*shared.LoadContextParams]string, error) {
+	summarizedShas := map[*shared.LoadContextParams]string{}
+
+	for _, params := range *req {
+		if !params.Summarize {
+			continue
+		}
+
+		numTokens, err := shared.GetNumTokens(params.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error getting num tokens: %v", err)
+		}
+
+		if numTokens <= shared.SummarizeContextMinTokens {
+			continue
+		}
+
+		if params.ApiKey == "" {
+			return nil, fmt.Errorf("api key is required to summarize context")
+		}
+
+		summary, err := summarizeContextBody(plan, usage, params.ApiKey, params.Name, params.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		hash := sha256.Sum256([]byte(params.Body))
+		summarizedShas[params] = hex.EncodeToString(hash[:])
+		params.Body = summary
+	}
+
+	return summarizedShas, nil
+}
+
+// summarizeUpdateContexts re-summarizes the updated bodies of contexts that were originally loaded as summaries
+func summarizeUpdateContexts(plan *db.Plan, usage model.UsageParams, contextsById map[string]*db.Context, req *shared.UpdateContextRequest) (map[string]string, error) {
+	summarizedShas := map[string]string{}
+
+	for id, params := range *req {
+		context, ok := contextsById[id]
+		// a body sent as just a sha can't be summarized, so it's sent again in full if it changed
+		if !ok || !context.Summarized || (params.Body == "" && params.BodySha != "") {
+			continue
+		}
+
+		if params.ApiKey == "" {
+			return nil, fmt.Errorf("api key is required to update summarized context %s", context.Name)
+		}
+
+		summary, err := summarizeContextBody(plan, usage, params.ApiKey, context.Name, params.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		hash := sha256.Sum256([]byte(params.Body))
+		summarizedShas[id] = hex.EncodeToString(hash[:])
+		params.Body = summary
+	}
+
+	return summarizedShas, nil
+}
+
+func summarizeContextBody(plan *db.Plan, usage model.UsageParams, apiKey, name, body string) (string, error) {
+	settings, err := db.GetPlanSettings(plan, true)
+	if err != nil {
+		return "", fmt.Errorf("error getting settings: %v", err)
+	}
+
+	log.Printf("Summarizing context %s\n", name)
+
+	client := model.NewClient(apiKey)
+	summary, err := model.SummarizeContext(client, settings.ModelSet.PlanSummary, name, body, usage, context.Background())
+	if err != nil {
+		return "", fmt.Errorf("error summarizing context %s: %v", name, err)
+	}
+
+	return summary, nil
+}
+
+func contextSummaryUsage(auth *types.ServerAuth, plan *db.Plan, branch string) model.UsageParams {
+	return model.UsageParams{
+		OrgId:  auth.OrgId,
+		UserId: auth.User.Id,
+		PlanId: plan.Id,
+		Branch: branch,
+		Role:   shared.ModelRolePlanSummary,
+	}
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"plandex-server/db"
+	"plandex-server/types"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/plandex/plandex/shared"
+)
+
+func ExportPlanHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for ExportPlanHandler")
+
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+	branch := vars["branch"]
+
+	log.Println("planId: ", planId, "branch: ", branch)
+
+	plan := authorizePlan(w, planId, auth)
+	if plan == nil {
+		return
+	}
+
+	var err error
+	ctx, cancel := context.WithCancel(context.Background())
+	unlockFn := lockRepo(w, r, auth, db.LockScopeRead, ctx, cancel, true)
+	if unlockFn == nil {
+		return
+	} else {
+		defer func() {
+			(*unlockFn)(err)
+		}()
+	}
+
+	files, err := db.GetPlanArchiveFiles(auth.OrgId, planId)
+
+	if err != nil {
+		log.Printf("Error getting plan files: %v\n", err)
+		http.Error(w, "Error getting plan files: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	summaries, err := db.GetPlanArchiveSummaries(auth.OrgId, planId)
+
+	if err != nil {
+		log.Printf("Error getting plan summaries: %v\n", err)
+		http.Error(w, "Error getting plan summaries: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	archive := shared.PlanArchive{
+		Version:    shared.PlanArchiveVersion,
+		Name:       plan.Name,
+		Branch:     branch,
+		ExportedAt: time.Now(),
+		Files:      files,
+		Summaries:  summaries,
+	}
+
+	bytes, err := json.Marshal(archive)
+
+	if err != nil {
+		log.Printf("Error marshalling archive: %v\n", err)
+		http.Error(w, "Error marshalling archive: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+
+	log.Println("Successfully exported plan", planId)
+}
+
+func ImportPlanHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for ImportPlanHandler")
+
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	if !auth.HasPermission(types.PermissionCreatePlan) {
+		log.Println("User does not have permission to create a plan")
+		http.Error(w, "User does not have permission to create a plan", http.StatusForbidden)
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectId := vars["projectId"]
+
+	log.Println("projectId: ", projectId)
+
+	if !authorizeProject(w, projectId, auth) {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error reading request body: %v\n", err)
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var archive shared.PlanArchive
+	if err := json.Unmarshal(body, &archive); err != nil {
+		log.Printf("Error parsing request body: %v\n", err)
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+
+	if archive.Version != shared.PlanArchiveVersion {
+		log.Printf("Unsupported archive version: %d\n", archive.Version)
+		http.Error(w, fmt.Sprintf("Unsupported archive version: %d", archive.Version), http.StatusBadRequest)
+		return
+	}
+
+	name := archive.Name
+	if name == "" || name == "draft" {
+		name = "imported"
+	}
+
+	name, err = db.GetUniquePlanName(projectId, auth.User.Id, name)
+
+	if err != nil {
+		log.Printf("Error checking if plan exists: %v\n", err)
+		http.Error(w, "Error checking if plan exists: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	plan, err := db.CreatePlan(auth.OrgId, projectId, auth.User.Id, name)
+
+	if err != nil {
+		log.Printf("Error creating plan: %v\n", err)
+		http.Error(w, "Error creating plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = importPlanArchive(auth, plan, &archive)
+
+	if err != nil {
+		log.Printf("Error importing plan: %v\n", err)
+
+		// clean up the partially imported plan
+		_, delErr := db.Conn.Exec("DELETE FROM plans WHERE id = $1", plan.Id)
+		if delErr != nil {
+			log.Printf("Error deleting plan after failed import: %v\n", delErr)
+		}
+		delErr = db.DeletePlanDir(auth.OrgId, plan.Id)
+		if delErr != nil {
+			log.Printf("Error deleting plan dir after failed import: %v\n", delErr)
+		}
+
+		http.Error(w, "Error importing plan: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := shared.ImportPlanResponse{
+		Id:   plan.Id,
+		Name: plan.Name,
+	}
+
+	bytes, err := json.Marshal(resp)
+
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+
+	log.Printf("Successfully imported plan: %v\n", plan)
+}
+
+func importPlanArchive(auth *types.ServerAuth, plan *db.Plan, archive *shared.PlanArchive) error {
+	err := db.StorePlanArchiveFiles(auth.OrgId, plan.Id, auth.User.Id, archive.Files)
+
+	if err != nil {
+		return err
+	}
+
+	for _, summary := range archive.Summaries {
+		err = db.StoreSummary(&db.ConvoSummary{
+			OrgId:                       auth.OrgId,
+			PlanId:                      plan.Id,
+			LatestConvoMessageId:        summary.LatestConvoMessageId,
+			LatestConvoMessageCreatedAt: summary.LatestConvoMessageCreatedAt,
+			Summary:                     summary.Summary,
+			Tokens:                      summary.Tokens,
+			NumMessages:                 summary.NumMessages,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err = db.GitAddAndCommit(auth.OrgId, plan.Id, "main", "📦 Imported plan")
+
+	if err != nil {
+		return fmt.Errorf("error committing imported plan: %v", err)
+	}
+
+	err = db.SyncPlanTokens(auth.OrgId, plan.Id, "main")
+
+	if err != nil {
+		return fmt.Errorf("error syncing plan tokens: %v", err)
+	}
+
+	return nil
+}
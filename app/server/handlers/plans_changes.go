@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"plandex-server/db"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -79,12 +81,29 @@ func ApplyPlanHandler(w http.ResponseWriter, r *http.Request) {
 	branch := vars["branch"]
 	log.Println("planId: ", planId)
 
-	plan := authorizePlan(w, planId, auth)
+	plan := authorizePlanApply(w, planId, auth)
 	if plan == nil {
 		return
 	}
 
-	var err error
+	// the request body is optional--without one, all pending changes are applied
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error reading request body: %v\n", err)
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var requestBody *shared.ApplyPlanRequest
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &requestBody); err != nil {
+			log.Printf("Error parsing request body: %v\n", err)
+			http.Error(w, "Error parsing request body", http.StatusBadRequest)
+			return
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	unlockFn := lockRepo(w, r, auth, db.LockScopeWrite, ctx, cancel, true)
 	if unlockFn == nil {
@@ -95,7 +114,7 @@ func ApplyPlanHandler(w http.ResponseWriter, r *http.Request) {
 		}()
 	}
 
-	err = db.ApplyPlan(auth.OrgId, auth.User.Id, branch, plan)
+	err = db.ApplyPlan(auth.OrgId, auth.User.Id, branch, plan, requestBody)
 
 	if err != nil {
 		log.Printf("Error applying plan: %v\n", err)
@@ -119,7 +138,7 @@ func RejectAllChangesHandler(w http.ResponseWriter, r *http.Request) {
 	branch := vars["branch"]
 	log.Println("planId: ", planId, "branch: ", branch)
 
-	if authorizePlan(w, planId, auth) == nil {
+	if authorizePlanPrompt(w, planId, auth) == nil {
 		return
 	}
 
@@ -167,7 +186,7 @@ func RejectFileHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("planId: ", planId, "branch: ", branch)
 
-	if authorizePlan(w, planId, auth) == nil {
+	if authorizePlanPrompt(w, planId, auth) == nil {
 		return
 	}
 
@@ -197,6 +216,16 @@ func RejectFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if strings.TrimSpace(req.Comment) != "" {
+		err = db.AddRejectedFileFeedback(auth.OrgId, planId, req.FilePath, strings.TrimSpace(req.Comment))
+
+		if err != nil {
+			log.Printf("Error storing rejected file feedback: %v\n", err)
+			http.Error(w, "Error storing rejected file feedback: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
 	err = db.GitAddAndCommit(auth.OrgId, planId, branch, fmt.Sprintf("🚫 Rejected pending changes to file: %s", req.FilePath))
 
 	if err != nil {
@@ -208,6 +237,66 @@ func RejectFileHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Successfully rejected plan file", req.FilePath)
 }
 
+func EditFileHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for EditFileHandler")
+
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+	branch := vars["branch"]
+
+	log.Println("planId: ", planId, "branch: ", branch)
+
+	if authorizePlanPrompt(w, planId, auth) == nil {
+		return
+	}
+
+	var req shared.EditFileRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Printf("Error decoding request: %v\n", err)
+		http.Error(w, "Error decoding request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	unlockFn := lockRepo(w, r, auth, db.LockScopeWrite, ctx, cancel, true)
+	if unlockFn == nil {
+		return
+	} else {
+		defer func() {
+			(*unlockFn)(err)
+		}()
+	}
+
+	changed, err := db.EditPlanFile(auth.OrgId, planId, req.FilePath, req.Content)
+
+	if err != nil {
+		log.Printf("Error editing file: %v\n", err)
+		http.Error(w, "Error editing file: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !changed {
+		log.Println("Edit didn't change plan file", req.FilePath)
+		return
+	}
+
+	err = db.GitAddAndCommit(auth.OrgId, planId, branch, fmt.Sprintf("✏️  Edited pending changes to file: %s", req.FilePath))
+
+	if err != nil {
+		log.Printf("Error committing edited file: %v\n", err)
+		http.Error(w, "Error committing edited file: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Println("Successfully edited plan file", req.FilePath)
+}
+
 func ArchivePlanHandler(w http.ResponseWriter, r *http.Request) {
 	auth := authenticate(w, r, true)
 	if auth == nil {
@@ -77,7 +77,7 @@ func LoadContextHandler(w http.ResponseWriter, r *http.Request) {
 	branchName := vars["branch"]
 	log.Println("planId: ", planId)
 
-	plan := authorizePlan(w, planId, auth)
+	plan := authorizePlanPrompt(w, planId, auth)
 	if plan == nil {
 		return
 	}
@@ -130,7 +130,7 @@ func UpdateContextHandler(w http.ResponseWriter, r *http.Request) {
 	branchName := vars["branch"]
 	log.Println("planId: ", planId)
 
-	plan := authorizePlan(w, planId, auth)
+	plan := authorizePlanPrompt(w, planId, auth)
 	if plan == nil {
 		return
 	}
@@ -161,11 +161,31 @@ func UpdateContextHandler(w http.ResponseWriter, r *http.Request) {
 		}()
 	}
 
+	dbContexts, err := db.GetPlanContexts(auth.OrgId, planId, false)
+	if err != nil {
+		log.Printf("Error getting contexts: %v\n", err)
+		http.Error(w, "Error getting contexts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	contextsById := make(map[string]*db.Context)
+	for _, dbContext := range dbContexts {
+		contextsById[dbContext.Id] = dbContext
+	}
+
+	summarizedShas, err := summarizeUpdateContexts(plan, contextSummaryUsage(auth, plan, branchName), contextsById, &requestBody)
+	if err != nil {
+		log.Printf("Error summarizing contexts: %v\n", err)
+		http.Error(w, "Error summarizing contexts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	updateRes, err := db.UpdateContexts(db.UpdateContextsParams{
-		Req:        &requestBody,
-		OrgId:      auth.OrgId,
-		Plan:       plan,
-		BranchName: branchName,
+		Req:            &requestBody,
+		OrgId:          auth.OrgId,
+		Plan:           plan,
+		BranchName:     branchName,
+		SummarizedShas: summarizedShas,
 	})
 
 	if err != nil {
@@ -174,8 +194,15 @@ func UpdateContextHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if updateRes.MaxTokensExceeded {
-		log.Printf("The total number of tokens (%d) exceeds the maximum allowed (%d)", updateRes.TotalTokens, updateRes.MaxTokens)
+	updateRes.AcceptsBodySha = true
+
+	// nothing to commit if every body sent as just a sha was unchanged
+	if updateRes.MaxTokensExceeded || len(updateRes.MissingBodyShas) > 0 || len(requestBody) == 0 {
+		if updateRes.MaxTokensExceeded {
+			log.Printf("The total number of tokens (%d) exceeds the maximum allowed (%d)", updateRes.TotalTokens, updateRes.MaxTokens)
+		} else if len(updateRes.MissingBodyShas) > 0 {
+			log.Printf("%d bodies sent as just a sha aren't in the plan", len(updateRes.MissingBodyShas))
+		}
 		bytes, err := json.Marshal(updateRes)
 
 		if err != nil {
@@ -222,7 +249,7 @@ func DeleteContextHandler(w http.ResponseWriter, r *http.Request) {
 	branchName := vars["branch"]
 	log.Println("planId: ", planId)
 
-	plan := authorizePlan(w, planId, auth)
+	plan := authorizePlanPrompt(w, planId, auth)
 
 	if plan == nil {
 		return
@@ -326,3 +353,89 @@ func DeleteContextHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Write(bytes)
 }
+
+func PinContextHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for PinContextHandler")
+
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+	branchName := vars["branch"]
+	log.Println("planId: ", planId)
+
+	plan := authorizePlanPrompt(w, planId, auth)
+	if plan == nil {
+		return
+	}
+
+	// read the request body
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error reading request body: %v\n", err)
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var requestBody shared.PinContextRequest
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		log.Printf("Error parsing request body: %v\n", err)
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	unlockFn := lockRepo(w, r, auth, db.LockScopeWrite, ctx, cancel, true)
+	if unlockFn == nil {
+		return
+	} else {
+		defer func() {
+			(*unlockFn)(err)
+		}()
+	}
+
+	var pinnedContexts []*shared.Context
+	for id := range requestBody.Ids {
+		var dbContext *db.Context
+		var changed bool
+		dbContext, changed, err = db.SetContextPinned(auth.OrgId, planId, id, requestBody.Pinned)
+		if err != nil {
+			log.Printf("Error pinning context: %v\n", err)
+			http.Error(w, "Error pinning context: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if changed {
+			pinnedContexts = append(pinnedContexts, dbContext.ToApi())
+		}
+	}
+
+	var res shared.PinContextResponse
+
+	if len(pinnedContexts) > 0 {
+		res.Msg = shared.SummaryForPinContext(pinnedContexts, requestBody.Pinned) + "\n\n" + shared.TableForPinContext(pinnedContexts)
+
+		err = db.GitAddAndCommit(auth.OrgId, planId, branchName, res.Msg)
+		if err != nil {
+			log.Printf("Error committing changes: %v\n", err)
+			http.Error(w, "Error committing changes: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	bytes, err := json.Marshal(res)
+
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Println("Successfully processed PinContextHandler request")
+
+	w.Write(bytes)
+}
@@ -3,11 +3,18 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"plandex-server/db"
+	"plandex-server/model"
+	modelPlan "plandex-server/model/plan"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/plandex/plandex/shared"
+	"github.com/sashabaranov/go-openai"
 )
 
 func ListConvoHandler(w http.ResponseWriter, r *http.Request) {
@@ -57,3 +64,213 @@ func ListConvoHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(bytes)
 
 }
+
+func CompactConvoHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received a request for CompactConvoHandler")
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+	branch := vars["branch"]
+
+	log.Println("planId: ", planId, "branch: ", branch)
+
+	plan := authorizePlanPrompt(w, planId, auth)
+	if plan == nil {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error reading request body: %v\n", err)
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var requestBody shared.CompactConvoRequest
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		log.Printf("Error parsing request body: %v\n", err)
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+
+	if requestBody.KeepRecent < 0 {
+		http.Error(w, "keepRecent can't be negative", http.StatusBadRequest)
+		return
+	}
+
+	// the conversation is loaded at the start of a reply and the reply is stored after it, so it can't change underneath
+	if modelPlan.GetActivePlan(planId, branch) != nil {
+		http.Error(w, "Can't compact the conversation while the plan is running", http.StatusConflict)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	unlockFn := lockRepo(w, r, auth, db.LockScopeWrite, ctx, cancel, true)
+	if unlockFn == nil {
+		return
+	} else {
+		defer func() {
+			(*unlockFn)(err)
+		}()
+	}
+
+	convo, err := db.GetPlanConvo(auth.OrgId, planId)
+	if err != nil {
+		log.Println("Error getting plan convo: ", err)
+		http.Error(w, "Error getting plan convo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// keep whole turns, so the verbatim part of the conversation starts with a prompt
+	cutoff := len(convo) - requestBody.KeepRecent
+	for cutoff > 0 && cutoff < len(convo) && convo[cutoff].Role != openai.ChatMessageRoleUser {
+		cutoff--
+	}
+
+	if cutoff < 2 {
+		http.Error(w, "Not enough messages to compact", http.StatusBadRequest)
+		return
+	}
+
+	compacted := convo[:cutoff]
+	last := compacted[len(compacted)-1]
+
+	tokensBefore := 0
+	compactedTokens := 0
+	for i, msg := range convo {
+		tokensBefore += msg.Tokens
+		if i < cutoff {
+			compactedTokens += msg.Tokens
+		}
+	}
+
+	compaction := &shared.ConvoCompaction{
+		FromNum:     compacted[0].Num,
+		ToNum:       last.Num,
+		NumMessages: len(compacted),
+		Tokens:      compactedTokens,
+		UserId:      auth.User.Id,
+		CompactedAt: time.Now().UTC(),
+	}
+
+	// a summary is generated in the background after each reply, so there's usually one ending at the cutoff already
+	var summary string
+	summaries, err := db.GetPlanSummaries(planId, []string{last.Id})
+	if err != nil {
+		log.Println("Error getting plan summaries: ", err)
+		http.Error(w, "Error getting plan summaries: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(summaries) > 0 {
+		s := summaries[len(summaries)-1]
+		summary = s.Summary
+		compaction.SummaryId = s.Id
+	} else {
+		if requestBody.ApiKey == "" {
+			http.Error(w, "API key is required to summarize the conversation", http.StatusBadRequest)
+			return
+		}
+
+		settings, err := db.GetPlanSettings(plan, true)
+		if err != nil {
+			log.Println("Error getting plan settings: ", err)
+			http.Error(w, "Error getting plan settings: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var messages []*openai.ChatCompletionMessage
+		for _, msg := range compacted {
+			messages = append(messages, &openai.ChatCompletionMessage{
+				Role:    msg.Role,
+				Content: msg.Message,
+			})
+		}
+
+		config := settings.ModelSet.PlanSummary
+		generated, err := model.PlanSummary(model.NewClient(requestBody.ApiKey), config, model.PlanSummaryParams{
+			Conversation:                messages,
+			LatestConvoMessageId:        last.Id,
+			LatestConvoMessageCreatedAt: last.CreatedAt,
+			NumMessages:                 len(compacted),
+			OrgId:                       auth.OrgId,
+			UserId:                      auth.User.Id,
+			PlanId:                      planId,
+			Branch:                      branch,
+		}, ctx)
+		if err != nil {
+			log.Println("Error summarizing conversation: ", err)
+			http.Error(w, "Error summarizing conversation: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		summary = generated.Summary
+		compaction.Model = config.BaseModelConfig.ModelName
+	}
+
+	summaryTokens, err := shared.GetNumTokens(summary)
+	if err != nil {
+		log.Println("Error getting summary num tokens: ", err)
+		http.Error(w, "Error getting summary num tokens: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if summaryTokens >= compactedTokens {
+		http.Error(w, "Compacting wouldn't make the conversation any smaller", http.StatusBadRequest)
+		return
+	}
+
+	summaryMsg := &db.ConvoMessage{
+		OrgId:      auth.OrgId,
+		PlanId:     planId,
+		UserId:     auth.User.Id,
+		Role:       openai.ChatMessageRoleAssistant,
+		Tokens:     summaryTokens,
+		Message:    summary,
+		Compaction: compaction,
+	}
+
+	err = db.CompactConvo(auth.OrgId, planId, compacted, summaryMsg)
+	if err != nil {
+		log.Println("Error compacting convo: ", err)
+		http.Error(w, "Error compacting convo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = db.SyncPlanTokens(auth.OrgId, planId, branch)
+	if err != nil {
+		log.Println("Error syncing plan tokens: ", err)
+		http.Error(w, "Error syncing plan tokens: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tokensAfter := tokensBefore - compactedTokens + summaryTokens
+
+	commitMsg := fmt.Sprintf("🗜️ Compacted messages #%d-#%d into a summary | %d → %d 🪙", compaction.FromNum, compaction.ToNum, compactedTokens, summaryTokens)
+	err = db.GitAddAndCommit(auth.OrgId, planId, branch, commitMsg)
+	if err != nil {
+		log.Println("Error committing compacted convo: ", err)
+		http.Error(w, "Error committing compacted convo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(shared.CompactConvoResponse{
+		Compaction:    compaction,
+		SummaryTokens: summaryTokens,
+		TokensBefore:  tokensBefore,
+		TokensAfter:   tokensAfter,
+	})
+	if err != nil {
+		log.Println("Error marshalling response: ", err)
+		http.Error(w, "Error marshalling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Println("Successfully processed request for CompactConvoHandler")
+	w.Write(bytes)
+}
@@ -95,24 +95,12 @@ func CreatePlanHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	} else {
-		i := 2
-		originalName := name
-		for {
-			var count int
-			err := db.Conn.Get(&count, "SELECT COUNT(*) FROM plans WHERE project_id = $1 AND owner_id = $2 AND name = $3", projectId, auth.User.Id, name)
-
-			if err != nil {
-				log.Printf("Error checking if plan exists: %v\n", err)
-				http.Error(w, "Error checking if plan exists: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
+		name, err = db.GetUniquePlanName(projectId, auth.User.Id, name)
 
-			if count == 0 {
-				break
-			}
-
-			name = originalName + "." + fmt.Sprint(i)
-			i++
+		if err != nil {
+			log.Printf("Error checking if plan exists: %v\n", err)
+			http.Error(w, "Error checking if plan exists: "+err.Error(), http.StatusInternalServerError)
+			return
 		}
 	}
 
@@ -289,10 +277,23 @@ func ListPlansHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	sharedPlans, permissionByPlanId, err := db.ListSharedPlans(projectIds, auth.User.Id)
+
+	if err != nil {
+		log.Printf("Error listing shared plans: %v\n", err)
+		http.Error(w, "Error listing shared plans: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	var apiPlans []*shared.Plan
 	for _, plan := range plans {
 		apiPlans = append(apiPlans, plan.ToApi())
 	}
+	for _, plan := range sharedPlans {
+		apiPlan := plan.ToApi()
+		apiPlan.SharedPermission = permissionByPlanId[plan.Id]
+		apiPlans = append(apiPlans, apiPlan)
+	}
 
 	bytes, err := json.Marshal(apiPlans)
 
@@ -542,6 +543,16 @@ func GetCurrentBranchByPlanIdHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	sharedPlans, _, err := db.ListSharedPlans([]string{projectId}, auth.User.Id)
+
+	if err != nil {
+		log.Printf("Error listing shared plans: %v\n", err)
+		http.Error(w, "Error listing shared plans: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	plans = append(plans, sharedPlans...)
+
 	if len(plans) == 0 {
 		log.Println("No plans found")
 		http.Error(w, "No plans found", http.StatusNotFound)
@@ -12,6 +12,7 @@ import (
 	"plandex-server/model"
 	modelPlan "plandex-server/model/plan"
 	"plandex-server/types"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -34,7 +35,7 @@ func TellPlanHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("planId: ", planId)
 
-	plan := authorizePlanExecUpdate(w, planId, auth)
+	plan := authorizePlanPrompt(w, planId, auth)
 	if plan == nil {
 		return
 	}
@@ -63,6 +64,18 @@ func TellPlanHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !checkModelQuotas(w, auth, true) {
+		return
+	}
+
+	for _, target := range requestBody.Notify {
+		if err := target.Validate(); err != nil {
+			log.Printf("Invalid notification target: %v\n", err)
+			http.Error(w, "Invalid notification target: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	if os.Getenv("IS_CLOUD") != "" {
 		user, err := db.GetUser(auth.User.Id)
 
@@ -87,6 +100,24 @@ func TellPlanHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	settings, err := db.GetPlanSettings(plan, true)
+	if err != nil {
+		log.Printf("Error getting plan settings: %v\n", err)
+		http.Error(w, "Error getting plan settings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// fail before the prompt is stored rather than once the plan is streaming, so the client can queue the prompt and send it
+	// again later
+	if !model.ChainAvailable(model.ModelChain(settings.ModelSet.Planner.ModelRoleConfig)) {
+		writeApiError(w, shared.ApiError{
+			Type:   shared.ApiErrorTypeProviderUnavailable,
+			Status: http.StatusServiceUnavailable,
+			Msg:    "The planner's model providers are failing. Try again shortly.",
+		})
+		return
+	}
+
 	client := model.NewClient(requestBody.ApiKey)
 	err = modelPlan.Tell(client, plan, branch, auth, &requestBody)
 
@@ -97,7 +128,7 @@ func TellPlanHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if requestBody.ConnectStream {
-		startResponseStream(w, auth, planId, branch, false)
+		startResponseStream(w, auth, planId, branch, false, 0)
 	}
 
 	log.Println("Successfully processed request for TellPlanHandler")
@@ -115,7 +146,7 @@ func BuildPlanHandler(w http.ResponseWriter, r *http.Request) {
 	branch := vars["branch"]
 
 	log.Println("planId: ", planId)
-	plan := authorizePlanExecUpdate(w, planId, auth)
+	plan := authorizePlanPrompt(w, planId, auth)
 	if plan == nil {
 		return
 	}
@@ -144,6 +175,10 @@ func BuildPlanHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !checkModelQuotas(w, auth, true) {
+		return
+	}
+
 	client := model.NewClient(requestBody.ApiKey)
 	numBuilds, err := modelPlan.Build(client, plan, branch, auth)
 
@@ -160,7 +195,7 @@ func BuildPlanHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if requestBody.ConnectStream {
-		startResponseStream(w, auth, planId, branch, false)
+		startResponseStream(w, auth, planId, branch, false, 0)
 	}
 
 	log.Println("Successfully processed request for BuildPlanHandler")
@@ -202,7 +237,19 @@ func ConnectPlanHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	startResponseStream(w, auth, planId, branch, true)
+	// a client whose connection dropped reconnects with the seq of the last message it received
+	var resumeFrom int
+	if s := r.URL.Query().Get("resumeFrom"); s != "" {
+		var err error
+		resumeFrom, err = strconv.Atoi(s)
+		if err != nil {
+			log.Printf("Error parsing resumeFrom: %v\n", err)
+			http.Error(w, "Invalid resumeFrom", http.StatusBadRequest)
+			return
+		}
+	}
+
+	startResponseStream(w, auth, planId, branch, true, resumeFrom)
 
 	log.Println("Successfully processed request for ConnectPlanHandler")
 }
@@ -233,7 +280,7 @@ func StopPlanHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if authorizePlan(w, planId, auth) == nil {
+	if authorizePlanPrompt(w, planId, auth) == nil {
 		return
 	}
 
@@ -297,7 +344,7 @@ func RespondMissingFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	plan := authorizePlan(w, planId, auth)
+	plan := authorizePlanPrompt(w, planId, auth)
 	if plan == nil {
 		return
 	}
@@ -334,14 +381,16 @@ func RespondMissingFileHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		dbContext := dbContexts[0]
+		if len(dbContexts) > 0 {
+			dbContext := dbContexts[0]
 
-		log.Println("loaded missing file:", dbContext.FilePath)
+			log.Println("loaded missing file:", dbContext.FilePath)
 
-		modelPlan.UpdateActivePlan(planId, branch, func(activePlan *types.ActivePlan) {
-			activePlan.Contexts = append(activePlan.Contexts, dbContext)
-			activePlan.ContextsByPath[dbContext.FilePath] = dbContext
-		})
+			modelPlan.UpdateActivePlan(planId, branch, func(activePlan *types.ActivePlan) {
+				activePlan.Contexts = append(activePlan.Contexts, dbContext)
+				activePlan.ContextsByPath[dbContext.FilePath] = dbContext
+			})
+		}
 	}
 
 	// This will resume model stream
@@ -350,18 +399,3 @@ func RespondMissingFileHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("Successfully processed request for RespondMissingFileHandler")
 }
-
-func authorizePlanExecUpdate(w http.ResponseWriter, planId string, auth *types.ServerAuth) *db.Plan {
-	plan := authorizePlan(w, planId, auth)
-	if plan == nil {
-		return nil
-	}
-
-	if plan.OwnerId != auth.User.Id && !auth.HasPermission(types.PermissionUpdateAnyPlan) {
-		log.Println("User does not have permission to update plan")
-		http.Error(w, "User does not have permission to update plan", http.StatusForbidden)
-		return nil
-	}
-
-	return plan
-}
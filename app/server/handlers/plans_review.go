@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"plandex-server/db"
+	"plandex-server/model"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/plandex/plandex/shared"
+)
+
+func ReviewHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received a request for ReviewHandler")
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+	branch := vars["branch"]
+
+	log.Println("planId: ", planId, "branch: ", branch)
+
+	plan := authorizePlanPrompt(w, planId, auth)
+	if plan == nil {
+		return
+	}
+
+	var req shared.ReviewRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Printf("Error decoding request: %v\n", err)
+		http.Error(w, "Error decoding request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(req.Diff) == "" {
+		http.Error(w, "Diff is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.ApiKey == "" {
+		http.Error(w, "Api key is required", http.StatusBadRequest)
+		return
+	}
+
+	if !checkModelQuotas(w, auth, false) {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	unlockFn := lockRepo(w, r, auth, db.LockScopeRead, ctx, cancel, true)
+	if unlockFn == nil {
+		return
+	}
+
+	settings, err := db.GetPlanSettings(plan, true)
+
+	// the repo isn't needed during the review, which can take a while
+	(*unlockFn)(err)
+
+	if err != nil {
+		log.Println("Error getting settings: ", err)
+		http.Error(w, "Error getting settings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res, err := model.Review(model.NewClient(req.ApiKey), settings.ModelSet.Planner.ModelRoleConfig, model.ReviewParams{
+		Range:  req.Range,
+		Log:    req.Log,
+		Diff:   req.Diff,
+		OrgId:  auth.OrgId,
+		UserId: auth.User.Id,
+		PlanId: planId,
+		Branch: branch,
+	}, r.Context())
+
+	if err != nil {
+		log.Println("Error reviewing changes: ", err)
+		http.Error(w, "Error reviewing changes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(res)
+
+	if err != nil {
+		log.Println("Error marshalling review: ", err)
+		http.Error(w, "Error marshalling review: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Println("Successfully processed request for ReviewHandler")
+	w.Write(bytes)
+}
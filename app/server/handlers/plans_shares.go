@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"plandex-server/db"
+	"plandex-server/types"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/plandex/plandex/shared"
+)
+
+func ListPlanSharesHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for ListPlanSharesHandler")
+
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+
+	log.Println("planId: ", planId)
+
+	if authorizePlan(w, planId, auth) == nil {
+		return
+	}
+
+	shares, err := db.ListPlanShares(planId)
+
+	if err != nil {
+		log.Printf("Error listing plan shares: %v\n", err)
+		http.Error(w, "Error listing plan shares: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	apiShares := []*shared.PlanShare{}
+	for _, share := range shares {
+		apiShares = append(apiShares, share.ToApi())
+	}
+
+	bytes, err := json.Marshal(apiShares)
+
+	if err != nil {
+		log.Printf("Error marshalling plan shares: %v\n", err)
+		http.Error(w, "Error marshalling plan shares: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+
+	log.Println("Successfully processed request for ListPlanSharesHandler")
+}
+
+func SharePlanHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for SharePlanHandler")
+
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	if auth.User.IsTrial {
+		writeApiError(w, shared.ApiError{
+			Type:   shared.ApiErrorTypeTrialActionNotAllowed,
+			Status: http.StatusForbidden,
+			Msg:    "Anonymous trial user can't share plans",
+		})
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+
+	log.Println("planId: ", planId)
+
+	plan := authorizePlanManageShares(w, planId, auth)
+	if plan == nil {
+		return
+	}
+
+	var req shared.SharePlanRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Printf("Error unmarshalling request: %v\n", err)
+		http.Error(w, "Error unmarshalling request: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !req.Permission.Valid() {
+		log.Printf("Invalid permission: %v\n", req.Permission)
+		http.Error(w, "Invalid permission: "+string(req.Permission), http.StatusBadRequest)
+		return
+	}
+
+	var userId *string
+	if req.Email != "" {
+		user, err := db.GetUserByEmail(strings.ToLower(req.Email))
+
+		if err != nil {
+			log.Printf("Error getting user: %v\n", err)
+			http.Error(w, "Error getting user: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		isMember := false
+		if user != nil {
+			isMember, err = db.ValidateOrgMembership(user.Id, auth.OrgId)
+
+			if err != nil {
+				log.Printf("Error validating org membership: %v\n", err)
+				http.Error(w, "Error validating org membership: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if !isMember {
+			log.Printf("User isn't a member of the org: %v\n", req.Email)
+			http.Error(w, req.Email+" isn't a member of the org. Invite them first.", http.StatusBadRequest)
+			return
+		}
+
+		if user.Id == plan.OwnerId {
+			http.Error(w, "Plan can't be shared with its owner", http.StatusBadRequest)
+			return
+		}
+
+		userId = &user.Id
+	}
+
+	err = db.SetPlanShare(auth.OrgId, planId, userId, req.Permission, auth.User.Id)
+
+	if err != nil {
+		log.Printf("Error sharing plan: %v\n", err)
+		http.Error(w, "Error sharing plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Println("Successfully shared plan")
+}
+
+func DeletePlanShareHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for DeletePlanShareHandler")
+
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+	shareId := vars["shareId"]
+
+	log.Println("planId: ", planId, "shareId: ", shareId)
+
+	if authorizePlanManageShares(w, planId, auth) == nil {
+		return
+	}
+
+	found, err := db.DeletePlanShare(planId, shareId)
+
+	if err != nil {
+		log.Printf("Error deleting plan share: %v\n", err)
+		http.Error(w, "Error deleting plan share: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !found {
+		log.Printf("Plan share not found: %v\n", shareId)
+		http.Error(w, "Plan share not found: "+shareId, http.StatusNotFound)
+		return
+	}
+
+	log.Println("Successfully deleted plan share")
+}
+
+func authorizePlanManageShares(w http.ResponseWriter, planId string, auth *types.ServerAuth) *db.Plan {
+	plan := authorizePlan(w, planId, auth)
+
+	if plan == nil {
+		return nil
+	}
+
+	if plan.OwnerId != auth.User.Id && !auth.HasPermission(types.PermissionManageAnyPlanShares) {
+		log.Println("User does not have permission to manage plan shares")
+		http.Error(w, "Only the plan's owner or an org admin can change who it's shared with", http.StatusForbidden)
+		return nil
+	}
+
+	return plan
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"plandex-server/db"
+
+	"github.com/gorilla/mux"
+)
+
+func ListPlanStepsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received a request for ListPlanStepsHandler")
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+
+	log.Println("planId: ", planId)
+
+	if authorizePlan(w, planId, auth) == nil {
+		return
+	}
+
+	var err error
+	ctx, cancel := context.WithCancel(context.Background())
+	unlockFn := lockRepo(w, r, auth, db.LockScopeRead, ctx, cancel, true)
+	if unlockFn == nil {
+		return
+	} else {
+		defer func() {
+			(*unlockFn)(err)
+		}()
+	}
+
+	steps, err := db.GetPlanSteps(auth.OrgId, planId)
+
+	if err != nil {
+		log.Println("Error getting plan steps: ", err)
+		http.Error(w, "Error getting plan steps: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(steps)
+
+	if err != nil {
+		log.Println("Error marshalling plan steps: ", err)
+		http.Error(w, "Error marshalling plan steps: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Println("Successfully processed request for ListPlanStepsHandler")
+	w.Write(bytes)
+}
@@ -81,7 +81,7 @@ func RewindPlanHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("planId: ", planId)
 
-	if authorizePlan(w, planId, auth) == nil {
+	if authorizePlanPrompt(w, planId, auth) == nil {
 		return
 	}
 
@@ -152,3 +152,70 @@ func RewindPlanHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("Successfully processed request for RewindPlanHandler")
 }
+
+func DiffPlanHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for DiffPlanHandler")
+
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+
+	log.Println("planId: ", planId)
+
+	if authorizePlan(w, planId, auth) == nil {
+		return
+	}
+
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	if from == "" {
+		http.Error(w, "from revision is required", http.StatusBadRequest)
+		return
+	}
+	if to == "" {
+		to = "HEAD"
+	}
+
+	var err error
+	ctx, cancel := context.WithCancel(context.Background())
+	unlockFn := lockRepo(w, r, auth, db.LockScopeRead, ctx, cancel, true)
+	if unlockFn == nil {
+		return
+	} else {
+		defer func() {
+			(*unlockFn)(err)
+		}()
+	}
+
+	res := shared.PlanDiffResponse{}
+
+	res.From, err = db.GetPlanRevision(auth.OrgId, planId, from)
+	if err != nil {
+		log.Println("Error getting from revision: ", err)
+		http.Error(w, "Error getting revision "+from+": "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res.To, err = db.GetPlanRevision(auth.OrgId, planId, to)
+	if err != nil {
+		log.Println("Error getting to revision: ", err)
+		http.Error(w, "Error getting revision "+to+": "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bytes, err := json.Marshal(res)
+
+	if err != nil {
+		log.Println("Error marshalling diff: ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+
+	log.Println("Successfully processed request for DiffPlanHandler")
+}
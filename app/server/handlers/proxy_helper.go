@@ -46,7 +46,9 @@ func proxyActivePlanMethod(w http.ResponseWriter, r *http.Request, planId, branc
 	} else {
 		log.Printf("Forwarding request to %s\n", modelStream.InternalIp)
 		proxyUrl := fmt.Sprintf("http://%s:%s/plans/%s/%s/%s", modelStream.InternalIp, os.Getenv("PORT"), planId, branch, method)
-		proxyUrl += "?proxy=true"
+		query := r.URL.Query()
+		query.Set("proxy", "true")
+		proxyUrl += "?" + query.Encode()
 
 		log.Printf("Proxy url: %s\n", proxyUrl)
 		proxyRequest(w, r, proxyUrl)
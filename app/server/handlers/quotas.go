@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"plandex-server/db"
+	"plandex-server/types"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// there's no way to know when another stream will finish, so clients are asked to check again after this long
+const concurrentStreamsRetryAfter = 15 * time.Second
+
+func ListQuotasHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for ListQuotasHandler")
+
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	if !auth.HasPermission(types.PermissionManageQuotas) {
+		log.Println("User doesn't have permission to manage quotas")
+		http.Error(w, "User doesn't have permission to manage quotas", http.StatusForbidden)
+		return
+	}
+
+	quotas, err := db.ListQuotas(auth.OrgId)
+	if err != nil {
+		log.Printf("Error listing quotas: %v\n", err)
+		http.Error(w, "Error listing quotas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := []*shared.Quota{}
+	for _, quota := range quotas {
+		res = append(res, quota.ToApi())
+	}
+
+	bytes, err := json.Marshal(res)
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Println("Successfully processed ListQuotasHandler request")
+
+	w.Write(bytes)
+}
+
+func SetQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for SetQuotaHandler")
+
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	if !auth.HasPermission(types.PermissionManageQuotas) {
+		log.Println("User doesn't have permission to manage quotas")
+		http.Error(w, "User doesn't have permission to manage quotas", http.StatusForbidden)
+		return
+	}
+
+	var req shared.SetQuotaRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Printf("Error unmarshalling request: %v\n", err)
+		http.Error(w, "Error unmarshalling request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, limit := range []*int{req.RequestsPerMinute, req.TokensPerDay, req.ConcurrentStreams} {
+		if limit != nil && *limit < 0 {
+			log.Println("Negative quota limit")
+			http.Error(w, "Quota limits can't be negative", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var userId *string
+	if req.UserEmail != "" {
+		user, err := db.GetUserByEmail(strings.ToLower(strings.TrimSpace(req.UserEmail)))
+		if err != nil {
+			log.Printf("Error getting user: %v\n", err)
+			http.Error(w, "Error getting user: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var isMember bool
+		if user != nil {
+			isMember, err = db.ValidateOrgMembership(user.Id, auth.OrgId)
+			if err != nil {
+				log.Printf("Error validating org membership: %v\n", err)
+				http.Error(w, "Error validating org membership: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if !isMember {
+			log.Printf("User %s isn't in the org\n", req.UserEmail)
+			http.Error(w, fmt.Sprintf("%s isn't a member of the org", req.UserEmail), http.StatusNotFound)
+			return
+		}
+
+		userId = &user.Id
+	}
+
+	quota, err := db.SetQuota(auth.OrgId, userId, &req)
+	if err != nil {
+		log.Printf("Error setting quota: %v\n", err)
+		http.Error(w, "Error setting quota: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Println("Successfully processed SetQuotaHandler request")
+
+	// no content if the quota was removed
+	if quota == nil {
+		return
+	}
+
+	bytes, err := json.Marshal(quota.ToApi())
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// checkRequestQuota counts a request against the org's and the user's requests per minute, and writes a 429 if either is
+// over its limit
+func checkRequestQuota(w http.ResponseWriter, auth *types.ServerAuth) bool {
+	orgQuota, userQuota, err := db.GetQuotasForUser(auth.OrgId, auth.User.Id)
+	if err != nil {
+		log.Printf("Error getting quotas: %v\n", err)
+		http.Error(w, "Error getting quotas: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	windowStart := time.Now().UTC().Truncate(time.Minute)
+
+	for _, quota := range []*db.Quota{orgQuota, userQuota} {
+		if quota == nil || quota.RequestsPerMinute == nil {
+			continue
+		}
+
+		forUser := quota.UserId != nil
+		key := "org:" + auth.OrgId
+		if forUser {
+			key = "user:" + auth.OrgId + ":" + auth.User.Id
+		}
+
+		count, err := db.IncrementRequestCount(key, windowStart)
+		if err != nil {
+			log.Printf("Error counting request: %v\n", err)
+			http.Error(w, "Error counting request: "+err.Error(), http.StatusInternalServerError)
+			return false
+		}
+
+		if count > *quota.RequestsPerMinute {
+			writeRateLimitError(w, shared.QuotaRequestsPerMinute, forUser, *quota.RequestsPerMinute, time.Until(windowStart.Add(time.Minute)))
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkModelQuotas writes a 429 if the org or the user has used up its tokens for the day, or, for requests that start a
+// stream, if it already has as many streams running as it's allowed
+func checkModelQuotas(w http.ResponseWriter, auth *types.ServerAuth, startsStream bool) bool {
+	orgQuota, userQuota, err := db.GetQuotasForUser(auth.OrgId, auth.User.Id)
+	if err != nil {
+		log.Printf("Error getting quotas: %v\n", err)
+		http.Error(w, "Error getting quotas: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	now := time.Now().UTC()
+	dayStart := now.Truncate(24 * time.Hour)
+
+	for _, quota := range []*db.Quota{orgQuota, userQuota} {
+		if quota == nil {
+			continue
+		}
+
+		forUser := quota.UserId != nil
+		var userId string
+		if forUser {
+			userId = auth.User.Id
+		}
+
+		if quota.TokensPerDay != nil {
+			tokens, err := db.GetTokensUsedSince(auth.OrgId, userId, dayStart)
+			if err != nil {
+				log.Printf("Error getting tokens used: %v\n", err)
+				http.Error(w, "Error getting tokens used: "+err.Error(), http.StatusInternalServerError)
+				return false
+			}
+
+			if tokens >= *quota.TokensPerDay {
+				writeRateLimitError(w, shared.QuotaTokensPerDay, forUser, *quota.TokensPerDay, dayStart.Add(24*time.Hour).Sub(now))
+				return false
+			}
+		}
+
+		if startsStream && quota.ConcurrentStreams != nil {
+			count, err := db.CountActiveModelStreams(auth.OrgId, userId)
+			if err != nil {
+				log.Printf("Error counting active streams: %v\n", err)
+				http.Error(w, "Error counting active streams: "+err.Error(), http.StatusInternalServerError)
+				return false
+			}
+
+			if count >= *quota.ConcurrentStreams {
+				writeRateLimitError(w, shared.QuotaConcurrentStreams, forUser, *quota.ConcurrentStreams, concurrentStreamsRetryAfter)
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func writeRateLimitError(w http.ResponseWriter, quota shared.QuotaType, forUser bool, limit int, retryAfter time.Duration) {
+	retryAfterSeconds := int(math.Ceil(retryAfter.Seconds()))
+	if retryAfterSeconds < 1 {
+		retryAfterSeconds = 1
+	}
+
+	whose := "The org's"
+	if forUser {
+		whose = "Your"
+	}
+
+	log.Printf("Rate limited: %s %s quota of %d reached\n", strings.ToLower(whose), quota, limit)
+
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+
+	writeApiError(w, shared.ApiError{
+		Type:   shared.ApiErrorTypeRateLimited,
+		Status: http.StatusTooManyRequests,
+		Msg:    fmt.Sprintf("%s quota of %d %s was reached", whose, limit, shared.QuotaLabels[quota]),
+		RateLimitError: &shared.RateLimitError{
+			Quota:             quota,
+			ForUser:           forUser,
+			Limit:             limit,
+			RetryAfterSeconds: retryAfterSeconds,
+		},
+	})
+}
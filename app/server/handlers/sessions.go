@@ -17,6 +17,10 @@ import (
 func CreateEmailVerificationHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Received request for CreateEmailVerificationHandler")
 
+	if rejectIfSsoRequired(w) {
+		return
+	}
+
 	// read the request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -98,6 +102,10 @@ func CreateEmailVerificationHandler(w http.ResponseWriter, r *http.Request) {
 func SignInHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Received request for SignInHandler")
 
+	if rejectIfSsoRequired(w) {
+		return
+	}
+
 	// read the request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -79,7 +79,7 @@ func UpdateSettingsHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("planId: ", planId, "branch: ", branch)
 
-	plan := authorizePlan(w, planId, auth)
+	plan := authorizePlanPrompt(w, planId, auth)
 
 	if plan == nil {
 		return
@@ -169,7 +169,7 @@ func getUpdateCommitMsg(settings *shared.PlanSettings, originalSettings *shared.
 
 	// log.Println("Changes to settings:", strings.Join(changes, "\n"))
 
-	s := "⚙️  Updated model settings:"
+	s := "⚙️  Updated settings:"
 
 	for _, change := range changes {
 		s += "\n" + "  • " + change
@@ -0,0 +1,571 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"math/big"
+	"net/http"
+	"plandex-server/db"
+	"plandex-server/sso"
+	"strings"
+
+	"github.com/plandex/plandex/shared"
+)
+
+func GetSsoConfigHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for GetSsoConfigHandler")
+
+	res := shared.SsoConfigResponse{
+		Enabled:  sso.Enabled(),
+		Required: sso.Required(),
+	}
+
+	bytes, err := json.Marshal(res)
+
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+func StartSsoHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for StartSsoHandler")
+
+	if !sso.Enabled() {
+		log.Println("SSO isn't configured")
+		http.Error(w, "SSO isn't configured on this server", http.StatusNotFound)
+		return
+	}
+
+	err := db.DeleteExpiredSsoLogins()
+
+	if err != nil {
+		// not worth failing the sign-in over
+		log.Printf("Error deleting expired sso logins: %v\n", err)
+	}
+
+	var values []string
+	// state, nonce, code verifier, poll token
+	for _, n := range []int{32, 32, 64, 32} {
+		b, err := shared.GetRandomAlphanumeric(n)
+		if err != nil {
+			log.Printf("Error generating random string: %v\n", err)
+			http.Error(w, "Error generating random string: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		values = append(values, string(b))
+	}
+	state, nonce, codeVerifier, pollToken := values[0], values[1], values[2], values[3]
+
+	userCode, err := newSsoUserCode()
+	if err != nil {
+		log.Printf("Error generating user code: %v\n", err)
+		http.Error(w, "Error generating user code: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	authUrl, err := sso.AuthUrl(state, nonce, codeVerifier)
+
+	if err != nil {
+		log.Printf("Error getting auth url: %v\n", err)
+		http.Error(w, "Error getting auth url: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	loginId, err := db.CreateSsoLogin(state, nonce, codeVerifier, pollToken, userCode)
+
+	if err != nil {
+		log.Printf("Error creating sso login: %v\n", err)
+		http.Error(w, "Error creating sso login: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := shared.StartSsoResponse{
+		LoginId:   loginId,
+		PollToken: pollToken,
+		AuthUrl:   authUrl,
+		UserCode:  userCode,
+	}
+
+	bytes, err := json.Marshal(res)
+
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Println("Successfully started sso login")
+
+	w.Write(bytes)
+}
+
+// SsoCallbackHandler is where the identity provider redirects the user's browser after they sign in. It records who
+// signed in and asks for the code shown in the terminal, since the browser that signs in might not belong to whoever
+// started the sign-in. ConfirmSsoHandler finishes it.
+func SsoCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for SsoCallbackHandler")
+
+	if !sso.Enabled() {
+		log.Println("SSO isn't configured")
+		writeSsoPage(w, http.StatusNotFound, "SSO isn't configured on this server.")
+		return
+	}
+
+	query := r.URL.Query()
+
+	login, err := db.GetPendingSsoLoginByState(query.Get("state"))
+
+	if err != nil {
+		log.Printf("Error getting sso login: %v\n", err)
+		writeSsoPage(w, http.StatusInternalServerError, "Error getting sign-in: "+err.Error())
+		return
+	}
+
+	if login == nil {
+		log.Println("SSO login not found or expired")
+		writeSsoPage(w, http.StatusBadRequest, "This sign-in expired or was already used. Run 'plandex sign-in' to try again.")
+		return
+	}
+
+	var user *db.User
+	if idpErr := query.Get("error"); idpErr != "" {
+		err = fmt.Errorf("identity provider returned an error: %s %s", idpErr, query.Get("error_description"))
+	} else {
+		var identity *sso.Identity
+		identity, err = sso.Exchange(query.Get("code"), login.CodeVerifier, login.Nonce)
+
+		if err == nil {
+			user, err = provisionSsoUser(identity)
+		}
+	}
+
+	if err != nil {
+		log.Printf("SSO sign-in failed: %v\n", err)
+
+		// the details are only shown in the browser, since the CLI polling for the result might not be this user's
+		errMsg := "sign-in failed in the browser"
+		completeErr := db.CompleteSsoLogin(login.Id, nil, &errMsg)
+
+		if completeErr != nil {
+			log.Printf("Error completing sso login: %v\n", completeErr)
+		}
+
+		writeSsoPage(w, http.StatusForbidden, "Sign-in failed: "+err.Error())
+		return
+	}
+
+	confirmToken, err := shared.GetRandomAlphanumeric(32)
+
+	if err != nil {
+		log.Printf("Error generating confirm token: %v\n", err)
+		writeSsoPage(w, http.StatusInternalServerError, "Error generating confirm token: "+err.Error())
+		return
+	}
+
+	err = db.SetSsoLoginUser(login.Id, user.Id, string(confirmToken))
+
+	if err != nil {
+		log.Printf("Error setting sso login user: %v\n", err)
+		writeSsoPage(w, http.StatusInternalServerError, "Error recording sign-in: "+err.Error())
+		return
+	}
+
+	log.Println("SSO login is waiting for the user code")
+
+	writeSsoConfirmPage(w, login.State, string(confirmToken), user.Email)
+}
+
+// ConfirmSsoHandler finishes an SSO sign-in when the code entered in the browser matches the one shown in the terminal.
+// A wrong code fails the sign-in, so it can't be guessed.
+func ConfirmSsoHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for ConfirmSsoHandler")
+
+	err := r.ParseForm()
+	if err != nil {
+		log.Printf("Error parsing form: %v\n", err)
+		writeSsoPage(w, http.StatusBadRequest, "Error parsing form: "+err.Error())
+		return
+	}
+
+	login, err := db.GetPendingSsoLoginByState(r.PostForm.Get("state"))
+
+	if err != nil {
+		log.Printf("Error getting sso login: %v\n", err)
+		writeSsoPage(w, http.StatusInternalServerError, "Error getting sign-in: "+err.Error())
+		return
+	}
+
+	if login == nil {
+		log.Println("SSO login not found or expired")
+		writeSsoPage(w, http.StatusBadRequest, "This sign-in expired or was already used. Run 'plandex sign-in' to try again.")
+		return
+	}
+
+	confirmTokenHash := db.HashSsoSecret(r.PostForm.Get("confirm_token"))
+	if login.UserId == nil || login.ConfirmTokenHash == nil || subtle.ConstantTimeCompare([]byte(*login.ConfirmTokenHash), []byte(confirmTokenHash)) != 1 {
+		log.Println("SSO login confirmed without a matching confirm token")
+		writeSsoPage(w, http.StatusForbidden, "This sign-in can only be finished in the browser that signed in with the identity provider.")
+		return
+	}
+
+	userCodeHash := db.HashSsoSecret(normalizeSsoUserCode(r.PostForm.Get("user_code")))
+	if subtle.ConstantTimeCompare([]byte(login.UserCodeHash), []byte(userCodeHash)) != 1 {
+		log.Println("SSO login user code doesn't match")
+
+		errMsg := "the code entered in the browser didn't match the one shown in the terminal"
+		err = db.CompleteSsoLogin(login.Id, nil, &errMsg)
+
+		if err != nil {
+			log.Printf("Error completing sso login: %v\n", err)
+		}
+
+		writeSsoPage(w, http.StatusForbidden, "That code doesn't match the one shown in the terminal, so the sign-in was canceled. If you didn't run 'plandex sign-in' yourself, someone may have sent you their sign-in link. Otherwise, run 'plandex sign-in' to try again.")
+		return
+	}
+
+	err = db.CompleteSsoLogin(login.Id, login.UserId, nil)
+
+	if err != nil {
+		log.Printf("Error completing sso login: %v\n", err)
+		writeSsoPage(w, http.StatusInternalServerError, "Error completing sign-in: "+err.Error())
+		return
+	}
+
+	log.Println("Successfully completed sso login")
+
+	writeSsoPage(w, http.StatusOK, "✅ Signed in to Plandex. You can close this tab and go back to your terminal.")
+}
+
+func PollSsoHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for PollSsoHandler")
+
+	var req shared.PollSsoRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Printf("Error unmarshalling request: %v\n", err)
+		http.Error(w, "Error unmarshalling request: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	login, err := db.GetSsoLogin(req.LoginId, req.PollToken)
+
+	if err != nil {
+		log.Printf("Error getting sso login: %v\n", err)
+		http.Error(w, "Error getting sso login: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if login == nil {
+		log.Println("SSO login not found or expired")
+		http.Error(w, "Sign-in not found or expired", http.StatusNotFound)
+		return
+	}
+
+	if login.CompletedAt == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// start a transaction
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v\n", err)
+		http.Error(w, "Error starting transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Ensure that rollback is attempted in case of failure
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("transaction rollback error: %v\n", rbErr)
+			} else {
+				log.Println("transaction rolled back")
+			}
+		}
+	}()
+
+	// deleting first means a sign-in can only be picked up once
+	found, err := db.DeleteSsoLogin(login.Id, tx)
+
+	if err != nil {
+		log.Printf("Error deleting sso login: %v\n", err)
+		http.Error(w, "Error deleting sso login: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !found {
+		log.Println("SSO login was already picked up")
+		http.Error(w, "Sign-in not found or expired", http.StatusNotFound)
+		return
+	}
+
+	if login.Error != nil {
+		err = tx.Commit()
+		if err != nil {
+			log.Printf("Error committing transaction: %v\n", err)
+			http.Error(w, "Error committing transaction: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		http.Error(w, *login.Error, http.StatusForbidden)
+		return
+	}
+
+	user, err := db.GetUser(*login.UserId)
+
+	if err != nil {
+		log.Printf("Error getting user: %v\n", err)
+		http.Error(w, "Error getting user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	token, _, err := db.CreateAuthToken(user.Id, false, tx)
+
+	if err != nil {
+		log.Printf("Error creating auth token: %v\n", err)
+		http.Error(w, "Error creating auth token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// commit transaction
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v\n", err)
+		http.Error(w, "Error committing transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// get orgs
+	orgs, err := db.GetAccessibleOrgsForUser(user)
+
+	if err != nil {
+		log.Printf("Error getting orgs for user: %v\n", err)
+		http.Error(w, "Error getting orgs for user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var apiOrgs []*shared.Org
+	for _, org := range orgs {
+		apiOrgs = append(apiOrgs, org.ToApi())
+	}
+
+	resp := shared.SessionResponse{
+		UserId:   user.Id,
+		Token:    token,
+		Email:    user.Email,
+		UserName: user.Name,
+		Orgs:     apiOrgs,
+	}
+
+	bytes, err := json.Marshal(resp)
+
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Println("Successfully signed in with sso")
+
+	w.Write(bytes)
+}
+
+// provisionSsoUser creates the user the first time they sign in and adds them to the SSO org with the role their groups
+// map to. Roles from group mappings are synced on every sign-in, so changes in the identity provider carry over. The
+// default role is only used when a user first joins, so it doesn't undo role changes made in Plandex.
+func provisionSsoUser(identity *sso.Identity) (*db.User, error) {
+	config := sso.GetConfig()
+
+	emailSplit := strings.Split(identity.Email, "@")
+	if len(emailSplit) != 2 {
+		return nil, fmt.Errorf("invalid email: %s", identity.Email)
+	}
+	domain := emailSplit[1]
+
+	if !config.DomainAllowed(domain) {
+		return nil, fmt.Errorf("%s isn't on a domain that's allowed to sign in", identity.Email)
+	}
+
+	roleName, fromGroup := config.RoleFor(identity.Groups)
+	if roleName == "" {
+		return nil, fmt.Errorf("%s isn't in a group that has access to Plandex", identity.Email)
+	}
+
+	org, err := db.GetOrgByNameOrId(config.Org)
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting org: %v", err)
+	}
+
+	if org == nil {
+		return nil, fmt.Errorf("org '%s' from PLANDEX_OIDC_ORG wasn't found or its name isn't unique", config.Org)
+	}
+
+	roleId, err := db.GetOrgRoleIdByName(org.Id, roleName)
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting role: %v", err)
+	}
+
+	if roleId == "" {
+		return nil, fmt.Errorf("role '%s' from the SSO role mapping doesn't exist", roleName)
+	}
+
+	user, err := db.GetUserByEmail(identity.Email)
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %v", err)
+	}
+
+	var orgUser *db.OrgUser
+	if user != nil {
+		orgUser, err = db.GetOrgUser(user.Id, org.Id)
+
+		if err != nil {
+			return nil, fmt.Errorf("error getting org user: %v", err)
+		}
+	}
+
+	// start a transaction
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+
+	// Ensure that rollback is attempted in case of failure
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("transaction rollback error: %v\n", rbErr)
+			} else {
+				log.Println("transaction rolled back")
+			}
+		}
+	}()
+
+	if user == nil {
+		name := identity.Name
+		if name == "" {
+			name = emailSplit[0]
+		}
+
+		user = &db.User{
+			Name:   name,
+			Email:  identity.Email,
+			Domain: domain,
+		}
+		err = db.CreateUser(user, tx)
+
+		if err != nil {
+			return nil, fmt.Errorf("error creating user: %v", err)
+		}
+
+		log.Printf("Created user %s from SSO sign-in\n", user.Email)
+	}
+
+	if orgUser == nil {
+		err = db.CreateOrgUser(org.Id, user.Id, roleId, tx)
+
+		if err != nil {
+			return nil, fmt.Errorf("error adding org user: %v", err)
+		}
+	} else if fromGroup && orgUser.OrgRoleId != roleId && org.OwnerId != user.Id {
+		// the org's owner keeps their role, so the org can't end up without one
+		err = db.UpdateOrgUserRole(org.Id, user.Id, roleId, tx)
+
+		if err != nil {
+			return nil, fmt.Errorf("error updating org user role: %v", err)
+		}
+
+		log.Printf("Updated role for %s to %s from SSO groups\n", user.Email, roleName)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return user, nil
+}
+
+// rejectIfSsoRequired stops email pin sign-ins and trials when the server only allows SSO
+func rejectIfSsoRequired(w http.ResponseWriter) bool {
+	if !sso.Required() {
+		return false
+	}
+
+	log.Println("SSO is required")
+	http.Error(w, "This server requires signing in with SSO", http.StatusForbidden)
+	return true
+}
+
+// consonants only, so codes are easy to read and type and don't spell words (RFC 8628)
+const ssoUserCodeChars = "BCDFGHJKLMNPQRSTVWXZ"
+
+// newSsoUserCode returns a code like BCDF-GHJK
+func newSsoUserCode() (string, error) {
+	var code []byte
+	for i := 0; i < 8; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(ssoUserCodeChars))))
+		if err != nil {
+			return "", err
+		}
+		if i == 4 {
+			code = append(code, '-')
+		}
+		code = append(code, ssoUserCodeChars[n.Int64()])
+	}
+	return string(code), nil
+}
+
+// normalizeSsoUserCode allows the code to be entered in lowercase and without the dash
+func normalizeSsoUserCode(code string) string {
+	code = strings.ToUpper(strings.Join(strings.Fields(code), ""))
+	code = strings.ReplaceAll(code, "-", "")
+	if len(code) == 8 {
+		code = code[:4] + "-" + code[4:]
+	}
+	return code
+}
+
+func writeSsoConfirmPage(w http.ResponseWriter, state, confirmToken, email string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Plandex</title></head>
+<body style="font-family: sans-serif; max-width: 40em; margin: 4em auto;">
+<p>You signed in as %s. To finish signing in to Plandex, enter the code shown in your terminal.</p>
+<p>If you didn't just run 'plandex sign-in', close this tab. Someone may have sent you their sign-in link.</p>
+<form method="post" action="confirm">
+<input type="hidden" name="state" value="%s">
+<input type="hidden" name="confirm_token" value="%s">
+<input type="text" name="user_code" placeholder="XXXX-XXXX" autocomplete="off" autofocus required>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`, html.EscapeString(email), html.EscapeString(state), html.EscapeString(confirmToken))
+}
+
+func writeSsoPage(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Plandex</title></head>
+<body style="font-family: sans-serif; max-width: 40em; margin: 4em auto;">
+<p>%s</p>
+</body>
+</html>
+`, html.EscapeString(msg))
+}
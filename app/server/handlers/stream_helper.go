@@ -13,7 +13,9 @@ import (
 	"github.com/plandex/plandex/shared"
 )
 
-func startResponseStream(w http.ResponseWriter, auth *types.ServerAuth, planId, branch string, isConnect bool) {
+// startResponseStream streams the active plan to the client. With resumeFrom set, the client is reconnecting after its
+// connection dropped, so it's sent the messages after that one instead of the plan's current state.
+func startResponseStream(w http.ResponseWriter, auth *types.ServerAuth, planId, branch string, isConnect bool, resumeFrom int) {
 	log.Println("Response stream manager: starting plan stream")
 
 	active := modelPlan.GetActivePlan(planId, branch)
@@ -46,6 +48,16 @@ func startResponseStream(w http.ResponseWriter, auth *types.ServerAuth, planId,
 		return
 	}
 
+	if resumeFrom > 0 {
+		subscriptionId, ch := modelPlan.ResumePlanSubscription(planId, branch, resumeFrom)
+		defer func() {
+			log.Println("Response stream manager: client stream closed")
+			modelPlan.UnsubscribePlan(planId, branch, subscriptionId)
+		}()
+		streamSubscription(w, active, ch)
+		return
+	}
+
 	if isConnect {
 		time.Sleep(100 * time.Millisecond)
 		err = initConnectActive(auth, planId, branch, w)
@@ -68,6 +80,10 @@ func startResponseStream(w http.ResponseWriter, auth *types.ServerAuth, planId,
 		time.Sleep(100 * time.Millisecond)
 	}
 
+	streamSubscription(w, active, ch)
+}
+
+func streamSubscription(w http.ResponseWriter, active *types.ActivePlan, ch chan string) {
 	for {
 		select {
 		case <-active.Ctx.Done():
@@ -75,13 +91,12 @@ func startResponseStream(w http.ResponseWriter, auth *types.ServerAuth, planId,
 			return
 		case msg := <-ch:
 			// log.Println("Response stream manager: sending message:", msg)
-			err = sendStreamMessage(w, msg)
+			err := sendStreamMessage(w, msg)
 			if err != nil {
 				return
 			}
 		}
 	}
-
 }
 
 func sendStreamMessage(w http.ResponseWriter, msg string) error {
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"plandex-server/db"
+	"plandex-server/types"
+	"time"
+)
+
+func GetUsageHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for GetUsageHandler")
+
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	planId := r.URL.Query().Get("planId")
+	sinceStr := r.URL.Query().Get("since")
+
+	log.Println("planId: ", planId, "since: ", sinceStr)
+
+	if planId != "" {
+		plan := authorizePlan(w, planId, auth)
+		if plan == nil {
+			return
+		}
+	}
+
+	var since time.Time
+	if sinceStr != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			log.Printf("Error parsing since: %v\n", err)
+			http.Error(w, "Error parsing since: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	res, err := db.GetUsage(auth.OrgId, auth.User.Id, planId, since)
+	if err != nil {
+		log.Printf("Error getting usage: %v\n", err)
+		http.Error(w, "Error getting usage: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(res)
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Println("Successfully processed GetUsageHandler request")
+
+	w.Write(bytes)
+}
+
+// GetOrgUsageHandler reports everyone's API calls and model usage in the org by day and user, as JSON or, with
+// format=csv, as a CSV file. It defaults to the current UTC month.
+func GetOrgUsageHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for GetOrgUsageHandler")
+
+	auth := authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	if !auth.HasPermission(types.PermissionViewOrgUsage) {
+		log.Println("User doesn't have permission to view org usage")
+		http.Error(w, "User doesn't have permission to view org usage", http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+
+	log.Println("since: ", query.Get("since"), "until: ", query.Get("until"), "format: ", format)
+
+	if format != "" && format != "json" && format != "csv" {
+		log.Printf("Invalid format: %s\n", format)
+		http.Error(w, "Invalid format: "+format+". Use json or csv.", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	since := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+
+	for name, t := range map[string]*time.Time{"since": &since, "until": &until} {
+		str := query.Get(name)
+		if str == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, str)
+		if err != nil {
+			log.Printf("Error parsing %s: %v\n", name, err)
+			http.Error(w, fmt.Sprintf("Error parsing %s: %v", name, err), http.StatusBadRequest)
+			return
+		}
+		*t = parsed
+	}
+
+	res, err := db.GetOrgUsage(auth.OrgId, since, until)
+	if err != nil {
+		log.Printf("Error getting org usage: %v\n", err)
+		http.Error(w, "Error getting org usage: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"plandex-usage-%s-%s.csv\"", res.Since, res.Until))
+
+		err = res.WriteCsv(w)
+		if err != nil {
+			log.Printf("Error writing csv: %v\n", err)
+			return
+		}
+
+		log.Println("Successfully processed GetOrgUsageHandler request")
+		return
+	}
+
+	bytes, err := json.Marshal(res)
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Println("Successfully processed GetOrgUsageHandler request")
+
+	w.Write(bytes)
+}
+
+// meterApiCall counts an authenticated request for usage exports. Metering shouldn't fail the request, so errors are only
+// logged.
+func meterApiCall(auth *types.ServerAuth) {
+	err := db.CountApiCall(auth.OrgId, auth.User.Id)
+	if err != nil {
+		log.Printf("Error metering api call: %v\n", err)
+	}
+}
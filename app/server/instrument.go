@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"plandex-server/logging"
+	"plandex-server/metrics"
+	"plandex-server/tracing"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/plandex/plandex/shared"
+)
+
+// error responses are logged with up to this much of their body, which is the error message
+const maxLoggedErrorLength = 500
+
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// instrumentRequests gives each request an id, logs it once it's done, records its latency and status in the metrics, and
+// traces it, continuing the caller's trace if it sent a traceparent header. Routes are labeled by their path template
+// without the version prefix, so a route's requests are counted together whatever the ids in the path.
+func instrumentRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = strings.TrimPrefix(template, apiV1Prefix)
+			}
+		}
+
+		requestId := r.Header.Get(shared.RequestIdHeader)
+		if !requestIdPattern.MatchString(requestId) {
+			requestId = newRequestId()
+		}
+		w.Header().Set(shared.RequestIdHeader, requestId)
+
+		ctx, info := logging.WithRequest(r.Context(), requestId)
+		ctx, span := tracing.StartServer(r.WithContext(ctx), r.Method+" "+route,
+			tracing.String("http.method", r.Method),
+			tracing.String("http.route", route),
+			tracing.String("request_id", requestId),
+		)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		metrics.HttpRequestsInFlight.Inc()
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		metrics.HttpRequestsInFlight.Dec()
+
+		duration := time.Since(start)
+
+		metrics.HttpRequests.Inc(route, r.Method, strconv.Itoa(rec.status))
+		metrics.HttpRequestDuration.Observe(duration.Seconds(), route, r.Method)
+
+		span.SetAttributes(tracing.Int("http.status_code", rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetError(fmt.Errorf("%d %s", rec.status, http.StatusText(rec.status)))
+		}
+		span.End()
+
+		logRequest(r, route, info, rec, duration)
+	})
+}
+
+func logRequest(r *http.Request, route string, info *logging.RequestInfo, rec *statusRecorder, duration time.Duration) {
+	level := slog.LevelInfo
+	switch {
+	case rec.status >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case rec.status >= http.StatusBadRequest:
+		level = slog.LevelWarn
+	case route == "/health" || route == "/metrics":
+		// probes and scrapes would drown out everything else
+		level = slog.LevelDebug
+	}
+
+	attrs := append(info.Attrs(),
+		slog.String("method", r.Method),
+		slog.String("route", route),
+		slog.Int("status", rec.status),
+		slog.Int64("duration_ms", duration.Milliseconds()),
+	)
+
+	vars := mux.Vars(r)
+	if planId := vars["planId"]; planId != "" {
+		attrs = append(attrs, slog.String("plan_id", planId))
+	}
+	if branch := vars["branch"]; branch != "" {
+		attrs = append(attrs, slog.String("branch", branch))
+	}
+	if rec.status >= http.StatusBadRequest && rec.errorBody.Len() > 0 {
+		attrs = append(attrs, slog.String("error", strings.TrimSpace(rec.errorBody.String())))
+	}
+
+	slog.LogAttrs(context.Background(), level, "request", attrs...)
+}
+
+func newRequestId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder keeps the response's status code, and the start of the body for errors. It passes through flushes so
+// streamed replies still stream.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	errorBody   bytes.Buffer
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	if rec.status >= http.StatusBadRequest && rec.errorBody.Len() < maxLoggedErrorLength {
+		rec.errorBody.Write(b[:min(len(b), maxLoggedErrorLength-rec.errorBody.Len())])
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *statusRecorder) Flush() {
+	rec.wroteHeader = true
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
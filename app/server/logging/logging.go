@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Init makes a leveled, structured slog logger the server's default. Output from the log package goes through it too, at
+// the info level. PLANDEX_LOG_FORMAT is text (the default) or json, and PLANDEX_LOG_LEVEL is debug, info (the default),
+// warn, or error.
+func Init() error {
+	var level slog.Level
+	levelStr := os.Getenv("PLANDEX_LOG_LEVEL")
+	if levelStr != "" {
+		err := level.UnmarshalText([]byte(levelStr))
+		if err != nil {
+			return fmt.Errorf("invalid PLANDEX_LOG_LEVEL %s: use debug, info, warn, or error", levelStr)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(os.Getenv("PLANDEX_LOG_FORMAT")) {
+	case "", "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("invalid PLANDEX_LOG_FORMAT %s: use text or json", os.Getenv("PLANDEX_LOG_FORMAT"))
+	}
+
+	slog.SetDefault(slog.New(handler))
+
+	return nil
+}
+
+type requestInfoKey struct{}
+
+// RequestInfo identifies a request in the logs. The user and org are filled in once the request is authenticated.
+type RequestInfo struct {
+	RequestId string
+
+	mu     sync.Mutex
+	userId string
+	orgId  string
+}
+
+// WithRequest adds a request's info to its context
+func WithRequest(ctx context.Context, requestId string) (context.Context, *RequestInfo) {
+	info := &RequestInfo{RequestId: requestId}
+	return context.WithValue(ctx, requestInfoKey{}, info), info
+}
+
+// SetUser records who made the request
+func SetUser(ctx context.Context, userId, orgId string) {
+	info, ok := ctx.Value(requestInfoKey{}).(*RequestInfo)
+	if !ok {
+		return
+	}
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	info.userId = userId
+	info.orgId = orgId
+}
+
+// Attrs are the request's ids, for logging
+func (info *RequestInfo) Attrs() []slog.Attr {
+	info.mu.Lock()
+	defer info.mu.Unlock()
+
+	attrs := []slog.Attr{slog.String("request_id", info.RequestId)}
+	if info.userId != "" {
+		attrs = append(attrs, slog.String("user_id", info.userId))
+	}
+	if info.orgId != "" {
+		attrs = append(attrs, slog.String("org_id", info.orgId))
+	}
+	return attrs
+}
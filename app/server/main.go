@@ -6,9 +6,15 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"plandex-server/blobs"
 	"plandex-server/db"
+	"plandex-server/encryption"
 	"plandex-server/host"
+	"plandex-server/logging"
+	"plandex-server/metrics"
 	"plandex-server/model/plan"
+	"plandex-server/sso"
+	"plandex-server/tracing"
 	"syscall"
 	"time"
 
@@ -16,7 +22,12 @@ import (
 )
 
 func main() {
-	err := host.LoadIp()
+	err := logging.Init()
+	if err != nil {
+		log.Fatal("Error initializing logging: ", err)
+	}
+
+	err = host.LoadIp()
 	if err != nil {
 		log.Fatal("Error loading IP: ", err)
 	}
@@ -31,6 +42,30 @@ func main() {
 		log.Fatal("Error running migrations: ", err)
 	}
 
+	err = encryption.Init()
+	if err != nil {
+		log.Fatal("Error initializing encryption: ", err)
+	}
+
+	err = blobs.Init()
+	if err != nil {
+		log.Fatal("Error initializing blob store: ", err)
+	}
+
+	err = sso.LoadConfig()
+	if err != nil {
+		log.Fatal("Error loading SSO config: ", err)
+	}
+
+	err = tracing.Init()
+	if err != nil {
+		log.Fatal("Error initializing tracing: ", err)
+	}
+
+	metrics.NewGaugeFunc("plandex_active_plans", "Plans with a reply or build in progress on this host.", func() float64 {
+		return float64(plan.NumActivePlans())
+	})
+
 	if os.Getenv("GOENV") == "development" {
 		log.Println("In development mode.")
 	}
@@ -59,6 +94,7 @@ func main() {
 			time.Sleep(1 * time.Second)
 		}
 
+		tracing.Flush()
 		os.Exit(0)
 	}()
 
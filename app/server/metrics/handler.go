@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Handler serves the metrics for Prometheus to scrape. If PLANDEX_METRICS_TOKEN is set, scrapes need it as a bearer token.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	if token := os.Getenv("PLANDEX_METRICS_TOKEN"); token != "" {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			http.Error(w, "invalid metrics token", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	err := WriteText(w)
+	if err != nil {
+		log.Printf("Error writing metrics: %v\n", err)
+	}
+}
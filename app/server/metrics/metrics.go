@@ -0,0 +1,24 @@
+package metrics
+
+// Metrics are exposed at /metrics in the Prometheus text format. Token throughput and error rates come from rate() over the
+// counters, e.g. rate(plandex_model_tokens_total[5m]) or the share of plandex_http_requests_total with a 5xx status.
+
+// in seconds; model calls and builds run much longer than most API requests
+var requestBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+var modelBuckets = []float64{0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300}
+var buildBuckets = []float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300, 600}
+
+var (
+	HttpRequests         = NewCounter("plandex_http_requests_total", "API requests by route, method, and status code.", "route", "method", "status")
+	HttpRequestDuration  = NewHistogram("plandex_http_request_duration_seconds", "API request latency. Streaming requests are timed until the stream ends.", requestBuckets, "route", "method")
+	HttpRequestsInFlight = NewGauge("plandex_http_requests_in_flight", "API requests being handled, including open streams.")
+
+	ModelRequests        = NewCounter("plandex_model_requests_total", "Model API calls by model, provider, and result (ok or error). Retries are counted once per model.", "model", "provider", "result")
+	ModelRequestDuration = NewHistogram("plandex_model_request_duration_seconds", "Model API call latency, including retries. Streamed calls are timed until the stream starts.", modelBuckets, "model", "provider")
+	ModelTokens          = NewCounter("plandex_model_tokens_total", "Model tokens by role, model, and type: input (not read from a prompt cache), cached_input, or output.", "role", "model", "type")
+
+	Builds        = NewCounter("plandex_builds_total", "File builds by result (ok, error, or stopped).", "result")
+	BuildDuration = NewHistogram("plandex_build_duration_seconds", "How long file builds take once they have a build slot, including retries.", buildBuckets)
+	BuildsQueued  = NewGauge("plandex_builds_queued", "File builds waiting for one of their plan's parallel build slots.")
+	BuildsRunning = NewGauge("plandex_builds_running", "File builds in progress.")
+)
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"strings"
+	"testing"
+)
+
+func writeCollector(c collector) string {
+	var sb strings.Builder
+	w := bufio.NewWriter(&sb)
+	c.write(w)
+	w.Flush()
+	return sb.String()
+}
+
+func TestCounterText(t *testing.T) {
+	c := &Counter{name: "plandex_http_requests_total", help: "API requests.\nBy route and status, with \\ escaped", labels: []string{"route", "status"}, series: map[string]*counterSeries{}}
+
+	c.Inc("/plans/{planId}/tell", "200")
+	c.Add(2, "/plans/{planId}/tell", "200")
+	c.Inc(`/a "quoted" \ route`+"\n", "500")
+	// counters only go up
+	c.Add(-1, "/plans/{planId}/tell", "200")
+
+	want := `# HELP plandex_http_requests_total API requests.\nBy route and status, with \\ escaped
+# TYPE plandex_http_requests_total counter
+plandex_http_requests_total{route="/a \"quoted\" \\ route\n",status="500"} 1
+plandex_http_requests_total{route="/plans/{planId}/tell",status="200"} 3
+`
+
+	if got := writeCollector(c); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGaugeText(t *testing.T) {
+	g := &Gauge{name: "plandex_build_queue_depth", help: "Builds waiting for a slot"}
+	g.Inc()
+	g.Inc()
+	g.Dec()
+
+	gf := &GaugeFunc{name: "plandex_active_plans", help: "Plans with an active stream", fn: func() float64 { return 0.5 }}
+
+	want := `# HELP plandex_build_queue_depth Builds waiting for a slot
+# TYPE plandex_build_queue_depth gauge
+plandex_build_queue_depth 1
+# HELP plandex_active_plans Plans with an active stream
+# TYPE plandex_active_plans gauge
+plandex_active_plans 0.5
+`
+
+	if got := writeCollector(g) + writeCollector(gf); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHistogramText(t *testing.T) {
+	h := &Histogram{name: "plandex_model_call_duration_seconds", help: "Model call latency", labels: []string{"model"}, buckets: []float64{0.5, 1, 2.5}, series: map[string]*histogramSeries{}}
+
+	h.Observe(0.25, "gpt-4o")
+	h.Observe(1, "gpt-4o")
+	h.Observe(10, "gpt-4o")
+	h.Observe(2, "claude-3-5-sonnet")
+
+	// buckets are cumulative and le is inclusive, and +Inf always equals the count
+	want := `# HELP plandex_model_call_duration_seconds Model call latency
+# TYPE plandex_model_call_duration_seconds histogram
+plandex_model_call_duration_seconds_bucket{model="claude-3-5-sonnet",le="0.5"} 0
+plandex_model_call_duration_seconds_bucket{model="claude-3-5-sonnet",le="1"} 0
+plandex_model_call_duration_seconds_bucket{model="claude-3-5-sonnet",le="2.5"} 1
+plandex_model_call_duration_seconds_bucket{model="claude-3-5-sonnet",le="+Inf"} 1
+plandex_model_call_duration_seconds_sum{model="claude-3-5-sonnet"} 2
+plandex_model_call_duration_seconds_count{model="claude-3-5-sonnet"} 1
+plandex_model_call_duration_seconds_bucket{model="gpt-4o",le="0.5"} 1
+plandex_model_call_duration_seconds_bucket{model="gpt-4o",le="1"} 2
+plandex_model_call_duration_seconds_bucket{model="gpt-4o",le="2.5"} 2
+plandex_model_call_duration_seconds_bucket{model="gpt-4o",le="+Inf"} 3
+plandex_model_call_duration_seconds_sum{model="gpt-4o"} 11.25
+plandex_model_call_duration_seconds_count{model="gpt-4o"} 3
+`
+
+	if got := writeCollector(h); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := map[float64]string{
+		0:            "0",
+		3:            "3",
+		0.005:        "0.005",
+		1e21:         "1e+21",
+		math.Inf(1):  "+Inf",
+		math.Inf(-1): "-Inf",
+	}
+	for v, want := range tests {
+		if got := formatFloat(v); got != want {
+			t.Errorf("formatFloat(%v) = %s, want %s", v, got, want)
+		}
+	}
+	if got := formatFloat(math.NaN()); got != "NaN" {
+		t.Errorf("formatFloat(NaN) = %s, want NaN", got)
+	}
+}
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS model_usage (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  org_id UUID NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- usage is kept after a plan is deleted so spend history stays accurate
+  plan_id UUID REFERENCES plans(id) ON DELETE SET NULL,
+  plan_name VARCHAR(255) NOT NULL,
+  branch VARCHAR(255) NOT NULL,
+  model_role VARCHAR(255) NOT NULL,
+  model_name VARCHAR(255) NOT NULL,
+  input_tokens INTEGER NOT NULL,
+  output_tokens INTEGER NOT NULL,
+  cost_usd DOUBLE PRECISION NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX model_usage_user_idx ON model_usage(org_id, user_id, created_at);
+CREATE INDEX model_usage_plan_idx ON model_usage(plan_id, created_at);
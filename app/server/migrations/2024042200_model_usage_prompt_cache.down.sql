@@ -0,0 +1,2 @@
+ALTER TABLE model_usage DROP COLUMN IF EXISTS cache_savings_usd;
+ALTER TABLE model_usage DROP COLUMN IF EXISTS cached_input_tokens;
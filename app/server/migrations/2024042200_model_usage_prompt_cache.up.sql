@@ -0,0 +1,2 @@
+ALTER TABLE model_usage ADD COLUMN cached_input_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE model_usage ADD COLUMN cache_savings_usd DOUBLE PRECISION NOT NULL DEFAULT 0;
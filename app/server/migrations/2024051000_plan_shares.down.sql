@@ -0,0 +1 @@
+DROP TABLE IF EXISTS plan_shares;
\ No newline at end of file
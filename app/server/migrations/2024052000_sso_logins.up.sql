@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS sso_logins (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  state VARCHAR(64) NOT NULL UNIQUE,
+  nonce VARCHAR(64) NOT NULL,
+  code_verifier VARCHAR(128) NOT NULL,
+  poll_token_hash VARCHAR(64) NOT NULL,
+  -- set when the identity provider redirects back, to the user who signed in or to why they couldn't
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  error TEXT,
+  completed_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS org_data_keys;
\ No newline at end of file
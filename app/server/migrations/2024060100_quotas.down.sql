@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE name = 'manage_quotas';
+
+DROP INDEX IF EXISTS model_streams_active_org_idx;
+ALTER TABLE model_streams DROP COLUMN IF EXISTS user_id;
+
+DROP TABLE IF EXISTS rate_limit_windows;
+DROP TABLE IF EXISTS quotas;
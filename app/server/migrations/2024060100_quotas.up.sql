@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS quotas (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  org_id UUID NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+  -- NULL for the org's quota, which limits everyone in the org together
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  -- NULL limits are unlimited
+  requests_per_minute INTEGER,
+  tokens_per_day INTEGER,
+  concurrent_streams INTEGER,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE TRIGGER update_quotas_modtime BEFORE UPDATE ON quotas FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE UNIQUE INDEX quotas_org_idx ON quotas(org_id) WHERE user_id IS NULL;
+CREATE UNIQUE INDEX quotas_org_user_idx ON quotas(org_id, user_id) WHERE user_id IS NOT NULL;
+
+-- request counts per minute, shared by all server hosts
+CREATE TABLE IF NOT EXISTS rate_limit_windows (
+  -- 'org:<org id>' or 'user:<org id>:<user id>'
+  key VARCHAR(255) NOT NULL,
+  window_start TIMESTAMP NOT NULL,
+  count INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (key, window_start)
+);
+
+ALTER TABLE model_streams ADD COLUMN user_id UUID REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX model_streams_active_org_idx ON model_streams(org_id) WHERE finished_at IS NULL;
+
+INSERT INTO permissions (name, description) VALUES ('manage_quotas', 'Set org and user quotas');
+
+INSERT INTO org_roles_permissions (org_role_id, permission_id)
+SELECT r.id, p.id
+FROM org_roles r, permissions p
+WHERE r.org_id IS NULL AND r.name IN ('owner', 'admin') AND p.name = 'manage_quotas';
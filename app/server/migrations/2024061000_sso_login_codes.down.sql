@@ -0,0 +1,2 @@
+ALTER TABLE sso_logins DROP COLUMN IF EXISTS confirm_token_hash;
+ALTER TABLE sso_logins DROP COLUMN IF EXISTS user_code_hash;
@@ -0,0 +1,3 @@
+UPDATE plans SET shared_with_org_at = ps.created_at
+FROM plan_shares ps
+WHERE ps.plan_id = plans.id AND ps.user_id IS NULL AND ps.permission = 'apply';
@@ -0,0 +1,9 @@
+-- plans shared with the org before per-plan permissions could be used fully by everyone in the org, so they become
+-- org-wide shares with the apply permission, which can then be changed or removed with share and unshare
+INSERT INTO plan_shares (org_id, plan_id, user_id, permission, created_by, created_at)
+SELECT p.org_id, p.id, NULL, 'apply', p.owner_id, p.shared_with_org_at
+FROM plans p
+WHERE p.shared_with_org_at IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM plan_shares ps WHERE ps.plan_id = p.id AND ps.user_id IS NULL);
+
+UPDATE plans SET shared_with_org_at = NULL WHERE shared_with_org_at IS NOT NULL;
@@ -0,0 +1,515 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/bedrockruntime"
+	"github.com/plandex/plandex/shared"
+	"github.com/sashabaranov/go-openai"
+)
+
+const bedrockAnthropicVersion = "bedrock-2023-05-31"
+
+// Claude requires max_tokens, so this is used when the role doesn't set one
+const bedrockDefaultMaxTokens = 4096
+
+// bedrockTransport lets the openai client call Anthropic models on Bedrock. Chat completion requests are translated to
+// Bedrock's InvokeModel api, which signs them with the server's AWS credentials, and the responses (streamed or not) are
+// translated back to OpenAI's format, so the rest of the server doesn't need to know which provider it's calling.
+type bedrockTransport struct {
+	runtime *bedrockruntime.BedrockRuntime
+}
+
+type bedrockClaudeRequest struct {
+	AnthropicVersion string                   `json:"anthropic_version"`
+	MaxTokens        int                      `json:"max_tokens"`
+	System           []*bedrockClaudeContent  `json:"system,omitempty"`
+	Messages         []*bedrockClaudeMessage  `json:"messages"`
+	Temperature      float32                  `json:"temperature,omitempty"`
+	TopP             float32                  `json:"top_p,omitempty"`
+	Tools            []bedrockClaudeTool      `json:"tools,omitempty"`
+	ToolChoice       *bedrockClaudeToolChoice `json:"tool_choice,omitempty"`
+}
+
+type bedrockClaudeMessage struct {
+	Role    string                  `json:"role"`
+	Content []*bedrockClaudeContent `json:"content"`
+}
+
+type bedrockClaudeContent struct {
+	Type   string                    `json:"type"`
+	Text   string                    `json:"text,omitempty"`
+	Source *bedrockClaudeImageSource `json:"source,omitempty"`
+	Id     string                    `json:"id,omitempty"`
+	Name   string                    `json:"name,omitempty"`
+	Input  json.RawMessage           `json:"input,omitempty"`
+	// marks the end of a prompt prefix for Anthropic to cache
+	CacheControl *bedrockClaudeCacheControl `json:"cache_control,omitempty"`
+}
+
+type bedrockClaudeCacheControl struct {
+	Type string `json:"type"`
+}
+
+type bedrockClaudeImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type bedrockClaudeTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+type bedrockClaudeToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type bedrockClaudeUsage struct {
+	// input tokens after the last cache breakpoint; tokens before it are counted as written to or read from the cache
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+type bedrockClaudeResponse struct {
+	Id         string                  `json:"id"`
+	Content    []*bedrockClaudeContent `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      bedrockClaudeUsage      `json:"usage"`
+}
+
+type bedrockClaudeStreamEvent struct {
+	Type         string                 `json:"type"`
+	Index        int                    `json:"index"`
+	Message      *bedrockClaudeResponse `json:"message,omitempty"`
+	ContentBlock *bedrockClaudeContent  `json:"content_block,omitempty"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJson string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+}
+
+func (t *bedrockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return bedrockErrorResponse(http.StatusNotFound, fmt.Sprintf("%s isn't supported for Bedrock models", req.URL.Path)), nil
+	}
+
+	reqBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading request body: %v", err)
+	}
+
+	var chatReq openai.ChatCompletionRequest
+	err = json.Unmarshal(reqBytes, &chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling chat completion request: %v", err)
+	}
+
+	claudeReq, err := toBedrockClaudeRequest(chatReq)
+	if err != nil {
+		return bedrockErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	body, err := json.Marshal(claudeReq)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling bedrock request: %v", err)
+	}
+
+	if chatReq.Stream {
+		out, err := t.runtime.InvokeModelWithResponseStreamWithContext(req.Context(), &bedrockruntime.InvokeModelWithResponseStreamInput{
+			ModelId:     aws.String(chatReq.Model),
+			ContentType: aws.String("application/json"),
+			Accept:      aws.String("application/json"),
+			Body:        body,
+		})
+		if err != nil {
+			return bedrockAwsErrorResponse(err), nil
+		}
+
+		pr, pw := io.Pipe()
+		go streamBedrockClaudeEvents(out.GetStream(), chatReq.Model, pw)
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+			Body:       pr,
+			Request:    req,
+		}, nil
+	}
+
+	out, err := t.runtime.InvokeModelWithContext(req.Context(), &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(chatReq.Model),
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+		Body:        body,
+	})
+	if err != nil {
+		return bedrockAwsErrorResponse(err), nil
+	}
+
+	var claudeRes bedrockClaudeResponse
+	err = json.Unmarshal(out.Body, &claudeRes)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling bedrock response: %v", err)
+	}
+
+	message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+	for _, block := range claudeRes.Content {
+		switch block.Type {
+		case "text":
+			message.Content += block.Text
+		case "tool_use":
+			message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+				ID:   block.Id,
+				Type: openai.ToolTypeFunction,
+				Function: openai.FunctionCall{
+					Name:      block.Name,
+					Arguments: string(block.Input),
+				},
+			})
+		}
+	}
+
+	usage := claudeRes.Usage
+	promptTokens := usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens
+
+	chatRes := openai.ChatCompletionResponse{
+		ID:      claudeRes.Id,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   chatReq.Model,
+		Choices: []openai.ChatCompletionChoice{
+			{
+				Message:      message,
+				FinishReason: bedrockFinishReason(claudeRes.StopReason),
+			},
+		},
+		Usage: openai.Usage{
+			PromptTokens:     promptTokens,
+			CompletionTokens: usage.OutputTokens,
+			TotalTokens:      promptTokens + usage.OutputTokens,
+		},
+	}
+
+	resBytes, err := json.Marshal(chatRes)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling chat completion response: %v", err)
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type":          []string{"application/json"},
+			cachedInputTokensHeader: []string{strconv.Itoa(usage.CacheReadInputTokens)},
+		},
+		Body:    io.NopCloser(bytes.NewReader(resBytes)),
+		Request: req,
+	}, nil
+}
+
+// streamBedrockClaudeEvents writes Claude's stream events to w as OpenAI server-sent events. Events that carry no content
+// aren't written, since the build stream treats a chunk without a tool call as an error.
+func streamBedrockClaudeEvents(stream *bedrockruntime.InvokeModelWithResponseStreamEventStream, model string, w *io.PipeWriter) {
+	defer stream.Close()
+
+	var id string
+	numToolCalls := 0
+	created := time.Now().Unix()
+
+	write := func(choice openai.ChatCompletionStreamChoice) error {
+		chunk := openai.ChatCompletionStreamResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: []openai.ChatCompletionStreamChoice{choice},
+		}
+		chunkBytes, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "data: %s\n\n", chunkBytes)
+		return err
+	}
+
+	for event := range stream.Events() {
+		part, ok := event.(*bedrockruntime.PayloadPart)
+		if !ok {
+			continue
+		}
+
+		var claudeEvent bedrockClaudeStreamEvent
+		err := json.Unmarshal(part.Bytes, &claudeEvent)
+		if err != nil {
+			log.Printf("Error unmarshalling bedrock stream event: %v\n", err)
+			w.CloseWithError(fmt.Errorf("error unmarshalling bedrock stream event: %v", err))
+			return
+		}
+
+		var choice *openai.ChatCompletionStreamChoice
+
+		switch claudeEvent.Type {
+		case "message_start":
+			if claudeEvent.Message != nil {
+				id = claudeEvent.Message.Id
+			}
+
+		case "content_block_start":
+			if claudeEvent.ContentBlock != nil && claudeEvent.ContentBlock.Type == "tool_use" {
+				index := numToolCalls
+				numToolCalls++
+				choice = &openai.ChatCompletionStreamChoice{
+					Delta: openai.ChatCompletionStreamChoiceDelta{
+						Role: openai.ChatMessageRoleAssistant,
+						ToolCalls: []openai.ToolCall{{
+							Index:    &index,
+							ID:       claudeEvent.ContentBlock.Id,
+							Type:     openai.ToolTypeFunction,
+							Function: openai.FunctionCall{Name: claudeEvent.ContentBlock.Name},
+						}},
+					},
+				}
+			}
+
+		case "content_block_delta":
+			switch claudeEvent.Delta.Type {
+			case "text_delta":
+				choice = &openai.ChatCompletionStreamChoice{
+					Delta: openai.ChatCompletionStreamChoiceDelta{Content: claudeEvent.Delta.Text},
+				}
+			case "input_json_delta":
+				index := numToolCalls - 1
+				choice = &openai.ChatCompletionStreamChoice{
+					Delta: openai.ChatCompletionStreamChoiceDelta{
+						ToolCalls: []openai.ToolCall{{
+							Index:    &index,
+							Function: openai.FunctionCall{Arguments: claudeEvent.Delta.PartialJson},
+						}},
+					},
+				}
+			}
+
+		case "message_delta":
+			if claudeEvent.Delta.StopReason != "" {
+				choice = &openai.ChatCompletionStreamChoice{
+					FinishReason: bedrockFinishReason(claudeEvent.Delta.StopReason),
+				}
+			}
+		}
+
+		if choice == nil {
+			continue
+		}
+
+		err = write(*choice)
+		if err != nil {
+			// the reader went away, e.g. because the stream was canceled
+			return
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		log.Printf("Bedrock stream error: %v\n", err)
+		w.CloseWithError(fmt.Errorf("bedrock stream error: %v", err))
+		return
+	}
+
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	w.Close()
+}
+
+func toBedrockClaudeRequest(chatReq openai.ChatCompletionRequest) (*bedrockClaudeRequest, error) {
+	claudeReq := &bedrockClaudeRequest{
+		AnthropicVersion: bedrockAnthropicVersion,
+		MaxTokens:        chatReq.MaxTokens,
+		Temperature:      chatReq.Temperature,
+		TopP:             chatReq.TopP,
+	}
+
+	if claudeReq.MaxTokens == 0 {
+		claudeReq.MaxTokens = bedrockDefaultMaxTokens
+	}
+
+	var systemParts []string
+
+	for _, msg := range chatReq.Messages {
+		if msg.Role == openai.ChatMessageRoleSystem {
+			systemParts = append(systemParts, msg.Content)
+			continue
+		}
+
+		// Claude only has user and assistant roles
+		role := openai.ChatMessageRoleUser
+		if msg.Role == openai.ChatMessageRoleAssistant {
+			role = openai.ChatMessageRoleAssistant
+		}
+
+		var content []*bedrockClaudeContent
+		if len(msg.MultiContent) > 0 {
+			for _, part := range msg.MultiContent {
+				switch part.Type {
+				case openai.ChatMessagePartTypeText:
+					content = append(content, &bedrockClaudeContent{Type: "text", Text: part.Text})
+				case openai.ChatMessagePartTypeImageURL:
+					source, err := bedrockClaudeImage(part.ImageURL)
+					if err != nil {
+						return nil, err
+					}
+					content = append(content, &bedrockClaudeContent{Type: "image", Source: source})
+				}
+			}
+		} else if msg.Content != "" {
+			content = append(content, &bedrockClaudeContent{Type: "text", Text: msg.Content})
+		}
+
+		if len(content) == 0 {
+			continue
+		}
+
+		// Claude requires the roles to alternate, so consecutive messages with the same role are merged
+		if n := len(claudeReq.Messages); n > 0 && claudeReq.Messages[n-1].Role == role {
+			claudeReq.Messages[n-1].Content = append(claudeReq.Messages[n-1].Content, content...)
+		} else {
+			claudeReq.Messages = append(claudeReq.Messages, &bedrockClaudeMessage{Role: role, Content: content})
+		}
+	}
+
+	if len(systemParts) > 0 {
+		claudeReq.System = []*bedrockClaudeContent{{Type: "text", Text: strings.Join(systemParts, "\n\n")}}
+	}
+
+	if len(claudeReq.Messages) == 0 {
+		return nil, fmt.Errorf("no messages to send")
+	}
+
+	// ... and the conversation must start with the user
+	if claudeReq.Messages[0].Role != openai.ChatMessageRoleUser {
+		claudeReq.Messages = append([]*bedrockClaudeMessage{{
+			Role:    openai.ChatMessageRoleUser,
+			Content: []*bedrockClaudeContent{{Type: "text", Text: "Continue."}},
+		}}, claudeReq.Messages...)
+	}
+
+	// a final assistant message is continued by the model, which fails if it ends in whitespace
+	last := claudeReq.Messages[len(claudeReq.Messages)-1]
+	if last.Role == openai.ChatMessageRoleAssistant {
+		for _, c := range last.Content {
+			c.Text = strings.TrimRight(c.Text, " \t\n")
+		}
+	}
+
+	for _, tool := range chatReq.Tools {
+		claudeReq.Tools = append(claudeReq.Tools, bedrockClaudeTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: tool.Function.Parameters,
+		})
+	}
+
+	// the tool choice is decoded as a generic value, so re-encode it to read it as a ToolChoice; "auto" and "none" are strings
+	if chatReq.ToolChoice != nil {
+		if _, isString := chatReq.ToolChoice.(string); !isString {
+			choiceBytes, err := json.Marshal(chatReq.ToolChoice)
+			if err != nil {
+				return nil, fmt.Errorf("error marshalling tool choice: %v", err)
+			}
+			var toolChoice openai.ToolChoice
+			err = json.Unmarshal(choiceBytes, &toolChoice)
+			if err != nil {
+				return nil, fmt.Errorf("error unmarshalling tool choice: %v", err)
+			}
+			if toolChoice.Function.Name != "" {
+				claudeReq.ToolChoice = &bedrockClaudeToolChoice{Type: "tool", Name: toolChoice.Function.Name}
+			}
+		}
+	}
+
+	if shared.ModelHasPromptCaching(chatReq.Model) {
+		addBedrockCacheBreakpoints(claudeReq)
+	}
+
+	return claudeReq, nil
+}
+
+// addBedrockCacheBreakpoints marks the end of the system prompt, which has the plan's context, and the end of the conversation
+// before the latest message for Anthropic to cache. A later request that starts with either prefix reads it from the cache.
+func addBedrockCacheBreakpoints(claudeReq *bedrockClaudeRequest) {
+	cacheControl := &bedrockClaudeCacheControl{Type: "ephemeral"}
+
+	if len(claudeReq.System) > 0 {
+		claudeReq.System[len(claudeReq.System)-1].CacheControl = cacheControl
+	}
+
+	if n := len(claudeReq.Messages); n > 1 {
+		content := claudeReq.Messages[n-2].Content
+		content[len(content)-1].CacheControl = cacheControl
+	}
+}
+
+func bedrockClaudeImage(imageUrl *openai.ChatMessageImageURL) (*bedrockClaudeImageSource, error) {
+	if imageUrl == nil {
+		return nil, fmt.Errorf("image part has no url")
+	}
+
+	// images are sent as data urls, like data:image/png;base64,...
+	header, data, ok := strings.Cut(strings.TrimPrefix(imageUrl.URL, "data:"), ",")
+	mediaType, encoding, _ := strings.Cut(header, ";")
+	if !ok || encoding != "base64" {
+		return nil, fmt.Errorf("only base64 data url images are supported for Bedrock models")
+	}
+
+	return &bedrockClaudeImageSource{
+		Type:      "base64",
+		MediaType: mediaType,
+		Data:      data,
+	}, nil
+}
+
+func bedrockFinishReason(stopReason string) openai.FinishReason {
+	switch stopReason {
+	case "max_tokens":
+		return openai.FinishReasonLength
+	case "tool_use":
+		return openai.FinishReasonToolCalls
+	case "":
+		return openai.FinishReasonNull
+	}
+	return openai.FinishReasonStop
+}
+
+// bedrockAwsErrorResponse returns an AWS error as an OpenAI error response so the client's usual error handling and retries apply
+func bedrockAwsErrorResponse(err error) *http.Response {
+	status := http.StatusInternalServerError
+	if reqErr, ok := err.(awserr.RequestFailure); ok {
+		status = reqErr.StatusCode()
+	}
+	return bedrockErrorResponse(status, err.Error())
+}
+
+func bedrockErrorResponse(status int, msg string) *http.Response {
+	errBytes, _ := json.Marshal(openai.ErrorResponse{
+		Error: &openai.APIError{Message: msg, Type: "bedrock_error"},
+	})
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(errBytes)),
+	}
+}
@@ -11,9 +11,11 @@ import (
 
 const OPENAI_STREAM_CHUNK_TIMEOUT = time.Duration(30) * time.Second
 
+const maxRetries = 5
+
 func NewClient(apiKey string) *openai.Client {
 	config := openai.DefaultConfig(apiKey)
-	return openai.NewClientWithConfig(config)
+	return openai.NewClientWithConfig(withRecording(config))
 }
 
 func CreateChatCompletionStreamWithRetries(
@@ -21,7 +23,7 @@ func CreateChatCompletionStreamWithRetries(
 	ctx context.Context,
 	req openai.ChatCompletionRequest,
 ) (*openai.ChatCompletionStream, error) {
-	return createChatCompletionStream(client, ctx, req, 0)
+	return createChatCompletionStream(client, ctx, req, 0, maxRetries)
 }
 
 func createChatCompletionStream(
@@ -29,6 +31,7 @@ func createChatCompletionStream(
 	ctx context.Context,
 	req openai.ChatCompletionRequest,
 	numRetry int,
+	maxRetries int,
 ) (*openai.ChatCompletionStream, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
@@ -44,9 +47,9 @@ func createChatCompletionStream(
 		}
 
 		// for retriable errors, retry with exponential backoff
-		if numRetry < 5 {
+		if numRetry < maxRetries {
 			waitBackoff(numRetry)
-			return createChatCompletionStream(client, ctx, req, numRetry+1)
+			return createChatCompletionStream(client, ctx, req, numRetry+1, maxRetries)
 		}
 
 		log.Println("Max retries reached - no retry")
@@ -56,12 +59,19 @@ func createChatCompletionStream(
 	return stream, nil
 }
 
+// CreateChatCompletionWithRetries records the call's usage once it succeeds. Streamed calls are recorded by their callers as the stream finishes.
 func CreateChatCompletionWithRetries(
 	client *openai.Client,
 	ctx context.Context,
 	req openai.ChatCompletionRequest,
+	usage UsageParams,
 ) (openai.ChatCompletionResponse, error) {
-	return createChatCompletion(client, ctx, req, 0)
+	resp, err := createChatCompletion(client, ctx, req, 0, maxRetries)
+	if err == nil {
+		cached := responseCachedInputTokens(resp, EstimateCachedInputTokens(usage.OrgId, req.Model, req.Messages))
+		RecordUsage(usage, req.Model, resp.Usage.PromptTokens, cached, resp.Usage.CompletionTokens)
+	}
+	return resp, err
 }
 
 func createChatCompletion(
@@ -69,6 +79,7 @@ func createChatCompletion(
 	ctx context.Context,
 	req openai.ChatCompletionRequest,
 	numRetry int,
+	maxRetries int,
 ) (openai.ChatCompletionResponse, error) {
 
 	if ctx.Err() != nil {
@@ -85,9 +96,9 @@ func createChatCompletion(
 		}
 
 		// for retriable errors, retry with exponential backoff
-		if numRetry < 5 {
+		if numRetry < maxRetries {
 			waitBackoff(numRetry)
-			return createChatCompletion(client, ctx, req, numRetry+1)
+			return createChatCompletion(client, ctx, req, numRetry+1, maxRetries)
 		}
 
 		log.Println("Max retries reached - no retry")
@@ -112,6 +123,11 @@ func isNonRetriableErr(err error) bool {
 		return true
 	}
 
+	if strings.Contains(errStr, replayMissErrMsg) {
+		log.Println("No recorded response to replay - no retry")
+		return true
+	}
+
 	if strings.Contains(errStr, "status code: 401") {
 		log.Println("Invalid auth or api key - no retry")
 		return true
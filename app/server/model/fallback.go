@@ -0,0 +1,241 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"plandex-server/metrics"
+	"plandex-server/tracing"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plandex/plandex/shared"
+	"github.com/sashabaranov/go-openai"
+)
+
+// when a role has fallbacks, a failing model is only retried this many times before moving on to the next one
+const maxRetriesBeforeFallback = 1
+
+// a provider's circuit breaker opens after this many consecutive failures, and its models are skipped until the cooldown passes
+const circuitBreakerThreshold = 3
+const circuitBreakerCooldown = time.Duration(60) * time.Second
+
+type circuitBreaker struct {
+	failures  int
+	openUntil time.Time
+}
+
+var circuitBreakers = map[string]*circuitBreaker{}
+var circuitBreakersMu sync.Mutex
+
+// ModelChain is a role's model followed by its fallbacks, in the order they're tried
+func ModelChain(config shared.ModelRoleConfig) []shared.BaseModelConfig {
+	return append([]shared.BaseModelConfig{config.BaseModelConfig}, config.Fallbacks...)
+}
+
+// CreateChatCompletionWithFallbacks calls the first model in the chain, falling back to the next on rate limits, timeouts, and
+// server errors. The request's model is set for each model that's tried.
+func CreateChatCompletionWithFallbacks(
+	client *openai.Client,
+	chain []shared.BaseModelConfig,
+	ctx context.Context,
+	req openai.ChatCompletionRequest,
+	usage UsageParams,
+) (openai.ChatCompletionResponse, error) {
+	var resp openai.ChatCompletionResponse
+	_, err := withFallbacks(client, chain, ctx, req, func(modelClient *openai.Client, modelReq openai.ChatCompletionRequest, retries int) error {
+		var err error
+		resp, err = createChatCompletion(modelClient, ctx, modelReq, 0, retries)
+		if err == nil {
+			cached := responseCachedInputTokens(resp, EstimateCachedInputTokens(usage.OrgId, modelReq.Model, modelReq.Messages))
+			RecordUsage(usage, modelReq.Model, resp.Usage.PromptTokens, cached, resp.Usage.CompletionTokens)
+		}
+		return err
+	})
+	return resp, err
+}
+
+// CreateChatCompletionStreamWithFallbacks is like CreateChatCompletionWithFallbacks for streams. It also returns the position in
+// the chain of the model that's streaming, so a stream that fails part way can fall back to the models after it.
+func CreateChatCompletionStreamWithFallbacks(
+	client *openai.Client,
+	chain []shared.BaseModelConfig,
+	ctx context.Context,
+	req openai.ChatCompletionRequest,
+) (*openai.ChatCompletionStream, int, error) {
+	var stream *openai.ChatCompletionStream
+	idx, err := withFallbacks(client, chain, ctx, req, func(modelClient *openai.Client, modelReq openai.ChatCompletionRequest, retries int) error {
+		var err error
+		stream, err = createChatCompletionStream(modelClient, ctx, modelReq, 0, retries)
+		return err
+	})
+	return stream, idx, err
+}
+
+// RecordModelFailure counts a failure against the model's provider, for failures that happen after a stream has started
+func RecordModelFailure(config shared.BaseModelConfig) {
+	circuitBreakersMu.Lock()
+	defer circuitBreakersMu.Unlock()
+
+	key := circuitBreakerKey(config)
+	breaker := circuitBreakers[key]
+	if breaker == nil {
+		breaker = &circuitBreaker{}
+		circuitBreakers[key] = breaker
+	}
+
+	breaker.failures++
+	if breaker.failures >= circuitBreakerThreshold {
+		breaker.openUntil = time.Now().Add(circuitBreakerCooldown)
+		log.Printf("Circuit breaker open for %s after %d consecutive failures\n", key, breaker.failures)
+	}
+}
+
+func recordModelSuccess(config shared.BaseModelConfig) {
+	circuitBreakersMu.Lock()
+	defer circuitBreakersMu.Unlock()
+	delete(circuitBreakers, circuitBreakerKey(config))
+}
+
+// once the cooldown passes, the next call is let through; if it fails the breaker opens again right away since the failure count
+// is still over the threshold
+func circuitBreakerAllows(config shared.BaseModelConfig) bool {
+	circuitBreakersMu.Lock()
+	defer circuitBreakersMu.Unlock()
+
+	breaker := circuitBreakers[circuitBreakerKey(config)]
+	return breaker == nil || time.Now().After(breaker.openUntil)
+}
+
+// ChainAvailable is false when every model in the chain has an open circuit breaker, i.e. all its providers are failing
+func ChainAvailable(chain []shared.BaseModelConfig) bool {
+	for _, config := range chain {
+		if circuitBreakerAllows(config) {
+			return true
+		}
+	}
+	return false
+}
+
+func circuitBreakerKey(config shared.BaseModelConfig) string {
+	switch config.Provider {
+	case shared.ModelProviderAzureOpenAI:
+		return string(config.Provider) + "|" + config.BaseUrl
+	case shared.ModelProviderBedrock:
+		return string(config.Provider) + "|" + config.Region
+	}
+	return string(config.Provider)
+}
+
+func withFallbacks(
+	client *openai.Client,
+	chain []shared.BaseModelConfig,
+	ctx context.Context,
+	req openai.ChatCompletionRequest,
+	call func(modelClient *openai.Client, modelReq openai.ChatCompletionRequest, retries int) error,
+) (int, error) {
+	if len(chain) == 0 {
+		return 0, fmt.Errorf("no models to call")
+	}
+
+	// if every provider's breaker is open, try them all anyway rather than failing without a call
+	anyAllowed := ChainAvailable(chain)
+
+	var lastErr error
+	for i, config := range chain {
+		isLast := i == len(chain)-1
+
+		if anyAllowed && !circuitBreakerAllows(config) {
+			log.Printf("Skipping %s, circuit breaker is open\n", config.ModelName)
+			continue
+		}
+
+		modelClient, err := ClientForModel(client, config)
+		if err != nil {
+			log.Printf("Error getting client for %s: %v\n", config.ModelName, err)
+			lastErr = err
+			continue
+		}
+
+		modelReq := req
+		modelReq.Model = config.ModelName
+		if modelReq.ResponseFormat != nil {
+			modelReq.ResponseFormat = shared.TaskModelConfigByName[config.ModelName].OpenAIResponseFormat
+		}
+
+		retries := maxRetries
+		if !isLast {
+			retries = maxRetriesBeforeFallback
+		}
+
+		err = callModel(ctx, config, func() error {
+			return call(modelClient, modelReq, retries)
+		})
+		if err == nil {
+			recordModelSuccess(config)
+			return i, nil
+		}
+
+		lastErr = err
+
+		if ctx.Err() != nil || !isFallbackErr(err) {
+			return i, err
+		}
+
+		RecordModelFailure(config)
+
+		if !isLast {
+			log.Printf("%s failed, falling back to %s: %v\n", config.ModelName, chain[i+1].ModelName, err)
+		}
+	}
+
+	return len(chain) - 1, lastErr
+}
+
+// callModel times a call to one model, with its retries, for metrics and tracing
+func callModel(ctx context.Context, config shared.BaseModelConfig, call func() error) error {
+	provider := string(config.Provider)
+	_, span := tracing.Start(ctx, "model call", tracing.String("model", config.ModelName), tracing.String("provider", provider))
+	start := time.Now()
+
+	err := call()
+
+	metrics.ModelRequestDuration.Observe(time.Since(start).Seconds(), config.ModelName, provider)
+	result := "ok"
+	if err != nil {
+		result = "error"
+		span.SetError(err)
+	}
+	metrics.ModelRequests.Inc(config.ModelName, provider, result)
+	span.End()
+
+	return err
+}
+
+// isFallbackErr is true for errors that mean the provider is having trouble rather than that the request is bad
+func isFallbackErr(err error) bool {
+	status := 0
+
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	if errors.As(err, &apiErr) {
+		status = apiErr.HTTPStatusCode
+	} else if errors.As(err, &reqErr) {
+		status = reqErr.HTTPStatusCode
+	}
+
+	if status == http.StatusTooManyRequests || status >= http.StatusInternalServerError {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "deadline exceeded") || strings.Contains(err.Error(), "timeout")
+}
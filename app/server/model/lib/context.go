@@ -3,15 +3,32 @@ package lib
 import (
 	"fmt"
 	"plandex-server/db"
+	"sort"
 	"strings"
 
 	"github.com/plandex/plandex/shared"
+	"github.com/sashabaranov/go-openai"
 )
 
-func FormatModelContext(context []*db.Context) (string, int, error) {
+// FormatModelContext formats context for the model's prompt and counts its tokens with the model's tokenizer. Context is
+// ordered by when it was last updated, see sortContextForPromptCache.
+func FormatModelContext(context []*db.Context, modelName string) (string, int, error) {
+	counter, err := shared.NewTokenCounterForModel(modelName)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get token counter: %v", err)
+	}
+
+	// context is counted with the default tokenizer as it's loaded, so it only needs recounting for models with a different one
+	recount := shared.TokenizerForModel(modelName) != shared.DefaultTokenizer
+
 	var contextMessages []string
 	var numTokens int
-	for _, part := range context {
+	for _, part := range sortContextForPromptCache(context) {
+		// images are sent separately as message parts, see FormatModelContextImages
+		if part.ContextType == shared.ContextImageType {
+			continue
+		}
+
 		var message string
 		var fmtStr string
 		var args []any
@@ -19,6 +36,27 @@ func FormatModelContext(context []*db.Context) (string, int, error) {
 		if part.ContextType == shared.ContextDirectoryTreeType {
 			fmtStr = "\n\n- %s | directory tree:\n\n```\n%s\n```"
 			args = append(args, part.FilePath, part.Body)
+		} else if part.ContextType == shared.ContextMapType {
+			fmtStr = "\n\n- %s | code map:\n\n```\n%s\n```"
+			args = append(args, part.FilePath, part.Body)
+		} else if part.ContextType == shared.ContextFileType && part.Summarized {
+			fmtStr = "\n\n- %s | summary of file:\n\n```\n%s\n```"
+			args = append(args, part.FilePath, part.Body)
+		} else if part.ContextType == shared.ContextSpecType && part.Raw {
+			fmtStr = "\n\n- %s | API spec:\n\n```\n%s\n```"
+			args = append(args, part.FilePath, part.Body)
+		} else if part.ContextType == shared.ContextSpecType {
+			fmtStr = "\n\n- %s | condensed summary of API spec:\n\n```\n%s\n```"
+			args = append(args, part.FilePath, part.Body)
+		} else if part.ContextType == shared.ContextDbSchemaType {
+			fmtStr = "\n\n- %s | database schema:\n\n```sql\n%s\n```"
+			args = append(args, part.Name, part.Body)
+		} else if part.ContextType == shared.ContextFileType && part.LineRange != "" {
+			fmtStr = "\n\n- %s | lines %s only:\n\n```\n%s\n```"
+			args = append(args, part.FilePath, part.LineRange, part.Body)
+		} else if part.ContextType == shared.ContextFileType && part.ChunkPart > 0 {
+			fmtStr = "\n\n- %s | part %d of file only:\n\n```\n%s\n```"
+			args = append(args, part.FilePath, part.ChunkPart, part.Body)
 		} else if part.ContextType == shared.ContextFileType {
 			fmtStr = "\n\n- %s:\n\n```\n%s\n```"
 			args = append(args, part.FilePath, part.Body)
@@ -30,13 +68,14 @@ func FormatModelContext(context []*db.Context) (string, int, error) {
 			args = append(args, part.Name, part.Body)
 		}
 
-		numContextTokens, err := shared.GetNumTokens(fmt.Sprintf(fmtStr, ""))
-		if err != nil {
-			err = fmt.Errorf("failed to get the number of tokens in the context: %v", err)
-			return "", 0, err
+		numContextTokens := counter.Count(fmt.Sprintf(fmtStr, ""))
+
+		partTokens := part.NumTokens
+		if recount {
+			partTokens = counter.Count(part.Body)
 		}
 
-		numTokens += part.NumTokens + numContextTokens
+		numTokens += partTokens + numContextTokens
 
 		message = fmt.Sprintf(fmtStr, args...)
 
@@ -44,3 +83,42 @@ func FormatModelContext(context []*db.Context) (string, int, error) {
 	}
 	return strings.Join(contextMessages, "\n"), numTokens, nil
 }
+
+func FormatModelContextImages(context []*db.Context) ([]openai.ChatMessagePart, int) {
+	var parts []openai.ChatMessagePart
+	var numTokens int
+
+	for _, part := range sortContextForPromptCache(context) {
+		if part.ContextType != shared.ContextImageType {
+			continue
+		}
+
+		parts = append(parts,
+			openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeText,
+				Text: fmt.Sprintf("- %s:", part.FilePath),
+			},
+			openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeImageURL,
+				ImageURL: &openai.ChatMessageImageURL{
+					URL:    part.Body,
+					Detail: openai.ImageURLDetailHigh,
+				},
+			},
+		)
+
+		numTokens += part.NumTokens
+	}
+
+	return parts, numTokens
+}
+
+// sortContextForPromptCache puts the context that has gone longest without changing first. Providers cache prompts by prefix,
+// so this keeps the cached prefix valid for as long as possible: updating context only changes the prompt from that context on.
+func sortContextForPromptCache(context []*db.Context) []*db.Context {
+	sorted := append([]*db.Context{}, context...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].UpdatedAt.Before(sorted[j].UpdatedAt)
+	})
+	return sorted
+}
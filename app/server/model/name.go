@@ -10,7 +10,7 @@ import (
 	"github.com/sashabaranov/go-openai"
 )
 
-func GenPlanName(client *openai.Client, config shared.TaskRoleConfig, planContent string) (string, error) {
+func GenPlanName(client *openai.Client, config shared.TaskRoleConfig, planContent string, usage UsageParams) (string, error) {
 	messages := []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleSystem,
@@ -23,8 +23,9 @@ func GenPlanName(client *openai.Client, config shared.TaskRoleConfig, planConten
 		Content: prompts.GetPlanNamePrompt(planContent),
 	})
 
-	resp, err := CreateChatCompletionWithRetries(
+	resp, err := CreateChatCompletionWithFallbacks(
 		client,
+		ModelChain(config.ModelRoleConfig),
 		context.Background(),
 		openai.ChatCompletionRequest{
 			Model: config.BaseModelConfig.ModelName,
@@ -42,9 +43,11 @@ func GenPlanName(client *openai.Client, config shared.TaskRoleConfig, planConten
 			},
 			Temperature:    config.Temperature,
 			TopP:           config.TopP,
+			MaxTokens:      config.MaxCompletionTokens,
 			Messages:       messages,
 			ResponseFormat: config.OpenAIResponseFormat,
 		},
+		usage,
 	)
 
 	var res string
@@ -34,6 +34,7 @@ func activatePlan(client *openai.Client, plan *db.Plan, branch string, auth *typ
 	modelStream = &db.ModelStream{
 		OrgId:      auth.OrgId,
 		PlanId:     plan.Id,
+		UserId:     &auth.User.Id,
 		InternalIp: host.Ip,
 		Branch:     branch,
 	}
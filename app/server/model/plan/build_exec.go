@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"plandex-server/db"
+	"plandex-server/metrics"
 	"plandex-server/model"
 	"plandex-server/model/prompts"
 	"plandex-server/types"
@@ -127,7 +128,24 @@ func (buildState *activeBuildStreamState) execPlanBuild(activeBuild *types.Activ
 		})
 	}
 
-	// stream initial status to client
+	// stream initial status to client; the file is queued until fewer than the plan's max parallel builds are running
+	activePlan.Stream(shared.StreamMessage{
+		Type: shared.StreamMessageBuildInfo,
+		BuildInfo: &shared.BuildInfo{
+			Path:   filePath,
+			Queued: true,
+		},
+	})
+
+	metrics.BuildsQueued.Inc()
+	acquired := activePlan.AcquireBuildSlot(buildState.settings.GetMaxParallelBuilds())
+	metrics.BuildsQueued.Dec()
+
+	if !acquired {
+		log.Printf("Plan stopped while build for file %s was queued\n", filePath)
+		return
+	}
+
 	buildInfo := &shared.BuildInfo{
 		Path:      filePath,
 		NumTokens: 0,
@@ -142,10 +160,15 @@ func (buildState *activeBuildStreamState) execPlanBuild(activeBuild *types.Activ
 		activeBuildStreamState: buildState,
 		filePath:               filePath,
 		activeBuild:            activeBuild,
+		hasBuildSlot:           true,
 	}
+	fileState.startBuild(activePlan.Ctx)
+
 	err := fileState.loadBuildFile(activeBuild)
 	if err != nil {
 		log.Printf("Error loading build file: %v\n", err)
+		fileState.releaseBuildSlot()
+		fileState.endBuild(err)
 		return
 	}
 
@@ -243,6 +266,10 @@ func (fileState *activeBuildStreamFileState) buildFile() {
 
 	sysPrompt := prompts.GetBuildSysPrompt(filePath, currentState, activeBuild.FileDescription, activeBuild.FileContent)
 
+	if len(fileState.syntaxErrors) > 0 {
+		sysPrompt += "\n\n" + prompts.GetBuildSyntaxRepairPrompt(fileState.syntaxErrors)
+	}
+
 	fileMessages := []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleSystem,
@@ -273,15 +300,30 @@ func (fileState *activeBuildStreamFileState) buildFile() {
 		Messages:       fileMessages,
 		Temperature:    config.Temperature,
 		TopP:           config.TopP,
+		MaxTokens:      config.MaxCompletionTokens,
 		ResponseFormat: config.OpenAIResponseFormat,
 	}
 
-	stream, err := model.CreateChatCompletionStreamWithRetries(client, activePlan.Ctx, modelReq)
+	fileState.inputTokens = model.NumMessagesTokens(modelReq.Model, fileMessages)
+	fileState.cachedInputTokens = model.EstimateCachedInputTokens(currentOrgId, modelReq.Model, fileMessages)
+
+	activePlan.Stream(shared.StreamMessage{
+		Type: shared.StreamMessageUsage,
+		Usage: &shared.StreamUsage{
+			Role:              shared.ModelRoleBuilder,
+			ModelName:         modelReq.Model,
+			InputTokens:       fileState.inputTokens,
+			CachedInputTokens: fileState.cachedInputTokens,
+		},
+	})
+
+	stream, idx, err := model.CreateChatCompletionStreamWithFallbacks(client, fileState.modelChain()[fileState.modelIdx:], fileState.buildCtx, modelReq)
 	if err != nil {
 		log.Printf("Error creating plan file stream for path '%s': %v\n", filePath, err)
 		fileState.onBuildFileError(fmt.Errorf("error creating plan file stream for path '%s': %v", filePath, err))
 		return
 	}
+	fileState.modelIdx += idx
 
 	go fileState.listenStream(stream)
 
@@ -193,6 +193,10 @@ func (fileState *activeBuildStreamFileState) onFinishBuildFile(planRes *db.PlanF
 	build := fileState.build
 	activeBuild := fileState.activeBuild
 
+	// the next queued build can start while this one's result is stored
+	fileState.releaseBuildSlot()
+	fileState.endBuild(nil)
+
 	activePlan := GetActivePlan(planId, branch)
 
 	if activePlan == nil {
@@ -254,6 +258,10 @@ func (fileState *activeBuildStreamFileState) onFinishBuildFile(planRes *db.PlanF
 			}
 			return err
 		}
+
+		// set while the repo is still locked, so a stop sees the build as finished exactly when its result is stored
+		activeBuild.Success = true
+
 		return nil
 	}()
 
@@ -261,8 +269,6 @@ func (fileState *activeBuildStreamFileState) onFinishBuildFile(planRes *db.PlanF
 		return
 	}
 
-	activeBuild.Success = true
-
 	UpdateActivePlan(planId, branch, func(ap *types.ActivePlan) {
 		ap.BuiltFiles[filePath] = true
 		if ap.BuildFinished() {
@@ -316,6 +322,9 @@ func (fileState *activeBuildStreamFileState) onBuildFileError(err error) {
 
 	log.Printf("Error for file %s: %v\n", filePath, err)
 
+	fileState.releaseBuildSlot()
+	fileState.endBuild(err)
+
 	activeBuild.Success = false
 	activeBuild.Error = err
 
@@ -7,9 +7,12 @@ import (
 	"log"
 	"math"
 	"plandex-server/db"
+	"plandex-server/metrics"
 	"plandex-server/model"
+	"plandex-server/tracing"
 	"plandex-server/types"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
@@ -39,6 +42,21 @@ type activeBuildStreamFileState struct {
 	activeBuild      *types.ActiveBuild
 	currentState     string
 	numRetry         int
+	numSyntaxRepairs int
+	syntaxErrors     []string
+	inputTokens      int
+	// position in the builder's model chain of the model to build with; moves to the next fallback when a stream fails
+	modelIdx int
+	// the part of inputTokens expected to be read from the model's prompt cache
+	cachedInputTokens int
+	// held from when the build starts until the file is finished or fails, including retries
+	hasBuildSlot bool
+	// for metrics and tracing, from when the build gets its slot until it's finished, fails, or the plan is stopped
+	buildCtx     context.Context
+	buildSpan    *tracing.Span
+	buildStart   time.Time
+	buildEndOnce *sync.Once
+	buildEnded   chan struct{}
 }
 
 func (fileState *activeBuildStreamFileState) listenStream(stream *openai.ChatCompletionStream) {
@@ -59,6 +77,19 @@ func (fileState *activeBuildStreamFileState) listenStream(stream *openai.ChatCom
 
 	defer stream.Close()
 
+	// streamed responses don't include usage, so each chunk is counted as a token
+	chunksReceived := 0
+	modelName := fileState.modelChain()[fileState.modelIdx].ModelName
+	defer func() {
+		model.RecordUsage(model.UsageParams{
+			OrgId:  currentOrgId,
+			UserId: fileState.currentUserId,
+			PlanId: planId,
+			Branch: branch,
+			Role:   shared.ModelRoleBuilder,
+		}, modelName, fileState.inputTokens, fileState.cachedInputTokens, chunksReceived)
+	}()
+
 	// Create a timer that will trigger if no chunk is received within the specified duration
 	timer := time.NewTimer(model.OPENAI_STREAM_CHUNK_TIMEOUT)
 	defer timer.Stop()
@@ -70,6 +101,7 @@ func (fileState *activeBuildStreamFileState) listenStream(stream *openai.ChatCom
 			return
 		case <-timer.C:
 			// Timer triggered because no new chunk was received in time
+			fileState.fallbackModel()
 			fileState.retryOrError(fmt.Errorf("stream timeout due to inactivity for file '%s'", filePath))
 			return
 		default:
@@ -91,6 +123,7 @@ func (fileState *activeBuildStreamFileState) listenStream(stream *openai.ChatCom
 					return
 				}
 
+				fileState.fallbackModel()
 				fileState.retryOrError(fmt.Errorf("stream error for file '%s': %v", filePath, err))
 				return
 			}
@@ -100,6 +133,7 @@ func (fileState *activeBuildStreamFileState) listenStream(stream *openai.ChatCom
 				return
 			}
 
+			chunksReceived++
 			choice := response.Choices[0]
 			var content string
 			delta := response.Choices[0].Delta
@@ -180,6 +214,10 @@ func (fileState *activeBuildStreamFileState) listenStream(stream *openai.ChatCom
 
 				}
 
+				if fileState.retryOnSyntaxErrors(planFileResult) {
+					return
+				}
+
 				buildInfo := &shared.BuildInfo{
 					Path:      filePath,
 					NumTokens: 0,
@@ -205,6 +243,82 @@ func (fileState *activeBuildStreamFileState) listenStream(stream *openai.ChatCom
 
 }
 
+func (fileState *activeBuildStreamFileState) releaseBuildSlot() {
+	if !fileState.hasBuildSlot {
+		return
+	}
+	fileState.hasBuildSlot = false
+
+	activePlan := GetActivePlan(fileState.plan.Id, fileState.branch)
+	if activePlan != nil {
+		activePlan.ReleaseBuildSlot()
+	}
+}
+
+// startBuild starts timing and tracing the file's build once it has a build slot
+func (fileState *activeBuildStreamFileState) startBuild(ctx context.Context) {
+	fileState.buildStart = time.Now()
+	fileState.buildEndOnce = &sync.Once{}
+	fileState.buildEnded = make(chan struct{})
+	fileState.buildCtx, fileState.buildSpan = tracing.Start(ctx, "build file",
+		tracing.String("plan_id", fileState.plan.Id),
+		tracing.String("branch", fileState.branch),
+		tracing.String("path", fileState.filePath),
+	)
+	metrics.BuildsRunning.Inc()
+
+	// a stopped plan's builds end without finishing or failing
+	ended := fileState.buildEnded
+	go func() {
+		select {
+		case <-ctx.Done():
+			fileState.endBuild(ctx.Err())
+		case <-ended:
+		}
+	}()
+}
+
+// endBuild records how the build went. Only the first call counts.
+func (fileState *activeBuildStreamFileState) endBuild(err error) {
+	if fileState.buildEndOnce == nil {
+		return
+	}
+
+	fileState.buildEndOnce.Do(func() {
+		close(fileState.buildEnded)
+
+		result := "ok"
+		if err == context.Canceled {
+			result = "stopped"
+		} else if err != nil {
+			result = "error"
+		}
+
+		metrics.BuildsRunning.Dec()
+		metrics.Builds.Inc(result)
+		metrics.BuildDuration.Observe(time.Since(fileState.buildStart).Seconds())
+
+		fileState.buildSpan.SetAttributes(tracing.String("result", result), tracing.Int("retries", fileState.numRetry))
+		fileState.buildSpan.SetError(err)
+		fileState.buildSpan.End()
+	})
+}
+
+func (fileState *activeBuildStreamFileState) modelChain() []shared.BaseModelConfig {
+	return model.ModelChain(fileState.settings.ModelSet.Builder.ModelRoleConfig)
+}
+
+// fallbackModel counts a stream failure against the current model's provider and moves the retry to the next model in the
+// builder's chain, if there is one
+func (fileState *activeBuildStreamFileState) fallbackModel() {
+	chain := fileState.modelChain()
+	model.RecordModelFailure(chain[fileState.modelIdx])
+	if fileState.modelIdx+1 < len(chain) {
+		fileState.modelIdx++
+		log.Printf("File %s: falling back to %s\n", fileState.filePath, chain[fileState.modelIdx].ModelName)
+	}
+}
+
 func (fileState *activeBuildStreamFileState) retryOrError(err error) {
 	if fileState.numRetry < MaxBuildStreamErrorRetries {
 		fileState.numRetry++
@@ -0,0 +1,89 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"plandex-server/db"
+	"strings"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// how many times a build that introduces syntax errors is sent back to the model to repair before it's accepted anyway
+const MaxBuildSyntaxRepairs = 2
+
+// only the first few errors are reported, since later ones are often caused by the first
+const maxSyntaxErrors = 10
+
+// getSyntaxErrors parses a file's content and describes any syntax errors, one per error with its line number. Files
+// in languages that can't be checked have no errors.
+func getSyntaxErrors(path, content string) []string {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	if ext == ".json" {
+		return getJsonSyntaxErrors(content)
+	}
+
+	return getCodeSyntaxErrors(path, ext, content)
+}
+
+// describeSyntaxError describes an error on a 0-based row, with the line's code if it isn't blank
+func describeSyntaxError(lines []string, row int, msg string) string {
+	if row < len(lines) && strings.TrimSpace(lines[row]) != "" {
+		return fmt.Sprintf("line %d: %s: `%s`", row+1, msg, strings.TrimSpace(lines[row]))
+	}
+	return fmt.Sprintf("line %d: %s", row+1, msg)
+}
+
+func getJsonSyntaxErrors(content string) []string {
+	var v any
+	err := json.Unmarshal([]byte(content), &v)
+	if err == nil {
+		return nil
+	}
+
+	if syntaxErr, ok := err.(*json.SyntaxError); ok {
+		line := strings.Count(content[:syntaxErr.Offset], "\n") + 1
+		return []string{fmt.Sprintf("line %d: %v", line, syntaxErr)}
+	}
+	return []string{err.Error()}
+}
+
+// retryOnSyntaxErrors checks the file that a build's changes produce, and if they introduced syntax errors, builds the
+// file again with the errors included in the prompt so the model can repair them. It returns true if the build is being
+// retried. Files that already had syntax errors before the changes aren't checked, and after MaxBuildSyntaxRepairs
+// attempts, the result is accepted with its errors.
+func (fileState *activeBuildStreamFileState) retryOnSyntaxErrors(planRes *db.PlanFileResult) bool {
+	filePath := fileState.filePath
+	currentState := fileState.currentState
+
+	updated, _ := shared.ApplyReplacements(currentState, planRes.Replacements, false)
+
+	errs := getSyntaxErrors(filePath, updated)
+	if len(errs) == 0 {
+		return false
+	}
+
+	if len(getSyntaxErrors(filePath, currentState)) > 0 {
+		log.Printf("File %s had syntax errors before the build, skipping syntax check\n", filePath)
+		return false
+	}
+
+	if fileState.numSyntaxRepairs >= MaxBuildSyntaxRepairs {
+		log.Printf("File %s still has syntax errors after %d repair attempts, accepting build:\n%s\n", filePath, fileState.numSyntaxRepairs, strings.Join(errs, "\n"))
+		return false
+	}
+
+	fileState.numSyntaxRepairs++
+	fileState.syntaxErrors = errs
+	fileState.activeBuild.Buffer = ""
+	fileState.activeBuild.BufferTokens = 0
+
+	log.Printf("File %s: build introduced syntax errors, retrying (repair %d/%d):\n%s\n", filePath, fileState.numSyntaxRepairs, MaxBuildSyntaxRepairs, strings.Join(errs, "\n"))
+
+	fileState.buildFile()
+
+	return true
+}
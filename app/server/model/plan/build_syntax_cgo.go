@@ -0,0 +1,100 @@
+//go:build cgo
+
+package plan
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/c"
+	"github.com/smacker/go-tree-sitter/cpp"
+	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/ruby"
+	"github.com/smacker/go-tree-sitter/rust"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+var syntaxLanguagesByExt = map[string]*sitter.Language{
+	".go":   golang.GetLanguage(),
+	".py":   python.GetLanguage(),
+	".js":   javascript.GetLanguage(),
+	".jsx":  javascript.GetLanguage(),
+	".mjs":  javascript.GetLanguage(),
+	".cjs":  javascript.GetLanguage(),
+	".ts":   typescript.GetLanguage(),
+	".mts":  typescript.GetLanguage(),
+	".cts":  typescript.GetLanguage(),
+	".tsx":  tsx.GetLanguage(),
+	".rs":   rust.GetLanguage(),
+	".java": java.GetLanguage(),
+	".c":    c.GetLanguage(),
+	".h":    c.GetLanguage(),
+	".cc":   cpp.GetLanguage(),
+	".cpp":  cpp.GetLanguage(),
+	".hpp":  cpp.GetLanguage(),
+	".rb":   ruby.GetLanguage(),
+}
+
+// getCodeSyntaxErrors checks code with tree-sitter, which has a grammar for each language in syntaxLanguagesByExt
+func getCodeSyntaxErrors(path, ext, content string) []string {
+	language, ok := syntaxLanguagesByExt[ext]
+	if !ok {
+		return nil
+	}
+
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(language)
+
+	src := []byte(content)
+	tree, err := parser.ParseCtx(context.Background(), nil, src)
+	if err != nil {
+		log.Printf("Error parsing %s to check syntax: %v\n", path, err)
+		return nil
+	}
+	defer tree.Close()
+
+	root := tree.RootNode()
+	if !root.HasError() {
+		return nil
+	}
+
+	lines := strings.Split(content, "\n")
+	var errs []string
+
+	var walk func(node *sitter.Node)
+	walk = func(node *sitter.Node) {
+		if len(errs) >= maxSyntaxErrors {
+			return
+		}
+
+		if node.IsMissing() {
+			errs = append(errs, describeSyntaxError(lines, int(node.StartPoint().Row), fmt.Sprintf("missing '%s'", node.Type())))
+			return
+		}
+
+		// an error node covers everything the parser couldn't make sense of, so there's no need to look inside it
+		if node.IsError() {
+			errs = append(errs, describeSyntaxError(lines, int(node.StartPoint().Row), "unexpected syntax"))
+			return
+		}
+
+		if !node.HasError() {
+			return
+		}
+
+		for i := 0; i < int(node.ChildCount()); i++ {
+			walk(node.Child(i))
+		}
+	}
+	walk(root)
+
+	return errs
+}
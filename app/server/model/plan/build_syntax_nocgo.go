@@ -0,0 +1,35 @@
+//go:build !cgo
+
+package plan
+
+import (
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// getCodeSyntaxErrors checks go files with go/parser, since there's no tree-sitter without cgo. Builds of files in
+// other languages aren't checked.
+func getCodeSyntaxErrors(path, ext, content string) []string {
+	if ext != ".go" {
+		return nil
+	}
+
+	_, err := parser.ParseFile(token.NewFileSet(), path, content, parser.SkipObjectResolution)
+	errList, ok := err.(scanner.ErrorList)
+	if !ok {
+		return nil
+	}
+
+	lines := strings.Split(content, "\n")
+	var errs []string
+	for _, e := range errList {
+		if len(errs) >= maxSyntaxErrors {
+			break
+		}
+		errs = append(errs, describeSyntaxError(lines, e.Pos.Line-1, e.Msg))
+	}
+
+	return errs
+}
@@ -12,14 +12,15 @@ import (
 	"github.com/sashabaranov/go-openai"
 )
 
-func genPlanDescription(client *openai.Client, config shared.TaskRoleConfig, planId, branch string, ctx context.Context) (*db.ConvoMessageDescription, error) {
+func genPlanDescription(client *openai.Client, config shared.TaskRoleConfig, orgId, userId, planId, branch string, ctx context.Context) (*db.ConvoMessageDescription, error) {
 	activePlan := GetActivePlan(planId, branch)
 	if activePlan == nil {
 		return nil, fmt.Errorf("active plan not found")
 	}
 
-	descResp, err := model.CreateChatCompletionWithRetries(
+	descResp, err := model.CreateChatCompletionWithFallbacks(
 		client,
+		model.ModelChain(config.ModelRoleConfig),
 		ctx,
 		openai.ChatCompletionRequest{
 			Model: config.BaseModelConfig.ModelName,
@@ -47,8 +48,16 @@ func genPlanDescription(client *openai.Client, config shared.TaskRoleConfig, pla
 			},
 			Temperature:    config.Temperature,
 			TopP:           config.TopP,
+			MaxTokens:      config.MaxCompletionTokens,
 			ResponseFormat: config.OpenAIResponseFormat,
 		},
+		model.UsageParams{
+			OrgId:  orgId,
+			UserId: userId,
+			PlanId: planId,
+			Branch: branch,
+			Role:   shared.ModelRoleCommitMsg,
+		},
 	)
 
 	if err != nil {
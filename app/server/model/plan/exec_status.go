@@ -13,7 +13,9 @@ import (
 	"github.com/sashabaranov/go-openai"
 )
 
-func ExecStatusShouldContinue(client *openai.Client, config shared.TaskRoleConfig, prompt, message string, ctx context.Context) (bool, error) {
+// ExecStatusShouldContinue checks whether the plan should continue after a reply. steps are the plan's steps that aren't done
+// yet, if it has any, and the numbers of the ones that the reply completed are returned along with the result.
+func ExecStatusShouldContinue(client *openai.Client, config shared.TaskRoleConfig, usage model.UsageParams, prompt, message string, steps []*shared.PlanStep, ctx context.Context) (bool, []int, error) {
 	log.Println("Checking if plan should continue based on exec status")
 
 	// First try to determine if the plan should continue based on the last paragraph without calling the model
@@ -24,30 +26,40 @@ func ExecStatusShouldContinue(client *openai.Client, config shared.TaskRoleConfi
 	// log.Printf("Last paragraph: %s\n", lastParagraphLower)
 
 	if lastParagraphLower != "" {
-		if strings.Contains(lastParagraphLower, "all tasks have been completed") ||
-			strings.Contains(lastParagraphLower, "plan cannot be continued") {
+		if strings.Contains(lastParagraphLower, "all tasks have been completed") {
+			log.Println("Plan is complete based on last paragraph")
+			var completedSteps []int
+			for _, step := range steps {
+				completedSteps = append(completedSteps, step.Num)
+			}
+			return false, completedSteps, nil
+		}
+
+		if strings.Contains(lastParagraphLower, "plan cannot be continued") {
 			log.Println("Plan cannot be continued based on last paragraph")
-			return false, nil
+			return false, nil, nil
 		}
 
+		// with steps to track, the model is still needed to tell which of them were completed
 		nextIdx := strings.Index(lastParagraph, "Next, ")
-		if nextIdx >= 0 {
+		if nextIdx >= 0 && len(steps) == 0 {
 			log.Println("Plan can be continued based on last paragraph")
-			return true, nil
+			return true, nil, nil
 		}
 	}
 
 	messages := []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleSystem,
-			Content: prompts.GetExecStatusShouldContinue(prompt, message),
+			Content: prompts.GetExecStatusShouldContinue(prompt, message, steps),
 		},
 	}
 
 	log.Println("Calling model to check if plan should continue")
 
-	resp, err := model.CreateChatCompletionWithRetries(
+	resp, err := model.CreateChatCompletionWithFallbacks(
 		client,
+		model.ModelChain(config.ModelRoleConfig),
 		ctx,
 		openai.ChatCompletionRequest{
 			Model: config.BaseModelConfig.ModelName,
@@ -67,7 +79,9 @@ func ExecStatusShouldContinue(client *openai.Client, config shared.TaskRoleConfi
 			ResponseFormat: config.OpenAIResponseFormat,
 			Temperature:    config.Temperature,
 			TopP:           config.TopP,
+			MaxTokens:      config.MaxCompletionTokens,
 		},
+		usage,
 	)
 
 	if err != nil {
@@ -75,13 +89,14 @@ func ExecStatusShouldContinue(client *openai.Client, config shared.TaskRoleConfi
 		// return false, fmt.Errorf("error during plan exec status check model call: %v", err)
 
 		// Instead of erroring out, just don't continue the plan
-		return false, nil
+		return false, nil, nil
 	}
 
 	var strRes string
 	var res struct {
 		Reasoning      string `json:"reasoning"`
 		ShouldContinue bool   `json:"shouldContinue"`
+		CompletedSteps []int  `json:"completedSteps"`
 	}
 
 	for _, choice := range resp.Choices {
@@ -100,7 +115,7 @@ func ExecStatusShouldContinue(client *openai.Client, config shared.TaskRoleConfi
 		// return false, fmt.Errorf("no shouldAutoContinue function call found in response")
 
 		// Instead of erroring out, just don't continue the plan
-		return false, nil
+		return false, nil, nil
 	}
 
 	err = json.Unmarshal([]byte(strRes), &res)
@@ -110,10 +125,10 @@ func ExecStatusShouldContinue(client *openai.Client, config shared.TaskRoleConfi
 		// return false, fmt.Errorf("error unmarshalling plan exec status response: %v", err)
 
 		// Instead of erroring out, just don't continue the plan
-		return false, nil
+		return false, nil, nil
 	}
 
 	log.Printf("Plan exec status response: %v\n", res)
 
-	return res.ShouldContinue, nil
+	return res.ShouldContinue, res.CompletedSteps, nil
 }
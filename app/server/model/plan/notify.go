@@ -0,0 +1,41 @@
+package plan
+
+import (
+	"log"
+	"plandex-server/db"
+	"plandex-server/notify"
+	"plandex-server/types"
+	"sort"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// notifyPlanFinished posts to the plan's chat channels when it finishes or fails. Plans stopped by the user aren't posted.
+func notifyPlanFinished(active *types.ActivePlan, apiErr *shared.ApiError) {
+	plan, err := db.GetPlan(active.Id)
+	if err != nil {
+		log.Printf("Error getting plan %s for notification: %v\n", active.Id, err)
+		return
+	}
+
+	n := &notify.PlanFinished{
+		PlanName: plan.Name,
+		Branch:   active.Branch,
+		Prompt:   active.Prompt,
+	}
+
+	seen := map[string]bool{}
+	for _, path := range active.Files {
+		if !seen[path] {
+			seen[path] = true
+			n.Files = append(n.Files, path)
+		}
+	}
+	sort.Strings(n.Files)
+
+	if apiErr != nil {
+		n.Err = apiErr.Msg
+	}
+
+	notify.SendPlanFinished(active.NotifyTargets, n)
+}
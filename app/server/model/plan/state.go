@@ -70,6 +70,10 @@ func CreateActivePlan(planId, branch, prompt string, buildOnly bool) *types.Acti
 					time.Sleep(50 * time.Millisecond)
 				}
 
+				if len(activePlan.NotifyTargets) > 0 {
+					go notifyPlanFinished(activePlan, apiErr)
+				}
+
 				activePlan.CancelFn()
 				DeleteActivePlan(planId, branch)
 				return
@@ -98,6 +102,16 @@ func SubscribePlan(planId, branch string) (string, chan string) {
 	return id, ch
 }
 
+func ResumePlanSubscription(planId, branch string, seq int) (string, chan string) {
+	log.Printf("Resuming subscription to plan %s after message %d\n", planId, seq)
+	var id string
+	var ch chan string
+	UpdateActivePlan(planId, branch, func(activePlan *types.ActivePlan) {
+		id, ch = activePlan.SubscribeFrom(seq)
+	})
+	return id, ch
+}
+
 func UnsubscribePlan(planId, branch, subscriptionId string) {
 	log.Printf("UnsubscribePlan %s - %s - %s\n", planId, branch, subscriptionId)
 
@@ -0,0 +1,74 @@
+package plan
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/plandex/plandex/shared"
+)
+
+var planStepRegex = regexp.MustCompile(`^(\d+)\.\s+(.+)$`)
+
+// parsePlanSteps returns the steps listed in a reply that breaks up the task, or nil if the reply doesn't. Only the numbered
+// list that starts a plan is tracked, so a reply that writes files or further breaks up a subtask doesn't replace the steps.
+func parsePlanSteps(reply string, replyFiles []string) []*shared.PlanStep {
+	if len(replyFiles) > 0 || strings.Contains(strings.ToLower(reply), "further break up this subtask") {
+		return nil
+	}
+
+	var steps []*shared.PlanStep
+	for _, line := range strings.Split(reply, "\n") {
+		// nested lists are indented, so only top level items are steps
+		match := planStepRegex.FindStringSubmatch(strings.TrimRight(line, " \r"))
+		if match == nil {
+			continue
+		}
+
+		num, err := strconv.Atoi(match[1])
+		if err != nil || num != len(steps)+1 {
+			continue
+		}
+
+		description := strings.TrimSpace(strings.ReplaceAll(match[2], "**", ""))
+		description = strings.TrimSuffix(description, ":")
+
+		steps = append(steps, &shared.PlanStep{
+			Num:         num,
+			Description: description,
+			Status:      shared.PlanStepStatusPending,
+		})
+	}
+
+	// a single numbered line is more likely part of an explanation than a plan
+	if len(steps) < 2 {
+		return nil
+	}
+
+	return steps
+}
+
+// unfinishedPlanSteps are the steps that are pending or failed
+func unfinishedPlanSteps(steps []*shared.PlanStep) []*shared.PlanStep {
+	var res []*shared.PlanStep
+	for _, step := range steps {
+		if step.Status != shared.PlanStepStatusDone {
+			res = append(res, step)
+		}
+	}
+	return res
+}
+
+// markPlanStepsDone returns whether any of the steps changed
+func markPlanStepsDone(steps []*shared.PlanStep, nums []int) bool {
+	changed := false
+	for _, num := range nums {
+		for _, step := range steps {
+			if step.Num == num && step.Status != shared.PlanStepStatusDone {
+				step.Status = shared.PlanStepStatusDone
+				changed = true
+			}
+		}
+	}
+	return changed
+}
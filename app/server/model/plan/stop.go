@@ -2,7 +2,9 @@ package plan
 
 import (
 	"fmt"
+	"log"
 	"plandex-server/db"
+	"plandex-server/types"
 
 	"github.com/sashabaranov/go-openai"
 )
@@ -14,14 +16,24 @@ func Stop(planId, branch, currentUserId, currentOrgId string) error {
 		return fmt.Errorf("no active plan with id %s", planId)
 	}
 
+	// canceling the plan's context also cancels any model requests that are still running
 	active.SummaryCancelFn()
 	active.CancelFn()
 
-	// rollback repo in case there are uncommitted builds
-	err := db.GitClearUncommittedChanges(currentOrgId, planId)
+	// files that finished building before the stop are kept as pending changes, and the rest are left to build later
+	numKept, err := keepFinishedBuilds(active, currentOrgId)
 
 	if err != nil {
-		return fmt.Errorf("error clearing uncommitted changes: %v", err)
+		return fmt.Errorf("error keeping finished builds: %v", err)
+	}
+
+	if numKept == 0 {
+		// rollback repo in case there are uncommitted builds
+		err = db.GitClearUncommittedChanges(currentOrgId, planId)
+
+		if err != nil {
+			return fmt.Errorf("error clearing uncommitted changes: %v", err)
+		}
 	}
 
 	if !active.BuildOnly && !active.RepliesFinished {
@@ -38,12 +50,79 @@ func Stop(planId, branch, currentUserId, currentOrgId string) error {
 			Message: active.CurrentReplyContent,
 		}
 
+		// this also commits the kept builds
 		_, err := db.StoreConvoMessage(&userMsg, currentUserId, branch, true)
 
 		if err != nil {
 			return fmt.Errorf("error storing convo message: %v", err)
 		}
+	} else if numKept > 0 {
+		err = db.GitAddAndCommit(currentOrgId, planId, branch, fmt.Sprintf("🛑 Build stopped | %d finished files kept", numKept))
+
+		if err != nil {
+			return fmt.Errorf("error committing finished builds: %v", err)
+		}
 	}
 
 	return nil
 }
+
+// keepFinishedBuilds marks the files that finished building as built on their descriptions, so only the files that didn't
+// finish are built again later. It returns the number of files kept.
+func keepFinishedBuilds(active *types.ActivePlan, currentOrgId string) (int, error) {
+	builtByReplyAndPath := map[string]bool{}
+	keptPaths := map[string]bool{}
+	for path, queue := range active.BuildQueuesByPath {
+		for _, build := range queue {
+			if build.Success {
+				builtByReplyAndPath[build.ReplyId+"|"+path] = true
+				keptPaths[path] = true
+			}
+		}
+	}
+
+	if len(keptPaths) == 0 {
+		return 0, nil
+	}
+
+	planDescs, err := db.GetConvoMessageDescriptions(currentOrgId, active.Id)
+	if err != nil {
+		return 0, fmt.Errorf("error getting pending build descriptions: %v", err)
+	}
+
+	for _, desc := range planDescs {
+		if desc.DidBuild && len(desc.BuildPathsInvalidated) == 0 {
+			continue
+		}
+
+		invalidated := map[string]bool{}
+		keptAny := false
+		for _, file := range desc.Files {
+			if desc.DidBuild && !desc.BuildPathsInvalidated[file] {
+				continue
+			}
+
+			if builtByReplyAndPath[desc.ConvoMessageId+"|"+file] {
+				keptAny = true
+			} else {
+				invalidated[file] = true
+			}
+		}
+
+		if !keptAny {
+			continue
+		}
+
+		desc.DidBuild = true
+		desc.BuildPathsInvalidated = invalidated
+
+		err = db.StoreDescription(desc)
+		if err != nil {
+			return 0, fmt.Errorf("error storing description: %v", err)
+		}
+	}
+
+	log.Printf("Kept %d finished builds for stopped plan %s\n", len(keptPaths), active.Id)
+
+	return len(keptPaths), nil
+}
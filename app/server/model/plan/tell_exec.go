@@ -27,6 +27,12 @@ func Tell(client *openai.Client, plan *db.Plan, branch string, auth *types.Serve
 		return err
 	}
 
+	if len(req.Notify) > 0 {
+		UpdateActivePlan(plan.Id, branch, func(ap *types.ActivePlan) {
+			ap.NotifyTargets = req.Notify
+		})
+	}
+
 	go execTellPlan(
 		client,
 		plan,
@@ -147,7 +153,7 @@ func execTellPlan(
 		}
 	}
 
-	modelContextText, modelContextTokens, err := lib.FormatModelContext(state.modelContext)
+	modelContextText, modelContextTokens, err := lib.FormatModelContext(state.modelContext, state.settings.ModelSet.Planner.BaseModelConfig.ModelName)
 	if err != nil {
 		err = fmt.Errorf("error formatting model modelContext: %v", err)
 		log.Println(err)
@@ -160,7 +166,53 @@ func execTellPlan(
 		return
 	}
 
+	var imageParts []openai.ChatMessagePart
+	if state.settings.ModelSet.Planner.BaseModelConfig.HasImageSupport {
+		var imageTokens int
+		imageParts, imageTokens = lib.FormatModelContextImages(state.modelContext)
+		modelContextTokens += imageTokens
+	}
+
 	systemMessageText := prompts.SysCreate + modelContextText
+
+	// once the task is broken into steps, the planner is told which are done so a continued plan doesn't start over
+	var stepsTokens int
+	if shared.NextPlanStep(state.steps) != nil {
+		stepsText := prompts.GetPlanStepsPrompt(state.steps)
+		stepsTokens, err = shared.GetNumTokensForModel(state.settings.ModelSet.Planner.BaseModelConfig.ModelName, stepsText)
+		if err != nil {
+			err = fmt.Errorf("error getting number of tokens in plan steps: %v", err)
+			log.Println(err)
+			active.StreamDoneCh <- &shared.ApiError{
+				Type:   shared.ApiErrorTypeOther,
+				Status: http.StatusInternalServerError,
+				Msg:    "Error getting number of tokens in plan steps",
+			}
+			return
+		}
+		systemMessageText += stepsText
+	}
+
+	var rejectedFilesTokens int
+	if len(state.rejectedFileFeedback) > 0 {
+		rejectedFilesText := prompts.RejectedFilesPrompt
+		for _, feedback := range state.rejectedFileFeedback {
+			rejectedFilesText += fmt.Sprintf("- %s: %s\n", feedback.Path, feedback.Comment)
+		}
+		rejectedFilesTokens, err = shared.GetNumTokensForModel(state.settings.ModelSet.Planner.BaseModelConfig.ModelName, rejectedFilesText)
+		if err != nil {
+			err = fmt.Errorf("error getting number of tokens in rejected file feedback: %v", err)
+			log.Println(err)
+			active.StreamDoneCh <- &shared.ApiError{
+				Type:   shared.ApiErrorTypeOther,
+				Status: http.StatusInternalServerError,
+				Msg:    "Error getting number of tokens in rejected file feedback",
+			}
+			return
+		}
+		systemMessageText += rejectedFilesText
+	}
+
 	systemMessage := openai.ChatCompletionMessage{
 		Role:    openai.ChatMessageRoleSystem,
 		Content: systemMessageText,
@@ -182,7 +234,7 @@ func execTellPlan(
 		promptTokens    int
 	)
 	if iteration == 0 && missingFileResponse == "" {
-		numPromptTokens, err = shared.GetNumTokens(req.Prompt)
+		numPromptTokens, err = shared.GetNumTokensForModel(state.settings.ModelSet.Planner.BaseModelConfig.ModelName, req.Prompt)
 		if err != nil {
 			err = fmt.Errorf("error getting number of tokens in prompt: %v", err)
 			log.Println(err)
@@ -196,11 +248,13 @@ func execTellPlan(
 		promptTokens = prompts.PromptWrapperTokens + numPromptTokens
 	}
 
-	state.tokensBeforeConvo = prompts.CreateSysMsgNumTokens + modelContextTokens + promptTokens
+	state.tokensBeforeConvo = prompts.CreateSysMsgNumTokens + modelContextTokens + stepsTokens + rejectedFilesTokens + promptTokens
 
 	// print out breakdown of token usage
 	log.Printf("System message tokens: %d\n", prompts.CreateSysMsgNumTokens)
 	log.Printf("Context tokens: %d\n", modelContextTokens)
+	log.Printf("Plan steps tokens: %d\n", stepsTokens)
+	log.Printf("Rejected file feedback tokens: %d\n", rejectedFilesTokens)
 	log.Printf("Prompt tokens: %d\n", promptTokens)
 	log.Printf("Total tokens before convo: %d\n", state.tokensBeforeConvo)
 
@@ -286,7 +340,7 @@ func execTellPlan(
 
 		if missingFileResponse == shared.RespondMissingFileChoiceSkip {
 			replyBeforeCurrentFile := state.replyParser.GetReplyBeforeCurrentPath()
-			numTokens, err = shared.GetNumTokens(replyBeforeCurrentFile)
+			numTokens, err = shared.GetNumTokensForModel(state.settings.ModelSet.Planner.BaseModelConfig.ModelName, replyBeforeCurrentFile)
 			if err != nil {
 				log.Printf("Error getting num tokens for reply before current file: %v\n", err)
 				active.StreamDoneCh <- &shared.ApiError{
@@ -341,15 +395,44 @@ func execTellPlan(
 	// 	log.Printf("%s: %s\n", message.Role, message.Content)
 	// }
 
+	if len(imageParts) > 0 {
+		// images can't be included in the system message, so they go in a user message directly after it
+		imageMessage := openai.ChatCompletionMessage{
+			Role: openai.ChatMessageRoleUser,
+			MultiContent: append([]openai.ChatMessagePart{{
+				Type: openai.ChatMessagePartTypeText,
+				Text: prompts.ImageContextPrompt,
+			}}, imageParts...),
+		}
+		state.messages = append(state.messages[:1], append([]openai.ChatCompletionMessage{imageMessage}, state.messages[1:]...)...)
+	}
+
 	modelReq := openai.ChatCompletionRequest{
 		Model:       state.settings.ModelSet.Planner.BaseModelConfig.ModelName,
 		Messages:    state.messages,
 		Stream:      true,
 		Temperature: state.settings.ModelSet.Planner.Temperature,
 		TopP:        state.settings.ModelSet.Planner.TopP,
+		MaxTokens:   state.settings.ModelSet.Planner.MaxCompletionTokens,
 	}
 
-	stream, err := model.CreateChatCompletionStreamWithRetries(client, active.ModelStreamCtx, modelReq)
+	state.inputTokens = model.NumMessagesTokens(modelReq.Model, state.messages)
+	state.cachedInputTokens = model.EstimateCachedInputTokens(currentOrgId, modelReq.Model, state.messages)
+
+	active.Stream(shared.StreamMessage{
+		Type: shared.StreamMessageUsage,
+		Usage: &shared.StreamUsage{
+			Role:              shared.ModelRolePlanner,
+			ModelName:         modelReq.Model,
+			InputTokens:       state.inputTokens,
+			CachedInputTokens: state.cachedInputTokens,
+		},
+	})
+
+	state.modelReq = modelReq
+	state.modelChain = model.ModelChain(state.settings.ModelSet.Planner.ModelRoleConfig)
+
+	stream, modelIdx, err := model.CreateChatCompletionStreamWithFallbacks(client, state.modelChain, active.ModelStreamCtx, modelReq)
 	if err != nil {
 		log.Printf("Error starting reply stream: %v\n", err)
 
@@ -360,6 +443,7 @@ func execTellPlan(
 		}
 		return
 	}
+	state.modelIdx = modelIdx
 
 	if shouldBuildPending {
 		go func() {
@@ -61,6 +61,8 @@ func (state *activeTellStreamState) loadTellPlan() error {
 	var convo []*db.ConvoMessage
 	var summaries []*db.ConvoSummary
 	var settings *shared.PlanSettings
+	var steps []*shared.PlanStep
+	var rejectedFileFeedback []*db.RejectedFileFeedback
 
 	// get name for plan and rename it's a draft
 	go func() {
@@ -73,7 +75,13 @@ func (state *activeTellStreamState) loadTellPlan() error {
 		settings = res
 
 		if plan.Name == "draft" {
-			name, err := model.GenPlanName(client, settings.ModelSet.Namer, req.Prompt)
+			name, err := model.GenPlanName(client, settings.ModelSet.Namer, req.Prompt, model.UsageParams{
+				OrgId:  auth.OrgId,
+				UserId: auth.User.Id,
+				PlanId: plan.Id,
+				Branch: branch,
+				Role:   shared.ModelRoleName,
+			})
 
 			if err != nil {
 				log.Printf("Error generating plan name: %v\n", err)
@@ -140,6 +148,28 @@ func (state *activeTellStreamState) loadTellPlan() error {
 		errCh <- nil
 	}()
 
+	go func() {
+		res, err := db.GetPlanSteps(currentOrgId, planId)
+		if err != nil {
+			log.Printf("Error getting plan steps: %v\n", err)
+			errCh <- fmt.Errorf("error getting plan steps: %v", err)
+			return
+		}
+		steps = res
+		errCh <- nil
+	}()
+
+	go func() {
+		res, err := db.GetRejectedFileFeedback(currentOrgId, planId)
+		if err != nil {
+			log.Printf("Error getting rejected file feedback: %v\n", err)
+			errCh <- fmt.Errorf("error getting rejected file feedback: %v", err)
+			return
+		}
+		rejectedFileFeedback = res
+		errCh <- nil
+	}()
+
 	go func() {
 		res, err := db.GetPlanConvo(currentOrgId, planId)
 		if err != nil {
@@ -164,7 +194,7 @@ func (state *activeTellStreamState) loadTellPlan() error {
 
 		go func() {
 			if iteration == 0 && missingFileResponse == "" && !req.IsUserContinue {
-				num := len(convo) + 1
+				num := db.NextConvoMessageNum(convo)
 
 				log.Printf("storing user message | len(convo): %d | num: %d\n", len(convo), num)
 
@@ -255,7 +285,7 @@ func (state *activeTellStreamState) loadTellPlan() error {
 			}
 		}()
 
-		for i := 0; i < 3; i++ {
+		for i := 0; i < 5; i++ {
 			err = <-errCh
 			if err != nil {
 				active.StreamDoneCh <- &shared.ApiError{
@@ -278,6 +308,8 @@ func (state *activeTellStreamState) loadTellPlan() error {
 	state.convo = convo
 	state.summaries = summaries
 	state.settings = settings
+	state.steps = steps
+	state.rejectedFileFeedback = rejectedFileFeedback
 
 	return nil
 }
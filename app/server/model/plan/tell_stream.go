@@ -36,7 +36,17 @@ type activeTellStreamState struct {
 	replyNumTokens        int
 	messages              []openai.ChatCompletionMessage
 	tokensBeforeConvo     int
+	inputTokens           int
+	cachedInputTokens     int
 	settings              *shared.PlanSettings
+	modelReq              openai.ChatCompletionRequest
+	modelChain            []shared.BaseModelConfig
+	// position in modelChain of the model that's streaming the reply
+	modelIdx int
+	// the plan's step checklist, if the planner has broken the task into steps
+	steps []*shared.PlanStep
+	// comments left when rejecting changes since the last reply
+	rejectedFileFeedback []*db.RejectedFileFeedback
 }
 
 func (state *activeTellStreamState) listenStream(stream *openai.ChatCompletionStream) {
@@ -71,6 +81,18 @@ func (state *activeTellStreamState) listenStream(stream *openai.ChatCompletionSt
 	chunksReceived := 0
 	maybeRedundantBacktickContent := ""
 
+	// streamed responses don't include usage, so each chunk is counted as a token
+	modelName := state.modelChain[state.modelIdx].ModelName
+	defer func() {
+		model.RecordUsage(model.UsageParams{
+			OrgId:  currentOrgId,
+			UserId: currentUserId,
+			PlanId: planId,
+			Branch: branch,
+			Role:   shared.ModelRolePlanner,
+		}, modelName, state.inputTokens, state.cachedInputTokens, chunksReceived)
+	}()
+
 	// Create a timer that will trigger if no chunk is received within the specified duration
 	timer := time.NewTimer(model.OPENAI_STREAM_CHUNK_TIMEOUT)
 	defer timer.Stop()
@@ -84,6 +106,9 @@ func (state *activeTellStreamState) listenStream(stream *openai.ChatCompletionSt
 		case <-timer.C:
 			// Timer triggered because no new chunk was received in time
 			log.Println("\nTell: stream timeout due to inactivity")
+			if state.fallbackStream(chunksReceived) {
+				return
+			}
 			state.onError(fmt.Errorf("stream timeout due to inactivity"), true, "", "")
 			return
 		default:
@@ -103,6 +128,9 @@ func (state *activeTellStreamState) listenStream(stream *openai.ChatCompletionSt
 					return
 				}
 
+				if state.fallbackStream(chunksReceived) {
+					return
+				}
 				state.onError(fmt.Errorf("stream error: %v", err), true, "", "")
 				return
 			}
@@ -142,6 +170,7 @@ func (state *activeTellStreamState) listenStream(stream *openai.ChatCompletionSt
 						summaries:     summaries,
 						promptMessage: promptMessage,
 						currentOrgId:  currentOrgId,
+						currentUserId: currentUserId,
 					}, active.SummaryCtx)
 				}
 
@@ -204,6 +233,14 @@ func (state *activeTellStreamState) listenStream(stream *openai.ChatCompletionSt
 
 					var description *db.ConvoMessageDescription
 
+					// a reply that breaks up the task starts a new step checklist, and a reply that implements steps marks them done
+					newSteps := parsePlanSteps(assistantMsg.Message, replyFiles)
+					var pendingSteps []*shared.PlanStep
+					if newSteps == nil {
+						pendingSteps = unfinishedPlanSteps(state.steps)
+					}
+					var completedSteps []int
+
 					errCh := make(chan error, 2)
 
 					go func() {
@@ -221,7 +258,7 @@ func (state *activeTellStreamState) listenStream(stream *openai.ChatCompletionSt
 							}
 						} else {
 							log.Println("Generating plan description")
-							description, err = genPlanDescription(client, settings.ModelSet.CommitMsg, planId, branch, active.Ctx)
+							description, err = genPlanDescription(client, settings.ModelSet.CommitMsg, currentOrgId, currentUserId, planId, branch, active.Ctx)
 							if err != nil {
 								state.onError(fmt.Errorf("failed to generate plan description: %v", err), true, assistantMsg.Id, convoCommitMsg)
 								return
@@ -259,7 +296,13 @@ func (state *activeTellStreamState) listenStream(stream *openai.ChatCompletionSt
 							prompt = promptMessage.Content
 						}
 
-						shouldContinue, err = ExecStatusShouldContinue(client, settings.ModelSet.ExecStatus, prompt, assistantMsg.Message, active.Ctx)
+						shouldContinue, completedSteps, err = ExecStatusShouldContinue(client, settings.ModelSet.ExecStatus, model.UsageParams{
+							OrgId:  currentOrgId,
+							UserId: currentUserId,
+							PlanId: planId,
+							Branch: branch,
+							Role:   shared.ModelRoleExecStatus,
+						}, prompt, assistantMsg.Message, pendingSteps, active.Ctx)
 						if err != nil {
 							state.onError(fmt.Errorf("failed to get exec status: %v", err), false, assistantMsg.Id, convoCommitMsg)
 							errCh <- err
@@ -278,6 +321,32 @@ func (state *activeTellStreamState) listenStream(stream *openai.ChatCompletionSt
 						}
 					}
 
+					stepsChanged := false
+					if newSteps != nil {
+						log.Printf("Reply broke up the task into %d steps\n", len(newSteps))
+						state.steps = newSteps
+						stepsChanged = true
+					} else {
+						stepsChanged = markPlanStepsDone(state.steps, completedSteps)
+					}
+
+					if stepsChanged {
+						err = db.StorePlanSteps(currentOrgId, planId, state.steps)
+						if err != nil {
+							state.onError(fmt.Errorf("failed to store plan steps: %v", err), false, assistantMsg.Id, convoCommitMsg)
+							return err
+						}
+					}
+
+					// once the model has replied, the feedback has been taken into account
+					if len(state.rejectedFileFeedback) > 0 {
+						err = db.ClearRejectedFileFeedback(currentOrgId, planId)
+						if err != nil {
+							state.onError(fmt.Errorf("failed to clear rejected file feedback: %v", err), false, assistantMsg.Id, convoCommitMsg)
+							return err
+						}
+					}
+
 					log.Println("Comitting reply message and description")
 
 					err = db.GitAddAndCommit(currentOrgId, planId, branch, convoCommitMsg)
@@ -533,7 +602,7 @@ func (state *activeTellStreamState) storeAssistantReply() (*db.ConvoMessage, str
 	replyId := state.replyId
 	convo := state.convo
 
-	num := len(convo) + 1
+	num := db.NextConvoMessageNum(convo)
 
 	log.Printf("storing assistant reply | len(convo) %d | num %d\n", len(convo), num)
 
@@ -569,6 +638,35 @@ func (state *activeTellStreamState) storeAssistantReply() (*db.ConvoMessage, str
 	return &assistantMsg, commitMsg, err
 }
 
+// fallbackStream restarts the reply on the next model in the planner's chain when the stream fails before anything was streamed.
+// It returns false if there's nothing to fall back to, and the error should be handled as usual.
+func (state *activeTellStreamState) fallbackStream(chunksReceived int) bool {
+	if chunksReceived > 0 || state.modelIdx+1 >= len(state.modelChain) {
+		return false
+	}
+
+	active := GetActivePlan(state.plan.Id, state.branch)
+	if active == nil {
+		return false
+	}
+
+	failed := state.modelChain[state.modelIdx]
+	model.RecordModelFailure(failed)
+	log.Printf("Tell: %s stream failed before the first chunk, falling back\n", failed.ModelName)
+
+	remaining := state.modelChain[state.modelIdx+1:]
+	stream, idx, err := model.CreateChatCompletionStreamWithFallbacks(state.client, remaining, active.ModelStreamCtx, state.modelReq)
+	if err != nil {
+		log.Printf("Tell: error starting fallback stream: %v\n", err)
+		return false
+	}
+
+	state.modelIdx += 1 + idx
+	go state.listenStream(stream)
+
+	return true
+}
+
 func (state *activeTellStreamState) onError(streamErr error, storeDesc bool, convoMessageId, commitMsg string) {
 	log.Printf("\nStream error: %v\n", streamErr)
 
@@ -621,7 +719,22 @@ func (state *activeTellStreamState) onError(streamErr error, storeDesc bool, con
 		}
 	}
 
-	if storedMessage || storedDesc {
+	// the step that was being worked on is marked failed so a continued plan retries it
+	storedSteps := false
+	if step := shared.NextPlanStep(state.steps); step != nil && step.Status != shared.PlanStepStatusFailed {
+		step.Status = shared.PlanStepStatusFailed
+		err := db.StorePlanSteps(currentOrgId, planId, state.steps)
+		if err == nil {
+			storedSteps = true
+		} else {
+			log.Printf("Error storing plan steps after stream error: %v\n", err)
+		}
+	}
+
+	if storedMessage || storedDesc || storedSteps {
+		if commitMsg == "" {
+			commitMsg = fmt.Sprintf("Step %d failed", shared.NextPlanStep(state.steps).Num)
+		}
 		err := db.GitAddAndCommit(currentOrgId, planId, branch, commitMsg)
 		if err != nil {
 			log.Printf("Error committing after stream error: %v\n", err)
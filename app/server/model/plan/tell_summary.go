@@ -155,6 +155,7 @@ type summarizeConvoParams struct {
 	summaries     []*db.ConvoSummary
 	promptMessage *openai.ChatCompletionMessage
 	currentOrgId  string
+	currentUserId string
 }
 
 func summarizeConvo(client *openai.Client, config shared.ModelRoleConfig, params summarizeConvoParams, ctx context.Context) error {
@@ -231,7 +232,9 @@ func summarizeConvo(client *openai.Client, config shared.ModelRoleConfig, params
 		LatestConvoMessageCreatedAt: latestMessageSummarizedAt,
 		NumMessages:                 numMessagesSummarized + 1,
 		OrgId:                       currentOrgId,
+		UserId:                      params.currentUserId,
 		PlanId:                      planId,
+		Branch:                      branch,
 	}, ctx)
 
 	if err != nil {
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/plandex/plandex/shared"
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenAI and Anthropic both keep a cached prompt prefix for at least 5 minutes after it was last used
+const promptCacheTTL = time.Duration(5) * time.Minute
+
+// neither provider caches prefixes shorter than this
+const promptCacheMinTokens = 1024
+
+// the Bedrock transport reports the input tokens that Anthropic read from its prompt cache in this response header
+const cachedInputTokensHeader = "X-Cached-Input-Tokens"
+
+// the prompt prefixes each org has recently sent to each model, keyed by the hash of the messages up to and including each
+// message, with the time the prefix expires from the provider's cache
+var promptCachePrefixes = map[string]map[string]time.Time{}
+var promptCachePrefixesMu sync.Mutex
+
+// EstimateCachedInputTokens estimates how many of a request's input tokens the provider will read from its prompt cache, from
+// the longest run of leading messages that the org sent to the same model before the cache expired, and records the request's
+// prefixes for the requests after it. Streamed responses don't include usage, so this is how their cache hits are counted. The
+// premium Anthropic charges for writing to the cache isn't counted.
+func EstimateCachedInputTokens(orgId, modelName string, messages []openai.ChatCompletionMessage) int {
+	if !shared.ModelHasPromptCaching(modelName) {
+		return 0
+	}
+
+	counter, err := shared.NewTokenCounterForModel(modelName)
+	if err != nil {
+		log.Printf("Error getting token counter: %v\n", err)
+		return 0
+	}
+
+	promptCachePrefixesMu.Lock()
+	defer promptCachePrefixesMu.Unlock()
+
+	now := time.Now()
+
+	key := orgId + "|" + modelName
+	prefixes := promptCachePrefixes[key]
+	if prefixes == nil {
+		prefixes = map[string]time.Time{}
+		promptCachePrefixes[key] = prefixes
+	}
+	for hash, expiresAt := range prefixes {
+		if now.After(expiresAt) {
+			delete(prefixes, hash)
+		}
+	}
+
+	// each hash covers every message before it, so a hit on a message's hash means the whole prefix through it is cached
+	hasher := sha256.New()
+	numTokens := 0
+	cachedTokens := 0
+	for _, message := range messages {
+		hasher.Write([]byte(message.Role))
+		hasher.Write([]byte(messageText(message)))
+		for _, part := range message.MultiContent {
+			if part.ImageURL != nil {
+				hasher.Write([]byte(part.ImageURL.URL))
+			}
+		}
+		hash := hex.EncodeToString(hasher.Sum(nil))
+
+		numTokens += counter.Count(messageText(message))
+
+		if _, ok := prefixes[hash]; ok {
+			cachedTokens = numTokens
+		}
+		prefixes[hash] = now.Add(promptCacheTTL)
+	}
+
+	if cachedTokens < promptCacheMinTokens {
+		return 0
+	}
+
+	return cachedTokens
+}
+
+// responseCachedInputTokens is the part of a response's input tokens that was read from the prompt cache: the provider's count
+// when it's reported, otherwise the estimate made for the request
+func responseCachedInputTokens(resp openai.ChatCompletionResponse, estimate int) int {
+	if header := resp.Header().Get(cachedInputTokensHeader); header != "" {
+		n, err := strconv.Atoi(header)
+		if err == nil {
+			return n
+		}
+	}
+	return min(estimate, resp.Usage.PromptTokens)
+}
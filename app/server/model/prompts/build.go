@@ -38,6 +38,11 @@ func getBuildPrompt(desc, changes string) string {
 	return s
 }
 
+// GetBuildSyntaxRepairPrompt is added to the build prompt when a previous attempt's changes left the file with syntax errors
+func GetBuildSyntaxRepairPrompt(syntaxErrors []string) string {
+	return "**A previous attempt to list these changes produced a file with syntax errors.** After the changes were applied, the file had these errors (line numbers are in the updated file):\n```\n" + strings.Join(syntaxErrors, "\n") + "\n```\n\nThis time, make sure that applying your changes leaves the file with valid syntax. Pay close attention to opening and closing brackets, parentheses, braces, and quotes, and to the lines just before and after each change."
+}
+
 func getBuildCurrentStatePrompt(filePath, withLineNums string) string {
 	if withLineNums == "" {
 		return ""
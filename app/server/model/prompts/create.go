@@ -183,3 +183,7 @@ const UserContinuePrompt = "Continue the plan."
 const AutoContinuePrompt = "Continue the plan from where you left off in the previous response. Don't repeat any part of your previous response. Don't begin your response with 'Next,'. Continue seamlessly from where your previous response left off. Never begin your response with 'The plan cannot be continued.' or 'All tasks have been completed.'."
 
 const SkippedPathsPrompt = "\n\nSome files have been skipped by the user and *must not* be generated. The user will handle any updates to these files themselves. Skip any parts of the plan that require generating these files. You *must not* generate a file block for any of these files.\nSkipped files:\n"
+
+const RejectedFilesPrompt = "\n\nThe user has rejected your changes to some files since your last response and explained why. Take their feedback into account when continuing with the plan. If the changes to a file are still needed, make them again in a way that addresses the feedback.\nRejected files:\n"
+
+const ImageContextPrompt = "The user has also loaded these images into context:"
@@ -1,6 +1,9 @@
 package prompts
 
 import (
+	"fmt"
+
+	"github.com/plandex/plandex/shared"
 	"github.com/sashabaranov/go-openai"
 	"github.com/sashabaranov/go-openai/jsonschema"
 )
@@ -25,8 +28,18 @@ Set 'shouldContinue' to true if the plan should automatically continue based on
 
 You must always call 'shouldAutoContinue'. Don't call any other function.`
 
-func GetExecStatusShouldContinue(userPrompt, message string) string {
+const execStatusStepsPrompt = `AI 1 is working through a list of subtasks. You must also set 'completedSteps' to the numbers of the subtasks below that AI 1 fully implemented in its latest message, or to an empty list if it didn't finish any. A subtask that was only started or described doesn't count.`
+
+// GetExecStatusShouldContinue builds the prompt for checking whether the plan should continue. With steps that aren't done
+// yet, the model also reports which of them the message completed.
+func GetExecStatusShouldContinue(userPrompt, message string, steps []*shared.PlanStep) string {
 	s := SysExecStatusShouldContinue
+	if len(steps) > 0 {
+		s += "\n\n" + execStatusStepsPrompt + "\n\n**Here are the subtasks that aren't done yet:**\n"
+		for _, step := range steps {
+			s += fmt.Sprintf("%d. %s\n", step.Num, step.Description)
+		}
+	}
 	if userPrompt != "" {
 		s += "\n\n**Here is the user's prompt:**\n" + userPrompt
 	}
@@ -45,6 +58,10 @@ var ShouldAutoContinueFn = openai.FunctionDefinition{
 			"shouldContinue": {
 				Type: jsonschema.Boolean,
 			},
+			"completedSteps": {
+				Type:  jsonschema.Array,
+				Items: &jsonschema.Definition{Type: jsonschema.Integer},
+			},
 		},
 		Required: []string{"reasoning", "shouldContinue"},
 	},
@@ -0,0 +1,64 @@
+package prompts
+
+import (
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+const SysReview = `You are an expert code reviewer. You review the changes in a range of git commits and report what's wrong with them. Call the 'reviewChanges' function with a valid JSON object that includes the 'summary' and 'findings' keys.
+
+'summary' is a short paragraph on what the changes do and your overall assessment of them.
+
+'findings' is an array of problems in the changes. Each finding is an object with these keys:
+- 'path': the path of the file, exactly as it appears in the diff.
+- 'line': the line number in the new version of the file that the finding is about. Each line of the diff that's in the new version of the file starts with its line number, followed by ' | '. Use 0 if the finding is about the file as a whole or about removed lines.
+- 'category': 'bug' for incorrect behavior, missing error handling, race conditions, edge cases, and the like; 'security' for vulnerabilities like injection, leaked secrets, unsafe input handling, or missing authorization; 'style' for readability, naming, duplication, and inconsistency with the surrounding code.
+- 'severity': 'high' for problems that should block merging, 'medium' for ones that should be fixed soon, 'low' for minor ones.
+- 'message': what the problem is and how to fix it, in a sentence or two.
+
+Only report real problems in the changed lines, not in unchanged code around them, and don't report the same problem twice. If the changes look good, 'findings' is an empty array.`
+
+func GetReviewPrompt(rng, log, diff string) string {
+	return fmt.Sprintf("Review the changes in %s.\n\nCommits:\n```\n%s\n```\n\nDiff:\n```diff\n%s\n```", rng, log, diff)
+}
+
+var ReviewChangesFn = openai.FunctionDefinition{
+	Name: "reviewChanges",
+	Parameters: &jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"summary": {
+				Type: jsonschema.String,
+			},
+			"findings": {
+				Type: jsonschema.Array,
+				Items: &jsonschema.Definition{
+					Type: jsonschema.Object,
+					Properties: map[string]jsonschema.Definition{
+						"path": {
+							Type: jsonschema.String,
+						},
+						"line": {
+							Type: jsonschema.Integer,
+						},
+						"category": {
+							Type: jsonschema.String,
+							Enum: []string{"bug", "security", "style"},
+						},
+						"severity": {
+							Type: jsonschema.String,
+							Enum: []string{"high", "medium", "low"},
+						},
+						"message": {
+							Type: jsonschema.String,
+						},
+					},
+					Required: []string{"path", "line", "category", "severity", "message"},
+				},
+			},
+		},
+		Required: []string{"summary", "findings"},
+	},
+}
@@ -0,0 +1,17 @@
+package prompts
+
+import (
+	"fmt"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// GetPlanStepsPrompt is added to the system prompt while a plan has steps that aren't done, so a plan that's continued
+// resumes from the right step instead of planning again
+func GetPlanStepsPrompt(steps []*shared.PlanStep) string {
+	s := "\n\n# Plan progress:\n\nYou've already broken the task into the subtasks below. Don't list or plan them again. Continue with the first subtask that isn't done. A failed subtask was interrupted before it was finished, so start it again.\n\n"
+	for _, step := range steps {
+		s += fmt.Sprintf("%d. [%s] %s\n", step.Num, step.Status, step.Description)
+	}
+	return s
+}
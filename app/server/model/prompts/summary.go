@@ -48,3 +48,9 @@ Based on the conversation so far, make a summary of the current state of the pla
 
 Output only the summary of the current state of the plan and nothing else.
 `
+
+const SysContextSummary = "You are an AI summarizer that condenses large files so they can be used as context for a software development task without including their full contents. Preserve everything needed to work with the file: its purpose, its structure, and the names, signatures, and types of any exported or important declarations, along with key constants, configuration, and behavior. Omit implementation details that can be described briefly. Output only the summary."
+
+func GetContextSummaryPrompt(name, body string) string {
+	return "Summarize the following file: " + name + "\n\n```\n" + body + "\n```"
+}
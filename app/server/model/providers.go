@@ -0,0 +1,95 @@
+package model
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/bedrockruntime"
+	"github.com/plandex/plandex/shared"
+	"github.com/sashabaranov/go-openai"
+)
+
+const DefaultAzureApiVersion = "2024-02-01"
+
+var bedrockClientsByRegion = map[string]*openai.Client{}
+var bedrockClientsMu sync.Mutex
+
+// ClientForModel returns the client to call a model with. OpenAI models use the client made from the user's api key. Azure OpenAI
+// and Bedrock models are called in the server's own cloud accounts, so their credentials come from the server's environment:
+// AZURE_OPENAI_API_KEY for Azure, and the standard AWS credential chain for Bedrock.
+func ClientForModel(client *openai.Client, config shared.BaseModelConfig) (*openai.Client, error) {
+	switch config.Provider {
+	case shared.ModelProviderAzureOpenAI:
+		return newAzureClient(config)
+	case shared.ModelProviderBedrock:
+		return getBedrockClient(config)
+	}
+	return client, nil
+}
+
+func newAzureClient(config shared.BaseModelConfig) (*openai.Client, error) {
+	apiKey := os.Getenv("AZURE_OPENAI_API_KEY")
+	// replayed responses don't need a key
+	if apiKey == "" && replayDir == "" {
+		return nil, fmt.Errorf("AZURE_OPENAI_API_KEY is not set on the server")
+	}
+
+	if config.BaseUrl == "" {
+		return nil, fmt.Errorf("no Azure OpenAI endpoint is set for %s", config.ModelName)
+	}
+
+	clientConfig := openai.DefaultAzureConfig(apiKey, config.BaseUrl)
+
+	if config.ApiVersion != "" {
+		clientConfig.APIVersion = config.ApiVersion
+	} else {
+		clientConfig.APIVersion = DefaultAzureApiVersion
+	}
+
+	deployment := config.AzureDeployment
+	if deployment == "" {
+		deployment = config.ModelName
+	}
+	clientConfig.AzureModelMapperFunc = func(model string) string {
+		return deployment
+	}
+
+	return openai.NewClientWithConfig(withRecording(clientConfig)), nil
+}
+
+func getBedrockClient(config shared.BaseModelConfig) (*openai.Client, error) {
+	region := config.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		return nil, fmt.Errorf("no AWS region is set for %s, and AWS_REGION is not set on the server", config.ModelName)
+	}
+
+	bedrockClientsMu.Lock()
+	defer bedrockClientsMu.Unlock()
+
+	if client, ok := bedrockClientsByRegion[region]; ok {
+		return client, nil
+	}
+
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return nil, fmt.Errorf("error creating AWS session: %v", err)
+	}
+
+	// requests are translated to Bedrock's api by the transport, so the client's api key and base url are never used
+	clientConfig := openai.DefaultConfig("")
+	clientConfig.HTTPClient = &http.Client{
+		Transport: &bedrockTransport{runtime: bedrockruntime.New(sess)},
+	}
+
+	client := openai.NewClientWithConfig(withRecording(clientConfig))
+	bedrockClientsByRegion[region] = client
+
+	return client, nil
+}
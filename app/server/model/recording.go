@@ -0,0 +1,229 @@
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// With PLANDEX_RECORD set to a directory, each model api request and its response (streamed or not) are saved there as a
+// fixture file. With PLANDEX_REPLAY set to a directory of fixtures, responses are served from them instead of calling the api,
+// so tests of the tell, build, and apply pipeline run offline and give the same results every time.
+var recordDir = os.Getenv("PLANDEX_RECORD")
+var replayDir = os.Getenv("PLANDEX_REPLAY")
+
+const replayMissErrMsg = "no recorded response to replay"
+
+type modelFixture struct {
+	Method   string          `json:"method"`
+	Path     string          `json:"path"`
+	Request  json.RawMessage `json:"request"`
+	Status   int             `json:"status"`
+	Headers  http.Header     `json:"headers"`
+	Response string          `json:"response"`
+}
+
+// recordingTransport records or replays the traffic of the transport it wraps. Fixtures are named by a hash of the request, and
+// the same request made more than once gets a fixture for each time, so a request that's retried or repeated replays the
+// responses in the order they were recorded.
+type recordingTransport struct {
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	numByRequest map[string]int
+}
+
+var recordingTransports = map[http.RoundTripper]*recordingTransport{}
+var recordingTransportsMu sync.Mutex
+
+// withRecording wraps a client's transport for PLANDEX_RECORD or PLANDEX_REPLAY, and returns it unchanged if neither is set
+func withRecording(config openai.ClientConfig) openai.ClientConfig {
+	if recordDir == "" && replayDir == "" {
+		return config
+	}
+
+	next := http.DefaultTransport
+	if config.HTTPClient != nil && config.HTTPClient.Transport != nil {
+		next = config.HTTPClient.Transport
+	}
+
+	// clients are made per request, so transports are shared to keep counting repeated requests across them
+	recordingTransportsMu.Lock()
+	transport, ok := recordingTransports[next]
+	if !ok {
+		transport = &recordingTransport{next: next, numByRequest: map[string]int{}}
+		recordingTransports[next] = transport
+	}
+	recordingTransportsMu.Unlock()
+
+	config.HTTPClient = &http.Client{Transport: transport}
+	return config
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBytes []byte
+	if req.Body != nil {
+		var err error
+		reqBytes, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error reading request body: %v", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBytes))
+	}
+
+	hash := sha256.Sum256([]byte(req.Method + " " + req.URL.Path + "\n" + string(reqBytes)))
+	key := hex.EncodeToString(hash[:])[:16]
+
+	t.mu.Lock()
+	num := t.numByRequest[key]
+	t.numByRequest[key]++
+	t.mu.Unlock()
+
+	if replayDir != "" {
+		return replayFixture(req, key, num)
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the headers that the server reads are kept, so fixtures don't include account details like the org id
+	headers := http.Header{}
+	for _, name := range []string{"Content-Type", cachedInputTokensHeader} {
+		if value := res.Header.Get(name); value != "" {
+			headers.Set(name, value)
+		}
+	}
+
+	fixture := &modelFixture{
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Request: reqBytes,
+		Status:  res.StatusCode,
+		Headers: headers,
+	}
+	if !json.Valid(reqBytes) {
+		fixture.Request = nil
+	}
+
+	// the fixture is written once the whole response has been read, so streams are recorded as they're used
+	res.Body = &recordingBody{
+		ReadCloser: res.Body,
+		fixture:    fixture,
+		path:       fixturePath(recordDir, key, num),
+	}
+
+	return res, nil
+}
+
+// replayFixture serves the num'th recording of a request. If the request was made more times than it was recorded, the last
+// recording is served again.
+func replayFixture(req *http.Request, key string, num int) (*http.Response, error) {
+	var fixtureBytes []byte
+	var err error
+	for ; num >= 0; num-- {
+		fixtureBytes, err = os.ReadFile(fixturePath(replayDir, key, num))
+		if err == nil || !os.IsNotExist(err) {
+			break
+		}
+	}
+	if os.IsNotExist(err) {
+		log.Printf("No fixture for %s %s with key %s\n", req.Method, req.URL.Path, key)
+		return replayErrorResponse(req, fmt.Sprintf("%s for %s %s (fixture key %s)", replayMissErrMsg, req.Method, req.URL.Path, key)), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading fixture: %v", err)
+	}
+
+	var fixture modelFixture
+	err = json.Unmarshal(fixtureBytes, &fixture)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling fixture: %v", err)
+	}
+
+	return &http.Response{
+		StatusCode: fixture.Status,
+		Header:     fixture.Headers,
+		Body:       io.NopCloser(bytes.NewReader([]byte(fixture.Response))),
+		Request:    req,
+	}, nil
+}
+
+func fixturePath(dir, key string, num int) string {
+	return filepath.Join(dir, fmt.Sprintf("%s-%d.json", key, num))
+}
+
+// a replay miss is a 400 so it isn't treated as a provider outage and retried or sent to a fallback
+func replayErrorResponse(req *http.Request, msg string) *http.Response {
+	errBytes, _ := json.Marshal(openai.ErrorResponse{
+		Error: &openai.APIError{Message: msg, Type: "replay_error"},
+	})
+	return &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(errBytes)),
+		Request:    req,
+	}
+}
+
+type recordingBody struct {
+	io.ReadCloser
+	fixture *modelFixture
+	path    string
+	buf     bytes.Buffer
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+// a stream that's closed before it finishes, like a canceled reply, isn't recorded since it couldn't be replayed
+func (b *recordingBody) Close() error {
+	complete := true
+	if strings.HasPrefix(b.fixture.Headers.Get("Content-Type"), "text/event-stream") {
+		complete = strings.Contains(b.buf.String(), "data: [DONE]")
+	} else {
+		// the json decoder can stop reading before the end of the body, so read the rest for the fixture
+		_, err := io.Copy(io.Discard, b)
+		complete = err == nil
+	}
+
+	if complete {
+		b.fixture.Response = b.buf.String()
+		err := writeFixture(b.path, b.fixture)
+		if err != nil {
+			log.Printf("Error writing fixture: %v\n", err)
+		}
+	} else {
+		log.Printf("Response for %s wasn't fully read, not recording it\n", b.path)
+	}
+
+	return b.ReadCloser.Close()
+}
+
+func writeFixture(path string, fixture *modelFixture) error {
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("error creating fixture dir: %v", err)
+	}
+
+	fixtureBytes, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling fixture: %v", err)
+	}
+
+	return os.WriteFile(path, fixtureBytes, 0644)
+}
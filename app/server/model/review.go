@@ -0,0 +1,96 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"plandex-server/model/prompts"
+
+	"github.com/plandex/plandex/shared"
+	"github.com/sashabaranov/go-openai"
+)
+
+type ReviewParams struct {
+	Range  string
+	Log    string
+	Diff   string
+	OrgId  string
+	UserId string
+	PlanId string
+	Branch string
+}
+
+// Review has the planner model review the changes in a range of commits, returning its findings
+func Review(client *openai.Client, config shared.ModelRoleConfig, params ReviewParams, ctx context.Context) (*shared.ReviewResponse, error) {
+	resp, err := CreateChatCompletionWithFallbacks(
+		client,
+		ModelChain(config),
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: config.BaseModelConfig.ModelName,
+			Tools: []openai.Tool{
+				{
+					Type:     "function",
+					Function: prompts.ReviewChangesFn,
+				},
+			},
+			ToolChoice: openai.ToolChoice{
+				Type: "function",
+				Function: openai.ToolFunction{
+					Name: prompts.ReviewChangesFn.Name,
+				},
+			},
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: prompts.SysReview,
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: prompts.GetReviewPrompt(params.Range, params.Log, params.Diff),
+				},
+			},
+			Temperature: config.Temperature,
+			TopP:        config.TopP,
+			MaxTokens:   config.MaxCompletionTokens,
+		},
+		UsageParams{
+			OrgId:  params.OrgId,
+			UserId: params.UserId,
+			PlanId: params.PlanId,
+			Branch: params.Branch,
+			Role:   shared.ModelRolePlanner,
+		},
+	)
+
+	if err != nil {
+		fmt.Println("Review err:", err)
+		return nil, err
+	}
+
+	var fnArgs string
+	for _, choice := range resp.Choices {
+		if len(choice.Message.ToolCalls) == 1 &&
+			choice.Message.ToolCalls[0].Function.Name == prompts.ReviewChangesFn.Name {
+			fnArgs = choice.Message.ToolCalls[0].Function.Arguments
+			break
+		}
+	}
+
+	if fnArgs == "" {
+		return nil, fmt.Errorf("no reviewChanges function call found in response")
+	}
+
+	var res shared.ReviewResponse
+	err = json.Unmarshal([]byte(fnArgs), &res)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling review response: %v", err)
+	}
+
+	res.Range = params.Range
+	if res.Findings == nil {
+		res.Findings = []*shared.ReviewFinding{}
+	}
+
+	return &res, nil
+}
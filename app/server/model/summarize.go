@@ -17,7 +17,9 @@ type PlanSummaryParams struct {
 	LatestConvoMessageCreatedAt time.Time
 	NumMessages                 int
 	OrgId                       string
+	UserId                      string
 	PlanId                      string
+	Branch                      string
 }
 
 func PlanSummary(client *openai.Client, config shared.ModelRoleConfig, params PlanSummaryParams, ctx context.Context) (*db.ConvoSummary, error) {
@@ -40,14 +42,23 @@ func PlanSummary(client *openai.Client, config shared.ModelRoleConfig, params Pl
 	// fmt.Println("summarizing messages:")
 	// spew.Dump(messages)
 
-	resp, err := CreateChatCompletionWithRetries(
+	resp, err := CreateChatCompletionWithFallbacks(
 		client,
+		ModelChain(config),
 		ctx,
 		openai.ChatCompletionRequest{
 			Model:       config.BaseModelConfig.ModelName,
 			Messages:    messages,
 			Temperature: config.Temperature,
 			TopP:        config.TopP,
+			MaxTokens:   config.MaxCompletionTokens,
+		},
+		UsageParams{
+			OrgId:  params.OrgId,
+			UserId: params.UserId,
+			PlanId: params.PlanId,
+			Branch: params.Branch,
+			Role:   shared.ModelRolePlanSummary,
 		},
 	)
 
@@ -74,3 +85,40 @@ func PlanSummary(client *openai.Client, config shared.ModelRoleConfig, params Pl
 	}, nil
 
 }
+
+func SummarizeContext(client *openai.Client, config shared.ModelRoleConfig, name, body string, usage UsageParams, ctx context.Context) (string, error) {
+	resp, err := CreateChatCompletionWithFallbacks(
+		client,
+		ModelChain(config),
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: config.BaseModelConfig.ModelName,
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: prompts.SysContextSummary,
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: prompts.GetContextSummaryPrompt(name, body),
+				},
+			},
+			Temperature: config.Temperature,
+			TopP:        config.TopP,
+			MaxTokens:   config.MaxCompletionTokens,
+		},
+		usage,
+	)
+
+	if err != nil {
+		fmt.Println("SummarizeContext err:", err)
+
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response from GPT")
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
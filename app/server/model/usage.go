@@ -0,0 +1,74 @@
+package model
+
+import (
+	"log"
+	"plandex-server/db"
+	"plandex-server/metrics"
+
+	"github.com/plandex/plandex/shared"
+	"github.com/sashabaranov/go-openai"
+)
+
+// UsageParams identifies the user, plan, and model role that a model call is recorded under
+type UsageParams struct {
+	OrgId  string
+	UserId string
+	PlanId string
+	Branch string
+	Role   shared.ModelRole
+}
+
+// RecordUsage stores the tokens and cost of a model call. cachedInputTokens is the part of inputTokens that was read from the
+// model's prompt cache. Errors are logged rather than returned so a failure to record usage never fails the call itself.
+func RecordUsage(params UsageParams, modelName string, inputTokens, cachedInputTokens, outputTokens int) {
+	if inputTokens == 0 && outputTokens == 0 {
+		return
+	}
+
+	role := string(params.Role)
+	metrics.ModelTokens.Add(float64(inputTokens-cachedInputTokens), role, modelName, "input")
+	metrics.ModelTokens.Add(float64(cachedInputTokens), role, modelName, "cached_input")
+	metrics.ModelTokens.Add(float64(outputTokens), role, modelName, "output")
+
+	err := db.StoreModelUsage(&db.ModelUsage{
+		OrgId:        params.OrgId,
+		UserId:       params.UserId,
+		PlanId:       params.PlanId,
+		Branch:       params.Branch,
+		ModelRole:    string(params.Role),
+		ModelName:    modelName,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+		CostUsd:      shared.GetModelCost(modelName, inputTokens, cachedInputTokens, outputTokens),
+
+		CachedInputTokens: cachedInputTokens,
+		CacheSavingsUsd:   shared.GetPromptCacheSavings(modelName, cachedInputTokens),
+	})
+
+	if err != nil {
+		log.Printf("Error recording model usage: %v\n", err)
+	}
+}
+
+// NumMessagesTokens estimates the input tokens of a streamed request with the model's tokenizer, since streamed responses don't include usage
+func NumMessagesTokens(modelName string, messages []openai.ChatCompletionMessage) int {
+	counter, err := shared.NewTokenCounterForModel(modelName)
+	if err != nil {
+		log.Printf("Error getting token counter: %v\n", err)
+		return 0
+	}
+
+	total := 0
+	for _, message := range messages {
+		total += counter.Count(messageText(message))
+	}
+	return total
+}
+
+func messageText(message openai.ChatCompletionMessage) string {
+	content := message.Content
+	for _, part := range message.MultiContent {
+		content += part.Text
+	}
+	return content
+}
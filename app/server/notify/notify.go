@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// discord rejects messages longer than this
+const discordMaxContentLength = 2000
+
+const maxPromptLength = 300
+const maxListedFiles = 20
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// PlanFinished describes a background plan that finished or failed, for posting to chat channels
+type PlanFinished struct {
+	PlanName string
+	Branch   string
+	Prompt   string
+	// files with changes from this run of the plan
+	Files []string
+	// set if the plan stopped with an error
+	Err string
+}
+
+// SendPlanFinished posts a summary of a finished plan to each target. Failures are logged, since there's no one to report
+// them to once a plan is running in the background.
+func SendPlanFinished(targets []*shared.NotifyTarget, n *PlanFinished) {
+	for _, target := range targets {
+		err := send(target, n)
+		if err != nil {
+			log.Printf("Error sending %s notification for plan %s: %v\n", target.Type, n.PlanName, err)
+		}
+	}
+}
+
+func send(target *shared.NotifyTarget, n *PlanFinished) error {
+	err := target.Validate()
+	if err != nil {
+		return err
+	}
+
+	var body interface{}
+	switch target.Type {
+	case shared.NotifyTargetSlack:
+		body = map[string]string{"text": planFinishedText(n, "*")}
+	case shared.NotifyTargetDiscord:
+		text := planFinishedText(n, "**")
+		if runes := []rune(text); len(runes) > discordMaxContentLength {
+			text = string(runes[:discordMaxContentLength-1]) + "…"
+		}
+		body = map[string]string{"content": text}
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error marshalling notification: %v", err)
+	}
+
+	resp, err := client.Post(target.WebhookUrl, "application/json", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("error posting notification: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
+
+// planFinishedText is the notification as markdown, with bold as it's written for the target: *text* in Slack and
+// **text** in Discord
+func planFinishedText(n *PlanFinished, bold string) string {
+	var b strings.Builder
+
+	if n.Err == "" {
+		fmt.Fprintf(&b, "✅ Plandex plan %s%s%s finished on branch `%s`\n", bold, n.PlanName, bold, n.Branch)
+	} else {
+		fmt.Fprintf(&b, "🚨 Plandex plan %s%s%s stopped with an error on branch `%s`: %s\n", bold, n.PlanName, bold, n.Branch, n.Err)
+	}
+
+	prompt := strings.Join(strings.Fields(n.Prompt), " ")
+	if prompt != "" {
+		if len([]rune(prompt)) > maxPromptLength {
+			prompt = string([]rune(prompt)[:maxPromptLength]) + "…"
+		}
+		fmt.Fprintf(&b, "> %s\n", prompt)
+	}
+
+	if len(n.Files) > 0 {
+		files := n.Files
+		more := ""
+		if len(files) > maxListedFiles {
+			more = fmt.Sprintf(" and %d more", len(files)-maxListedFiles)
+			files = files[:maxListedFiles]
+		}
+		fmt.Fprintf(&b, "\n%sFiles with changes%s: `%s`%s\n", bold, bold, strings.Join(files, "`, `"), more)
+	}
+
+	cmds := []string{fmt.Sprintf("plandex cd \"%s\"", n.PlanName)}
+	if n.Branch != "main" {
+		cmds = append(cmds, "plandex checkout "+n.Branch)
+	}
+	if len(n.Files) > 0 {
+		cmds = append(cmds, "plandex changes")
+	} else {
+		cmds = append(cmds, "plandex convo")
+	}
+	fmt.Fprintf(&b, "\nReview with `%s`", strings.Join(cmds, " && "))
+
+	return b.String()
+}
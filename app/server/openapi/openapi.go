@@ -0,0 +1,288 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/plandex/plandex/shared"
+)
+
+// Route is an API endpoint along with what the OpenAPI document says about it. Request and Response are zero values of the
+// types sent and returned as JSON, or nil if there's no body.
+type Route struct {
+	Method  string
+	Path    string
+	Handler http.HandlerFunc
+
+	Tag     string
+	Summary string
+	// query parameters, all optional strings; a name ending in [] can be repeated
+	Query    []string
+	Request  interface{}
+	Response interface{}
+	// the response is a stream of JSON messages separated by shared.STREAM_MESSAGE_SEPARATOR rather than a JSON body
+	Stream bool
+	// the route doesn't need an auth token
+	Public bool
+}
+
+type Document struct {
+	OpenApi    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Servers    []Server                        `json:"servers"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type Server struct {
+	Url string `json:"url"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description"`
+}
+
+type Operation struct {
+	OperationId string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var pathParamRegex = regexp.MustCompile(`\{([a-zA-Z]+)\}`)
+
+// Generate builds the OpenAPI document for routes served under basePath. Schemas come from the routes' request and response
+// types by reflection, following their json tags, so the document stays in sync with the handlers.
+func Generate(routes []*Route, basePath, version string) *Document {
+	doc := &Document{
+		OpenApi: "3.0.3",
+		Info: Info{
+			Title:       "Plandex API",
+			Version:     version,
+			Description: "Authenticate with 'Authorization: Bearer <token>', where the token is either an API key starting with pdx_ or the base64-encoded JSON {\"token\": \"...\", \"orgId\": \"...\"} from a session. Errors are returned as a JSON ApiError.",
+		},
+		Servers: []Server{{Url: basePath}},
+		Paths:   map[string]map[string]Operation{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearer": {Type: "http", Scheme: "bearer", Description: "an API key, or base64-encoded JSON {\"token\": \"...\", \"orgId\": \"...\"}"},
+			},
+		},
+	}
+
+	g := &generator{schemas: doc.Components.Schemas}
+	errSchema := g.schemaFor(reflect.TypeOf(shared.ApiError{}))
+
+	for _, route := range routes {
+		op := Operation{
+			OperationId: operationId(route),
+			Summary:     route.Summary,
+			Tags:        []string{route.Tag},
+			Responses: map[string]Response{
+				"default": {Description: "Error", Content: map[string]MediaType{"application/json": {Schema: errSchema}}},
+			},
+			Security: []map[string][]string{{"bearer": {}}},
+		}
+		if route.Public {
+			op.Security = []map[string][]string{}
+		}
+
+		for _, match := range pathParamRegex.FindAllStringSubmatch(route.Path, -1) {
+			op.Parameters = append(op.Parameters, Parameter{Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+		for _, name := range route.Query {
+			schema := &Schema{Type: "string"}
+			if strings.HasSuffix(name, "[]") {
+				name = strings.TrimSuffix(name, "[]")
+				schema = &Schema{Type: "array", Items: &Schema{Type: "string"}}
+			}
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "query", Schema: schema})
+		}
+
+		if route.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{"application/json": {Schema: g.schemaFor(reflect.TypeOf(route.Request))}},
+			}
+		}
+
+		ok := Response{Description: "OK"}
+		if route.Stream {
+			ok.Description = "A stream of StreamMessage JSON objects, each followed by the separator @@PX@@"
+			ok.Content = map[string]MediaType{"text/plain": {Schema: &Schema{Type: "string"}}}
+			// the message type is still documented, for clients that parse the stream
+			if route.Response != nil {
+				g.schemaFor(reflect.TypeOf(route.Response))
+			}
+		} else if route.Response != nil {
+			ok.Content = map[string]MediaType{"application/json": {Schema: g.schemaFor(reflect.TypeOf(route.Response))}}
+		}
+		op.Responses["200"] = ok
+
+		if doc.Paths[route.Path] == nil {
+			doc.Paths[route.Path] = map[string]Operation{}
+		}
+		doc.Paths[route.Path][strings.ToLower(route.Method)] = op
+	}
+
+	return doc
+}
+
+// operationId is the handler's name without its package and Handler suffix, like ListContext
+func operationId(route *Route) string {
+	name := runtime.FuncForPC(reflect.ValueOf(route.Handler).Pointer()).Name()
+	name = name[strings.LastIndex(name, ".")+1:]
+	return strings.TrimSuffix(name, "Handler")
+}
+
+type generator struct {
+	schemas map[string]*Schema
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *generator) schemaFor(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time", Nullable: nullable}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string", Nullable: nullable}
+	case reflect.Bool:
+		return &Schema{Type: "boolean", Nullable: nullable}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Nullable: nullable}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Nullable: nullable}
+	case reflect.Slice, reflect.Array:
+		// []byte is encoded as base64
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte", Nullable: nullable}
+		}
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem()), Nullable: true}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		return g.structRef(t)
+	}
+
+	// interfaces can hold anything
+	return &Schema{}
+}
+
+// structRef adds a named struct to the document's schemas, and returns a reference to it
+func (g *generator) structRef(t reflect.Type) *Schema {
+	name := t.Name()
+	if name == "" {
+		return g.structSchema(t)
+	}
+
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := g.schemas[name]; ok {
+		return ref
+	}
+
+	// added before its fields so types that refer to themselves don't recurse forever
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.structSchema(t)
+	return ref
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		// embedded structs' fields are encoded as if they were the outer struct's
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			ft := field.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for name, prop := range g.structSchema(ft).Properties {
+					schema.Properties[name] = prop
+				}
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if tagName := strings.Split(tag, ",")[0]; tagName != "" {
+			name = tagName
+		}
+
+		schema.Properties[name] = g.schemaFor(field.Type)
+	}
+
+	return schema
+}
@@ -1,22 +1,128 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"plandex-server/handlers"
+	"plandex-server/metrics"
+	"plandex-server/openapi"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/plandex/plandex/shared"
 )
 
+// the current version of the API. Every route is served under this prefix, and also without it for clients from before the
+// API was versioned.
+const apiV1Prefix = "/v1"
+
+// apiRoutes is every route of the API, with what the OpenAPI document at /v1/openapi.json says about each one
+var apiRoutes = []*openapi.Route{
+	{Method: "POST", Path: "/accounts/start_trial", Handler: handlers.StartTrialHandler, Tag: "accounts", Summary: "Start an anonymous trial", Response: shared.StartTrialResponse{}, Public: true},
+	{Method: "POST", Path: "/accounts/email_verifications", Handler: handlers.CreateEmailVerificationHandler, Tag: "accounts", Summary: "Send a pin to verify an email", Request: shared.CreateEmailVerificationRequest{}, Response: shared.CreateEmailVerificationResponse{}, Public: true},
+	{Method: "POST", Path: "/accounts/sign_in", Handler: handlers.SignInHandler, Tag: "accounts", Summary: "Sign in with a verified email", Request: shared.SignInRequest{}, Response: shared.SessionResponse{}, Public: true},
+	{Method: "POST", Path: "/accounts/sign_out", Handler: handlers.SignOutHandler, Tag: "accounts", Summary: "Sign out, invalidating the auth token"},
+	{Method: "POST", Path: "/accounts", Handler: handlers.CreateAccountHandler, Tag: "accounts", Summary: "Create an account", Request: shared.CreateAccountRequest{}, Response: shared.SessionResponse{}, Public: true},
+	{Method: "GET", Path: "/accounts/sso", Handler: handlers.GetSsoConfigHandler, Tag: "accounts", Summary: "Check whether the server has SSO sign-in", Response: shared.SsoConfigResponse{}, Public: true},
+	{Method: "POST", Path: "/accounts/sso/start", Handler: handlers.StartSsoHandler, Tag: "accounts", Summary: "Start signing in with SSO, returning the identity provider url to open", Response: shared.StartSsoResponse{}, Public: true},
+	{Method: "GET", Path: "/accounts/sso/callback", Handler: handlers.SsoCallbackHandler, Tag: "accounts", Summary: "Where the identity provider redirects the browser after signing in", Query: []string{"code", "state"}, Public: true},
+	{Method: "POST", Path: "/accounts/sso/confirm", Handler: handlers.ConfirmSsoHandler, Tag: "accounts", Summary: "Finish an SSO sign-in in the browser with the code shown in the terminal", Public: true},
+	{Method: "POST", Path: "/accounts/sso/poll", Handler: handlers.PollSsoHandler, Tag: "accounts", Summary: "Get the session for an SSO sign-in. Returns 202 until the user finishes signing in", Request: shared.PollSsoRequest{}, Response: shared.SessionResponse{}, Public: true},
+	{Method: "POST", Path: "/accounts/convert_trial", Handler: handlers.ConvertTrialHandler, Tag: "accounts", Summary: "Convert a trial to a full account", Request: shared.ConvertTrialRequest{}, Response: shared.SessionResponse{}},
+
+	{Method: "GET", Path: "/orgs/session", Handler: handlers.GetOrgSessionHandler, Tag: "orgs", Summary: "Check the auth token's org session"},
+	{Method: "GET", Path: "/orgs", Handler: handlers.ListOrgsHandler, Tag: "orgs", Summary: "List the user's orgs", Response: []*shared.Org{}},
+	{Method: "POST", Path: "/orgs", Handler: handlers.CreateOrgHandler, Tag: "orgs", Summary: "Create an org", Request: shared.CreateOrgRequest{}, Response: shared.CreateOrgResponse{}},
+
+	{Method: "GET", Path: "/users", Handler: handlers.ListUsersHandler, Tag: "orgs", Summary: "List the org's users", Response: shared.ListUsersResponse{}},
+	{Method: "DELETE", Path: "/orgs/users/{userId}", Handler: handlers.DeleteOrgUserHandler, Tag: "orgs", Summary: "Remove a user from the org"},
+	{Method: "GET", Path: "/orgs/roles", Handler: handlers.ListOrgRolesHandler, Tag: "orgs", Summary: "List the org's roles", Response: []*shared.OrgRole{}},
+
+	{Method: "POST", Path: "/invites", Handler: handlers.InviteUserHandler, Tag: "orgs", Summary: "Invite a user to the org", Request: shared.InviteRequest{}},
+	{Method: "GET", Path: "/invites/pending", Handler: handlers.ListPendingInvitesHandler, Tag: "orgs", Summary: "List pending invites", Response: []*shared.Invite{}},
+	{Method: "GET", Path: "/invites/accepted", Handler: handlers.ListAcceptedInvitesHandler, Tag: "orgs", Summary: "List accepted invites", Response: []*shared.Invite{}},
+	{Method: "GET", Path: "/invites/all", Handler: handlers.ListAllInvitesHandler, Tag: "orgs", Summary: "List all invites", Response: []*shared.Invite{}},
+	{Method: "DELETE", Path: "/invites/{inviteId}", Handler: handlers.DeleteInviteHandler, Tag: "orgs", Summary: "Delete an invite"},
+
+	{Method: "POST", Path: "/api_keys", Handler: handlers.CreateApiKeyHandler, Tag: "api_keys", Summary: "Create an API key for automation, returning the key", Request: shared.CreateApiKeyRequest{}, Response: shared.CreateApiKeyResponse{}},
+	{Method: "GET", Path: "/api_keys", Handler: handlers.ListApiKeysHandler, Tag: "api_keys", Summary: "List the user's API keys in the org", Response: []*shared.ApiKey{}},
+	{Method: "DELETE", Path: "/api_keys/{apiKeyId}", Handler: handlers.RevokeApiKeyHandler, Tag: "api_keys", Summary: "Revoke an API key, by id or name"},
+
+	{Method: "POST", Path: "/projects", Handler: handlers.CreateProjectHandler, Tag: "projects", Summary: "Create a project", Request: shared.CreateProjectRequest{}, Response: shared.CreateProjectResponse{}},
+	{Method: "GET", Path: "/projects", Handler: handlers.ListProjectsHandler, Tag: "projects", Summary: "List projects", Response: []*shared.Project{}},
+	{Method: "PUT", Path: "/projects/{projectId}/set_plan", Handler: handlers.ProjectSetPlanHandler, Tag: "projects", Summary: "Set a project's current plan", Request: shared.SetProjectPlanRequest{}},
+	{Method: "PUT", Path: "/projects/{projectId}/rename", Handler: handlers.RenameProjectHandler, Tag: "projects", Summary: "Rename a project", Request: shared.RenameProjectRequest{}},
+	{Method: "POST", Path: "/projects/{projectId}/plans/current_branches", Handler: handlers.GetCurrentBranchByPlanIdHandler, Tag: "projects", Summary: "Get the current branch of each plan", Request: shared.GetCurrentBranchByPlanIdRequest{}, Response: map[string]*shared.Branch{}},
+
+	{Method: "GET", Path: "/plans", Handler: handlers.ListPlansHandler, Tag: "plans", Summary: "List plans in projects", Query: []string{"projectId[]"}, Response: []*shared.Plan{}},
+	{Method: "GET", Path: "/plans/archive", Handler: handlers.ListArchivedPlansHandler, Tag: "plans", Summary: "List archived plans in projects", Query: []string{"projectId[]"}, Response: []*shared.Plan{}},
+	{Method: "GET", Path: "/plans/ps", Handler: handlers.ListPlansRunningHandler, Tag: "plans", Summary: "List active and recently finished plan streams", Query: []string{"projectId[]", "recent"}, Response: shared.ListPlansRunningResponse{}},
+
+	{Method: "GET", Path: "/quotas", Handler: handlers.ListQuotasHandler, Tag: "orgs", Summary: "List the org's quota and its users' quotas", Response: []*shared.Quota{}},
+	{Method: "PUT", Path: "/quotas", Handler: handlers.SetQuotaHandler, Tag: "orgs", Summary: "Set the org's quota or a user's quota. A limit of 0 removes it", Request: shared.SetQuotaRequest{}, Response: shared.Quota{}},
+	{Method: "GET", Path: "/usage", Handler: handlers.GetUsageHandler, Tag: "plans", Summary: "Get model token usage and spend", Query: []string{"planId", "since"}, Response: shared.UsageResponse{}},
+	{Method: "GET", Path: "/usage/org", Handler: handlers.GetOrgUsageHandler, Tag: "orgs", Summary: "Get everyone's API calls and model usage in the org by day and user, as JSON or CSV", Query: []string{"since", "until", "format"}, Response: shared.OrgUsageResponse{}},
+
+	{Method: "POST", Path: "/projects/{projectId}/plans", Handler: handlers.CreatePlanHandler, Tag: "plans", Summary: "Create a plan", Request: shared.CreatePlanRequest{}, Response: shared.CreatePlanResponse{}},
+	{Method: "DELETE", Path: "/projects/{projectId}/plans", Handler: handlers.DeleteAllPlansHandler, Tag: "plans", Summary: "Delete all of the user's plans in a project"},
+	{Method: "POST", Path: "/projects/{projectId}/plans/import", Handler: handlers.ImportPlanHandler, Tag: "plans", Summary: "Import a plan from an archive", Request: shared.PlanArchive{}, Response: shared.ImportPlanResponse{}},
+
+	{Method: "GET", Path: "/plans/{planId}", Handler: handlers.GetPlanHandler, Tag: "plans", Summary: "Get a plan", Response: shared.Plan{}},
+	{Method: "DELETE", Path: "/plans/{planId}", Handler: handlers.DeletePlanHandler, Tag: "plans", Summary: "Delete a plan"},
+	{Method: "PATCH", Path: "/plans/{planId}/{branch}/archive", Handler: handlers.ArchivePlanHandler, Tag: "plans", Summary: "Archive a plan"},
+	{Method: "GET", Path: "/plans/{planId}/{branch}/export", Handler: handlers.ExportPlanHandler, Tag: "plans", Summary: "Export a plan branch to an archive", Response: shared.PlanArchive{}},
+
+	{Method: "POST", Path: "/plans/{planId}/{branch}/tell", Handler: handlers.TellPlanHandler, Tag: "messages", Summary: "Send a prompt. With connectStream, the response streams the plan", Request: shared.TellPlanRequest{}, Response: shared.StreamMessage{}, Stream: true},
+	{Method: "POST", Path: "/plans/{planId}/{branch}/respond_missing_file", Handler: handlers.RespondMissingFileHandler, Tag: "messages", Summary: "Answer a stream's prompt about a file that isn't in context", Request: shared.RespondMissingFileRequest{}},
+	{Method: "PATCH", Path: "/plans/{planId}/{branch}/build", Handler: handlers.BuildPlanHandler, Tag: "changes", Summary: "Build pending changes. With connectStream, the response streams the build", Request: shared.BuildPlanRequest{}, Response: shared.StreamMessage{}, Stream: true},
+	{Method: "PATCH", Path: "/plans/{planId}/{branch}/connect", Handler: handlers.ConnectPlanHandler, Tag: "messages", Summary: "Connect to an active plan's stream, resuming after the message numbered resumeFrom if it's set", Query: []string{"resumeFrom"}, Response: shared.StreamMessage{}, Stream: true},
+	{Method: "DELETE", Path: "/plans/{planId}/{branch}/stop", Handler: handlers.StopPlanHandler, Tag: "messages", Summary: "Stop an active plan stream"},
+
+	{Method: "GET", Path: "/plans/{planId}/{branch}/current_plan", Handler: handlers.CurrentPlanHandler, Tag: "changes", Summary: "Get pending changes and the plan state they come from", Response: shared.CurrentPlanState{}},
+	{Method: "PATCH", Path: "/plans/{planId}/{branch}/apply", Handler: handlers.ApplyPlanHandler, Tag: "applies", Summary: "Mark pending changes applied, all of them unless paths are given", Request: shared.ApplyPlanRequest{}},
+	{Method: "PATCH", Path: "/plans/{planId}/{branch}/reject_all", Handler: handlers.RejectAllChangesHandler, Tag: "changes", Summary: "Reject all pending changes"},
+	{Method: "PATCH", Path: "/plans/{planId}/{branch}/reject_file", Handler: handlers.RejectFileHandler, Tag: "changes", Summary: "Reject pending changes to a file", Request: shared.RejectFileRequest{}},
+	{Method: "PATCH", Path: "/plans/{planId}/{branch}/edit_file", Handler: handlers.EditFileHandler, Tag: "changes", Summary: "Replace a file's pending changes", Request: shared.EditFileRequest{}},
+	{Method: "POST", Path: "/plans/{planId}/{branch}/review", Handler: handlers.ReviewHandler, Tag: "changes", Summary: "Review a git commit range", Request: shared.ReviewRequest{}, Response: shared.ReviewResponse{}},
+
+	{Method: "GET", Path: "/plans/{planId}/{branch}/context", Handler: handlers.ListContextHandler, Tag: "contexts", Summary: "List context", Response: []*shared.Context{}},
+	{Method: "POST", Path: "/plans/{planId}/{branch}/context", Handler: handlers.LoadContextHandler, Tag: "contexts", Summary: "Load context", Request: shared.LoadContextRequest{}, Response: shared.LoadContextResponse{}},
+	{Method: "PUT", Path: "/plans/{planId}/{branch}/context", Handler: handlers.UpdateContextHandler, Tag: "contexts", Summary: "Update outdated context", Request: shared.UpdateContextRequest{}, Response: shared.UpdateContextResponse{}},
+	{Method: "DELETE", Path: "/plans/{planId}/{branch}/context", Handler: handlers.DeleteContextHandler, Tag: "contexts", Summary: "Remove context", Request: shared.DeleteContextRequest{}, Response: shared.DeleteContextResponse{}},
+	{Method: "PUT", Path: "/plans/{planId}/{branch}/context/pin", Handler: handlers.PinContextHandler, Tag: "contexts", Summary: "Pin or unpin context", Request: shared.PinContextRequest{}, Response: shared.PinContextResponse{}},
+
+	{Method: "GET", Path: "/plans/{planId}/{branch}/convo", Handler: handlers.ListConvoHandler, Tag: "messages", Summary: "List conversation messages", Response: []*shared.ConvoMessage{}},
+	{Method: "PATCH", Path: "/plans/{planId}/{branch}/compact", Handler: handlers.CompactConvoHandler, Tag: "messages", Summary: "Replace older messages with a summary", Request: shared.CompactConvoRequest{}, Response: shared.CompactConvoResponse{}},
+	{Method: "GET", Path: "/plans/{planId}/{branch}/steps", Handler: handlers.ListPlanStepsHandler, Tag: "messages", Summary: "List the plan's steps", Response: []*shared.PlanStep{}},
+	{Method: "PATCH", Path: "/plans/{planId}/{branch}/rewind", Handler: handlers.RewindPlanHandler, Tag: "plans", Summary: "Rewind to an earlier revision", Request: shared.RewindPlanRequest{}, Response: shared.RewindPlanResponse{}},
+	{Method: "GET", Path: "/plans/{planId}/{branch}/logs", Handler: handlers.ListLogsHandler, Tag: "plans", Summary: "List the plan's revisions", Response: shared.LogResponse{}},
+	{Method: "GET", Path: "/plans/{planId}/{branch}/diff", Handler: handlers.DiffPlanHandler, Tag: "plans", Summary: "Compare the plan between two revisions", Query: []string{"from", "to"}, Response: shared.PlanDiffResponse{}},
+
+	{Method: "GET", Path: "/plans/{planId}/shares", Handler: handlers.ListPlanSharesHandler, Tag: "shares", Summary: "List who a plan is shared with", Response: []*shared.PlanShare{}},
+	{Method: "POST", Path: "/plans/{planId}/shares", Handler: handlers.SharePlanHandler, Tag: "shares", Summary: "Share a plan with a user or the whole org, or change a share's permission", Request: shared.SharePlanRequest{}},
+	{Method: "DELETE", Path: "/plans/{planId}/shares/{shareId}", Handler: handlers.DeletePlanShareHandler, Tag: "shares", Summary: "Stop sharing a plan with a user or the org"},
+
+	{Method: "GET", Path: "/plans/{planId}/branches", Handler: handlers.ListBranchesHandler, Tag: "branches", Summary: "List branches", Response: []*shared.Branch{}},
+	{Method: "DELETE", Path: "/plans/{planId}/branches/{branch}", Handler: handlers.DeleteBranchHandler, Tag: "branches", Summary: "Delete a branch"},
+	{Method: "POST", Path: "/plans/{planId}/{branch}/branches", Handler: handlers.CreateBranchHandler, Tag: "branches", Summary: "Create a branch from this one", Request: shared.CreateBranchRequest{}},
+
+	{Method: "GET", Path: "/plans/{planId}/{branch}/settings", Handler: handlers.GetSettingsHandler, Tag: "settings", Summary: "Get plan settings", Response: shared.PlanSettings{}},
+	{Method: "PUT", Path: "/plans/{planId}/{branch}/settings", Handler: handlers.UpdateSettingsHandler, Tag: "settings", Summary: "Update plan settings", Request: shared.UpdateSettingsRequest{}, Response: shared.UpdateSettingsResponse{}},
+}
+
 func routes() *mux.Router {
 	r := mux.NewRouter()
 
-	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+	health := func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprint(w, "OK")
-	})
+	}
+	r.HandleFunc("/health", health)
+	r.HandleFunc(apiV1Prefix+"/health", health)
 
-	r.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+	version := func(w http.ResponseWriter, r *http.Request) {
 		// get version from version.txt
 		bytes, err := os.ReadFile("version.txt")
 
@@ -26,77 +132,41 @@ func routes() *mux.Router {
 		}
 
 		fmt.Fprint(w, string(bytes))
-	})
-
-	r.HandleFunc("/accounts/start_trial", handlers.StartTrialHandler).Methods("POST")
-	r.HandleFunc("/accounts/email_verifications", handlers.CreateEmailVerificationHandler).Methods("POST")
-	r.HandleFunc("/accounts/sign_in", handlers.SignInHandler).Methods("POST")
-	r.HandleFunc("/accounts/sign_out", handlers.SignOutHandler).Methods("POST")
-	r.HandleFunc("/accounts", handlers.CreateAccountHandler).Methods("POST")
-	r.HandleFunc("/accounts/convert_trial", handlers.ConvertTrialHandler).Methods("POST")
-
-	r.HandleFunc("/orgs/session", handlers.GetOrgSessionHandler).Methods("GET")
-	r.HandleFunc("/orgs", handlers.ListOrgsHandler).Methods("GET")
-	r.HandleFunc("/orgs", handlers.CreateOrgHandler).Methods("POST")
-
-	r.HandleFunc("/users", handlers.ListUsersHandler).Methods("GET")
-	r.HandleFunc("/orgs/users/{userId}", handlers.DeleteOrgUserHandler).Methods("DELETE")
-	r.HandleFunc("/orgs/roles", handlers.ListOrgRolesHandler).Methods("GET")
-
-	r.HandleFunc("/invites", handlers.InviteUserHandler).Methods("POST")
-	r.HandleFunc("/invites/pending", handlers.ListPendingInvitesHandler).Methods("GET")
-	r.HandleFunc("/invites/accepted", handlers.ListAcceptedInvitesHandler).Methods("GET")
-	r.HandleFunc("/invites/all", handlers.ListAllInvitesHandler).Methods("GET")
-	r.HandleFunc("/invites/{inviteId}", handlers.DeleteInviteHandler).Methods("DELETE")
-
-	r.HandleFunc("/projects", handlers.CreateProjectHandler).Methods("POST")
-	r.HandleFunc("/projects", handlers.ListProjectsHandler).Methods("GET")
-	r.HandleFunc("/projects/{projectId}/set_plan", handlers.ProjectSetPlanHandler).Methods("PUT")
-	r.HandleFunc("/projects/{projectId}/rename", handlers.RenameProjectHandler).Methods("PUT")
-
-	r.HandleFunc("/projects/{projectId}/plans/current_branches", handlers.GetCurrentBranchByPlanIdHandler).Methods("POST")
+	}
+	r.HandleFunc("/version", version)
+	r.HandleFunc(apiV1Prefix+"/version", version)
 
-	r.HandleFunc("/plans", handlers.ListPlansHandler).Methods("GET")
-	r.HandleFunc("/plans/archive", handlers.ListArchivedPlansHandler).Methods("GET")
-	r.HandleFunc("/plans/ps", handlers.ListPlansRunningHandler).Methods("GET")
+	r.HandleFunc(apiV1Prefix+"/openapi.json", openApiHandler).Methods("GET")
 
-	r.HandleFunc("/projects/{projectId}/plans", handlers.CreatePlanHandler).Methods("POST")
+	r.HandleFunc("/metrics", metrics.Handler).Methods("GET")
 
-	r.HandleFunc("/projects/{projectId}/plans", handlers.CreatePlanHandler).Methods("DELETE")
+	v1 := r.PathPrefix(apiV1Prefix).Subrouter()
+	for _, route := range apiRoutes {
+		v1.HandleFunc(route.Path, route.Handler).Methods(route.Method)
+		r.HandleFunc(route.Path, route.Handler).Methods(route.Method)
+	}
 
-	r.HandleFunc("/plans/{planId}", handlers.GetPlanHandler).Methods("GET")
-	r.HandleFunc("/plans/{planId}", handlers.DeletePlanHandler).Methods("DELETE")
+	r.Use(instrumentRequests)
 
-	r.HandleFunc("/plans/{planId}/{branch}/tell", handlers.TellPlanHandler).Methods("POST")
-
-	r.HandleFunc("/plans/{planId}/{branch}/respond_missing_file", handlers.RespondMissingFileHandler).Methods("POST")
-
-	r.HandleFunc("/plans/{planId}/{branch}/build", handlers.BuildPlanHandler).Methods("PATCH")
-	r.HandleFunc("/plans/{planId}/{branch}/connect", handlers.ConnectPlanHandler).Methods("PATCH")
-	r.HandleFunc("/plans/{planId}/{branch}/stop", handlers.StopPlanHandler).Methods("DELETE")
-
-	r.HandleFunc("/plans/{planId}/{branch}/current_plan", handlers.CurrentPlanHandler).Methods("GET")
-	r.HandleFunc("/plans/{planId}/{branch}/apply", handlers.ApplyPlanHandler).Methods("PATCH")
-	r.HandleFunc("/plans/{planId}/{branch}/archive", handlers.ArchivePlanHandler).Methods("PATCH")
-	r.HandleFunc("/plans/{planId}/{branch}/reject_all", handlers.RejectAllChangesHandler).Methods("PATCH")
-	r.HandleFunc("/plans/{planId}/{branch}/reject_file", handlers.RejectFileHandler).Methods("PATCH")
-
-	r.HandleFunc("/plans/{planId}/{branch}/context", handlers.ListContextHandler).Methods("GET")
-	r.HandleFunc("/plans/{planId}/{branch}/context", handlers.LoadContextHandler).Methods("POST")
-	r.HandleFunc("/plans/{planId}/{branch}/context", handlers.UpdateContextHandler).Methods("PUT")
-	r.HandleFunc("/plans/{planId}/{branch}/context", handlers.DeleteContextHandler).Methods("DELETE")
-
-	r.HandleFunc("/plans/{planId}/{branch}/convo", handlers.ListConvoHandler).Methods("GET")
-	r.HandleFunc("/plans/{planId}/{branch}/rewind", handlers.RewindPlanHandler).Methods("PATCH")
-	r.HandleFunc("/plans/{planId}/{branch}/logs", handlers.ListLogsHandler).Methods("GET")
+	return r
+}
 
-	r.HandleFunc("/plans/{planId}/branches", handlers.ListBranchesHandler).Methods("GET")
-	r.HandleFunc("/plans/{planId}/branches/{branch}", handlers.DeleteBranchHandler).Methods("DELETE")
-	r.HandleFunc("/plans/{planId}/{branch}/branches", handlers.CreateBranchHandler).Methods("POST")
+func openApiHandler(w http.ResponseWriter, r *http.Request) {
+	version := "unknown"
+	bytes, err := os.ReadFile("version.txt")
+	if err == nil {
+		version = strings.TrimSpace(string(bytes))
+	}
 
-	r.HandleFunc("/plans/{planId}/{branch}/settings", handlers.GetSettingsHandler).Methods("GET")
-	r.HandleFunc("/plans/{planId}/{branch}/settings", handlers.UpdateSettingsHandler).Methods("PUT")
+	doc := openapi.Generate(apiRoutes, apiV1Prefix, version)
 
-	return r
+	bytes, err = json.Marshal(doc)
+	if err != nil {
+		log.Printf("Error marshalling OpenAPI document: %v\n", err)
+		http.Error(w, "Error marshalling OpenAPI document: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bytes)
 }
@@ -0,0 +1,135 @@
+package sso
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// RoleMapping gives members of an identity provider group an org role
+type RoleMapping struct {
+	Group string
+	Role  string
+}
+
+type Config struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	// the server's /accounts/sso/callback url, as registered with the identity provider
+	RedirectUrl string
+	// name or id of the org that users who sign in with SSO are added to
+	Org string
+	// the ID token claim with the user's groups
+	GroupsClaim string
+	// checked in order, so the first group the user is in sets their role
+	RoleMappings []*RoleMapping
+	// role for users who aren't in any mapped group. If it's empty, they can't sign in.
+	DefaultRole string
+	// if set, only emails on these domains can sign in
+	AllowedDomains []string
+	// if set, signing in with an email pin and starting trials are turned off
+	Required bool
+}
+
+var config *Config
+
+// LoadConfig reads SSO settings from the environment. SSO is turned on by setting PLANDEX_OIDC_ISSUER.
+func LoadConfig() error {
+	issuer := os.Getenv("PLANDEX_OIDC_ISSUER")
+	if issuer == "" {
+		return nil
+	}
+
+	c := &Config{
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientId:     os.Getenv("PLANDEX_OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("PLANDEX_OIDC_CLIENT_SECRET"),
+		RedirectUrl:  os.Getenv("PLANDEX_OIDC_REDIRECT_URL"),
+		Org:          os.Getenv("PLANDEX_OIDC_ORG"),
+		GroupsClaim:  os.Getenv("PLANDEX_OIDC_GROUPS_CLAIM"),
+		DefaultRole:  os.Getenv("PLANDEX_OIDC_DEFAULT_ROLE"),
+		Required:     os.Getenv("PLANDEX_SSO_REQUIRED") != "",
+	}
+
+	if c.ClientId == "" || c.ClientSecret == "" || c.RedirectUrl == "" || c.Org == "" {
+		return fmt.Errorf("PLANDEX_OIDC_CLIENT_ID, PLANDEX_OIDC_CLIENT_SECRET, PLANDEX_OIDC_REDIRECT_URL, and PLANDEX_OIDC_ORG are required when PLANDEX_OIDC_ISSUER is set")
+	}
+
+	if c.GroupsClaim == "" {
+		c.GroupsClaim = "groups"
+	}
+
+	// e.g. "plandex-admins=admin,engineering=member"
+	mappings := os.Getenv("PLANDEX_OIDC_ROLE_MAPPING")
+	for _, mapping := range strings.Split(mappings, ",") {
+		mapping = strings.TrimSpace(mapping)
+		if mapping == "" {
+			continue
+		}
+
+		group, role, found := strings.Cut(mapping, "=")
+		group = strings.TrimSpace(group)
+		role = strings.TrimSpace(role)
+		if !found || group == "" || role == "" {
+			return fmt.Errorf("invalid PLANDEX_OIDC_ROLE_MAPPING entry '%s'. Use group=role", mapping)
+		}
+
+		c.RoleMappings = append(c.RoleMappings, &RoleMapping{Group: group, Role: role})
+	}
+
+	if len(c.RoleMappings) == 0 && c.DefaultRole == "" {
+		return fmt.Errorf("PLANDEX_OIDC_ROLE_MAPPING or PLANDEX_OIDC_DEFAULT_ROLE must be set so users who sign in with SSO get a role")
+	}
+
+	for _, domain := range strings.Split(os.Getenv("PLANDEX_OIDC_ALLOWED_DOMAINS"), ",") {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" {
+			c.AllowedDomains = append(c.AllowedDomains, domain)
+		}
+	}
+
+	config = c
+
+	return nil
+}
+
+func Enabled() bool {
+	return config != nil
+}
+
+func Required() bool {
+	return config != nil && config.Required
+}
+
+func GetConfig() *Config {
+	return config
+}
+
+// RoleFor returns the org role for a user in the given groups. fromGroup is false if the role is the default role, and
+// role is empty if the user shouldn't be let in.
+func (c *Config) RoleFor(groups []string) (role string, fromGroup bool) {
+	for _, mapping := range c.RoleMappings {
+		for _, group := range groups {
+			if group == mapping.Group {
+				return mapping.Role, true
+			}
+		}
+	}
+
+	return c.DefaultRole, false
+}
+
+func (c *Config) DomainAllowed(domain string) bool {
+	if len(c.AllowedDomains) == 0 {
+		return true
+	}
+
+	for _, allowed := range c.AllowedDomains {
+		if domain == allowed {
+			return true
+		}
+	}
+
+	return false
+}
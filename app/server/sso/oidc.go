@@ -0,0 +1,329 @@
+package sso
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// allowed difference between our clock and the identity provider's when checking token times
+const clockSkew = 2 * time.Minute
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// Identity is who the identity provider says signed in
+type Identity struct {
+	Subject string
+	Email   string
+	Name    string
+	Groups  []string
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+var mu sync.Mutex
+var provider *discovery
+var keys map[string]*rsa.PublicKey
+
+// AuthUrl returns the identity provider url that starts signing in. The code verifier is kept by the caller and passed to
+// Exchange when the provider redirects back (PKCE).
+func AuthUrl(state, nonce, codeVerifier string) (string, error) {
+	p, err := getProvider()
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", config.ClientId)
+	query.Set("redirect_uri", config.RedirectUrl)
+	query.Set("scope", "openid email profile")
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return p.AuthorizationEndpoint + sep + query.Encode(), nil
+}
+
+// Exchange trades an authorization code for an ID token and returns the identity in it, once the token is verified
+func Exchange(code, codeVerifier, nonce string) (*Identity, error) {
+	p, err := getProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", config.RedirectUrl)
+	form.Set("client_id", config.ClientId)
+	form.Set("client_secret", config.ClientSecret)
+	form.Set("code_verifier", codeVerifier)
+
+	resp, err := client.PostForm(p.TokenEndpoint, form)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading token response: %v", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokenRes struct {
+		IdToken string `json:"id_token"`
+	}
+	err = json.Unmarshal(body, &tokenRes)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling token response: %v", err)
+	}
+
+	if tokenRes.IdToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+
+	claims, err := verifyIdToken(p, tokenRes.IdToken, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %v", err)
+	}
+
+	return identityFromClaims(claims)
+}
+
+func verifyIdToken(p *discovery, idToken, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding header: %v", err)
+	}
+
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported signing algorithm %s", header.Alg)
+	}
+
+	key, err := getKey(p, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("error decoding signature: %v", err)
+	}
+
+	hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sig)
+	if err != nil {
+		return nil, fmt.Errorf("bad signature")
+	}
+
+	var claims map[string]interface{}
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding claims: %v", err)
+	}
+
+	if iss, _ := claims["iss"].(string); iss != p.Issuer {
+		return nil, fmt.Errorf("issuer is %s, expected %s", iss, p.Issuer)
+	}
+
+	audOk := false
+	switch aud := claims["aud"].(type) {
+	case string:
+		audOk = aud == config.ClientId
+	case []interface{}:
+		for _, a := range aud {
+			if a == config.ClientId {
+				audOk = true
+				break
+			}
+		}
+	}
+	if !audOk {
+		return nil, fmt.Errorf("token wasn't issued for this client")
+	}
+
+	exp, _ := claims["exp"].(float64)
+	if time.Unix(int64(exp), 0).Add(clockSkew).Before(time.Now()) {
+		return nil, fmt.Errorf("token is expired")
+	}
+
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, fmt.Errorf("nonce doesn't match")
+	}
+
+	return claims, nil
+}
+
+func identityFromClaims(claims map[string]interface{}) (*Identity, error) {
+	identity := &Identity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Name, _ = claims["name"].(string)
+	identity.Email, _ = claims["email"].(string)
+
+	// Azure AD only includes email as an optional claim, but the username is usually the email
+	if identity.Email == "" {
+		for _, claim := range []string{"preferred_username", "upn"} {
+			if v, _ := claims[claim].(string); strings.Contains(v, "@") {
+				identity.Email = v
+				break
+			}
+		}
+	}
+
+	if identity.Email == "" {
+		return nil, fmt.Errorf("identity provider didn't return an email. Make sure the 'email' scope is allowed for this client")
+	}
+	identity.Email = strings.ToLower(identity.Email)
+
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		return nil, fmt.Errorf("email %s isn't verified with the identity provider", identity.Email)
+	}
+
+	switch groups := claims[config.GroupsClaim].(type) {
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				identity.Groups = append(identity.Groups, s)
+			}
+		}
+	case string:
+		identity.Groups = []string{groups}
+	}
+
+	return identity, nil
+}
+
+func getProvider() (*discovery, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if provider != nil {
+		return provider, nil
+	}
+
+	var p discovery
+	err := getJson(config.Issuer+"/.well-known/openid-configuration", &p)
+	if err != nil {
+		return nil, fmt.Errorf("error getting OIDC discovery document: %v", err)
+	}
+
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JwksUri == "" {
+		return nil, fmt.Errorf("OIDC discovery document is missing endpoints")
+	}
+
+	provider = &p
+	return provider, nil
+}
+
+// getKey returns the provider's signing key with the given id. Keys are refetched when the id isn't known, since providers
+// rotate them.
+func getKey(p *discovery, kid string) (*rsa.PublicKey, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	err := getJson(p.JwksUri, &jwks)
+	if err != nil {
+		return nil, fmt.Errorf("error getting signing keys: %v", err)
+	}
+
+	keys = map[string]*rsa.PublicKey{}
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("no signing key with id %s", kid)
+	}
+
+	return key, nil
+}
+
+func getJson(u string, v interface{}) error {
+	resp, err := client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %d", u, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	bytes, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, v)
+}
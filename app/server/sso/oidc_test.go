@@ -0,0 +1,265 @@
+package sso
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testIssuer = "https://idp.example.com"
+const testClientId = "plandex"
+const testNonce = "nonce-1"
+
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	return key
+}
+
+func signTestToken(t *testing.T, key *rsa.PrivateKey, header, claims map[string]interface{}) string {
+	encode := func(v interface{}) string {
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("error marshalling: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(bytes)
+	}
+
+	signed := encode(header) + "." + encode(claims)
+	hashed := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatalf("error signing: %v", err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":   testIssuer,
+		"aud":   testClientId,
+		"sub":   "user-1",
+		"email": "Dev@Example.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": testNonce,
+	}
+}
+
+// setupTestProvider serves the key as the provider's JWKS, so verifyIdToken fetches it the same way it does in production
+func setupTestProvider(t *testing.T, key *rsa.PrivateKey) *discovery {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key-1",
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	prevConfig, prevKeys := config, keys
+	t.Cleanup(func() {
+		config, keys = prevConfig, prevKeys
+	})
+	config = &Config{Issuer: testIssuer, ClientId: testClientId, GroupsClaim: "groups"}
+	keys = nil
+
+	return &discovery{Issuer: testIssuer, JwksUri: server.URL}
+}
+
+func TestVerifyIdToken(t *testing.T) {
+	key := newTestKey(t)
+	otherKey := newTestKey(t)
+	header := map[string]interface{}{"alg": "RS256", "kid": "key-1"}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			token: func() string {
+				return signTestToken(t, key, header, validClaims())
+			},
+		},
+		{
+			name: "audience list with the client",
+			token: func() string {
+				claims := validClaims()
+				claims["aud"] = []string{"other", testClientId}
+				return signTestToken(t, key, header, claims)
+			},
+		},
+		{
+			name: "expired within clock skew",
+			token: func() string {
+				claims := validClaims()
+				claims["exp"] = time.Now().Add(-time.Minute).Unix()
+				return signTestToken(t, key, header, claims)
+			},
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := validClaims()
+				claims["iss"] = "https://attacker.example.com"
+				return signTestToken(t, key, header, claims)
+			},
+			wantErr: "issuer",
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := validClaims()
+				claims["aud"] = "other"
+				return signTestToken(t, key, header, claims)
+			},
+			wantErr: "wasn't issued for this client",
+		},
+		{
+			name: "audience list without the client",
+			token: func() string {
+				claims := validClaims()
+				claims["aud"] = []string{"other"}
+				return signTestToken(t, key, header, claims)
+			},
+			wantErr: "wasn't issued for this client",
+		},
+		{
+			name: "expired",
+			token: func() string {
+				claims := validClaims()
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+				return signTestToken(t, key, header, claims)
+			},
+			wantErr: "expired",
+		},
+		{
+			name: "no expiry",
+			token: func() string {
+				claims := validClaims()
+				delete(claims, "exp")
+				return signTestToken(t, key, header, claims)
+			},
+			wantErr: "expired",
+		},
+		{
+			name: "wrong nonce",
+			token: func() string {
+				claims := validClaims()
+				claims["nonce"] = "nonce-2"
+				return signTestToken(t, key, header, claims)
+			},
+			wantErr: "nonce",
+		},
+		{
+			name: "signed with another key",
+			token: func() string {
+				return signTestToken(t, otherKey, header, validClaims())
+			},
+			wantErr: "bad signature",
+		},
+		{
+			name: "claims changed after signing",
+			token: func() string {
+				parts := strings.Split(signTestToken(t, key, header, validClaims()), ".")
+				claims := validClaims()
+				claims["email"] = "admin@example.com"
+				bytes, _ := json.Marshal(claims)
+				parts[1] = base64.RawURLEncoding.EncodeToString(bytes)
+				return strings.Join(parts, ".")
+			},
+			wantErr: "bad signature",
+		},
+		{
+			name: "unsigned",
+			token: func() string {
+				parts := strings.Split(signTestToken(t, key, map[string]interface{}{"alg": "none", "kid": "key-1"}, validClaims()), ".")
+				return parts[0] + "." + parts[1] + "."
+			},
+			wantErr: "unsupported signing algorithm",
+		},
+		{
+			name: "unknown key id",
+			token: func() string {
+				return signTestToken(t, key, map[string]interface{}{"alg": "RS256", "kid": "key-2"}, validClaims())
+			},
+			wantErr: "no signing key",
+		},
+		{
+			name: "malformed",
+			token: func() string {
+				return "not-a-token"
+			},
+			wantErr: "malformed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := setupTestProvider(t, key)
+
+			claims, err := verifyIdToken(p, tt.token(), testNonce)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected token to verify, got %v", err)
+				}
+				if claims["sub"] != "user-1" {
+					t.Errorf("expected sub user-1, got %v", claims["sub"])
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected error containing %q, token verified", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestIdentityFromClaims(t *testing.T) {
+	prevConfig := config
+	t.Cleanup(func() {
+		config = prevConfig
+	})
+	config = &Config{GroupsClaim: "groups"}
+
+	claims := validClaims()
+	claims["groups"] = []interface{}{"eng", "admins"}
+	identity, err := identityFromClaims(claims)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity.Email != "dev@example.com" {
+		t.Errorf("expected lowercased email, got %s", identity.Email)
+	}
+	if len(identity.Groups) != 2 || identity.Groups[1] != "admins" {
+		t.Errorf("unexpected groups %v", identity.Groups)
+	}
+
+	claims = validClaims()
+	claims["email_verified"] = false
+	_, err = identityFromClaims(claims)
+	if err == nil {
+		t.Error("expected an unverified email to be rejected")
+	}
+}
@@ -0,0 +1,211 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const exportInterval = 5 * time.Second
+const maxBatchSize = 512
+
+// spans are dropped rather than slowing down requests if the collector can't keep up
+const maxQueuedSpans = 4096
+
+type exporter struct {
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+
+	mu      sync.Mutex
+	queue   []*otlpSpan
+	dropped int
+
+	// one export at a time, so a flush on shutdown doesn't race the background export
+	exportMu sync.Mutex
+}
+
+func newExporter(endpoint string, headers map[string]string, serviceName string) *exporter {
+	return &exporter{
+		endpoint:    endpoint,
+		headers:     headers,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (e *exporter) run() {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		e.flush()
+	}
+}
+
+func (e *exporter) add(s *Span, end time.Time) {
+	s.mu.Lock()
+	span := &otlpSpan{
+		TraceId:           hex.EncodeToString(s.traceId[:]),
+		SpanId:            hex.EncodeToString(s.spanId[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(end.UnixNano(), 10),
+		Attributes:        otlpAttributes(s.attrs),
+	}
+	if s.parentSpanId != [8]byte{} {
+		span.ParentSpanId = hex.EncodeToString(s.parentSpanId[:])
+	}
+	if s.errorMsg != "" {
+		span.Status = &otlpStatus{Code: statusCodeError, Message: s.errorMsg}
+	}
+	s.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.queue) >= maxQueuedSpans {
+		e.dropped++
+		return
+	}
+	e.queue = append(e.queue, span)
+}
+
+func (e *exporter) flush() {
+	e.exportMu.Lock()
+	defer e.exportMu.Unlock()
+
+	for {
+		e.mu.Lock()
+		if e.dropped > 0 {
+			log.Printf("Dropped %d trace spans, the OTLP endpoint isn't keeping up\n", e.dropped)
+			e.dropped = 0
+		}
+		n := min(len(e.queue), maxBatchSize)
+		batch := e.queue[:n]
+		e.queue = e.queue[n:]
+		e.mu.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+
+		err := e.export(batch)
+		if err != nil {
+			log.Printf("Error exporting %d trace spans: %v\n", len(batch), err)
+			return
+		}
+	}
+}
+
+func (e *exporter) export(spans []*otlpSpan) error {
+	body, err := json.Marshal(otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: otlpAttributes([]Attr{String("service.name", e.serviceName)})},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "plandex-server"},
+				Spans: spans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("OTLP endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// the OTLP JSON encoding: ids are hex, and 64-bit integers are strings
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope   `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func otlpAttributes(attrs []Attr) []otlpAttribute {
+	var res []otlpAttribute
+	for _, attr := range attrs {
+		var value otlpAnyValue
+		switch v := attr.Value.(type) {
+		case int:
+			str := strconv.Itoa(v)
+			value.IntValue = &str
+		case string:
+			value.StringValue = &v
+		default:
+			continue
+		}
+		res = append(res, otlpAttribute{Key: attr.Key, Value: value})
+	}
+	return res
+}
@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExportPayload(t *testing.T) {
+	var gotBody []byte
+	var gotHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeaders = r.Header
+	}))
+	defer server.Close()
+
+	e := newExporter(server.URL, map[string]string{"x-api-key": "secret"}, "plandex-test")
+
+	start := time.Unix(1717000000, 123456789)
+
+	parent := &Span{
+		traceId: [16]byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		spanId:  [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		name:    "POST /plans/{planId}/tell",
+		kind:    spanKindServer,
+		start:   start,
+		attrs:   []Attr{String("http.method", "POST"), Int("http.status_code", 500)},
+	}
+	parent.SetError(errors.New("model unavailable"))
+
+	child := &Span{
+		traceId:      parent.traceId,
+		spanId:       [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
+		parentSpanId: parent.spanId,
+		name:         "model call",
+		kind:         spanKindInternal,
+		start:        start.Add(time.Millisecond),
+		// only string and int attributes are exported
+		attrs: []Attr{String("model", "gpt-4o"), {Key: "ignored", Value: 1.5}},
+	}
+
+	e.add(child, start.Add(2*time.Millisecond))
+	e.add(parent, start.Add(3*time.Millisecond))
+	e.flush()
+
+	want := `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"plandex-test"}}]},` +
+		`"scopeSpans":[{"scope":{"name":"plandex-server"},"spans":[` +
+		`{"traceId":"4bf92f3577b34da6a3ce929d0e0e4736","spanId":"0102030405060708","parentSpanId":"00f067aa0ba902b7",` +
+		`"name":"model call","kind":1,"startTimeUnixNano":"1717000000124456789","endTimeUnixNano":"1717000000125456789",` +
+		`"attributes":[{"key":"model","value":{"stringValue":"gpt-4o"}}]},` +
+		`{"traceId":"4bf92f3577b34da6a3ce929d0e0e4736","spanId":"00f067aa0ba902b7",` +
+		`"name":"POST /plans/{planId}/tell","kind":2,"startTimeUnixNano":"1717000000123456789","endTimeUnixNano":"1717000000126456789",` +
+		`"attributes":[{"key":"http.method","value":{"stringValue":"POST"}},{"key":"http.status_code","value":{"intValue":"500"}}],` +
+		`"status":{"code":2,"message":"model unavailable"}}` +
+		`]}]}]}`
+
+	if string(gotBody) != want {
+		t.Errorf("got\n%s\nwant\n%s", gotBody, want)
+	}
+	if gotHeaders.Get("Content-Type") != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %q", gotHeaders.Get("Content-Type"))
+	}
+	if gotHeaders.Get("x-api-key") != "secret" {
+		t.Errorf("expected the configured headers to be sent, got %v", gotHeaders)
+	}
+
+	// the queue is empty once it's flushed, so nothing is sent twice
+	gotBody = nil
+	e.flush()
+	if gotBody != nil {
+		t.Errorf("expected no export after the queue was flushed, got %s", gotBody)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	traceId, spanId, ok := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok {
+		t.Fatal("expected a valid traceparent")
+	}
+	if traceId != [16]byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36} {
+		t.Errorf("unexpected trace id %x", traceId)
+	}
+	if spanId != [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7} {
+		t.Errorf("unexpected span id %x", spanId)
+	}
+
+	for _, header := range []string{"", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "00-xyz-00f067aa0ba902b7-01"} {
+		if _, _, ok := parseTraceparent(header); ok {
+			t.Errorf("expected %q to be rejected", header)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OTLP span kinds and status codes
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+
+	statusCodeError = 2
+)
+
+var exp *exporter
+
+type spanContextKey struct{}
+
+// Init turns on tracing if an OTLP endpoint is set with the standard OpenTelemetry environment variables. Spans are exported
+// as OTLP over HTTP with JSON encoding, which OpenTelemetry collectors and most tracing backends accept. Without an endpoint,
+// spans are no-ops.
+func Init() error {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" {
+			return nil
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+	if protocol != "" && protocol != "http/json" {
+		return fmt.Errorf("OTLP protocol %s isn't supported, use http/json", protocol)
+	}
+
+	headers, err := parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	if err != nil {
+		return err
+	}
+
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "plandex-server"
+	}
+
+	exp = newExporter(endpoint, headers, serviceName)
+	go exp.run()
+
+	return nil
+}
+
+// Flush sends the spans that haven't been exported yet, for shutting down
+func Flush() {
+	if exp != nil {
+		exp.flush()
+	}
+}
+
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key, value string) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+func Int(key string, value int) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Span is one timed operation in a trace. A nil span, which is what Start returns when tracing is off, ignores every call.
+type Span struct {
+	traceId      [16]byte
+	spanId       [8]byte
+	parentSpanId [8]byte
+	name         string
+	kind         int
+	start        time.Time
+
+	mu       sync.Mutex
+	attrs    []Attr
+	errorMsg string
+	ended    bool
+}
+
+// Start starts a span that's a child of the span in ctx, if there is one. The returned context carries the new span.
+func Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	if exp == nil {
+		return ctx, nil
+	}
+
+	span := &Span{name: name, kind: spanKindInternal, start: time.Now(), attrs: attrs}
+	if parent, ok := ctx.Value(spanContextKey{}).(*Span); ok && parent != nil {
+		span.traceId = parent.traceId
+		span.parentSpanId = parent.spanId
+	} else {
+		rand.Read(span.traceId[:])
+	}
+	rand.Read(span.spanId[:])
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// StartServer starts a span for an incoming request, continuing the caller's trace if it sent a W3C traceparent header
+func StartServer(r *http.Request, name string, attrs ...Attr) (context.Context, *Span) {
+	ctx, span := Start(r.Context(), name, attrs...)
+	if span == nil {
+		return ctx, nil
+	}
+	span.kind = spanKindServer
+
+	traceId, parentSpanId, ok := parseTraceparent(r.Header.Get("traceparent"))
+	if ok {
+		span.traceId = traceId
+		span.parentSpanId = parentSpanId
+	}
+
+	return ctx, span
+}
+
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// SetError marks the span as failed
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errorMsg = err.Error()
+}
+
+// End finishes the span and queues it for export. Only the first call counts.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.mu.Unlock()
+
+	exp.add(s, time.Now())
+}
+
+// traceparent is version-traceid-parentid-flags, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func parseTraceparent(header string) (traceId [16]byte, spanId [8]byte, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return traceId, spanId, false
+	}
+
+	traceBytes, err := hex.DecodeString(parts[1])
+	if err != nil || len(traceBytes) != 16 {
+		return traceId, spanId, false
+	}
+	spanBytes, err := hex.DecodeString(parts[2])
+	if err != nil || len(spanBytes) != 8 {
+		return traceId, spanId, false
+	}
+
+	copy(traceId[:], traceBytes)
+	copy(spanId[:], spanBytes)
+
+	if traceId == [16]byte{} || spanId == [8]byte{} {
+		return traceId, spanId, false
+	}
+
+	return traceId, spanId, true
+}
+
+// headers are comma-separated key=value pairs with url-encoded values, like OTEL_EXPORTER_OTLP_HEADERS for other OpenTelemetry
+// exporters
+func parseHeaders(str string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(str, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS entry: %s", pair)
+		}
+
+		unescaped, err := url.PathUnescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS value for %s: %v", key, err)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(unescaped)
+	}
+	return headers, nil
+}
@@ -66,9 +66,21 @@ type ActivePlan struct {
 	AllowOverwritePaths     map[string]bool
 	SkippedPaths            map[string]bool
 	StoredReplyIds          []string
-	streamCh                chan string
-	subscriptions           map[string]*subscription
-	subscriptionMu          sync.Mutex
+	// chat channels notified when the plan finishes
+	NotifyTargets  []*shared.NotifyTarget
+	streamCh       chan string
+	subscriptions  map[string]*subscription
+	subscriptionMu sync.Mutex
+
+	// every message streamed so far, in order, so a client whose connection drops can resume where it left off. A message's
+	// Seq is its index here plus 1.
+	streamLog []string
+	streamMu  sync.Mutex
+	lastSeq   int
+
+	// limits how many files build at once, see AcquireBuildSlot
+	buildSlots   chan struct{}
+	buildSlotsMu sync.Mutex
 }
 
 func NewActivePlan(planId, branch, prompt string, buildOnly bool) *ActivePlan {
@@ -117,13 +129,13 @@ func NewActivePlan(planId, branch, prompt string, buildOnly bool) *ActivePlan {
 			case <-active.Ctx.Done():
 				return
 			case msg := <-active.streamCh:
-				var subscriptions map[string]*subscription
+				// logging and sending under the lock means a subscriber that resumes gets each message exactly once
 				active.subscriptionMu.Lock()
-				subscriptions = active.subscriptions
-				active.subscriptionMu.Unlock()
-				for _, sub := range subscriptions {
+				active.streamLog = append(active.streamLog, msg)
+				for _, sub := range active.subscriptions {
 					sub.enqueueMessage(msg)
 				}
+				active.subscriptionMu.Unlock()
 
 			}
 		}
@@ -133,8 +145,13 @@ func NewActivePlan(planId, branch, prompt string, buildOnly bool) *ActivePlan {
 }
 
 func (ap *ActivePlan) Stream(msg shared.StreamMessage) {
+	// messages are streamed from more than one goroutine, like for concurrent builds, so numbering and sending them happens
+	// together to keep the numbers in order
+	ap.streamMu.Lock()
+	msg.Seq = ap.lastSeq + 1
 	msgJson, err := json.Marshal(msg)
 	if err != nil {
+		ap.streamMu.Unlock()
 		ap.StreamDoneCh <- &shared.ApiError{
 			Type:   shared.ApiErrorTypeOther,
 			Status: http.StatusInternalServerError,
@@ -142,10 +159,12 @@ func (ap *ActivePlan) Stream(msg shared.StreamMessage) {
 		}
 		return
 	}
+	ap.lastSeq = msg.Seq
 
 	// log.Printf("ActivePlan: sending stream message: %s\n", string(msgJson))
 
 	ap.streamCh <- string(msgJson)
+	ap.streamMu.Unlock()
 
 	if msg.Type == shared.StreamMessageFinished {
 		// Wait briefly allow last stream message to be sent
@@ -185,6 +204,22 @@ func (ap *ActivePlan) Subscribe() (string, chan string) {
 	return id, sub.ch
 }
 
+// SubscribeFrom subscribes to the stream starting with the messages after seq, for a client that's resuming after its
+// connection dropped
+func (ap *ActivePlan) SubscribeFrom(seq int) (string, chan string) {
+	ap.subscriptionMu.Lock()
+	defer ap.subscriptionMu.Unlock()
+	id := uuid.New().String()
+	sub := newSubscription()
+	if seq < len(ap.streamLog) {
+		for _, msg := range ap.streamLog[seq:] {
+			sub.enqueueMessage(msg)
+		}
+	}
+	ap.subscriptions[id] = sub
+	return id, sub.ch
+}
+
 func (ap *ActivePlan) Unsubscribe(id string) {
 	ap.subscriptionMu.Lock()
 	defer ap.subscriptionMu.Unlock()
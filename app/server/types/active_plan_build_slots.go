@@ -0,0 +1,30 @@
+package types
+
+// AcquireBuildSlot waits until fewer than maxParallel files are building. It returns false if the plan is stopped while
+// waiting.
+func (ap *ActivePlan) AcquireBuildSlot(maxParallel int) bool {
+	ap.buildSlotsMu.Lock()
+	if ap.buildSlots == nil {
+		ap.buildSlots = make(chan struct{}, maxParallel)
+	}
+	slots := ap.buildSlots
+	ap.buildSlotsMu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-ap.Ctx.Done():
+		return false
+	}
+}
+
+func (ap *ActivePlan) ReleaseBuildSlot() {
+	ap.buildSlotsMu.Lock()
+	slots := ap.buildSlots
+	ap.buildSlotsMu.Unlock()
+
+	select {
+	case <-slots:
+	default:
+	}
+}
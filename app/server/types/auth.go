@@ -6,10 +6,13 @@ import (
 )
 
 type ServerAuth struct {
+	// nil if the request was authenticated with an API key
 	AuthToken   *db.AuthToken
 	User        *db.User
 	OrgId       string
 	Permissions map[Permission]bool
+	// set if the request was authenticated with an API key
+	ApiKey *db.ApiKey
 }
 
 func (a *ServerAuth) HasPermission(permission Permission) bool {
@@ -46,4 +49,6 @@ const (
 	PermissionDeleteAnyPlan         Permission = "delete_any_plan"
 	PermissionUpdateAnyPlan         Permission = "update_any_plan"
 	PermissionArchiveAnyPlan        Permission = "archive_any_plan"
+	PermissionManageQuotas          Permission = "manage_quotas"
+	PermissionViewOrgUsage          Permission = "view_org_usage"
 )
@@ -10,6 +10,12 @@ var AvailableModels = []BaseModelConfig{
 		ModelName: openai.GPT4TurboPreview,
 		MaxTokens: 128000,
 	},
+	{
+		Provider:        ModelProviderOpenAI,
+		ModelName:       GPT4o,
+		MaxTokens:       128000,
+		HasImageSupport: true,
+	},
 	{
 		Provider:  ModelProviderOpenAI,
 		ModelName: openai.GPT4Turbo0125,
@@ -20,6 +26,12 @@ var AvailableModels = []BaseModelConfig{
 		ModelName: openai.GPT4Turbo1106,
 		MaxTokens: 128000,
 	},
+	{
+		Provider:        ModelProviderOpenAI,
+		ModelName:       openai.GPT4VisionPreview,
+		MaxTokens:       128000,
+		HasImageSupport: true,
+	},
 	{
 		Provider:  ModelProviderOpenAI,
 		ModelName: openai.GPT4,
@@ -40,13 +52,46 @@ var AvailableModels = []BaseModelConfig{
 		ModelName: openai.GPT3Dot5Turbo1106,
 		MaxTokens: 16385,
 	},
+	{
+		Provider:        ModelProviderBedrock,
+		ModelName:       BedrockClaude3Sonnet,
+		MaxTokens:       200000,
+		HasImageSupport: true,
+	},
+	{
+		Provider:        ModelProviderBedrock,
+		ModelName:       BedrockClaude3Haiku,
+		MaxTokens:       200000,
+		HasImageSupport: true,
+	},
+	{
+		Provider:  ModelProviderBedrock,
+		ModelName: BedrockClaude35Haiku,
+		MaxTokens: 200000,
+	},
 }
 
+// OpenAI model ids that go-openai doesn't have constants for yet
+const (
+	GPT4o = "gpt-4o"
+)
+
+// Bedrock model ids
+const (
+	BedrockClaude3Sonnet = "anthropic.claude-3-sonnet-20240229-v1:0"
+	BedrockClaude3Haiku  = "anthropic.claude-3-haiku-20240307-v1:0"
+	BedrockClaude35Haiku = "anthropic.claude-3-5-haiku-20241022-v1:0"
+)
+
 var PlannerModelConfigByName = map[string]PlannerModelConfig{
 	openai.GPT4TurboPreview: {
 		MaxConvoTokens:       10000,
 		ReservedOutputTokens: 4096,
 	},
+	GPT4o: {
+		MaxConvoTokens:       10000,
+		ReservedOutputTokens: 4096,
+	},
 	openai.GPT4Turbo0125: {
 		MaxConvoTokens:       10000,
 		ReservedOutputTokens: 4096,
@@ -55,6 +100,10 @@ var PlannerModelConfigByName = map[string]PlannerModelConfig{
 		MaxConvoTokens:       10000,
 		ReservedOutputTokens: 4096,
 	},
+	openai.GPT4VisionPreview: {
+		MaxConvoTokens:       10000,
+		ReservedOutputTokens: 4096,
+	},
 	openai.GPT4: {
 		MaxConvoTokens:       2500,
 		ReservedOutputTokens: 1000,
@@ -71,18 +120,36 @@ var PlannerModelConfigByName = map[string]PlannerModelConfig{
 		MaxConvoTokens:       5000,
 		ReservedOutputTokens: 2000,
 	},
+	BedrockClaude3Sonnet: {
+		MaxConvoTokens:       10000,
+		ReservedOutputTokens: 4096,
+	},
+	BedrockClaude3Haiku: {
+		MaxConvoTokens:       10000,
+		ReservedOutputTokens: 4096,
+	},
+	BedrockClaude35Haiku: {
+		MaxConvoTokens:       10000,
+		ReservedOutputTokens: 4096,
+	},
 }
 
 var TaskModelConfigByName = map[string]TaskModelConfig{
 	openai.GPT4TurboPreview: {
 		OpenAIResponseFormat: &openai.ChatCompletionResponseFormat{Type: "json_object"},
 	},
+	GPT4o: {
+		OpenAIResponseFormat: &openai.ChatCompletionResponseFormat{Type: "json_object"},
+	},
 	openai.GPT4Turbo0125: {
 		OpenAIResponseFormat: &openai.ChatCompletionResponseFormat{Type: "json_object"},
 	},
 	openai.GPT4Turbo1106: {
 		OpenAIResponseFormat: &openai.ChatCompletionResponseFormat{Type: "json_object"},
 	},
+	openai.GPT4VisionPreview: {
+		OpenAIResponseFormat: nil,
+	},
 	openai.GPT4: {
 		OpenAIResponseFormat: nil,
 	},
@@ -95,6 +162,66 @@ var TaskModelConfigByName = map[string]TaskModelConfig{
 	openai.GPT3Dot5Turbo1106: {
 		OpenAIResponseFormat: &openai.ChatCompletionResponseFormat{Type: "json_object"},
 	},
+	BedrockClaude3Sonnet: {
+		OpenAIResponseFormat: nil,
+	},
+	BedrockClaude3Haiku: {
+		OpenAIResponseFormat: nil,
+	},
+	BedrockClaude35Haiku: {
+		OpenAIResponseFormat: nil,
+	},
+}
+
+// ModelPricing is a model's cost in US dollars per million tokens
+type ModelPricing struct {
+	Input  float64
+	Output float64
+	// for models with prompt caching, the price of input tokens that are read from the cache; 0 if the model doesn't cache prompts
+	CachedInput float64
+}
+
+var ModelPricingByName = map[string]ModelPricing{
+	openai.GPT4TurboPreview:  {Input: 10, Output: 30},
+	GPT4o:                    {Input: 2.5, Output: 10, CachedInput: 1.25},
+	openai.GPT4Turbo0125:     {Input: 10, Output: 30},
+	openai.GPT4Turbo1106:     {Input: 10, Output: 30},
+	openai.GPT4VisionPreview: {Input: 10, Output: 30},
+	openai.GPT4:              {Input: 30, Output: 60},
+	openai.GPT3Dot5Turbo:     {Input: 0.5, Output: 1.5},
+	openai.GPT3Dot5Turbo0125: {Input: 0.5, Output: 1.5},
+	openai.GPT3Dot5Turbo1106: {Input: 1, Output: 2},
+	BedrockClaude3Sonnet:     {Input: 3, Output: 15},
+	BedrockClaude3Haiku:      {Input: 0.25, Output: 1.25},
+	BedrockClaude35Haiku:     {Input: 0.8, Output: 4, CachedInput: 0.08},
+}
+
+// GetModelCost returns the cost in US dollars of a model call, or 0 if the model's pricing isn't known. cachedInputTokens is
+// the part of inputTokens that was read from the model's prompt cache.
+func GetModelCost(modelName string, inputTokens, cachedInputTokens, outputTokens int) float64 {
+	pricing, ok := ModelPricingByName[modelName]
+	if !ok {
+		return 0
+	}
+	if pricing.CachedInput == 0 {
+		cachedInputTokens = 0
+	}
+	return (float64(inputTokens-cachedInputTokens)*pricing.Input + float64(cachedInputTokens)*pricing.CachedInput + float64(outputTokens)*pricing.Output) / 1_000_000
+}
+
+// GetPromptCacheSavings returns how much less in US dollars cached input tokens cost than they would have without the cache
+func GetPromptCacheSavings(modelName string, cachedInputTokens int) float64 {
+	pricing := ModelPricingByName[modelName]
+	if pricing.CachedInput == 0 {
+		return 0
+	}
+	return float64(cachedInputTokens) * (pricing.Input - pricing.CachedInput) / 1_000_000
+}
+
+// ModelHasPromptCaching is true for models that bill repeated prompt prefixes at a lower price. OpenAI caches prefixes on its
+// own; Anthropic only caches up to the cache_control breakpoints in a request, which the server's Bedrock transport adds.
+func ModelHasPromptCaching(modelName string) bool {
+	return ModelPricingByName[modelName].CachedInput > 0
 }
 
 var AvailableModelsByName = map[string]BaseModelConfig{}
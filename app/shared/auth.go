@@ -5,6 +5,10 @@ type AuthHeader struct {
 	OrgId string `json:"orgId"`
 }
 
+// RequestIdHeader identifies a request in both the CLI's and the server's logs. The CLI sends one with each request, and the
+// server makes one up if it's missing. Either way, the server sends it back.
+const RequestIdHeader = "X-Request-Id"
+
 type ApiErrorType string
 
 const (
@@ -15,6 +19,15 @@ const (
 
 	ApiErrorTypeContinueNoMessages ApiErrorType = "continue_no_messages"
 
+	// an org or user quota was reached
+	ApiErrorTypeRateLimited ApiErrorType = "rate_limited"
+
+	// set by the client when a request couldn't be sent, e.g. because the server is unreachable
+	ApiErrorTypeNetwork ApiErrorType = "network"
+
+	// every model provider for the planner is failing, so a prompt can't run until one is reachable again
+	ApiErrorTypeProviderUnavailable ApiErrorType = "provider_unavailable"
+
 	ApiErrorTypeOther ApiErrorType = "other"
 )
 
@@ -26,6 +39,15 @@ type TrialMessagesExceededError struct {
 	MaxReplies int `json:"maxMessages"`
 }
 
+type RateLimitError struct {
+	Quota QuotaType `json:"quota"`
+	// whether the org's quota or the user's quota was reached
+	ForUser bool `json:"forUser"`
+	Limit   int  `json:"limit"`
+	// how long until a retry could succeed. For concurrent streams, it's a suggested interval to check again.
+	RetryAfterSeconds int `json:"retryAfterSeconds"`
+}
+
 type ApiError struct {
 	Type   ApiErrorType `json:"type"`
 	Status int          `json:"status"`
@@ -36,4 +58,33 @@ type ApiError struct {
 
 	// only used for trial messages exceeded error
 	TrialMessagesExceededError *TrialMessagesExceededError `json:"trialMessagesExceededError,omitempty"`
+
+	// only used for rate limited error
+	RateLimitError *RateLimitError `json:"rateLimitError,omitempty"`
+}
+
+// API keys are sent as the bearer token as is, rather than as an encoded AuthHeader. Each key belongs to one org and acts as
+// the user who created it, limited by its scope.
+const ApiKeyPrefix = "pdx_"
+
+type ApiKeyScope string
+
+const (
+	// GET requests only
+	ApiKeyScopeRead ApiKeyScope = "read"
+	// everything on projects and plans except applying changes
+	ApiKeyScopeWrite ApiKeyScope = "write"
+	// everything on projects and plans
+	ApiKeyScopeApply ApiKeyScope = "apply"
+)
+
+var ApiKeyScopes = []ApiKeyScope{ApiKeyScopeRead, ApiKeyScopeWrite, ApiKeyScopeApply}
+
+func (s ApiKeyScope) Valid() bool {
+	for _, scope := range ApiKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
@@ -9,6 +9,9 @@ import (
 	"github.com/olekukonko/tablewriter"
 )
 
+// files loaded with --summarize are only summarized if they're larger than this
+const SummarizeContextMinTokens = 2000
+
 type ContextUpdateResult struct {
 	UpdatedContexts []*Context
 	TokenDiffsById  map[string]int
@@ -17,6 +20,8 @@ type ContextUpdateResult struct {
 	NumFiles        int
 	NumUrls         int
 	NumTrees        int
+	NumMaps         int
+	NumSchemas      int
 	MaxTokens       int
 }
 
@@ -39,6 +44,18 @@ func (c *Context) TypeAndIcon() (string, string) {
 	case ContextPipedDataType:
 		icon = "↔️ "
 		t = "piped"
+	case ContextImageType:
+		icon = "🖼️ "
+		t = "image"
+	case ContextMapType:
+		icon = "🗺️ "
+		t = "map"
+	case ContextDbSchemaType:
+		icon = "🛢️ "
+		t = "schema"
+	case ContextSpecType:
+		icon = "📐"
+		t = "spec"
 	}
 
 	return t, icon
@@ -78,15 +95,27 @@ func SummaryForLoadContext(contexts []*Context, tokensAdded, totalTokens int) st
 	var numFiles int
 	var numTrees int
 	var numUrls int
+	var numImages int
+	var numMaps int
+	var numSchemas int
+	var numSpecs int
 
 	for _, context := range contexts {
 		switch context.ContextType {
 		case ContextFileType:
 			numFiles++
+		case ContextImageType:
+			numImages++
 		case ContextURLType:
 			numUrls++
 		case ContextDirectoryTreeType:
 			numTrees++
+		case ContextMapType:
+			numMaps++
+		case ContextDbSchemaType:
+			numSchemas++
+		case ContextSpecType:
+			numSpecs++
 		case ContextNoteType:
 			hasNote = true
 		case ContextPipedDataType:
@@ -116,6 +145,34 @@ func SummaryForLoadContext(contexts []*Context, tokensAdded, totalTokens int) st
 		}
 		added = append(added, fmt.Sprintf("%d %s", numTrees, label))
 	}
+	if numMaps > 0 {
+		label := "code map"
+		if numMaps > 1 {
+			label = "code maps"
+		}
+		added = append(added, fmt.Sprintf("%d %s", numMaps, label))
+	}
+	if numSchemas > 0 {
+		label := "database schema"
+		if numSchemas > 1 {
+			label = "database schemas"
+		}
+		added = append(added, fmt.Sprintf("%d %s", numSchemas, label))
+	}
+	if numSpecs > 0 {
+		label := "API spec"
+		if numSpecs > 1 {
+			label = "API specs"
+		}
+		added = append(added, fmt.Sprintf("%d %s", numSpecs, label))
+	}
+	if numImages > 0 {
+		label := "image"
+		if numImages > 1 {
+			label = "images"
+		}
+		added = append(added, fmt.Sprintf("%d %s", numImages, label))
+	}
 	if numUrls > 0 {
 		label := "url"
 		if numUrls > 1 {
@@ -169,6 +226,38 @@ func TableForRemoveContext(contexts []*Context) string {
 	return tableString.String()
 }
 
+func SummaryForPinContext(contexts []*Context, pinned bool) string {
+	suffix := ""
+	if len(contexts) > 1 {
+		suffix = "s"
+	}
+
+	if pinned {
+		return fmt.Sprintf("📌 Pinned %d piece%s of context", len(contexts), suffix)
+	}
+	return fmt.Sprintf("Unpinned %d piece%s of context", len(contexts), suffix)
+}
+
+func TableForPinContext(contexts []*Context) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetHeader([]string{"Name", "Type", "🪙"})
+	table.SetAutoWrapText(false)
+
+	for _, context := range contexts {
+		t, icon := context.TypeAndIcon()
+		table.Append([]string{
+			" " + icon + " " + context.Name,
+			t,
+			strconv.Itoa(context.NumTokens),
+		})
+	}
+
+	table.Render()
+
+	return tableString.String()
+}
+
 func SummaryForRemoveContext(contexts []*Context, previousTotalTokens int) string {
 	removedTokens := 0
 
@@ -190,6 +279,8 @@ func SummaryForUpdateContext(updateRes *ContextUpdateResult) string {
 	numFiles := updateRes.NumFiles
 	numTrees := updateRes.NumTrees
 	numUrls := updateRes.NumUrls
+	numMaps := updateRes.NumMaps
+	numSchemas := updateRes.NumSchemas
 	tokensDiff := updateRes.TokensDiff
 	totalTokens := updateRes.TotalTokens
 
@@ -209,6 +300,20 @@ func SummaryForUpdateContext(updateRes *ContextUpdateResult) string {
 		}
 		toAdd = append(toAdd, fmt.Sprintf("%d tree%s", numTrees, postfix))
 	}
+	if numMaps > 0 {
+		postfix := "s"
+		if numMaps == 1 {
+			postfix = ""
+		}
+		toAdd = append(toAdd, fmt.Sprintf("%d map%s", numMaps, postfix))
+	}
+	if numSchemas > 0 {
+		postfix := "s"
+		if numSchemas == 1 {
+			postfix = ""
+		}
+		toAdd = append(toAdd, fmt.Sprintf("%d schema%s", numSchemas, postfix))
+	}
 	if numUrls > 0 {
 		postfix := "s"
 		if numUrls == 1 {
@@ -54,6 +54,53 @@ type Plan struct {
 	ArchivedAt      *time.Time `json:"archivedAt,omitempty"`
 	CreatedAt       time.Time  `json:"createdAt"`
 	UpdatedAt       time.Time  `json:"updatedAt"`
+	// set when listing plans to the requesting user's permission on a plan that's shared with them rather than their own
+	SharedPermission PlanPermission `json:"sharedPermission,omitempty"`
+}
+
+// PlanPermission is what a user can do with a plan that's shared with them. Each permission includes the ones before it.
+// A plan's owner, and org owners and admins, can do everything.
+type PlanPermission string
+
+const (
+	// read the plan: its conversation, context, changes, and history
+	PlanPermissionView PlanPermission = "view"
+	// send prompts, build, and update context and pending changes
+	PlanPermissionPrompt PlanPermission = "prompt"
+	// apply changes
+	PlanPermissionApply PlanPermission = "apply"
+)
+
+var PlanPermissions = []PlanPermission{PlanPermissionView, PlanPermissionPrompt, PlanPermissionApply}
+
+func (p PlanPermission) rank() int {
+	for i, permission := range PlanPermissions {
+		if p == permission {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (p PlanPermission) Valid() bool {
+	return p.rank() > 0
+}
+
+// Allows reports whether p includes required
+func (p PlanPermission) Allows(required PlanPermission) bool {
+	return p.Valid() && p.rank() >= required.rank()
+}
+
+// PlanShare grants a permission on a plan to a user in its org, or to everyone in the org if UserId is nil
+type PlanShare struct {
+	Id         string         `json:"id"`
+	PlanId     string         `json:"planId"`
+	UserId     *string        `json:"userId"`
+	UserEmail  string         `json:"userEmail,omitempty"`
+	UserName   string         `json:"userName,omitempty"`
+	Permission PlanPermission `json:"permission"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
 }
 
 type Branch struct {
@@ -79,6 +126,10 @@ const (
 	ContextNoteType          ContextType = "note"
 	ContextDirectoryTreeType ContextType = "directory tree"
 	ContextPipedDataType     ContextType = "piped data"
+	ContextImageType         ContextType = "image"
+	ContextMapType           ContextType = "map"
+	ContextDbSchemaType      ContextType = "db_schema"
+	ContextSpecType          ContextType = "spec"
 )
 
 type Context struct {
@@ -92,6 +143,19 @@ type Context struct {
 	NumTokens       int         `json:"numTokens"`
 	Body            string      `json:"body,omitempty"`
 	ForceSkipIgnore bool        `json:"forceSkipIgnore"`
+	MaxDepth        int         `json:"maxDepth,omitempty"`
+	PdfPages        string      `json:"pdfPages,omitempty"`
+	Rendered        bool        `json:"rendered,omitempty"`
+	Raw             bool        `json:"raw,omitempty"`
+	Summarized      bool        `json:"summarized,omitempty"`
+	ChunkPart       int         `json:"chunkPart,omitempty"`
+	ChunkMaxTokens  int         `json:"chunkMaxTokens,omitempty"`
+	LineRange       string      `json:"lineRange,omitempty"`
+	NoRedact        bool        `json:"noRedact,omitempty"`
+	Pinned          bool        `json:"pinned,omitempty"`
+	SourcePlanId    string      `json:"sourcePlanId,omitempty"`
+	SourceBranch    string      `json:"sourceBranch,omitempty"`
+	SourceContextId string      `json:"sourceContextId,omitempty"`
 	CreatedAt       time.Time   `json:"createdAt"`
 	UpdatedAt       time.Time   `json:"updatedAt"`
 }
@@ -105,6 +169,23 @@ type ConvoMessage struct {
 	Message   string    `json:"message"`
 	Stopped   bool      `json:"stopped"`
 	CreatedAt time.Time `json:"createdAt"`
+	// set on the summary message that replaced older messages when the conversation was compacted
+	Compaction *ConvoCompaction `json:"compaction,omitempty"`
+}
+
+// ConvoCompaction records which messages a compacted summary message replaced and where its summary came from
+type ConvoCompaction struct {
+	FromNum     int `json:"fromNum"`
+	ToNum       int `json:"toNum"`
+	NumMessages int `json:"numMessages"`
+	// total tokens of the messages that were replaced
+	Tokens int `json:"tokens"`
+	// the stored conversation summary that was reused, if there was one covering exactly the replaced messages
+	SummaryId string `json:"summaryId,omitempty"`
+	// the model that generated the summary otherwise
+	Model       string    `json:"model,omitempty"`
+	UserId      string    `json:"userId"`
+	CompactedAt time.Time `json:"compactedAt"`
 }
 
 type ConvoSummary struct {
@@ -197,6 +278,15 @@ type BaseModelConfig struct {
 	BaseUrl   string        `json:"baseUrl"`
 	ModelName string        `json:"modelName"`
 	MaxTokens int           `json:"maxTokens"`
+
+	HasImageSupport bool `json:"hasImageSupport"`
+
+	// for Azure OpenAI, the deployment serving the model (defaults to the model name) and the api version to call it with;
+	// the resource's endpoint goes in BaseUrl
+	AzureDeployment string `json:"azureDeployment,omitempty"`
+	ApiVersion      string `json:"apiVersion,omitempty"`
+	// for Bedrock, the AWS region to call the model in; defaults to the server's AWS_REGION
+	Region string `json:"region,omitempty"`
 }
 
 type PlannerModelConfig struct {
@@ -213,6 +303,10 @@ type ModelRoleConfig struct {
 	BaseModelConfig BaseModelConfig `json:"baseModelConfig"`
 	Temperature     float32         `json:"temperature"`
 	TopP            float32         `json:"topP"`
+	// caps the length of each reply from this role's model; 0 leaves it to the model
+	MaxCompletionTokens int `json:"maxCompletionTokens,omitempty"`
+	// models to fall back to, in order, when this role's model is rate limited, times out, or has server errors
+	Fallbacks []BaseModelConfig `json:"fallbacks,omitempty"`
 }
 
 type PlannerRoleConfig struct {
@@ -241,7 +335,51 @@ type ModelOverrides struct {
 }
 
 type PlanSettings struct {
-	ModelOverrides ModelOverrides `json:"modelOverrides"`
-	ModelSet       *ModelSet      `json:"modelSet"`
-	UpdatedAt      time.Time      `json:"updatedAt"`
+	ModelOverrides    ModelOverrides `json:"modelOverrides"`
+	ModelSet          *ModelSet      `json:"modelSet"`
+	AutoUpdateContext bool           `json:"autoUpdateContext,omitempty"`
+	UpdatedAt         time.Time      `json:"updatedAt"`
+	// overrides the limit on what's sent to the planner, see GetPlannerEffectiveMaxTokens; 0 derives it from the model
+	MaxContextTokens int `json:"maxContextTokens,omitempty"`
+	// how many files are built at once, see GetMaxParallelBuilds; 0 uses DefaultMaxParallelBuilds
+	MaxParallelBuilds int `json:"maxParallelBuilds,omitempty"`
+}
+
+type QuotaType string
+
+const (
+	QuotaRequestsPerMinute QuotaType = "requests_per_minute"
+	QuotaTokensPerDay      QuotaType = "tokens_per_day"
+	QuotaConcurrentStreams QuotaType = "concurrent_streams"
+)
+
+var QuotaLabels = map[QuotaType]string{
+	QuotaRequestsPerMinute: "requests per minute",
+	QuotaTokensPerDay:      "tokens per day",
+	QuotaConcurrentStreams: "concurrent streams",
+}
+
+// Quota limits an org's total use of the server, or one user's use within an org. A nil limit is unlimited. Tokens are
+// model input and output tokens, counted per UTC day.
+type Quota struct {
+	OrgId string `json:"orgId"`
+	// empty for the org's quota
+	UserId            string    `json:"userId,omitempty"`
+	UserEmail         string    `json:"userEmail,omitempty"`
+	RequestsPerMinute *int      `json:"requestsPerMinute"`
+	TokensPerDay      *int      `json:"tokensPerDay"`
+	ConcurrentStreams *int      `json:"concurrentStreams"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+type ApiKey struct {
+	Id    string      `json:"id"`
+	OrgId string      `json:"orgId"`
+	Name  string      `json:"name"`
+	Scope ApiKeyScope `json:"scope"`
+	// the start of the key, to tell keys apart; the full key is only returned when it's created
+	KeyPrefix  string     `json:"keyPrefix"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
 }
@@ -0,0 +1,95 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// token costs for high detail images, per OpenAI's vision pricing
+const (
+	imageBaseTokens      = 85
+	imageTileTokens      = 170
+	imageTileSize        = 512
+	imageMaxSide         = 2048
+	imageShortSideTarget = 768
+)
+
+var imageMimeTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+func IsImageMimeType(mimeType string) bool {
+	return imageMimeTypes[mimeType]
+}
+
+// GetImageDataUrl sniffs the content type of an image file and returns it encoded as a base64 data url, which is how image contexts are stored and sent to the model
+func GetImageDataUrl(content []byte) (string, error) {
+	mimeType := http.DetectContentType(content)
+	if !IsImageMimeType(mimeType) {
+		return "", fmt.Errorf("unsupported image type: %s", mimeType)
+	}
+
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(content)), nil
+}
+
+// GetImageNumTokens calculates the token cost of an image from its dimensions.
+// The image is scaled to fit within 2048x2048, then scaled so its shortest side is 768px, then each 512px tile costs 170 tokens plus a base of 85.
+func GetImageNumTokens(width, height int) int {
+	w := float64(width)
+	h := float64(height)
+
+	if w > imageMaxSide || h > imageMaxSide {
+		scale := imageMaxSide / math.Max(w, h)
+		w *= scale
+		h *= scale
+	}
+
+	if math.Min(w, h) > imageShortSideTarget {
+		scale := imageShortSideTarget / math.Min(w, h)
+		w *= scale
+		h *= scale
+	}
+
+	tiles := int(math.Ceil(w/imageTileSize) * math.Ceil(h/imageTileSize))
+
+	return imageBaseTokens + tiles*imageTileTokens
+}
+
+func GetImageDataUrlNumTokens(dataUrl string) (int, error) {
+	_, encoded, found := strings.Cut(dataUrl, ";base64,")
+	if !found {
+		return 0, fmt.Errorf("invalid image data url")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, fmt.Errorf("error decoding image: %v", err)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(decoded))
+	if err != nil {
+		// no decoder is registered for webp, so assume the worst case
+		return GetImageNumTokens(imageShortSideTarget, imageMaxSide), nil
+	}
+
+	return GetImageNumTokens(config.Width, config.Height), nil
+}
+
+func GetContextNumTokens(contextType ContextType, body string) (int, error) {
+	if contextType == ContextImageType {
+		return GetImageDataUrlNumTokens(body)
+	}
+
+	return GetNumTokens(body)
+}
@@ -0,0 +1,22 @@
+package shared
+
+import "testing"
+
+func TestGetImageNumTokens(t *testing.T) {
+	tests := []struct {
+		width, height int
+		want          int
+	}{
+		{512, 512, 255},
+		{1024, 1024, 765},
+		{2048, 4096, 1105},
+		{100, 4000, 765},
+	}
+
+	for _, tt := range tests {
+		got := GetImageNumTokens(tt.width, tt.height)
+		if got != tt.want {
+			t.Errorf("GetImageNumTokens(%d, %d) = %d, want %d", tt.width, tt.height, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package shared
+
+import (
+	"fmt"
+	"strings"
+)
+
+type NotifyTargetType string
+
+const (
+	NotifyTargetSlack   NotifyTargetType = "slack"
+	NotifyTargetDiscord NotifyTargetType = "discord"
+)
+
+// NotifyTarget is a Slack or Discord channel that's posted to through an incoming webhook
+type NotifyTarget struct {
+	Type       NotifyTargetType `json:"type"`
+	WebhookUrl string           `json:"webhookUrl"`
+}
+
+var notifyWebhookPrefixes = map[NotifyTargetType][]string{
+	NotifyTargetSlack:   {"https://hooks.slack.com/"},
+	NotifyTargetDiscord: {"https://discord.com/api/webhooks/", "https://discordapp.com/api/webhooks/"},
+}
+
+// Validate checks that the webhook url belongs to the target's service, so the server only ever posts to Slack or Discord
+func (t *NotifyTarget) Validate() error {
+	prefixes, ok := notifyWebhookPrefixes[t.Type]
+	if !ok {
+		return fmt.Errorf("unknown notification target %q", t.Type)
+	}
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(t.WebhookUrl, prefix) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid %s webhook url, expected it to start with %s", t.Type, strings.Join(prefixes, " or "))
+}
@@ -0,0 +1,172 @@
+package shared
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const maxCommitSubjectLen = 72
+
+var commitTypeKeywords = []struct {
+	commitType string
+	re         *regexp.Regexp
+}{
+	{"fix", regexp.MustCompile(`(?i)\b(fix(es|ed)?|bug|resolve[sd]?|correct(s|ed)?)\b`)},
+	{"refactor", regexp.MustCompile(`(?i)\b(refactor(s|ed)?|rename[sd]?|restructure[sd]?|clean(s|ed)? up|simplif(y|ies|ied))\b`)},
+	{"perf", regexp.MustCompile(`(?i)\b(performance|optimi[sz](e|es|ed))\b`)},
+	{"test", regexp.MustCompile(`(?i)\btests?\b`)},
+	{"docs", regexp.MustCompile(`(?i)\b(docs?|documentation|readme)\b`)},
+}
+
+// ConventionalCommitMsg builds a conventional commit message (type(scope): subject, then a body) for applying the
+// pending changes to the given paths, from the descriptions of the plan's replies that made them. The type is guessed
+// from the descriptions and files, and the scope is the directory all the files share, if any.
+func (state *CurrentPlanState) ConventionalCommitMsg(paths []string) string {
+	pathsSet := map[string]bool{}
+	for _, path := range paths {
+		pathsSet[path] = true
+	}
+
+	descByConvoMessageId := map[string]*ConvoMessageDescription{}
+	for _, desc := range state.ConvoMessageDescriptions {
+		descByConvoMessageId[desc.ConvoMessageId] = desc
+	}
+
+	var descs []*ConvoMessageDescription
+	seen := map[string]bool{}
+	allNew := true
+	for _, result := range state.PlanResult.Results {
+		if !result.IsPending() || !pathsSet[result.Path] {
+			continue
+		}
+
+		if len(result.Replacements) > 0 || result.Content == "" {
+			allNew = false
+		}
+
+		desc := descByConvoMessageId[result.ConvoMessageId]
+		if desc == nil || desc.CommitMsg == "" || seen[desc.ConvoMessageId] {
+			continue
+		}
+		seen[desc.ConvoMessageId] = true
+		descs = append(descs, desc)
+	}
+
+	sort.Slice(descs, func(i, j int) bool {
+		return descs[i].CreatedAt.Before(descs[j].CreatedAt)
+	})
+
+	var msgs []string
+	for _, desc := range descs {
+		msgs = append(msgs, strings.TrimSpace(desc.CommitMsg))
+	}
+
+	commitType := conventionalCommitType(msgs, paths, allNew)
+
+	header := commitType
+	if scope := commitScope(paths); scope != "" {
+		header += "(" + scope + ")"
+	}
+	header += ": "
+
+	subject := "apply plandex changes"
+	if len(msgs) > 0 {
+		subject = commitSubject(msgs[0])
+	}
+	if n := maxCommitSubjectLen - len(header) - 3; len(header)+len(subject) > maxCommitSubjectLen && n > 0 {
+		subject = strings.TrimSpace(subject[:n]) + "..."
+	}
+
+	lines := []string{header + subject}
+
+	if len(msgs) > 1 {
+		lines = append(lines, "")
+		for _, msg := range msgs {
+			lines = append(lines, "- "+commitSubject(msg))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func conventionalCommitType(msgs, paths []string, allNew bool) string {
+	allDocs := len(paths) > 0
+	for _, path := range paths {
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".md" && ext != ".mdx" && ext != ".txt" && ext != ".rst" {
+			allDocs = false
+			break
+		}
+	}
+	if allDocs {
+		return "docs"
+	}
+
+	allTests := len(paths) > 0
+	for _, path := range paths {
+		base := strings.ToLower(filepath.Base(path))
+		if !strings.Contains(base, "_test.") && !strings.Contains(base, ".test.") && !strings.Contains(base, ".spec.") && !strings.HasPrefix(base, "test_") {
+			allTests = false
+			break
+		}
+	}
+	if allTests {
+		return "test"
+	}
+
+	if allNew {
+		return "feat"
+	}
+
+	text := strings.Join(msgs, "\n")
+	for _, keyword := range commitTypeKeywords {
+		if keyword.re.MatchString(text) {
+			return keyword.commitType
+		}
+	}
+
+	return "feat"
+}
+
+// commitScope is the deepest directory that contains all the paths, or "" if they're at the root or have nothing in common
+func commitScope(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+
+	common := strings.Split(filepath.ToSlash(filepath.Dir(paths[0])), "/")
+	for _, path := range paths[1:] {
+		parts := strings.Split(filepath.ToSlash(filepath.Dir(path)), "/")
+		n := 0
+		for n < len(common) && n < len(parts) && common[n] == parts[n] {
+			n++
+		}
+		common = common[:n]
+	}
+
+	if len(common) == 0 || common[0] == "." {
+		return ""
+	}
+	return common[len(common)-1]
+}
+
+// commitSubject is the first line of a description's commit message, lowercased and without a trailing period as
+// conventional commits expect
+func commitSubject(msg string) string {
+	subject := strings.TrimSpace(strings.SplitN(msg, "\n", 2)[0])
+	subject = strings.TrimSuffix(subject, ".")
+
+	if len(subject) > 1 && !isUpperWord(subject) {
+		subject = strings.ToLower(subject[:1]) + subject[1:]
+	}
+
+	return subject
+}
+
+// isUpperWord is true if the subject starts with an acronym like API, which shouldn't be lowercased
+func isUpperWord(s string) bool {
+	word := strings.SplitN(s, " ", 2)[0]
+	return len(word) > 1 && strings.ToUpper(word) == word
+}
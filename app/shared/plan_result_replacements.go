@@ -21,7 +21,8 @@ func ApplyReplacements(content string, replacements []*Replacement, setFailed bo
 
 			pre := updated[:lastInsertedIdx]
 			sub := updated[lastInsertedIdx:]
-			originalIdx := strings.Index(updated, replacement.Old)
+			// search after the previous replacement, since that's where strings.Replace below looks
+			originalIdx := strings.Index(sub, replacement.Old)
 
 			// log.Println("originalIdx:", originalIdx)
 
@@ -1,8 +1,16 @@
 package shared
 
+import "strings"
+
 type ModelProvider string
 
-const ModelProviderOpenAI ModelProvider = "openai"
+const (
+	ModelProviderOpenAI      ModelProvider = "openai"
+	ModelProviderAzureOpenAI ModelProvider = "azure-openai"
+	ModelProviderBedrock     ModelProvider = "bedrock"
+)
+
+var AllModelProviders = []ModelProvider{ModelProviderOpenAI, ModelProviderAzureOpenAI, ModelProviderBedrock}
 
 type ModelRole string
 
@@ -24,14 +32,60 @@ var ModelRoleDescriptions = map[ModelRole]string{
 	ModelRoleCommitMsg:   "writes commit messages",
 	ModelRoleExecStatus:  "determines whether to auto-continue",
 }
+
+// alternate names accepted for roles, e.g. by 'plandex set-model'
+var ModelRoleAliases = map[string]ModelRole{
+	"coder":    ModelRoleBuilder,
+	"namer":    ModelRoleName,
+	"verifier": ModelRoleExecStatus,
+}
+
+// ModelRoleFromString matches a role by name or alias, case-insensitively. It returns an empty role if nothing matches.
+func ModelRoleFromString(s string) ModelRole {
+	for _, r := range AllModelRoles {
+		if strings.EqualFold(string(r), s) {
+			return r
+		}
+	}
+	return ModelRoleAliases[strings.ToLower(s)]
+}
+
 var SettingDescriptions = map[string]string{
 	"max-convo-tokens":       "max conversation 🪙 before summarization",
 	"max-tokens":             "overall 🪙 limit",
 	"reserved-output-tokens": "🪙 reserved for model output",
+	"auto-update-context":    "update outdated context before tell and continue without asking",
+	"max-context-tokens":     "max 🪙 of context, prompt, and conversation sent to the planner (can only lower the model's limit)",
+	"max-parallel-builds":    "max files built at once (lower it if the builder model is rate limited)",
 }
 
 var ModelOverridePropsDasherized = []string{"max-convo-tokens", "max-tokens", "reserved-output-tokens"}
 
+var ConfigSettingsDasherized = []string{"auto-update-context", "max-context-tokens", "max-parallel-builds"}
+
+// files beyond this are queued until a build finishes, so a reply that changes many files doesn't hit the builder model's rate
+// limits all at once
+const DefaultMaxParallelBuilds = 5
+
+// RoleConfig returns the settings for a role, for updating settings that every role has
+func (ms *ModelSet) RoleConfig(role ModelRole) *ModelRoleConfig {
+	switch role {
+	case ModelRolePlanner:
+		return &ms.Planner.ModelRoleConfig
+	case ModelRolePlanSummary:
+		return &ms.PlanSummary
+	case ModelRoleBuilder:
+		return &ms.Builder.ModelRoleConfig
+	case ModelRoleName:
+		return &ms.Namer.ModelRoleConfig
+	case ModelRoleCommitMsg:
+		return &ms.CommitMsg.ModelRoleConfig
+	case ModelRoleExecStatus:
+		return &ms.ExecStatus.ModelRoleConfig
+	}
+	return nil
+}
+
 func (ps PlanSettings) GetPlannerMaxTokens() int {
 	if ps.ModelOverrides.MaxTokens == nil {
 		if ps.ModelSet == nil {
@@ -68,6 +122,25 @@ func (ps PlanSettings) GetPlannerReservedOutputTokens() int {
 	}
 }
 
-func (ps PlanSettings) GetPlannerEffectiveMaxTokens() int {
+// GetPlannerModelMaxContextTokens is the planner model's context window minus the tokens reserved for its output
+func (ps PlanSettings) GetPlannerModelMaxContextTokens() int {
 	return ps.GetPlannerMaxTokens() - ps.GetPlannerReservedOutputTokens()
 }
+
+// GetPlannerEffectiveMaxTokens is the limit on what's sent to the planner: the plan's max-context-tokens if it's set, otherwise
+// the limit derived from the model. A plan's setting can't raise the limit past what the model allows.
+func (ps PlanSettings) GetPlannerEffectiveMaxTokens() int {
+	modelMax := ps.GetPlannerModelMaxContextTokens()
+	if ps.MaxContextTokens > 0 && ps.MaxContextTokens < modelMax {
+		return ps.MaxContextTokens
+	}
+	return modelMax
+}
+
+// GetMaxParallelBuilds is how many files the plan builds at once
+func (ps PlanSettings) GetMaxParallelBuilds() int {
+	if ps.MaxParallelBuilds > 0 {
+		return ps.MaxParallelBuilds
+	}
+	return DefaultMaxParallelBuilds
+}
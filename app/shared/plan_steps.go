@@ -0,0 +1,27 @@
+package shared
+
+type PlanStepStatus string
+
+const (
+	PlanStepStatusPending PlanStepStatus = "pending"
+	PlanStepStatusDone    PlanStepStatus = "done"
+	PlanStepStatusFailed  PlanStepStatus = "failed"
+)
+
+// PlanStep is one of the subtasks the planner breaks a task into. The steps are tracked as replies complete them, so a plan
+// that's continued picks up from the first step that isn't done.
+type PlanStep struct {
+	Num         int            `json:"num"`
+	Description string         `json:"description"`
+	Status      PlanStepStatus `json:"status"`
+}
+
+// NextPlanStep is the first step that isn't done, or nil if they all are
+func NextPlanStep(steps []*PlanStep) *PlanStep {
+	for _, step := range steps {
+		if step.Status != PlanStepStatusDone {
+			return step
+		}
+	}
+	return nil
+}
@@ -39,6 +39,28 @@ type SessionResponse struct {
 	Orgs     []*Org `json:"orgs"`
 }
 
+type SsoConfigResponse struct {
+	Enabled bool `json:"enabled"`
+	// set if the server only allows signing in with SSO
+	Required bool `json:"required"`
+}
+
+type StartSsoResponse struct {
+	LoginId string `json:"loginId"`
+	// sent when polling for the result, so only the client that started the sign-in can finish it
+	PollToken string `json:"pollToken"`
+	// where the user signs in with the identity provider
+	AuthUrl string `json:"authUrl"`
+	// shown in the terminal and entered in the browser after signing in, so a sign-in started by someone else can't be
+	// finished by opening their link
+	UserCode string `json:"userCode"`
+}
+
+type PollSsoRequest struct {
+	LoginId   string `json:"loginId"`
+	PollToken string `json:"pollToken"`
+}
+
 type CreateOrgRequest struct {
 	Name               string `json:"name"`
 	AutoAddDomainUsers bool   `json:"autoAddDomainUsers"`
@@ -114,6 +136,8 @@ type TellPlanRequest struct {
 	IsUserContinue bool            `json:"isUserContinue"`
 	ApiKey         string          `json:"apiKey"`
 	ProjectPaths   map[string]bool `json:"projectPaths"`
+	// chat channels to notify when the plan finishes, for plans running in the background
+	Notify []*NotifyTarget `json:"notify,omitempty"`
 }
 
 type BuildPlanRequest struct {
@@ -145,20 +169,47 @@ type LoadContextParams struct {
 	FilePath        string      `json:"file_path"`
 	Body            string      `json:"body"`
 	ForceSkipIgnore bool        `json:"forceSkipIgnore"`
+	MaxDepth        int         `json:"maxDepth,omitempty"`
+	PdfPages        string      `json:"pdfPages,omitempty"`
+	Rendered        bool        `json:"rendered,omitempty"`
+	Raw             bool        `json:"raw,omitempty"`
+	Summarize       bool        `json:"summarize,omitempty"`
+	ChunkPart       int         `json:"chunkPart,omitempty"`
+	ChunkMaxTokens  int         `json:"chunkMaxTokens,omitempty"`
+	LineRange       string      `json:"lineRange,omitempty"`
+	NoRedact        bool        `json:"noRedact,omitempty"`
+	ApiKey          string      `json:"apiKey,omitempty"`
+
+	// set to load another plan's context by reference instead of sending a body
+	SourcePlanId    string `json:"sourcePlanId,omitempty"`
+	SourceBranch    string `json:"sourceBranch,omitempty"`
+	SourceContextId string `json:"sourceContextId,omitempty"`
+
+	// sent in place of the body when the server already has a body with this sha256
+	BodySha string `json:"bodySha,omitempty"`
 }
 
 type LoadContextRequest []*LoadContextParams
 
 type LoadContextResponse struct {
-	TokensAdded       int    `json:"tokensAdded"`
-	TotalTokens       int    `json:"totalTokens"`
-	MaxTokensExceeded bool   `json:"maxTokensExceeded"`
-	MaxTokens         int    `json:"maxTokens"`
-	Msg               string `json:"msg"`
+	TokensAdded       int      `json:"tokensAdded"`
+	TotalTokens       int      `json:"totalTokens"`
+	MaxTokensExceeded bool     `json:"maxTokensExceeded"`
+	MaxTokens         int      `json:"maxTokens"`
+	Msg               string   `json:"msg"`
+	Unchanged         []string `json:"unchanged,omitempty"`
+
+	// shas sent in place of bodies that the server doesn't have, so nothing was loaded. They need to be sent again with their bodies.
+	MissingBodyShas []string `json:"missingBodyShas,omitempty"`
+
+	// set by servers that accept a BodySha in place of a body
+	AcceptsBodySha bool `json:"acceptsBodySha,omitempty"`
 }
 
 type UpdateContextParams struct {
-	Body string `json:"body"`
+	Body    string `json:"body"`
+	ApiKey  string `json:"apiKey,omitempty"`
+	BodySha string `json:"bodySha,omitempty"`
 }
 
 type UpdateContextRequest map[string]*UpdateContextParams
@@ -169,14 +220,46 @@ type DeleteContextRequest struct {
 	Ids map[string]bool `json:"ids"`
 }
 
+type PinContextRequest struct {
+	Ids    map[string]bool `json:"ids"`
+	Pinned bool            `json:"pinned"`
+}
+
+type PinContextResponse struct {
+	Msg string `json:"msg"`
+}
+
 type DeleteContextResponse struct {
 	TokensRemoved int    `json:"tokensRemoved"`
 	TotalTokens   int    `json:"totalTokens"`
 	Msg           string `json:"msg"`
 }
 
+// ApplyPlanRequest limits an apply to some of a plan's pending changes. Changes that aren't applied stay pending.
+type ApplyPlanRequest struct {
+	// when set, only pending changes to these paths are applied
+	Paths []string `json:"paths,omitempty"`
+	// files with only some of their changes applied, by path
+	PartialByPath map[string]*PartialApply `json:"partialByPath,omitempty"`
+}
+
+type PartialApply struct {
+	// the file's content as written to the project
+	Content string `json:"content"`
+	// the rejected changes to Content, which stay pending
+	Replacements []*Replacement `json:"replacements"`
+}
+
 type RejectFileRequest struct {
 	FilePath string `json:"filePath"`
+	// why the changes were rejected, which the model sees with the next prompt
+	Comment string `json:"comment,omitempty"`
+}
+
+type EditFileRequest struct {
+	FilePath string `json:"filePath"`
+	// the file as the user edited it, which replaces the plan's version
+	Content string `json:"content"`
 }
 
 type RewindPlanRequest struct {
@@ -188,11 +271,78 @@ type RewindPlanResponse struct {
 	LatestCommit string `json:"latestCommit"`
 }
 
+type CompactConvoRequest struct {
+	// number of the most recent messages to keep verbatim
+	KeepRecent int    `json:"keepRecent"`
+	ApiKey     string `json:"apiKey"`
+}
+
+type CompactConvoResponse struct {
+	Compaction    *ConvoCompaction `json:"compaction"`
+	SummaryTokens int              `json:"summaryTokens"`
+	TokensBefore  int              `json:"tokensBefore"`
+	TokensAfter   int              `json:"tokensAfter"`
+}
+
 type LogResponse struct {
 	Shas []string `json:"shas"`
 	Body string   `json:"body"`
 }
 
+// PlanRevision is the plan's state as of one commit in its history
+type PlanRevision struct {
+	Sha string `json:"sha"`
+	// the plan's version of each file with pending changes
+	Files map[string]string `json:"files"`
+	// when each file was last applied
+	AppliedAtByPath map[string]time.Time `json:"appliedAtByPath"`
+	Contexts        []*Context           `json:"contexts"`
+}
+
+type PlanDiffResponse struct {
+	From *PlanRevision `json:"from"`
+	To   *PlanRevision `json:"to"`
+}
+
+type ReviewRequest struct {
+	Range string `json:"range"`
+	// the output of git log and git diff for the range
+	Log    string `json:"log"`
+	Diff   string `json:"diff"`
+	ApiKey string `json:"apiKey"`
+}
+
+type ReviewCategory string
+
+const (
+	ReviewCategoryBug      ReviewCategory = "bug"
+	ReviewCategoryStyle    ReviewCategory = "style"
+	ReviewCategorySecurity ReviewCategory = "security"
+)
+
+type ReviewSeverity string
+
+const (
+	ReviewSeverityHigh   ReviewSeverity = "high"
+	ReviewSeverityMedium ReviewSeverity = "medium"
+	ReviewSeverityLow    ReviewSeverity = "low"
+)
+
+type ReviewFinding struct {
+	Path string `json:"path"`
+	// line in the new version of the file, or 0 if the finding isn't about a specific line
+	Line     int            `json:"line"`
+	Category ReviewCategory `json:"category"`
+	Severity ReviewSeverity `json:"severity"`
+	Message  string         `json:"message"`
+}
+
+type ReviewResponse struct {
+	Range    string           `json:"range"`
+	Summary  string           `json:"summary"`
+	Findings []*ReviewFinding `json:"findings"`
+}
+
 type CreateBranchRequest struct {
 	Name string `json:"name"`
 }
@@ -209,3 +359,92 @@ type ListUsersResponse struct {
 	Users            []*User             `json:"users"`
 	OrgUsersByUserId map[string]*OrgUser `json:"orgUsersByUserId"`
 }
+
+// PlanArchiveVersion is bumped whenever the layout of a plan's stored files changes, so an older server can refuse archives it can't read
+const PlanArchiveVersion = 1
+
+// PlanArchive is a portable copy of a plan branch, as written by export and read by import
+type PlanArchive struct {
+	Version    int       `json:"version"`
+	Name       string    `json:"name"`
+	Branch     string    `json:"branch"`
+	ExportedAt time.Time `json:"exportedAt"`
+	// the plan's stored settings, context, conversation, results, and descriptions by path relative to the plan dir
+	Files     map[string]string `json:"files"`
+	Summaries []*ConvoSummary   `json:"summaries"`
+}
+
+type ImportPlanResponse = CreatePlanResponse
+
+// UsageRow totals model usage for a plan, day, or model, or overall
+type UsageRow struct {
+	// plan name, day (YYYY-MM-DD in UTC), or model name
+	Key          string  `json:"key"`
+	PlanId       string  `json:"planId,omitempty"`
+	NumRequests  int     `json:"numRequests"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	CostUsd      float64 `json:"costUsd"`
+	// input tokens read from prompt caches, and how much less they cost than uncached input
+	CachedInputTokens int     `json:"cachedInputTokens"`
+	CacheSavingsUsd   float64 `json:"cacheSavingsUsd"`
+}
+
+type UsageResponse struct {
+	Total   *UsageRow   `json:"total"`
+	ByPlan  []*UsageRow `json:"byPlan"`
+	ByDay   []*UsageRow `json:"byDay"`
+	ByModel []*UsageRow `json:"byModel"`
+}
+
+// OrgUsageRow totals an org's API calls and model usage for a user on a day, for a user, or overall
+type OrgUsageRow struct {
+	// YYYY-MM-DD in UTC. Empty in totals that span days.
+	Day string `json:"day,omitempty"`
+	// empty in the org's total
+	UserId            string  `json:"userId,omitempty"`
+	UserEmail         string  `json:"userEmail,omitempty"`
+	ApiCalls          int     `json:"apiCalls"`
+	ModelRequests     int     `json:"modelRequests"`
+	InputTokens       int     `json:"inputTokens"`
+	CachedInputTokens int     `json:"cachedInputTokens"`
+	OutputTokens      int     `json:"outputTokens"`
+	CostUsd           float64 `json:"costUsd"`
+	CacheSavingsUsd   float64 `json:"cacheSavingsUsd"`
+}
+
+type OrgUsageResponse struct {
+	// the first day included and the day after the last, YYYY-MM-DD in UTC
+	Since        string         `json:"since"`
+	Until        string         `json:"until"`
+	Total        *OrgUsageRow   `json:"total"`
+	ByUser       []*OrgUsageRow `json:"byUser"`
+	ByDayAndUser []*OrgUsageRow `json:"byDayAndUser"`
+}
+
+// SetQuotaRequest sets the org's quota, or a user's if UserEmail is set. A nil limit is left as is, and 0 removes it.
+type SetQuotaRequest struct {
+	UserEmail         string `json:"userEmail,omitempty"`
+	RequestsPerMinute *int   `json:"requestsPerMinute,omitempty"`
+	TokensPerDay      *int   `json:"tokensPerDay,omitempty"`
+	ConcurrentStreams *int   `json:"concurrentStreams,omitempty"`
+}
+
+type CreateApiKeyRequest struct {
+	Name  string      `json:"name"`
+	Scope ApiKeyScope `json:"scope"`
+	// 0 for a key that doesn't expire
+	ExpiresInDays int `json:"expiresInDays"`
+}
+
+type CreateApiKeyResponse struct {
+	ApiKey *ApiKey `json:"apiKey"`
+	// the key to authenticate with; it isn't stored, so it can't be shown again
+	Key string `json:"key"`
+}
+
+type SharePlanRequest struct {
+	// the user to share with, or empty to share with everyone in the org
+	Email      string         `json:"email"`
+	Permission PlanPermission `json:"permission"`
+}
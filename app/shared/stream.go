@@ -6,6 +6,17 @@ type BuildInfo struct {
 	Path      string `json:"path"`
 	NumTokens int    `json:"numTokens"`
 	Finished  bool   `json:"finished"`
+	// waiting for another file's build to finish before starting, see PlanSettings.GetMaxParallelBuilds
+	Queued bool `json:"queued,omitempty"`
+}
+
+// StreamUsage is sent as each model stream starts. Output is counted at one token per streamed chunk, as the server records it, so the client can estimate cost as the stream runs.
+type StreamUsage struct {
+	Role        ModelRole `json:"role"`
+	ModelName   string    `json:"modelName"`
+	InputTokens int       `json:"inputTokens"`
+	// the part of InputTokens that's expected to be read from the model's prompt cache
+	CachedInputTokens int `json:"cachedInputTokens,omitempty"`
 }
 
 type StreamMessageType string
@@ -17,6 +28,7 @@ const (
 	StreamMessageDescribing        StreamMessageType = "describing"
 	StreamMessageRepliesFinished   StreamMessageType = "repliesFinished"
 	StreamMessageBuildInfo         StreamMessageType = "buildInfo"
+	StreamMessageUsage             StreamMessageType = "usage"
 	StreamMessagePromptMissingFile StreamMessageType = "promptMissingFile"
 	StreamMessageAborted           StreamMessageType = "aborted"
 	StreamMessageFinished          StreamMessageType = "finished"
@@ -29,6 +41,7 @@ type StreamMessage struct {
 	ReplyChunk string `json:"replyChunk,omitempty"`
 
 	BuildInfo       *BuildInfo               `json:"buildInfo,omitempty"`
+	Usage           *StreamUsage             `json:"usage,omitempty"`
 	Description     *ConvoMessageDescription `json:"description,omitempty"`
 	Error           *ApiError                `json:"error,omitempty"`
 	MissingFilePath string                   `json:"missingFilePath,omitempty"`
@@ -37,4 +50,7 @@ type StreamMessage struct {
 	InitPrompt    string   `json:"initPrompt,omitempty"`
 	InitReplies   []string `json:"initReplies,omitempty"`
 	InitBuildOnly bool     `json:"initBuildOnly,omitempty"`
+
+	// numbers the plan's stream messages in order, so a client can resume after the last one it received
+	Seq int `json:"seq,omitempty"`
 }
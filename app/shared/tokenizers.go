@@ -0,0 +1,160 @@
+package shared
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+type TokenizerName string
+
+const (
+	TokenizerCl100k TokenizerName = "cl100k_base"
+	TokenizerO200k  TokenizerName = "o200k_base"
+	// Anthropic doesn't publish Claude 3's tokenizer, so Claude counts are approximated from cl100k
+	TokenizerClaude TokenizerName = "claude"
+	// Llama 2's sentencepiece vocabulary is much smaller than cl100k's, so its counts are approximated from cl100k too
+	TokenizerLlama2 TokenizerName = "llama2"
+)
+
+// DefaultTokenizer is used for models that aren't in the registry, and for counts made before the model is known, like the
+// token counts of context as it's loaded
+const DefaultTokenizer = TokenizerCl100k
+
+type tokenizerConfig struct {
+	// the tiktoken encoding that's used to count
+	encoding TokenizerName
+	// for approximations, how many of the model's tokens there are per token of the encoding
+	scale float64
+}
+
+var tokenizerConfigs = map[TokenizerName]tokenizerConfig{
+	TokenizerCl100k: {encoding: TokenizerCl100k},
+	TokenizerO200k:  {encoding: TokenizerO200k},
+	TokenizerClaude: {encoding: TokenizerCl100k, scale: 1.15},
+	TokenizerLlama2: {encoding: TokenizerCl100k, scale: 1.3},
+}
+
+// tokenizers by model name prefix, checked in order so that more specific prefixes come first
+var tokenizersByModelPrefix = []struct {
+	prefix    string
+	tokenizer TokenizerName
+}{
+	{"gpt-4o", TokenizerO200k},
+	{"gpt-4", TokenizerCl100k},
+	{"gpt-3.5", TokenizerCl100k},
+	{"anthropic.claude", TokenizerClaude},
+	{"claude", TokenizerClaude},
+	// Llama 3's vocabulary extends cl100k's, so cl100k counts are close
+	{"meta.llama3", TokenizerCl100k},
+	{"llama3", TokenizerCl100k},
+	{"meta.llama2", TokenizerLlama2},
+	{"llama2", TokenizerLlama2},
+}
+
+// TokenizerForModel returns the tokenizer that counts tokens for a model, or DefaultTokenizer if the model isn't in the registry
+func TokenizerForModel(modelName string) TokenizerName {
+	for _, t := range tokenizersByModelPrefix {
+		if strings.HasPrefix(modelName, t.prefix) {
+			return t.tokenizer
+		}
+	}
+	return DefaultTokenizer
+}
+
+// GetNumTokensForModel counts text's tokens with the model's tokenizer
+func GetNumTokensForModel(modelName, text string) (int, error) {
+	return countTokensCached(TokenizerForModel(modelName), text, func() (int, error) {
+		counter, err := NewTokenCounterForModel(modelName)
+		if err != nil {
+			return 0, err
+		}
+		return counter.Count(text), nil
+	})
+}
+
+func NewTokenCounterForModel(modelName string) (*TokenCounter, error) {
+	config := tokenizerConfigs[TokenizerForModel(modelName)]
+
+	tkm, err := getEncoding(config.encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenCounter{tkm: tkm, scale: config.scale}, nil
+}
+
+var encodings = map[TokenizerName]*tiktoken.Tiktoken{}
+var encodingsMu sync.Mutex
+
+// getEncoding loads each encoding once, since building one compiles its regex and ranks
+func getEncoding(name TokenizerName) (*tiktoken.Tiktoken, error) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	if tkm, ok := encodings[name]; ok {
+		return tkm, nil
+	}
+
+	var tkm *tiktoken.Tiktoken
+	var err error
+	if name == TokenizerO200k {
+		tkm, err = o200kEncoding()
+	} else {
+		tkm, err = tiktoken.GetEncoding(string(name))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s encoding: %v", name, err)
+	}
+
+	encodings[name] = tkm
+	return tkm, nil
+}
+
+// tiktoken-go doesn't include o200k_base, so it's built from OpenAI's published ranks and pattern
+func o200kEncoding() (*tiktoken.Tiktoken, error) {
+	ranks, err := tiktoken.NewDefaultBpeLoader().LoadTiktokenBpe("https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken")
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := &tiktoken.Encoding{
+		Name: string(TokenizerO200k),
+		PatStr: strings.Join([]string{
+			`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+			`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+			`\p{N}{1,3}`,
+			` ?[^\s\p{L}\p{N}]+[\r\n/]*`,
+			`\s*[\r\n]+`,
+			`\s+(?!\S)`,
+			`\s+`,
+		}, "|"),
+		MergeableRanks: ranks,
+		SpecialTokens: map[string]int{
+			tiktoken.ENDOFTEXT:   199999,
+			tiktoken.ENDOFPROMPT: 200018,
+		},
+	}
+
+	bpe, err := tiktoken.NewCoreBPE(encoding.MergeableRanks, encoding.SpecialTokens, encoding.PatStr)
+	if err != nil {
+		return nil, err
+	}
+
+	specialTokensSet := map[string]any{}
+	for k := range encoding.SpecialTokens {
+		specialTokensSet[k] = true
+	}
+
+	return tiktoken.NewTiktoken(bpe, encoding, specialTokensSet), nil
+}
+
+func (c *TokenCounter) scaled(n int) int {
+	if c.scale == 0 {
+		return n
+	}
+	return int(math.Ceil(float64(n) * c.scale))
+}
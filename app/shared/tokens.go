@@ -1,16 +1,168 @@
 package shared
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"strings"
 
 	"github.com/pkoukk/tiktoken-go"
 )
 
 func GetNumTokens(text string) (int, error) {
-	tkm, err := tiktoken.EncodingForModel("gpt-4")
+	return countTokensCached(DefaultTokenizer, text, func() (int, error) {
+		tkm, err := getEncoding(DefaultTokenizer)
+		if err != nil {
+			return 0, err
+		}
+		return len(tkm.Encode(text, nil, nil)), nil
+	})
+}
+
+// TokenCountCache stores token counts by the sha256 of the counted text, so the same text isn't tokenized again
+type TokenCountCache interface {
+	GetTokenCount(sha string, tokenizer TokenizerName) (int, bool)
+	SetTokenCount(sha string, tokenizer TokenizerName, numTokens int)
+}
+
+var tokenCountCache TokenCountCache
+
+// hashing is much faster than tokenizing, but for short text a cache lookup costs more than it saves
+const minCachedTokenCountSize = 1024
+
+// SetTokenCountCache sets the cache used by GetNumTokens and GetNumTokensForModel. Without one, every count tokenizes.
+func SetTokenCountCache(cache TokenCountCache) {
+	tokenCountCache = cache
+}
+
+func countTokensCached(tokenizer TokenizerName, text string, count func() (int, error)) (int, error) {
+	if tokenCountCache == nil || len(text) < minCachedTokenCountSize {
+		return count()
+	}
+
+	hash := sha256.Sum256([]byte(text))
+	sha := hex.EncodeToString(hash[:])
+
+	if numTokens, ok := tokenCountCache.GetTokenCount(sha, tokenizer); ok {
+		return numTokens, nil
+	}
+
+	numTokens, err := count()
 	if err != nil {
-		err = fmt.Errorf("error getting encoding for model: %v", err)
 		return 0, err
 	}
-	return len(tkm.Encode(text, nil, nil)), nil
+
+	tokenCountCache.SetTokenCount(sha, tokenizer, numTokens)
+	return numTokens, nil
+}
+
+// TokenCounter reuses one encoding across calls, for counting many small pieces of text like the lines of a stream
+type TokenCounter struct {
+	tkm   *tiktoken.Tiktoken
+	scale float64
+}
+
+func NewTokenCounter() (*TokenCounter, error) {
+	tkm, err := getEncoding(DefaultTokenizer)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenCounter{tkm: tkm}, nil
+}
+
+func (c *TokenCounter) Count(text string) int {
+	return c.scaled(len(c.tkm.Encode(text, nil, nil)))
+}
+
+const truncatedMarkerFmt = "\n\n[... %d tokens truncated ...]\n\n"
+
+// TruncateHeadTail keeps the beginning and end of text within maxTokens, replacing the middle with a marker
+func TruncateHeadTail(text string, maxTokens int) (string, error) {
+	tkm, err := getEncoding(DefaultTokenizer)
+	if err != nil {
+		return "", err
+	}
+
+	tokens := tkm.Encode(text, nil, nil)
+	if len(tokens) <= maxTokens {
+		return text, nil
+	}
+
+	markerTokens := len(tkm.Encode(fmt.Sprintf(truncatedMarkerFmt, len(tokens)), nil, nil))
+	keep := maxTokens - markerTokens
+	if keep < 2 {
+		keep = 2
+	}
+
+	head := tokens[:keep/2]
+	tail := tokens[len(tokens)-(keep-keep/2):]
+	truncated := len(tokens) - len(head) - len(tail)
+
+	return tkm.Decode(head) + fmt.Sprintf(truncatedMarkerFmt, truncated) + tkm.Decode(tail), nil
+}
+
+// ChunkByBoundaries splits text into chunks of at most maxTokens, breaking at section boundaries where possible.
+// A boundary is a line with no leading whitespace that follows a blank line, which is where top-level functions, types, and markdown sections usually start.
+// A section that's too large on its own is split between lines, and a single line larger than maxTokens becomes its own chunk.
+func ChunkByBoundaries(text string, maxTokens int) ([]string, error) {
+	tkm, err := getEncoding(DefaultTokenizer)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+
+	var sections [][]string
+	var current []string
+	prevBlank := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		isBoundary := prevBlank && trimmed != "" && line[0] != ' ' && line[0] != '\t' && line[0] != '}' && line[0] != ')'
+		if isBoundary && len(current) > 0 {
+			sections = append(sections, current)
+			current = nil
+		}
+		current = append(current, line)
+		prevBlank = trimmed == ""
+	}
+	if len(current) > 0 {
+		sections = append(sections, current)
+	}
+
+	var chunks []string
+	var chunk strings.Builder
+	chunkTokens := 0
+
+	flush := func() {
+		if chunk.Len() > 0 {
+			chunks = append(chunks, chunk.String())
+			chunk.Reset()
+			chunkTokens = 0
+		}
+	}
+
+	add := func(s string, numTokens int) {
+		if chunkTokens+numTokens > maxTokens {
+			flush()
+		}
+		chunk.WriteString(s)
+		chunkTokens += numTokens
+	}
+
+	for _, section := range sections {
+		sectionText := strings.Join(section, "")
+		sectionTokens := len(tkm.Encode(sectionText, nil, nil))
+
+		if sectionTokens <= maxTokens {
+			add(sectionText, sectionTokens)
+			continue
+		}
+
+		for _, line := range section {
+			add(line, len(tkm.Encode(line, nil, nil)))
+		}
+	}
+	flush()
+
+	return chunks, nil
 }
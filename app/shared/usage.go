@@ -0,0 +1,41 @@
+package shared
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var orgUsageCsvHeader = []string{"day", "user_email", "user_id", "api_calls", "model_requests", "input_tokens", "cached_input_tokens", "output_tokens", "cost_usd", "cache_savings_usd"}
+
+// WriteCsv writes a row per user per day, for importing into a spreadsheet or billing system
+func (res *OrgUsageResponse) WriteCsv(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write(orgUsageCsvHeader)
+	if err != nil {
+		return fmt.Errorf("error writing csv header: %v", err)
+	}
+
+	for _, row := range res.ByDayAndUser {
+		err = writer.Write([]string{
+			row.Day,
+			row.UserEmail,
+			row.UserId,
+			strconv.Itoa(row.ApiCalls),
+			strconv.Itoa(row.ModelRequests),
+			strconv.Itoa(row.InputTokens),
+			strconv.Itoa(row.CachedInputTokens),
+			strconv.Itoa(row.OutputTokens),
+			strconv.FormatFloat(row.CostUsd, 'f', 6, 64),
+			strconv.FormatFloat(row.CacheSavingsUsd, 'f', 6, 64),
+		})
+		if err != nil {
+			return fmt.Errorf("error writing csv row: %v", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}